@@ -0,0 +1,98 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"log"
+	"os"
+	"os/exec"
+	"strings"
+
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/vorpalengineering/x402-go/facilitator"
+)
+
+// promptPassphrase asks for the passphrase of file on the terminal, with
+// echo turned off while it is typed
+func promptPassphrase(file string) ([]byte, error) {
+	tty, err := os.OpenFile("/dev/tty", os.O_RDWR, 0)
+	if err != nil {
+		return nil, fmt.Errorf("no terminal to ask for the passphrase of %s, set the passphrase environment variable", file)
+	}
+	defer tty.Close()
+
+	fmt.Fprintf(tty, "Passphrase for %s: ", file)
+	if stty(tty, "-echo") == nil {
+		defer func() {
+			stty(tty, "echo")
+			fmt.Fprintln(tty)
+		}()
+	}
+	line, err := bufio.NewReader(tty).ReadBytes('\n')
+	if err != nil {
+		clear(line)
+		return nil, fmt.Errorf("failed to read passphrase: %w", err)
+	}
+	return bytes.TrimRight(line, "\r\n"), nil
+}
+
+// stty changes the terminal mode with the stty command
+func stty(tty *os.File, mode string) error {
+	cmd := exec.Command("stty", mode)
+	cmd.Stdin = tty
+	return cmd.Run()
+}
+
+// encryptKey writes the key in X402_FACILITATOR_PRIVATE_KEY to path as an
+// AES-GCM key file for signer.key_file
+func encryptKey(path string) int {
+	keyStr := os.Getenv("X402_FACILITATOR_PRIVATE_KEY")
+	if keyStr == "" {
+		log.Printf("X402_FACILITATOR_PRIVATE_KEY environment variable required")
+		return 1
+	}
+	key, err := crypto.HexToECDSA(strings.TrimPrefix(keyStr, "0x"))
+	if err != nil {
+		log.Printf("Failed to parse private key: %v", err)
+		return 1
+	}
+
+	// Passphrase from the environment, or typed twice
+	passphrase := []byte(os.Getenv("X402_FACILITATOR_KEYSTORE_PASSPHRASE"))
+	if len(passphrase) == 0 {
+		passphrase, err = promptPassphrase(path)
+		if err != nil {
+			log.Printf("%v", err)
+			return 1
+		}
+		confirm, err := promptPassphrase(path + " (again)")
+		if err != nil {
+			log.Printf("%v", err)
+			return 1
+		}
+		match := bytes.Equal(passphrase, confirm)
+		clear(confirm)
+		if !match {
+			log.Printf("Passphrases do not match")
+			return 1
+		}
+	}
+	defer clear(passphrase)
+	if len(passphrase) == 0 {
+		log.Printf("Empty passphrase")
+		return 1
+	}
+
+	data, err := facilitator.EncryptKeyFile(key, passphrase)
+	if err != nil {
+		log.Printf("Failed to encrypt key: %v", err)
+		return 1
+	}
+	if err := os.WriteFile(path, data, 0600); err != nil {
+		log.Printf("Failed to write key file: %v", err)
+		return 1
+	}
+	log.Printf("Wrote key file for %s to %s", crypto.PubkeyToAddress(key.PublicKey).Hex(), path)
+	return 0
+}
@@ -2,26 +2,73 @@ package main
 
 import (
 	"context"
+	"encoding/json"
 	"flag"
+	"fmt"
 	"log"
+	"log/slog"
 	"os"
 	"os/signal"
 	"syscall"
+	"time"
 
 	"github.com/vorpalengineering/x402-go/facilitator"
+	"github.com/vorpalengineering/x402-go/utils"
+	"github.com/vorpalengineering/x402-go/version"
 )
 
 func main() {
 	// Parse command line flags
-	configPath := flag.String("config", "facilitator/config.yaml", "Path to config file")
+	configPath := flag.String("config", "facilitator/config.yaml", "Path to a YAML or JSON config file")
+	fromEnv := flag.Bool("env", false, "Load config from X402_* environment variables instead of a file")
+	replayDir := flag.String("replay", "", "Replay captured exchanges from this directory and exit")
+	exportAudit := flag.String("export-audit", "", "Verify the audit log, write it to stdout as json or csv and exit")
+	encryptKeyPath := flag.String("encrypt-key", "", "Encrypt X402_FACILITATOR_PRIVATE_KEY into this key file and exit")
+	printVersion := flag.Bool("version", false, "Print version information and exit")
 	flag.Parse()
 
+	// Print version without loading config
+	if *printVersion {
+		info := version.Get()
+		fmt.Printf("facilitator %s\ncommit: %s\nbuilt: %s\ngo: %s\nx402: v%d\n",
+			info.Version, info.Commit, info.BuildDate, info.GoVersion, info.X402Version)
+		return
+	}
+
+	// Write an encrypted key file without loading config
+	if *encryptKeyPath != "" {
+		os.Exit(encryptKey(*encryptKeyPath))
+	}
+
+	// Ask for key file and keystore passphrases missing from the environment
+	facilitator.PassphrasePrompt = promptPassphrase
+
 	// Load config
-	cfg, err := facilitator.LoadConfig(*configPath)
+	loadConfig := func() (*facilitator.FacilitatorConfig, error) {
+		if *fromEnv {
+			return facilitator.LoadConfigFromEnv()
+		}
+		return facilitator.LoadConfig(*configPath)
+	}
+	cfg, err := loadConfig()
 	if err != nil {
 		log.Fatalf("Failed to load config: %v", err)
 	}
 
+	// Replay captured traffic instead of serving
+	if *replayDir != "" {
+		os.Exit(replay(cfg, *replayDir))
+	}
+
+	// Export the audit log instead of serving
+	if *exportAudit != "" {
+		os.Exit(exportAuditLog(cfg, *exportAudit))
+	}
+
+	// Log through slog at the configured level, including libraries using log
+	logger := utils.NewLogger(cfg.Log.Level, cfg.Log.Format)
+	slog.SetDefault(logger)
+
 	// Create context that listens for shutdown signals
 	ctx, cancel := context.WithCancel(context.Background())
 	defer cancel()
@@ -32,7 +79,7 @@ func main() {
 
 	go func() {
 		sig := <-sigChan
-		log.Printf("Received signal: %v", sig)
+		logger.Info("received signal", "signal", sig.String())
 		cancel()
 	}()
 
@@ -40,7 +87,96 @@ func main() {
 	f := facilitator.NewFacilitator(cfg)
 	defer f.Close()
 
+	// Reload config on SIGHUP
+	reloadChan := make(chan os.Signal, 1)
+	signal.Notify(reloadChan, syscall.SIGHUP)
+
+	go func() {
+		for range reloadChan {
+			logger.Info("reloading config", "path", *configPath, "env", *fromEnv)
+			next, err := loadConfig()
+			if err != nil {
+				logger.Error("failed to reload config", "error", err)
+				continue
+			}
+			if err := f.Reload(next); err != nil {
+				logger.Error("failed to reload config", "error", err)
+			}
+		}
+	}()
+
 	if err := f.Run(ctx); err != nil {
-		log.Fatalf("Failed to run facilitator: %v", err)
+		logger.Error("failed to run facilitator", "error", err)
+		f.Close()
+		os.Exit(1)
+	}
+}
+
+func replay(cfg *facilitator.FacilitatorConfig, dir string) int {
+	exchanges, err := facilitator.LoadCapturedExchanges(dir)
+	if err != nil {
+		log.Printf("Failed to load captured exchanges: %v", err)
+		return 1
+	}
+
+	results, err := facilitator.Replay(cfg, exchanges)
+	if err != nil {
+		log.Printf("Failed to replay: %v", err)
+		return 1
+	}
+
+	// Report mismatches
+	mismatches := 0
+	for _, result := range results {
+		if result.Match {
+			continue
+		}
+		mismatches++
+		log.Printf("MISMATCH %s at %s", result.Exchange.Path, result.Exchange.Timestamp.Format(time.RFC3339))
+		log.Printf("  captured: %d %s", result.Exchange.Status, result.Exchange.Response)
+		log.Printf("  replayed: %d %s", result.Status, result.Response)
+	}
+	log.Printf("Replayed %d exchanges, %d mismatches", len(results), mismatches)
+
+	if mismatches > 0 {
+		return 1
+	}
+	return 0
+}
+
+func exportAuditLog(cfg *facilitator.FacilitatorConfig, format string) int {
+	if cfg.Audit.File == "" {
+		log.Printf("No audit file configured")
+		return 1
+	}
+	records, err := facilitator.ReadAuditLog(cfg.Audit.File)
+	if err != nil {
+		log.Printf("Failed to read audit log: %v", err)
+		return 1
+	}
+	if err := facilitator.VerifyAuditChain(records); err != nil {
+		log.Printf("Audit log does not verify: %v", err)
+		return 1
+	}
+
+	switch format {
+	case "json":
+		if records == nil {
+			records = []facilitator.AuditRecord{}
+		}
+		encoder := json.NewEncoder(os.Stdout)
+		encoder.SetIndent("", "  ")
+		err = encoder.Encode(records)
+	case "csv":
+		err = facilitator.WriteAuditCSV(os.Stdout, records)
+	default:
+		log.Printf("Unknown export format %q (must be json or csv)", format)
+		return 1
+	}
+	if err != nil {
+		log.Printf("Failed to export audit log: %v", err)
+		return 1
 	}
+	log.Printf("Exported %d audit records, chain verified", len(records))
+	return 0
 }
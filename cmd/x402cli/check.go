@@ -13,21 +13,30 @@ func checkCommand() {
 	// Define flags for check command
 	checkFlags := flag.NewFlagSet("check", flag.ExitOnError)
 	var url, output, method, data string
+	var headers stringList
 	checkFlags.StringVar(&url, "url", "", "URL of the resource to check (required)")
 	checkFlags.StringVar(&url, "u", "", "URL of the resource to check (required)")
 	checkFlags.StringVar(&output, "output", "", "File path to write JSON output")
 	checkFlags.StringVar(&output, "o", "", "File path to write JSON output")
-	checkFlags.StringVar(&method, "method", "GET", "HTTP method (GET or POST)")
-	checkFlags.StringVar(&method, "m", "GET", "HTTP method (GET or POST)")
-	checkFlags.StringVar(&data, "data", "", "Request body data")
-	checkFlags.StringVar(&data, "d", "", "Request body data")
+	checkFlags.StringVar(&method, "method", "GET", "HTTP method (GET, HEAD, POST, PUT, PATCH, DELETE, OPTIONS)")
+	checkFlags.StringVar(&method, "m", "GET", "HTTP method (GET, HEAD, POST, PUT, PATCH, DELETE, OPTIONS)")
+	checkFlags.StringVar(&data, "data", "", "Request body data (- reads stdin)")
+	checkFlags.StringVar(&data, "d", "", "Request body data (- reads stdin)")
+	checkFlags.Var(&headers, "header", "Request header as \"Name: value\" (repeatable)")
+	checkFlags.Var(&headers, "H", "Request header as \"Name: value\" (repeatable)")
 
 	// Parse flags
 	checkFlags.Parse(os.Args[2:])
 
 	// Validate method
-	if method != "GET" && method != "POST" {
-		fmt.Fprintf(os.Stderr, "Error: --method must be GET or POST, got %s\n", method)
+	method, err := parseMethod(method)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+	requestHeaders, err := parseHeaders(headers)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
 		os.Exit(1)
 	}
 
@@ -43,9 +52,10 @@ func checkCommand() {
 
 	// Create read-only client (no private key needed for checking)
 	rc := client.NewResourceClient(nil)
+	rc.SetRequestHeaders(requestHeaders)
 
 	// Check if payment is required
-	resp, paymentRequired, err := rc.Check(method, url, "", []byte(data))
+	resp, paymentRequired, err := rc.Check(method, url, "", readBody(data))
 	if err != nil {
 		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
 		os.Exit(1)
@@ -1,9 +1,14 @@
 package main
 
 import (
+	"encoding/json"
 	"fmt"
+	"io"
+	"net/http"
 	"os"
 	"strings"
+
+	"github.com/vorpalengineering/x402-go/types"
 )
 
 // readJSONOrFile returns JSON bytes from either an inline JSON string or a file path.
@@ -18,3 +23,111 @@ func readJSONOrFile(input string) []byte {
 	}
 	return data
 }
+
+// writeOutput writes data to the output file if set, otherwise to stdout.
+func writeOutput(output string, data []byte, label string) {
+	if output != "" {
+		if err := os.WriteFile(output, data, 0644); err != nil {
+			fmt.Fprintf(os.Stderr, "Error writing file: %v\n", err)
+			os.Exit(1)
+		}
+		fmt.Fprintf(os.Stderr, "%s written to %s\n", label, output)
+	} else {
+		fmt.Println(string(data))
+	}
+}
+
+// httpMethods are the request methods accepted by --method
+var httpMethods = []string{
+	http.MethodGet,
+	http.MethodHead,
+	http.MethodPost,
+	http.MethodPut,
+	http.MethodPatch,
+	http.MethodDelete,
+	http.MethodOptions,
+}
+
+// parseMethod returns the upper-cased method, or an error if it is not a standard HTTP method.
+func parseMethod(method string) (string, error) {
+	method = strings.ToUpper(method)
+	for _, m := range httpMethods {
+		if method == m {
+			return method, nil
+		}
+	}
+	return "", fmt.Errorf("--method must be one of %s, got %s", strings.Join(httpMethods, ", "), method)
+}
+
+// stringList collects the values of a repeatable flag.
+type stringList []string
+
+func (l *stringList) String() string {
+	return strings.Join(*l, ", ")
+}
+
+func (l *stringList) Set(value string) error {
+	*l = append(*l, value)
+	return nil
+}
+
+// parseHeader splits a "Name: value" request header flag.
+func parseHeader(input string) (string, string, bool) {
+	name, value, ok := strings.Cut(input, ":")
+	name = strings.TrimSpace(name)
+	if !ok || name == "" {
+		return "", "", false
+	}
+	return name, strings.TrimSpace(value), true
+}
+
+// parseHeaders builds request headers from "Name: value" flags, or returns an error on malformed input.
+func parseHeaders(inputs []string) (http.Header, error) {
+	headers := http.Header{}
+	for _, input := range inputs {
+		name, value, ok := parseHeader(input)
+		if !ok {
+			return nil, fmt.Errorf("--header must be \"Name: value\", got %q", input)
+		}
+		headers.Add(name, value)
+	}
+	return headers, nil
+}
+
+// readBody returns the request body from --data, reading stdin when it is "-".
+func readBody(data string) []byte {
+	if data != "-" {
+		return []byte(data)
+	}
+	body, err := io.ReadAll(os.Stdin)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error reading request body from stdin: %v\n", err)
+		os.Exit(1)
+	}
+	return body
+}
+
+// printJSON pretty-prints v to stdout
+func printJSON(v any) {
+	jsonBytes, err := json.MarshalIndent(v, "", "  ")
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error formatting response: %v\n", err)
+		os.Exit(1)
+	}
+	fmt.Println(string(jsonBytes))
+}
+
+// printErrorCode explains a verify or settle failure code on stderr
+func printErrorCode(code, message string) {
+	if code == "" {
+		return
+	}
+	if description := types.ErrorCodeDescription(code); description != "" {
+		fmt.Fprintf(os.Stderr, "%s: %s\n", code, description)
+	} else {
+		fmt.Fprintln(os.Stderr, code)
+	}
+	if message != "" {
+		fmt.Fprintf(os.Stderr, "  %s\n", message)
+	}
+}
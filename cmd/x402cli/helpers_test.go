@@ -0,0 +1,92 @@
+package main
+
+import (
+	"net/http"
+	"reflect"
+	"testing"
+)
+
+func TestParseMethod(t *testing.T) {
+	tests := []struct {
+		name     string
+		input    string
+		expected string
+		wantErr  bool
+	}{
+		{name: "upper case", input: "GET", expected: "GET"},
+		{name: "lower case", input: "put", expected: "PUT"},
+		{name: "mixed case", input: "Options", expected: "OPTIONS"},
+		{name: "unknown method", input: "FETCH", wantErr: true},
+		{name: "empty", input: "", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			method, err := parseMethod(tt.input)
+			if tt.wantErr {
+				if err == nil {
+					t.Errorf("Expected error for %q, got %s", tt.input, method)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Expected no error, got %v", err)
+			}
+			if method != tt.expected {
+				t.Errorf("Expected %s, got %s", tt.expected, method)
+			}
+		})
+	}
+}
+
+func TestParseHeaders(t *testing.T) {
+	tests := []struct {
+		name     string
+		inputs   []string
+		expected http.Header
+		wantErr  bool
+	}{
+		{name: "none", inputs: nil, expected: http.Header{}},
+		{
+			name:     "trims name and value",
+			inputs:   []string{" authorization :  Bearer abc "},
+			expected: http.Header{"Authorization": {"Bearer abc"}},
+		},
+		{
+			name:     "value containing colons",
+			inputs:   []string{"X-Url: https://example.com:8080"},
+			expected: http.Header{"X-Url": {"https://example.com:8080"}},
+		},
+		{
+			name:     "repeated header",
+			inputs:   []string{"Accept: text/plain", "accept: application/json"},
+			expected: http.Header{"Accept": {"text/plain", "application/json"}},
+		},
+		{
+			name:     "empty value",
+			inputs:   []string{"X-Empty:"},
+			expected: http.Header{"X-Empty": {""}},
+		},
+		{name: "missing colon", inputs: []string{"PAYMENT-SIGNATURE"}, wantErr: true},
+		{name: "missing name", inputs: []string{": value"}, wantErr: true},
+		{name: "one malformed", inputs: []string{"Accept: text/plain", "X-Api-Key"}, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			headers, err := parseHeaders(tt.inputs)
+			if tt.wantErr {
+				if err == nil {
+					t.Errorf("Expected error for %q, got %v", tt.inputs, headers)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Expected no error, got %v", err)
+			}
+			if !reflect.DeepEqual(headers, tt.expected) {
+				t.Errorf("Expected %v, got %v", tt.expected, headers)
+			}
+		})
+	}
+}
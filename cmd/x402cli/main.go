@@ -33,6 +33,8 @@ func main() {
 		requirementsCommand()
 	case "proof":
 		proofCommand()
+	case "version", "--version", "-v":
+		versionCommand()
 	default:
 		fmt.Fprintf(os.Stderr, "Unknown command: %s\n\n", subcommand)
 		printUsage()
@@ -56,6 +58,7 @@ func printUsage() {
 	fmt.Fprintln(os.Stderr, "  payload     Generate a payment payload with EIP-3009 authorization")
 	fmt.Fprintln(os.Stderr, "  req         Generate a payment requirements object")
 	fmt.Fprintln(os.Stderr, "  proof       Ownership proof commands (gen, verify)")
+	fmt.Fprintln(os.Stderr, "  version     Print the version of this build or of a facilitator")
 	fmt.Fprintln(os.Stderr, "")
 	fmt.Fprintln(os.Stderr, "Examples:")
 	fmt.Fprintln(os.Stderr, "  x402cli browse -u https://api.example.com")
@@ -67,4 +70,5 @@ func printUsage() {
 	fmt.Fprintln(os.Stderr, "  x402cli payload --to 0x... --value 10000 --private-key 0x...")
 	fmt.Fprintln(os.Stderr, "  x402cli req --scheme exact --network eip155:84532 --amount 10000")
 	fmt.Fprintln(os.Stderr, "  x402cli proof gen -u https://api.example.com --private-key 0x...")
+	fmt.Fprintln(os.Stderr, "  x402cli version -u http://localhost:8080")
 }
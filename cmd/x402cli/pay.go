@@ -13,16 +13,18 @@ import (
 
 	"github.com/vorpalengineering/x402-go/types"
 	"github.com/vorpalengineering/x402-go/utils"
+	"github.com/vorpalengineering/x402-go/version"
 )
 
 func payCommand() {
 	// Define flags
 	payFlags := flag.NewFlagSet("pay", flag.ExitOnError)
-	var url, method, payloadInput, requirementsInput, output, data string
+	var url, method, payloadInput, requirementsInput, output, data, headerName string
+	var headers stringList
 	payFlags.StringVar(&url, "url", "", "URL of the resource to pay for (required)")
 	payFlags.StringVar(&url, "u", "", "URL of the resource to pay for (required)")
-	payFlags.StringVar(&method, "method", "GET", "HTTP method (GET or POST)")
-	payFlags.StringVar(&method, "m", "GET", "HTTP method (GET or POST)")
+	payFlags.StringVar(&method, "method", "GET", "HTTP method (GET, HEAD, POST, PUT, PATCH, DELETE, OPTIONS)")
+	payFlags.StringVar(&method, "m", "GET", "HTTP method (GET, HEAD, POST, PUT, PATCH, DELETE, OPTIONS)")
 	payFlags.StringVar(&payloadInput, "payload", "", "Inner payload as JSON or file path (required)")
 	payFlags.StringVar(&payloadInput, "p", "", "Inner payload as JSON or file path (required)")
 	payFlags.StringVar(&requirementsInput, "requirements", "", "PaymentRequirements as JSON or file path (required)")
@@ -30,15 +32,31 @@ func payCommand() {
 	payFlags.StringVar(&requirementsInput, "r", "", "PaymentRequirements as JSON or file path (required)")
 	payFlags.StringVar(&output, "output", "", "File path to write response body")
 	payFlags.StringVar(&output, "o", "", "File path to write response body")
-	payFlags.StringVar(&data, "data", "", "Request body as JSON string or file path")
-	payFlags.StringVar(&data, "d", "", "Request body as JSON string or file path")
+	payFlags.StringVar(&data, "data", "", "Request body as JSON string or file path (- reads stdin)")
+	payFlags.StringVar(&data, "d", "", "Request body as JSON string or file path (- reads stdin)")
+	payFlags.Var(&headers, "header", "Request header as \"Name: value\" (repeatable)")
+	payFlags.Var(&headers, "H", "Request header as \"Name: value\" (repeatable)")
+	payFlags.StringVar(&headerName, "payment-header", utils.DefaultPaymentHeaderName, "Header to send the payment payload in")
 
 	// Parse flags
 	payFlags.Parse(os.Args[2:])
 
 	// Validate method
-	if method != "GET" && method != "POST" {
-		fmt.Fprintf(os.Stderr, "Error: --method must be GET or POST, got %s\n", method)
+	method, err := parseMethod(method)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+
+	// Validate headers
+	extraHeaders, err := parseHeaders(headers)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+	headerName = strings.TrimSpace(headerName)
+	if headerName == "" {
+		fmt.Fprintln(os.Stderr, "Error: --payment-header must not be empty")
 		os.Exit(1)
 	}
 
@@ -81,21 +99,26 @@ func payCommand() {
 		os.Exit(1)
 	}
 
-	// Make HTTP request with PAYMENT-SIGNATURE header
+	// Make HTTP request with payment header
 	var reqBody io.Reader
-	if data != "" {
-		dataBytes := readJSONOrFile(data)
-		reqBody = bytes.NewReader(dataBytes)
+	if data == "-" {
+		reqBody = bytes.NewReader(readBody(data))
+	} else if data != "" {
+		reqBody = bytes.NewReader(readJSONOrFile(data))
 	}
 	req, err := http.NewRequest(method, url, reqBody)
 	if err != nil {
 		fmt.Fprintf(os.Stderr, "Error creating request: %v\n", err)
 		os.Exit(1)
 	}
-	req.Header.Set("PAYMENT-SIGNATURE", paymentHeader)
+	req.Header.Set("User-Agent", version.UserAgent("x402cli"))
 	if data != "" {
 		req.Header.Set("Content-Type", "application/json")
 	}
+	for name, values := range extraHeaders {
+		req.Header[name] = values
+	}
+	req.Header.Set(headerName, paymentHeader)
 
 	resp, err := http.DefaultClient.Do(req)
 	if err != nil {
@@ -140,6 +163,13 @@ func payCommand() {
 	case resp.StatusCode == http.StatusPaymentRequired:
 		// Payment failed — print the PaymentRequired response
 		fmt.Fprintf(os.Stderr, "Payment required (402)\n")
+		// Hint if the server expects the payment in a different header
+		var paymentRequired types.PaymentRequired
+		if json.Unmarshal(body, &paymentRequired) == nil {
+			if expected := utils.GetPaymentHeaderName(&paymentRequired); expected != headerName {
+				fmt.Fprintf(os.Stderr, "Server expects payment in the %s header (use --payment-header %s)\n", expected, expected)
+			}
+		}
 		// Try to pretty-print if it's JSON
 		if strings.Contains(resp.Header.Get("Content-Type"), "json") {
 			var prettyJSON json.RawMessage
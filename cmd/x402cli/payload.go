@@ -13,8 +13,10 @@ import (
 	"time"
 
 	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/vorpalengineering/x402-go/resource/client"
 	"github.com/vorpalengineering/x402-go/types"
 	"github.com/vorpalengineering/x402-go/utils"
+	"github.com/vorpalengineering/x402-go/wallet"
 )
 
 const DEFAULT_PAYLOAD_VALID_DURATION = 600 // 10 minutes in seconds
@@ -26,9 +28,14 @@ func payloadCommand() {
 	var validAfter, validBefore, validDuration int64
 	var asset, domainName, domainVersion string
 	var chainID int64
+	var typedDataOnly bool
+	var mnemonic, hdPath string
+	var externalSignature string
 	payloadFlags.StringVar(&output, "output", "", "File path to write JSON output")
 	payloadFlags.StringVar(&output, "o", "", "File path to write JSON output")
 	payloadFlags.StringVar(&privateKeyHex, "private-key", "", "Hex-encoded private key for signing")
+	payloadFlags.StringVar(&mnemonic, "mnemonic", "", "BIP-39 mnemonic to derive the signing key from (alternative to --private-key)")
+	payloadFlags.StringVar(&hdPath, "hd-path", wallet.DefaultDerivationPath, "HD derivation path used with --mnemonic")
 	payloadFlags.StringVar(&from, "from", "", "Payer address (derived from private key if omitted)")
 	payloadFlags.StringVar(&to, "to", "", "Recipient address (required)")
 	payloadFlags.StringVar(&value, "value", "", "Amount in smallest unit (required)")
@@ -40,6 +47,8 @@ func payloadCommand() {
 	payloadFlags.StringVar(&domainName, "name", "", "EIP-712 domain name (required with --private-key)")
 	payloadFlags.StringVar(&domainVersion, "version", "", "EIP-712 domain version (required with --private-key)")
 	payloadFlags.Int64Var(&chainID, "chain-id", 0, "Chain ID (required with --private-key)")
+	payloadFlags.BoolVar(&typedDataOnly, "typed-data-only", false, "Output the EIP-712 typed data (eth_signTypedData_v4) instead of a payload")
+	payloadFlags.StringVar(&externalSignature, "signature", "", "Externally produced signature to use instead of signing locally (requires --from)")
 	var requirementsInput string
 	payloadFlags.StringVar(&requirementsInput, "requirements", "", "PaymentRequirements as JSON or file path")
 	payloadFlags.StringVar(&requirementsInput, "req", "", "PaymentRequirements as JSON or file path")
@@ -95,14 +104,36 @@ func payloadCommand() {
 		os.Exit(1)
 	}
 
+	// Validate mutually exclusive signing sources
+	signingSources := 0
+	for _, source := range []string{externalSignature, privateKeyHex, mnemonic} {
+		if source != "" {
+			signingSources++
+		}
+	}
+	if signingSources > 1 {
+		fmt.Fprintln(os.Stderr, "Error: --signature, --private-key and --mnemonic are mutually exclusive")
+		os.Exit(1)
+	}
+	if externalSignature != "" && from == "" {
+		fmt.Fprintln(os.Stderr, "Error: --from is required with --signature")
+		os.Exit(1)
+	}
+
 	// Resolve private key and from address
 	var privateKey *ecdsa.PrivateKey
-	if privateKeyHex != "" {
+	if privateKeyHex != "" || mnemonic != "" {
 		if asset == "" || domainName == "" || domainVersion == "" || chainID == 0 {
-			fmt.Fprintln(os.Stderr, "Error: --asset, --name, --version, and --chain-id are required when --private-key is provided")
+			fmt.Fprintln(os.Stderr, "Error: --asset, --name, --version, and --chain-id are required when --private-key or --mnemonic is provided")
 			os.Exit(1)
 		}
-		pk, err := crypto.HexToECDSA(strings.TrimPrefix(privateKeyHex, "0x"))
+		var pk *ecdsa.PrivateKey
+		var err error
+		if mnemonic != "" {
+			pk, err = wallet.DeriveKeyFromMnemonic(mnemonic, os.Getenv("X402_MNEMONIC_PASSPHRASE"), hdPath)
+		} else {
+			pk, err = crypto.HexToECDSA(strings.TrimPrefix(privateKeyHex, "0x"))
+		}
 		if err != nil {
 			fmt.Fprintf(os.Stderr, "Error parsing private key: %v\n", err)
 			os.Exit(1)
@@ -153,9 +184,45 @@ func payloadCommand() {
 		Nonce:       nonceHex,
 	}
 
+	// Output typed data for external signing
+	if typedDataOnly {
+		if from == "" || asset == "" || domainName == "" || domainVersion == "" || chainID == 0 {
+			fmt.Fprintln(os.Stderr, "Error: --from (or --private-key), --asset, --name, --version, and --chain-id are required with --typed-data-only")
+			os.Exit(1)
+		}
+		jsonBytes, err := typedDataJSON(&auth, asset, domainName, domainVersion, chainID)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+		writeOutput(output, jsonBytes, "Typed data")
+		fmt.Fprintf(os.Stderr, "Assemble the payload with: --from %s --nonce %s --valid-after %d --valid-before %d --signature <sig>\n",
+			from, nonceHex, validAfter, validBefore)
+		return
+	}
+
 	// Sign if private key is provided and from is set
 	signature := "0x" + strings.Repeat("00", 65)
-	if privateKey != nil && from != "" {
+	if externalSignature != "" {
+		// Check the signature recovers to the payer before emitting
+		if asset == "" || domainName == "" || domainVersion == "" || chainID == 0 {
+			fmt.Fprintln(os.Stderr, "Error: --asset, --name, --version, and --chain-id are required to validate --signature")
+			os.Exit(1)
+		}
+		_, err := client.BuildExactEVMPayload(&types.PaymentRequirements{
+			Network: fmt.Sprintf("eip155:%d", chainID),
+			Asset:   asset,
+			Extra: map[string]any{
+				"name":    domainName,
+				"version": domainVersion,
+			},
+		}, &auth, externalSignature)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+		signature = externalSignature
+	} else if privateKey != nil && from != "" {
 		sig, err := utils.SignEIP3009(&auth, privateKey, asset, domainName, domainVersion, chainID)
 		if err != nil {
 			fmt.Fprintf(os.Stderr, "Warning: signing failed: %v (using placeholder)\n", err)
@@ -178,13 +245,26 @@ func payloadCommand() {
 	}
 
 	// Output
-	if output != "" {
-		if err := os.WriteFile(output, jsonBytes, 0644); err != nil {
-			fmt.Fprintf(os.Stderr, "Error writing file: %v\n", err)
-			os.Exit(1)
-		}
-		fmt.Fprintf(os.Stderr, "Payload written to %s\n", output)
-	} else {
-		fmt.Println(string(jsonBytes))
+	writeOutput(output, jsonBytes, "Payload")
+}
+
+// typedDataJSON returns the eth_signTypedData_v4 JSON of auth for an external
+// wallet to sign
+func typedDataJSON(auth *types.ExactEVMSchemeAuthorization, asset, domainName, domainVersion string, chainID int64) ([]byte, error) {
+	typedData, err := utils.BuildEIP712TypedData(auth, &types.PaymentRequirements{
+		Network: fmt.Sprintf("eip155:%d", chainID),
+		Asset:   asset,
+		Extra: map[string]any{
+			"name":    domainName,
+			"version": domainVersion,
+		},
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to build typed data: %w", err)
+	}
+	jsonBytes, err := utils.EncodeTypedDataV4(typedData)
+	if err != nil {
+		return nil, fmt.Errorf("failed to format typed data: %w", err)
 	}
+	return jsonBytes, nil
 }
@@ -0,0 +1,45 @@
+package main
+
+import (
+	"encoding/hex"
+	"encoding/json"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/signer/core/apitypes"
+	"github.com/vorpalengineering/x402-go/types"
+)
+
+func TestTypedDataJSON(t *testing.T) {
+	auth := &types.ExactEVMSchemeAuthorization{
+		From:        "0xf39Fd6e51aad88F6F4ce6aB8827279cffFb92266",
+		To:          "0x3C44CdDdB6a900fa2b585dd299e03d12FA4293BC",
+		Value:       "1000000",
+		ValidAfter:  0,
+		ValidBefore: 1893456000,
+		Nonce:       "0x0000000000000000000000000000000000000000000000000000000000000001",
+	}
+
+	// The --typed-data-only output hashes to the transferWithAuthorization
+	// vector digest (see utils.TestEncodeTypedDataV4)
+	jsonBytes, err := typedDataJSON(auth, "0x036CbD53842c5426634e7929541eC2318f3dCF7e", "USDC", "2", 84532)
+	if err != nil {
+		t.Fatalf("Failed to build typed data: %v", err)
+	}
+	var typedData apitypes.TypedData
+	if err := json.Unmarshal(jsonBytes, &typedData); err != nil {
+		t.Fatalf("Expected eth_signTypedData_v4 JSON, got %v", err)
+	}
+	digest, _, err := apitypes.TypedDataAndHash(typedData)
+	if err != nil {
+		t.Fatalf("Failed to hash typed data: %v", err)
+	}
+	expected := "2b837f58b0f591f34f562dc31f86ed8407e0673354347de96f6e0d41781804b6"
+	if hex.EncodeToString(digest) != expected {
+		t.Errorf("Expected digest %s, got %x", expected, digest)
+	}
+
+	// A missing domain is an error
+	if _, err := typedDataJSON(auth, "0x036CbD53842c5426634e7929541eC2318f3dCF7e", "", "2", 84532); err == nil {
+		t.Error("Expected error without a domain name")
+	}
+}
@@ -5,11 +5,11 @@ import (
 	"flag"
 	"fmt"
 	"os"
-	"strconv"
 	"strings"
 
 	"github.com/ethereum/go-ethereum/common"
 	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/vorpalengineering/x402-go/utils"
 )
 
 func proofCommand() {
@@ -69,9 +69,7 @@ func proofGenCommand() {
 	}
 
 	// EIP-191 personal message hash: keccak256("\x19Ethereum Signed Message:\n" + len(msg) + msg)
-	msg := url
-	prefix := "\x19Ethereum Signed Message:\n" + strconv.Itoa(len(msg))
-	hash := crypto.Keccak256Hash([]byte(prefix + msg))
+	hash := utils.HashPersonalMessage(url)
 
 	// Sign
 	sig, err := crypto.Sign(hash.Bytes(), privateKey)
@@ -125,9 +123,7 @@ func proofVerifyCommand() {
 	}
 
 	// Compute message hash (EIP-191)
-	msg := url
-	prefix := "\x19Ethereum Signed Message:\n" + strconv.Itoa(len(msg))
-	hash := crypto.Keccak256Hash([]byte(prefix + msg))
+	hash := utils.HashPersonalMessage(url)
 
 	// Recover public key from signature
 	pubKey, err := crypto.SigToPub(hash.Bytes(), sig)
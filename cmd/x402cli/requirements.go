@@ -15,14 +15,17 @@ func requirementsCommand() {
 	reqFlags := flag.NewFlagSet("requirements", flag.ExitOnError)
 	var output, url, method, data, scheme, network, amount, asset, payTo, extraName, extraVersion string
 	var maxTimeout, index int
+	var headers stringList
 	reqFlags.StringVar(&output, "output", "", "File path to write JSON output")
 	reqFlags.StringVar(&output, "o", "", "File path to write JSON output")
 	reqFlags.StringVar(&url, "url", "", "URL of resource to fetch requirements from")
 	reqFlags.StringVar(&url, "u", "", "URL of resource to fetch requirements from")
 	reqFlags.StringVar(&method, "method", "GET", "HTTP method to use when fetching requirements")
 	reqFlags.StringVar(&method, "m", "GET", "HTTP method to use when fetching requirements")
-	reqFlags.StringVar(&data, "data", "", "Request body data")
-	reqFlags.StringVar(&data, "d", "", "Request body data")
+	reqFlags.StringVar(&data, "data", "", "Request body data (- reads stdin)")
+	reqFlags.StringVar(&data, "d", "", "Request body data (- reads stdin)")
+	reqFlags.Var(&headers, "header", "Request header as \"Name: value\" (repeatable)")
+	reqFlags.Var(&headers, "H", "Request header as \"Name: value\" (repeatable)")
 	reqFlags.IntVar(&index, "index", 0, "Index into accepts array (default: 0)")
 	reqFlags.IntVar(&index, "i", 0, "Index into accepts array (default: 0)")
 	reqFlags.StringVar(&scheme, "scheme", "", "Payment scheme (e.g. exact)")
@@ -42,8 +45,19 @@ func requirementsCommand() {
 
 	if url != "" {
 		// Fetch requirements from resource server
+		method, err := parseMethod(method)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+		requestHeaders, err := parseHeaders(headers)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
 		rc := client.NewResourceClient(nil)
-		fetched, err := rc.Requirements(method, url, "", []byte(data), index)
+		rc.SetRequestHeaders(requestHeaders)
+		fetched, err := rc.Requirements(method, url, "", readBody(data), index)
 		if err != nil {
 			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
 			os.Exit(1)
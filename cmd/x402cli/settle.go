@@ -5,6 +5,7 @@ import (
 	"flag"
 	"fmt"
 	"os"
+	"time"
 
 	facilitatorclient "github.com/vorpalengineering/x402-go/facilitator/client"
 	"github.com/vorpalengineering/x402-go/types"
@@ -13,7 +14,8 @@ import (
 func settleCommand() {
 	// Define flags for settle command
 	settleFlags := flag.NewFlagSet("settle", flag.ExitOnError)
-	var url, payloadInput, requirementsInput string
+	var url, payloadInput, requirementsInput, jobID string
+	var async bool
 	settleFlags.StringVar(&url, "url", "", "URL of the facilitator service (required)")
 	settleFlags.StringVar(&url, "u", "", "URL of the facilitator service (required)")
 	settleFlags.StringVar(&payloadInput, "payload", "", "Payload object as JSON string or file path (required)")
@@ -21,10 +23,26 @@ func settleCommand() {
 	settleFlags.StringVar(&requirementsInput, "requirements", "", "PaymentRequirements as JSON string or file path (required)")
 	settleFlags.StringVar(&requirementsInput, "req", "", "PaymentRequirements as JSON string or file path (required)")
 	settleFlags.StringVar(&requirementsInput, "r", "", "PaymentRequirements as JSON string or file path (required)")
+	settleFlags.BoolVar(&async, "async", false, "Queue the settlement and poll the job until it finishes")
+	settleFlags.StringVar(&jobID, "job", "", "Print the status of an asynchronous settlement job")
 
 	// Parse flags
 	settleFlags.Parse(os.Args[2:])
 
+	// Look up an existing job
+	if url != "" && jobID != "" {
+		job, err := facilitatorclient.NewFacilitatorClient(url).SettleJob(jobID)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+		printJSON(job)
+		if job.Response != nil && !job.Response.Success {
+			printErrorCode(job.Response.ErrorReason, job.Response.ErrorMessage)
+		}
+		return
+	}
+
 	// Validate required flags
 	if url == "" || payloadInput == "" || requirementsInput == "" {
 		fmt.Fprintln(os.Stderr, "Error: --url, --payload, and --requirement flags are all required")
@@ -60,19 +78,39 @@ func settleCommand() {
 		PaymentRequirements: requirements,
 	}
 
-	// Call facilitator /settle
 	fc := facilitatorclient.NewFacilitatorClient(url)
+
+	// Queue the settlement and poll until it finishes
+	if async {
+		job, err := fc.SettleAsync(&req)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+		fmt.Fprintf(os.Stderr, "Settlement job %s queued\n", job.ID)
+		for job.Status == types.SettleJobPending {
+			time.Sleep(time.Second)
+			job, err = fc.SettleJob(job.ID)
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+				os.Exit(1)
+			}
+		}
+		printJSON(job)
+		if job.Response != nil && !job.Response.Success {
+			printErrorCode(job.Response.ErrorReason, job.Response.ErrorMessage)
+		}
+		return
+	}
+
+	// Call facilitator /settle
 	resp, err := fc.Settle(&req)
 	if err != nil {
 		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
 		os.Exit(1)
 	}
-
-	// Pretty-print the response
-	jsonBytes, err := json.MarshalIndent(resp, "", "  ")
-	if err != nil {
-		fmt.Fprintf(os.Stderr, "Error formatting response: %v\n", err)
-		os.Exit(1)
+	printJSON(resp)
+	if !resp.Success {
+		printErrorCode(resp.ErrorReason, resp.ErrorMessage)
 	}
-	fmt.Println(string(jsonBytes))
 }
@@ -14,6 +14,7 @@ func verifyCommand() {
 	// Define flags for verify command
 	verifyFlags := flag.NewFlagSet("verify", flag.ExitOnError)
 	var url, payloadInput, requirementsInput string
+	var report bool
 	verifyFlags.StringVar(&url, "url", "", "URL of the facilitator service (required)")
 	verifyFlags.StringVar(&url, "u", "", "URL of the facilitator service (required)")
 	verifyFlags.StringVar(&payloadInput, "payload", "", "Payload object as JSON string or file path (required)")
@@ -21,6 +22,7 @@ func verifyCommand() {
 	verifyFlags.StringVar(&requirementsInput, "requirements", "", "PaymentRequirements as JSON string or file path (required)")
 	verifyFlags.StringVar(&requirementsInput, "req", "", "PaymentRequirements as JSON string or file path (required)")
 	verifyFlags.StringVar(&requirementsInput, "r", "", "PaymentRequirements as JSON string or file path (required)")
+	verifyFlags.BoolVar(&report, "report", false, "Report every failed check instead of only the first")
 
 	// Parse flags
 	verifyFlags.Parse(os.Args[2:])
@@ -62,7 +64,13 @@ func verifyCommand() {
 
 	// Call facilitator /verify
 	fc := facilitatorclient.NewFacilitatorClient(url)
-	resp, err := fc.Verify(&req)
+	var resp *types.VerifyResponse
+	var err error
+	if report {
+		resp, err = fc.VerifyReport(&req)
+	} else {
+		resp, err = fc.Verify(&req)
+	}
 	if err != nil {
 		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
 		os.Exit(1)
@@ -75,4 +83,7 @@ func verifyCommand() {
 		os.Exit(1)
 	}
 	fmt.Println(string(jsonBytes))
+	if !resp.IsValid {
+		printErrorCode(resp.InvalidReason, resp.InvalidMessage)
+	}
 }
@@ -0,0 +1,42 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+
+	facilitatorclient "github.com/vorpalengineering/x402-go/facilitator/client"
+	"github.com/vorpalengineering/x402-go/version"
+)
+
+func versionCommand() {
+	// Define flags for version command
+	versionFlags := flag.NewFlagSet("version", flag.ExitOnError)
+	var url string
+	versionFlags.StringVar(&url, "url", "", "URL of a facilitator to query instead of this build")
+	versionFlags.StringVar(&url, "u", "", "URL of a facilitator to query instead of this build")
+
+	// Parse flags
+	versionFlags.Parse(os.Args[2:])
+
+	// Report this build unless a facilitator is given
+	info := version.Get()
+	if url != "" {
+		fc := facilitatorclient.NewFacilitatorClient(url)
+		remote, err := fc.Version()
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+		info = *remote
+	}
+
+	// Pretty-print the version as JSON
+	jsonBytes, err := json.MarshalIndent(info, "", "  ")
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error formatting response: %v\n", err)
+		os.Exit(1)
+	}
+	fmt.Println(string(jsonBytes))
+}
@@ -0,0 +1,236 @@
+package facilitator
+
+import (
+	"context"
+	"crypto/subtle"
+	"fmt"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/vorpalengineering/x402-go/types"
+	"github.com/vorpalengineering/x402-go/utils"
+	"gopkg.in/yaml.v3"
+)
+
+// redactedValue replaces secrets in the config returned by /admin/config
+const redactedValue = "[redacted]"
+
+// RPCState is the health of a network's RPC client
+type RPCState struct {
+	Network   string             `json:"network"`
+	Connected bool               `json:"connected"`
+	Status    string             `json:"status"`
+	Error     string             `json:"error,omitempty"`
+	LatencyMs int64              `json:"latencyMs"`
+	Endpoints []RPCEndpointState `json:"endpoints,omitempty"`
+}
+
+// QueueState is the settlement work pending in the facilitator
+type QueueState struct {
+	// Asynchronous settlement jobs waiting for a worker, and the queue capacity
+	JobsQueued   int `json:"jobsQueued"`
+	JobsCapacity int `json:"jobsCapacity"`
+	// Asynchronous jobs not finished yet, including those being settled
+	JobsPending int `json:"jobsPending"`
+	// Settlements being sent or confirmed, synchronous or not
+	SettlementsInFlight int `json:"settlementsInFlight"`
+	// Payment streams still open
+	StreamsOpen int `json:"streamsOpen"`
+	// Deferred payments waiting for their batch
+	DeferredPending int `json:"deferredPending"`
+}
+
+// adminAuth requires the admin token as a bearer token
+func (f *Facilitator) adminAuth() gin.HandlerFunc {
+	return func(ginCtx *gin.Context) {
+		token, ok := strings.CutPrefix(ginCtx.GetHeader("Authorization"), "Bearer ")
+		expected := f.cfg().Admin.Token
+		if !ok || expected == "" || subtle.ConstantTimeCompare([]byte(token), []byte(expected)) != 1 {
+			ginCtx.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{
+				"error": "unauthorized",
+			})
+			return
+		}
+		ginCtx.Next()
+	}
+}
+
+func (f *Facilitator) registerAdminRoutes() {
+	admin := f.router.Group("/admin", f.adminAuth())
+	admin.GET("/signers", f.handleSigners)
+	admin.POST("/signers/rotate", f.handleRotateSigner)
+	admin.GET("/nonces", f.handleNonces)
+	admin.GET("/config", f.handleAdminConfig)
+	admin.GET("/rpc", f.handleAdminRPC)
+	admin.GET("/queue", f.handleAdminQueue)
+	admin.GET("/errors", f.handleAdminErrors)
+	if f.audit != nil {
+		admin.GET("/audit", f.handleAuditExport)
+	}
+	if f.deferred != nil {
+		admin.GET("/deferred", f.handleDeferredLedger)
+	}
+}
+
+func (f *Facilitator) handleNonces(ctx *gin.Context) {
+	ctx.JSON(http.StatusOK, f.nonces.snapshot())
+}
+
+func (f *Facilitator) handleAdminConfig(ctx *gin.Context) {
+	config, err := redactConfig(f.cfg())
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{
+			"error": err.Error(),
+		})
+		return
+	}
+	ctx.JSON(http.StatusOK, config)
+}
+
+func (f *Facilitator) handleAdminRPC(ctx *gin.Context) {
+	ctx.JSON(http.StatusOK, f.rpcStates(ctx.Request.Context()))
+}
+
+func (f *Facilitator) handleAdminQueue(ctx *gin.Context) {
+	var state QueueState
+	state.JobsQueued, state.JobsCapacity, state.JobsPending = f.jobs.depth()
+	state.SettlementsInFlight = f.settlements.inflightCount()
+	if f.streams != nil {
+		for _, stream := range f.streams.all() {
+			if stream.snapshot().Status == types.StreamOpen {
+				state.StreamsOpen++
+			}
+		}
+	}
+	if f.deferred != nil {
+		state.DeferredPending = f.deferred.pendingCount()
+	}
+	ctx.JSON(http.StatusOK, state)
+}
+
+func (f *Facilitator) handleAdminErrors(ctx *gin.Context) {
+	ctx.JSON(http.StatusOK, f.recentErrors.recent())
+}
+
+// rpcStates checks the RPC client of every EVM network, reporting the
+// failover state of each endpoint when the network has several
+func (f *Facilitator) rpcStates(ctx context.Context) []RPCState {
+	ctx, cancel := context.WithTimeout(ctx, healthCheckTimeout)
+	defer cancel()
+
+	var states []RPCState
+	for network := range f.cfg().Networks {
+		if utils.IsSolanaNetwork(network) {
+			continue
+		}
+		f.rpcClientsMu.RLock()
+		_, connected := f.rpcClients[network]
+		var endpoints []RPCEndpointState
+		if failover, ok := f.rpcFailovers[network]; ok {
+			endpoints = failover.snapshot()
+		}
+		f.rpcClientsMu.RUnlock()
+
+		start := time.Now()
+		state := RPCState{
+			Network:   network,
+			Connected: connected,
+			Status:    types.HealthStatusOK,
+			Endpoints: endpoints,
+		}
+		// Report networks that were never dialed rather than dialing them here
+		if !connected {
+			state.Status = types.HealthStatusUnavailable
+			state.Error = "RPC client not connected"
+		} else if err := f.checkRPC(ctx, network); err != nil {
+			state.Status = types.HealthStatusUnavailable
+			state.Error = err.Error()
+		}
+		state.LatencyMs = time.Since(start).Milliseconds()
+		states = append(states, state)
+	}
+	sort.Slice(states, func(i, j int) bool {
+		return states[i].Network < states[j].Network
+	})
+	return states
+}
+
+// redactConfig returns the config as it would be written in YAML, without
+// keys, RPC URL paths (which often carry API keys), alert destinations,
+// webhook secrets, Redis and NATS credentials or client API keys
+func redactConfig(config *FacilitatorConfig) (map[string]any, error) {
+	data, err := yaml.Marshal(config)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode config: %w", err)
+	}
+	var redacted map[string]any
+	if err := yaml.Unmarshal(data, &redacted); err != nil {
+		return nil, fmt.Errorf("failed to decode config: %w", err)
+	}
+
+	// Client quotas are keyed by API key, list them by name instead
+	if quotas, ok := redacted["quotas"].(map[string]any); ok {
+		if clients, ok := quotas["clients"].(map[string]any); ok {
+			named := make(map[string]any, len(clients))
+			i := 0
+			for _, client := range clients {
+				i++
+				name := fmt.Sprintf("client-%d", i)
+				if fields, ok := client.(map[string]any); ok && fields["name"] != "" && fields["name"] != nil {
+					name = fmt.Sprint(fields["name"])
+				}
+				named[name] = client
+			}
+			quotas["clients"] = named
+		}
+	}
+
+	redactValues(redacted)
+	return redacted, nil
+}
+
+// redactValues replaces secret fields found anywhere in a decoded config
+func redactValues(value any) {
+	switch v := value.(type) {
+	case map[string]any:
+		for key, field := range v {
+			if field == nil || field == "" {
+				continue
+			}
+			switch key {
+			case "rpc_url":
+				v[key] = redactURL(fmt.Sprint(field))
+			case "rpc_urls":
+				if urls, ok := field.([]any); ok {
+					for i, rpcURL := range urls {
+						urls[i] = redactURL(fmt.Sprint(rpcURL))
+					}
+				}
+			case "webhook_url", "routing_key", "url", "secrets", "password", "token":
+				v[key] = redactedValue
+			default:
+				redactValues(field)
+			}
+		}
+	case []any:
+		for _, item := range v {
+			redactValues(item)
+		}
+	}
+}
+
+// redactURL keeps only the scheme and host of a URL
+func redactURL(rawURL string) string {
+	parsed, err := url.Parse(rawURL)
+	if err != nil || parsed.Host == "" {
+		return redactedValue
+	}
+	if parsed.Path == "" && parsed.RawQuery == "" && parsed.User == nil {
+		return parsed.Scheme + "://" + parsed.Host
+	}
+	return parsed.Scheme + "://" + parsed.Host + "/" + redactedValue
+}
@@ -0,0 +1,113 @@
+package facilitator
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/vorpalengineering/x402-go/types"
+	"github.com/vorpalengineering/x402-go/webhook"
+)
+
+func TestAdminInspection(t *testing.T) {
+	rpcServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req struct {
+			ID     json.RawMessage `json:"id"`
+			Method string          `json:"method"`
+		}
+		json.NewDecoder(r.Body).Decode(&req)
+		result := "0x10"
+		if req.Method == "eth_chainId" {
+			result = "0x2105"
+		}
+		w.Header().Set("Content-Type", "application/json")
+		fmt.Fprintf(w, `{"jsonrpc":"2.0","id":%s,"result":"%s"}`, req.ID, result)
+	}))
+	defer rpcServer.Close()
+
+	f := NewFacilitator(&FacilitatorConfig{
+		Networks: map[string]NetworkConfig{
+			"eip155:8453": {
+				RpcUrl:  rpcServer.URL + "/v2/apikey",
+				RpcUrls: []string{rpcServer.URL + "/backup"},
+			},
+		},
+		Alerts: AlertsConfig{
+			Slack: []SlackAlertConfig{{WebhookURL: "https://hooks.slack.com/services/secret"}},
+			Webhooks: []WebhookAlertConfig{{
+				Endpoint: webhook.Endpoint{URL: "https://ops.example.com/hook", Secrets: []string{"whsec"}},
+			}},
+		},
+		Quotas: QuotasConfig{
+			Clients: map[string]ClientQuota{"client-api-key": {Name: "acme"}},
+		},
+		Events: EventsConfig{
+			NATS: NATSConfig{Addr: "127.0.0.1:1", Token: "nats-token"},
+		},
+		Log:   LogConfig{Level: "error"},
+		Admin: AdminConfig{Enabled: true, Token: "secret", RecentErrors: 2},
+	})
+	defer f.Close()
+
+	admin := func(path string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest("GET", path, nil)
+		req.Header.Set("Authorization", "Bearer secret")
+		recorder := httptest.NewRecorder()
+		f.router.ServeHTTP(recorder, req)
+		if recorder.Code != http.StatusOK {
+			t.Fatalf("Expected status 200 for %s, got %d: %s", path, recorder.Code, recorder.Body.String())
+		}
+		return recorder
+	}
+
+	// Secrets are left out of the config
+	body := admin("/admin/config").Body.String()
+	for _, secret := range []string{"apikey", "backup", "hooks.slack.com", "ops.example.com", "whsec", "client-api-key", "nats-token"} {
+		if strings.Contains(body, secret) {
+			t.Errorf("Expected %q to be redacted from config, got %s", secret, body)
+		}
+	}
+	if !strings.Contains(body, "acme") || !strings.Contains(body, strings.TrimPrefix(rpcServer.URL, "http://")) {
+		t.Errorf("Expected client names and RPC hosts in config, got %s", body)
+	}
+
+	// Networks that were never dialed are not reported healthy
+	var rpcStates []RPCState
+	json.Unmarshal(admin("/admin/rpc").Body.Bytes(), &rpcStates)
+	if len(rpcStates) != 1 || rpcStates[0].Connected || rpcStates[0].Status != types.HealthStatusUnavailable {
+		t.Errorf("Expected one unavailable network before dialing, got %+v", rpcStates)
+	}
+
+	// RPC clients report health and the state of each endpoint
+	if err := f.DialRPCClients(); err != nil {
+		t.Fatalf("Failed to dial RPC clients: %v", err)
+	}
+	json.Unmarshal(admin("/admin/rpc").Body.Bytes(), &rpcStates)
+	if len(rpcStates) != 1 || !rpcStates[0].Connected || rpcStates[0].Status != types.HealthStatusOK || len(rpcStates[0].Endpoints) != 2 {
+		t.Errorf("Expected one healthy network with two endpoints, got %+v", rpcStates)
+	}
+
+	// The queue is empty
+	var queue QueueState
+	json.Unmarshal(admin("/admin/queue").Body.Bytes(), &queue)
+	if queue.JobsQueued != 0 || queue.JobsCapacity != defaultSettleJobQueueSize || queue.SettlementsInFlight != 0 {
+		t.Errorf("Expected an empty queue, got %+v", queue)
+	}
+
+	// Warnings are kept even though the log level is error, newest first
+	f.logger.Warn("first", "network", "eip155:8453")
+	f.logger.With("network", "eip155:1").Warn("second")
+	f.logger.Error("third", "error", "boom")
+	f.logger.Info("not kept")
+	var recent []RecentError
+	json.Unmarshal(admin("/admin/errors").Body.Bytes(), &recent)
+	if len(recent) != 2 || recent[0].Message != "third" || recent[1].Message != "second" {
+		t.Fatalf("Expected the two latest errors, got %+v", recent)
+	}
+	if recent[0].Level != "ERROR" || recent[0].Attrs["error"] != "boom" || recent[1].Attrs["network"] != "eip155:1" {
+		t.Errorf("Expected levels and attributes to be kept, got %+v", recent)
+	}
+}
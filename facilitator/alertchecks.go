@@ -0,0 +1,119 @@
+package facilitator
+
+import (
+	"context"
+	"fmt"
+	"math/big"
+	"time"
+
+	"github.com/vorpalengineering/x402-go/utils"
+)
+
+// monitorAlerts periodically checks RPC health, signer balance and nonce gaps
+// on every configured network until ctx is cancelled
+func (f *Facilitator) monitorAlerts(ctx context.Context) {
+	interval := defaultAlertCheckInterval
+	if f.cfg().Alerts.CheckIntervalSeconds > 0 {
+		interval = time.Duration(f.cfg().Alerts.CheckIntervalSeconds) * time.Second
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			for network := range f.cfg().Networks {
+				f.checkNetworkAlerts(ctx, network)
+			}
+		}
+	}
+}
+
+// checkNetworkAlerts runs the periodic checks for one network and sends an
+// alert for each failing condition
+func (f *Facilitator) checkNetworkAlerts(ctx context.Context, network string) {
+	ctx, cancel := context.WithTimeout(ctx, alertDeliveryTimeout)
+	defer cancel()
+
+	for _, alert := range f.networkAlerts(ctx, network) {
+		f.alerts.send(ctx, alert, f.now())
+	}
+}
+
+func (f *Facilitator) networkAlerts(ctx context.Context, network string) []Alert {
+	// Only RPC health is checked on Solana networks
+	if utils.IsSolanaNetwork(network) {
+		if err := f.checkSVMRPC(ctx, network); err != nil {
+			return []Alert{{
+				Type:     AlertRPCDown,
+				Severity: AlertSeverityCritical,
+				Network:  network,
+				Message:  err.Error(),
+			}}
+		}
+		return nil
+	}
+
+	// RPC health
+	client, err := f.getRPCClient(network)
+	if err == nil {
+		_, err = client.BlockNumber(ctx)
+	}
+	if err != nil {
+		return []Alert{{
+			Type:     AlertRPCDown,
+			Severity: AlertSeverityCritical,
+			Network:  network,
+			Message:  fmt.Sprintf("RPC unavailable: %v", err),
+		}}
+	}
+
+	signer, ok := f.signers.address(network)
+	if !ok {
+		return nil
+	}
+	var alerts []Alert
+
+	// Signer balance
+	if minBalance, ok := f.cfg().Alerts.MinSignerBalance[network]; ok {
+		threshold, _ := new(big.Int).SetString(minBalance, 10)
+		balance, err := client.BalanceAt(ctx, signer, nil)
+		if err != nil {
+			f.logger.Warn("failed to check signer balance", "network", network, "error", err)
+		} else if threshold != nil && balance.Cmp(threshold) < 0 {
+			alerts = append(alerts, Alert{
+				Type:     AlertSignerBalanceLow,
+				Severity: AlertSeverityCritical,
+				Network:  network,
+				Message:  fmt.Sprintf("signer %s balance %s wei is below %s wei", signer.Hex(), balance.String(), minBalance),
+			})
+		}
+	}
+
+	// Pending transactions stuck behind a nonce gap
+	if f.cfg().Alerts.MaxNonceGap > 0 {
+		confirmed, err := client.NonceAt(ctx, signer, nil)
+		if err != nil {
+			f.logger.Warn("failed to check signer nonce", "network", network, "error", err)
+			return alerts
+		}
+		pending, err := client.PendingNonceAt(ctx, signer)
+		if err != nil {
+			f.logger.Warn("failed to check signer pending nonce", "network", network, "error", err)
+			return alerts
+		}
+		if pending > confirmed && pending-confirmed >= f.cfg().Alerts.MaxNonceGap {
+			alerts = append(alerts, Alert{
+				Type:     AlertNonceGap,
+				Severity: AlertSeverityWarning,
+				Network:  network,
+				Message:  fmt.Sprintf("signer %s has %d pending transactions (nonce %d, pending %d)", signer.Hex(), pending-confirmed, confirmed, pending),
+			})
+		}
+	}
+
+	return alerts
+}
@@ -0,0 +1,296 @@
+package facilitator
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/vorpalengineering/x402-go/version"
+	"github.com/vorpalengineering/x402-go/webhook"
+)
+
+// Alert severities, in increasing order
+const (
+	AlertSeverityInfo     = "info"
+	AlertSeverityWarning  = "warning"
+	AlertSeverityCritical = "critical"
+)
+
+// Alert types
+const (
+	AlertSignerBalanceLow  = "signer_balance_low"
+	AlertSettlementFailure = "settlement_failure_spike"
+	AlertRPCDown           = "rpc_down"
+	AlertNonceGap          = "nonce_gap"
+	AlertSettlementReorged = "settlement_reorged"
+)
+
+const (
+	defaultAlertMinInterval        = 5 * time.Minute
+	defaultAlertCheckInterval      = time.Minute
+	defaultSettlementFailureWindow = 5 * time.Minute
+	defaultPagerDutyEventsURL      = "https://events.pagerduty.com/v2/enqueue"
+	alertDeliveryTimeout           = 10 * time.Second
+	alertSource                    = "x402-facilitator"
+)
+
+var alertSeverityRank = map[string]int{
+	AlertSeverityInfo:     0,
+	AlertSeverityWarning:  1,
+	AlertSeverityCritical: 2,
+}
+
+// Alert is an operator notification for a critical condition
+type Alert struct {
+	Type      string `json:"type"`
+	Severity  string `json:"severity"`
+	Network   string `json:"network,omitempty"`
+	Message   string `json:"message"`
+	Timestamp int64  `json:"timestamp"`
+}
+
+// key identifies repeats of the same alert for rate limiting
+func (a Alert) key() string {
+	return a.Type + "|" + a.Network
+}
+
+type alertNotifier interface {
+	notify(ctx context.Context, alert Alert) error
+}
+
+type alertDestination struct {
+	name        string
+	url         string
+	minSeverity string
+	notifier    alertNotifier
+}
+
+// alerter delivers alerts to the configured destinations, rate limiting
+// repeats of the same alert, and tracks settlement failures for spike detection.
+type alerter struct {
+	destinations     []alertDestination
+	minInterval      time.Duration
+	failureThreshold int
+	failureWindow    time.Duration
+
+	// onWebhookDelivery is called after each generic webhook delivery
+	onWebhookDelivery func(url string, alert Alert, err error)
+
+	logger *slog.Logger
+
+	mu       sync.Mutex
+	lastSent map[string]time.Time
+	failures []time.Time
+}
+
+func newAlerter(cfg AlertsConfig) *alerter {
+	httpClient := &http.Client{Timeout: alertDeliveryTimeout, Transport: version.NewTransport("x402-facilitator")}
+
+	a := &alerter{
+		minInterval:      defaultAlertMinInterval,
+		failureThreshold: cfg.SettlementFailureThreshold,
+		failureWindow:    defaultSettlementFailureWindow,
+		lastSent:         make(map[string]time.Time),
+		logger:           slog.Default(),
+	}
+	if cfg.MinIntervalSeconds > 0 {
+		a.minInterval = time.Duration(cfg.MinIntervalSeconds) * time.Second
+	}
+	if cfg.SettlementFailureWindowSeconds > 0 {
+		a.failureWindow = time.Duration(cfg.SettlementFailureWindowSeconds) * time.Second
+	}
+
+	for _, slack := range cfg.Slack {
+		a.destinations = append(a.destinations, alertDestination{
+			name:        "slack",
+			minSeverity: slack.MinSeverity,
+			notifier:    &slackNotifier{webhookURL: slack.WebhookURL, httpClient: httpClient},
+		})
+	}
+	for _, pd := range cfg.PagerDuty {
+		eventsURL := pd.URL
+		if eventsURL == "" {
+			eventsURL = defaultPagerDutyEventsURL
+		}
+		a.destinations = append(a.destinations, alertDestination{
+			name:        "pagerduty",
+			minSeverity: pd.MinSeverity,
+			notifier:    &pagerDutyNotifier{eventsURL: eventsURL, routingKey: pd.RoutingKey, httpClient: httpClient},
+		})
+	}
+	for _, wh := range cfg.Webhooks {
+		a.destinations = append(a.destinations, alertDestination{
+			name:        "webhook",
+			url:         wh.URL,
+			minSeverity: wh.MinSeverity,
+			notifier:    &webhookNotifier{endpoint: wh.Endpoint, httpClient: httpClient},
+		})
+	}
+
+	return a
+}
+
+// send delivers alert to every destination whose minimum severity it meets,
+// unless the same alert was sent within the rate limit interval.
+// Returns false if the alert was rate limited.
+func (a *alerter) send(ctx context.Context, alert Alert, now time.Time) bool {
+	// Rate limit repeats
+	a.mu.Lock()
+	if last, ok := a.lastSent[alert.key()]; ok && now.Sub(last) < a.minInterval {
+		a.mu.Unlock()
+		return false
+	}
+	a.lastSent[alert.key()] = now
+	a.mu.Unlock()
+
+	alert.Timestamp = now.Unix()
+	a.logger.Warn("alert", "severity", alert.Severity, "type", alert.Type, "network", alert.Network, "message", alert.Message)
+
+	for _, dest := range a.destinations {
+		if alertSeverityRank[alert.Severity] < alertSeverityRank[dest.minSeverity] {
+			continue
+		}
+		deliveryCtx, cancel := context.WithTimeout(ctx, alertDeliveryTimeout)
+		err := dest.notifier.notify(deliveryCtx, alert)
+		cancel()
+		if err != nil {
+			a.logger.Error("failed to deliver alert", "destination", dest.name, "type", alert.Type, "error", err)
+		}
+		if dest.name == "webhook" && a.onWebhookDelivery != nil {
+			a.onWebhookDelivery(dest.url, alert, err)
+		}
+	}
+
+	return true
+}
+
+// recordSettlement tracks settlement outcomes and returns a spike alert when
+// failures within the window reach the threshold
+func (a *alerter) recordSettlement(success bool, now time.Time) (Alert, bool) {
+	if success || a.failureThreshold <= 0 {
+		return Alert{}, false
+	}
+
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	// Keep failures within the window
+	cutoff := now.Add(-a.failureWindow)
+	kept := a.failures[:0]
+	for _, ts := range a.failures {
+		if ts.After(cutoff) {
+			kept = append(kept, ts)
+		}
+	}
+	a.failures = append(kept, now)
+
+	if len(a.failures) < a.failureThreshold {
+		return Alert{}, false
+	}
+	return Alert{
+		Type:     AlertSettlementFailure,
+		Severity: AlertSeverityCritical,
+		Message:  fmt.Sprintf("%d settlement failures in the last %s", len(a.failures), a.failureWindow),
+	}, true
+}
+
+// postJSON sends body as JSON and treats any non-2xx status as an error
+func postJSON(ctx context.Context, httpClient *http.Client, req *http.Request) error {
+	resp, err := httpClient.Do(req.WithContext(ctx))
+	if err != nil {
+		return fmt.Errorf("failed to send request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("unexpected status code: %d", resp.StatusCode)
+	}
+	return nil
+}
+
+func newJSONRequest(ctx context.Context, url string, payload any) (*http.Request, error) {
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal alert: %w", err)
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(body))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	return req, nil
+}
+
+// slackNotifier posts to a Slack incoming webhook
+type slackNotifier struct {
+	webhookURL string
+	httpClient *http.Client
+}
+
+func (n *slackNotifier) notify(ctx context.Context, alert Alert) error {
+	text := fmt.Sprintf("[%s] %s: %s", strings.ToUpper(alert.Severity), alert.Type, alert.Message)
+	if alert.Network != "" {
+		text = fmt.Sprintf("[%s] %s (%s): %s", strings.ToUpper(alert.Severity), alert.Type, alert.Network, alert.Message)
+	}
+
+	req, err := newJSONRequest(ctx, n.webhookURL, map[string]string{"text": text})
+	if err != nil {
+		return err
+	}
+	return postJSON(ctx, n.httpClient, req)
+}
+
+// pagerDutyNotifier triggers incidents through the PagerDuty Events API v2.
+// Repeats of the same alert share a dedup key.
+type pagerDutyNotifier struct {
+	eventsURL  string
+	routingKey string
+	httpClient *http.Client
+}
+
+func (n *pagerDutyNotifier) notify(ctx context.Context, alert Alert) error {
+	event := map[string]any{
+		"routing_key":  n.routingKey,
+		"event_action": "trigger",
+		"dedup_key":    alertSource + "|" + alert.key(),
+		"payload": map[string]any{
+			"summary":        alert.Message,
+			"source":         alertSource,
+			"severity":       alert.Severity,
+			"timestamp":      time.Unix(alert.Timestamp, 0).UTC().Format(time.RFC3339),
+			"component":      alert.Network,
+			"class":          alert.Type,
+			"custom_details": alert,
+		},
+	}
+
+	req, err := newJSONRequest(ctx, n.eventsURL, event)
+	if err != nil {
+		return err
+	}
+	return postJSON(ctx, n.httpClient, req)
+}
+
+// webhookNotifier posts the alert as JSON, signed like other outbound webhooks
+type webhookNotifier struct {
+	endpoint   webhook.Endpoint
+	httpClient *http.Client
+}
+
+func (n *webhookNotifier) notify(ctx context.Context, alert Alert) error {
+	body, err := json.Marshal(alert)
+	if err != nil {
+		return fmt.Errorf("failed to marshal alert: %w", err)
+	}
+	req, err := webhook.NewRequest(ctx, n.endpoint, body)
+	if err != nil {
+		return err
+	}
+	return postJSON(ctx, n.httpClient, req)
+}
@@ -0,0 +1,94 @@
+package facilitator
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/vorpalengineering/x402-go/webhook"
+)
+
+func TestAlerterDelivery(t *testing.T) {
+	// Record deliveries per destination
+	var mu sync.Mutex
+	received := make(map[string]int)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/hook" {
+			if _, err := webhook.VerifyRequest(r, []string{"secret"}, webhook.DefaultTolerance); err != nil {
+				t.Errorf("Expected signed webhook delivery, got %v", err)
+			}
+		}
+		mu.Lock()
+		received[r.URL.Path]++
+		mu.Unlock()
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	a := newAlerter(AlertsConfig{
+		Slack: []SlackAlertConfig{
+			{WebhookURL: server.URL + "/slack", MinSeverity: AlertSeverityCritical},
+		},
+		PagerDuty: []PagerDutyAlertConfig{
+			{RoutingKey: "key", URL: server.URL + "/pagerduty"},
+		},
+		Webhooks: []WebhookAlertConfig{
+			{Endpoint: webhook.Endpoint{URL: server.URL + "/hook", Secrets: []string{"secret"}}, MinSeverity: AlertSeverityWarning},
+		},
+	})
+	now := time.Now()
+
+	// Warning skips slack
+	warning := Alert{Type: AlertNonceGap, Severity: AlertSeverityWarning, Network: "eip155:8453", Message: "stuck"}
+	if !a.send(context.Background(), warning, now) {
+		t.Fatalf("Expected first alert to be sent")
+	}
+
+	// Same alert is rate limited
+	if a.send(context.Background(), warning, now.Add(time.Minute)) {
+		t.Errorf("Expected repeated alert to be rate limited")
+	}
+
+	// Critical goes everywhere
+	critical := Alert{Type: AlertRPCDown, Severity: AlertSeverityCritical, Network: "eip155:8453", Message: "down"}
+	a.send(context.Background(), critical, now)
+
+	if received["/slack"] != 1 {
+		t.Errorf("Expected 1 slack delivery, got %d", received["/slack"])
+	}
+	if received["/pagerduty"] != 2 {
+		t.Errorf("Expected 2 pagerduty deliveries, got %d", received["/pagerduty"])
+	}
+	if received["/hook"] != 2 {
+		t.Errorf("Expected 2 webhook deliveries, got %d", received["/hook"])
+	}
+}
+
+func TestAlerterSettlementFailureSpike(t *testing.T) {
+	a := newAlerter(AlertsConfig{
+		SettlementFailureThreshold:     3,
+		SettlementFailureWindowSeconds: 60,
+	})
+	now := time.Now()
+
+	// Two failures, then one outside the window
+	a.recordSettlement(false, now)
+	a.recordSettlement(false, now.Add(10*time.Second))
+	if _, spike := a.recordSettlement(false, now.Add(2*time.Minute)); spike {
+		t.Errorf("Expected old failures to fall out of the window")
+	}
+
+	// Successes are not counted
+	a.recordSettlement(true, now.Add(2*time.Minute))
+	a.recordSettlement(false, now.Add(2*time.Minute+time.Second))
+	alert, spike := a.recordSettlement(false, now.Add(2*time.Minute+2*time.Second))
+	if !spike {
+		t.Fatalf("Expected spike after 3 failures within the window")
+	}
+	if alert.Type != AlertSettlementFailure || alert.Severity != AlertSeverityCritical {
+		t.Errorf("Expected critical settlement failure alert, got %+v", alert)
+	}
+}
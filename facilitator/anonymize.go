@@ -0,0 +1,292 @@
+package facilitator
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"encoding/hex"
+	"encoding/json"
+	"sort"
+	"strings"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/vorpalengineering/x402-go/types"
+	"github.com/vorpalengineering/x402-go/utils"
+)
+
+// captureAnonymizer replaces the payer and payee addresses, nonces,
+// signatures and transaction hashes of a captured exchange with pseudonyms.
+// Pseudonyms are derived from a salt, so a value gets the same pseudonym in
+// the request, the response and the RPC calls of every exchange captured
+// with that salt. Addresses are replaced by the address of a key derived
+// from them, and exact-scheme authorizations are re-signed with those keys
+// so that anonymized captures verify the same way on replay.
+type captureAnonymizer struct {
+	salt         []byte
+	replacements map[string]string
+}
+
+func newCaptureAnonymizer(salt []byte) *captureAnonymizer {
+	return &captureAnonymizer{
+		salt:         salt,
+		replacements: make(map[string]string),
+	}
+}
+
+// anonymizeExchange rewrites exchange with pseudonyms derived from salt
+func (f *Facilitator) anonymizeExchange(ctx context.Context, salt []byte, exchange *CapturedExchange) {
+	a := newCaptureAnonymizer(salt)
+
+	// Collect the values to replace from the request and response
+	var request, response any
+	if err := json.Unmarshal(exchange.Request, &request); err == nil {
+		a.request(ctx, f, request)
+	}
+	if err := json.Unmarshal(exchange.Response, &response); err == nil {
+		a.result(response)
+	}
+
+	// Replace them wherever they appear, including ABI encoded RPC calls
+	exchange.Request = a.apply(exchange.Request)
+	exchange.Response = a.apply(exchange.Response)
+	calls := make([]CapturedRPCCall, len(exchange.RPCCalls))
+	for i, call := range exchange.RPCCalls {
+		calls[i] = CapturedRPCCall{
+			Network:  call.Network,
+			Request:  a.apply(call.Request),
+			Response: a.apply(call.Response),
+		}
+	}
+	exchange.RPCCalls = calls
+}
+
+// request finds the payments of a verify, settle or batch request
+func (a *captureAnonymizer) request(ctx context.Context, f *Facilitator, value any) {
+	switch value := value.(type) {
+	case map[string]any:
+		if _, ok := value["paymentPayload"]; ok {
+			var req types.VerifyRequest
+			data, err := json.Marshal(value)
+			if err == nil && json.Unmarshal(data, &req) == nil {
+				a.payment(ctx, f, &req.PaymentPayload, &req.PaymentRequirements)
+			}
+			return
+		}
+		for _, v := range value {
+			a.request(ctx, f, v)
+		}
+	case []any:
+		for _, v := range value {
+			a.request(ctx, f, v)
+		}
+	}
+}
+
+// payment registers pseudonyms for a payment's payee, and for the payer,
+// nonce and signature of its payload
+func (a *captureAnonymizer) payment(ctx context.Context, f *Facilitator, payload *types.PaymentPayload, requirements *types.PaymentRequirements) {
+	a.opaque(requirements.PayTo)
+	a.opaque(payload.Accepted.PayTo)
+	if requirements.Scheme == "exact" && !utils.IsSolanaNetwork(requirements.Network) && a.authorization(ctx, f, payload, requirements) {
+		return
+	}
+	a.opaque(payload.Payload)
+}
+
+// authorization registers pseudonyms for an EIP-3009 authorization and
+// re-signs it, returning false if it cannot be read
+func (a *captureAnonymizer) authorization(ctx context.Context, f *Facilitator, payload *types.PaymentPayload, requirements *types.PaymentRequirements) bool {
+	auth, err := utils.ExtractExactAuthorization(payload)
+	if err != nil || !common.IsHexAddress(auth.From) || !common.IsHexAddress(auth.To) {
+		return false
+	}
+	nonce, err := hexutil.Decode(auth.Nonce)
+	if err != nil {
+		return false
+	}
+	domain := f.withTokenDomain(ctx, requirements)
+	hash, signature, reason := authorizationHash(auth, payload, domain)
+	if reason != "" {
+		return false
+	}
+
+	// Hash the authorization with its fields replaced
+	pseudonymous := *auth
+	pseudonymous.From = a.address(auth.From).Hex()
+	pseudonymous.To = a.address(auth.To).Hex()
+	pseudonymous.Nonce = hexutil.Encode(a.replace(nonce))
+	pseudonymousHash, _, reason := authorizationHash(&pseudonymous, payload, domain)
+	if reason != "" {
+		return false
+	}
+	a.register(hash.Bytes(), pseudonymousHash.Bytes())
+
+	// Signatures that cannot be recovered are only replaced, keeping v
+	signer, err := utils.RecoverAddress(hash.Bytes(), signature)
+	if err != nil {
+		replaced := a.pseudonym(signature, len(signature))
+		if len(signature) == 65 {
+			replaced[64] = signature[64]
+		}
+		a.register(signature, replaced)
+		return true
+	}
+
+	// Re-sign with the key standing in for the signer, so a signature valid
+	// for the payer is valid for its pseudonym and an invalid one recovers
+	// to the pseudonym of the address it did
+	resigned, err := crypto.Sign(pseudonymousHash.Bytes(), a.key(signer))
+	if err != nil {
+		return false
+	}
+	resigned[64] += 27
+	a.address(signer.Hex())
+	a.register(signature, resigned)
+
+	// The v, r, s overload encodes v as a word ahead of r and s
+	normalized, _ := utils.NormalizeSignature(signature)
+	a.register(vrsWords(normalized), vrsWords(resigned))
+	return true
+}
+
+// result registers pseudonyms for the payer and transaction of a response
+func (a *captureAnonymizer) result(value any) {
+	switch value := value.(type) {
+	case map[string]any:
+		for key, v := range value {
+			if key == "payer" || key == "transaction" {
+				a.opaque(v)
+				continue
+			}
+			a.result(v)
+		}
+	case []any:
+		for _, v := range value {
+			a.result(v)
+		}
+	}
+}
+
+// opaque registers pseudonyms for every identifying value of a payload it
+// cannot otherwise read. Hex values keep their length, other long strings
+// such as serialized transactions are redacted.
+func (a *captureAnonymizer) opaque(value any) {
+	switch value := value.(type) {
+	case map[string]any:
+		for _, v := range value {
+			a.opaque(v)
+		}
+	case []any:
+		for _, v := range value {
+			a.opaque(v)
+		}
+	case string:
+		decoded, err := hexutil.Decode(value)
+		switch {
+		case err == nil && len(decoded) == common.AddressLength:
+			a.address(value)
+		case err == nil && len(decoded) > common.AddressLength:
+			a.replace(decoded)
+		case err != nil && len(value) >= 32:
+			a.replacements[value] = "redacted-" + hex.EncodeToString(a.pseudonym([]byte(value), 16))
+		}
+	}
+}
+
+// address registers and returns the pseudonym of an address, the address
+// of the key standing in for it
+func (a *captureAnonymizer) address(text string) common.Address {
+	addr := common.HexToAddress(text)
+	if !identifying(addr.Bytes()) {
+		return addr
+	}
+	pseudonym := crypto.PubkeyToAddress(a.key(addr).PublicKey)
+	a.register(addr.Bytes(), pseudonym.Bytes())
+	a.replacements[strings.TrimPrefix(text, "0x")] = pseudonym.Hex()[2:]
+	return pseudonym
+}
+
+// replace registers and returns the pseudonym of a hex value
+func (a *captureAnonymizer) replace(value []byte) []byte {
+	if !identifying(value) {
+		return value
+	}
+	pseudonym := a.pseudonym(value, len(value))
+	a.register(value, pseudonym)
+	return pseudonym
+}
+
+// register replaces old with pseudonym wherever it appears as hex, in
+// lower, upper or checksummed case
+func (a *captureAnonymizer) register(old, pseudonym []byte) {
+	oldHex, pseudonymHex := hex.EncodeToString(old), hex.EncodeToString(pseudonym)
+	a.replacements[oldHex] = pseudonymHex
+	a.replacements[strings.ToUpper(oldHex)] = strings.ToUpper(pseudonymHex)
+	if len(old) == common.AddressLength {
+		a.replacements[common.BytesToAddress(old).Hex()[2:]] = common.BytesToAddress(pseudonym).Hex()[2:]
+	}
+}
+
+// pseudonym returns n bytes derived from value and the salt
+func (a *captureAnonymizer) pseudonym(value []byte, n int) []byte {
+	out := make([]byte, 0, n+32)
+	digest := crypto.Keccak256(a.salt, value)
+	for len(out) < n {
+		out = append(out, digest...)
+		digest = crypto.Keccak256(a.salt, digest)
+	}
+	return out[:n]
+}
+
+// key returns the private key standing in for addr
+func (a *captureAnonymizer) key(addr common.Address) *ecdsa.PrivateKey {
+	seed := addr.Bytes()
+	for {
+		key, err := crypto.ToECDSA(a.pseudonym(seed, 32))
+		if err == nil {
+			return key
+		}
+		seed = crypto.Keccak256(seed)
+	}
+}
+
+// apply replaces every registered value in data, longest first
+func (a *captureAnonymizer) apply(data []byte) []byte {
+	if len(a.replacements) == 0 || len(data) == 0 {
+		return data
+	}
+	olds := make([]string, 0, len(a.replacements))
+	for old := range a.replacements {
+		olds = append(olds, old)
+	}
+	sort.Slice(olds, func(i, j int) bool {
+		if len(olds[i]) != len(olds[j]) {
+			return len(olds[i]) > len(olds[j])
+		}
+		return olds[i] < olds[j]
+	})
+	pairs := make([]string, 0, 2*len(olds))
+	for _, old := range olds {
+		pairs = append(pairs, old, a.replacements[old])
+	}
+	return []byte(strings.NewReplacer(pairs...).Replace(string(data)))
+}
+
+// identifying reports whether a value is worth replacing. Mostly zero
+// values such as the zero address or a zero nonce identify nobody, and
+// replacing them would corrupt the padding of ABI encoded calls.
+func identifying(value []byte) bool {
+	nonzero := 0
+	for _, b := range value {
+		if b != 0 {
+			nonzero++
+		}
+	}
+	return nonzero*2 >= len(value)
+}
+
+// vrsWords encodes a 65 byte signature as the v, r and s words of an ABI call
+func vrsWords(signature []byte) []byte {
+	return append(common.LeftPadBytes(signature[64:], 32), signature[:64]...)
+}
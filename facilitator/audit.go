@@ -0,0 +1,281 @@
+package facilitator
+
+import (
+	"bufio"
+	"crypto/sha256"
+	"encoding/csv"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/gin-gonic/gin"
+)
+
+// auditGenesisHash is the previous hash of the first audit record
+var auditGenesisHash = strings.Repeat("0", 64)
+
+// AuditRecord is one verify, settle or refund decision in the audit log. Hash
+// is the SHA-256 of the record encoded with an empty Hash, and PrevHash the
+// hash of the record before it, so editing or dropping a record breaks the
+// chain. Settlement links a refund to the settlement it reverses.
+type AuditRecord struct {
+	Seq         uint64 `json:"seq"`
+	Timestamp   int64  `json:"timestamp"`
+	Type        string `json:"type"`
+	RequestID   string `json:"requestId,omitempty"`
+	Scheme      string `json:"scheme"`
+	Network     string `json:"network"`
+	Asset       string `json:"asset,omitempty"`
+	PayTo       string `json:"payTo,omitempty"`
+	Payer       string `json:"payer,omitempty"`
+	Amount      string `json:"amount,omitempty"`
+	Success     bool   `json:"success"`
+	Code        string `json:"code,omitempty"`
+	Reason      string `json:"reason,omitempty"`
+	Transaction string `json:"transaction,omitempty"`
+	Settlement  string `json:"settlement,omitempty"`
+	PrevHash    string `json:"prevHash"`
+	Hash        string `json:"hash"`
+}
+
+// hash returns the hash of the record's content and PrevHash
+func (r AuditRecord) hash() string {
+	r.Hash = ""
+	data, _ := json.Marshal(r)
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+// auditLog appends hash-chained records to a JSON lines file
+type auditLog struct {
+	path     string
+	mu       sync.Mutex
+	file     *os.File
+	seq      uint64
+	lastHash string
+}
+
+// openAuditLog opens the audit log for appending, continuing the chain of the
+// records already in it. A log whose chain doesn't verify is not opened.
+func openAuditLog(path string) (*auditLog, error) {
+	records, err := ReadAuditLog(path)
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return nil, err
+	}
+	if err := VerifyAuditChain(records); err != nil {
+		return nil, fmt.Errorf("audit log %s: %w", path, err)
+	}
+
+	file, err := os.OpenFile(path, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0o600)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open audit log: %w", err)
+	}
+	l := &auditLog{path: path, file: file, lastHash: auditGenesisHash}
+	if len(records) > 0 {
+		last := records[len(records)-1]
+		l.seq = last.Seq
+		l.lastHash = last.Hash
+	}
+	return l, nil
+}
+
+// append chains a record to the log and writes it
+func (l *auditLog) append(record AuditRecord) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	record.Seq = l.seq + 1
+	record.PrevHash = l.lastHash
+	record.Hash = record.hash()
+	data, err := json.Marshal(record)
+	if err != nil {
+		return fmt.Errorf("failed to encode audit record: %w", err)
+	}
+	if _, err := l.file.Write(append(data, '\n')); err != nil {
+		return fmt.Errorf("failed to write audit record: %w", err)
+	}
+	l.seq = record.Seq
+	l.lastHash = record.Hash
+	return nil
+}
+
+// records reads the log back, without racing a record being written
+func (l *auditLog) records() ([]AuditRecord, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return ReadAuditLog(l.path)
+}
+
+func (l *auditLog) close() error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.file.Close()
+}
+
+// ReadAuditLog reads every record of an audit log file
+func ReadAuditLog(path string) ([]AuditRecord, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	var records []AuditRecord
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for line := 1; scanner.Scan(); line++ {
+		if len(scanner.Bytes()) == 0 {
+			continue
+		}
+		var record AuditRecord
+		if err := json.Unmarshal(scanner.Bytes(), &record); err != nil {
+			return nil, fmt.Errorf("invalid audit record on line %d: %w", line, err)
+		}
+		records = append(records, record)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read audit log: %w", err)
+	}
+	return records, nil
+}
+
+// VerifyAuditChain checks that records are numbered in order, that each hash
+// matches its record and that each record points at the one before it
+func VerifyAuditChain(records []AuditRecord) error {
+	prevHash := auditGenesisHash
+	for i, record := range records {
+		if record.Seq != uint64(i)+1 {
+			return fmt.Errorf("record %d out of sequence: got seq %d", i+1, record.Seq)
+		}
+		if record.PrevHash != prevHash {
+			return fmt.Errorf("record %d does not follow record %d", record.Seq, record.Seq-1)
+		}
+		if record.hash() != record.Hash {
+			return fmt.Errorf("record %d hash mismatch", record.Seq)
+		}
+		prevHash = record.Hash
+	}
+	return nil
+}
+
+// auditCSVHeader lists the columns written by WriteAuditCSV
+var auditCSVHeader = []string{
+	"seq", "timestamp", "type", "request_id", "scheme", "network", "asset", "pay_to",
+	"payer", "amount", "success", "code", "reason", "transaction", "settlement", "prev_hash", "hash",
+}
+
+// WriteAuditCSV writes records as CSV with a header row
+func WriteAuditCSV(w io.Writer, records []AuditRecord) error {
+	writer := csv.NewWriter(w)
+	if err := writer.Write(auditCSVHeader); err != nil {
+		return err
+	}
+	for _, r := range records {
+		if err := writer.Write([]string{
+			strconv.FormatUint(r.Seq, 10),
+			strconv.FormatInt(r.Timestamp, 10),
+			r.Type,
+			r.RequestID,
+			r.Scheme,
+			r.Network,
+			r.Asset,
+			r.PayTo,
+			r.Payer,
+			r.Amount,
+			strconv.FormatBool(r.Success),
+			r.Code,
+			r.Reason,
+			r.Transaction,
+			r.Settlement,
+			r.PrevHash,
+			r.Hash,
+		}); err != nil {
+			return err
+		}
+	}
+	writer.Flush()
+	return writer.Error()
+}
+
+// auditEvent writes a verify, settle or refund event to the audit log if enabled
+func (f *Facilitator) auditEvent(requestID string, event Event) {
+	if f.audit == nil {
+		return
+	}
+	record := AuditRecord{
+		Timestamp:   f.now().Unix(),
+		Type:        event.Type,
+		RequestID:   requestID,
+		Scheme:      event.Scheme,
+		Network:     event.Network,
+		Asset:       event.Asset,
+		PayTo:       event.PayTo,
+		Payer:       event.Payer,
+		Amount:      event.Amount,
+		Success:     event.Success,
+		Code:        event.Code,
+		Reason:      event.Reason,
+		Transaction: event.Transaction,
+		Settlement:  event.Settlement,
+	}
+	if err := f.audit.append(record); err != nil {
+		f.logger.Error("failed to write audit record", "type", event.Type, "error", err)
+	}
+}
+
+// handleAuditExport returns the audit log as JSON, or as CSV with ?format=csv.
+// ?since=<seq> only returns records after that sequence number.
+func (f *Facilitator) handleAuditExport(ctx *gin.Context) {
+	records, err := f.audit.records()
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{
+			"error": err.Error(),
+		})
+		return
+	}
+	if err := VerifyAuditChain(records); err != nil {
+		ctx.JSON(http.StatusConflict, gin.H{
+			"error": err.Error(),
+		})
+		return
+	}
+
+	if since := ctx.Query("since"); since != "" {
+		seq, err := strconv.ParseUint(since, 10, 64)
+		if err != nil {
+			ctx.JSON(http.StatusBadRequest, gin.H{
+				"error": "invalid since",
+			})
+			return
+		}
+		if seq > uint64(len(records)) {
+			seq = uint64(len(records))
+		}
+		records = records[seq:]
+	}
+
+	switch ctx.DefaultQuery("format", "json") {
+	case "json":
+		if records == nil {
+			records = []AuditRecord{}
+		}
+		ctx.JSON(http.StatusOK, records)
+	case "csv":
+		ctx.Header("Content-Type", "text/csv")
+		ctx.Status(http.StatusOK)
+		if err := WriteAuditCSV(ctx.Writer, records); err != nil {
+			f.logger.Error("failed to write audit export", "error", err)
+		}
+	default:
+		ctx.JSON(http.StatusBadRequest, gin.H{
+			"error": "format must be json or csv",
+		})
+	}
+}
@@ -0,0 +1,85 @@
+package facilitator
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/vorpalengineering/x402-go/types"
+)
+
+func TestAuditLog(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "audit.jsonl")
+	newAuditedFacilitator := func() *Facilitator {
+		return NewFacilitator(&FacilitatorConfig{
+			Supported: []types.SupportedKind{{Scheme: "exact", Network: "eip155:8453"}},
+			Log:       LogConfig{Level: "error"},
+			Admin:     AdminConfig{Enabled: true, Token: "secret"},
+			Audit:     AuditConfig{Enabled: true, File: path},
+		})
+	}
+	verify := func(f *Facilitator) {
+		requirements := types.PaymentRequirements{Scheme: "exact", Network: "eip155:8453", Amount: "1000"}
+		body, _ := json.Marshal(types.VerifyRequest{
+			PaymentPayload:      types.PaymentPayload{X402Version: 2, Accepted: requirements},
+			PaymentRequirements: requirements,
+		})
+		req := httptest.NewRequest("POST", "/verify", bytes.NewReader(body))
+		req.Header.Set("X-Request-ID", "req-1")
+		f.router.ServeHTTP(httptest.NewRecorder(), req)
+	}
+
+	// Rejections are recorded with their reason, and a reopened log continues the chain
+	f := newAuditedFacilitator()
+	verify(f)
+	f.Close()
+	f = newAuditedFacilitator()
+	defer f.Close()
+	verify(f)
+
+	records, err := ReadAuditLog(path)
+	if err != nil {
+		t.Fatalf("Failed to read audit log: %v", err)
+	}
+	if len(records) != 2 {
+		t.Fatalf("Expected 2 records, got %d", len(records))
+	}
+	if err := VerifyAuditChain(records); err != nil {
+		t.Errorf("Expected chain to verify, got %v", err)
+	}
+	record := records[1]
+	if record.Seq != 2 || record.Type != EventVerify || record.Success || record.Reason != "missing signature" || record.RequestID != "req-1" {
+		t.Errorf("Expected second rejected verify record, got %+v", record)
+	}
+
+	// The admin export returns CSV with a header row
+	req := httptest.NewRequest("GET", "/admin/audit?format=csv&since=1", nil)
+	req.Header.Set("Authorization", "Bearer secret")
+	recorder := httptest.NewRecorder()
+	f.router.ServeHTTP(recorder, req)
+	if recorder.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d: %s", recorder.Code, recorder.Body.String())
+	}
+	rows, err := csv.NewReader(recorder.Body).ReadAll()
+	if err != nil || len(rows) != 2 || rows[0][0] != "seq" || rows[1][0] != "2" {
+		t.Errorf("Expected header and record 2, got %v (%v)", rows, err)
+	}
+
+	// Editing a record breaks the chain
+	data, _ := os.ReadFile(path)
+	tampered := strings.Replace(string(data), "missing signature", "valid", 1)
+	os.WriteFile(path, []byte(tampered), 0o600)
+	records, _ = ReadAuditLog(path)
+	if err := VerifyAuditChain(records); err == nil || !strings.Contains(err.Error(), "record 1 hash mismatch") {
+		t.Errorf("Expected hash mismatch on record 1, got %v", err)
+	}
+	if _, err := openAuditLog(path); err == nil {
+		t.Error("Expected a tampered log not to be opened")
+	}
+}
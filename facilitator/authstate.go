@@ -0,0 +1,65 @@
+package facilitator
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/accounts/abi"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/ethclient"
+	"github.com/vorpalengineering/x402-go/types"
+	"github.com/vorpalengineering/x402-go/utils"
+)
+
+// authorizationUsed asks an EIP-3009 token whether the authorizer's nonce was
+// already used or canceled
+func authorizationUsed(ctx context.Context, client *ethclient.Client, token, authorizer common.Address, nonce [32]byte) (bool, error) {
+	// Parse the ABI
+	parsedABI, err := abi.JSON(strings.NewReader(utils.EIP3009AuthorizationStateABI))
+	if err != nil {
+		return false, fmt.Errorf("failed to parse ABI: %w", err)
+	}
+
+	// Encode the authorizationState call
+	callData, err := parsedABI.Pack("authorizationState", authorizer, nonce)
+	if err != nil {
+		return false, fmt.Errorf("failed to encode authorizationState call: %w", err)
+	}
+
+	result, err := client.CallContract(ctx, ethereum.CallMsg{To: &token, Data: callData}, nil)
+	if err != nil {
+		return false, fmt.Errorf("failed to call authorizationState: %w", err)
+	}
+
+	// Decode the result
+	values, err := parsedABI.Unpack("authorizationState", result)
+	if err != nil {
+		return false, fmt.Errorf("failed to decode authorizationState: %w", err)
+	}
+	return values[0].(bool), nil
+}
+
+// checkAuthorizationState fails with SettleErrAuthorizationUsed if the token
+// reports the authorization's nonce as used, instead of sending a transaction
+// that would revert. Tokens without authorizationState, and RPC failures, let
+// settlement go ahead.
+func (f *Facilitator) checkAuthorizationState(ctx context.Context, client *ethclient.Client, auth *types.ExactEVMSchemeAuthorization, requirements *types.PaymentRequirements) error {
+	var nonce [32]byte
+	copy(nonce[:], common.FromHex(auth.Nonce))
+
+	used, err := authorizationUsed(ctx, client, common.HexToAddress(requirements.Asset), common.HexToAddress(auth.From), nonce)
+	if err != nil {
+		f.log(ctx).Debug("skipping authorization state check", "network", requirements.Network, "asset", requirements.Asset, "error", err)
+		return nil
+	}
+	if used {
+		return &settleError{
+			code: SettleErrAuthorizationUsed,
+			err:  errors.New("authorization already used or canceled"),
+		}
+	}
+	return nil
+}
@@ -0,0 +1,89 @@
+package facilitator
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/vorpalengineering/x402-go/types"
+)
+
+func TestSettleAuthorizationAlreadyUsed(t *testing.T) {
+	// RPC where the token reports the nonce as used, or doesn't implement authorizationState
+	implemented := true
+	var sent int
+	rpcServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req struct {
+			ID     json.RawMessage `json:"id"`
+			Method string          `json:"method"`
+		}
+		json.NewDecoder(r.Body).Decode(&req)
+		w.Header().Set("Content-Type", "application/json")
+		switch req.Method {
+		case "eth_call":
+			if !implemented {
+				fmt.Fprintf(w, `{"jsonrpc":"2.0","id":%s,"error":{"code":3,"message":"execution reverted"}}`, req.ID)
+				return
+			}
+			fmt.Fprintf(w, `{"jsonrpc":"2.0","id":%s,"result":"0x%064x"}`, req.ID, 1)
+			return
+		case "eth_sendRawTransaction":
+			sent++
+		}
+		fmt.Fprintf(w, `{"jsonrpc":"2.0","id":%s,"result":"0x1"}`, req.ID)
+	}))
+	defer rpcServer.Close()
+
+	key, _ := crypto.HexToECDSA("ac0974bec39a17e36ba4a6b4d238ff944bacb478cbed5efcae784d7bf4f2ff80")
+	f := NewFacilitator(&FacilitatorConfig{
+		Networks: map[string]NetworkConfig{
+			"eip155:8453": {RpcUrl: rpcServer.URL},
+		},
+		Transaction: TransactionConfig{TimeoutSeconds: 5, MaxGasPrice: "100000000000"},
+		Log:         LogConfig{Level: "error"},
+		Signer:      SignerConfig{PrivateKey: key},
+	})
+	defer f.Close()
+
+	requirements := &types.PaymentRequirements{
+		Scheme:  "exact",
+		Network: "eip155:8453",
+		Amount:  "1000000",
+		PayTo:   "0x3C44CdDdB6a900fa2b585dd299e03d12FA4293BC",
+		Asset:   "0x833589fCD6eDb6E08f4c7C32D4f71b54bdA02913",
+	}
+	auth := &types.ExactEVMSchemeAuthorization{
+		From:        "0x70997970C51812dc3A010C7d01b50e0d17dc79C8",
+		To:          requirements.PayTo,
+		Value:       "1000000",
+		ValidBefore: 4102444800,
+		Nonce:       "0x" + strings.Repeat("01", 32),
+	}
+	payload := &types.PaymentPayload{
+		Payload: map[string]any{
+			"signature":     "0x" + strings.Repeat("ab", 64) + "1b",
+			"authorization": auth,
+		},
+	}
+
+	// A used nonce fails with its own code and nothing is sent
+	resp := f.settleExactScheme(context.Background(), payload, requirements)
+	if resp.Success || resp.ErrorReason != SettleErrAuthorizationUsed {
+		t.Errorf("Expected %s, got %+v", SettleErrAuthorizationUsed, resp)
+	}
+	if sent != 0 {
+		t.Errorf("Expected no transaction to be sent, got %d", sent)
+	}
+
+	// Tokens without authorizationState are settled as before
+	implemented = false
+	client, _ := f.getRPCClient("eip155:8453")
+	if err := f.checkAuthorizationState(context.Background(), client, auth, requirements); err != nil {
+		t.Errorf("Expected the check to be skipped, got %v", err)
+	}
+}
@@ -0,0 +1,398 @@
+package facilitator
+
+import (
+	"context"
+	"fmt"
+	"math/big"
+	"net/http"
+	"strings"
+
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/accounts/abi"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/ethclient"
+	"github.com/gin-gonic/gin"
+	"github.com/vorpalengineering/x402-go/types"
+	"github.com/vorpalengineering/x402-go/utils"
+)
+
+const defaultBatchMaxSize = 50
+
+// multicallCall and multicallResult mirror Multicall3's Call3 and Result structs
+type multicallCall struct {
+	Target       common.Address
+	AllowFailure bool
+	CallData     []byte
+}
+
+type multicallResult struct {
+	Success    bool
+	ReturnData []byte
+}
+
+// batchItem is a batch entry ready to be aggregated
+type batchItem struct {
+	index int
+	payer string
+	call  multicallCall
+}
+
+func (f *Facilitator) handleSettleBatch(ginCtx *gin.Context) {
+	// Decode request
+	var req types.BatchSettleRequest
+	if err := ginCtx.ShouldBindJSON(&req); err != nil {
+		ginCtx.JSON(http.StatusBadRequest, gin.H{
+			"error": err.Error(),
+		})
+		return
+	}
+
+	// Check batch size
+	maxSize := f.cfg().Batch.MaxSize
+	if maxSize <= 0 {
+		maxSize = defaultBatchMaxSize
+	}
+	if len(req.Items) == 0 || len(req.Items) > maxSize {
+		ginCtx.JSON(http.StatusBadRequest, gin.H{
+			"error": fmt.Sprintf("batch must contain 1-%d items, got %d", maxSize, len(req.Items)),
+		})
+		return
+	}
+
+	// Count every item against the client's quotas, rejecting the whole batch
+	// if any is over
+	releases := make([]func(), 0, len(req.Items))
+	for i := range req.Items {
+		release, ok := f.reserveSettlementQuota(ginCtx, &req.Items[i].PaymentRequirements)
+		if !ok {
+			for _, release := range releases {
+				release()
+			}
+			return
+		}
+		releases = append(releases, release)
+	}
+
+	resp := f.settleBatch(ginCtx.Request.Context(), req.Items)
+	for i, result := range resp.Results {
+		if !result.Success && result.Transaction == "" {
+			releases[i]()
+		}
+	}
+	ginCtx.JSON(http.StatusOK, resp)
+}
+
+// settleBatch settles every item, aggregating each network's exact scheme
+// settlements into a single Multicall3 transaction unless the batch mode is
+// sequential
+func (f *Facilitator) settleBatch(ctx context.Context, items []types.SettleRequest) *types.BatchSettleResponse {
+	results := make([]types.SettleResponse, len(items))
+
+	// Settle one by one through the regular settlement path
+	if f.cfg().Batch.Mode == BatchModeSequential {
+		for i := range items {
+			results[i] = *f.settle(ctx, &items[i])
+		}
+		return &types.BatchSettleResponse{Results: results}
+	}
+
+	// Group items by network, in request order
+	var networks []string
+	groups := make(map[string][]int)
+	seen := make(map[string]bool)
+	for i := range items {
+		scheme := items[i].PaymentPayload.Accepted.Scheme
+		if scheme != "exact" && scheme != "upto" {
+			results[i] = *settleFailed(types.ErrCodeUnsupportedScheme, fmt.Sprintf("unsupported scheme: %s", scheme))
+			continue
+		}
+		key := settlementKey(&items[i].PaymentPayload, &items[i].PaymentRequirements)
+		if key != "" && seen[key] {
+			results[i] = *settleFailed(types.ErrCodeInvalidPayload, "duplicate authorization in batch")
+			continue
+		}
+		seen[key] = true
+
+		// Solana payments are complete transactions and upto transfers must be
+		// sent by the Permit2 spender, these are sent one by one
+		network := items[i].PaymentRequirements.Network
+		if utils.IsSolanaNetwork(network) || scheme == "upto" {
+			results[i] = *f.settlePayment(ctx, &items[i])
+			continue
+		}
+		if _, ok := groups[network]; !ok {
+			networks = append(networks, network)
+		}
+		groups[network] = append(groups[network], i)
+	}
+
+	// Aggregate each network into one transaction
+	for _, network := range networks {
+		f.settleMulticall(ctx, network, items, groups[network], results)
+	}
+
+	for i := range items {
+		f.settled(ctx, &items[i], &results[i])
+	}
+	return &types.BatchSettleResponse{Results: results}
+}
+
+// settleMulticall settles the items at indexes, all on network, in a single
+// aggregate3 transaction. Items whose transfer would revert are left out of
+// the transaction so they can't fail the others.
+func (f *Facilitator) settleMulticall(ctx context.Context, network string, items []types.SettleRequest, indexes []int, results []types.SettleResponse) {
+	fail := func(indexes []int, resp *types.SettleResponse) {
+		for _, i := range indexes {
+			results[i] = *resp
+		}
+	}
+
+	// Get network config and RPC client
+	networkCfg, err := f.cfg().GetNetworkConfig(network)
+	if err != nil {
+		fail(indexes, settleFailed(types.ErrCodeUnsupportedNetwork, err.Error()))
+		return
+	}
+	client, err := f.getRPCClient(network)
+	if err != nil {
+		fail(indexes, settleFailed(types.ErrCodeNetworkError, fmt.Sprintf("failed to connect to network: %v", err)))
+		return
+	}
+	multicall := networkCfg.GetMulticallAddress()
+
+	// The batch transaction takes one worker of the network
+	done, err := f.acquireSettleWorker(ctx, network)
+	if err != nil {
+		fail(indexes, settleFailure(err, 0))
+		return
+	}
+	defer done()
+	ctx, cancel := f.withTransactionTimeout(ctx)
+	defer cancel()
+
+	// Encode each authorization as a transferWithAuthorization call
+	var batch []batchItem
+	for _, i := range indexes {
+		signatureHex, ok := items[i].PaymentPayload.Payload["signature"].(string)
+		if !ok || signatureHex == "" {
+			fail([]int{i}, settleFailed(types.ErrCodeInvalidSignature, "missing signature"))
+			continue
+		}
+		auth, err := utils.ExtractExactAuthorization(&items[i].PaymentPayload)
+		if err != nil {
+			fail([]int{i}, settleFailed(types.ErrCodeInvalidPayload, fmt.Sprintf("invalid authorization: %v", err)))
+			continue
+		}
+		contract, err := isContractWallet(ctx, client, common.HexToAddress(auth.From))
+		if err != nil {
+			fail([]int{i}, settleFailed(types.ErrCodeNetworkError, fmt.Sprintf("failed to check payer: %v", err)))
+			continue
+		}
+		candidates, err := encodeTransferWithAuthorization(auth, signatureHex, contract)
+		if err != nil {
+			fail([]int{i}, settleFailed(types.ErrCodeInvalidPayload, fmt.Sprintf("failed to settle payment: %v", err)))
+			continue
+		}
+		batch = append(batch, batchItem{
+			index: i,
+			payer: auth.From,
+			call: multicallCall{
+				Target:   common.HexToAddress(items[i].PaymentRequirements.Asset),
+				CallData: candidates[0].data,
+			},
+		})
+	}
+	if len(batch) == 0 {
+		return
+	}
+
+	// Get the network's signer, tracked as in flight while sending
+	signer, release, err := f.signers.acquire(network)
+	if err != nil {
+		fail(batchIndexes(batch), settleFailed(types.ErrCodeSettlementFailed, err.Error()))
+		return
+	}
+	defer release()
+
+	// Simulate the batch and drop the transfers that would revert
+	multicallABI, err := abi.JSON(strings.NewReader(utils.Multicall3ABI))
+	if err != nil {
+		fail(batchIndexes(batch), settleFailed(types.ErrCodeSettlementFailed, fmt.Sprintf("failed to parse ABI: %v", err)))
+		return
+	}
+	simulated, err := simulateMulticall(ctx, client, multicallABI, signer.Address(), multicall, batch)
+	if err != nil {
+		fail(batchIndexes(batch), settleFailed(types.ErrCodeSettlementFailed, fmt.Sprintf("failed to simulate batch: %v", err)))
+		return
+	}
+	passing := batch[:0]
+	for i, item := range batch {
+		if !simulated[i].Success {
+			fail([]int{item.index}, settleFailed(SettleErrTransactionReverted, "transfer reverted in batch simulation"))
+			continue
+		}
+		passing = append(passing, item)
+	}
+	if len(passing) == 0 {
+		return
+	}
+
+	// Send the remaining transfers in one transaction that reverts as a whole,
+	// so every result matches the transaction's outcome
+	txHash, err := f.sendMulticall(ctx, client, signer, network, networkCfg, multicallABI, multicall, passing)
+	if err != nil {
+		fail(batchIndexes(passing), settleFailure(err, 0))
+		return
+	}
+
+	resps := make([]*types.SettleResponse, 0, len(passing))
+	for _, item := range passing {
+		results[item.index] = types.SettleResponse{
+			Success:     true,
+			Transaction: txHash,
+			Network:     network,
+			Payer:       item.payer,
+		}
+		resps = append(resps, &results[item.index])
+	}
+	if f.cfg().Transaction.Confirmations > 0 {
+		f.confirmSettlement(ctx, client, txHash, resps...)
+	}
+}
+
+// simulateMulticall runs the batch through eth_call with failures allowed and
+// returns the result of each call
+func simulateMulticall(
+	ctx context.Context,
+	client *ethclient.Client,
+	multicallABI abi.ABI,
+	from common.Address,
+	multicall common.Address,
+	batch []batchItem,
+) ([]multicallResult, error) {
+	calls := make([]multicallCall, len(batch))
+	for i, item := range batch {
+		calls[i] = item.call
+		calls[i].AllowFailure = true
+	}
+	data, err := multicallABI.Pack("aggregate3", calls)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode call: %w", err)
+	}
+
+	output, err := client.CallContract(ctx, ethereum.CallMsg{From: from, To: &multicall, Data: data}, nil)
+	if err != nil {
+		return nil, err
+	}
+	unpacked, err := multicallABI.Unpack("aggregate3", output)
+	if err != nil || len(unpacked) != 1 {
+		return nil, fmt.Errorf("failed to decode multicall result: %v", err)
+	}
+	results := *abi.ConvertType(unpacked[0], new([]multicallResult)).(*[]multicallResult)
+	if len(results) != len(batch) {
+		return nil, fmt.Errorf("expected %d multicall results, got %d", len(batch), len(results))
+	}
+	return results, nil
+}
+
+// sendMulticall estimates, signs and broadcasts an aggregate3 transaction
+// that reverts if any of its calls fails
+func (f *Facilitator) sendMulticall(
+	ctx context.Context,
+	client *ethclient.Client,
+	signer Signer,
+	network string,
+	networkCfg NetworkConfig,
+	multicallABI abi.ABI,
+	multicall common.Address,
+	batch []batchItem,
+) (string, error) {
+	calls := make([]multicallCall, len(batch))
+	for i, item := range batch {
+		calls[i] = item.call
+	}
+	data, err := multicallABI.Pack("aggregate3", calls)
+	if err != nil {
+		return "", fmt.Errorf("failed to encode call: %w", err)
+	}
+
+	// Determine gas limit from the network's gas settings
+	msg := ethereum.CallMsg{From: signer.Address(), To: &multicall, Data: data}
+	gas, err := f.multicallGasLimit(ctx, rpcGasEstimator{client}, network, networkCfg.Gas, msg, len(batch))
+	if err != nil {
+		return "", err
+	}
+
+	// Get gas price and check it against max gas price from config
+	gasPrice, err := client.SuggestGasPrice(ctx)
+	if err != nil {
+		return "", fmt.Errorf("failed to get gas price: %w", err)
+	}
+	maxGasPrice, ok := new(big.Int).SetString(f.cfg().Transaction.MaxGasPrice, 10)
+	if !ok {
+		return "", fmt.Errorf("failed to parse max gas price: %s", f.cfg().Transaction.MaxGasPrice)
+	}
+	if gasPrice.Cmp(maxGasPrice) > 0 {
+		return "", fmt.Errorf("gas price too high: suggested %s wei exceeds max %s wei", gasPrice.String(), maxGasPrice.String())
+	}
+
+	// Get chain ID
+	chainID, err := utils.GetChainID(network)
+	if err != nil {
+		return "", fmt.Errorf("failed to get chain id: %w", err)
+	}
+
+	// Sign and send, retrying once with a fresh nonce if the node says it was used
+	call := &settlementCall{data: data, gas: gas}
+	txHash, err := f.sendSettlementTx(ctx, client, signer, network, chainID, multicall, gasPrice, call)
+	if err != nil && isNonceError(err) {
+		f.log(ctx).Warn("nonce already used, resynced and retrying", "network", network, "error", err)
+		txHash, err = f.sendSettlementTx(ctx, client, signer, network, chainID, multicall, gasPrice, call)
+	}
+	return txHash, err
+}
+
+func batchIndexes(batch []batchItem) []int {
+	indexes := make([]int, len(batch))
+	for i, item := range batch {
+		indexes[i] = item.index
+	}
+	return indexes
+}
+
+// multicallGasLimit picks the gas limit of an aggregate3 transaction. The
+// network's limit_override and fallback_limit are per transfer, so they are
+// scaled by the number of transfers in the batch, and the multiplier applies
+// to successful estimates as it does for single settlements.
+func (f *Facilitator) multicallGasLimit(
+	ctx context.Context,
+	estimator gasEstimator,
+	network string,
+	gasCfg GasConfig,
+	msg ethereum.CallMsg,
+	transfers int,
+) (uint64, error) {
+	// Manual override takes precedence over estimation
+	if gasCfg.LimitOverride > 0 {
+		return gasCfg.LimitOverride * uint64(transfers), nil
+	}
+
+	gas, err := estimator.EstimateGas(ctx, msg)
+	if err != nil {
+		if isRevertError(err) {
+			return 0, &settleError{code: SettleErrTransactionReverted, err: err}
+		}
+		if gasCfg.FallbackLimit > 0 {
+			f.log(ctx).Warn("gas estimation failed, using fallback limit",
+				"network", network, "fallback_limit", gasCfg.FallbackLimit, "transfers", transfers, "error", err)
+			return gasCfg.FallbackLimit * uint64(transfers), nil
+		}
+		return 0, &settleError{code: SettleErrGasEstimationFailed, err: err}
+	}
+
+	// Apply multiplier buffer
+	if gasCfg.Multiplier > 1 {
+		gas = uint64(float64(gas) * gasCfg.Multiplier)
+	}
+	return gas, nil
+}
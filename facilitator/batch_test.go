@@ -0,0 +1,222 @@
+package facilitator
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/accounts/abi"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	ethtypes "github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/vorpalengineering/x402-go/types"
+	"github.com/vorpalengineering/x402-go/utils"
+)
+
+func batchSettleRequest(payer, nonce string) types.SettleRequest {
+	requirements := types.PaymentRequirements{
+		Scheme:  "exact",
+		Network: "eip155:8453",
+		Amount:  "1000000",
+		PayTo:   "0x742d35Cc6634C0532925a3b844Bc9e7595f0bEb",
+		Asset:   "0x833589fCD6eDb6E08f4c7C32D4f71b54bdA02913",
+	}
+	return types.SettleRequest{
+		PaymentPayload: types.PaymentPayload{
+			X402Version: 2,
+			Accepted:    requirements,
+			Payload: map[string]any{
+				"signature": "0x" + strings.Repeat("11", 64) + "1b",
+				"authorization": map[string]any{
+					"from":        payer,
+					"to":          requirements.PayTo,
+					"value":       "1000000",
+					"validAfter":  0,
+					"validBefore": 4102444800,
+					"nonce":       nonce,
+				},
+			},
+		},
+		PaymentRequirements: requirements,
+	}
+}
+
+func TestSettleBatchMulticall(t *testing.T) {
+	multicallABI, _ := abi.JSON(strings.NewReader(utils.Multicall3ABI))
+	unpackCalls := func(data []byte) []multicallCall {
+		args, err := multicallABI.Methods["aggregate3"].Inputs.Unpack(data[4:])
+		if err != nil {
+			t.Fatalf("Failed to decode aggregate3 call: %v", err)
+		}
+		return *abi.ConvertType(args[0], new([]multicallCall)).(*[]multicallCall)
+	}
+
+	// RPC where the second transfer of a batch reverts
+	var mu sync.Mutex
+	var sent []*ethtypes.Transaction
+	rpcServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req struct {
+			ID     json.RawMessage   `json:"id"`
+			Method string            `json:"method"`
+			Params []json.RawMessage `json:"params"`
+		}
+		json.NewDecoder(r.Body).Decode(&req)
+		result := `"0x1"`
+		switch req.Method {
+		case "eth_call":
+			var arg struct {
+				Input hexutil.Bytes `json:"input"`
+			}
+			json.Unmarshal(req.Params[0], &arg)
+			results := make([]multicallResult, len(unpackCalls(arg.Input)))
+			for i := range results {
+				results[i].Success = i != 1
+			}
+			output, _ := multicallABI.Methods["aggregate3"].Outputs.Pack(results)
+			result = fmt.Sprintf(`"%s"`, hexutil.Encode(output))
+		case "eth_getCode":
+			result = `"0x"`
+		case "eth_estimateGas":
+			result = `"0x30000"`
+		case "eth_gasPrice":
+			result = `"0x3b9aca00"`
+		case "eth_getTransactionCount":
+			result = `"0x5"`
+		case "eth_sendRawTransaction":
+			var raw hexutil.Bytes
+			json.Unmarshal(req.Params[0], &raw)
+			tx := new(ethtypes.Transaction)
+			tx.UnmarshalBinary(raw)
+			mu.Lock()
+			sent = append(sent, tx)
+			mu.Unlock()
+			result = fmt.Sprintf(`"%s"`, tx.Hash().Hex())
+		}
+		w.Header().Set("Content-Type", "application/json")
+		fmt.Fprintf(w, `{"jsonrpc":"2.0","id":%s,"result":%s}`, req.ID, result)
+	}))
+	defer rpcServer.Close()
+
+	key, _ := crypto.HexToECDSA("ac0974bec39a17e36ba4a6b4d238ff944bacb478cbed5efcae784d7bf4f2ff80")
+	f := NewFacilitator(&FacilitatorConfig{
+		Networks: map[string]NetworkConfig{
+			"eip155:8453": {RpcUrl: rpcServer.URL},
+		},
+		Transaction: TransactionConfig{TimeoutSeconds: 5, MaxGasPrice: "100000000000"},
+		Log:         LogConfig{Level: "error"},
+		Batch:       BatchConfig{Enabled: true, MaxSize: 3},
+		Signer:      SignerConfig{PrivateKey: key},
+	})
+	defer f.Close()
+
+	post := func(items []types.SettleRequest) *httptest.ResponseRecorder {
+		body, _ := json.Marshal(types.BatchSettleRequest{Items: items})
+		recorder := httptest.NewRecorder()
+		f.router.ServeHTTP(recorder, httptest.NewRequest("POST", "/settle/batch", bytes.NewReader(body)))
+		return recorder
+	}
+
+	// Batches over the maximum size are rejected
+	first := batchSettleRequest("0xf39Fd6e51aad88F6F4ce6aB8827279cffFb92266", "0x"+strings.Repeat("01", 32))
+	second := batchSettleRequest("0x70997970C51812dc3A010C7d01b50e0d17dc79C8", "0x"+strings.Repeat("02", 32))
+	if recorder := post([]types.SettleRequest{first, first, first, first}); recorder.Code != http.StatusBadRequest {
+		t.Errorf("Expected status 400 for oversized batch, got %d", recorder.Code)
+	}
+
+	// Valid transfers share one transaction, reverting and duplicate ones fail alone
+	recorder := post([]types.SettleRequest{first, second, first})
+	if recorder.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d: %s", recorder.Code, recorder.Body.String())
+	}
+	var resp types.BatchSettleResponse
+	json.Unmarshal(recorder.Body.Bytes(), &resp)
+	if len(resp.Results) != 3 {
+		t.Fatalf("Expected 3 results, got %d", len(resp.Results))
+	}
+	if !resp.Results[0].Success || resp.Results[0].Payer != "0xf39Fd6e51aad88F6F4ce6aB8827279cffFb92266" {
+		t.Errorf("Expected first item to settle, got %+v", resp.Results[0])
+	}
+	if resp.Results[1].Success || !strings.HasPrefix(resp.Results[1].ErrorReason, SettleErrTransactionReverted) {
+		t.Errorf("Expected second item to revert, got %+v", resp.Results[1])
+	}
+	if resp.Results[2].Success || resp.Results[2].ErrorReason != types.ErrCodeInvalidPayload || resp.Results[2].ErrorMessage != "duplicate authorization in batch" {
+		t.Errorf("Expected third item to be a duplicate, got %+v", resp.Results[2])
+	}
+
+	// The transaction calls Multicall3 with only the passing transfer
+	if len(sent) != 1 {
+		t.Fatalf("Expected 1 transaction, got %d", len(sent))
+	}
+	if sent[0].Hash().Hex() != resp.Results[0].Transaction {
+		t.Errorf("Expected result to carry transaction %s, got %s", sent[0].Hash().Hex(), resp.Results[0].Transaction)
+	}
+	if *sent[0].To() != common.HexToAddress(utils.Multicall3Address) {
+		t.Errorf("Expected transaction to Multicall3, got %s", sent[0].To().Hex())
+	}
+	calls := unpackCalls(sent[0].Data())
+	if len(calls) != 1 || calls[0].AllowFailure {
+		t.Errorf("Expected one call that may not fail, got %+v", calls)
+	}
+	if sent[0].Nonce() != 5 {
+		t.Errorf("Expected nonce 5, got %d", sent[0].Nonce())
+	}
+}
+
+// unreachableEstimator fails every estimation as a flaky RPC would
+type unreachableEstimator struct {
+	fakeEstimator
+}
+
+func (e *unreachableEstimator) EstimateGas(ctx context.Context, msg ethereum.CallMsg) (uint64, error) {
+	return 0, errors.New("503 service unavailable")
+}
+
+func TestMulticallGasLimit(t *testing.T) {
+	f := NewFacilitator(&FacilitatorConfig{Log: LogConfig{Level: "error"}})
+	defer f.Close()
+	msg := ethereum.CallMsg{Data: []byte{1}}
+	estimator := &fakeEstimator{gas: map[byte]uint64{1: 100000}}
+
+	tests := []struct {
+		name      string
+		estimator gasEstimator
+		gasCfg    GasConfig
+		expected  uint64
+		errCode   string
+	}{
+		{"estimate", estimator, GasConfig{}, 100000, ""},
+		{"estimate with multiplier", estimator, GasConfig{Multiplier: 1.5}, 150000, ""},
+		{"override per transfer", estimator, GasConfig{LimitOverride: 70000, Multiplier: 1.5}, 210000, ""},
+		{"fallback per transfer", &unreachableEstimator{}, GasConfig{FallbackLimit: 80000}, 240000, ""},
+		{"no fallback", &unreachableEstimator{}, GasConfig{}, 0, SettleErrGasEstimationFailed},
+		{"revert skips fallback", &fakeEstimator{}, GasConfig{FallbackLimit: 80000}, 0, SettleErrTransactionReverted},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			gas, err := f.multicallGasLimit(context.Background(), tt.estimator, "eip155:8453", tt.gasCfg, msg, 3)
+			if tt.errCode != "" {
+				var settleErr *settleError
+				if !errors.As(err, &settleErr) || settleErr.code != tt.errCode {
+					t.Fatalf("Expected %s error, got %v", tt.errCode, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Expected no error, got %v", err)
+			}
+			if gas != tt.expected {
+				t.Errorf("Expected gas limit %d, got %d", tt.expected, gas)
+			}
+		})
+	}
+}
@@ -0,0 +1,190 @@
+package facilitator
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// CapturedExchange is a recorded verify/settle request together with the
+// facilitator response and every RPC call made while handling it. HTTP
+// headers are not recorded, and payer and payee addresses, nonces,
+// signatures and transaction hashes are replaced with pseudonyms before
+// the exchange is written.
+type CapturedExchange struct {
+	Timestamp time.Time         `json:"timestamp"`
+	Path      string            `json:"path"`
+	Request   json.RawMessage   `json:"request"`
+	Status    int               `json:"status"`
+	Response  json.RawMessage   `json:"response"`
+	RPCCalls  []CapturedRPCCall `json:"rpcCalls"`
+}
+
+// CapturedRPCCall is a single JSON-RPC request/response pair
+type CapturedRPCCall struct {
+	Network  string          `json:"network"`
+	Request  json.RawMessage `json:"request"`
+	Response json.RawMessage `json:"response"`
+}
+
+type captureContextKey struct{}
+
+// captureRecorder collects RPC calls for the request it is attached to
+type captureRecorder struct {
+	mu    sync.Mutex
+	calls []CapturedRPCCall
+}
+
+func (r *captureRecorder) add(call CapturedRPCCall) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.calls = append(r.calls, call)
+}
+
+// captureTransport records JSON-RPC traffic for requests carrying a captureRecorder
+type captureTransport struct {
+	network string
+	base    http.RoundTripper
+}
+
+func (t *captureTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	// Pass through requests that are not being captured
+	recorder, ok := req.Context().Value(captureContextKey{}).(*captureRecorder)
+	if !ok || req.Body == nil {
+		return t.base.RoundTrip(req)
+	}
+
+	// Read and restore request body
+	reqBody, err := io.ReadAll(req.Body)
+	req.Body.Close()
+	if err != nil {
+		return nil, err
+	}
+	req.Body = io.NopCloser(bytes.NewReader(reqBody))
+
+	// Forward request
+	resp, err := t.base.RoundTrip(req)
+	if err != nil {
+		return nil, err
+	}
+
+	// Read and restore response body
+	respBody, err := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if err != nil {
+		return nil, err
+	}
+	resp.Body = io.NopCloser(bytes.NewReader(respBody))
+
+	recorder.add(CapturedRPCCall{
+		Network:  t.network,
+		Request:  json.RawMessage(reqBody),
+		Response: json.RawMessage(respBody),
+	})
+
+	return resp, nil
+}
+
+// captureWriter tees the response body so it can be recorded
+type captureWriter struct {
+	gin.ResponseWriter
+	body *bytes.Buffer
+}
+
+func (w *captureWriter) Write(data []byte) (int, error) {
+	w.body.Write(data)
+	return w.ResponseWriter.Write(data)
+}
+
+func (f *Facilitator) captureMiddleware() gin.HandlerFunc {
+	// Salt pseudonyms per process, so they can't be linked to the values
+	// they replace or across runs
+	salt := make([]byte, 32)
+	rand.Read(salt)
+
+	return func(ginCtx *gin.Context) {
+		// Read and restore request body
+		reqBody, err := io.ReadAll(ginCtx.Request.Body)
+		if err != nil {
+			ginCtx.JSON(http.StatusBadRequest, gin.H{
+				"error": err.Error(),
+			})
+			ginCtx.Abort()
+			return
+		}
+		ginCtx.Request.Body = io.NopCloser(bytes.NewReader(reqBody))
+
+		// Attach recorder so the RPC transport can find it
+		recorder := &captureRecorder{}
+		ctx := context.WithValue(ginCtx.Request.Context(), captureContextKey{}, recorder)
+		ginCtx.Request = ginCtx.Request.WithContext(ctx)
+
+		// Tee the response
+		writer := &captureWriter{ResponseWriter: ginCtx.Writer, body: &bytes.Buffer{}}
+		ginCtx.Writer = writer
+
+		timestamp := f.now()
+		ginCtx.Next()
+
+		exchange := CapturedExchange{
+			Timestamp: timestamp,
+			Path:      ginCtx.FullPath(),
+			Request:   json.RawMessage(reqBody),
+			Status:    writer.Status(),
+			Response:  json.RawMessage(writer.body.Bytes()),
+			RPCCalls:  recorder.calls,
+		}
+		f.anonymizeExchange(ginCtx.Request.Context(), salt, &exchange)
+		if err := writeCapturedExchange(f.cfg().Capture.Dir, &exchange); err != nil {
+			f.log(ginCtx.Request.Context()).Error("failed to write captured exchange", "error", err)
+		}
+	}
+}
+
+func writeCapturedExchange(dir string, exchange *CapturedExchange) error {
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return fmt.Errorf("failed to create capture dir: %w", err)
+	}
+
+	data, err := json.MarshalIndent(exchange, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal exchange: %w", err)
+	}
+
+	name := fmt.Sprintf("%d-%s.json", exchange.Timestamp.UnixNano(), strings.TrimPrefix(exchange.Path, "/"))
+	return os.WriteFile(filepath.Join(dir, name), data, 0600)
+}
+
+// LoadCapturedExchanges reads every captured exchange in dir, ordered by capture time
+func LoadCapturedExchanges(dir string) ([]CapturedExchange, error) {
+	paths, err := filepath.Glob(filepath.Join(dir, "*.json"))
+	if err != nil {
+		return nil, fmt.Errorf("failed to list capture dir: %w", err)
+	}
+
+	exchanges := make([]CapturedExchange, 0, len(paths))
+	for _, path := range paths {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read %s: %w", path, err)
+		}
+		var exchange CapturedExchange
+		if err := json.Unmarshal(data, &exchange); err != nil {
+			return nil, fmt.Errorf("failed to parse %s: %w", path, err)
+		}
+		exchanges = append(exchanges, exchange)
+	}
+
+	return exchanges, nil
+}
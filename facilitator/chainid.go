@@ -0,0 +1,70 @@
+package facilitator
+
+import (
+	"context"
+	"fmt"
+	"math/big"
+	"time"
+
+	"github.com/ethereum/go-ethereum/ethclient"
+	"github.com/vorpalengineering/x402-go/utils"
+)
+
+// chainIDCheckTimeout bounds the eth_chainId call made to each endpoint
+const chainIDCheckTimeout = 5 * time.Second
+
+// checkChainID compares the chain ID reported by every RPC endpoint of an EVM
+// network with the one of its CAIP-2 key, so a misconfigured URL doesn't
+// verify payments against the wrong chain. Mismatches are returned as an
+// error, or only logged with chain_id_check "warn". Endpoints that can't be
+// reached are logged and skipped, failover takes care of them.
+func (f *Facilitator) checkChainID(network string, networkCfg NetworkConfig) error {
+	mode := f.cfg().ChainIDCheck
+	if mode == ChainIDCheckOff || utils.IsSolanaNetwork(network) {
+		return nil
+	}
+
+	// Get the expected chain ID from the network key
+	expected, err := utils.GetChainID(network)
+	if err != nil {
+		return fmt.Errorf("failed to get chain id: %w", err)
+	}
+
+	for _, rpcURL := range networkCfg.GetRpcUrls() {
+		chainID, err := fetchChainID(rpcURL)
+		if err != nil {
+			f.logger.Warn("failed to check rpc chain id", "network", network, "error", err)
+			continue
+		}
+		if chainID.Cmp(expected) == 0 {
+			continue
+		}
+
+		// Don't log the URL, it may hold an API key
+		if mode == ChainIDCheckWarn {
+			f.logger.Warn("rpc endpoint is on another chain", "network", network, "chain_id", chainID.String())
+			continue
+		}
+		return fmt.Errorf("chain id mismatch: rpc endpoint reports %s, expected %s", chainID, expected)
+	}
+
+	return nil
+}
+
+// fetchChainID asks an RPC endpoint for its chain ID over a connection of its own
+func fetchChainID(rpcURL string) (*big.Int, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), chainIDCheckTimeout)
+	defer cancel()
+
+	client, err := ethclient.DialContext(ctx, rpcURL)
+	if err != nil {
+		return nil, fmt.Errorf("failed to dial rpc: %w", err)
+	}
+	defer client.Close()
+
+	chainID, err := client.ChainID(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get chain id: %w", err)
+	}
+	return chainID, nil
+}
@@ -0,0 +1,69 @@
+package facilitator
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func chainIDServer(chainID string) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"jsonrpc":"2.0","id":1,"result":"` + chainID + `"}`))
+	}))
+}
+
+func TestDialRPCClientsChainIDCheck(t *testing.T) {
+	base := chainIDServer("0x2105")
+	defer base.Close()
+	mainnet := chainIDServer("0x1")
+	defer mainnet.Close()
+
+	tests := []struct {
+		name      string
+		mode      string
+		rpcURLs   []string
+		expectErr bool
+	}{
+		{"matching chain", "", []string{base.URL}, false},
+		{"mismatched chain", "", []string{mainnet.URL}, true},
+		{"mismatched fallback", ChainIDCheckFail, []string{base.URL, mainnet.URL}, true},
+		{"mismatch with warn", ChainIDCheckWarn, []string{mainnet.URL}, false},
+		{"mismatch with check off", ChainIDCheckOff, []string{mainnet.URL}, false},
+		{"unreachable endpoint", "", []string{"http://127.0.0.1:1"}, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			testConfig := &FacilitatorConfig{
+				Networks: map[string]NetworkConfig{
+					"eip155:8453": {
+						RpcUrl:  tt.rpcURLs[0],
+						RpcUrls: tt.rpcURLs[1:],
+					},
+				},
+				Log: LogConfig{
+					Level: "error",
+				},
+				ChainIDCheck: tt.mode,
+			}
+			f := NewFacilitator(testConfig)
+			defer f.Close()
+
+			err := f.DialRPCClients()
+			if tt.expectErr {
+				if err == nil || !strings.Contains(err.Error(), "chain id mismatch") {
+					t.Fatalf("Expected chain id mismatch error, got %v", err)
+				}
+				if len(f.rpcClients) != 0 {
+					t.Errorf("Expected no RPC clients after a mismatch, got %d", len(f.rpcClients))
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Expected no error, got %v", err)
+			}
+		})
+	}
+}
@@ -2,29 +2,137 @@ package client
 
 import (
 	"bytes"
+	"context"
+	"crypto/tls"
+	"crypto/x509"
 	"encoding/json"
 	"fmt"
 	"net/http"
+	"os"
 
 	"github.com/vorpalengineering/x402-go/types"
+	"github.com/vorpalengineering/x402-go/version"
 )
 
 type FacilitatorClient struct {
 	facilitatorURL string
 	httpClient     *http.Client
+	transport      *headerTransport
 }
 
 func NewFacilitatorClient(facilitatorURL string) *FacilitatorClient {
+	transport := &headerTransport{base: version.NewTransport("x402-go")}
 	return &FacilitatorClient{
 		facilitatorURL: facilitatorURL,
-		httpClient:     &http.Client{},
+		httpClient:     &http.Client{Transport: transport},
+		transport:      transport,
 	}
 }
 
+// SetTLSConfig sets the TLS settings used to reach the facilitator, e.g. a
+// client certificate for mutual TLS or a private CA. See LoadTLSConfig.
+// Call before making requests.
+func (fc *FacilitatorClient) SetTLSConfig(tlsConfig *tls.Config) {
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.TLSClientConfig = tlsConfig
+	fc.transport.base = &version.Transport{
+		Base:      transport,
+		UserAgent: version.UserAgent("x402-go"),
+	}
+}
+
+// SetRequestHeaders sets extra headers sent with every request to the
+// facilitator (e.g. an API key). Call before making requests.
+func (fc *FacilitatorClient) SetRequestHeaders(headers http.Header) {
+	fc.transport.headers = headers.Clone()
+}
+
+// headerTransport adds the configured headers to each request
+type headerTransport struct {
+	base    http.RoundTripper
+	headers http.Header
+}
+
+func (t *headerTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if len(t.headers) == 0 {
+		return t.base.RoundTrip(req)
+	}
+
+	// RoundTrippers must not modify the caller's request
+	req = req.Clone(req.Context())
+	for name, values := range t.headers {
+		req.Header[name] = values
+	}
+	return t.base.RoundTrip(req)
+}
+
+// StatusError is returned when the facilitator answers with an unexpected
+// HTTP status
+type StatusError struct {
+	StatusCode int
+	// Message is the facilitator's error message, if it sent one
+	Message string
+}
+
+func (e *StatusError) Error() string {
+	if e.Message != "" {
+		return fmt.Sprintf("unexpected status code: %d: %s", e.StatusCode, e.Message)
+	}
+	return fmt.Sprintf("unexpected status code: %d", e.StatusCode)
+}
+
+// Temporary reports whether the request may succeed when sent again: the
+// facilitator timed out, was rate limited or failed with a 5xx status
+func (e *StatusError) Temporary() bool {
+	switch e.StatusCode {
+	case http.StatusRequestTimeout, http.StatusTooManyRequests:
+		return true
+	}
+	return e.StatusCode >= 500 && e.StatusCode != http.StatusNotImplemented
+}
+
+// LoadTLSConfig builds a client TLS config from PEM files. certFile and keyFile
+// are the client certificate presented for mutual TLS, caFile the CAs trusted
+// for the facilitator's certificate in place of the system roots. Empty paths
+// are skipped.
+func LoadTLSConfig(certFile, keyFile, caFile string) (*tls.Config, error) {
+	config := &tls.Config{MinVersion: tls.VersionTLS12}
+	if certFile != "" || keyFile != "" {
+		cert, err := tls.LoadX509KeyPair(certFile, keyFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load client certificate: %w", err)
+		}
+		config.Certificates = []tls.Certificate{cert}
+	}
+	if caFile != "" {
+		pem, err := os.ReadFile(caFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read CA file: %w", err)
+		}
+		config.RootCAs = x509.NewCertPool()
+		if !config.RootCAs.AppendCertsFromPEM(pem) {
+			return nil, fmt.Errorf("no certificates found in %s", caFile)
+		}
+	}
+	return config, nil
+}
+
 func (fc *FacilitatorClient) Verify(req *types.VerifyRequest) (*types.VerifyResponse, error) {
-	// Build verify endpoint url
-	url := fmt.Sprintf("%s/verify", fc.facilitatorURL)
+	return fc.VerifyContext(context.Background(), req)
+}
+
+// VerifyContext is Verify, abandoning the request when ctx is done
+func (fc *FacilitatorClient) VerifyContext(ctx context.Context, req *types.VerifyRequest) (*types.VerifyResponse, error) {
+	return fc.verify(ctx, fmt.Sprintf("%s/verify", fc.facilitatorURL), req)
+}
 
+// VerifyReport runs every verification check instead of stopping at the
+// first failure. All failed checks are returned in the response's Failures.
+func (fc *FacilitatorClient) VerifyReport(req *types.VerifyRequest) (*types.VerifyResponse, error) {
+	return fc.verify(context.Background(), fmt.Sprintf("%s/verify?report=full", fc.facilitatorURL), req)
+}
+
+func (fc *FacilitatorClient) verify(ctx context.Context, url string, req *types.VerifyRequest) (*types.VerifyResponse, error) {
 	// Encode request
 	body, err := json.Marshal(req)
 	if err != nil {
@@ -32,7 +140,7 @@ func (fc *FacilitatorClient) Verify(req *types.VerifyRequest) (*types.VerifyResp
 	}
 
 	// Make request to facilitator
-	resp, err := fc.httpClient.Post(url, "application/json", bytes.NewBuffer(body))
+	resp, err := fc.post(ctx, url, body)
 	if err != nil {
 		return nil, fmt.Errorf("failed to send request: %w", err)
 	}
@@ -40,7 +148,7 @@ func (fc *FacilitatorClient) Verify(req *types.VerifyRequest) (*types.VerifyResp
 
 	// Check response
 	if resp.StatusCode != http.StatusOK {
-		return nil, fmt.Errorf("unexpected status code: %d", resp.StatusCode)
+		return nil, &StatusError{StatusCode: resp.StatusCode}
 	}
 
 	// Decode response
@@ -52,7 +160,46 @@ func (fc *FacilitatorClient) Verify(req *types.VerifyRequest) (*types.VerifyResp
 	return &verifyResp, nil
 }
 
+// Simulate runs only the settlement simulation of a payment and returns the
+// decoded revert reason if it would fail
+func (fc *FacilitatorClient) Simulate(req *types.VerifyRequest) (*types.SimulateResponse, error) {
+	// Build simulate endpoint url
+	url := fmt.Sprintf("%s/simulate", fc.facilitatorURL)
+
+	// Encode request
+	body, err := json.Marshal(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal request: %w", err)
+	}
+
+	// Make request to facilitator
+	resp, err := fc.httpClient.Post(url, "application/json", bytes.NewBuffer(body))
+	if err != nil {
+		return nil, fmt.Errorf("failed to send request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	// Check response
+	if resp.StatusCode != http.StatusOK {
+		return nil, &StatusError{StatusCode: resp.StatusCode}
+	}
+
+	// Decode response
+	var simulateResp types.SimulateResponse
+	if err := json.NewDecoder(resp.Body).Decode(&simulateResp); err != nil {
+		return nil, fmt.Errorf("failed to decode response: %w", err)
+	}
+
+	return &simulateResp, nil
+}
+
 func (fc *FacilitatorClient) Settle(req *types.SettleRequest) (*types.SettleResponse, error) {
+	return fc.SettleContext(context.Background(), req)
+}
+
+// SettleContext is Settle, abandoning the request when ctx is done. The
+// facilitator may still settle a payment whose request was abandoned.
+func (fc *FacilitatorClient) SettleContext(ctx context.Context, req *types.SettleRequest) (*types.SettleResponse, error) {
 	// Build settle endpoint url
 	url := fmt.Sprintf("%s/settle", fc.facilitatorURL)
 
@@ -63,7 +210,7 @@ func (fc *FacilitatorClient) Settle(req *types.SettleRequest) (*types.SettleResp
 	}
 
 	// Make request to facilitator
-	resp, err := fc.httpClient.Post(url, "application/json", bytes.NewBuffer(body))
+	resp, err := fc.post(ctx, url, body)
 	if err != nil {
 		return nil, fmt.Errorf("failed to send request: %w", err)
 	}
@@ -71,7 +218,7 @@ func (fc *FacilitatorClient) Settle(req *types.SettleRequest) (*types.SettleResp
 
 	// Check response
 	if resp.StatusCode != http.StatusOK {
-		return nil, fmt.Errorf("unexpected status code: %d", resp.StatusCode)
+		return nil, &StatusError{StatusCode: resp.StatusCode}
 	}
 
 	// Decode response
@@ -83,6 +230,108 @@ func (fc *FacilitatorClient) Settle(req *types.SettleRequest) (*types.SettleResp
 	return &settleResp, nil
 }
 
+// post sends a JSON body to url, abandoning the request when ctx is done
+func (fc *FacilitatorClient) post(ctx context.Context, url string, body []byte) (*http.Response, error) {
+	httpReq, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	httpReq.Header.Set("Content-Type", "application/json")
+	return fc.httpClient.Do(httpReq)
+}
+
+// SettleBatch settles several payments at once. The facilitator must have
+// batch settlement enabled.
+func (fc *FacilitatorClient) SettleBatch(req *types.BatchSettleRequest) (*types.BatchSettleResponse, error) {
+	// Build batch settle endpoint url
+	url := fmt.Sprintf("%s/settle/batch", fc.facilitatorURL)
+
+	// Encode request
+	body, err := json.Marshal(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal request: %w", err)
+	}
+
+	// Make request to facilitator
+	resp, err := fc.httpClient.Post(url, "application/json", bytes.NewBuffer(body))
+	if err != nil {
+		return nil, fmt.Errorf("failed to send request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	// Check response
+	if resp.StatusCode != http.StatusOK {
+		return nil, &StatusError{StatusCode: resp.StatusCode}
+	}
+
+	// Decode response
+	var batchResp types.BatchSettleResponse
+	if err := json.NewDecoder(resp.Body).Decode(&batchResp); err != nil {
+		return nil, fmt.Errorf("failed to decode response: %w", err)
+	}
+
+	return &batchResp, nil
+}
+
+// SettleAsync queues a settlement and returns the pending job immediately.
+// Poll SettleJob until its status is confirmed or failed.
+func (fc *FacilitatorClient) SettleAsync(req *types.SettleRequest) (*types.SettleJob, error) {
+	// Build settle endpoint url
+	url := fmt.Sprintf("%s/settle?async=true", fc.facilitatorURL)
+
+	// Encode request
+	body, err := json.Marshal(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal request: %w", err)
+	}
+
+	// Make request to facilitator
+	resp, err := fc.httpClient.Post(url, "application/json", bytes.NewBuffer(body))
+	if err != nil {
+		return nil, fmt.Errorf("failed to send request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	// Check response
+	if resp.StatusCode != http.StatusAccepted {
+		return nil, &StatusError{StatusCode: resp.StatusCode}
+	}
+
+	// Decode response
+	var job types.SettleJob
+	if err := json.NewDecoder(resp.Body).Decode(&job); err != nil {
+		return nil, fmt.Errorf("failed to decode response: %w", err)
+	}
+
+	return &job, nil
+}
+
+// SettleJob fetches the status of an asynchronous settlement
+func (fc *FacilitatorClient) SettleJob(jobID string) (*types.SettleJob, error) {
+	// Build job endpoint url
+	url := fmt.Sprintf("%s/settle/%s", fc.facilitatorURL, jobID)
+
+	// Make request to facilitator
+	resp, err := fc.httpClient.Get(url)
+	if err != nil {
+		return nil, fmt.Errorf("failed to send request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	// Check response
+	if resp.StatusCode != http.StatusOK {
+		return nil, &StatusError{StatusCode: resp.StatusCode}
+	}
+
+	// Decode response
+	var job types.SettleJob
+	if err := json.NewDecoder(resp.Body).Decode(&job); err != nil {
+		return nil, fmt.Errorf("failed to decode response: %w", err)
+	}
+
+	return &job, nil
+}
+
 func (fc *FacilitatorClient) Supported() (*types.SupportedResponse, error) {
 	// Build supported endpoint url
 	url := fmt.Sprintf("%s/supported", fc.facilitatorURL)
@@ -96,7 +345,7 @@ func (fc *FacilitatorClient) Supported() (*types.SupportedResponse, error) {
 
 	// Check response
 	if resp.StatusCode != http.StatusOK {
-		return nil, fmt.Errorf("unexpected status code: %d", resp.StatusCode)
+		return nil, &StatusError{StatusCode: resp.StatusCode}
 	}
 
 	// Decode response
@@ -107,3 +356,160 @@ func (fc *FacilitatorClient) Supported() (*types.SupportedResponse, error) {
 
 	return &supportedResp, nil
 }
+
+// Version fetches the build and protocol version of the facilitator
+func (fc *FacilitatorClient) Version() (*types.VersionInfo, error) {
+	// Build version endpoint url
+	url := fmt.Sprintf("%s/version", fc.facilitatorURL)
+
+	// Make request to facilitator
+	resp, err := fc.httpClient.Get(url)
+	if err != nil {
+		return nil, fmt.Errorf("failed to send request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	// Check response
+	if resp.StatusCode != http.StatusOK {
+		return nil, &StatusError{StatusCode: resp.StatusCode}
+	}
+
+	// Decode response
+	var versionResp types.VersionInfo
+	if err := json.NewDecoder(resp.Body).Decode(&versionResp); err != nil {
+		return nil, fmt.Errorf("failed to decode response: %w", err)
+	}
+
+	return &versionResp, nil
+}
+
+func (fc *FacilitatorClient) StatementChallenge(payer string) (*types.StatementChallengeResponse, error) {
+	// Build challenge endpoint url
+	url := fmt.Sprintf("%s/statements/challenge?payer=%s", fc.facilitatorURL, payer)
+
+	// Make request to facilitator
+	resp, err := fc.httpClient.Get(url)
+	if err != nil {
+		return nil, fmt.Errorf("failed to send request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	// Check response
+	if resp.StatusCode != http.StatusOK {
+		return nil, &StatusError{StatusCode: resp.StatusCode}
+	}
+
+	// Decode response
+	var challengeResp types.StatementChallengeResponse
+	if err := json.NewDecoder(resp.Body).Decode(&challengeResp); err != nil {
+		return nil, fmt.Errorf("failed to decode response: %w", err)
+	}
+
+	return &challengeResp, nil
+}
+
+func (fc *FacilitatorClient) Statement(req *types.StatementRequest) (*types.Statement, error) {
+	// Build statements endpoint url
+	url := fmt.Sprintf("%s/statements", fc.facilitatorURL)
+
+	// Encode request
+	body, err := json.Marshal(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal request: %w", err)
+	}
+
+	// Make request to facilitator
+	resp, err := fc.httpClient.Post(url, "application/json", bytes.NewBuffer(body))
+	if err != nil {
+		return nil, fmt.Errorf("failed to send request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	// Check response
+	if resp.StatusCode != http.StatusOK {
+		return nil, &StatusError{StatusCode: resp.StatusCode}
+	}
+
+	// Decode response
+	var statement types.Statement
+	if err := json.NewDecoder(resp.Body).Decode(&statement); err != nil {
+		return nil, fmt.Errorf("failed to decode response: %w", err)
+	}
+
+	return &statement, nil
+}
+
+// OpenStream opens a payment stream capped at the requirements' amount. The
+// facilitator must have payment streams enabled.
+func (fc *FacilitatorClient) OpenStream(req *types.StreamOpenRequest) (*types.PaymentStream, error) {
+	return fc.postStream(fmt.Sprintf("%s/streams", fc.facilitatorURL), req, http.StatusCreated)
+}
+
+// AdvanceStream adds an increment, signed as an exact scheme authorization of
+// its value, to a payment stream
+func (fc *FacilitatorClient) AdvanceStream(streamID string, req *types.StreamAdvanceRequest) (*types.PaymentStream, error) {
+	return fc.postStream(fmt.Sprintf("%s/streams/%s/advance", fc.facilitatorURL, streamID), req, http.StatusOK)
+}
+
+// CloseStream closes a payment stream and settles its remaining increments
+func (fc *FacilitatorClient) CloseStream(streamID string) (*types.PaymentStream, error) {
+	return fc.postStream(fmt.Sprintf("%s/streams/%s/close", fc.facilitatorURL, streamID), struct{}{}, http.StatusOK)
+}
+
+// Stream fetches the state of a payment stream
+func (fc *FacilitatorClient) Stream(streamID string) (*types.PaymentStream, error) {
+	// Build stream endpoint url
+	url := fmt.Sprintf("%s/streams/%s", fc.facilitatorURL, streamID)
+
+	// Make request to facilitator
+	resp, err := fc.httpClient.Get(url)
+	if err != nil {
+		return nil, fmt.Errorf("failed to send request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	// Check response
+	if resp.StatusCode != http.StatusOK {
+		return nil, &StatusError{StatusCode: resp.StatusCode}
+	}
+
+	// Decode response
+	var stream types.PaymentStream
+	if err := json.NewDecoder(resp.Body).Decode(&stream); err != nil {
+		return nil, fmt.Errorf("failed to decode response: %w", err)
+	}
+
+	return &stream, nil
+}
+
+func (fc *FacilitatorClient) postStream(url string, req any, expectedStatus int) (*types.PaymentStream, error) {
+	// Encode request
+	body, err := json.Marshal(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal request: %w", err)
+	}
+
+	// Make request to facilitator
+	resp, err := fc.httpClient.Post(url, "application/json", bytes.NewBuffer(body))
+	if err != nil {
+		return nil, fmt.Errorf("failed to send request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	// Check response
+	if resp.StatusCode != expectedStatus {
+		var errResp struct {
+			Error string `json:"error"`
+		}
+		json.NewDecoder(resp.Body).Decode(&errResp)
+		return nil, &StatusError{StatusCode: resp.StatusCode, Message: errResp.Error}
+	}
+
+	// Decode response
+	var stream types.PaymentStream
+	if err := json.NewDecoder(resp.Body).Decode(&stream); err != nil {
+		return nil, fmt.Errorf("failed to decode response: %w", err)
+	}
+
+	return &stream, nil
+}
@@ -2,6 +2,7 @@ package client
 
 import (
 	"encoding/json"
+	"errors"
 	"net/http"
 	"net/http/httptest"
 	"testing"
@@ -189,6 +190,52 @@ func TestSettle(t *testing.T) {
 			t.Errorf("Expected Error='transaction reverted', got '%s'", resp.ErrorReason)
 		}
 	})
+
+	t.Run("unexpected status", func(t *testing.T) {
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusBadGateway)
+		}))
+		defer server.Close()
+
+		fc := NewFacilitatorClient(server.URL)
+		_, err := fc.Settle(&types.SettleRequest{})
+
+		var statusErr *StatusError
+		if !errors.As(err, &statusErr) {
+			t.Fatalf("Expected StatusError, got %v", err)
+		}
+		if statusErr.StatusCode != http.StatusBadGateway || !statusErr.Temporary() {
+			t.Errorf("Expected temporary 502, got %+v", statusErr)
+		}
+	})
+}
+
+func TestSettleBatch(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/settle/batch" {
+			t.Errorf("Expected /settle/batch path, got %s", r.URL.Path)
+		}
+
+		// Return one result per item
+		var req types.BatchSettleRequest
+		json.NewDecoder(r.Body).Decode(&req)
+		resp := types.BatchSettleResponse{}
+		for range req.Items {
+			resp.Results = append(resp.Results, types.SettleResponse{Success: true, Transaction: "0xabc123"})
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(resp)
+	}))
+	defer server.Close()
+
+	fc := NewFacilitatorClient(server.URL)
+	resp, err := fc.SettleBatch(&types.BatchSettleRequest{Items: make([]types.SettleRequest, 2)})
+	if err != nil {
+		t.Fatalf("SettleBatch failed: %v", err)
+	}
+	if len(resp.Results) != 2 || resp.Results[1].Transaction != "0xabc123" {
+		t.Errorf("Expected 2 results sharing a transaction, got %+v", resp.Results)
+	}
 }
 
 func TestSupported(t *testing.T) {
@@ -253,3 +300,22 @@ func TestSupported(t *testing.T) {
 		}
 	})
 }
+
+func TestRequestHeaders(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("X-API-Key") != "secret" {
+			t.Errorf("Expected API key header, got %q", r.Header.Get("X-API-Key"))
+		}
+		if r.Header.Get("User-Agent") == "" {
+			t.Error("Expected User-Agent to still be set")
+		}
+		json.NewEncoder(w).Encode(types.SupportedResponse{})
+	}))
+	defer server.Close()
+
+	fc := NewFacilitatorClient(server.URL)
+	fc.SetRequestHeaders(http.Header{"X-Api-Key": []string{"secret"}})
+	if _, err := fc.Supported(); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+}
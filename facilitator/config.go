@@ -1,49 +1,376 @@
 package facilitator
 
 import (
+	"context"
 	"crypto/ecdsa"
+	"crypto/ed25519"
+	"encoding/json"
 	"fmt"
+	"math/big"
+	"net"
 	"os"
+	"path/filepath"
 	"strings"
 
+	"github.com/ethereum/go-ethereum/accounts/keystore"
 	"github.com/ethereum/go-ethereum/common"
 	"github.com/ethereum/go-ethereum/crypto"
 	"github.com/vorpalengineering/x402-go/types"
+	"github.com/vorpalengineering/x402-go/utils"
+	"github.com/vorpalengineering/x402-go/webhook"
 	"gopkg.in/yaml.v3"
 )
 
+const (
+	defaultPrivateKeyEnv = "X402_FACILITATOR_PRIVATE_KEY"
+	defaultPassphraseEnv = "X402_FACILITATOR_KEYSTORE_PASSPHRASE"
+	defaultAdminTokenEnv = "X402_FACILITATOR_ADMIN_TOKEN"
+	defaultSolanaKeyEnv  = "X402_FACILITATOR_SOLANA_PRIVATE_KEY"
+)
+
 type FacilitatorConfig struct {
 	Server      ServerConfig             `yaml:"server"`
 	Networks    map[string]NetworkConfig `yaml:"networks"`
 	Supported   []types.SupportedKind    `yaml:"supported"`
 	Transaction TransactionConfig        `yaml:"transaction"`
 	Log         LogConfig                `yaml:"log"`
-	Signer      SignerConfig             `yaml:"-"`
+	Capture     CaptureConfig            `yaml:"capture"`
+	Statements  StatementsConfig         `yaml:"statements"`
+	Alerts      AlertsConfig             `yaml:"alerts"`
+	Events      EventsConfig             `yaml:"events"`
+	SettleJobs  SettleJobsConfig         `yaml:"settle_jobs"`
+	SettlePool  SettlePoolConfig         `yaml:"settle_pool"`
+	Quotas      QuotasConfig             `yaml:"quotas"`
+	Admin       AdminConfig              `yaml:"admin"`
+	Batch       BatchConfig              `yaml:"batch"`
+	Streams     StreamsConfig            `yaml:"streams"`
+	Tokens      TokensConfig             `yaml:"tokens"`
+	Fees        FeesConfig               `yaml:"fees"`
+	Audit       AuditConfig              `yaml:"audit"`
+	Signer      SignerConfig             `yaml:"signer"`
+	Solana      SolanaConfig             `yaml:"solana"`
+	Redis       RedisConfig              `yaml:"redis"`
+	Deferred    DeferredConfig           `yaml:"deferred"`
+	Refunds     RefundsConfig            `yaml:"refunds"`
+
+	// ChainIDCheck is what happens when an RPC endpoint reports another chain
+	// than its network key: "fail" (default), "warn" or "off"
+	ChainIDCheck string `yaml:"chain_id_check"`
 }
 
 type ServerConfig struct {
-	Host string `yaml:"host"`
-	Port int    `yaml:"port"`
+	Host string    `yaml:"host"`
+	Port int       `yaml:"port"`
+	TLS  TLSConfig `yaml:"tls"`
+	// TrustedProxies are the IPs or CIDRs whose X-Forwarded-For and
+	// X-Real-IP headers give the client IP. None are trusted by default.
+	TrustedProxies []string `yaml:"trusted_proxies"`
 }
 
 type NetworkConfig struct {
 	RpcUrl string `yaml:"rpc_url"`
+	// RpcUrls are fallback endpoints tried in order when rpc_url fails
+	RpcUrls  []string             `yaml:"rpc_urls"`
+	Failover FailoverConfig       `yaml:"failover"`
+	Gas      GasConfig            `yaml:"gas"`
+	AssetGas map[string]GasConfig `yaml:"asset_gas"`
+
+	// Signer is a hot wallet for this network only, the top-level signer is used if unset
+	Signer *SignerConfig `yaml:"signer"`
+
+	// Multicall is the Multicall3 contract batch settlements are aggregated
+	// through (default 0xcA11bde05977b3631167028862bE2a173976CA11)
+	Multicall string `yaml:"multicall"`
+
+	// SettleWorkers overrides settle_pool.workers for this network
+	SettleWorkers int `yaml:"settle_workers"`
+}
+
+// FailoverConfig tunes switching between the RPC endpoints of a network.
+// Zero values use the defaults.
+type FailoverConfig struct {
+	// FailureThreshold is the number of consecutive failures that takes an endpoint out of rotation
+	FailureThreshold int `yaml:"failure_threshold"`
+
+	// CooldownSeconds is how long an endpoint stays out before it is tried again
+	CooldownSeconds int `yaml:"cooldown_seconds"`
+
+	// HealthCheckSeconds is the interval of background eth_blockNumber checks, 0 disables them
+	HealthCheckSeconds int `yaml:"health_check_seconds"`
+}
+
+// GasConfig controls how the gas limit of a settlement transaction is chosen.
+// Zero values leave the corresponding behaviour disabled.
+type GasConfig struct {
+	// LimitOverride skips estimation and uses this gas limit as-is
+	LimitOverride uint64 `yaml:"limit_override"`
+
+	// FallbackLimit is used when estimation fails for a reason other than a revert
+	FallbackLimit uint64 `yaml:"fallback_limit"`
+
+	// Multiplier scales successful estimates (e.g. 1.2 for a 20% buffer)
+	Multiplier float64 `yaml:"multiplier"`
+
+	// Optimize picks the cheaper transferWithAuthorization overload and
+	// attaches an EIP-2930 access list when it lowers the estimate
+	Optimize bool `yaml:"optimize"`
 }
 
 type TransactionConfig struct {
 	TimeoutSeconds int    `yaml:"timeout_seconds"`
 	MaxGasPrice    string `yaml:"max_gas_price"`
+	// Confirmations to wait for before reporting success, 0 returns once sent
+	Confirmations int `yaml:"confirmations"`
+	// Retry transient failures before the transaction is broadcast
+	Retry RetryConfig `yaml:"retry"`
+	// ReorgWatchBlocks keeps watching exact scheme settlements for this many
+	// blocks for reorganizations, 0 disables
+	ReorgWatchBlocks int `yaml:"reorg_watch_blocks"`
+	// Replace settlement transactions stuck in the mempool with higher fees
+	Replacement ReplacementConfig `yaml:"replacement"`
+	// Look for competing transactions using the same authorization before
+	// settling
+	MempoolCheck MempoolCheckConfig `yaml:"mempool_check"`
 }
 
 type LogConfig struct {
 	Level string `yaml:"level"`
+	// Format is "text" (default) or "json"
+	Format string `yaml:"format"`
+}
+
+type CaptureConfig struct {
+	Enabled bool   `yaml:"enabled"`
+	Dir     string `yaml:"dir"`
+}
+
+type StatementsConfig struct {
+	Enabled             bool `yaml:"enabled"`
+	ChallengeTTLSeconds int  `yaml:"challenge_ttl_seconds"`
+	// Days settled payments are kept for statements and refunds (default 400)
+	RetentionDays int `yaml:"retention_days"`
+	// Settled payments kept at most, the oldest are dropped first (default 1000000)
+	MaxRecords int `yaml:"max_records"`
+}
+
+type AlertsConfig struct {
+	Enabled bool `yaml:"enabled"`
+	// Minimum time between repeats of the same alert (default 300)
+	MinIntervalSeconds int `yaml:"min_interval_seconds"`
+	// How often RPC, balance and nonce checks run (default 60)
+	CheckIntervalSeconds int `yaml:"check_interval_seconds"`
+	// Minimum native signer balance in wei per network
+	MinSignerBalance map[string]string `yaml:"min_signer_balance"`
+	// Alert when this many settlements fail within the window (0 disables)
+	SettlementFailureThreshold     int `yaml:"settlement_failure_threshold"`
+	SettlementFailureWindowSeconds int `yaml:"settlement_failure_window_seconds"`
+	// Alert when this many signer transactions are pending (0 disables)
+	MaxNonceGap uint64 `yaml:"max_nonce_gap"`
+
+	Slack     []SlackAlertConfig     `yaml:"slack"`
+	PagerDuty []PagerDutyAlertConfig `yaml:"pagerduty"`
+	Webhooks  []WebhookAlertConfig   `yaml:"webhooks"`
+}
+
+type SlackAlertConfig struct {
+	WebhookURL  string `yaml:"webhook_url"`
+	MinSeverity string `yaml:"min_severity"`
+}
+
+type PagerDutyAlertConfig struct {
+	RoutingKey  string `yaml:"routing_key"`
+	MinSeverity string `yaml:"min_severity"`
+	// Events API URL, defaults to https://events.pagerduty.com/v2/enqueue
+	URL string `yaml:"url"`
+}
+
+type WebhookAlertConfig struct {
+	webhook.Endpoint `yaml:",inline"`
+	MinSeverity      string `yaml:"min_severity"`
+}
+
+type EventsConfig struct {
+	// Subject/topic prefix, events go to "<prefix>.<type>" (default "x402")
+	SubjectPrefix string `yaml:"subject_prefix"`
+	// Events buffered while the publisher is slow (default 1024)
+	BufferSize int `yaml:"buffer_size"`
+	// NATS publishes events to a NATS server
+	NATS NATSConfig `yaml:"nats"`
+	// SSE streams events at GET /events
+	SSE SSEConfig `yaml:"sse"`
+}
+
+type SettleJobsConfig struct {
+	// Workers submitting asynchronous settlements (default 4)
+	Workers int `yaml:"workers"`
+	// Jobs waiting for a worker before /settle?async=true returns 503 (default 256)
+	QueueSize int `yaml:"queue_size"`
+	// How long finished jobs can be polled (default 3600)
+	RetentionSeconds int `yaml:"retention_seconds"`
+	// JournalDir persists accepted settlements until they finish, and the
+	// ones a crash interrupted are settled again on the next start
+	JournalDir string `yaml:"journal_dir"`
+}
+
+// Chain ID check modes
+const (
+	ChainIDCheckFail = "fail"
+	ChainIDCheckWarn = "warn"
+	ChainIDCheckOff  = "off"
+)
+
+// Batch settlement modes
+const (
+	BatchModeMulticall  = "multicall"
+	BatchModeSequential = "sequential"
+)
+
+// BatchConfig enables POST /settle/batch
+type BatchConfig struct {
+	Enabled bool `yaml:"enabled"`
+	// Most settlements accepted in one batch (default 50)
+	MaxSize int `yaml:"max_size"`
+	// "multicall" aggregates each network's settlements into one transaction
+	// (default), "sequential" sends one transaction per settlement
+	Mode string `yaml:"mode"`
+}
+
+// StreamsConfig enables payment streams under /streams
+type StreamsConfig struct {
+	Enabled bool `yaml:"enabled"`
+	// Seconds between settlements of an open stream's running total (default 60)
+	SettleIntervalSeconds int `yaml:"settle_interval_seconds"`
+	// Open streams without an increment for this long are closed (default 600)
+	IdleTimeoutSeconds int `yaml:"idle_timeout_seconds"`
+	// How long closed streams can be polled (default 3600)
+	RetentionSeconds int `yaml:"retention_seconds"`
+}
+
+// TokensConfig tunes the token metadata read from asset contracts
+type TokensConfig struct {
+	// Seconds token names, versions and decimals are cached (default 3600)
+	CacheTTLSeconds int `yaml:"cache_ttl_seconds"`
 }
 
+// QuotasConfig rate limits clients of the facilitator. Clients listed under
+// Clients are identified by the API key in KeyHeader or by source IP, anyone
+// else by source IP with the Default limits.
+type QuotasConfig struct {
+	Enabled bool `yaml:"enabled"`
+	// Header carrying the client's API key (default X-API-Key)
+	KeyHeader string                 `yaml:"key_header"`
+	Default   QuotaLimits            `yaml:"default"`
+	Clients   map[string]ClientQuota `yaml:"clients"`
+}
+
+// QuotaLimits are the limits applied to one client. Zero values are unlimited.
+type QuotaLimits struct {
+	// RequestsPerSecond across all endpoints, with bursts up to Burst
+	RequestsPerSecond float64 `yaml:"requests_per_second"`
+	Burst             int     `yaml:"burst"`
+	// SettlementsPerHour counts settlements per clock hour
+	SettlementsPerHour int `yaml:"settlements_per_hour"`
+	// ValuePerDay caps the amount settled per UTC day, keyed by asset address, in base units
+	ValuePerDay map[string]string `yaml:"value_per_day"`
+}
+
+// ClientQuota overrides the default limits for one API key or IP address.
+// Non-zero fields take precedence over the defaults.
+type ClientQuota struct {
+	// Name identifies the client in metrics and logs instead of its key
+	Name        string `yaml:"name"`
+	QuotaLimits `yaml:",inline"`
+}
+
+// AdminConfig enables the /admin routes, authenticated with a bearer token
+type AdminConfig struct {
+	Enabled bool `yaml:"enabled"`
+
+	// TokenEnv names the environment variable holding the token,
+	// X402_FACILITATOR_ADMIN_TOKEN by default
+	TokenEnv string `yaml:"token_env"`
+
+	// RecentErrors is how many warnings and errors /admin/errors keeps (default 100)
+	RecentErrors int `yaml:"recent_errors"`
+
+	Token string `yaml:"-"`
+}
+
+// SignerConfig holds a settlement key. The top-level key is read from
+// X402_FACILITATOR_PRIVATE_KEY unless PrivateKeyEnv, KeystoreFile, KeyFile or
+// KMS is set.
 type SignerConfig struct {
-	Address    common.Address    `yaml:"address"`
+	// PrivateKeyEnv names the environment variable holding a hex private key
+	PrivateKeyEnv string `yaml:"private_key_env"`
+
+	// KeystoreFile is an encrypted go-ethereum JSON keystore holding the key
+	KeystoreFile string `yaml:"keystore_file"`
+
+	// KeyFile is a hex private key encrypted with a passphrase, by age
+	// (`age -p`) or with AES-GCM by `facilitator -encrypt-key`
+	KeyFile string `yaml:"key_file"`
+
+	// PassphraseEnv names the environment variable holding the keystore or
+	// key file passphrase, X402_FACILITATOR_KEYSTORE_PASSPHRASE by default.
+	// PassphrasePrompt asks for it when the variable is unset.
+	PassphraseEnv string `yaml:"passphrase_env"`
+
+	// KMS signs with a key held in AWS KMS instead of a local key
+	KMS KMSConfig `yaml:"kms"`
+
+	Address    common.Address    `yaml:"-"`
 	PrivateKey *ecdsa.PrivateKey `yaml:"-"`
+
+	// Backend signs instead of PrivateKey when set. LoadConfig sets it for KMS,
+	// services can set their own Signer.
+	Backend Signer `yaml:"-"`
+
+	// ownsKey is set when load read PrivateKey, so Close may zero it
+	ownsKey bool
+	// noPrompt requires the passphrase from the environment
+	noPrompt bool
+}
+
+// AuditConfig enables the append-only log of verify and settle decisions
+type AuditConfig struct {
+	Enabled bool `yaml:"enabled"`
+	// File the hash-chained records are appended to, as JSON lines
+	File string `yaml:"file"`
 }
 
+// FeesConfig controls collection of the fees set per supported pair under fee
+type FeesConfig struct {
+	// Sweep moves each fee from payTo to Recipient with a second transfer once
+	// the payment is mined. payTo must approve the network's signer to spend the asset.
+	Sweep bool `yaml:"sweep"`
+
+	// Recipient receives swept fees
+	Recipient string `yaml:"recipient"`
+}
+
+// SolanaConfig holds the fee payer of solana:* networks, which completes and
+// pays for the transactions of exact scheme payments
+type SolanaConfig struct {
+	// PrivateKeyEnv names the environment variable holding the fee payer key,
+	// base58 or a solana-keygen JSON byte array, X402_FACILITATOR_SOLANA_PRIVATE_KEY by default
+	PrivateKeyEnv string `yaml:"private_key_env"`
+
+	// MaxComputeUnitLimit caps the compute units a payment may request (default 200000)
+	MaxComputeUnitLimit uint32 `yaml:"max_compute_unit_limit"`
+
+	// MaxComputeUnitPrice caps the priority fee in micro-lamports per compute
+	// unit a payment may request (default 5000000)
+	MaxComputeUnitPrice uint64 `yaml:"max_compute_unit_price"`
+
+	PrivateKey ed25519.PrivateKey `yaml:"-"`
+
+	// ownsKey is set when LoadConfig read PrivateKey, so Close may zero it
+	ownsKey bool
+}
+
+// LoadConfig reads a YAML config file, or JSON if the path ends in .json, then
+// applies X402_* environment variable overrides (see applyEnvOverrides).
+// ${VAR} in a value is replaced by that environment variable.
 func LoadConfig(configPath string) (*FacilitatorConfig, error) {
 	// Read config file
 	data, err := os.ReadFile(configPath)
@@ -51,26 +378,49 @@ func LoadConfig(configPath string) (*FacilitatorConfig, error) {
 		return nil, fmt.Errorf("failed to read config file: %w", err)
 	}
 
-	// Parse YAML
+	// JSON uses the same keys as YAML and is decoded the same way once its
+	// syntax is checked, so errors point at the offending offset
+	if strings.EqualFold(filepath.Ext(configPath), ".json") {
+		var syntax any
+		if err := json.Unmarshal(data, &syntax); err != nil {
+			return nil, fmt.Errorf("failed to parse config: %w", err)
+		}
+	}
+
+	// Parse YAML, expanding ${VAR} references in values
+	var document yaml.Node
+	if err := yaml.Unmarshal(data, &document); err != nil {
+		return nil, fmt.Errorf("failed to parse config: %w", err)
+	}
+	if err := interpolateEnv(&document); err != nil {
+		return nil, fmt.Errorf("failed to parse config: %w", err)
+	}
 	var facilitatorConfig FacilitatorConfig
-	if err := yaml.Unmarshal(data, &facilitatorConfig); err != nil {
+	if err := document.Decode(&facilitatorConfig); err != nil {
 		return nil, fmt.Errorf("failed to parse config: %w", err)
 	}
 
+	// Apply environment variable overrides
+	if err := applyEnvOverrides(&facilitatorConfig, os.Environ()); err != nil {
+		return nil, fmt.Errorf("failed to load env config: %w", err)
+	}
+
+	return finishLoadConfig(&facilitatorConfig)
+}
+
+// finishLoadConfig loads secrets and validates a parsed config
+func finishLoadConfig(facilitatorConfig *FacilitatorConfig) (*FacilitatorConfig, error) {
 	// Load secrets from environment variables
-	if err := loadEnvVars(&facilitatorConfig); err != nil {
+	if err := loadEnvVars(facilitatorConfig); err != nil {
 		return nil, fmt.Errorf("failed to load env vars: %w", err)
 	}
 
-	// Derive signer address
-	facilitatorConfig.Signer.Address = crypto.PubkeyToAddress(facilitatorConfig.Signer.PrivateKey.PublicKey)
-
 	// Validate config
 	if err := facilitatorConfig.Validate(); err != nil {
 		return nil, fmt.Errorf("invalid config: %w", err)
 	}
 
-	return &facilitatorConfig, nil
+	return facilitatorConfig, nil
 }
 
 func (config *FacilitatorConfig) GetNetworkConfig(network string) (NetworkConfig, error) {
@@ -81,6 +431,53 @@ func (config *FacilitatorConfig) GetNetworkConfig(network string) (NetworkConfig
 	return networkConfig, nil
 }
 
+// GetRpcUrls returns rpc_url followed by the fallback rpc_urls, without duplicates
+func (networkConfig NetworkConfig) GetRpcUrls() []string {
+	urls := make([]string, 0, len(networkConfig.RpcUrls)+1)
+	seen := make(map[string]bool)
+	for _, rpcURL := range append([]string{networkConfig.RpcUrl}, networkConfig.RpcUrls...) {
+		if rpcURL == "" || seen[rpcURL] {
+			continue
+		}
+		seen[rpcURL] = true
+		urls = append(urls, rpcURL)
+	}
+	return urls
+}
+
+// GetMulticallAddress returns the Multicall3 contract used for batch settlements
+func (networkConfig NetworkConfig) GetMulticallAddress() common.Address {
+	if networkConfig.Multicall != "" {
+		return common.HexToAddress(networkConfig.Multicall)
+	}
+	return common.HexToAddress(utils.Multicall3Address)
+}
+
+// GetGasConfig returns the gas settings for an asset on this network.
+// Non-zero fields from the asset entry take precedence over the network defaults.
+func (networkConfig NetworkConfig) GetGasConfig(asset string) GasConfig {
+	gasCfg := networkConfig.Gas
+	for addr, assetCfg := range networkConfig.AssetGas {
+		if !strings.EqualFold(addr, asset) {
+			continue
+		}
+		if assetCfg.LimitOverride != 0 {
+			gasCfg.LimitOverride = assetCfg.LimitOverride
+		}
+		if assetCfg.FallbackLimit != 0 {
+			gasCfg.FallbackLimit = assetCfg.FallbackLimit
+		}
+		if assetCfg.Multiplier != 0 {
+			gasCfg.Multiplier = assetCfg.Multiplier
+		}
+		if assetCfg.Optimize {
+			gasCfg.Optimize = true
+		}
+		break
+	}
+	return gasCfg
+}
+
 func (config *FacilitatorConfig) IsSupported(scheme, network string) bool {
 	for _, s := range config.Supported {
 		if s.Scheme == scheme && s.Network == network {
@@ -90,11 +487,45 @@ func (config *FacilitatorConfig) IsSupported(scheme, network string) bool {
 	return false
 }
 
+// GetSupportedKind returns the configured scheme-network pair
+func (config *FacilitatorConfig) GetSupportedKind(scheme, network string) (types.SupportedKind, bool) {
+	for _, s := range config.Supported {
+		if s.Scheme == scheme && s.Network == network {
+			return s, true
+		}
+	}
+	return types.SupportedKind{}, false
+}
+
 func (config *FacilitatorConfig) Validate() error {
 	// Validate server config
 	if config.Server.Port <= 0 || config.Server.Port > 65535 {
 		return fmt.Errorf("invalid server port: %d (must be 1-65535)", config.Server.Port)
 	}
+	if err := config.Server.TLS.validate(); err != nil {
+		return fmt.Errorf("invalid server tls config: %w", err)
+	}
+	for _, proxy := range config.Server.TrustedProxies {
+		if _, _, err := net.ParseCIDR(proxy); err != nil && net.ParseIP(proxy) == nil {
+			return fmt.Errorf("invalid server trusted proxy %q", proxy)
+		}
+	}
+
+	// Validate quotas
+	if config.Quotas.Enabled {
+		if err := config.Quotas.Default.validate(); err != nil {
+			return fmt.Errorf("invalid default quota: %w", err)
+		}
+		for client, quota := range config.Quotas.Clients {
+			if err := quota.validate(); err != nil {
+				name := quota.Name
+				if name == "" {
+					name = maskAPIKey(client)
+				}
+				return fmt.Errorf("invalid quota for %s: %w", name, err)
+			}
+		}
+	}
 
 	// Validate networks
 	if len(config.Networks) == 0 {
@@ -102,9 +533,75 @@ func (config *FacilitatorConfig) Validate() error {
 	}
 
 	for network, netCfg := range config.Networks {
-		if netCfg.RpcUrl == "" {
+		rpcURLs := netCfg.GetRpcUrls()
+		if len(rpcURLs) == 0 {
 			return fmt.Errorf("network %s missing rpc_url", network)
 		}
+		if len(rpcURLs) > 1 {
+			for _, rpcURL := range rpcURLs {
+				if !strings.HasPrefix(rpcURL, "http://") && !strings.HasPrefix(rpcURL, "https://") {
+					return fmt.Errorf("network %s rpc url %s must be http(s) to use failover", network, rpcURL)
+				}
+			}
+		}
+		if err := netCfg.Failover.validate(); err != nil {
+			return fmt.Errorf("network %s invalid failover config: %w", network, err)
+		}
+		if err := netCfg.Gas.validate(); err != nil {
+			return fmt.Errorf("network %s invalid gas config: %w", network, err)
+		}
+		for asset, gasCfg := range netCfg.AssetGas {
+			if err := gasCfg.validate(); err != nil {
+				return fmt.Errorf("network %s invalid gas config for asset %s: %w", network, asset, err)
+			}
+		}
+		if netCfg.SettleWorkers < 0 {
+			return fmt.Errorf("network %s settle_workers cannot be negative, got %d", network, netCfg.SettleWorkers)
+		}
+	}
+	if err := config.SettlePool.validate(); err != nil {
+		return fmt.Errorf("invalid settle pool config: %w", err)
+	}
+
+	if err := config.Redis.validate(); err != nil {
+		return fmt.Errorf("invalid redis config: %w", err)
+	}
+	if err := config.Deferred.validate(); err != nil {
+		return fmt.Errorf("invalid deferred config: %w", err)
+	}
+	if err := config.Refunds.validate(); err != nil {
+		return fmt.Errorf("invalid refunds config: %w", err)
+	}
+	if config.Refunds.Enabled && !config.Admin.Enabled {
+		return fmt.Errorf("refunds require the admin API, whose token authenticates them")
+	}
+	if config.Statements.RetentionDays < 0 || config.Statements.MaxRecords < 0 {
+		return fmt.Errorf("statements retention_days and max_records cannot be negative")
+	}
+	if err := config.Events.NATS.validate(); err != nil {
+		return fmt.Errorf("invalid events nats config: %w", err)
+	}
+	if err := config.Events.SSE.validate(); err != nil {
+		return fmt.Errorf("invalid events sse config: %w", err)
+	}
+	if config.Events.SSE.RequireAdminToken && !config.Admin.Enabled {
+		return fmt.Errorf("events sse require_admin_token requires the admin API")
+	}
+
+	// Validate audit log
+	if config.Audit.Enabled && config.Audit.File == "" {
+		return fmt.Errorf("audit file must be set when audit is enabled")
+	}
+
+	// Validate fee sweeping
+	if config.Fees.Sweep && !common.IsHexAddress(config.Fees.Recipient) {
+		return fmt.Errorf("fees recipient must be an address when sweep is enabled, got %q", config.Fees.Recipient)
+	}
+
+	switch config.ChainIDCheck {
+	case "", ChainIDCheckFail, ChainIDCheckWarn, ChainIDCheckOff:
+	default:
+		return fmt.Errorf("invalid chain_id_check: %s (must be fail, warn or off)", config.ChainIDCheck)
 	}
 
 	// Validate supported schemes reference valid networks
@@ -118,6 +615,9 @@ func (config *FacilitatorConfig) Validate() error {
 		if _, exists := config.Networks[pair.Network]; !exists {
 			return fmt.Errorf("supported network %s is not defined in networks config", pair.Network)
 		}
+		if err := validateSupportedTerms(pair); err != nil {
+			return fmt.Errorf("supported %s-%s: %w", pair.Scheme, pair.Network, err)
+		}
 	}
 
 	// Validate transaction config
@@ -127,6 +627,34 @@ func (config *FacilitatorConfig) Validate() error {
 	if config.Transaction.MaxGasPrice == "" {
 		return fmt.Errorf("transaction max_gas_price must be set")
 	}
+	if config.Transaction.Confirmations < 0 {
+		return fmt.Errorf("transaction confirmations cannot be negative, got %d", config.Transaction.Confirmations)
+	}
+	if config.Transaction.ReorgWatchBlocks < 0 {
+		return fmt.Errorf("transaction reorg_watch_blocks cannot be negative, got %d", config.Transaction.ReorgWatchBlocks)
+	}
+	if err := config.Transaction.Retry.validate(); err != nil {
+		return fmt.Errorf("invalid transaction retry config: %w", err)
+	}
+	if err := config.Transaction.Replacement.validate(); err != nil {
+		return fmt.Errorf("invalid transaction replacement config: %w", err)
+	}
+	if err := config.Transaction.MempoolCheck.validate(); err != nil {
+		return fmt.Errorf("invalid transaction mempool check config: %w", err)
+	}
+
+	// Validate batch config
+	if config.Batch.MaxSize < 0 {
+		return fmt.Errorf("batch max_size cannot be negative, got %d", config.Batch.MaxSize)
+	}
+	if config.Batch.Mode != "" && config.Batch.Mode != BatchModeMulticall && config.Batch.Mode != BatchModeSequential {
+		return fmt.Errorf("invalid batch mode: %s (must be multicall or sequential)", config.Batch.Mode)
+	}
+	for network, netCfg := range config.Networks {
+		if netCfg.Multicall != "" && !common.IsHexAddress(netCfg.Multicall) {
+			return fmt.Errorf("network %s invalid multicall address: %s", network, netCfg.Multicall)
+		}
+	}
 
 	// Validate log config
 	validLogLevels := map[string]bool{
@@ -138,27 +666,350 @@ func (config *FacilitatorConfig) Validate() error {
 	if !validLogLevels[config.Log.Level] {
 		return fmt.Errorf("invalid log level: %s (must be debug, info, warn, or error)", config.Log.Level)
 	}
+	if config.Log.Format != "" && config.Log.Format != "text" && config.Log.Format != "json" {
+		return fmt.Errorf("invalid log format: %s (must be text or json)", config.Log.Format)
+	}
+
+	// Validate capture config
+	if config.Capture.Enabled && config.Capture.Dir == "" {
+		return fmt.Errorf("capture dir must be set when capture is enabled")
+	}
 
-	// Validate private key is set
-	if config.Signer.PrivateKey == nil {
-		return fmt.Errorf("private key must be set")
+	// Validate alerts config
+	if config.Alerts.Enabled {
+		if err := config.Alerts.validate(config.Networks); err != nil {
+			return fmt.Errorf("invalid alerts config: %w", err)
+		}
+	}
+
+	// Validate admin token is set
+	if config.Admin.Enabled && config.Admin.Token == "" {
+		return fmt.Errorf("admin token must be set")
+	}
+
+	// Validate every network has a private key or signer backend
+	for network, networkCfg := range config.Networks {
+		if utils.IsSolanaNetwork(network) {
+			if networkCfg.Signer != nil {
+				return fmt.Errorf("network %s cannot have an EVM signer", network)
+			}
+			if config.Solana.PrivateKey == nil {
+				return fmt.Errorf("solana private key must be set for %s", network)
+			}
+			continue
+		}
+		if networkCfg.Signer == nil {
+			if !config.Signer.loaded() {
+				return fmt.Errorf("private key must be set")
+			}
+		} else if !networkCfg.Signer.loaded() {
+			return fmt.Errorf("private key must be set for %s", network)
+		}
+	}
+
+	return nil
+}
+
+// validateSupportedTerms checks the assets, amount limits and fee advertised for a pair
+func validateSupportedTerms(kind types.SupportedKind) error {
+	if !utils.IsSolanaNetwork(kind.Network) {
+		for _, asset := range kind.Assets {
+			if !common.IsHexAddress(asset) {
+				return fmt.Errorf("invalid asset address: %s", asset)
+			}
+		}
+	}
+	minAmount, err := parseOptionalAmount(kind.MinAmount)
+	if err != nil {
+		return fmt.Errorf("invalid min_amount: %w", err)
+	}
+	maxAmount, err := parseOptionalAmount(kind.MaxAmount)
+	if err != nil {
+		return fmt.Errorf("invalid max_amount: %w", err)
+	}
+	if minAmount != nil && maxAmount != nil && minAmount.Cmp(maxAmount) > 0 {
+		return fmt.Errorf("min_amount %s exceeds max_amount %s", minAmount, maxAmount)
+	}
+	if kind.Fee != nil {
+		if _, err := parseOptionalAmount(kind.Fee.Flat); err != nil {
+			return fmt.Errorf("invalid fee flat: %w", err)
+		}
+		if kind.Fee.BasisPoints < 0 || kind.Fee.BasisPoints > 10000 {
+			return fmt.Errorf("fee basis_points must be 0-10000, got %d", kind.Fee.BasisPoints)
+		}
+	}
+	if kind.ExpectedConfirmationSeconds < 0 {
+		return fmt.Errorf("expected_confirmation_seconds cannot be negative, got %d", kind.ExpectedConfirmationSeconds)
+	}
+	return nil
+}
+
+// parseOptionalAmount parses a non-negative amount in atomic units, returning nil when unset
+func parseOptionalAmount(amount string) (*big.Int, error) {
+	if amount == "" {
+		return nil, nil
+	}
+	value, ok := new(big.Int).SetString(amount, 10)
+	if !ok || value.Sign() < 0 {
+		return nil, fmt.Errorf("%q is not a non-negative integer", amount)
+	}
+	return value, nil
+}
+
+func (gasCfg GasConfig) validate() error {
+	if gasCfg.Multiplier != 0 && gasCfg.Multiplier < 1 {
+		return fmt.Errorf("multiplier must be at least 1, got %v", gasCfg.Multiplier)
+	}
+	return nil
+}
+
+func (failoverCfg FailoverConfig) validate() error {
+	if failoverCfg.FailureThreshold < 0 || failoverCfg.CooldownSeconds < 0 || failoverCfg.HealthCheckSeconds < 0 {
+		return fmt.Errorf("values cannot be negative")
+	}
+	return nil
+}
+
+func (alertsCfg AlertsConfig) validate(networks map[string]NetworkConfig) error {
+	if len(alertsCfg.Slack)+len(alertsCfg.PagerDuty)+len(alertsCfg.Webhooks) == 0 {
+		return fmt.Errorf("at least one slack, pagerduty or webhook destination must be configured")
+	}
+
+	// Validate thresholds
+	for network, minBalance := range alertsCfg.MinSignerBalance {
+		if _, exists := networks[network]; !exists {
+			return fmt.Errorf("min_signer_balance network %s is not defined in networks config", network)
+		}
+		if _, ok := new(big.Int).SetString(minBalance, 10); !ok {
+			return fmt.Errorf("invalid min_signer_balance for %s: %s", network, minBalance)
+		}
+	}
+
+	// Validate destinations
+	severities := []string{}
+	for _, slack := range alertsCfg.Slack {
+		if slack.WebhookURL == "" {
+			return fmt.Errorf("slack webhook_url must be set")
+		}
+		severities = append(severities, slack.MinSeverity)
+	}
+	for _, pd := range alertsCfg.PagerDuty {
+		if pd.RoutingKey == "" {
+			return fmt.Errorf("pagerduty routing_key must be set")
+		}
+		severities = append(severities, pd.MinSeverity)
+	}
+	for _, wh := range alertsCfg.Webhooks {
+		if wh.URL == "" || len(wh.Secrets) == 0 {
+			return fmt.Errorf("webhook url and secrets must be set")
+		}
+		severities = append(severities, wh.MinSeverity)
+	}
+	for _, severity := range severities {
+		if _, ok := alertSeverityRank[severity]; severity != "" && !ok {
+			return fmt.Errorf("invalid min_severity: %s (must be info, warning, or critical)", severity)
+		}
 	}
 
 	return nil
 }
 
 func loadEnvVars(config *FacilitatorConfig) error {
-	// Load from environment variable
+	// Load signers of networks with their own hot wallet
+	usesDefault, usesSolana := false, false
+	for network, networkCfg := range config.Networks {
+		if utils.IsSolanaNetwork(network) {
+			usesSolana = true
+			continue
+		}
+		if networkCfg.Signer == nil {
+			usesDefault = true
+			continue
+		}
+		if err := networkCfg.Signer.load(""); err != nil {
+			return fmt.Errorf("failed to load signer for %s: %w", network, err)
+		}
+	}
+
+	// Load the top-level signer, unless every network has its own
 	// ex: export X402_FACILITATOR_PRIVATE_KEY=0x123...
-	privateKeyStr := os.Getenv("X402_FACILITATOR_PRIVATE_KEY")
-	if privateKeyStr == "" {
-		return fmt.Errorf("X402_FACILITATOR_PRIVATE_KEY environment variable required")
+	if usesDefault || config.Signer.configured() || os.Getenv(defaultPrivateKeyEnv) != "" {
+		if err := config.Signer.load(defaultPrivateKeyEnv); err != nil {
+			return err
+		}
 	}
-	privateKey, err := crypto.HexToECDSA(strings.TrimPrefix(privateKeyStr, "0x"))
-	if err != nil {
-		return fmt.Errorf("failed to parse private key: %w", err)
+
+	// Load the Solana fee payer
+	// ex: export X402_FACILITATOR_SOLANA_PRIVATE_KEY=4NMwxzmY...
+	if usesSolana {
+		keyEnv := config.Solana.PrivateKeyEnv
+		if keyEnv == "" {
+			keyEnv = defaultSolanaKeyEnv
+		}
+		keyStr := os.Getenv(keyEnv)
+		if keyStr == "" {
+			return fmt.Errorf("%s environment variable required", keyEnv)
+		}
+		privateKey, err := parseSolanaPrivateKey(keyStr)
+		if err != nil {
+			return fmt.Errorf("failed to parse solana private key: %w", err)
+		}
+		config.Solana.PrivateKey = privateKey
+		config.Solana.ownsKey = true
+	}
+
+	// Load the keys refunds are sent from
+	if config.Refunds.Enabled {
+		if err := config.Refunds.loadPayeeSigners(); err != nil {
+			return err
+		}
+	}
+
+	// Load admin token
+	if config.Admin.Enabled {
+		tokenEnv := config.Admin.TokenEnv
+		if tokenEnv == "" {
+			tokenEnv = defaultAdminTokenEnv
+		}
+		config.Admin.Token = os.Getenv(tokenEnv)
 	}
-	config.Signer.PrivateKey = privateKey
 
 	return nil
 }
+
+// configured reports whether a key source is set explicitly
+func (signerCfg *SignerConfig) configured() bool {
+	return signerCfg.PrivateKeyEnv != "" || signerCfg.KeystoreFile != "" || signerCfg.KeyFile != "" || signerCfg.KMS.KeyID != ""
+}
+
+// loaded reports whether the signer can sign
+func (signerCfg *SignerConfig) loaded() bool {
+	return signerCfg.PrivateKey != nil || signerCfg.Backend != nil
+}
+
+// load reads the private key, decrypts the keystore or key file or connects
+// to KMS, and derives the signer address. defaultKeyEnv is read when
+// PrivateKeyEnv is unset.
+func (signerCfg *SignerConfig) load(defaultKeyEnv string) error {
+	keyEnv := signerCfg.PrivateKeyEnv
+	if keyEnv == "" {
+		keyEnv = defaultKeyEnv
+	}
+	var privateKeyStr string
+	if keyEnv != "" {
+		privateKeyStr = os.Getenv(keyEnv)
+	}
+
+	switch {
+	case signerCfg.KMS.KeyID != "":
+		// Sign with AWS KMS, no key is loaded
+		if privateKeyStr != "" || signerCfg.KeystoreFile != "" || signerCfg.KeyFile != "" {
+			return fmt.Errorf("kms cannot be combined with %s, keystore_file or key_file", keyEnv)
+		}
+		ctx, cancel := context.WithTimeout(context.Background(), kmsRequestTimeout)
+		defer cancel()
+		kms, err := newKMSSigner(ctx, signerCfg.KMS)
+		if err != nil {
+			return fmt.Errorf("failed to load kms signer: %w", err)
+		}
+		signerCfg.Backend = kms
+		signerCfg.Address = kms.Address()
+
+	case signerCfg.KeystoreFile != "":
+		// Decrypt keystore
+		if privateKeyStr != "" {
+			return fmt.Errorf("%s and keystore_file cannot both be set", keyEnv)
+		}
+		if signerCfg.KeyFile != "" {
+			return fmt.Errorf("keystore_file and key_file cannot both be set")
+		}
+		privateKey, err := signerCfg.loadKeystore()
+		if err != nil {
+			return err
+		}
+		signerCfg.PrivateKey = privateKey
+		signerCfg.Address = crypto.PubkeyToAddress(privateKey.PublicKey)
+
+	case signerCfg.KeyFile != "":
+		// Decrypt age or AES-GCM key file
+		if privateKeyStr != "" {
+			return fmt.Errorf("%s and key_file cannot both be set", keyEnv)
+		}
+		privateKey, err := signerCfg.loadKeyFile()
+		if err != nil {
+			return err
+		}
+		signerCfg.PrivateKey = privateKey
+		signerCfg.Address = crypto.PubkeyToAddress(privateKey.PublicKey)
+
+	default:
+		// Parse hex key from the environment
+		if keyEnv == "" {
+			return fmt.Errorf("private_key_env, keystore_file, key_file or kms required")
+		}
+		if privateKeyStr == "" {
+			return fmt.Errorf("%s environment variable required", keyEnv)
+		}
+		privateKey, err := crypto.HexToECDSA(strings.TrimPrefix(privateKeyStr, "0x"))
+		if err != nil {
+			return fmt.Errorf("failed to parse private key: %w", err)
+		}
+		signerCfg.PrivateKey = privateKey
+		signerCfg.Address = crypto.PubkeyToAddress(privateKey.PublicKey)
+	}
+
+	signerCfg.ownsKey = signerCfg.PrivateKey != nil
+	return nil
+}
+
+// loadKeystore decrypts the keystore file with the passphrase
+func (signerCfg SignerConfig) loadKeystore() (*ecdsa.PrivateKey, error) {
+	keyJSON, err := os.ReadFile(signerCfg.KeystoreFile)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read keystore: %w", err)
+	}
+	passphrase, err := signerCfg.passphrase(signerCfg.KeystoreFile)
+	if err != nil {
+		return nil, err
+	}
+	defer clear(passphrase)
+	key, err := keystore.DecryptKey(keyJSON, string(passphrase))
+	if err != nil {
+		return nil, fmt.Errorf("failed to decrypt keystore: %w", err)
+	}
+	return key.PrivateKey, nil
+}
+
+// loadKeyFile decrypts the key file with the passphrase
+func (signerCfg SignerConfig) loadKeyFile() (*ecdsa.PrivateKey, error) {
+	data, err := os.ReadFile(signerCfg.KeyFile)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read key file: %w", err)
+	}
+	passphrase, err := signerCfg.passphrase(signerCfg.KeyFile)
+	if err != nil {
+		return nil, err
+	}
+	defer clear(passphrase)
+	key, err := decryptKeyFile(data, passphrase)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decrypt key file: %w", err)
+	}
+	return key, nil
+}
+
+// passphrase reads the passphrase of file from the environment, or asks
+// PassphrasePrompt when the variable is unset
+func (signerCfg SignerConfig) passphrase(file string) ([]byte, error) {
+	passphraseEnv := signerCfg.PassphraseEnv
+	if passphraseEnv == "" {
+		passphraseEnv = defaultPassphraseEnv
+	}
+	if passphrase, ok := os.LookupEnv(passphraseEnv); ok {
+		return []byte(passphrase), nil
+	}
+	if PassphrasePrompt != nil && !signerCfg.noPrompt {
+		return PassphrasePrompt(file)
+	}
+	return nil, fmt.Errorf("%s environment variable required for %s", passphraseEnv, file)
+}
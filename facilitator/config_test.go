@@ -1,8 +1,12 @@
 package facilitator
 
 import (
+	"os"
+	"path/filepath"
+	"strings"
 	"testing"
 
+	"github.com/ethereum/go-ethereum/accounts/keystore"
 	"github.com/ethereum/go-ethereum/crypto"
 	"github.com/vorpalengineering/x402-go/types"
 )
@@ -268,6 +272,39 @@ func TestValidateMissingMaxGasPrice(t *testing.T) {
 	}
 }
 
+func TestValidateNegativeConfirmations(t *testing.T) {
+	privKey, err := crypto.HexToECDSA("ac0974bec39a17e36ba4a6b4d238ff944bacb478cbed5efcae784d7bf4f2ff80")
+	addr := crypto.PubkeyToAddress(privKey.PublicKey)
+	config := &FacilitatorConfig{
+		Server: ServerConfig{
+			Host: "localhost",
+			Port: 8080,
+		},
+		Networks: map[string]NetworkConfig{
+			"eip155:8453": {
+				RpcUrl: "https://mainnet.base.org",
+			},
+		},
+		Transaction: TransactionConfig{
+			TimeoutSeconds: 120,
+			MaxGasPrice:    "100000000000",
+			Confirmations:  -1, // Invalid
+		},
+		Log: LogConfig{
+			Level: "info",
+		},
+		Signer: SignerConfig{
+			Address:    addr,
+			PrivateKey: privKey,
+		},
+	}
+
+	err = config.Validate()
+	if err == nil {
+		t.Error("Expected error for negative confirmations, got nil")
+	}
+}
+
 func TestValidateInvalidLogLevel(t *testing.T) {
 	privKey, err := crypto.HexToECDSA("ac0974bec39a17e36ba4a6b4d238ff944bacb478cbed5efcae784d7bf4f2ff80")
 	addr := crypto.PubkeyToAddress(privKey.PublicKey)
@@ -331,3 +368,462 @@ func TestValidateMissingPrivateKey(t *testing.T) {
 		t.Error("Expected error for missing private key, got nil")
 	}
 }
+
+func TestValidateInvalidGasMultiplier(t *testing.T) {
+	privKey, err := crypto.HexToECDSA("ac0974bec39a17e36ba4a6b4d238ff944bacb478cbed5efcae784d7bf4f2ff80")
+	addr := crypto.PubkeyToAddress(privKey.PublicKey)
+	config := &FacilitatorConfig{
+		Server: ServerConfig{
+			Host: "localhost",
+			Port: 8080,
+		},
+		Networks: map[string]NetworkConfig{
+			"eip155:8453": {
+				RpcUrl: "https://mainnet.base.org",
+				Gas: GasConfig{
+					Multiplier: 0.5, // Invalid
+				},
+			},
+		},
+		Transaction: TransactionConfig{
+			TimeoutSeconds: 120,
+			MaxGasPrice:    "100000000000",
+		},
+		Log: LogConfig{
+			Level: "info",
+		},
+		Signer: SignerConfig{
+			Address:    addr,
+			PrivateKey: privKey,
+		},
+	}
+
+	err = config.Validate()
+	if err == nil {
+		t.Error("Expected error for gas multiplier below 1, got nil")
+	}
+}
+
+func TestValidateSupportedTerms(t *testing.T) {
+	privKey, err := crypto.HexToECDSA("ac0974bec39a17e36ba4a6b4d238ff944bacb478cbed5efcae784d7bf4f2ff80")
+	addr := crypto.PubkeyToAddress(privKey.PublicKey)
+
+	tests := []struct {
+		name      string
+		kind      types.SupportedKind
+		expectErr bool
+	}{
+		{"valid terms", types.SupportedKind{MinAmount: "1", MaxAmount: "10", Fee: &types.FacilitatorFee{Flat: "1", BasisPoints: 30}}, false},
+		{"invalid asset", types.SupportedKind{Assets: []string{"usdc"}}, true},
+		{"negative minimum", types.SupportedKind{MinAmount: "-1"}, true},
+		{"minimum above maximum", types.SupportedKind{MinAmount: "10", MaxAmount: "1"}, true},
+		{"fee over 100%", types.SupportedKind{Fee: &types.FacilitatorFee{BasisPoints: 10001}}, true},
+		{"invalid flat fee", types.SupportedKind{Fee: &types.FacilitatorFee{Flat: "0.5"}}, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.kind.Scheme = "exact"
+			tt.kind.Network = "eip155:8453"
+			config := &FacilitatorConfig{
+				Server: ServerConfig{
+					Host: "localhost",
+					Port: 8080,
+				},
+				Networks: map[string]NetworkConfig{
+					"eip155:8453": {
+						RpcUrl: "https://mainnet.base.org",
+					},
+				},
+				Supported: []types.SupportedKind{tt.kind},
+				Transaction: TransactionConfig{
+					TimeoutSeconds: 120,
+					MaxGasPrice:    "100000000000",
+				},
+				Log: LogConfig{
+					Level: "info",
+				},
+				Signer: SignerConfig{
+					Address:    addr,
+					PrivateKey: privKey,
+				},
+			}
+
+			err = config.Validate()
+			if tt.expectErr && err == nil {
+				t.Error("Expected error, got nil")
+			}
+			if !tt.expectErr && err != nil {
+				t.Errorf("Expected no error, got %v", err)
+			}
+		})
+	}
+}
+
+func TestValidateAlertsMissingDestination(t *testing.T) {
+	privKey, err := crypto.HexToECDSA("ac0974bec39a17e36ba4a6b4d238ff944bacb478cbed5efcae784d7bf4f2ff80")
+	addr := crypto.PubkeyToAddress(privKey.PublicKey)
+	config := &FacilitatorConfig{
+		Server: ServerConfig{
+			Host: "localhost",
+			Port: 8080,
+		},
+		Networks: map[string]NetworkConfig{
+			"eip155:8453": {
+				RpcUrl: "https://mainnet.base.org",
+			},
+		},
+		Transaction: TransactionConfig{
+			TimeoutSeconds: 120,
+			MaxGasPrice:    "100000000000",
+		},
+		Log: LogConfig{
+			Level: "info",
+		},
+		Alerts: AlertsConfig{
+			Enabled: true, // No destinations
+		},
+		Signer: SignerConfig{
+			Address:    addr,
+			PrivateKey: privKey,
+		},
+	}
+
+	err = config.Validate()
+	if err == nil {
+		t.Error("Expected error for alerts without destinations, got nil")
+	}
+}
+
+func TestGetGasConfig(t *testing.T) {
+	networkConfig := NetworkConfig{
+		RpcUrl: "https://mainnet.base.org",
+		Gas: GasConfig{
+			FallbackLimit: 100000,
+			Multiplier:    1.2,
+		},
+		AssetGas: map[string]GasConfig{
+			"0x833589fCD6eDb6E08f4c7C32D4f71b54bdA02913": {
+				FallbackLimit: 150000,
+			},
+		},
+	}
+
+	// Asset entries override network defaults (matched case-insensitively)
+	gasCfg := networkConfig.GetGasConfig("0x833589fcd6edb6e08f4c7c32d4f71b54bda02913")
+	if gasCfg.FallbackLimit != 150000 {
+		t.Errorf("Expected asset fallback limit 150000, got %d", gasCfg.FallbackLimit)
+	}
+	if gasCfg.Multiplier != 1.2 {
+		t.Errorf("Expected network multiplier 1.2, got %v", gasCfg.Multiplier)
+	}
+
+	// Unknown assets use network defaults
+	gasCfg = networkConfig.GetGasConfig("0x0000000000000000000000000000000000000001")
+	if gasCfg.FallbackLimit != 100000 {
+		t.Errorf("Expected network fallback limit 100000, got %d", gasCfg.FallbackLimit)
+	}
+}
+
+func TestLoadConfigKeystore(t *testing.T) {
+	dir := t.TempDir()
+
+	// Encrypt the test key into a keystore file
+	privKey, _ := crypto.HexToECDSA("ac0974bec39a17e36ba4a6b4d238ff944bacb478cbed5efcae784d7bf4f2ff80")
+	key := &keystore.Key{
+		Address:    crypto.PubkeyToAddress(privKey.PublicKey),
+		PrivateKey: privKey,
+	}
+	keyJSON, err := keystore.EncryptKey(key, "correct horse", keystore.LightScryptN, keystore.LightScryptP)
+	if err != nil {
+		t.Fatalf("Failed to encrypt key: %v", err)
+	}
+	keystorePath := filepath.Join(dir, "signer.json")
+	os.WriteFile(keystorePath, keyJSON, 0600)
+
+	configPath := filepath.Join(dir, "config.yaml")
+	os.WriteFile(configPath, []byte(`
+server:
+  port: 8080
+networks:
+  "eip155:8453":
+    rpc_url: "https://mainnet.base.org"
+supported:
+  - scheme: "exact"
+    network: "eip155:8453"
+transaction:
+  timeout_seconds: 120
+  max_gas_price: "100000000000"
+log:
+  level: "info"
+signer:
+  keystore_file: "`+keystorePath+`"
+  passphrase_env: "TEST_KEYSTORE_PASSPHRASE"
+`), 0600)
+
+	// Decrypts with the passphrase from the configured env var
+	t.Setenv("X402_FACILITATOR_PRIVATE_KEY", "")
+	t.Setenv("TEST_KEYSTORE_PASSPHRASE", "correct horse")
+	config, err := LoadConfig(configPath)
+	if err != nil {
+		t.Fatalf("Expected keystore config to load, got error: %v", err)
+	}
+	if config.Signer.Address != key.Address {
+		t.Errorf("Expected signer address %s, got %s", key.Address, config.Signer.Address)
+	}
+
+	// Wrong passphrase
+	t.Setenv("TEST_KEYSTORE_PASSPHRASE", "wrong")
+	if _, err := LoadConfig(configPath); err == nil {
+		t.Error("Expected error for wrong keystore passphrase")
+	}
+
+	// Raw key and keystore are mutually exclusive
+	t.Setenv("TEST_KEYSTORE_PASSPHRASE", "correct horse")
+	t.Setenv("X402_FACILITATOR_PRIVATE_KEY", "0xac0974bec39a17e36ba4a6b4d238ff944bacb478cbed5efcae784d7bf4f2ff80")
+	if _, err := LoadConfig(configPath); err == nil {
+		t.Error("Expected error when both raw key and keystore are set")
+	}
+}
+
+func TestLoadConfigNetworkSigners(t *testing.T) {
+	configPath := filepath.Join(t.TempDir(), "config.yaml")
+	os.WriteFile(configPath, []byte(`
+server:
+  port: 8080
+networks:
+  "eip155:8453":
+    rpc_url: "https://mainnet.base.org"
+    signer:
+      private_key_env: "TEST_BASE_KEY"
+  "eip155:1":
+    rpc_url: "https://eth.llamarpc.com"
+    signer:
+      private_key_env: "TEST_MAINNET_KEY"
+supported:
+  - scheme: "exact"
+    network: "eip155:8453"
+transaction:
+  timeout_seconds: 120
+  max_gas_price: "100000000000"
+log:
+  level: "info"
+admin:
+  enabled: true
+  token_env: "TEST_ADMIN_TOKEN"
+`), 0600)
+
+	// Top-level key is not needed when every network has its own
+	t.Setenv("X402_FACILITATOR_PRIVATE_KEY", "")
+	t.Setenv("TEST_BASE_KEY", "0xac0974bec39a17e36ba4a6b4d238ff944bacb478cbed5efcae784d7bf4f2ff80")
+	t.Setenv("TEST_MAINNET_KEY", "0x59c6995e998f97a5a0044966f0945389dc9e86dae88c7a8412f4603b6b78690d")
+	t.Setenv("TEST_ADMIN_TOKEN", "secret")
+	config, err := LoadConfig(configPath)
+	if err != nil {
+		t.Fatalf("Expected config to load, got error: %v", err)
+	}
+	if config.Networks["eip155:8453"].Signer.Address.Hex() != "0xf39Fd6e51aad88F6F4ce6aB8827279cffFb92266" {
+		t.Errorf("Expected base signer address, got %s", config.Networks["eip155:8453"].Signer.Address.Hex())
+	}
+	if config.Networks["eip155:1"].Signer.Address.Hex() != "0x70997970C51812dc3A010C7d01b50e0d17dc79C8" {
+		t.Errorf("Expected mainnet signer address, got %s", config.Networks["eip155:1"].Signer.Address.Hex())
+	}
+	if config.Admin.Token != "secret" {
+		t.Errorf("Expected admin token from env, got %q", config.Admin.Token)
+	}
+
+	// Missing network key
+	t.Setenv("TEST_MAINNET_KEY", "")
+	if _, err := LoadConfig(configPath); err == nil {
+		t.Error("Expected error for missing network signer key")
+	}
+
+	// Missing admin token
+	t.Setenv("TEST_MAINNET_KEY", "0x59c6995e998f97a5a0044966f0945389dc9e86dae88c7a8412f4603b6b78690d")
+	t.Setenv("TEST_ADMIN_TOKEN", "")
+	if _, err := LoadConfig(configPath); err == nil {
+		t.Error("Expected error for missing admin token")
+	}
+}
+
+func TestLoadConfigSolana(t *testing.T) {
+	configPath := filepath.Join(t.TempDir(), "config.yaml")
+	os.WriteFile(configPath, []byte(`
+server:
+  port: 8080
+networks:
+  "solana:devnet":
+    rpc_url: "https://api.devnet.solana.com"
+supported:
+  - scheme: "exact"
+    network: "solana:devnet"
+transaction:
+  timeout_seconds: 120
+  max_gas_price: "100000000000"
+log:
+  level: "info"
+`), 0600)
+
+	// Solana-only configs need no EVM key
+	t.Setenv("X402_FACILITATOR_PRIVATE_KEY", "")
+	t.Setenv("X402_FACILITATOR_SOLANA_PRIVATE_KEY", "99eUso3aSbE9tqGSTXzo3TLfKb9RkMTURrHKQ1K7Zh3StnzFNUx8FKCPPPPpR479qsw5zv2WNBKmgiz7WqgAJfM")
+	config, err := LoadConfig(configPath)
+	if err != nil {
+		t.Fatalf("Expected config to load, got error: %v", err)
+	}
+	if config.Solana.PrivateKey == nil {
+		t.Error("Expected solana private key to be loaded")
+	}
+
+	// Missing solana key
+	t.Setenv("X402_FACILITATOR_SOLANA_PRIVATE_KEY", "")
+	if _, err := LoadConfig(configPath); err == nil {
+		t.Error("Expected error for missing solana private key")
+	}
+}
+
+func TestLoadConfigJSON(t *testing.T) {
+	configPath := filepath.Join(t.TempDir(), "config.json")
+	os.WriteFile(configPath, []byte(`{
+  "server": {"port": 8080},
+  "networks": {"eip155:8453": {"rpc_url": "https://mainnet.base.org", "gas": {"multiplier": 1.2}}},
+  "supported": [{"scheme": "exact", "network": "eip155:8453"}],
+  "transaction": {"timeout_seconds": 120, "max_gas_price": "100000000000"},
+  "log": {"level": "info"}
+}`), 0600)
+
+	t.Setenv("X402_FACILITATOR_PRIVATE_KEY", "0xac0974bec39a17e36ba4a6b4d238ff944bacb478cbed5efcae784d7bf4f2ff80")
+	config, err := LoadConfig(configPath)
+	if err != nil {
+		t.Fatalf("Expected config to load, got error: %v", err)
+	}
+	if config.Server.Port != 8080 || config.Networks["eip155:8453"].Gas.Multiplier != 1.2 {
+		t.Errorf("Expected JSON keys to match YAML keys, got %+v", config)
+	}
+
+	// Environment variables override the file
+	t.Setenv("X402_SERVER_PORT", "9090")
+	t.Setenv("X402_NETWORK_BASE_RPC_URLS", "https://base.llamarpc.com, https://base-rpc.publicnode.com")
+	config, err = LoadConfig(configPath)
+	if err != nil {
+		t.Fatalf("Expected config to load, got error: %v", err)
+	}
+	if config.Server.Port != 9090 {
+		t.Errorf("Expected port from env, got %d", config.Server.Port)
+	}
+	if urls := config.Networks["eip155:8453"].GetRpcUrls(); len(urls) != 3 || config.Networks["eip155:8453"].Gas.Multiplier != 1.2 {
+		t.Errorf("Expected fallback urls added to the file's network, got %v", urls)
+	}
+
+	// Syntax errors are reported as JSON errors
+	os.WriteFile(configPath, []byte(`{"server": {"port": 8080,}}`), 0600)
+	if _, err := LoadConfig(configPath); err == nil || !strings.Contains(err.Error(), "invalid character") {
+		t.Errorf("Expected JSON syntax error, got %v", err)
+	}
+}
+
+func TestLoadConfigFromEnv(t *testing.T) {
+	t.Setenv("X402_FACILITATOR_PRIVATE_KEY", "0xac0974bec39a17e36ba4a6b4d238ff944bacb478cbed5efcae784d7bf4f2ff80")
+	t.Setenv("X402_SERVER_PORT", "8080")
+	t.Setenv("X402_NETWORK_BASE_RPC_URL", "https://mainnet.base.org")
+	t.Setenv("X402_NETWORK_BASE_SEPOLIA_RPC_URL", "https://sepolia.base.org")
+	t.Setenv("X402_NETWORK_BASE_SEPOLIA_GAS_MULTIPLIER", "1.5")
+	t.Setenv("X402_NETWORK_EIP155_1_RPC_URL", "https://eth.llamarpc.com")
+	t.Setenv("X402_TRANSACTION_RETRY_MAX_ATTEMPTS", "3")
+	t.Setenv("X402_LOG_FORMAT", "json")
+
+	// Every network supports exact by default
+	config, err := LoadConfigFromEnv()
+	if err != nil {
+		t.Fatalf("Expected config to load, got error: %v", err)
+	}
+	if config.Server.Host != "0.0.0.0" || config.Server.Port != 8080 || config.Transaction.TimeoutSeconds != 120 {
+		t.Errorf("Expected defaults with port from env, got %+v", config)
+	}
+	if config.Networks["eip155:84532"].Gas.Multiplier != 1.5 || config.Networks["eip155:1"].RpcUrl != "https://eth.llamarpc.com" {
+		t.Errorf("Expected networks from aliases and CAIP-2 ids, got %+v", config.Networks)
+	}
+	if config.Transaction.Retry.MaxAttempts != 3 || config.Log.Format != "json" {
+		t.Errorf("Expected nested settings from env, got %+v %+v", config.Transaction, config.Log)
+	}
+	if len(config.Supported) != 3 || !config.IsSupported("exact", "eip155:84532") {
+		t.Errorf("Expected exact on every network, got %+v", config.Supported)
+	}
+
+	// X402_SUPPORTED replaces the default
+	t.Setenv("X402_SUPPORTED", "exact:base,upto:eip155:8453")
+	config, err = LoadConfigFromEnv()
+	if err != nil {
+		t.Fatalf("Expected config to load, got error: %v", err)
+	}
+	if len(config.Supported) != 2 || !config.IsSupported("upto", "eip155:8453") || config.IsSupported("exact", "eip155:1") {
+		t.Errorf("Expected supported from env, got %+v", config.Supported)
+	}
+
+	// Invalid values name the variable
+	t.Setenv("X402_SERVER_PORT", "http")
+	if _, err := LoadConfigFromEnv(); err == nil || !strings.Contains(err.Error(), "X402_SERVER_PORT") {
+		t.Errorf("Expected error naming X402_SERVER_PORT, got %v", err)
+	}
+	t.Setenv("X402_SERVER_PORT", "8080")
+	t.Setenv("X402_NETWORK_BASE_RPC", "https://mainnet.base.org")
+	if _, err := LoadConfigFromEnv(); err == nil || !strings.Contains(err.Error(), "X402_NETWORK_BASE_RPC") {
+		t.Errorf("Expected error for unknown network setting, got %v", err)
+	}
+}
+
+func TestLoadConfigInterpolation(t *testing.T) {
+	configPath := filepath.Join(t.TempDir(), "config.yaml")
+	os.WriteFile(configPath, []byte(`
+server:
+  port: ${TEST_PORT}
+networks:
+  "eip155:8453":
+    # rpc_url: "https://mainnet.base.org/${NOT_CHECKED}"
+    rpc_url: "https://base-mainnet.infura.io/v3/${TEST_INFURA_KEY}"
+supported:
+  - scheme: "exact"
+    network: "eip155:8453"
+transaction:
+  timeout_seconds: 120
+  max_gas_price: "${TEST_MAX_GAS_PRICE}"
+log:
+  level: "info"
+redis:
+  addr: "localhost:6379"
+  password: "${TEST_REDIS_PASSWORD}"
+`), 0600)
+
+	t.Setenv("X402_FACILITATOR_PRIVATE_KEY", "0xac0974bec39a17e36ba4a6b4d238ff944bacb478cbed5efcae784d7bf4f2ff80")
+	t.Setenv("TEST_PORT", "8080")
+	t.Setenv("TEST_REDIS_PASSWORD", "null")
+	t.Setenv("TEST_INFURA_KEY", "abc123")
+	t.Setenv("TEST_MAX_GAS_PRICE", "100000000000")
+	config, err := LoadConfig(configPath)
+	if err != nil {
+		t.Fatalf("Expected config to load, got error: %v", err)
+	}
+	if config.Server.Port != 8080 {
+		t.Errorf("Expected port 8080, got %d", config.Server.Port)
+	}
+	if config.Networks["eip155:8453"].RpcUrl != "https://base-mainnet.infura.io/v3/abc123" {
+		t.Errorf("Expected key in rpc url, got %s", config.Networks["eip155:8453"].RpcUrl)
+	}
+	if config.Transaction.MaxGasPrice != "100000000000" {
+		t.Errorf("Expected max gas price from env, got %s", config.Transaction.MaxGasPrice)
+	}
+	if config.Redis.Password != "null" {
+		t.Errorf("Expected quoted value to stay a string, got %q", config.Redis.Password)
+	}
+
+	// Every unset variable is reported with its line, comments are ignored
+	os.Unsetenv("TEST_INFURA_KEY")
+	os.Unsetenv("TEST_MAX_GAS_PRICE")
+	_, err = LoadConfig(configPath)
+	if err == nil || !strings.Contains(err.Error(), "TEST_INFURA_KEY (line 7), TEST_MAX_GAS_PRICE (line 13)") {
+		t.Errorf("Expected missing variables with lines, got %v", err)
+	}
+	if err != nil && strings.Contains(err.Error(), "NOT_CHECKED") {
+		t.Errorf("Expected comments not to be expanded, got %v", err)
+	}
+}
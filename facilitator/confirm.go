@@ -0,0 +1,71 @@
+package facilitator
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/common"
+	ethtypes "github.com/ethereum/go-ethereum/core/types"
+)
+
+// defaultConfirmationPollInterval is how often the receipt and head are polled
+const defaultConfirmationPollInterval = time.Second
+
+// receiptReader is the subset of RPC calls needed to wait for confirmations
+type receiptReader interface {
+	TransactionReceipt(ctx context.Context, txHash common.Hash) (*ethtypes.Receipt, error)
+	BlockNumber(ctx context.Context) (uint64, error)
+}
+
+// waitForConfirmations polls until the transaction is mined and buried under
+// the given number of blocks, counting its own block as the first. It returns
+// the receipt, or an error once ctx is done.
+func waitForConfirmations(
+	ctx context.Context,
+	reader receiptReader,
+	txHash common.Hash,
+	confirmations uint64,
+	interval time.Duration,
+) (*ethtypes.Receipt, error) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	var receipt *ethtypes.Receipt
+	for {
+		// Fetch the receipt until the transaction is mined
+		if receipt == nil {
+			r, err := reader.TransactionReceipt(ctx, txHash)
+			if err == nil {
+				receipt = r
+			} else if !errors.Is(err, ethereum.NotFound) && ctx.Err() == nil {
+				return nil, fmt.Errorf("failed to get receipt: %w", err)
+			}
+		}
+
+		// Check the depth of the block it was mined in
+		if receipt != nil {
+			if receipt.Status != ethtypes.ReceiptStatusSuccessful {
+				return receipt, nil
+			}
+			head, err := reader.BlockNumber(ctx)
+			if err != nil && ctx.Err() == nil {
+				return nil, fmt.Errorf("failed to get block number: %w", err)
+			}
+			if err == nil && head+1 >= receipt.BlockNumber.Uint64()+confirmations {
+				return receipt, nil
+			}
+		}
+
+		select {
+		case <-ctx.Done():
+			if receipt == nil {
+				return nil, fmt.Errorf("transaction not mined: %w", ctx.Err())
+			}
+			return nil, fmt.Errorf("transaction mined in block %d but not confirmed: %w", receipt.BlockNumber.Uint64(), ctx.Err())
+		case <-ticker.C:
+		}
+	}
+}
@@ -0,0 +1,83 @@
+package facilitator
+
+import (
+	"context"
+	"math/big"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/common"
+	ethtypes "github.com/ethereum/go-ethereum/core/types"
+)
+
+// fakeChain mines the receipt once the head reaches its block and advances
+// the head by one block per BlockNumber call
+type fakeChain struct {
+	receipt *ethtypes.Receipt
+	head    uint64
+}
+
+func (c *fakeChain) TransactionReceipt(ctx context.Context, txHash common.Hash) (*ethtypes.Receipt, error) {
+	if c.receipt == nil || c.head < c.receipt.BlockNumber.Uint64() {
+		c.head++
+		return nil, ethereum.NotFound
+	}
+	return c.receipt, nil
+}
+
+func (c *fakeChain) BlockNumber(ctx context.Context) (uint64, error) {
+	c.head++
+	return c.head, nil
+}
+
+func TestWaitForConfirmations(t *testing.T) {
+	chain := &fakeChain{
+		receipt: &ethtypes.Receipt{
+			Status:      ethtypes.ReceiptStatusSuccessful,
+			BlockNumber: big.NewInt(5),
+			GasUsed:     52000,
+		},
+	}
+
+	receipt, err := waitForConfirmations(context.Background(), chain, common.Hash{}, 3, time.Millisecond)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if receipt.GasUsed != 52000 {
+		t.Errorf("Expected gas used 52000, got %d", receipt.GasUsed)
+	}
+	if chain.head < 7 {
+		t.Errorf("Expected head to reach block 7 for 3 confirmations, got %d", chain.head)
+	}
+}
+
+func TestWaitForConfirmationsReverted(t *testing.T) {
+	chain := &fakeChain{
+		receipt: &ethtypes.Receipt{
+			Status:      ethtypes.ReceiptStatusFailed,
+			BlockNumber: big.NewInt(1),
+		},
+	}
+
+	// Reverted transactions are returned without waiting for confirmations
+	receipt, err := waitForConfirmations(context.Background(), chain, common.Hash{}, 100, time.Millisecond)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if receipt.Status != ethtypes.ReceiptStatusFailed {
+		t.Errorf("Expected failed receipt, got status %d", receipt.Status)
+	}
+}
+
+func TestWaitForConfirmationsTimeout(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+
+	// A transaction that never gets mined
+	_, err := waitForConfirmations(ctx, &fakeChain{}, common.Hash{}, 1, time.Millisecond)
+	if err == nil || !strings.Contains(err.Error(), "not mined") {
+		t.Errorf("Expected not mined error, got %v", err)
+	}
+}
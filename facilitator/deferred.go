@@ -0,0 +1,405 @@
+package facilitator
+
+import (
+	"context"
+	"fmt"
+	"math/big"
+	"net/http"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/vorpalengineering/x402-go/types"
+	"github.com/vorpalengineering/x402-go/utils"
+)
+
+// Deferred settlement accumulates small exact scheme payments per payer and
+// payTo and settles them together, trading latency for one transaction per
+// batch instead of one per payment. A deferred payment is verified and
+// answered at once with a deferred status and no transaction. Batches go
+// out at an interval, when a group's pending value reaches the flush amount
+// and on shutdown.
+
+const (
+	defaultDeferredFlushInterval = time.Minute
+	defaultDeferredPollInterval  = time.Second
+)
+
+// DeferredConfig enables deferred settlement of payments up to MaxAmount
+type DeferredConfig struct {
+	Enabled bool `yaml:"enabled"`
+	// MaxAmount is the largest payment, in the asset's atomic units, that is
+	// deferred. Larger payments settle right away.
+	MaxAmount string `yaml:"max_amount"`
+	// FlushIntervalSeconds is the longest a payment waits (default 60)
+	FlushIntervalSeconds int `yaml:"flush_interval_seconds"`
+	// FlushAmount settles a payer/payTo group as soon as its pending value
+	// reaches it (optional)
+	FlushAmount string `yaml:"flush_amount"`
+}
+
+func (deferredCfg DeferredConfig) validate() error {
+	if !deferredCfg.Enabled {
+		return nil
+	}
+	if amount, ok := new(big.Int).SetString(deferredCfg.MaxAmount, 10); !ok || amount.Sign() <= 0 {
+		return fmt.Errorf("max_amount must be a positive integer, got %q", deferredCfg.MaxAmount)
+	}
+	if deferredCfg.FlushAmount != "" {
+		if amount, ok := new(big.Int).SetString(deferredCfg.FlushAmount, 10); !ok || amount.Sign() <= 0 {
+			return fmt.Errorf("flush_amount must be a positive integer, got %q", deferredCfg.FlushAmount)
+		}
+	}
+	if deferredCfg.FlushIntervalSeconds < 0 {
+		return fmt.Errorf("flush_interval_seconds cannot be negative")
+	}
+	return nil
+}
+
+func (deferredCfg DeferredConfig) flushInterval() time.Duration {
+	if deferredCfg.FlushIntervalSeconds > 0 {
+		return time.Duration(deferredCfg.FlushIntervalSeconds) * time.Second
+	}
+	return defaultDeferredFlushInterval
+}
+
+// DeferredBalance is the ledger of one payer/payTo group: what waits to be
+// settled and what was settled or failed so far
+type DeferredBalance struct {
+	Network       string   `json:"network"`
+	Asset         string   `json:"asset"`
+	Payer         string   `json:"payer"`
+	PayTo         string   `json:"payTo"`
+	Pending       int      `json:"pending"`
+	PendingAmount string   `json:"pendingAmount"`
+	Settled       int      `json:"settled"`
+	SettledAmount string   `json:"settledAmount"`
+	Failed        int      `json:"failed"`
+	FailedAmount  string   `json:"failedAmount"`
+	Transactions  []string `json:"transactions"`
+	LastFlush     int64    `json:"lastFlush,omitempty"`
+}
+
+// deferredPayment is an accepted payment waiting for its batch
+type deferredPayment struct {
+	req   types.SettleRequest
+	key   string
+	value *big.Int
+	// journal is the payment's entry in the settlement journal, if kept
+	journal string
+}
+
+type deferredGroup struct {
+	balance       DeferredBalance
+	pending       []deferredPayment
+	pendingAmount *big.Int
+	settledAmount *big.Int
+	failedAmount  *big.Int
+	oldest        time.Time
+}
+
+// deferredLedger holds the deferred payments of every group
+type deferredLedger struct {
+	maxAmount   *big.Int
+	flushAmount *big.Int
+	interval    time.Duration
+
+	mu     sync.Mutex
+	groups map[string]*deferredGroup
+	keys   map[string]bool
+
+	// flushMu serializes flushes so a group's payments settle in order
+	flushMu sync.Mutex
+	wg      sync.WaitGroup
+}
+
+func newDeferredLedger(cfg DeferredConfig) *deferredLedger {
+	l := &deferredLedger{
+		interval: cfg.flushInterval(),
+		groups:   make(map[string]*deferredGroup),
+		keys:     make(map[string]bool),
+	}
+	l.maxAmount, _ = new(big.Int).SetString(cfg.MaxAmount, 10)
+	if cfg.FlushAmount != "" {
+		l.flushAmount, _ = new(big.Int).SetString(cfg.FlushAmount, 10)
+	}
+	return l
+}
+
+// eligible reports whether a payment is small enough to defer and its
+// authorization stays valid until the batch is sent
+func (l *deferredLedger) eligible(req *types.SettleRequest, now time.Time) (*types.ExactEVMSchemeAuthorization, *big.Int, bool) {
+	requirements := &req.PaymentRequirements
+	if req.PaymentPayload.Accepted.Scheme != "exact" || utils.IsSolanaNetwork(requirements.Network) {
+		return nil, nil, false
+	}
+	auth, err := utils.ExtractExactAuthorization(&req.PaymentPayload)
+	if err != nil {
+		return nil, nil, false
+	}
+	value, ok := new(big.Int).SetString(auth.Value, 10)
+	if !ok || value.Sign() <= 0 || value.Cmp(l.maxAmount) > 0 {
+		return nil, nil, false
+	}
+	// Leave a second interval for a slow flush
+	if time.Unix(auth.ValidBefore, 0).Before(now.Add(2 * l.interval)) {
+		return nil, nil, false
+	}
+	return auth, value, true
+}
+
+func deferredGroupKey(network, asset, payer, payTo string) string {
+	return strings.ToLower(strings.Join([]string{network, asset, payer, payTo}, "|"))
+}
+
+// add queues a verified payment and reports whether its group reached the
+// flush amount. A payment already deferred is not added twice.
+func (l *deferredLedger) add(payment deferredPayment, payer string, now time.Time) (string, bool) {
+	requirements := &payment.req.PaymentRequirements
+	groupKey := deferredGroupKey(requirements.Network, requirements.Asset, payer, requirements.PayTo)
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if l.keys[payment.key] {
+		return groupKey, false
+	}
+	group, ok := l.groups[groupKey]
+	if !ok {
+		group = &deferredGroup{
+			balance: DeferredBalance{
+				Network:      requirements.Network,
+				Asset:        requirements.Asset,
+				Payer:        payer,
+				PayTo:        requirements.PayTo,
+				Transactions: []string{},
+			},
+			pendingAmount: new(big.Int),
+			settledAmount: new(big.Int),
+			failedAmount:  new(big.Int),
+		}
+		l.groups[groupKey] = group
+	}
+	if len(group.pending) == 0 {
+		group.oldest = now
+	}
+	l.keys[payment.key] = true
+	group.pending = append(group.pending, payment)
+	group.pendingAmount.Add(group.pendingAmount, payment.value)
+	return groupKey, l.flushAmount != nil && group.pendingAmount.Cmp(l.flushAmount) >= 0
+}
+
+// due returns the groups whose oldest payment waited the flush interval, or
+// every group with payments when all is set
+func (l *deferredLedger) due(now time.Time, all bool) []string {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	var due []string
+	for groupKey, group := range l.groups {
+		if len(group.pending) > 0 && (all || now.Sub(group.oldest) >= l.interval) {
+			due = append(due, groupKey)
+		}
+	}
+	slices.Sort(due)
+	return due
+}
+
+// take removes a group's pending payments for settlement
+func (l *deferredLedger) take(groupKey string) []deferredPayment {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	group, ok := l.groups[groupKey]
+	if !ok {
+		return nil
+	}
+	pending := group.pending
+	group.pending = nil
+	group.pendingAmount.SetInt64(0)
+	return pending
+}
+
+// settled books the results of a group's batch
+func (l *deferredLedger) settled(groupKey string, payments []deferredPayment, results []types.SettleResponse, now time.Time) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	group := l.groups[groupKey]
+	for i, payment := range payments {
+		delete(l.keys, payment.key)
+		if results[i].Success {
+			group.balance.Settled++
+			group.settledAmount.Add(group.settledAmount, payment.value)
+			if !slices.Contains(group.balance.Transactions, results[i].Transaction) {
+				group.balance.Transactions = append(group.balance.Transactions, results[i].Transaction)
+			}
+			continue
+		}
+		group.balance.Failed++
+		group.failedAmount.Add(group.failedAmount, payment.value)
+	}
+	group.balance.LastFlush = now.Unix()
+}
+
+// pendingCount returns the payments waiting for a batch
+func (l *deferredLedger) pendingCount() int {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	count := 0
+	for _, group := range l.groups {
+		count += len(group.pending)
+	}
+	return count
+}
+
+// balances returns the ledger of every group
+func (l *deferredLedger) balances() []DeferredBalance {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	balances := make([]DeferredBalance, 0, len(l.groups))
+	for _, group := range l.groups {
+		balance := group.balance
+		balance.Pending = len(group.pending)
+		balance.PendingAmount = group.pendingAmount.String()
+		balance.SettledAmount = group.settledAmount.String()
+		balance.FailedAmount = group.failedAmount.String()
+		balance.Transactions = slices.Clone(group.balance.Transactions)
+		balances = append(balances, balance)
+	}
+	slices.SortFunc(balances, func(a, b DeferredBalance) int {
+		return strings.Compare(
+			deferredGroupKey(a.Network, a.Asset, a.Payer, a.PayTo),
+			deferredGroupKey(b.Network, b.Asset, b.Payer, b.PayTo),
+		)
+	})
+	return balances
+}
+
+// deferSettlement verifies an eligible payment and queues it for its batch.
+// It returns nil when the payment must settle right away.
+func (f *Facilitator) deferSettlement(ctx context.Context, req *types.SettleRequest) *types.SettleResponse {
+	if !f.cfg().IsSupported(req.PaymentRequirements.Scheme, req.PaymentRequirements.Network) {
+		return nil
+	}
+	auth, value, ok := f.deferred.eligible(req, f.now())
+	if !ok {
+		return nil
+	}
+
+	// The payment is checked now, the batch only drops transfers that revert
+	if failures := f.verifyPaymentChecks(ctx, &req.PaymentPayload, &req.PaymentRequirements, false); len(failures) > 0 {
+		return settleFailed(failures[0].Code, failures[0].Reason)
+	}
+
+	// Persist the payment until its batch is sent
+	payment := deferredPayment{
+		req:   *req,
+		key:   settlementKey(&req.PaymentPayload, &req.PaymentRequirements),
+		value: value,
+	}
+	if f.journal != nil {
+		name, err := f.journal.add(payment.key, journalEntry{
+			RequestID:  requestIDFromContext(ctx),
+			AcceptedAt: f.now().Unix(),
+			Request:    *req,
+		})
+		if err != nil {
+			return settleFailed(types.ErrCodeSettlementFailed, err.Error())
+		}
+		payment.journal = name
+	}
+
+	groupKey, full := f.deferred.add(payment, auth.From, f.now())
+	f.log(ctx).Info("payment deferred", append(paymentLogAttrs(&req.PaymentPayload, &req.PaymentRequirements), "group", groupKey)...)
+	if full {
+		f.deferred.wg.Add(1)
+		go func() {
+			defer f.deferred.wg.Done()
+			f.flushDeferred(context.WithoutCancel(ctx), groupKey)
+		}()
+	}
+
+	return &types.SettleResponse{
+		Success: true,
+		Status:  types.SettleStatusDeferred,
+		Network: req.PaymentRequirements.Network,
+		Payer:   auth.From,
+	}
+}
+
+// flushDeferred settles a group's pending payments in batches of the batch
+// size
+func (f *Facilitator) flushDeferred(ctx context.Context, groupKey string) {
+	f.deferred.flushMu.Lock()
+	defer f.deferred.flushMu.Unlock()
+
+	payments := f.deferred.take(groupKey)
+	if len(payments) == 0 {
+		return
+	}
+	maxSize := f.cfg().Batch.MaxSize
+	if maxSize <= 0 {
+		maxSize = defaultBatchMaxSize
+	}
+
+	results := make([]types.SettleResponse, 0, len(payments))
+	for chunk := range slices.Chunk(payments, maxSize) {
+		items := make([]types.SettleRequest, len(chunk))
+		for i, payment := range chunk {
+			items[i] = payment.req
+		}
+		results = append(results, f.settleBatch(ctx, items).Results...)
+	}
+	f.deferred.settled(groupKey, payments, results, f.now())
+
+	// Failed payments are not retried, their results are in the ledger
+	for i, payment := range payments {
+		if payment.journal != "" {
+			if err := f.journal.remove(payment.journal); err != nil {
+				f.log(ctx).Warn("failed to update settlement journal", "error", err)
+			}
+		}
+		if !results[i].Success {
+			f.log(ctx).Warn("deferred settlement failed", "group", groupKey, "code", results[i].ErrorReason, "reason", results[i].ErrorMessage)
+		}
+	}
+	f.log(ctx).Info("deferred payments settled", "group", groupKey, "payments", len(payments))
+}
+
+// flushDueDeferred settles the groups whose interval passed, or every group
+// with payments when all is set
+func (f *Facilitator) flushDueDeferred(ctx context.Context, all bool) {
+	for _, groupKey := range f.deferred.due(f.now(), all) {
+		f.flushDeferred(ctx, groupKey)
+	}
+}
+
+// runDeferred flushes deferred payments in the background until ctx is done
+func (f *Facilitator) runDeferred(ctx context.Context) {
+	ticker := time.NewTicker(defaultDeferredPollInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			f.flushDueDeferred(ctx, false)
+		}
+	}
+}
+
+// closeDeferred waits for background flushes and settles every pending
+// payment before shutdown
+func (f *Facilitator) closeDeferred(ctx context.Context) {
+	f.deferred.wg.Wait()
+	f.flushDueDeferred(ctx, true)
+}
+
+func (f *Facilitator) handleDeferredLedger(ctx *gin.Context) {
+	ctx.JSON(http.StatusOK, f.deferred.balances())
+}
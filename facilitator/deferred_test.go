@@ -0,0 +1,91 @@
+package facilitator
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/vorpalengineering/x402-go/types"
+)
+
+func TestDeferredLedger(t *testing.T) {
+	now := time.Unix(1700000000, 0)
+	ledger := newDeferredLedger(DeferredConfig{
+		Enabled:              true,
+		MaxAmount:            "1000000",
+		FlushIntervalSeconds: 60,
+		FlushAmount:          "2500000",
+	})
+	payer := "0xf39Fd6e51aad88F6F4ce6aB8827279cffFb92266"
+	payment := func(nonce string) (deferredPayment, bool) {
+		req := batchSettleRequest(payer, "0x"+strings.Repeat(nonce, 32))
+		_, value, ok := ledger.eligible(&req, now)
+		return deferredPayment{
+			req:   req,
+			key:   settlementKey(&req.PaymentPayload, &req.PaymentRequirements),
+			value: value,
+		}, ok
+	}
+
+	// Payments over the maximum or expiring before a flush settle right away
+	large := batchSettleRequest(payer, "0x"+strings.Repeat("09", 32))
+	large.PaymentPayload.Payload["authorization"].(map[string]any)["value"] = "1000001"
+	if _, _, ok := ledger.eligible(&large, now); ok {
+		t.Error("Expected payment over max_amount not to be deferred")
+	}
+	expiring := batchSettleRequest(payer, "0x"+strings.Repeat("09", 32))
+	expiring.PaymentPayload.Payload["authorization"].(map[string]any)["validBefore"] = now.Add(time.Minute).Unix()
+	if _, _, ok := ledger.eligible(&expiring, now); ok {
+		t.Error("Expected payment expiring before the flush not to be deferred")
+	}
+
+	// Small payments accumulate until the flush amount
+	first, ok := payment("01")
+	if !ok {
+		t.Fatal("Expected payment to be deferred")
+	}
+	groupKey, full := ledger.add(first, payer, now)
+	if full {
+		t.Error("Expected group below flush amount")
+	}
+	ledger.add(first, payer, now)
+	if count := ledger.pendingCount(); count != 1 {
+		t.Errorf("Expected duplicate not to be added, got %d pending", count)
+	}
+	second, _ := payment("02")
+	third, _ := payment("03")
+	ledger.add(second, payer, now.Add(10*time.Second))
+	if _, full := ledger.add(third, payer, now.Add(20*time.Second)); !full {
+		t.Error("Expected group to reach flush amount")
+	}
+
+	// Groups are due once their oldest payment waited the interval
+	if due := ledger.due(now.Add(59*time.Second), false); len(due) != 0 {
+		t.Errorf("Expected no group due, got %v", due)
+	}
+	if due := ledger.due(now.Add(time.Minute), false); len(due) != 1 || due[0] != groupKey {
+		t.Errorf("Expected group %s due, got %v", groupKey, due)
+	}
+
+	// Results of a batch are booked in the ledger
+	payments := ledger.take(groupKey)
+	if len(payments) != 3 || ledger.pendingCount() != 0 {
+		t.Fatalf("Expected 3 payments taken, got %d", len(payments))
+	}
+	ledger.settled(groupKey, payments, []types.SettleResponse{
+		{Success: true, Transaction: "0xabc"},
+		{Success: false, ErrorReason: SettleErrTransactionReverted},
+		{Success: true, Transaction: "0xabc"},
+	}, now.Add(time.Minute))
+	balances := ledger.balances()
+	if len(balances) != 1 {
+		t.Fatalf("Expected 1 balance, got %d", len(balances))
+	}
+	balance := balances[0]
+	if balance.Settled != 2 || balance.SettledAmount != "2000000" || balance.Failed != 1 || balance.FailedAmount != "1000000" {
+		t.Errorf("Unexpected settled balance %+v", balance)
+	}
+	if balance.Pending != 0 || balance.PendingAmount != "0" || len(balance.Transactions) != 1 {
+		t.Errorf("Unexpected pending balance %+v", balance)
+	}
+}
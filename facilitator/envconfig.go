@@ -0,0 +1,343 @@
+package facilitator
+
+import (
+	"fmt"
+	"maps"
+	"os"
+	"reflect"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/vorpalengineering/x402-go/types"
+	"gopkg.in/yaml.v3"
+)
+
+const (
+	envConfigPrefix  = "X402_"
+	envNetworkPrefix = "X402_NETWORK_"
+	envSupported     = "X402_SUPPORTED"
+)
+
+// envNetworkAliases are the network names accepted in X402_NETWORK_<NAME>_*
+// variables and X402_SUPPORTED besides the CAIP-2 id with ":" written as "_"
+var envNetworkAliases = map[string]string{
+	"ETHEREUM":         "eip155:1",
+	"MAINNET":          "eip155:1",
+	"SEPOLIA":          "eip155:11155111",
+	"ETHEREUM_SEPOLIA": "eip155:11155111",
+	"BASE":             "eip155:8453",
+	"BASE_SEPOLIA":     "eip155:84532",
+	"OPTIMISM":         "eip155:10",
+	"ARBITRUM":         "eip155:42161",
+	"POLYGON":          "eip155:137",
+	"AVALANCHE":        "eip155:43114",
+	"SOLANA":           "solana:mainnet",
+	"SOLANA_MAINNET":   "solana:mainnet",
+	"SOLANA_DEVNET":    "solana:devnet",
+	"SOLANA_TESTNET":   "solana:testnet",
+	"SOLANA_LOCALNET":  "solana:localnet",
+}
+
+// LoadConfigFromEnv builds the config from X402_* environment variables alone,
+// for containers that don't mount a config file. Server, transaction and log
+// settings start from the defaults of config.example.yaml, and every
+// configured network supports the exact scheme unless X402_SUPPORTED is set.
+func LoadConfigFromEnv() (*FacilitatorConfig, error) {
+	facilitatorConfig := FacilitatorConfig{
+		Server:      ServerConfig{Host: "0.0.0.0", Port: 4020},
+		Transaction: TransactionConfig{TimeoutSeconds: 120, MaxGasPrice: "100000000000"},
+		Log:         LogConfig{Level: "info"},
+	}
+
+	// Apply environment variables
+	if err := applyEnvOverrides(&facilitatorConfig, os.Environ()); err != nil {
+		return nil, fmt.Errorf("failed to load env config: %w", err)
+	}
+	if _, set := os.LookupEnv(envSupported); !set {
+		for _, network := range slices.Sorted(maps.Keys(facilitatorConfig.Networks)) {
+			facilitatorConfig.Supported = append(facilitatorConfig.Supported, types.SupportedKind{Scheme: "exact", Network: network})
+		}
+	}
+
+	return finishLoadConfig(&facilitatorConfig)
+}
+
+// applyEnvOverrides sets config fields from X402_* variables in environ.
+// Sections and keys are the upper-cased YAML keys joined by "_" (e.g.
+// X402_SERVER_PORT, X402_TRANSACTION_RETRY_MAX_ATTEMPTS), network settings are
+// X402_NETWORK_<NAME>_<KEY> (e.g. X402_NETWORK_BASE_RPC_URL) and
+// X402_SUPPORTED is a comma-separated list of scheme:network pairs that
+// replaces the supported list. Lists are comma-separated.
+func applyEnvOverrides(config *FacilitatorConfig, environ []string) error {
+	vars := make(map[string]string)
+	for _, entry := range environ {
+		name, value, ok := strings.Cut(entry, "=")
+		if ok && strings.HasPrefix(name, envConfigPrefix) {
+			vars[name] = value
+		}
+	}
+	lookup := func(name string) (string, bool) {
+		value, ok := vars[name]
+		return value, ok
+	}
+
+	// Top-level sections
+	if err := applyEnv(reflect.ValueOf(config).Elem(), envConfigPrefix, lookup); err != nil {
+		return err
+	}
+
+	// Networks, grouped by the name between the prefix and a network key
+	networkKeys := envKeys(reflect.TypeOf(NetworkConfig{}), "")
+	networkVars := make(map[string]map[string]string)
+	for name, value := range vars {
+		rest, ok := strings.CutPrefix(name, envNetworkPrefix)
+		if !ok {
+			continue
+		}
+		network, key, found := splitEnvNetworkVar(rest, networkKeys)
+		if !found {
+			return fmt.Errorf("%s: unknown network setting", name)
+		}
+		if networkVars[network] == nil {
+			networkVars[network] = make(map[string]string)
+		}
+		networkVars[network][key] = value
+	}
+	for name, settings := range networkVars {
+		network, err := envNetworkID(name)
+		if err != nil {
+			return fmt.Errorf("%s%s: %w", envNetworkPrefix, name, err)
+		}
+		if config.Networks == nil {
+			config.Networks = make(map[string]NetworkConfig)
+		}
+		networkCfg := config.Networks[network]
+		err = applyEnv(reflect.ValueOf(&networkCfg).Elem(), "", func(key string) (string, bool) {
+			value, ok := settings[key]
+			return value, ok
+		})
+		if err != nil {
+			return fmt.Errorf("network %s: %w", network, err)
+		}
+		config.Networks[network] = networkCfg
+	}
+
+	// Supported scheme-network pairs
+	if value, ok := lookup(envSupported); ok {
+		supported, err := parseEnvSupported(value)
+		if err != nil {
+			return fmt.Errorf("%s: %w", envSupported, err)
+		}
+		config.Supported = supported
+	}
+
+	return nil
+}
+
+// applyEnv sets the scalar and string list fields of the struct v from the
+// variables named prefix plus the upper-cased YAML key, recursing into nested
+// structs. Maps, pointers and lists of sections are left to the file.
+func applyEnv(v reflect.Value, prefix string, lookup func(string) (string, bool)) error {
+	t := v.Type()
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		key, _, _ := strings.Cut(field.Tag.Get("yaml"), ",")
+		if key == "" || key == "-" || !field.IsExported() {
+			continue
+		}
+		name := prefix + strings.ToUpper(key)
+
+		if field.Type.Kind() == reflect.Struct {
+			if err := applyEnv(v.Field(i), name+"_", lookup); err != nil {
+				return err
+			}
+			continue
+		}
+		if !envSettable(field.Type) {
+			continue
+		}
+		value, ok := lookup(name)
+		if !ok {
+			continue
+		}
+		if err := setEnvValue(v.Field(i), value); err != nil {
+			return fmt.Errorf("%s: %w", name, err)
+		}
+	}
+	return nil
+}
+
+// envKeys lists the variable names applyEnv reads for the struct type t
+func envKeys(t reflect.Type, prefix string) []string {
+	var keys []string
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		key, _, _ := strings.Cut(field.Tag.Get("yaml"), ",")
+		if key == "" || key == "-" || !field.IsExported() {
+			continue
+		}
+		name := prefix + strings.ToUpper(key)
+		if field.Type.Kind() == reflect.Struct {
+			keys = append(keys, envKeys(field.Type, name+"_")...)
+		} else if envSettable(field.Type) {
+			keys = append(keys, name)
+		}
+	}
+	return keys
+}
+
+func envSettable(t reflect.Type) bool {
+	switch t.Kind() {
+	case reflect.String, reflect.Bool, reflect.Float32, reflect.Float64,
+		reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return true
+	case reflect.Slice:
+		return t.Elem().Kind() == reflect.String
+	}
+	return false
+}
+
+func setEnvValue(v reflect.Value, value string) error {
+	switch v.Kind() {
+	case reflect.String:
+		v.SetString(value)
+	case reflect.Bool:
+		b, err := strconv.ParseBool(value)
+		if err != nil {
+			return fmt.Errorf("invalid boolean %q", value)
+		}
+		v.SetBool(b)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		n, err := strconv.ParseInt(value, 10, v.Type().Bits())
+		if err != nil {
+			return fmt.Errorf("invalid integer %q", value)
+		}
+		v.SetInt(n)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		n, err := strconv.ParseUint(value, 10, v.Type().Bits())
+		if err != nil {
+			return fmt.Errorf("invalid unsigned integer %q", value)
+		}
+		v.SetUint(n)
+	case reflect.Float32, reflect.Float64:
+		n, err := strconv.ParseFloat(value, v.Type().Bits())
+		if err != nil {
+			return fmt.Errorf("invalid number %q", value)
+		}
+		v.SetFloat(n)
+	case reflect.Slice:
+		var items []string
+		for _, item := range strings.Split(value, ",") {
+			if item = strings.TrimSpace(item); item != "" {
+				items = append(items, item)
+			}
+		}
+		v.Set(reflect.ValueOf(items))
+	}
+	return nil
+}
+
+// splitEnvNetworkVar splits <NAME>_<KEY> at the first "_" that leaves a known
+// network key, so names containing "_" like BASE_SEPOLIA work
+func splitEnvNetworkVar(rest string, keys []string) (network, key string, found bool) {
+	for i := 0; i < len(rest); i++ {
+		if rest[i] != '_' {
+			continue
+		}
+		if slices.Contains(keys, rest[i+1:]) {
+			return rest[:i], rest[i+1:], true
+		}
+	}
+	return "", "", false
+}
+
+// envNetworkID resolves a network alias, or a CAIP-2 id written with "_"
+// instead of ":" (EIP155_8453, SOLANA_DEVNET)
+func envNetworkID(name string) (string, error) {
+	name = strings.ToUpper(name)
+	if network, ok := envNetworkAliases[name]; ok {
+		return network, nil
+	}
+	namespace, reference, ok := strings.Cut(name, "_")
+	if !ok || reference == "" {
+		return "", fmt.Errorf("unknown network %s", name)
+	}
+	return strings.ToLower(namespace) + ":" + strings.ToLower(reference), nil
+}
+
+// parseEnvSupported parses scheme:network pairs, the network being a CAIP-2
+// id or an alias (exact:eip155:8453,exact:base_sepolia)
+func parseEnvSupported(value string) ([]types.SupportedKind, error) {
+	var supported []types.SupportedKind
+	for _, pair := range strings.Split(value, ",") {
+		pair = strings.TrimSpace(pair)
+		if pair == "" {
+			continue
+		}
+		scheme, network, ok := strings.Cut(pair, ":")
+		if !ok || scheme == "" || network == "" {
+			return nil, fmt.Errorf("invalid pair %q (want scheme:network)", pair)
+		}
+		if !strings.Contains(network, ":") {
+			alias, ok := envNetworkAliases[strings.ToUpper(network)]
+			if !ok {
+				return nil, fmt.Errorf("unknown network %s", network)
+			}
+			network = alias
+		}
+		supported = append(supported, types.SupportedKind{Scheme: scheme, Network: network})
+	}
+	return supported, nil
+}
+
+// envReference matches ${VAR} in config values
+var envReference = regexp.MustCompile(`\$\{([A-Za-z_][A-Za-z0-9_]*)\}`)
+
+// interpolateEnv replaces ${VAR} in the scalar values of a parsed config with
+// the environment variable, so secrets like RPC API keys can stay out of the
+// file. Keys and comments are left as-is. Every unset variable is reported,
+// with the line it is referenced on.
+func interpolateEnv(node *yaml.Node) error {
+	var missing []string
+	var walk func(node *yaml.Node)
+	walk = func(node *yaml.Node) {
+		switch node.Kind {
+		case yaml.ScalarNode:
+			if !envReference.MatchString(node.Value) {
+				return
+			}
+			node.Value = envReference.ReplaceAllStringFunc(node.Value, func(reference string) string {
+				name := envReference.FindStringSubmatch(reference)[1]
+				value, ok := os.LookupEnv(name)
+				if !ok {
+					missing = append(missing, fmt.Sprintf("%s (line %d)", name, node.Line))
+				}
+				return value
+			})
+			// Resolve an expanded plain value again so port: ${PORT} decodes
+			// as a number. Quoted, block and tagged values stay strings, so a
+			// secret of "null" or "~" isn't read as null.
+			if node.Style == 0 {
+				node.Tag = ""
+			}
+		case yaml.MappingNode:
+			// Values only, keys sit at even indexes
+			for i := 1; i < len(node.Content); i += 2 {
+				walk(node.Content[i])
+			}
+		default:
+			for _, child := range node.Content {
+				walk(child)
+			}
+		}
+	}
+	walk(node)
+
+	if len(missing) > 0 {
+		return fmt.Errorf("environment variables not set: %s", strings.Join(missing, ", "))
+	}
+	return nil
+}
@@ -0,0 +1,52 @@
+package facilitator
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/accounts/abi"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/ethclient"
+	"github.com/vorpalengineering/x402-go/utils"
+)
+
+// isContractWallet reports whether a payer is a smart contract wallet rather
+// than an EOA
+func isContractWallet(ctx context.Context, client *ethclient.Client, payer common.Address) (bool, error) {
+	code, err := client.CodeAt(ctx, payer, nil)
+	if err != nil {
+		return false, fmt.Errorf("failed to get code: %w", err)
+	}
+	return len(code) > 0, nil
+}
+
+// verifyERC1271Signature asks a contract wallet whether it accepts signature
+// for hash
+func verifyERC1271Signature(ctx context.Context, client *ethclient.Client, wallet common.Address, hash common.Hash, signature []byte) (bool, error) {
+	// Parse the ERC-1271 ABI
+	parsedABI, err := abi.JSON(strings.NewReader(utils.ERC1271IsValidSignatureABI))
+	if err != nil {
+		return false, fmt.Errorf("failed to parse ABI: %w", err)
+	}
+
+	// Encode the isValidSignature call
+	callData, err := parsedABI.Pack("isValidSignature", hash, signature)
+	if err != nil {
+		return false, fmt.Errorf("failed to encode isValidSignature call: %w", err)
+	}
+
+	// A revert means the wallet rejected the signature
+	result, err := client.CallContract(ctx, ethereum.CallMsg{To: &wallet, Data: callData}, nil)
+	if err != nil {
+		if isRevertError(err) {
+			return false, nil
+		}
+		return false, fmt.Errorf("failed to call isValidSignature: %w", err)
+	}
+
+	// Valid signatures return the magic value in the first 4 bytes
+	return len(result) >= 4 && bytes.Equal(result[:4], common.FromHex(utils.ERC1271MagicValue)), nil
+}
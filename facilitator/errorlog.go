@@ -0,0 +1,110 @@
+package facilitator
+
+import (
+	"context"
+	"log/slog"
+	"sync"
+	"time"
+)
+
+const defaultRecentErrors = 100
+
+// RecentError is a warning or error logged by the facilitator
+type RecentError struct {
+	Time    int64             `json:"time"`
+	Level   string            `json:"level"`
+	Message string            `json:"message"`
+	Attrs   map[string]string `json:"attrs,omitempty"`
+}
+
+// errorLog keeps the most recent warnings and errors in a ring buffer
+type errorLog struct {
+	mu      sync.Mutex
+	entries []RecentError
+	next    int
+	full    bool
+}
+
+func newErrorLog(size int) *errorLog {
+	if size <= 0 {
+		size = defaultRecentErrors
+	}
+	return &errorLog{entries: make([]RecentError, size)}
+}
+
+func (l *errorLog) add(entry RecentError) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.entries[l.next] = entry
+	l.next = (l.next + 1) % len(l.entries)
+	if l.next == 0 {
+		l.full = true
+	}
+}
+
+// recent returns the kept entries, newest first
+func (l *errorLog) recent() []RecentError {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	count := l.next
+	if l.full {
+		count = len(l.entries)
+	}
+	recent := make([]RecentError, 0, count)
+	for i := 1; i <= count; i++ {
+		recent = append(recent, l.entries[(l.next-i+len(l.entries))%len(l.entries)])
+	}
+	return recent
+}
+
+// errorLogHandler passes records on to the wrapped handler and keeps warnings
+// and errors in an errorLog, whatever the configured log level
+type errorLogHandler struct {
+	handler slog.Handler
+	log     *errorLog
+	attrs   []slog.Attr
+}
+
+func (h *errorLogHandler) Enabled(ctx context.Context, level slog.Level) bool {
+	return level >= slog.LevelWarn || h.handler.Enabled(ctx, level)
+}
+
+func (h *errorLogHandler) Handle(ctx context.Context, record slog.Record) error {
+	if record.Level >= slog.LevelWarn {
+		entry := RecentError{
+			Time:    record.Time.Unix(),
+			Level:   record.Level.String(),
+			Message: record.Message,
+			Attrs:   make(map[string]string, len(h.attrs)+record.NumAttrs()),
+		}
+		if record.Time.IsZero() {
+			entry.Time = time.Now().Unix()
+		}
+		for _, attr := range h.attrs {
+			entry.Attrs[attr.Key] = attr.Value.String()
+		}
+		record.Attrs(func(attr slog.Attr) bool {
+			entry.Attrs[attr.Key] = attr.Value.String()
+			return true
+		})
+		h.log.add(entry)
+	}
+	if !h.handler.Enabled(ctx, record.Level) {
+		return nil
+	}
+	return h.handler.Handle(ctx, record)
+}
+
+func (h *errorLogHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	return &errorLogHandler{
+		handler: h.handler.WithAttrs(attrs),
+		log:     h.log,
+		attrs:   append(h.attrs[:len(h.attrs):len(h.attrs)], attrs...),
+	}
+}
+
+func (h *errorLogHandler) WithGroup(name string) slog.Handler {
+	return &errorLogHandler{handler: h.handler.WithGroup(name), log: h.log, attrs: h.attrs}
+}
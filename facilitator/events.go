@@ -0,0 +1,194 @@
+package facilitator
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"log/slog"
+	"sync"
+	"time"
+
+	"github.com/vorpalengineering/x402-go/types"
+	"github.com/vorpalengineering/x402-go/utils"
+)
+
+// Event types published to the event bus
+const (
+	EventVerify           = "verify"
+	EventSettle           = "settle"
+	EventSettleConfirmed  = "settle.confirmed"
+	EventSettleReorged    = "settle.reorged"
+	EventSettleReplaced   = "settle.replaced"
+	EventRefund           = "refund"
+	EventWebhookDelivered = "webhook.delivered"
+	EventWebhookFailed    = "webhook.failed"
+)
+
+const (
+	defaultEventSubjectPrefix = "x402"
+	defaultEventBufferSize    = 1024
+	eventPublishTimeout       = 10 * time.Second
+)
+
+// Event is a facilitator lifecycle event. Fields that don't apply to an
+// event type are omitted.
+type Event struct {
+	ID          string `json:"id"`
+	Type        string `json:"type"`
+	Timestamp   int64  `json:"timestamp"`
+	Scheme      string `json:"scheme,omitempty"`
+	Network     string `json:"network,omitempty"`
+	Asset       string `json:"asset,omitempty"`
+	PayTo       string `json:"payTo,omitempty"`
+	Payer       string `json:"payer,omitempty"`
+	Amount      string `json:"amount,omitempty"`
+	Success     bool   `json:"success"`
+	Code        string `json:"code,omitempty"`
+	Reason      string `json:"reason,omitempty"`
+	Transaction string `json:"transaction,omitempty"`
+	Settlement  string `json:"settlement,omitempty"`
+	URL         string `json:"url,omitempty"`
+	AlertType   string `json:"alertType,omitempty"`
+}
+
+// EventPublisher delivers an encoded event to a Kafka topic or NATS subject.
+// Wrap the client of your choice to satisfy it. NATS publishers can ignore key.
+type EventPublisher interface {
+	Publish(ctx context.Context, subject string, key, data []byte) error
+}
+
+// eventBus publishes events in order from a single worker so request
+// handlers never wait on the broker. Events are dropped when the buffer is full.
+type eventBus struct {
+	publisher EventPublisher
+	prefix    string
+	events    chan Event
+	done      chan struct{}
+	closeOnce sync.Once
+	logger    *slog.Logger
+}
+
+func newEventBus(publisher EventPublisher, cfg EventsConfig, logger *slog.Logger) *eventBus {
+	prefix := cfg.SubjectPrefix
+	if prefix == "" {
+		prefix = defaultEventSubjectPrefix
+	}
+	bufferSize := cfg.BufferSize
+	if bufferSize <= 0 {
+		bufferSize = defaultEventBufferSize
+	}
+
+	bus := &eventBus{
+		publisher: publisher,
+		prefix:    prefix,
+		events:    make(chan Event, bufferSize),
+		done:      make(chan struct{}),
+		logger:    logger,
+	}
+	go bus.run()
+	return bus
+}
+
+func (b *eventBus) run() {
+	defer close(b.done)
+	for event := range b.events {
+		data, err := json.Marshal(event)
+		if err != nil {
+			b.logger.Error("failed to encode event", "type", event.Type, "error", err)
+			continue
+		}
+
+		ctx, cancel := context.WithTimeout(context.Background(), eventPublishTimeout)
+		if err := b.publisher.Publish(ctx, b.subject(event.Type), []byte(event.Payer), data); err != nil {
+			b.logger.Error("failed to publish event", "type", event.Type, "error", err)
+		}
+		cancel()
+	}
+}
+
+// subject maps an event type to "<prefix>.<type>", e.g. "x402.settle"
+func (b *eventBus) subject(eventType string) string {
+	return b.prefix + "." + eventType
+}
+
+func (b *eventBus) publish(event Event) {
+	select {
+	case b.events <- event:
+	default:
+		b.logger.Warn("event buffer full, dropping event", "type", event.Type)
+	}
+}
+
+// close stops accepting events and waits for buffered ones to be published
+func (b *eventBus) close() {
+	b.closeOnce.Do(func() {
+		close(b.events)
+	})
+	<-b.done
+}
+
+// SetEventPublisher enables publishing verify, settle and webhook delivery
+// events through publisher, in place of the NATS server of events.nats.
+// Call before Run. Close flushes buffered events.
+func (f *Facilitator) SetEventPublisher(publisher EventPublisher) {
+	if f.events != nil {
+		f.events.close()
+	}
+	f.events = newEventBus(publisher, f.cfg().Events, f.logger)
+}
+
+// publishEvent stamps and queues an event if a publisher is set, and sends
+// it to the SSE subscribers if enabled
+func (f *Facilitator) publishEvent(event Event) {
+	if f.events == nil && f.sse == nil {
+		return
+	}
+
+	var id [16]byte
+	rand.Read(id[:])
+	event.ID = hex.EncodeToString(id[:])
+	event.Timestamp = f.now().Unix()
+
+	if f.events != nil {
+		f.events.publish(event)
+	}
+	if f.sse != nil {
+		f.sse.publish(event)
+	}
+}
+
+// paymentEvent fills the payment fields shared by verify and settle events
+func paymentEvent(eventType string, payload *types.PaymentPayload, requirements *types.PaymentRequirements) Event {
+	event := Event{
+		Type:    eventType,
+		Scheme:  requirements.Scheme,
+		Network: requirements.Network,
+		Asset:   requirements.Asset,
+		PayTo:   requirements.PayTo,
+		Amount:  requirements.Amount,
+	}
+	if auth, err := utils.ExtractExactAuthorization(payload); err == nil {
+		event.Payer = auth.From
+		if auth.Value != "" {
+			event.Amount = auth.Value
+		}
+	}
+	return event
+}
+
+// publishAlertDelivery reports the outcome of an alert webhook delivery
+func (f *Facilitator) publishAlertDelivery(url string, alert Alert, err error) {
+	event := Event{
+		Type:      EventWebhookDelivered,
+		Network:   alert.Network,
+		Success:   err == nil,
+		URL:       url,
+		AlertType: alert.Type,
+	}
+	if err != nil {
+		event.Type = EventWebhookFailed
+		event.Reason = err.Error()
+	}
+	f.publishEvent(event)
+}
@@ -0,0 +1,106 @@
+package facilitator
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/vorpalengineering/x402-go/types"
+)
+
+type recordingPublisher struct {
+	mu       sync.Mutex
+	subjects []string
+	keys     []string
+	events   []Event
+}
+
+func (p *recordingPublisher) Publish(ctx context.Context, subject string, key, data []byte) error {
+	var event Event
+	if err := json.Unmarshal(data, &event); err != nil {
+		return err
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.subjects = append(p.subjects, subject)
+	p.keys = append(p.keys, string(key))
+	p.events = append(p.events, event)
+	return nil
+}
+
+func TestVerifyPublishesEvent(t *testing.T) {
+	testConfig := &FacilitatorConfig{
+		Networks: map[string]NetworkConfig{
+			"eip155:8453": {
+				RpcUrl: "http://127.0.0.1:1",
+			},
+		},
+		Supported: []types.SupportedKind{
+			{Scheme: "exact", Network: "eip155:8453"},
+		},
+		Log: LogConfig{
+			Level: "info",
+		},
+		Events: EventsConfig{
+			SubjectPrefix: "payments",
+		},
+	}
+
+	// Create facilitator with publisher
+	f := NewFacilitator(testConfig)
+	publisher := &recordingPublisher{}
+	f.SetEventPublisher(publisher)
+
+	requirements := types.PaymentRequirements{
+		Scheme:  "exact",
+		Network: "eip155:8453",
+		Amount:  "1000000",
+		PayTo:   "0x742d35Cc6634C0532925a3b844Bc9e7595f0bEb",
+		Asset:   "0x833589fCD6eDb6E08f4c7C32D4f71b54bdA02913",
+	}
+	payer := "0xf39Fd6e51aad88F6F4ce6aB8827279cffFb92266"
+	body, _ := json.Marshal(types.VerifyRequest{
+		PaymentPayload: types.PaymentPayload{
+			X402Version: 2,
+			Accepted:    requirements,
+			Payload: map[string]any{
+				"signature": "0x" + strings.Repeat("11", 65),
+				"authorization": map[string]any{
+					"from":        payer,
+					"to":          requirements.PayTo,
+					"value":       "1000000",
+					"validAfter":  0,
+					"validBefore": 0,
+					"nonce":       "0x" + strings.Repeat("00", 32),
+				},
+			},
+		},
+		PaymentRequirements: requirements,
+	})
+
+	req, _ := http.NewRequest("POST", "/verify", bytes.NewReader(body))
+	recorder := httptest.NewRecorder()
+	f.router.ServeHTTP(recorder, req)
+
+	// Close flushes buffered events
+	f.Close()
+
+	if len(publisher.events) != 1 {
+		t.Fatalf("Expected 1 event, got %d", len(publisher.events))
+	}
+	event := publisher.events[0]
+	if publisher.subjects[0] != "payments.verify" {
+		t.Errorf("Expected subject payments.verify, got %s", publisher.subjects[0])
+	}
+	if publisher.keys[0] != payer || event.Payer != payer {
+		t.Errorf("Expected event keyed by payer %s, got %s", payer, publisher.keys[0])
+	}
+	if event.Type != EventVerify || event.Success || event.Reason == "" || event.ID == "" {
+		t.Errorf("Expected failed verify event with reason and id, got %+v", event)
+	}
+}
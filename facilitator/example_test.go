@@ -0,0 +1,51 @@
+package facilitator_test
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/vorpalengineering/x402-go/facilitator"
+	"github.com/vorpalengineering/x402-go/types"
+)
+
+func ExampleFacilitator_Run() {
+	// Services usually call facilitator.LoadConfig; the signer key comes from
+	// X402_FACILITATOR_PRIVATE_KEY there
+	privateKey, err := crypto.HexToECDSA("ac0974bec39a17e36ba4a6b4d238ff944bacb478cbed5efcae784d7bf4f2ff80")
+	if err != nil {
+		log.Fatal(err)
+	}
+	config := &facilitator.FacilitatorConfig{
+		Server: facilitator.ServerConfig{Host: "127.0.0.1", Port: 0},
+		Networks: map[string]facilitator.NetworkConfig{
+			"eip155:8453": {RpcUrl: "http://127.0.0.1:8545"},
+		},
+		Supported: []types.SupportedKind{
+			{X402Version: 2, Scheme: "exact", Network: "eip155:8453"},
+		},
+		Transaction: facilitator.TransactionConfig{TimeoutSeconds: 120},
+		Log:         facilitator.LogConfig{Level: "info"},
+		Signer: facilitator.SignerConfig{
+			Address:    crypto.PubkeyToAddress(privateKey.PublicKey),
+			PrivateKey: privateKey,
+		},
+	}
+
+	f := facilitator.NewFacilitator(config)
+	defer f.Close()
+
+	// Run serves until the context is cancelled, then shuts down gracefully.
+	// Services usually pass signal.NotifyContext(ctx, os.Interrupt).
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+	if err := f.Run(ctx); err != nil {
+		log.Fatal(err)
+	}
+	fmt.Println("stopped")
+
+	// Output:
+	// stopped
+}
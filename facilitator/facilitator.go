@@ -2,22 +2,61 @@ package facilitator
 
 import (
 	"context"
+	"crypto/tls"
 	"fmt"
-	"log"
+	"log/slog"
+	"maps"
 	"net/http"
 	"sync"
+	"sync/atomic"
 	"time"
 
+	"github.com/ethereum/go-ethereum/common"
 	"github.com/ethereum/go-ethereum/ethclient"
+	"github.com/ethereum/go-ethereum/rpc"
 	"github.com/gin-gonic/gin"
+	"github.com/vorpalengineering/x402-go/svm"
 	"github.com/vorpalengineering/x402-go/types"
+	"github.com/vorpalengineering/x402-go/utils"
+	"github.com/vorpalengineering/x402-go/version"
 )
 
 type Facilitator struct {
-	config       *FacilitatorConfig
+	config       atomic.Pointer[FacilitatorConfig]
 	router       *gin.Engine
+	logger       *slog.Logger
 	rpcClients   map[string]*ethclient.Client
+	rpcFailovers map[string]*failoverTransport
+	svmClients   map[string]*svm.Client
 	rpcClientsMu sync.RWMutex
+	now          func() time.Time
+	statements   *statementStore
+	settlements  *settlementGroup
+	alerts       *alerter
+	events       *eventBus
+	nats         *NATSPublisher
+	sse          *sseHub
+	gasOptimizer *gasOptimizer
+	jobs         *settleJobs
+	streams      *streamStore
+	tokens       *tokenCache
+	quotas       *quotaLimiter
+	signers      *signerRegistry
+	nonces       *nonceManager
+	sweeps       sync.WaitGroup
+	recentErrors *errorLog
+	audit        *auditLog
+	reorgs       *reorgWatcher
+	replacements *replacementTracker
+	settlePool   *settlePool
+	redis        *redisState
+	journal      *settleJournal
+	deferred     *deferredLedger
+	payees       map[common.Address]Signer
+
+	readinessChecks          map[string]ReadinessCheck
+	confirmationPollInterval time.Duration
+	signerDrainInterval      time.Duration
 }
 
 func NewFacilitator(config *FacilitatorConfig) *Facilitator {
@@ -28,14 +67,125 @@ func NewFacilitator(config *FacilitatorConfig) *Facilitator {
 		gin.SetMode(gin.ReleaseMode)
 	}
 
-	// Create Gin router
-	router := gin.Default()
+	// Settle from the backend's account when a custom signer is set
+	if config.Signer.Backend != nil && config.Signer.Address == (common.Address{}) {
+		config.Signer.Address = config.Signer.Backend.Address()
+	}
+
+	// Create Gin router, requests are logged by requestLogger. Forwarded
+	// client IPs are only read from trusted proxies, Validate checks them.
+	router := gin.New()
+	router.SetTrustedProxies(config.Server.TrustedProxies)
+	router.Use(gin.Recovery())
 
 	// Create Facilitator instance
 	f := &Facilitator{
-		config:     config,
-		router:     router,
-		rpcClients: make(map[string]*ethclient.Client),
+		router:       router,
+		logger:       utils.NewLogger(config.Log.Level, config.Log.Format),
+		rpcClients:   make(map[string]*ethclient.Client),
+		rpcFailovers: make(map[string]*failoverTransport),
+		svmClients:   make(map[string]*svm.Client),
+		now:          time.Now,
+		settlements:  newSettlementGroup(),
+		gasOptimizer: newGasOptimizer(),
+		nonces:       newNonceManager(),
+		tokens:       newTokenCache(config.Tokens),
+		reorgs:       newReorgWatcher(),
+		replacements: newReplacementTracker(),
+		settlePool:   newSettlePool(),
+
+		readinessChecks:          make(map[string]ReadinessCheck),
+		confirmationPollInterval: defaultConfirmationPollInterval,
+		signerDrainInterval:      defaultSignerDrainInterval,
+	}
+	f.config.Store(config)
+
+	// Keep recent warnings and errors for the admin API if enabled
+	if config.Admin.Enabled {
+		f.recentErrors = newErrorLog(config.Admin.RecentErrors)
+		f.logger = slog.New(&errorLogHandler{handler: f.logger.Handler(), log: f.recentErrors})
+	}
+	f.signers = newSignerRegistry(config, func() time.Time { return f.now() })
+
+	// Keep settled payments for statements and refunds if enabled
+	if config.Statements.Enabled || config.Refunds.Enabled {
+		f.statements = newStatementStore(config.Statements)
+	}
+
+	// Send refunds to payTo addresses with a key from them
+	if config.Refunds.Enabled {
+		f.payees = make(map[common.Address]Signer)
+		for payTo, signerCfg := range config.Refunds.PayeeSigners {
+			if signer := signerCfg.signer(); signer != nil {
+				f.payees[common.HexToAddress(payTo)] = signer
+			}
+		}
+	}
+
+	// Notify operators of critical conditions if enabled
+	if config.Alerts.Enabled {
+		f.alerts = newAlerter(config.Alerts)
+		f.alerts.onWebhookDelivery = f.publishAlertDelivery
+		f.alerts.logger = f.logger
+	}
+
+	// Publish events to NATS if configured
+	if config.Events.NATS.enabled() {
+		f.nats = NewNATSPublisher(config.Events.NATS)
+		f.events = newEventBus(f.nats, config.Events, f.logger)
+	}
+
+	// Stream events to dashboards if enabled
+	if config.Events.SSE.Enabled {
+		f.sse = newSSEHub(config.Events.SSE)
+	}
+
+	// Rate limit clients if enabled
+	if config.Quotas.Enabled {
+		f.quotas = newQuotaLimiter(config.Quotas, func() time.Time { return f.now() })
+	}
+
+	// Run asynchronous settlements in the background
+	f.jobs = newSettleJobs(config.SettleJobs, func() time.Time { return f.now() }, f.settle)
+
+	// Persist accepted settlements until they finish if enabled, Run
+	// refuses to start without the journal
+	if config.SettleJobs.JournalDir != "" {
+		journal, err := openSettleJournal(config.SettleJobs.JournalDir)
+		if err != nil {
+			f.logger.Error("failed to open settlement journal", "error", err)
+		}
+		f.journal = journal
+		f.jobs.journal = journal
+		f.jobs.logger = f.logger
+	}
+
+	// Record verify and settle decisions if enabled, Run refuses to start
+	// without the log
+	if config.Audit.Enabled {
+		audit, err := openAuditLog(config.Audit.File)
+		if err != nil {
+			f.logger.Error("failed to open audit log", "error", err)
+		}
+		f.audit = audit
+	}
+
+	// Share settlement claims and nonces with other replicas if configured
+	if config.Redis.enabled() {
+		f.redis = newRedisState(config.Redis)
+		f.settlements.shared = f.redis
+		f.nonces.shared = f.redis
+		f.readinessChecks["redis"] = f.redis.ping
+	}
+
+	// Keep payment streams if enabled
+	if config.Streams.Enabled {
+		f.streams = newStreamStore(config.Streams)
+	}
+
+	// Batch small payments if enabled
+	if config.Deferred.Enabled {
+		f.deferred = newDeferredLedger(config.Deferred)
 	}
 
 	// Register routes
@@ -44,23 +194,90 @@ func NewFacilitator(config *FacilitatorConfig) *Facilitator {
 	return f
 }
 
-func (f *Facilitator) Run(ctx context.Context) error {
+// Start connects to the configured networks and starts background work
+// (alerts, transaction replacement, streams and deferred batches) until ctx
+// is done, without serving HTTP. Run calls it. Use it with Verify and Settle
+// to embed the facilitator in another server.
+func (f *Facilitator) Start(ctx context.Context) error {
 	// Initialize RPC connections
-	log.Println("Initializing RPC connections...")
+	build := version.Get()
+	f.logger.Info("x402 facilitator", "version", build.Version, "commit", build.Commit)
+	if f.cfg().Audit.Enabled && f.audit == nil {
+		return fmt.Errorf("audit log %s could not be opened", f.cfg().Audit.File)
+	}
+	if f.cfg().SettleJobs.JournalDir != "" && f.journal == nil {
+		return fmt.Errorf("settlement journal %s could not be opened", f.cfg().SettleJobs.JournalDir)
+	}
+	f.logger.Info("initializing RPC connections")
 	if err := f.DialRPCClients(); err != nil {
 		return fmt.Errorf("failed to initialize RPC clients: %w", err)
 	}
-	log.Println("RPC connections established")
+	f.logger.Info("RPC connections established")
+
+	// Settle again what the previous run accepted but didn't finish
+	if f.journal != nil {
+		if err := f.recoverSettlements(); err != nil {
+			return err
+		}
+	}
+
+	// Start alert checks
+	if f.alerts != nil {
+		go f.monitorAlerts(ctx)
+	}
+
+	// Replace stuck settlement transactions
+	if f.cfg().Transaction.Replacement.enabled() {
+		go f.monitorStuckTransactions(ctx)
+	}
+
+	// Settle payment streams at intervals
+	if f.streams != nil {
+		go f.runStreams(ctx)
+	}
+
+	// Settle deferred payments in batches
+	if f.deferred != nil {
+		go f.runDeferred(ctx)
+	}
+
+	return nil
+}
+
+func (f *Facilitator) Run(ctx context.Context) error {
+	if err := f.Start(ctx); err != nil {
+		return err
+	}
+
+	// Load TLS certificates if the server terminates HTTPS itself
+	var tlsConfig *tls.Config
+	if f.cfg().Server.TLS.Enabled() {
+		var err error
+		tlsConfig, err = f.cfg().Server.TLS.load()
+		if err != nil {
+			return fmt.Errorf("failed to load TLS config: %w", err)
+		}
+	}
 
 	// Start server
-	addr := fmt.Sprintf("%s:%d", f.config.Server.Host, f.config.Server.Port)
-	log.Printf("Starting x402 Facilitator service on %s", addr)
-	log.Printf("Supported Schemes: %v", f.config.Supported)
+	addr := fmt.Sprintf("%s:%d", f.cfg().Server.Host, f.cfg().Server.Port)
+	f.logger.Info("starting facilitator service",
+		"addr", addr,
+		"tls", tlsConfig != nil,
+		"mtls", tlsConfig != nil && tlsConfig.ClientCAs != nil,
+		"supported", f.cfg().Supported,
+	)
 
 	// Create HTTP server with our router
 	srv := &http.Server{
-		Addr:    addr,
-		Handler: f.router,
+		Addr:      addr,
+		Handler:   f.router,
+		TLSConfig: tlsConfig,
+	}
+
+	// End event streams so shutdown doesn't wait on them
+	if f.sse != nil {
+		srv.RegisterOnShutdown(f.sse.close)
 	}
 
 	// Channel to receive server errors
@@ -68,7 +285,13 @@ func (f *Facilitator) Run(ctx context.Context) error {
 
 	// Start server in a goroutine
 	go func() {
-		if err := srv.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+		var err error
+		if tlsConfig != nil {
+			err = srv.ListenAndServeTLS("", "")
+		} else {
+			err = srv.ListenAndServe()
+		}
+		if err != nil && err != http.ErrServerClosed {
 			serverErrors <- fmt.Errorf("failed to start server: %w", err)
 		}
 	}()
@@ -78,7 +301,7 @@ func (f *Facilitator) Run(ctx context.Context) error {
 	case err := <-serverErrors:
 		return err
 	case <-ctx.Done():
-		log.Println("Shutting down facilitator service...")
+		f.logger.Info("shutting down facilitator service")
 
 		// Create shutdown context with timeout
 		shutdownCtx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
@@ -89,13 +312,52 @@ func (f *Facilitator) Run(ctx context.Context) error {
 			return fmt.Errorf("server shutdown failed: %w", err)
 		}
 
-		log.Println("Facilitator service stopped")
+		f.logger.Info("facilitator service stopped")
 		return nil
 	}
 }
 
 func (f *Facilitator) Close() {
+	// Let queued settlements finish before dropping RPC connections
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+	f.jobs.close(ctx)
+	if f.streams != nil {
+		f.closeStreams(ctx)
+	}
+	if f.deferred != nil {
+		f.closeDeferred(ctx)
+	}
+	f.sweeps.Wait()
+	f.reorgs.close()
+
+	f.signers.close()
+	for _, payee := range f.payees {
+		if s, ok := payee.(*privateKeySigner); ok {
+			s.wipe()
+		}
+	}
+	if solanaCfg := f.cfg().Solana; solanaCfg.ownsKey {
+		clear(solanaCfg.PrivateKey)
+	}
 	f.closeAllRPCClients()
+	if f.redis != nil {
+		f.redis.close()
+	}
+	if f.events != nil {
+		f.events.close()
+	}
+	if f.nats != nil {
+		f.nats.Close()
+	}
+	if f.sse != nil {
+		f.sse.close()
+	}
+	if f.audit != nil {
+		if err := f.audit.close(); err != nil {
+			f.logger.Error("failed to close audit log", "error", err)
+		}
+	}
 }
 
 func (f *Facilitator) DialRPCClients() error {
@@ -103,14 +365,22 @@ func (f *Facilitator) DialRPCClients() error {
 	f.rpcClientsMu.Lock()
 	defer f.rpcClientsMu.Unlock()
 
-	// Dial eth client for each network in config
-	for network := range f.config.Networks {
-		networkCfg, err := f.config.GetNetworkConfig(network)
+	// Dial eth client for each EVM network in config
+	for network := range f.cfg().Networks {
+		if utils.IsSolanaNetwork(network) {
+			continue
+		}
+		networkCfg, err := f.cfg().GetNetworkConfig(network)
 		if err != nil {
 			return fmt.Errorf("failed to get config for %s: %w", network, err)
 		}
 
-		client, err := ethclient.Dial(networkCfg.RpcUrl)
+		// Make sure every endpoint is on the network's chain
+		if err := f.checkChainID(network, networkCfg); err != nil {
+			return fmt.Errorf("failed to check %s RPC: %w", network, err)
+		}
+
+		client, err := f.dialRPC(network, networkCfg)
 		if err != nil {
 			return fmt.Errorf("failed to connect to %s RPC: %w", network, err)
 		}
@@ -138,12 +408,12 @@ func (f *Facilitator) getRPCClient(network string) (*ethclient.Client, error) {
 		return client, nil
 	}
 
-	networkCfg, err := f.config.GetNetworkConfig(network)
+	networkCfg, err := f.cfg().GetNetworkConfig(network)
 	if err != nil {
 		return nil, err
 	}
 
-	client, err := ethclient.Dial(networkCfg.RpcUrl)
+	client, err := f.dialRPC(network, networkCfg)
 	if err != nil {
 		return nil, fmt.Errorf("failed to connect to RPC: %w", err)
 	}
@@ -152,6 +422,48 @@ func (f *Facilitator) getRPCClient(network string) (*ethclient.Client, error) {
 	return client, nil
 }
 
+// dialRPC connects to a network's RPC endpoints. Callers must hold rpcClientsMu.
+func (f *Facilitator) dialRPC(network string, networkCfg NetworkConfig) (*ethclient.Client, error) {
+	rpcURLs := networkCfg.GetRpcUrls()
+	if len(rpcURLs) == 0 {
+		return nil, fmt.Errorf("no rpc url configured for %s", network)
+	}
+
+	// Plain dial unless RPC traffic needs to be captured or failed over
+	if !f.cfg().Capture.Enabled && len(rpcURLs) == 1 {
+		return ethclient.Dial(rpcURLs[0])
+	}
+
+	// Spread calls over the endpoints with failover
+	var transport http.RoundTripper = http.DefaultTransport
+	if len(rpcURLs) > 1 {
+		failover, err := newFailoverTransport(network, transport, rpcURLs, networkCfg.Failover, f.logger)
+		if err != nil {
+			return nil, err
+		}
+		if previous, ok := f.rpcFailovers[network]; ok {
+			previous.close()
+		}
+		f.rpcFailovers[network] = failover
+		transport = failover
+	}
+
+	// Route RPC calls through the capture transport
+	if f.cfg().Capture.Enabled {
+		transport = &captureTransport{network: network, base: transport}
+	}
+
+	rpcClient, err := rpc.DialOptions(context.Background(), rpcURLs[0], rpc.WithHTTPClient(&http.Client{Transport: transport}))
+	if err != nil {
+		if failover, ok := f.rpcFailovers[network]; ok {
+			failover.close()
+			delete(f.rpcFailovers, network)
+		}
+		return nil, err
+	}
+	return ethclient.NewClient(rpcClient), nil
+}
+
 func (f *Facilitator) closeAllRPCClients() {
 	// Acquire write lock
 	f.rpcClientsMu.Lock()
@@ -162,79 +474,346 @@ func (f *Facilitator) closeAllRPCClients() {
 		client.Close()
 	}
 	f.rpcClients = make(map[string]*ethclient.Client)
+
+	// Stop health checks
+	for _, failover := range f.rpcFailovers {
+		failover.close()
+	}
+	f.rpcFailovers = make(map[string]*failoverTransport)
+	f.svmClients = make(map[string]*svm.Client)
 }
 
 func (f *Facilitator) registerRoutes() {
-	f.router.POST("/verify", f.handleVerify)
-	f.router.POST("/settle", f.handleSettle)
+	f.router.Use(f.requestLogger())
+	if f.quotas != nil {
+		f.router.Use(f.quotaMiddleware())
+		f.router.GET("/metrics/quotas", f.handleQuotaMetrics)
+	}
+
+	// Record verify/settle traffic when capture mode is enabled
+	handlers := []gin.HandlerFunc{}
+	if f.cfg().Capture.Enabled {
+		handlers = append(handlers, f.captureMiddleware())
+	}
+
+	f.router.POST("/verify", append(handlers, f.handleVerify)...)
+	f.router.POST("/settle", append(handlers, f.handleSettle)...)
+	f.router.POST("/simulate", f.handleSimulate)
+	f.router.GET("/settle/:jobId", f.handleSettleJob)
+	if f.cfg().Batch.Enabled {
+		f.router.POST("/settle/batch", append(handlers, f.handleSettleBatch)...)
+	}
 	f.router.GET("/supported", f.handleSupported)
+	f.router.GET("/version", f.handleVersion)
+	f.router.GET("/healthz", f.handleHealthz)
+	f.router.GET("/readyz", f.handleReadyz)
+	f.router.GET("/metrics/gas", f.handleGasMetrics)
+	f.router.GET("/metrics/reorgs", f.handleReorgMetrics)
+	f.router.GET("/metrics/settlements", f.handleSettlementMetrics)
+	f.router.GET("/openapi.json", f.handleOpenAPI)
+
+	if f.cfg().Streams.Enabled {
+		f.router.POST("/streams", f.handleOpenStream)
+		f.router.GET("/streams/:streamId", f.handleGetStream)
+		f.router.POST("/streams/:streamId/advance", f.handleAdvanceStream)
+		f.router.POST("/streams/:streamId/close", f.handleCloseStream)
+	}
+
+	if f.cfg().Statements.Enabled {
+		f.router.GET("/statements/challenge", f.handleStatementChallenge)
+		f.router.POST("/statements", f.handleStatement)
+	}
+
+	if f.sse != nil {
+		if f.cfg().Events.SSE.RequireAdminToken {
+			f.router.GET("/events", f.adminAuth(), f.handleEvents)
+		} else {
+			f.router.GET("/events", f.handleEvents)
+		}
+	}
+
+	if f.cfg().Admin.Enabled {
+		f.registerAdminRoutes()
+		if f.cfg().Refunds.Enabled {
+			f.router.POST("/refund", f.adminAuth(), f.handleRefund)
+		}
+	}
 }
 
 func (f *Facilitator) handleVerify(ginCtx *gin.Context) {
-	// Decode request
-	var req types.VerifyRequest
-	if err := ginCtx.ShouldBindJSON(&req); err != nil {
+	// Decode request, in either wire format
+	wireReq, wireVersion, err := bindPaymentRequest(ginCtx)
+	if err != nil {
 		ginCtx.JSON(http.StatusBadRequest, gin.H{
 			"error": err.Error(),
 		})
 		return
 	}
+	req := types.VerifyRequest{
+		PaymentPayload:      wireReq.PaymentPayload,
+		PaymentRequirements: wireReq.PaymentRequirements,
+	}
 
+	// Verify request (?report=full runs every check instead of stopping at the first failure)
+	res := f.verify(ginCtx.Request.Context(), &req, wireVersion, ginCtx.Query("report") == "full")
+	ginCtx.JSON(http.StatusOK, res)
+}
+
+// verify verifies a payment, and logs and publishes the outcome
+func (f *Facilitator) verify(ctx context.Context, req *types.VerifyRequest, wireVersion int, fullReport bool) *types.VerifyResponse {
 	// Check scheme-network pair is supported
-	if !f.config.IsSupported(req.PaymentRequirements.Scheme, req.PaymentRequirements.Network) {
-		res := types.VerifyResponse{
-			IsValid:       false,
-			InvalidReason: fmt.Sprintf("unsupported scheme-network: %s-%s", req.PaymentRequirements.Scheme, req.PaymentRequirements.Network),
+	if !f.cfg().IsSupported(req.PaymentRequirements.Scheme, req.PaymentRequirements.Network) {
+		code := types.ErrCodeUnsupportedScheme
+		if _, err := f.cfg().GetNetworkConfig(req.PaymentRequirements.Network); err != nil {
+			code = types.ErrCodeUnsupportedNetwork
+		}
+		return &types.VerifyResponse{
+			X402Version:    wireVersion,
+			IsValid:        false,
+			InvalidReason:  code,
+			InvalidMessage: fmt.Sprintf("unsupported scheme-network: %s-%s", req.PaymentRequirements.Scheme, req.PaymentRequirements.Network),
 		}
-		ginCtx.JSON(http.StatusOK, res)
-		return
 	}
 
-	// Extract context from HTTP request
-	ctx := ginCtx.Request.Context()
-
-	// Verify request
-	isValid, invalidReason := f.verifyPayment(ctx, &req.PaymentPayload, &req.PaymentRequirements)
+	// Run the scheme's checks
+	failures := f.verifyPaymentChecks(ctx, &req.PaymentPayload, &req.PaymentRequirements, fullReport)
 
 	// Craft response
 	res := types.VerifyResponse{
-		IsValid:       isValid,
-		InvalidReason: invalidReason,
+		X402Version: wireVersion,
+		IsValid:     len(failures) == 0,
+	}
+	if len(failures) > 0 {
+		res.InvalidReason = failures[0].Code
+		res.InvalidMessage = failures[0].Reason
+		if fullReport {
+			res.Failures = failures
+		}
+	}
+	if auth, err := utils.ExtractExactAuthorization(&req.PaymentPayload); err == nil {
+		res.Payer = auth.From
+	} else if _, transfer, _, err := decodeSVMPayment(&req.PaymentPayload); err == nil {
+		res.Payer = transfer.Authority.String()
 	}
 
-	ginCtx.JSON(http.StatusOK, res)
+	// Log outcome
+	logger := f.log(ctx).With(paymentLogAttrs(&req.PaymentPayload, &req.PaymentRequirements)...)
+	if res.IsValid {
+		logger.Debug("payment verified")
+	} else {
+		logger.Info("payment invalid", "code", res.InvalidReason, "reason", res.InvalidMessage)
+	}
+
+	// Publish verify event
+	event := paymentEvent(EventVerify, &req.PaymentPayload, &req.PaymentRequirements)
+	event.Success = res.IsValid
+	event.Code = res.InvalidReason
+	event.Reason = res.InvalidMessage
+	f.publishEvent(event)
+	f.auditEvent(requestIDFromContext(ctx), event)
+
+	return &res
 }
 
 func (f *Facilitator) handleSettle(ginCtx *gin.Context) {
-	// Decode request
-	var req types.SettleRequest
-	if err := ginCtx.ShouldBindJSON(&req); err != nil {
+	// Decode request, in either wire format
+	req, wireVersion, err := bindPaymentRequest(ginCtx)
+	if err != nil {
 		ginCtx.JSON(http.StatusBadRequest, gin.H{
 			"error": err.Error(),
 		})
 		return
 	}
 
-	// Extract context from HTTP request
-	ctx := ginCtx.Request.Context()
+	// Count the settlement against the client's quotas, releasing it if no
+	// transaction was sent
+	release, ok := f.reserveSettlementQuota(ginCtx, &req.PaymentRequirements)
+	if !ok {
+		return
+	}
+	settled := func(resp *types.SettleResponse) {
+		if !resp.Success && resp.Transaction == "" {
+			release()
+		}
+	}
 
-	// Settle request
-	resp := f.settlePayment(ctx, &req.PaymentPayload, &req.PaymentRequirements)
+	// Accept small payments for a later batch if enabled
+	async := ginCtx.Query("async") == "true"
+	if f.deferred != nil && !async {
+		if resp := f.deferSettlement(ginCtx.Request.Context(), req); resp != nil {
+			settled(resp)
+			ginCtx.JSON(http.StatusOK, versionedSettleResponse(resp, wireVersion))
+			return
+		}
+	}
 
-	ginCtx.JSON(http.StatusOK, resp)
+	// Queue the settlement and return a job to poll if requested
+	if async {
+		job, err := f.jobs.submit(ginCtx.Request.Context(), *req, settled)
+		if err != nil {
+			release()
+			ginCtx.JSON(http.StatusServiceUnavailable, gin.H{
+				"error": err.Error(),
+			})
+			return
+		}
+		ginCtx.JSON(http.StatusAccepted, job)
+		return
+	}
+
+	resp, err := f.settleJournaled(ginCtx.Request.Context(), req)
+	if err != nil {
+		release()
+		ginCtx.JSON(http.StatusServiceUnavailable, gin.H{
+			"error": err.Error(),
+		})
+		return
+	}
+	settled(resp)
+	ginCtx.JSON(http.StatusOK, versionedSettleResponse(resp, wireVersion))
+}
+
+// settleJournaled settles a payment, persisted in the journal if enabled
+// until it finishes
+func (f *Facilitator) settleJournaled(ctx context.Context, req *types.SettleRequest) (*types.SettleResponse, error) {
+	var journaled string
+	if f.journal != nil {
+		var err error
+		journaled, err = f.journal.add(settlementKey(&req.PaymentPayload, &req.PaymentRequirements), journalEntry{
+			RequestID:  requestIDFromContext(ctx),
+			AcceptedAt: f.now().Unix(),
+			Request:    *req,
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	resp := f.settle(ctx, req)
+	if journaled != "" {
+		if err := f.journal.remove(journaled); err != nil {
+			f.log(ctx).Warn("failed to update settlement journal", "error", err)
+		}
+	}
+	return resp, nil
+}
+
+// Verify verifies a payment in process, as POST /verify does
+func (f *Facilitator) Verify(ctx context.Context, req *types.VerifyRequest) *types.VerifyResponse {
+	return f.verify(ctx, req, 2, false)
+}
+
+// Settle settles a payment in process, as POST /settle does. Client quotas
+// and deferred settlement only apply to HTTP requests. Errors are failures
+// to journal the settlement before sending it.
+func (f *Facilitator) Settle(ctx context.Context, req *types.SettleRequest) (*types.SettleResponse, error) {
+	return f.settleJournaled(ctx, req)
+}
+
+func (f *Facilitator) handleSettleJob(ginCtx *gin.Context) {
+	job, ok := f.jobs.get(ginCtx.Param("jobId"))
+	if !ok {
+		ginCtx.JSON(http.StatusNotFound, gin.H{
+			"error": "settlement job not found",
+		})
+		return
+	}
+	ginCtx.JSON(http.StatusOK, job)
+}
+
+// settle settles a payment, sharing the result with duplicate concurrent
+// requests, and records, publishes and alerts on the outcome
+func (f *Facilitator) settle(ctx context.Context, req *types.SettleRequest) *types.SettleResponse {
+	key := settlementKey(&req.PaymentPayload, &req.PaymentRequirements)
+	resp, shared := f.settlements.do(ctx, key, f.now(), func() *types.SettleResponse {
+		return f.settlePayment(ctx, req)
+	})
+	if !shared {
+		f.settled(ctx, req, resp)
+	}
+
+	return resp
+}
+
+// settled records, logs, publishes and alerts on the outcome of a settlement
+func (f *Facilitator) settled(ctx context.Context, req *types.SettleRequest, resp *types.SettleResponse) {
+	f.recordSettlement(&req.PaymentPayload, &req.PaymentRequirements, resp)
+
+	// Log outcome
+	logger := f.log(ctx).With(paymentLogAttrs(&req.PaymentPayload, &req.PaymentRequirements)...)
+	if resp.Success {
+		logger.Info("payment settled", "tx", resp.Transaction)
+	} else {
+		logger.Warn("settlement failed", "tx", resp.Transaction, "code", resp.ErrorReason, "reason", resp.ErrorMessage)
+	}
+
+	// Publish settle event
+	event := paymentEvent(EventSettle, &req.PaymentPayload, &req.PaymentRequirements)
+	event.Success = resp.Success
+	event.Code = resp.ErrorReason
+	event.Reason = resp.ErrorMessage
+	event.Transaction = resp.Transaction
+	f.publishEvent(event)
+	f.auditEvent(requestIDFromContext(ctx), event)
+
+	if f.alerts != nil {
+		if alert, spike := f.alerts.recordSettlement(resp.Success, f.now()); spike {
+			go f.alerts.send(context.Background(), alert, f.now())
+		}
+	}
 }
 
 func (f *Facilitator) handleSupported(ctx *gin.Context) {
+	// List the top-level signer for all EVM networks and per-network signers separately
+	signers := map[string][]string{
+		"solana:*": []string{},
+	}
+	for network, signer := range f.signers.signers() {
+		if network == defaultSignerNetwork {
+			network = "eip155:*"
+		}
+		signers[network] = []string{signer.Address().String()}
+	}
+
+	// Solana clients build transactions around the fee payer and upto
+	// clients name the signer as the Permit2 spender
+	feePayer := f.solanaFeePayer()
+	if feePayer != nil {
+		signers["solana:*"] = []string{svm.PublicKeyOf(feePayer).String()}
+	}
+	kinds := make([]types.SupportedKind, len(f.cfg().Supported))
+	for i, kind := range f.cfg().Supported {
+		extra := map[string]any{}
+		if utils.IsSolanaNetwork(kind.Network) {
+			if feePayer != nil {
+				extra["feePayer"] = svm.PublicKeyOf(feePayer).String()
+			}
+		} else if kind.Scheme == "upto" {
+			if spender, ok := f.signers.address(kind.Network); ok {
+				extra["spender"] = spender.Hex()
+			}
+		}
+		if len(extra) > 0 {
+			kind.Extra = maps.Clone(kind.Extra)
+			if kind.Extra == nil {
+				kind.Extra = make(map[string]any)
+			}
+			maps.Copy(kind.Extra, extra)
+		}
+		kinds[i] = kind
+	}
+
+	info := version.Get()
 	res := types.SupportedResponse{
-		Kinds:      f.config.Supported,
+		Kinds:      kinds,
 		Extensions: []string{},
-		Signers: map[string][]string{
-			"eip155:*": []string{
-				f.config.Signer.Address.String(),
-			},
-			"solana:*": []string{},
-		},
+		Signers:    signers,
+		Version:    &info,
 	}
 
 	ctx.JSON(http.StatusOK, res)
 }
+
+func (f *Facilitator) handleVersion(ctx *gin.Context) {
+	ctx.JSON(http.StatusOK, version.Get())
+}
@@ -212,3 +212,46 @@ func TestDialRPCClients(t *testing.T) {
 		}
 	}
 }
+
+func TestSupportedTerms(t *testing.T) {
+	testConfig := &FacilitatorConfig{
+		Supported: []types.SupportedKind{
+			{
+				Scheme:                      "exact",
+				Network:                     "eip155:8453",
+				Assets:                      []string{"0x833589fCD6eDb6E08f4c7C32D4f71b54bdA02913"},
+				MinAmount:                   "1000",
+				MaxAmount:                   "1000000000",
+				Fee:                         &types.FacilitatorFee{Flat: "100", BasisPoints: 25},
+				ExpectedConfirmationSeconds: 4,
+			},
+		},
+		Log: LogConfig{
+			Level: "info",
+		},
+	}
+	f := NewFacilitator(testConfig)
+
+	req, _ := http.NewRequest("GET", "/supported", nil)
+	recorder := httptest.NewRecorder()
+	f.router.ServeHTTP(recorder, req)
+
+	var response types.SupportedResponse
+	json.NewDecoder(recorder.Body).Decode(&response)
+	if len(response.Kinds) != 1 {
+		t.Fatalf("Expected 1 supported kind, got %d", len(response.Kinds))
+	}
+	kind := response.Kinds[0]
+	if len(kind.Assets) != 1 || kind.Assets[0] != "0x833589fCD6eDb6E08f4c7C32D4f71b54bdA02913" {
+		t.Errorf("Expected advertised asset, got %v", kind.Assets)
+	}
+	if kind.MinAmount != "1000" || kind.MaxAmount != "1000000000" {
+		t.Errorf("Expected amount limits 1000-1000000000, got %s-%s", kind.MinAmount, kind.MaxAmount)
+	}
+	if kind.Fee == nil || kind.Fee.Flat != "100" || kind.Fee.BasisPoints != 25 {
+		t.Errorf("Expected fee of 100 plus 25 basis points, got %+v", kind.Fee)
+	}
+	if kind.ExpectedConfirmationSeconds != 4 {
+		t.Errorf("Expected confirmation time of 4s, got %d", kind.ExpectedConfirmationSeconds)
+	}
+}
@@ -0,0 +1,275 @@
+package facilitator
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	defaultFailoverThreshold = 3
+	defaultFailoverCooldown  = 30 * time.Second
+	failoverHealthTimeout    = 5 * time.Second
+)
+
+type rpcEndpoint struct {
+	url       *url.URL
+	failures  int
+	downUntil time.Time
+}
+
+// failoverTransport sends JSON-RPC requests to the first available endpoint of
+// a network and moves on to the next when one fails. An endpoint that fails
+// threshold times in a row is skipped until its cooldown passes, or until a
+// health check finds it working again. If every endpoint is down they are all
+// tried anyway.
+type failoverTransport struct {
+	network   string
+	base      http.RoundTripper
+	threshold int
+	cooldown  time.Duration
+	now       func() time.Time
+	logger    *slog.Logger
+
+	mu        sync.Mutex
+	endpoints []*rpcEndpoint
+
+	stop     chan struct{}
+	stopOnce sync.Once
+}
+
+func newFailoverTransport(network string, base http.RoundTripper, rpcURLs []string, cfg FailoverConfig, logger *slog.Logger) (*failoverTransport, error) {
+	t := &failoverTransport{
+		network:   network,
+		base:      base,
+		threshold: defaultFailoverThreshold,
+		cooldown:  defaultFailoverCooldown,
+		now:       time.Now,
+		logger:    logger,
+		stop:      make(chan struct{}),
+	}
+	if cfg.FailureThreshold > 0 {
+		t.threshold = cfg.FailureThreshold
+	}
+	if cfg.CooldownSeconds > 0 {
+		t.cooldown = time.Duration(cfg.CooldownSeconds) * time.Second
+	}
+	for _, rpcURL := range rpcURLs {
+		parsed, err := url.Parse(rpcURL)
+		if err != nil {
+			return nil, fmt.Errorf("invalid rpc url: %w", err)
+		}
+		t.endpoints = append(t.endpoints, &rpcEndpoint{url: parsed})
+	}
+
+	// Check endpoints in the background if enabled
+	if cfg.HealthCheckSeconds > 0 {
+		go t.healthCheck(time.Duration(cfg.HealthCheckSeconds) * time.Second)
+	}
+	return t, nil
+}
+
+func (t *failoverTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	// Read body so it can be resent to each endpoint
+	var body []byte
+	if req.Body != nil {
+		var err error
+		body, err = io.ReadAll(req.Body)
+		req.Body.Close()
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	var lastResp *http.Response
+	var lastErr error
+	for _, i := range t.order() {
+		resp, err := t.send(req.Context(), req, i, body)
+		if err == nil && !isRetryableRPCStatus(resp.StatusCode) {
+			t.succeeded(i)
+			if lastResp != nil {
+				lastResp.Body.Close()
+			}
+			return resp, nil
+		}
+
+		// Don't blame the endpoint when the caller gave up
+		if req.Context().Err() != nil {
+			if resp != nil {
+				resp.Body.Close()
+			}
+			if lastResp != nil {
+				lastResp.Body.Close()
+			}
+			return nil, req.Context().Err()
+		}
+
+		t.failed(i, err, resp)
+		if lastResp != nil {
+			lastResp.Body.Close()
+		}
+		lastResp, lastErr = resp, err
+	}
+	return lastResp, lastErr
+}
+
+// send forwards a copy of req with the given body to endpoint i
+func (t *failoverTransport) send(ctx context.Context, req *http.Request, i int, body []byte) (*http.Response, error) {
+	t.mu.Lock()
+	target := *t.endpoints[i].url
+	t.mu.Unlock()
+
+	attempt := req.Clone(ctx)
+	attempt.URL = &target
+	attempt.Host = ""
+	attempt.Body = io.NopCloser(bytes.NewReader(body))
+	attempt.ContentLength = int64(len(body))
+	return t.base.RoundTrip(attempt)
+}
+
+// order returns endpoint indexes to try: available ones by priority, then the
+// ones that are down
+func (t *failoverTransport) order() []int {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	now := t.now()
+	available := make([]int, 0, len(t.endpoints))
+	var down []int
+	for i, endpoint := range t.endpoints {
+		if now.Before(endpoint.downUntil) {
+			down = append(down, i)
+		} else {
+			available = append(available, i)
+		}
+	}
+	return append(available, down...)
+}
+
+func (t *failoverTransport) succeeded(i int) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	endpoint := t.endpoints[i]
+	if endpoint.failures >= t.threshold {
+		t.logger.Info("RPC endpoint recovered", "network", t.network, "endpoint", endpoint.url.Host)
+	}
+	endpoint.failures = 0
+	endpoint.downUntil = time.Time{}
+}
+
+func (t *failoverTransport) failed(i int, err error, resp *http.Response) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	endpoint := t.endpoints[i]
+	endpoint.failures++
+	if endpoint.failures < t.threshold {
+		return
+	}
+	if endpoint.failures == t.threshold {
+		reason := fmt.Sprint(err)
+		if resp != nil {
+			reason = resp.Status
+		}
+		t.logger.Warn("RPC endpoint down", "network", t.network, "endpoint", endpoint.url.Host, "cooldown", t.cooldown, "reason", reason)
+	}
+	endpoint.downUntil = t.now().Add(t.cooldown)
+}
+
+// RPCEndpointState is the failover state of one RPC endpoint, identified by host
+type RPCEndpointState struct {
+	Endpoint  string `json:"endpoint"`
+	Failures  int    `json:"failures"`
+	Down      bool   `json:"down"`
+	DownUntil int64  `json:"downUntil,omitempty"`
+}
+
+// snapshot returns the state of every endpoint, in configured order
+func (t *failoverTransport) snapshot() []RPCEndpointState {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	now := t.now()
+	states := make([]RPCEndpointState, len(t.endpoints))
+	for i, endpoint := range t.endpoints {
+		states[i] = RPCEndpointState{
+			Endpoint: endpoint.url.Host,
+			Failures: endpoint.failures,
+			Down:     now.Before(endpoint.downUntil),
+		}
+		if states[i].Down {
+			states[i].DownUntil = endpoint.downUntil.Unix()
+		}
+	}
+	return states
+}
+
+// healthCheck calls eth_blockNumber on every endpoint at each interval
+func (t *failoverTransport) healthCheck(interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-t.stop:
+			return
+		case <-ticker.C:
+		}
+		for i := range t.endpoints {
+			ctx, cancel := context.WithTimeout(context.Background(), failoverHealthTimeout)
+			err := t.check(ctx, i)
+			cancel()
+			if err != nil {
+				t.failed(i, err, nil)
+			} else {
+				t.succeeded(i)
+			}
+		}
+	}
+}
+
+func (t *failoverTransport) check(ctx context.Context, i int) error {
+	body := []byte(`{"jsonrpc":"2.0","id":1,"method":"eth_blockNumber","params":[]}`)
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, "", nil)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := t.send(ctx, req, i, body)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	respBody, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return err
+	}
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("status %d", resp.StatusCode)
+	}
+	if !strings.Contains(string(respBody), `"result"`) {
+		return fmt.Errorf("unexpected response: %s", respBody)
+	}
+	return nil
+}
+
+func (t *failoverTransport) close() {
+	t.stopOnce.Do(func() {
+		close(t.stop)
+	})
+}
+
+// isRetryableRPCStatus reports whether another endpoint may succeed where one
+// answered with this status
+func isRetryableRPCStatus(status int) bool {
+	return status == http.StatusTooManyRequests || status >= http.StatusInternalServerError
+}
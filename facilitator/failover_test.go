@@ -0,0 +1,108 @@
+package facilitator
+
+import (
+	"context"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestRPCFailover(t *testing.T) {
+	var primaryCalls, backupCalls atomic.Int32
+	primary := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		primaryCalls.Add(1)
+		w.WriteHeader(http.StatusBadGateway)
+	}))
+	defer primary.Close()
+	backup := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		backupCalls.Add(1)
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"jsonrpc":"2.0","id":1,"result":"0x2105"}`))
+	}))
+	defer backup.Close()
+
+	testConfig := &FacilitatorConfig{
+		Networks: map[string]NetworkConfig{
+			"eip155:8453": {
+				RpcUrl:   primary.URL,
+				RpcUrls:  []string{backup.URL},
+				Failover: FailoverConfig{FailureThreshold: 2, CooldownSeconds: 60},
+			},
+		},
+		Log: LogConfig{
+			Level: "info",
+		},
+	}
+	f := NewFacilitator(testConfig)
+	defer f.Close()
+
+	client, err := f.getRPCClient("eip155:8453")
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	// Calls fail over to the backup until the primary is taken out of rotation
+	for range 4 {
+		chainID, err := client.ChainID(context.Background())
+		if err != nil {
+			t.Fatalf("Expected no error, got %v", err)
+		}
+		if chainID.Int64() != 8453 {
+			t.Errorf("Expected chain id 8453, got %d", chainID.Int64())
+		}
+	}
+	if primaryCalls.Load() != 2 {
+		t.Errorf("Expected primary to be skipped after 2 failures, got %d calls", primaryCalls.Load())
+	}
+	if backupCalls.Load() != 4 {
+		t.Errorf("Expected 4 backup calls, got %d", backupCalls.Load())
+	}
+
+	// The primary is tried again once its cooldown passes
+	failover := f.rpcFailovers["eip155:8453"]
+	failover.now = func() time.Time { return time.Now().Add(time.Minute) }
+	if _, err := client.ChainID(context.Background()); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if primaryCalls.Load() != 3 {
+		t.Errorf("Expected primary to be retried after cooldown, got %d calls", primaryCalls.Load())
+	}
+}
+
+func TestRPCFailoverHealthCheck(t *testing.T) {
+	var healthy atomic.Bool
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !healthy.Load() {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		w.Write([]byte(`{"jsonrpc":"2.0","id":1,"result":"0x1"}`))
+	}))
+	defer server.Close()
+
+	failover, err := newFailoverTransport("eip155:8453", http.DefaultTransport, []string{server.URL}, FailoverConfig{FailureThreshold: 1}, slog.Default())
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	defer failover.close()
+
+	// A failed check takes the endpoint out, a passing one brings it back
+	if err := failover.check(context.Background(), 0); err == nil {
+		t.Fatal("Expected failed health check")
+	}
+	failover.failed(0, nil, nil)
+	if !failover.now().Before(failover.endpoints[0].downUntil) {
+		t.Errorf("Expected endpoint to be down")
+	}
+	healthy.Store(true)
+	if err := failover.check(context.Background(), 0); err != nil {
+		t.Fatalf("Expected passing health check, got %v", err)
+	}
+	failover.succeeded(0)
+	if !failover.endpoints[0].downUntil.IsZero() {
+		t.Errorf("Expected endpoint to be back in rotation")
+	}
+}
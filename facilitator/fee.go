@@ -0,0 +1,169 @@
+package facilitator
+
+import (
+	"context"
+	"fmt"
+	"math/big"
+	"strings"
+	"time"
+
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/accounts/abi"
+	"github.com/ethereum/go-ethereum/common"
+	ethtypes "github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/ethclient"
+	"github.com/vorpalengineering/x402-go/types"
+	"github.com/vorpalengineering/x402-go/utils"
+)
+
+// feeAmount is the fee charged on a required amount: the flat fee plus the
+// basis points share, rounded down
+func feeAmount(fee *types.FacilitatorFee, amount *big.Int) *big.Int {
+	total := new(big.Int)
+	if fee == nil {
+		return total
+	}
+	if flat, ok := new(big.Int).SetString(fee.Flat, 10); ok {
+		total.Add(total, flat)
+	}
+	if fee.BasisPoints > 0 {
+		share := new(big.Int).Mul(amount, big.NewInt(int64(fee.BasisPoints)))
+		total.Add(total, share.Div(share, big.NewInt(10000)))
+	}
+	return total
+}
+
+// requiredFee returns the fee of the requirements' supported kind, zero when
+// the kind has none
+func (f *Facilitator) requiredFee(requirements *types.PaymentRequirements) *big.Int {
+	kind, ok := f.cfg().GetSupportedKind(requirements.Scheme, requirements.Network)
+	if !ok || kind.Fee == nil {
+		return new(big.Int)
+	}
+	amount, ok := new(big.Int).SetString(requirements.Amount, 10)
+	if !ok {
+		return new(big.Int)
+	}
+	return feeAmount(kind.Fee, amount)
+}
+
+// sweepFeeAfter moves the fee of a settled payment from payTo to the fee
+// recipient once the settlement transaction is mined. It runs in the
+// background, failures are logged and don't affect the settlement.
+func (f *Facilitator) sweepFeeAfter(ctx context.Context, client *ethclient.Client, requirements types.PaymentRequirements, settlementTx string) {
+	fee := f.requiredFee(&requirements)
+	if !f.cfg().Fees.Sweep || fee.Sign() == 0 {
+		return
+	}
+
+	f.sweeps.Add(1)
+	go func() {
+		defer f.sweeps.Done()
+
+		// Outlive the request, allowing for both the settlement and the sweep to be mined
+		ctx, cancel := context.WithTimeout(context.WithoutCancel(ctx), 2*time.Duration(f.cfg().Transaction.TimeoutSeconds)*time.Second)
+		defer cancel()
+		logger := f.log(ctx).With("network", requirements.Network, "asset", requirements.Asset, "fee", fee.String())
+
+		// The fee can only be pulled once the payment reached payTo
+		receipt, err := waitForConfirmations(ctx, client, common.HexToHash(settlementTx), 1, f.confirmationPollInterval)
+		if err != nil || receipt.Status != ethtypes.ReceiptStatusSuccessful {
+			logger.Warn("fee not swept, settlement not mined", "transaction", settlementTx, "error", err)
+			return
+		}
+
+		signer, release, err := f.signers.acquire(requirements.Network)
+		if err != nil {
+			logger.Warn("fee not swept", "error", err)
+			return
+		}
+		defer release()
+
+		txHash, _, err := f.sendWithRetry(ctx, requirements.Network, func(attempt int) (string, error) {
+			return f.sendFeeSweep(ctx, client, signer, &requirements, fee, attempt)
+		})
+		if err != nil {
+			logger.Warn("failed to sweep fee", "error", err)
+			return
+		}
+		logger.Info("fee swept", "transaction", txHash, "settlement", settlementTx)
+	}()
+}
+
+// sendFeeSweep sends transferFrom(payTo, recipient, fee) on the asset. payTo
+// must have approved the network's signer to spend the asset.
+func (f *Facilitator) sendFeeSweep(
+	ctx context.Context,
+	client *ethclient.Client,
+	signer Signer,
+	requirements *types.PaymentRequirements,
+	fee *big.Int,
+	attempt int,
+) (string, error) {
+	// Encode the transferFrom call
+	transferFromABI, err := abi.JSON(strings.NewReader(utils.ERC20TransferFromABI))
+	if err != nil {
+		return "", fmt.Errorf("failed to parse ABI: %w", err)
+	}
+	callData, err := transferFromABI.Pack(
+		"transferFrom",
+		common.HexToAddress(requirements.PayTo),
+		common.HexToAddress(f.cfg().Fees.Recipient),
+		fee,
+	)
+	if err != nil {
+		return "", fmt.Errorf("failed to encode call: %w", err)
+	}
+	return f.sendTokenCall(ctx, client, signer, requirements.Network, requirements.Asset, callData, attempt)
+}
+
+// sendTokenCall signs and sends a call to a token from signer, with the
+// network's gas price limit and gas multiplier
+func (f *Facilitator) sendTokenCall(
+	ctx context.Context,
+	client *ethclient.Client,
+	signer Signer,
+	network string,
+	asset string,
+	callData []byte,
+	attempt int,
+) (string, error) {
+	// Get gas price and check it against max gas price from config
+	gasPrice, err := client.SuggestGasPrice(ctx)
+	if err != nil {
+		return "", fmt.Errorf("failed to get gas price: %w", err)
+	}
+	maxGasPrice, ok := new(big.Int).SetString(f.cfg().Transaction.MaxGasPrice, 10)
+	if !ok {
+		return "", fmt.Errorf("failed to parse max gas price: %s", f.cfg().Transaction.MaxGasPrice)
+	}
+	if gasPrice.Cmp(maxGasPrice) > 0 {
+		return "", fmt.Errorf("gas price too high: suggested %s wei exceeds max %s wei", gasPrice.String(), maxGasPrice.String())
+	}
+	gasPrice = f.cfg().Transaction.Retry.bumpGasPrice(gasPrice, maxGasPrice, attempt)
+
+	// Get chain ID
+	chainID, err := utils.GetChainID(network)
+	if err != nil {
+		return "", fmt.Errorf("failed to get chain id: %w", err)
+	}
+
+	// Estimate gas, applying the network's multiplier buffer
+	tokenAddress := common.HexToAddress(asset)
+	gas, err := client.EstimateGas(ctx, ethereum.CallMsg{From: signer.Address(), To: &tokenAddress, Data: callData})
+	if err != nil {
+		if isRevertError(err) {
+			return "", &settleError{code: SettleErrTransactionReverted, err: err}
+		}
+		return "", &settleError{code: SettleErrGasEstimationFailed, err: err}
+	}
+	networkCfg, err := f.cfg().GetNetworkConfig(network)
+	if err != nil {
+		return "", err
+	}
+	if multiplier := networkCfg.GetGasConfig(asset).Multiplier; multiplier > 1 {
+		gas = uint64(float64(gas) * multiplier)
+	}
+
+	return f.sendSettlementTx(ctx, client, signer, network, chainID, tokenAddress, gasPrice, &settlementCall{data: callData, gas: gas})
+}
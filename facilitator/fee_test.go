@@ -0,0 +1,152 @@
+package facilitator
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/accounts/abi"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	ethtypes "github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/vorpalengineering/x402-go/types"
+	"github.com/vorpalengineering/x402-go/utils"
+)
+
+func TestFeeAmount(t *testing.T) {
+	tests := []struct {
+		name     string
+		fee      *types.FacilitatorFee
+		amount   int64
+		expected int64
+	}{
+		{"no fee", nil, 1000000, 0},
+		{"flat", &types.FacilitatorFee{Flat: "500"}, 1000000, 500},
+		{"basis points", &types.FacilitatorFee{BasisPoints: 25}, 1000000, 2500},
+		{"flat and basis points", &types.FacilitatorFee{Flat: "500", BasisPoints: 25}, 1000000, 3000},
+		{"rounded down", &types.FacilitatorFee{BasisPoints: 1}, 9999, 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fee := feeAmount(tt.fee, big.NewInt(tt.amount))
+			if fee.Int64() != tt.expected {
+				t.Errorf("Expected fee %d, got %s", tt.expected, fee)
+			}
+		})
+	}
+}
+
+func TestVerifyAmountWithFee(t *testing.T) {
+	f := NewFacilitator(&FacilitatorConfig{
+		Supported: []types.SupportedKind{
+			{Scheme: "exact", Network: "eip155:8453", Fee: &types.FacilitatorFee{Flat: "100", BasisPoints: 100}},
+		},
+		Log: LogConfig{Level: "error"},
+	})
+	defer f.Close()
+	requirements := &types.PaymentRequirements{Scheme: "exact", Network: "eip155:8453", Amount: "10000"}
+
+	// The fee is 100 + 1% of 10000 = 200 on top of the required amount
+	for value, expectValid := range map[string]bool{"10000": false, "10199": false, "10200": true} {
+		valid, reason := f.verifyAmount(context.Background(), &types.ExactEVMSchemeAuthorization{Value: value}, requirements)
+		if valid != expectValid {
+			t.Errorf("Expected %s to be valid=%v, got %v (%s)", value, expectValid, valid, reason)
+		}
+		if !valid && !strings.Contains(reason, "plus fee 200") {
+			t.Errorf("Expected reason to name the fee, got %s", reason)
+		}
+	}
+}
+
+func TestSendFeeSweep(t *testing.T) {
+	var sent *ethtypes.Transaction
+	rpcServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req struct {
+			ID     json.RawMessage   `json:"id"`
+			Method string            `json:"method"`
+			Params []json.RawMessage `json:"params"`
+		}
+		json.NewDecoder(r.Body).Decode(&req)
+		result := `"0x1"`
+		switch req.Method {
+		case "eth_estimateGas":
+			result = `"0xea60"`
+		case "eth_gasPrice":
+			result = `"0x3b9aca00"`
+		case "eth_getTransactionCount":
+			result = `"0x2"`
+		case "eth_sendRawTransaction":
+			var raw hexutil.Bytes
+			json.Unmarshal(req.Params[0], &raw)
+			sent = new(ethtypes.Transaction)
+			sent.UnmarshalBinary(raw)
+			result = fmt.Sprintf(`"%s"`, sent.Hash().Hex())
+		}
+		w.Header().Set("Content-Type", "application/json")
+		fmt.Fprintf(w, `{"jsonrpc":"2.0","id":%s,"result":%s}`, req.ID, result)
+	}))
+	defer rpcServer.Close()
+
+	key, _ := crypto.HexToECDSA("ac0974bec39a17e36ba4a6b4d238ff944bacb478cbed5efcae784d7bf4f2ff80")
+	recipient := "0x70997970C51812dc3A010C7d01b50e0d17dc79C8"
+	f := NewFacilitator(&FacilitatorConfig{
+		Networks: map[string]NetworkConfig{
+			"eip155:8453": {RpcUrl: rpcServer.URL, Gas: GasConfig{Multiplier: 1.5}},
+		},
+		Transaction: TransactionConfig{TimeoutSeconds: 5, MaxGasPrice: "100000000000"},
+		Log:         LogConfig{Level: "error"},
+		Fees:        FeesConfig{Sweep: true, Recipient: recipient},
+		Signer:      SignerConfig{PrivateKey: key},
+	})
+	defer f.Close()
+
+	client, err := f.getRPCClient("eip155:8453")
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	signer, release, err := f.signers.acquire("eip155:8453")
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	defer release()
+
+	requirements := &types.PaymentRequirements{
+		Scheme:  "exact",
+		Network: "eip155:8453",
+		Amount:  "10000",
+		PayTo:   "0x742d35Cc6634C0532925a3b844Bc9e7595f0bEb",
+		Asset:   "0x833589fCD6eDb6E08f4c7C32D4f71b54bdA02913",
+	}
+	txHash, err := f.sendFeeSweep(context.Background(), client, signer, requirements, big.NewInt(200), 1)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	// The sweep pulls the fee from payTo to the recipient on the asset
+	if sent == nil || sent.Hash().Hex() != txHash {
+		t.Fatalf("Expected transaction %s to be sent", txHash)
+	}
+	if *sent.To() != common.HexToAddress(requirements.Asset) {
+		t.Errorf("Expected transaction to the asset, got %s", sent.To().Hex())
+	}
+	if sent.Gas() != 90000 {
+		t.Errorf("Expected gas limit 90000, got %d", sent.Gas())
+	}
+	transferFromABI, _ := abi.JSON(strings.NewReader(utils.ERC20TransferFromABI))
+	args, err := transferFromABI.Methods["transferFrom"].Inputs.Unpack(sent.Data()[4:])
+	if err != nil {
+		t.Fatalf("Failed to decode transferFrom: %v", err)
+	}
+	if args[0].(common.Address) != common.HexToAddress(requirements.PayTo) ||
+		args[1].(common.Address) != common.HexToAddress(recipient) ||
+		args[2].(*big.Int).Int64() != 200 {
+		t.Errorf("Expected transferFrom(payTo, recipient, 200), got %v", args)
+	}
+}
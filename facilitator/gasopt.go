@@ -0,0 +1,232 @@
+package facilitator
+
+import (
+	"context"
+	"net/http"
+	"strings"
+	"sync"
+
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	ethtypes "github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/ethclient"
+	"github.com/gin-gonic/gin"
+)
+
+// transferWithAuthorization overloads a settlement can be sent with
+const (
+	OverloadVRS   = "vrs"
+	OverloadBytes = "bytes"
+)
+
+// GasStats summarizes settlement gas optimization on one network. Gas figures
+// are the estimates taken when each transaction was built.
+type GasStats struct {
+	Settlements  uint64            `json:"settlements"`
+	AccessLists  uint64            `json:"accessLists"`
+	Overloads    map[string]uint64 `json:"overloads"`
+	BaselineGas  uint64            `json:"baselineGas"`
+	OptimizedGas uint64            `json:"optimizedGas"`
+	GasSaved     uint64            `json:"gasSaved"`
+}
+
+// settlementCall is the calldata, access list and gas limit of a settlement transaction
+type settlementCall struct {
+	overload   string
+	data       []byte
+	accessList ethtypes.AccessList
+	gas        uint64
+}
+
+// gasEstimator is the subset of RPC calls the optimizer needs
+type gasEstimator interface {
+	EstimateGas(ctx context.Context, msg ethereum.CallMsg) (uint64, error)
+	CreateAccessList(ctx context.Context, msg ethereum.CallMsg) (*ethtypes.AccessList, uint64, string, error)
+}
+
+type rpcGasEstimator struct {
+	*ethclient.Client
+}
+
+// CreateAccessList calls eth_createAccessList, returning the access list, the
+// gas used with it and any execution error reported by the node
+func (e rpcGasEstimator) CreateAccessList(ctx context.Context, msg ethereum.CallMsg) (*ethtypes.AccessList, uint64, string, error) {
+	arg := map[string]any{
+		"from":  msg.From,
+		"to":    msg.To,
+		"input": hexutil.Bytes(msg.Data),
+	}
+	var result struct {
+		AccessList *ethtypes.AccessList `json:"accessList"`
+		Error      string               `json:"error,omitempty"`
+		GasUsed    hexutil.Uint64       `json:"gasUsed"`
+	}
+	if err := e.Client.Client().CallContext(ctx, &result, "eth_createAccessList", arg, "latest"); err != nil {
+		return nil, 0, "", err
+	}
+	return result.AccessList, uint64(result.GasUsed), result.Error, nil
+}
+
+// gasOptimizer remembers the cheapest overload per token and tracks savings
+type gasOptimizer struct {
+	mu        sync.Mutex
+	preferred map[string]string
+	savings   map[string]uint64
+	stats     map[string]*GasStats
+}
+
+func newGasOptimizer() *gasOptimizer {
+	return &gasOptimizer{
+		preferred: make(map[string]string),
+		savings:   make(map[string]uint64),
+		stats:     make(map[string]*GasStats),
+	}
+}
+
+// optimize estimates the candidate calls and returns the cheapest, with an
+// access list attached if it lowers the estimate. Candidates are tried in
+// order and the first is the baseline savings are measured against. The
+// overload choice is probed once per token and reused afterwards.
+func (o *gasOptimizer) optimize(
+	ctx context.Context,
+	estimator gasEstimator,
+	network string,
+	msg ethereum.CallMsg,
+	candidates []settlementCall,
+) (*settlementCall, error) {
+	key := strings.ToLower(network + "|" + msg.To.Hex())
+
+	// Use the known overload for this token, or probe all of them
+	o.mu.Lock()
+	preferred, known := o.preferred[key]
+	saving := o.savings[key]
+	o.mu.Unlock()
+
+	var call *settlementCall
+	if known {
+		for i := range candidates {
+			if candidates[i].overload == preferred {
+				call = &candidates[i]
+			}
+		}
+	}
+	if call != nil {
+		msg.Data = call.data
+		gas, err := estimator.EstimateGas(ctx, msg)
+		if err != nil {
+			return nil, err
+		}
+		call.gas = gas
+	} else {
+		probed, probeSaving, err := o.probe(ctx, estimator, key, msg, candidates)
+		if err != nil {
+			return nil, err
+		}
+		call, saving = probed, probeSaving
+	}
+
+	// Attach an access list when it makes the call cheaper
+	msg.Data = call.data
+	accessList, _, execErr, err := estimator.CreateAccessList(ctx, msg)
+	if err == nil && execErr == "" && accessList != nil && len(*accessList) > 0 {
+		msg.AccessList = *accessList
+		gas, err := estimator.EstimateGas(ctx, msg)
+		if err == nil && gas < call.gas {
+			saving += call.gas - gas
+			call.gas = gas
+			call.accessList = *accessList
+		}
+	}
+
+	o.record(network, call, saving)
+	return call, nil
+}
+
+// probe estimates every candidate and returns the cheapest and its saving
+// over the first. The choice is cached unless an estimate failed for a reason
+// other than a revert, since that says nothing about the token's ABI.
+func (o *gasOptimizer) probe(
+	ctx context.Context,
+	estimator gasEstimator,
+	key string,
+	msg ethereum.CallMsg,
+	candidates []settlementCall,
+) (*settlementCall, uint64, error) {
+	var best *settlementCall
+	var firstErr error
+	conclusive := true
+	for i := range candidates {
+		msg.Data = candidates[i].data
+		gas, err := estimator.EstimateGas(ctx, msg)
+		if err != nil {
+			if firstErr == nil {
+				firstErr = err
+			}
+			if !isRevertError(err) {
+				conclusive = false
+			}
+			continue
+		}
+		candidates[i].gas = gas
+		if best == nil || gas < best.gas {
+			best = &candidates[i]
+		}
+	}
+	if best == nil {
+		return nil, 0, firstErr
+	}
+
+	// Savings are measured against the first candidate when it works
+	var saving uint64
+	if candidates[0].gas > best.gas {
+		saving = candidates[0].gas - best.gas
+	}
+
+	if conclusive {
+		o.mu.Lock()
+		o.preferred[key] = best.overload
+		o.savings[key] = saving
+		o.mu.Unlock()
+	}
+	return best, saving, nil
+}
+
+func (o *gasOptimizer) record(network string, call *settlementCall, saving uint64) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	stats, ok := o.stats[network]
+	if !ok {
+		stats = &GasStats{Overloads: make(map[string]uint64)}
+		o.stats[network] = stats
+	}
+	stats.Settlements++
+	stats.Overloads[call.overload]++
+	if len(call.accessList) > 0 {
+		stats.AccessLists++
+	}
+	stats.BaselineGas += call.gas + saving
+	stats.OptimizedGas += call.gas
+	stats.GasSaved += saving
+}
+
+// snapshot returns a copy of the per-network stats
+func (o *gasOptimizer) snapshot() map[string]GasStats {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	snapshot := make(map[string]GasStats, len(o.stats))
+	for network, stats := range o.stats {
+		copied := *stats
+		copied.Overloads = make(map[string]uint64, len(stats.Overloads))
+		for overload, count := range stats.Overloads {
+			copied.Overloads[overload] = count
+		}
+		snapshot[network] = copied
+	}
+	return snapshot
+}
+
+func (f *Facilitator) handleGasMetrics(ctx *gin.Context) {
+	ctx.JSON(http.StatusOK, f.gasOptimizer.snapshot())
+}
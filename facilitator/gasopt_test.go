@@ -0,0 +1,110 @@
+package facilitator
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/common"
+	ethtypes "github.com/ethereum/go-ethereum/core/types"
+)
+
+// fakeEstimator prices calls by their first calldata byte
+type fakeEstimator struct {
+	gas         map[byte]uint64
+	accessList  ethtypes.AccessList
+	withList    uint64
+	estimations int
+}
+
+func (e *fakeEstimator) EstimateGas(ctx context.Context, msg ethereum.CallMsg) (uint64, error) {
+	e.estimations++
+	if len(msg.AccessList) > 0 {
+		return e.withList, nil
+	}
+	gas, ok := e.gas[msg.Data[0]]
+	if !ok {
+		return 0, errors.New("execution reverted")
+	}
+	return gas, nil
+}
+
+func (e *fakeEstimator) CreateAccessList(ctx context.Context, msg ethereum.CallMsg) (*ethtypes.AccessList, uint64, string, error) {
+	return &e.accessList, e.withList, "", nil
+}
+
+func testCandidates() []settlementCall {
+	return []settlementCall{
+		{overload: OverloadVRS, data: []byte{1}},
+		{overload: OverloadBytes, data: []byte{2}},
+	}
+}
+
+func TestGasOptimizerPicksCheapestCall(t *testing.T) {
+	o := newGasOptimizer()
+	token := common.HexToAddress("0x833589fCD6eDb6E08f4c7C32D4f71b54bdA02913")
+	msg := ethereum.CallMsg{To: &token}
+	estimator := &fakeEstimator{
+		gas: map[byte]uint64{1: 60000, 2: 58000},
+		accessList: ethtypes.AccessList{
+			{Address: common.HexToAddress("0x43506849D7C04F9138D1A2050bbF3A0c054402dd")},
+		},
+		withList: 57000,
+	}
+
+	call, err := o.optimize(context.Background(), estimator, "eip155:8453", msg, testCandidates())
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if call.overload != OverloadBytes {
+		t.Errorf("Expected bytes overload, got %s", call.overload)
+	}
+	if call.gas != 57000 || len(call.accessList) != 1 {
+		t.Errorf("Expected access list lowering gas to 57000, got %d with %d entries", call.gas, len(call.accessList))
+	}
+
+	// The overload choice is reused without probing again
+	estimator.estimations = 0
+	if _, err := o.optimize(context.Background(), estimator, "eip155:8453", msg, testCandidates()); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if estimator.estimations != 2 {
+		t.Errorf("Expected 2 estimations (call and access list), got %d", estimator.estimations)
+	}
+
+	stats := o.snapshot()["eip155:8453"]
+	if stats.Settlements != 2 || stats.AccessLists != 2 || stats.Overloads[OverloadBytes] != 2 {
+		t.Errorf("Unexpected stats: %+v", stats)
+	}
+	if stats.GasSaved != 6000 || stats.BaselineGas != 120000 || stats.OptimizedGas != 114000 {
+		t.Errorf("Expected 6000 gas saved of 120000, got %d of %d", stats.GasSaved, stats.BaselineGas)
+	}
+}
+
+func TestGasOptimizerUnsupportedOverload(t *testing.T) {
+	o := newGasOptimizer()
+	token := common.HexToAddress("0x833589fCD6eDb6E08f4c7C32D4f71b54bdA02913")
+	msg := ethereum.CallMsg{To: &token}
+
+	// Token without the bytes overload, and an access list that does not help
+	estimator := &fakeEstimator{gas: map[byte]uint64{1: 60000}, withList: 61000}
+	call, err := o.optimize(context.Background(), estimator, "eip155:8453", msg, testCandidates())
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if call.overload != OverloadVRS || call.gas != 60000 || len(call.accessList) != 0 {
+		t.Errorf("Expected plain v/r/s call at 60000 gas, got %s at %d", call.overload, call.gas)
+	}
+	if o.preferred["eip155:8453|"+strings.ToLower(token.Hex())] != OverloadVRS {
+		t.Errorf("Expected v/r/s to be remembered for the token")
+	}
+
+	// Reverting transfers fail on every overload
+	estimator.gas = map[byte]uint64{}
+	o = newGasOptimizer()
+	if _, err := o.optimize(context.Background(), estimator, "eip155:8453", msg, testCandidates()); err == nil || !isRevertError(err) {
+		t.Errorf("Expected revert error, got %v", err)
+	}
+}
@@ -0,0 +1,133 @@
+package facilitator
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/vorpalengineering/x402-go/types"
+	"github.com/vorpalengineering/x402-go/utils"
+)
+
+// healthCheckTimeout bounds each readiness check
+const healthCheckTimeout = 5 * time.Second
+
+// ReadinessCheck reports whether a dependency is usable, returning nil when it is
+type ReadinessCheck func(ctx context.Context) error
+
+// AddReadinessCheck registers an extra dependency reported by /readyz, such as
+// a shared datastore. Call before Run.
+func (f *Facilitator) AddReadinessCheck(name string, check ReadinessCheck) {
+	f.readinessChecks[name] = check
+}
+
+// handleHealthz reports the process is alive. Dependencies are not checked so
+// an RPC outage doesn't get the facilitator restarted.
+func (f *Facilitator) handleHealthz(ctx *gin.Context) {
+	ctx.JSON(http.StatusOK, types.HealthResponse{Status: types.HealthStatusOK})
+}
+
+// handleReadyz reports whether the facilitator can verify and settle, with the
+// status of every dependency. Responds 503 if any of them is unavailable.
+func (f *Facilitator) handleReadyz(ctx *gin.Context) {
+	res := f.readiness(ctx.Request.Context())
+	status := http.StatusOK
+	if res.Status != types.HealthStatusOK {
+		status = http.StatusServiceUnavailable
+	}
+	ctx.JSON(status, res)
+}
+
+// readiness runs every readiness check concurrently
+func (f *Facilitator) readiness(ctx context.Context) types.HealthResponse {
+	checks := map[string]ReadinessCheck{}
+	for network := range f.cfg().Networks {
+		if utils.IsSolanaNetwork(network) {
+			checks["rpc:"+network] = func(ctx context.Context) error {
+				return f.checkSVMRPC(ctx, network)
+			}
+			continue
+		}
+		checks["signer"] = f.checkSigner
+		checks["rpc:"+network] = func(ctx context.Context) error {
+			return f.checkRPC(ctx, network)
+		}
+	}
+	for name, check := range f.readinessChecks {
+		checks[name] = check
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, healthCheckTimeout)
+	defer cancel()
+
+	res := types.HealthResponse{
+		Status: types.HealthStatusOK,
+		Checks: make(map[string]types.HealthCheck, len(checks)),
+	}
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	for name, check := range checks {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			start := time.Now()
+			err := check(ctx)
+			result := types.HealthCheck{
+				Status:    types.HealthStatusOK,
+				LatencyMs: time.Since(start).Milliseconds(),
+			}
+			if err != nil {
+				result.Status = types.HealthStatusUnavailable
+				result.Error = err.Error()
+			}
+
+			mu.Lock()
+			res.Checks[name] = result
+			if err != nil {
+				res.Status = types.HealthStatusUnavailable
+			}
+			mu.Unlock()
+		}()
+	}
+	wg.Wait()
+	return res
+}
+
+// checkRPC fetches the latest block number of a network
+func (f *Facilitator) checkRPC(ctx context.Context, network string) error {
+	client, err := f.getRPCClient(network)
+	if err != nil {
+		return err
+	}
+	if _, err := client.BlockNumber(ctx); err != nil {
+		return fmt.Errorf("RPC unavailable: %w", err)
+	}
+	return nil
+}
+
+// checkSigner makes sure a settlement signer is loaded, and that KMS keys are
+// reachable and still match their address
+func (f *Facilitator) checkSigner(ctx context.Context) error {
+	signers := f.signers.signers()
+	if len(signers) == 0 {
+		return errors.New("signer key not loaded")
+	}
+	for network, signer := range signers {
+		kms, ok := signer.(*kmsSigner)
+		if !ok {
+			continue
+		}
+		address, err := kms.fetchAddress(ctx)
+		if err != nil {
+			return fmt.Errorf("signer for %s: %w", network, err)
+		}
+		if address != kms.Address() {
+			return fmt.Errorf("signer for %s does not match kms key", network)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,95 @@
+package facilitator
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/vorpalengineering/x402-go/types"
+)
+
+func TestHealthAndReadiness(t *testing.T) {
+	rpcServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"jsonrpc":"2.0","id":1,"result":"0x10"}`))
+	}))
+	defer rpcServer.Close()
+
+	privKey, _ := crypto.HexToECDSA("ac0974bec39a17e36ba4a6b4d238ff944bacb478cbed5efcae784d7bf4f2ff80")
+	testConfig := &FacilitatorConfig{
+		Networks: map[string]NetworkConfig{
+			"eip155:8453": {
+				RpcUrl: rpcServer.URL,
+			},
+			"eip155:1": {
+				RpcUrl: "http://127.0.0.1:1",
+			},
+		},
+		Log: LogConfig{
+			Level: "error",
+		},
+		Signer: SignerConfig{
+			Address:    crypto.PubkeyToAddress(privKey.PublicKey),
+			PrivateKey: privKey,
+		},
+	}
+	f := NewFacilitator(testConfig)
+	defer f.Close()
+
+	// Liveness does not depend on the RPC
+	recorder := httptest.NewRecorder()
+	f.router.ServeHTTP(recorder, httptest.NewRequest("GET", "/healthz", nil))
+	if recorder.Code != http.StatusOK {
+		t.Errorf("Expected status 200, got %d", recorder.Code)
+	}
+
+	// Readiness reports the unreachable network
+	recorder = httptest.NewRecorder()
+	f.router.ServeHTTP(recorder, httptest.NewRequest("GET", "/readyz", nil))
+	if recorder.Code != http.StatusServiceUnavailable {
+		t.Fatalf("Expected status 503, got %d", recorder.Code)
+	}
+	var res types.HealthResponse
+	json.Unmarshal(recorder.Body.Bytes(), &res)
+	if res.Checks["rpc:eip155:8453"].Status != types.HealthStatusOK {
+		t.Errorf("Expected reachable RPC to be ok, got %+v", res.Checks["rpc:eip155:8453"])
+	}
+	if res.Checks["rpc:eip155:1"].Status != types.HealthStatusUnavailable {
+		t.Errorf("Expected unreachable RPC to be unavailable, got %+v", res.Checks["rpc:eip155:1"])
+	}
+	if res.Checks["signer"].Status != types.HealthStatusOK {
+		t.Errorf("Expected signer to be ok, got %+v", res.Checks["signer"])
+	}
+
+	// Registered checks are included
+	delete(testConfig.Networks, "eip155:1")
+	f.AddReadinessCheck("datastore", func(ctx context.Context) error {
+		return errors.New("connection refused")
+	})
+	res = f.readiness(context.Background())
+	if res.Status != types.HealthStatusUnavailable || res.Checks["datastore"].Error != "connection refused" {
+		t.Errorf("Expected datastore to be unavailable, got %+v", res)
+	}
+	if len(res.Checks) != 3 {
+		t.Errorf("Expected 3 checks, got %d", len(res.Checks))
+	}
+}
+
+func TestCheckSigner(t *testing.T) {
+	f := &Facilitator{}
+	f.signers = newSignerRegistry(&FacilitatorConfig{}, time.Now)
+	if err := f.checkSigner(context.Background()); err == nil {
+		t.Error("Expected error for missing signer key")
+	}
+
+	privKey, _ := crypto.HexToECDSA("ac0974bec39a17e36ba4a6b4d238ff944bacb478cbed5efcae784d7bf4f2ff80")
+	f.signers = newSignerRegistry(&FacilitatorConfig{Signer: SignerConfig{PrivateKey: privKey}}, time.Now)
+	if err := f.checkSigner(context.Background()); err != nil {
+		t.Errorf("Expected loaded signer to pass, got %v", err)
+	}
+}
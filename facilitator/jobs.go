@@ -0,0 +1,312 @@
+package facilitator
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"log/slog"
+	"sync"
+	"time"
+
+	"github.com/vorpalengineering/x402-go/types"
+)
+
+const (
+	defaultSettleJobWorkers   = 4
+	defaultSettleJobQueueSize = 256
+	defaultSettleJobRetention = time.Hour
+)
+
+var (
+	errSettleJobQueueFull = errors.New("settlement queue is full")
+	errSettleJobsClosed   = errors.New("facilitator is shutting down")
+)
+
+type settleJob struct {
+	job       types.SettleJob
+	req       types.SettleRequest
+	requestID string
+	done      func(*types.SettleResponse)
+	// journal is the job's entry in the settlement journal, if kept
+	journal string
+}
+
+// settleJobs runs asynchronous settlements on a fixed pool of workers and
+// keeps their status for polling until the retention period passes
+type settleJobs struct {
+	settle    func(ctx context.Context, req *types.SettleRequest) *types.SettleResponse
+	now       func() time.Time
+	retention time.Duration
+
+	// journal persists jobs until they finish, if enabled
+	journal *settleJournal
+	logger  *slog.Logger
+
+	mu     sync.Mutex
+	jobs   map[string]*settleJob
+	closed bool
+
+	queue     chan *settleJob
+	ctx       context.Context
+	cancel    context.CancelFunc
+	wg        sync.WaitGroup
+	closeOnce sync.Once
+}
+
+func newSettleJobs(
+	cfg SettleJobsConfig,
+	now func() time.Time,
+	settle func(ctx context.Context, req *types.SettleRequest) *types.SettleResponse,
+) *settleJobs {
+	workers := cfg.Workers
+	if workers <= 0 {
+		workers = defaultSettleJobWorkers
+	}
+	queueSize := cfg.QueueSize
+	if queueSize <= 0 {
+		queueSize = defaultSettleJobQueueSize
+	}
+	retention := defaultSettleJobRetention
+	if cfg.RetentionSeconds > 0 {
+		retention = time.Duration(cfg.RetentionSeconds) * time.Second
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	j := &settleJobs{
+		settle:    settle,
+		now:       now,
+		retention: retention,
+		logger:    slog.Default(),
+		jobs:      make(map[string]*settleJob),
+		queue:     make(chan *settleJob, queueSize),
+		ctx:       ctx,
+		cancel:    cancel,
+	}
+	for range workers {
+		j.wg.Add(1)
+		go j.work()
+	}
+	return j
+}
+
+// submit queues a settlement and returns its pending job. The request ID of
+// ctx is kept for logging, ctx itself is not used by the settlement. done, if
+// set, is called with the result.
+func (j *settleJobs) submit(ctx context.Context, req types.SettleRequest, done func(*types.SettleResponse)) (types.SettleJob, error) {
+	var id [16]byte
+	rand.Read(id[:])
+	now := j.now().Unix()
+	job := &settleJob{
+		job: types.SettleJob{
+			ID:        hex.EncodeToString(id[:]),
+			Status:    types.SettleJobPending,
+			CreatedAt: now,
+			UpdatedAt: now,
+		},
+		req:       req,
+		requestID: requestIDFromContext(ctx),
+		done:      done,
+	}
+
+	// Persist the job before accepting it
+	if j.journal != nil {
+		name, err := j.journal.add(settlementKey(&req.PaymentPayload, &req.PaymentRequirements), journalEntry{
+			JobID:      job.job.ID,
+			RequestID:  job.requestID,
+			AcceptedAt: now,
+			Request:    req,
+		})
+		if err != nil {
+			return types.SettleJob{}, err
+		}
+		job.journal = name
+	}
+
+	if err := j.enqueue(job); err != nil {
+		j.forget(job)
+		return types.SettleJob{}, err
+	}
+	return job.job, nil
+}
+
+// restore queues a settlement recovered from the journal under its original
+// job ID, or a new one when it was settled synchronously
+func (j *settleJobs) restore(record journalRecord) (types.SettleJob, error) {
+	id := record.entry.JobID
+	if id == "" {
+		var random [16]byte
+		rand.Read(random[:])
+		id = hex.EncodeToString(random[:])
+	}
+	job := &settleJob{
+		job: types.SettleJob{
+			ID:        id,
+			Status:    types.SettleJobPending,
+			CreatedAt: record.entry.AcceptedAt,
+			UpdatedAt: j.now().Unix(),
+		},
+		req:       record.entry.Request,
+		requestID: record.entry.RequestID,
+		journal:   record.name,
+	}
+	if err := j.enqueue(job); err != nil {
+		return types.SettleJob{}, err
+	}
+	return job.job, nil
+}
+
+// enqueue hands a job to the workers
+func (j *settleJobs) enqueue(job *settleJob) error {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	if j.closed {
+		return errSettleJobsClosed
+	}
+	j.prune()
+
+	select {
+	case j.queue <- job:
+		j.jobs[job.job.ID] = job
+		return nil
+	default:
+		return errSettleJobQueueFull
+	}
+}
+
+// forget removes a finished job from the journal
+func (j *settleJobs) forget(job *settleJob) {
+	if job.journal == "" {
+		return
+	}
+	if err := j.journal.remove(job.journal); err != nil {
+		j.logger.Warn("failed to update settlement journal", "job", job.job.ID, "error", err)
+	}
+}
+
+// get returns a snapshot of a job
+func (j *settleJobs) get(id string) (types.SettleJob, bool) {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	job, ok := j.jobs[id]
+	if !ok {
+		return types.SettleJob{}, false
+	}
+	return job.job, true
+}
+
+// depth returns the jobs waiting for a worker, the queue capacity and the
+// jobs still pending, including those being settled
+func (j *settleJobs) depth() (queued, capacity, pending int) {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	for _, job := range j.jobs {
+		if job.job.Status == types.SettleJobPending {
+			pending++
+		}
+	}
+	return len(j.queue), cap(j.queue), pending
+}
+
+func (j *settleJobs) work() {
+	defer j.wg.Done()
+	for job := range j.queue {
+		resp := j.settle(withRequestID(j.ctx, job.requestID), &job.req)
+		if job.done != nil {
+			job.done(resp)
+		}
+
+		// A settlement cut short by shutdown stays in the journal to be
+		// settled again on the next start
+		if resp.Success || resp.Transaction != "" || j.ctx.Err() == nil {
+			j.forget(job)
+		}
+
+		j.mu.Lock()
+		job.job.Response = resp
+		job.job.Status = types.SettleJobFailed
+		if resp.Success {
+			job.job.Status = types.SettleJobConfirmed
+		}
+		job.job.UpdatedAt = j.now().Unix()
+		j.mu.Unlock()
+	}
+}
+
+// reorged updates the job settled by txHash after a chain reorganization
+// dropped the transaction. Its status becomes reorged, with the replacement
+// transaction if one was sent, or fails with lostErr.
+func (j *settleJobs) reorged(txHash, replacement string, lostErr error) {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	for _, job := range j.jobs {
+		if job.job.Response == nil || job.job.Response.Transaction != txHash {
+			continue
+		}
+		resp := *job.job.Response
+		resp.Status = types.SettleStatusReorged
+		if replacement != "" {
+			resp.Transaction = replacement
+		}
+		if lostErr != nil {
+			(&settleError{code: SettleErrTransactionReorged, err: lostErr}).fail(&resp)
+			job.job.Status = types.SettleJobFailed
+		}
+		job.job.Response = &resp
+		job.job.UpdatedAt = j.now().Unix()
+	}
+}
+
+// replaced points the job settled by txHash to the transaction that replaced
+// it with a higher gas price
+func (j *settleJobs) replaced(txHash, replacement string) {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	for _, job := range j.jobs {
+		if job.job.Response == nil || job.job.Response.Transaction != txHash {
+			continue
+		}
+		resp := *job.job.Response
+		resp.Transaction = replacement
+		job.job.Response = &resp
+		job.job.UpdatedAt = j.now().Unix()
+	}
+}
+
+// prune forgets finished jobs past retention. Callers must hold mu.
+func (j *settleJobs) prune() {
+	cutoff := j.now().Add(-j.retention).Unix()
+	for id, job := range j.jobs {
+		if job.job.Status != types.SettleJobPending && job.job.UpdatedAt < cutoff {
+			delete(j.jobs, id)
+		}
+	}
+}
+
+// close stops accepting jobs and waits for queued ones until ctx is done,
+// then cancels the settlements still running
+func (j *settleJobs) close(ctx context.Context) {
+	j.closeOnce.Do(func() {
+		j.mu.Lock()
+		j.closed = true
+		close(j.queue)
+		j.mu.Unlock()
+
+		done := make(chan struct{})
+		go func() {
+			j.wg.Wait()
+			close(done)
+		}()
+		select {
+		case <-done:
+		case <-ctx.Done():
+			j.cancel()
+			<-done
+		}
+		j.cancel()
+	})
+}
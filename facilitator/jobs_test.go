@@ -0,0 +1,121 @@
+package facilitator
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/vorpalengineering/x402-go/types"
+)
+
+func TestAsyncSettle(t *testing.T) {
+	testConfig := &FacilitatorConfig{
+		Networks: map[string]NetworkConfig{
+			"eip155:8453": {
+				RpcUrl: "http://127.0.0.1:1",
+			},
+		},
+		Supported: []types.SupportedKind{
+			{Scheme: "exact", Network: "eip155:8453"},
+		},
+		Log: LogConfig{
+			Level: "info",
+		},
+	}
+	f := NewFacilitator(testConfig)
+	defer f.Close()
+
+	// Payload without a signature fails without touching the chain
+	requirements := types.PaymentRequirements{
+		Scheme:  "exact",
+		Network: "eip155:8453",
+		Amount:  "1000000",
+		Asset:   "0x833589fCD6eDb6E08f4c7C32D4f71b54bdA02913",
+		PayTo:   "0x70997970C51812dc3A010C7d01b50e0d17dc79C8",
+	}
+	body, _ := json.Marshal(types.SettleRequest{
+		PaymentPayload: types.PaymentPayload{
+			X402Version: 2,
+			Accepted:    requirements,
+			Payload:     map[string]any{},
+		},
+		PaymentRequirements: requirements,
+	})
+
+	// Submit returns a pending job immediately
+	req, _ := http.NewRequest("POST", "/settle?async=true", bytes.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	recorder := httptest.NewRecorder()
+	f.router.ServeHTTP(recorder, req)
+	if recorder.Code != http.StatusAccepted {
+		t.Fatalf("Expected status 202, got %d", recorder.Code)
+	}
+	var job types.SettleJob
+	json.Unmarshal(recorder.Body.Bytes(), &job)
+	if job.ID == "" || job.Status != types.SettleJobPending {
+		t.Fatalf("Expected pending job with an ID, got %+v", job)
+	}
+
+	// Poll until the job finishes
+	deadline := time.Now().Add(5 * time.Second)
+	for job.Status == types.SettleJobPending && time.Now().Before(deadline) {
+		time.Sleep(5 * time.Millisecond)
+		recorder = httptest.NewRecorder()
+		f.router.ServeHTTP(recorder, httptest.NewRequest("GET", "/settle/"+job.ID, nil))
+		if recorder.Code != http.StatusOK {
+			t.Fatalf("Expected status 200, got %d", recorder.Code)
+		}
+		json.Unmarshal(recorder.Body.Bytes(), &job)
+	}
+	if job.Status != types.SettleJobFailed || job.Response == nil || job.Response.ErrorMessage != "missing signature" {
+		t.Errorf("Expected failed job with missing signature, got %+v", job)
+	}
+
+	// Unknown jobs are not found
+	recorder = httptest.NewRecorder()
+	f.router.ServeHTTP(recorder, httptest.NewRequest("GET", "/settle/unknown", nil))
+	if recorder.Code != http.StatusNotFound {
+		t.Errorf("Expected status 404, got %d", recorder.Code)
+	}
+}
+
+func TestSettleJobsQueueFull(t *testing.T) {
+	release := make(chan struct{})
+	jobs := newSettleJobs(SettleJobsConfig{Workers: 1, QueueSize: 1}, time.Now, func(ctx context.Context, req *types.SettleRequest) *types.SettleResponse {
+		<-release
+		return &types.SettleResponse{Success: true, Transaction: "0xabc"}
+	})
+
+	// One job running, one queued, the next is rejected
+	first, err := jobs.submit(context.Background(), types.SettleRequest{}, nil)
+	if err != nil {
+		t.Fatalf("Expected first job to be accepted, got %v", err)
+	}
+	for {
+		if len(jobs.queue) == 0 {
+			break
+		}
+		time.Sleep(time.Millisecond)
+	}
+	if _, err := jobs.submit(context.Background(), types.SettleRequest{}, nil); err != nil {
+		t.Fatalf("Expected second job to be queued, got %v", err)
+	}
+	if _, err := jobs.submit(context.Background(), types.SettleRequest{}, nil); err != errSettleJobQueueFull {
+		t.Errorf("Expected queue full error, got %v", err)
+	}
+
+	// Close waits for queued jobs
+	close(release)
+	jobs.close(context.Background())
+	job, _ := jobs.get(first.ID)
+	if job.Status != types.SettleJobConfirmed || job.Response.Transaction != "0xabc" {
+		t.Errorf("Expected confirmed job, got %+v", job)
+	}
+	if _, err := jobs.submit(context.Background(), types.SettleRequest{}, nil); err != errSettleJobsClosed {
+		t.Errorf("Expected closed error, got %v", err)
+	}
+}
@@ -0,0 +1,181 @@
+package facilitator
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/vorpalengineering/x402-go/types"
+)
+
+// journalEntry is an accepted settlement that has not finished yet
+type journalEntry struct {
+	// JobID is the asynchronous job, empty for /settle without async
+	JobID      string              `json:"jobId,omitempty"`
+	RequestID  string              `json:"requestId,omitempty"`
+	AcceptedAt int64               `json:"acceptedAt"`
+	Request    types.SettleRequest `json:"request"`
+}
+
+// settleJournal keeps a file per accepted settlement until it finishes, so
+// settlements a crash interrupted can be settled again on the next start.
+// Files are named by the authorization's settlement key, so duplicates of
+// an authorization share one entry.
+type settleJournal struct {
+	dir string
+
+	mu   sync.Mutex
+	refs map[string]int
+}
+
+// openSettleJournal creates the journal directory if needed
+func openSettleJournal(dir string) (*settleJournal, error) {
+	if err := os.MkdirAll(dir, 0o700); err != nil {
+		return nil, fmt.Errorf("failed to create settlement journal: %w", err)
+	}
+	return &settleJournal{dir: dir, refs: make(map[string]int)}, nil
+}
+
+// add records a settlement before it is queued or sent and returns the name
+// to pass to remove when it finishes
+func (j *settleJournal) add(key string, entry journalEntry) (string, error) {
+	name := journalName(key)
+
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	// A duplicate of an authorization already recorded
+	if j.refs[name] > 0 {
+		j.refs[name]++
+		return name, nil
+	}
+
+	data, err := json.Marshal(entry)
+	if err != nil {
+		return "", fmt.Errorf("failed to encode settlement: %w", err)
+	}
+	if err := writeFileSync(filepath.Join(j.dir, name), data); err != nil {
+		return "", fmt.Errorf("failed to persist settlement: %w", err)
+	}
+	j.refs[name]++
+	return name, nil
+}
+
+// remove drops a finished settlement once no duplicate still uses its entry
+func (j *settleJournal) remove(name string) error {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	j.refs[name]--
+	if j.refs[name] > 0 {
+		return nil
+	}
+	delete(j.refs, name)
+	if err := os.Remove(filepath.Join(j.dir, name)); err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("failed to remove settlement from journal: %w", err)
+	}
+	return nil
+}
+
+// journalRecord is an entry read back from the journal
+type journalRecord struct {
+	name  string
+	entry journalEntry
+}
+
+// pending returns the settlements left by the previous run, oldest first,
+// and takes a reference to each for the caller to remove
+func (j *settleJournal) pending() ([]journalRecord, error) {
+	files, err := os.ReadDir(j.dir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read settlement journal: %w", err)
+	}
+
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	var records []journalRecord
+	for _, file := range files {
+		name := file.Name()
+		if file.IsDir() || !strings.HasSuffix(name, ".json") {
+			continue
+		}
+		data, err := os.ReadFile(filepath.Join(j.dir, name))
+		if err != nil {
+			return nil, fmt.Errorf("failed to read journal entry %s: %w", name, err)
+		}
+		var entry journalEntry
+		if err := json.Unmarshal(data, &entry); err != nil {
+			return nil, fmt.Errorf("invalid journal entry %s: %w", name, err)
+		}
+		records = append(records, journalRecord{name: name, entry: entry})
+	}
+	for _, record := range records {
+		j.refs[record.name]++
+	}
+	sort.SliceStable(records, func(a, b int) bool {
+		return records[a].entry.AcceptedAt < records[b].entry.AcceptedAt
+	})
+	return records, nil
+}
+
+// journalName names the entry of a settlement key, or a random name for
+// payloads without one
+func journalName(key string) string {
+	if key == "" {
+		var id [16]byte
+		rand.Read(id[:])
+		return "settle-" + hex.EncodeToString(id[:]) + ".json"
+	}
+	sum := sha256.Sum256([]byte(key))
+	return hex.EncodeToString(sum[:]) + ".json"
+}
+
+// writeFileSync writes a file through a temporary file renamed into place,
+// synced so it survives a crash
+func writeFileSync(path string, data []byte) error {
+	tmp, err := os.CreateTemp(filepath.Dir(path), ".tmp-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
+
+// recoverSettlements queues the settlements left in the journal as jobs.
+// Jobs accepted with ?async=true keep their ID, so clients can keep polling.
+func (f *Facilitator) recoverSettlements() error {
+	records, err := f.journal.pending()
+	if err != nil {
+		return err
+	}
+	for _, record := range records {
+		job, err := f.jobs.restore(record)
+		if err != nil {
+			// Left in the journal for the next start
+			f.logger.Error("failed to recover settlement", "job", record.entry.JobID, "error", err)
+			continue
+		}
+		f.logger.Warn("recovered unfinished settlement",
+			append(paymentLogAttrs(&record.entry.Request.PaymentPayload, &record.entry.Request.PaymentRequirements), "job", job.ID)...)
+	}
+	return nil
+}
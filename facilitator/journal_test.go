@@ -0,0 +1,113 @@
+package facilitator
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/vorpalengineering/x402-go/types"
+)
+
+func TestSettleJournalRecovery(t *testing.T) {
+	dir := t.TempDir()
+	requirements := types.PaymentRequirements{
+		Scheme:  "exact",
+		Network: "eip155:8453",
+		Amount:  "1000000",
+		Asset:   "0x833589fCD6eDb6E08f4c7C32D4f71b54bdA02913",
+		PayTo:   "0x3C44CdDdB6a900fa2b585dd299e03d12FA4293BC",
+	}
+	req := types.SettleRequest{
+		PaymentPayload: types.PaymentPayload{
+			X402Version: 2,
+			Accepted:    requirements,
+			Payload: map[string]any{
+				"signature": "0x" + strings.Repeat("ab", 64) + "1b",
+				"authorization": map[string]any{
+					"from":        "0x70997970C51812dc3A010C7d01b50e0d17dc79C8",
+					"to":          requirements.PayTo,
+					"value":       "1000000",
+					"validAfter":  0,
+					"validBefore": 4102444800,
+					"nonce":       "0x" + strings.Repeat("01", 32),
+				},
+			},
+		},
+		PaymentRequirements: requirements,
+	}
+
+	// The first run accepts the settlement twice and shuts down before it finishes
+	journal, err := openSettleJournal(dir)
+	if err != nil {
+		t.Fatalf("Failed to open journal: %v", err)
+	}
+	jobs := newSettleJobs(SettleJobsConfig{Workers: 1}, time.Now, func(ctx context.Context, req *types.SettleRequest) *types.SettleResponse {
+		<-ctx.Done()
+		return settleFailed(types.ErrCodeSettlementFailed, ctx.Err().Error())
+	})
+	jobs.journal = journal
+	first, err := jobs.submit(context.Background(), req, nil)
+	if err != nil {
+		t.Fatalf("Failed to submit job: %v", err)
+	}
+	if _, err := jobs.submit(context.Background(), req, nil); err != nil {
+		t.Fatalf("Failed to submit duplicate job: %v", err)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	jobs.close(ctx)
+
+	// One entry per authorization survives
+	files, _ := os.ReadDir(dir)
+	if len(files) != 1 {
+		t.Fatalf("Expected one journal entry, got %d", len(files))
+	}
+
+	// The next run settles it again under the same job ID
+	f := NewFacilitator(&FacilitatorConfig{
+		Networks:   map[string]NetworkConfig{"eip155:8453": {RpcUrl: "http://127.0.0.1:1"}},
+		Log:        LogConfig{Level: "error"},
+		SettleJobs: SettleJobsConfig{JournalDir: dir},
+	})
+	defer f.Close()
+	var settled *types.SettleRequest
+	f.jobs.settle = func(ctx context.Context, req *types.SettleRequest) *types.SettleResponse {
+		settled = req
+		return &types.SettleResponse{Success: true, Transaction: "0xabc", Network: req.PaymentRequirements.Network}
+	}
+	if err := f.recoverSettlements(); err != nil {
+		t.Fatalf("Failed to recover settlements: %v", err)
+	}
+
+	var job types.SettleJob
+	deadline := time.Now().Add(5 * time.Second)
+	for job.Status != types.SettleJobConfirmed && time.Now().Before(deadline) {
+		time.Sleep(5 * time.Millisecond)
+		recorder := httptest.NewRecorder()
+		f.router.ServeHTTP(recorder, httptest.NewRequest("GET", "/settle/"+first.ID, nil))
+		if recorder.Code == http.StatusOK {
+			json.Unmarshal(recorder.Body.Bytes(), &job)
+		}
+	}
+	if job.Status != types.SettleJobConfirmed || job.Response.Transaction != "0xabc" {
+		t.Fatalf("Expected recovered job %s to be confirmed, got %+v", first.ID, job)
+	}
+	if settled.PaymentPayload.Payload["signature"] != req.PaymentPayload.Payload["signature"] {
+		t.Errorf("Expected the original payload to be settled, got %+v", settled.PaymentPayload)
+	}
+
+	// Finished settlements leave the journal
+	deadline = time.Now().Add(time.Second)
+	for time.Now().Before(deadline) {
+		if files, _ := os.ReadDir(dir); len(files) == 0 {
+			return
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+	t.Error("Expected the journal to be empty")
+}
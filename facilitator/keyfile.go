@@ -0,0 +1,317 @@
+package facilitator
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/ecdsa"
+	"crypto/hkdf"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+
+	"github.com/ethereum/go-ethereum/crypto"
+	"golang.org/x/crypto/chacha20poly1305"
+	"golang.org/x/crypto/scrypt"
+)
+
+// PassphrasePrompt reads the passphrase of an encrypted key file or keystore
+// whose passphrase environment variable is unset. cmd/facilitator sets it to
+// a terminal prompt. When nil, the environment variable is required.
+var PassphrasePrompt func(file string) ([]byte, error)
+
+// keyFileMagic starts the AES-GCM key files written by EncryptKeyFile. It is
+// followed by the scrypt work factor (log2 N), a 16-byte salt, a 12-byte
+// nonce and the sealed hex key. The header is authenticated as additional data.
+const keyFileMagic = "X402KEY1"
+
+const (
+	// keyFileLogN is the scrypt work factor of new key files (N = 2^18)
+	keyFileLogN = 18
+	// maxKeyFileLogN caps the work factor of files read, as age does
+	maxKeyFileLogN = 22
+)
+
+// EncryptKeyFile seals a private key for signer.key_file with AES-256-GCM
+// under a scrypt-derived key
+func EncryptKeyFile(key *ecdsa.PrivateKey, passphrase []byte) ([]byte, error) {
+	header := make([]byte, 0, len(keyFileMagic)+1+16+12)
+	header = append(header, keyFileMagic...)
+	header = append(header, keyFileLogN)
+	salt := make([]byte, 16)
+	nonce := make([]byte, 12)
+	if _, err := rand.Read(salt); err != nil {
+		return nil, err
+	}
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, err
+	}
+	header = append(header, salt...)
+	header = append(header, nonce...)
+
+	aead, err := keyFileCipher(passphrase, salt, keyFileLogN)
+	if err != nil {
+		return nil, err
+	}
+	plaintext := []byte(hex.EncodeToString(crypto.FromECDSA(key)))
+	defer clear(plaintext)
+	return aead.Seal(header, nonce, plaintext, header), nil
+}
+
+// decryptKeyFile opens an age file encrypted with a passphrase (binary or
+// armored) or an AES-GCM file from EncryptKeyFile, and parses the hex private
+// key inside
+func decryptKeyFile(data, passphrase []byte) (*ecdsa.PrivateKey, error) {
+	var plaintext []byte
+	var err error
+	switch {
+	case bytes.HasPrefix(data, []byte(keyFileMagic)):
+		plaintext, err = decryptAESGCMKeyFile(data, passphrase)
+	case bytes.HasPrefix(data, []byte(ageArmorBegin)):
+		data, err = ageDearmor(data)
+		if err == nil {
+			plaintext, err = decryptAgeFile(data, passphrase)
+		}
+	case bytes.HasPrefix(data, []byte(ageVersionLine)):
+		plaintext, err = decryptAgeFile(data, passphrase)
+	default:
+		return nil, errors.New("unknown key file format, expected age or AES-GCM")
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer clear(plaintext)
+
+	keyHex := bytes.TrimPrefix(bytes.TrimSpace(plaintext), []byte("0x"))
+	keyBytes := make([]byte, hex.DecodedLen(len(keyHex)))
+	defer clear(keyBytes)
+	if _, err := hex.Decode(keyBytes, keyHex); err != nil {
+		return nil, errors.New("key file does not hold a hex private key")
+	}
+	key, err := crypto.ToECDSA(keyBytes)
+	if err != nil {
+		return nil, fmt.Errorf("invalid private key: %w", err)
+	}
+	return key, nil
+}
+
+func keyFileCipher(passphrase, salt []byte, logN int) (cipher.AEAD, error) {
+	derived, err := scrypt.Key(passphrase, salt, 1<<logN, 8, 1, 32)
+	if err != nil {
+		return nil, err
+	}
+	defer clear(derived)
+	block, err := aes.NewCipher(derived)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+func decryptAESGCMKeyFile(data, passphrase []byte) ([]byte, error) {
+	headerLen := len(keyFileMagic) + 1 + 16 + 12
+	if len(data) < headerLen {
+		return nil, errors.New("key file is truncated")
+	}
+	header := data[:headerLen]
+	logN := int(header[len(keyFileMagic)])
+	if logN < 1 || logN > maxKeyFileLogN {
+		return nil, fmt.Errorf("unsupported scrypt work factor %d", logN)
+	}
+	salt := header[len(keyFileMagic)+1 : len(keyFileMagic)+17]
+	nonce := header[len(keyFileMagic)+17:]
+
+	aead, err := keyFileCipher(passphrase, salt, logN)
+	if err != nil {
+		return nil, err
+	}
+	plaintext, err := aead.Open(nil, nonce, data[headerLen:], header)
+	if err != nil {
+		return nil, errors.New("wrong passphrase or corrupted key file")
+	}
+	return plaintext, nil
+}
+
+// age format (https://age-encryption.org/v1), limited to files encrypted
+// with a passphrase, i.e. a single scrypt recipient stanza
+const (
+	ageVersionLine  = "age-encryption.org/v1"
+	ageArmorBegin   = "-----BEGIN AGE ENCRYPTED FILE-----"
+	ageArmorEnd     = "-----END AGE ENCRYPTED FILE-----"
+	ageScryptLabel  = "age-encryption.org/v1/scrypt"
+	ageChunkSize    = 64 * 1024
+	ageFileKeySize  = 16
+	agePayloadNonce = 16
+)
+
+var ageBase64 = base64.RawStdEncoding.Strict()
+
+// ageDearmor decodes a PEM-style armored age file
+func ageDearmor(data []byte) ([]byte, error) {
+	text := strings.TrimSpace(string(data))
+	if !strings.HasSuffix(text, ageArmorEnd) {
+		return nil, errors.New("age armor is not terminated")
+	}
+	body := strings.TrimSuffix(strings.TrimPrefix(text, ageArmorBegin), ageArmorEnd)
+	decoded, err := base64.StdEncoding.DecodeString(strings.Join(strings.Fields(body), ""))
+	if err != nil {
+		return nil, fmt.Errorf("invalid age armor: %w", err)
+	}
+	return decoded, nil
+}
+
+// decryptAgeFile unwraps the file key with the passphrase, checks the header
+// MAC and decrypts the STREAM payload
+func decryptAgeFile(data, passphrase []byte) ([]byte, error) {
+	r := bufio.NewReader(bytes.NewReader(data))
+	readLine := func() (string, error) {
+		line, err := r.ReadString('\n')
+		if err != nil {
+			return "", errors.New("age header is truncated")
+		}
+		return strings.TrimSuffix(line, "\n"), nil
+	}
+
+	// Version line, then exactly one scrypt stanza
+	var header bytes.Buffer
+	line, err := readLine()
+	if err != nil || line != ageVersionLine {
+		return nil, errors.New("unsupported age version")
+	}
+	header.WriteString(line + "\n")
+	line, err = readLine()
+	if err != nil {
+		return nil, err
+	}
+	args := strings.Split(line, " ")
+	if len(args) != 4 || args[0] != "->" || args[1] != "scrypt" {
+		return nil, errors.New("age file is not encrypted with a passphrase")
+	}
+	header.WriteString(line + "\n")
+	salt, err := ageBase64.DecodeString(args[2])
+	if err != nil || len(salt) != 16 {
+		return nil, errors.New("invalid age scrypt salt")
+	}
+	logN, err := strconv.Atoi(args[3])
+	if err != nil || logN < 1 || logN > maxKeyFileLogN || args[3] != strconv.Itoa(logN) {
+		return nil, fmt.Errorf("unsupported age scrypt work factor %s", args[3])
+	}
+	var body []byte
+	for {
+		line, err = readLine()
+		if err != nil {
+			return nil, err
+		}
+		header.WriteString(line + "\n")
+		chunk, err := ageBase64.DecodeString(line)
+		if err != nil {
+			return nil, errors.New("invalid age stanza body")
+		}
+		body = append(body, chunk...)
+		if len(line) < 64 {
+			break
+		}
+	}
+
+	// MAC line
+	line, err = readLine()
+	if err != nil {
+		return nil, err
+	}
+	if !strings.HasPrefix(line, "--- ") {
+		return nil, errors.New("age file has more than one recipient")
+	}
+	header.WriteString("---")
+	mac, err := ageBase64.DecodeString(strings.TrimPrefix(line, "--- "))
+	if err != nil {
+		return nil, errors.New("invalid age header mac")
+	}
+
+	// Unwrap the file key
+	wrapSalt := append([]byte(ageScryptLabel), salt...)
+	wrapKey, err := scrypt.Key(passphrase, wrapSalt, 1<<logN, 8, 1, chacha20poly1305.KeySize)
+	if err != nil {
+		return nil, err
+	}
+	defer clear(wrapKey)
+	wrap, err := chacha20poly1305.New(wrapKey)
+	if err != nil {
+		return nil, err
+	}
+	if len(body) != ageFileKeySize+wrap.Overhead() {
+		return nil, errors.New("invalid age scrypt stanza")
+	}
+	fileKey, err := wrap.Open(nil, make([]byte, chacha20poly1305.NonceSize), body, nil)
+	if err != nil {
+		return nil, errors.New("wrong passphrase or corrupted key file")
+	}
+	defer clear(fileKey)
+
+	// Check the header
+	macKey, err := hkdf.Key(sha256.New, fileKey, nil, "header", sha256.Size)
+	if err != nil {
+		return nil, err
+	}
+	defer clear(macKey)
+	h := hmac.New(sha256.New, macKey)
+	h.Write(header.Bytes())
+	if !hmac.Equal(h.Sum(nil), mac) {
+		return nil, errors.New("age header mac mismatch")
+	}
+
+	// Decrypt the payload in 64 KiB chunks
+	nonce := make([]byte, agePayloadNonce)
+	if _, err := io.ReadFull(r, nonce); err != nil {
+		return nil, errors.New("age payload is truncated")
+	}
+	streamKey, err := hkdf.Key(sha256.New, fileKey, nonce, "payload", chacha20poly1305.KeySize)
+	if err != nil {
+		return nil, err
+	}
+	defer clear(streamKey)
+	stream, err := chacha20poly1305.New(streamKey)
+	if err != nil {
+		return nil, err
+	}
+	payload, _ := io.ReadAll(r)
+	var plaintext []byte
+	chunkNonce := make([]byte, chacha20poly1305.NonceSize)
+	for counter := uint64(0); ; counter++ {
+		size := min(len(payload), ageChunkSize+stream.Overhead())
+		last := size == len(payload)
+		binary.BigEndian.PutUint64(chunkNonce[3:11], counter)
+		if last {
+			chunkNonce[11] = 1
+		}
+		chunk, err := stream.Open(nil, chunkNonce, payload[:size], nil)
+		if err != nil {
+			clear(plaintext)
+			return nil, errors.New("age payload is corrupted")
+		}
+		plaintext = append(plaintext, chunk...)
+		clear(chunk)
+		payload = payload[size:]
+		if last {
+			return plaintext, nil
+		}
+	}
+}
+
+// zeroKey overwrites a private key's scalar so it doesn't linger in memory
+// after shutdown
+func zeroKey(key *ecdsa.PrivateKey) {
+	if key == nil || key.D == nil {
+		return
+	}
+	clear(key.D.Bits())
+	key.D.SetInt64(0)
+}
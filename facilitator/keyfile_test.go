@@ -0,0 +1,115 @@
+package facilitator
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/crypto"
+)
+
+// ageKeyFile is the test key encrypted with `age -p` semantics under the
+// passphrase "correct horse" (scrypt work factor 10), armored
+const ageKeyFile = `-----BEGIN AGE ENCRYPTED FILE-----
+YWdlLWVuY3J5cHRpb24ub3JnL3YxCi0+IHNjcnlwdCB0MlRxTEFqUnBubEFlRUlL
+NUVGbWl3IDEwClBjRS94UFZiaTRDTDBxZHRYR0taQ3hJTUR0MkpOUmU2L2kxaDY5
+N0hrNlEKLS0tIFRRMjZOMmZsUGlJcXZOelczSHgxSnFyUHJ1dmxtR29JMEhidmxZ
+RkwwdEEK7Zkuq2fFL8gZuHE37SFc7pj++lQAHYaRHnijQP+Ym60rcN9AGRttJ//T
+AOmtnj/LYTliIBkgy6UhTQGNGtam/YSX3McwPOgYaFiTPMj+zKVKh0EvN+gMiY0k
+6RSIa/TqAQpZ
+-----END AGE ENCRYPTED FILE-----
+`
+
+func TestLoadConfigKeyFile(t *testing.T) {
+	dir := t.TempDir()
+	privKey, _ := crypto.HexToECDSA("ac0974bec39a17e36ba4a6b4d238ff944bacb478cbed5efcae784d7bf4f2ff80")
+	address := crypto.PubkeyToAddress(privKey.PublicKey)
+
+	aesPath := filepath.Join(dir, "signer.key")
+	data, err := EncryptKeyFile(privKey, []byte("correct horse"))
+	if err != nil {
+		t.Fatalf("Failed to encrypt key: %v", err)
+	}
+	os.WriteFile(aesPath, data, 0600)
+	agePath := filepath.Join(dir, "signer.age")
+	os.WriteFile(agePath, []byte(ageKeyFile), 0600)
+
+	writeConfig := func(keyFile string) string {
+		configPath := filepath.Join(dir, "config.yaml")
+		os.WriteFile(configPath, []byte(`
+server:
+  port: 8080
+networks:
+  "eip155:8453":
+    rpc_url: "https://mainnet.base.org"
+supported:
+  - scheme: "exact"
+    network: "eip155:8453"
+transaction:
+  timeout_seconds: 120
+  max_gas_price: "100000000000"
+log:
+  level: "info"
+signer:
+  key_file: "`+keyFile+`"
+  passphrase_env: "TEST_KEY_PASSPHRASE"
+`), 0600)
+		return configPath
+	}
+	t.Setenv("X402_FACILITATOR_PRIVATE_KEY", "")
+
+	// Both formats decrypt with the passphrase from the configured env var
+	t.Setenv("TEST_KEY_PASSPHRASE", "correct horse")
+	for _, keyFile := range []string{aesPath, agePath} {
+		config, err := LoadConfig(writeConfig(keyFile))
+		if err != nil {
+			t.Fatalf("Expected %s to load, got error: %v", keyFile, err)
+		}
+		if config.Signer.Address != address {
+			t.Errorf("Expected signer address %s, got %s", address, config.Signer.Address)
+		}
+	}
+
+	// Wrong passphrase
+	t.Setenv("TEST_KEY_PASSPHRASE", "wrong")
+	for _, keyFile := range []string{aesPath, agePath} {
+		if _, err := LoadConfig(writeConfig(keyFile)); err == nil {
+			t.Errorf("Expected error for wrong passphrase of %s", keyFile)
+		}
+	}
+
+	// Without the env var the passphrase is asked for
+	os.Unsetenv("TEST_KEY_PASSPHRASE")
+	if _, err := LoadConfig(writeConfig(aesPath)); err == nil {
+		t.Error("Expected error without passphrase or prompt")
+	}
+	var prompted string
+	PassphrasePrompt = func(file string) ([]byte, error) {
+		prompted = file
+		return []byte("correct horse"), nil
+	}
+	defer func() { PassphrasePrompt = nil }()
+	config, err := LoadConfig(writeConfig(aesPath))
+	if err != nil {
+		t.Fatalf("Expected prompted passphrase to decrypt, got error: %v", err)
+	}
+	if prompted != aesPath {
+		t.Errorf("Expected prompt for %s, got %q", aesPath, prompted)
+	}
+
+	// The loaded key is zeroed on shutdown, keys set by callers are not
+	f := NewFacilitator(config)
+	f.Close()
+	if config.Signer.PrivateKey.D.Sign() != 0 {
+		t.Error("Expected the loaded key to be zeroed on close")
+	}
+	f = NewFacilitator(&FacilitatorConfig{
+		Networks:    config.Networks,
+		Transaction: config.Transaction,
+		Signer:      SignerConfig{PrivateKey: privKey},
+	})
+	f.Close()
+	if privKey.D.Sign() == 0 {
+		t.Error("Expected a caller's key to be left alone")
+	}
+}
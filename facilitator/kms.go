@@ -0,0 +1,300 @@
+package facilitator
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"crypto/x509/pkix"
+	"encoding/asn1"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math/big"
+	"net/http"
+	"os"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	ethtypes "github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/signer/core/apitypes"
+)
+
+const (
+	kmsKeySpecSecp256k1 = "ECC_SECG_P256K1"
+	kmsRequestTimeout   = 10 * time.Second
+)
+
+// KMSConfig delegates signing to an asymmetric ECC_SECG_P256K1 key in AWS KMS,
+// so the private key is never loaded into the facilitator
+type KMSConfig struct {
+	// KeyID is the key ARN, key ID or alias
+	KeyID string `yaml:"key_id"`
+
+	// Region defaults to the region of a key ARN, then AWS_REGION
+	Region string `yaml:"region"`
+
+	// Endpoint overrides https://kms.<region>.amazonaws.com, e.g. for a VPC endpoint
+	Endpoint string `yaml:"endpoint"`
+}
+
+type awsCredentials struct {
+	accessKeyID     string
+	secretAccessKey string
+	sessionToken    string
+}
+
+// kmsSigner signs transaction hashes with a KMS key. Credentials are read from
+// AWS_ACCESS_KEY_ID, AWS_SECRET_ACCESS_KEY and AWS_SESSION_TOKEN.
+type kmsSigner struct {
+	keyID    string
+	region   string
+	endpoint string
+	creds    awsCredentials
+	client   *http.Client
+	now      func() time.Time
+	address  common.Address
+}
+
+func newKMSSigner(ctx context.Context, cfg KMSConfig) (*kmsSigner, error) {
+	// Resolve region
+	region := cfg.Region
+	if region == "" {
+		if parts := strings.Split(cfg.KeyID, ":"); len(parts) > 3 && parts[0] == "arn" {
+			region = parts[3]
+		}
+	}
+	if region == "" {
+		region = os.Getenv("AWS_REGION")
+	}
+	if region == "" {
+		return nil, fmt.Errorf("kms region required")
+	}
+	endpoint := cfg.Endpoint
+	if endpoint == "" {
+		endpoint = fmt.Sprintf("https://kms.%s.amazonaws.com", region)
+	}
+
+	// Load credentials
+	creds := awsCredentials{
+		accessKeyID:     os.Getenv("AWS_ACCESS_KEY_ID"),
+		secretAccessKey: os.Getenv("AWS_SECRET_ACCESS_KEY"),
+		sessionToken:    os.Getenv("AWS_SESSION_TOKEN"),
+	}
+	if creds.accessKeyID == "" || creds.secretAccessKey == "" {
+		return nil, fmt.Errorf("AWS_ACCESS_KEY_ID and AWS_SECRET_ACCESS_KEY environment variables required for kms")
+	}
+
+	signer := &kmsSigner{
+		keyID:    cfg.KeyID,
+		region:   region,
+		endpoint: strings.TrimSuffix(endpoint, "/"),
+		creds:    creds,
+		client:   &http.Client{Timeout: kmsRequestTimeout},
+		now:      time.Now,
+	}
+
+	// Derive address from the public key
+	address, err := signer.fetchAddress(ctx)
+	if err != nil {
+		return nil, err
+	}
+	signer.address = address
+	return signer, nil
+}
+
+// fetchAddress gets the public key of the KMS key and returns its address
+func (s *kmsSigner) fetchAddress(ctx context.Context) (common.Address, error) {
+	var res struct {
+		KeySpec   string
+		PublicKey []byte
+	}
+	if err := s.call(ctx, "GetPublicKey", map[string]any{"KeyId": s.keyID}, &res); err != nil {
+		return common.Address{}, err
+	}
+	if res.KeySpec != kmsKeySpecSecp256k1 {
+		return common.Address{}, fmt.Errorf("kms key spec must be %s, got %s", kmsKeySpecSecp256k1, res.KeySpec)
+	}
+
+	// Decode DER SubjectPublicKeyInfo
+	var spki struct {
+		Algorithm pkix.AlgorithmIdentifier
+		PublicKey asn1.BitString
+	}
+	if _, err := asn1.Unmarshal(res.PublicKey, &spki); err != nil {
+		return common.Address{}, fmt.Errorf("failed to parse kms public key: %w", err)
+	}
+	pubKey, err := crypto.UnmarshalPubkey(spki.PublicKey.Bytes)
+	if err != nil {
+		return common.Address{}, fmt.Errorf("failed to parse kms public key: %w", err)
+	}
+	return crypto.PubkeyToAddress(*pubKey), nil
+}
+
+// SignHash signs a 32-byte digest, returning an Ethereum [R || S || V] signature with V 0 or 1
+func (s *kmsSigner) SignHash(ctx context.Context, hash []byte) ([]byte, error) {
+	var res struct {
+		Signature []byte
+	}
+	err := s.call(ctx, "Sign", map[string]any{
+		"KeyId":            s.keyID,
+		"Message":          hash,
+		"MessageType":      "DIGEST",
+		"SigningAlgorithm": "ECDSA_SHA_256",
+	}, &res)
+	if err != nil {
+		return nil, err
+	}
+
+	// Decode DER signature
+	var der struct {
+		R, S *big.Int
+	}
+	if _, err := asn1.Unmarshal(res.Signature, &der); err != nil {
+		return nil, fmt.Errorf("failed to parse kms signature: %w", err)
+	}
+
+	// Ethereum only accepts the low-s form
+	curveN := crypto.S256().Params().N
+	if der.S.Cmp(new(big.Int).Rsh(curveN, 1)) > 0 {
+		der.S = new(big.Int).Sub(curveN, der.S)
+	}
+	sig := make([]byte, crypto.SignatureLength)
+	der.R.FillBytes(sig[:32])
+	der.S.FillBytes(sig[32:64])
+
+	// KMS doesn't return the recovery ID, find the one that recovers our address
+	for v := byte(0); v < 2; v++ {
+		sig[64] = v
+		pubKey, err := crypto.SigToPub(hash, sig)
+		if err == nil && crypto.PubkeyToAddress(*pubKey) == s.address {
+			return sig, nil
+		}
+	}
+	return nil, fmt.Errorf("kms signature does not recover to %s", s.address)
+}
+
+func (s *kmsSigner) Address() common.Address {
+	return s.address
+}
+
+func (s *kmsSigner) SignTx(ctx context.Context, tx *ethtypes.Transaction, chainID *big.Int) (*ethtypes.Transaction, error) {
+	signer := ethtypes.NewEIP2930Signer(chainID)
+	sig, err := s.SignHash(ctx, signer.Hash(tx).Bytes())
+	if err != nil {
+		return nil, err
+	}
+	return tx.WithSignature(signer, sig)
+}
+
+func (s *kmsSigner) SignTypedData(ctx context.Context, typedData apitypes.TypedData) ([]byte, error) {
+	hash, _, err := apitypes.TypedDataAndHash(typedData)
+	if err != nil {
+		return nil, fmt.Errorf("failed to hash typed data: %w", err)
+	}
+	sig, err := s.SignHash(ctx, hash)
+	if err != nil {
+		return nil, err
+	}
+	sig[64] += 27
+	return sig, nil
+}
+
+// call invokes a KMS JSON API action
+func (s *kmsSigner) call(ctx context.Context, action string, input any, output any) error {
+	body, err := json.Marshal(input)
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, s.endpoint+"/", bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/x-amz-json-1.1")
+	req.Header.Set("X-Amz-Target", "TrentService."+action)
+	s.sign(req, body, s.now().UTC())
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("kms %s failed: %w", action, err)
+	}
+	defer resp.Body.Close()
+	respBody, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return fmt.Errorf("kms %s failed: %w", action, err)
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		var kmsErr struct {
+			Type    string `json:"__type"`
+			Message string `json:"message"`
+		}
+		json.Unmarshal(respBody, &kmsErr)
+		return fmt.Errorf("kms %s failed: status %d: %s %s", action, resp.StatusCode, kmsErr.Type, kmsErr.Message)
+	}
+	if err := json.Unmarshal(respBody, output); err != nil {
+		return fmt.Errorf("failed to decode kms %s response: %w", action, err)
+	}
+	return nil
+}
+
+// sign adds an AWS Signature Version 4 Authorization header to req
+func (s *kmsSigner) sign(req *http.Request, body []byte, now time.Time) {
+	amzDate := now.Format("20060102T150405Z")
+	date := now.Format("20060102")
+	req.Header.Set("X-Amz-Date", amzDate)
+	if s.creds.sessionToken != "" {
+		req.Header.Set("X-Amz-Security-Token", s.creds.sessionToken)
+	}
+
+	// Canonical headers, sorted by lowercase name
+	headers := map[string]string{"host": req.URL.Host}
+	for name := range req.Header {
+		headers[strings.ToLower(name)] = strings.TrimSpace(req.Header.Get(name))
+	}
+	names := make([]string, 0, len(headers))
+	for name := range headers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	var canonicalHeaders strings.Builder
+	for _, name := range names {
+		canonicalHeaders.WriteString(name + ":" + headers[name] + "\n")
+	}
+	signedHeaders := strings.Join(names, ";")
+
+	payloadHash := sha256.Sum256(body)
+	canonicalRequest := strings.Join([]string{
+		req.Method,
+		"/",
+		"",
+		canonicalHeaders.String(),
+		signedHeaders,
+		hex.EncodeToString(payloadHash[:]),
+	}, "\n")
+
+	// String to sign
+	scope := date + "/" + s.region + "/kms/aws4_request"
+	requestHash := sha256.Sum256([]byte(canonicalRequest))
+	stringToSign := "AWS4-HMAC-SHA256\n" + amzDate + "\n" + scope + "\n" + hex.EncodeToString(requestHash[:])
+
+	// Derive signing key
+	key := hmacSHA256([]byte("AWS4"+s.creds.secretAccessKey), date)
+	key = hmacSHA256(key, s.region)
+	key = hmacSHA256(key, "kms")
+	key = hmacSHA256(key, "aws4_request")
+	signature := hex.EncodeToString(hmacSHA256(key, stringToSign))
+
+	req.Header.Set("Authorization", fmt.Sprintf("AWS4-HMAC-SHA256 Credential=%s/%s, SignedHeaders=%s, Signature=%s",
+		s.creds.accessKeyID, scope, signedHeaders, signature))
+}
+
+func hmacSHA256(key []byte, data string) []byte {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(data))
+	return mac.Sum(nil)
+}
@@ -0,0 +1,138 @@
+package facilitator
+
+import (
+	"context"
+	"crypto/x509/pkix"
+	"encoding/asn1"
+	"encoding/json"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	ethtypes "github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+)
+
+// fakeKMS serves GetPublicKey and Sign for a local key. highS returns the
+// high-s form of every signature.
+func fakeKMS(highS bool) (*httptest.Server, common.Address) {
+	privKey, _ := crypto.HexToECDSA("ac0974bec39a17e36ba4a6b4d238ff944bacb478cbed5efcae784d7bf4f2ff80")
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !strings.HasPrefix(r.Header.Get("Authorization"), "AWS4-HMAC-SHA256 Credential=AKIDEXAMPLE/") ||
+			!strings.Contains(r.Header.Get("Authorization"), "/us-east-1/kms/aws4_request") {
+			w.WriteHeader(http.StatusBadRequest)
+			w.Write([]byte(`{"__type":"MissingAuthenticationTokenException","message":"bad signature"}`))
+			return
+		}
+		var req struct {
+			KeyId   string
+			Message []byte
+		}
+		json.NewDecoder(r.Body).Decode(&req)
+
+		w.Header().Set("Content-Type", "application/x-amz-json-1.1")
+		switch r.Header.Get("X-Amz-Target") {
+		case "TrentService.GetPublicKey":
+			spki, _ := asn1.Marshal(struct {
+				Algorithm pkix.AlgorithmIdentifier
+				PublicKey asn1.BitString
+			}{
+				Algorithm: pkix.AlgorithmIdentifier{
+					Algorithm:  asn1.ObjectIdentifier{1, 2, 840, 10045, 2, 1},
+					Parameters: asn1.RawValue{FullBytes: mustMarshalOID(asn1.ObjectIdentifier{1, 3, 132, 0, 10})},
+				},
+				PublicKey: asn1.BitString{Bytes: crypto.FromECDSAPub(&privKey.PublicKey), BitLength: 65 * 8},
+			})
+			json.NewEncoder(w).Encode(map[string]any{
+				"KeyId":     req.KeyId,
+				"KeySpec":   kmsKeySpecSecp256k1,
+				"PublicKey": spki,
+			})
+		case "TrentService.Sign":
+			sig, _ := crypto.Sign(req.Message, privKey)
+			r, s := new(big.Int).SetBytes(sig[:32]), new(big.Int).SetBytes(sig[32:64])
+			if highS {
+				s.Sub(crypto.S256().Params().N, s)
+			}
+			der, _ := asn1.Marshal(struct{ R, S *big.Int }{r, s})
+			json.NewEncoder(w).Encode(map[string]any{"KeyId": req.KeyId, "Signature": der})
+		default:
+			w.WriteHeader(http.StatusBadRequest)
+		}
+	}))
+	return server, crypto.PubkeyToAddress(privKey.PublicKey)
+}
+
+func mustMarshalOID(oid asn1.ObjectIdentifier) []byte {
+	der, _ := asn1.Marshal(oid)
+	return der
+}
+
+func TestKMSSigner(t *testing.T) {
+	t.Setenv("AWS_ACCESS_KEY_ID", "AKIDEXAMPLE")
+	t.Setenv("AWS_SECRET_ACCESS_KEY", "secret")
+
+	for _, highS := range []bool{false, true} {
+		server, expected := fakeKMS(highS)
+		defer server.Close()
+
+		signer, err := newKMSSigner(context.Background(), KMSConfig{
+			KeyID:    "arn:aws:kms:us-east-1:111122223333:key/1234abcd-12ab-34cd-56ef-1234567890ab",
+			Endpoint: server.URL,
+		})
+		if err != nil {
+			t.Fatalf("Failed to create kms signer: %v", err)
+		}
+		if signer.region != "us-east-1" {
+			t.Errorf("Expected region from ARN, got %s", signer.region)
+		}
+		if signer.address != expected {
+			t.Errorf("Expected address %s, got %s", expected, signer.address)
+		}
+
+		// Signed transaction recovers to the KMS key's address
+		chainID := big.NewInt(8453)
+		tx := ethtypes.NewTransaction(0, common.HexToAddress("0x70997970C51812dc3A010C7d01b50e0d17dc79C8"), big.NewInt(0), 21000, big.NewInt(1), nil)
+		signedTx, err := signer.SignTx(context.Background(), tx, chainID)
+		if err != nil {
+			t.Fatalf("Failed to sign transaction (highS=%v): %v", highS, err)
+		}
+		sender, err := ethtypes.Sender(ethtypes.NewEIP2930Signer(chainID), signedTx)
+		if err != nil {
+			t.Fatalf("Failed to recover sender (highS=%v): %v", highS, err)
+		}
+		if sender != expected {
+			t.Errorf("Expected sender %s, got %s", expected, sender)
+		}
+	}
+}
+
+func TestKMSSignerErrors(t *testing.T) {
+	server, _ := fakeKMS(false)
+	defer server.Close()
+
+	// Missing credentials
+	t.Setenv("AWS_ACCESS_KEY_ID", "")
+	t.Setenv("AWS_SECRET_ACCESS_KEY", "")
+	if _, err := newKMSSigner(context.Background(), KMSConfig{KeyID: "alias/x402", Region: "us-east-1", Endpoint: server.URL}); err == nil {
+		t.Error("Expected error for missing credentials")
+	}
+
+	// Missing region
+	t.Setenv("AWS_ACCESS_KEY_ID", "AKIDEXAMPLE")
+	t.Setenv("AWS_SECRET_ACCESS_KEY", "secret")
+	t.Setenv("AWS_REGION", "")
+	if _, err := newKMSSigner(context.Background(), KMSConfig{KeyID: "alias/x402", Endpoint: server.URL}); err == nil {
+		t.Error("Expected error for missing region")
+	}
+
+	// KMS rejects the request
+	_, err := newKMSSigner(context.Background(), KMSConfig{KeyID: "alias/x402", Region: "eu-west-1", Endpoint: server.URL})
+	if err == nil || !strings.Contains(err.Error(), "MissingAuthenticationTokenException") {
+		t.Errorf("Expected KMS error to be reported, got %v", err)
+	}
+}
@@ -0,0 +1,75 @@
+package facilitator
+
+import (
+	"context"
+	"log/slog"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/vorpalengineering/x402-go/types"
+	"github.com/vorpalengineering/x402-go/utils"
+)
+
+type requestIDContextKey struct{}
+
+// withRequestID returns ctx carrying a request ID for log correlation
+func withRequestID(ctx context.Context, requestID string) context.Context {
+	return context.WithValue(ctx, requestIDContextKey{}, requestID)
+}
+
+func requestIDFromContext(ctx context.Context) string {
+	requestID, _ := ctx.Value(requestIDContextKey{}).(string)
+	return requestID
+}
+
+// log returns the facilitator logger with the request ID of ctx attached
+func (f *Facilitator) log(ctx context.Context) *slog.Logger {
+	if requestID := requestIDFromContext(ctx); requestID != "" {
+		return f.logger.With("request_id", requestID)
+	}
+	return f.logger
+}
+
+// requestLogger assigns each request an ID, taken from X-Request-ID when the
+// caller sets one, and logs the request once it completes
+func (f *Facilitator) requestLogger() gin.HandlerFunc {
+	return func(ginCtx *gin.Context) {
+		requestID := ginCtx.GetHeader(utils.RequestIDHeader)
+		if requestID == "" || len(requestID) > 128 {
+			requestID = utils.NewRequestID()
+		}
+		ginCtx.Header(utils.RequestIDHeader, requestID)
+		ginCtx.Request = ginCtx.Request.WithContext(withRequestID(ginCtx.Request.Context(), requestID))
+
+		start := time.Now()
+		ginCtx.Next()
+
+		level := slog.LevelInfo
+		if ginCtx.Writer.Status() >= 500 {
+			level = slog.LevelError
+		} else if path := ginCtx.Request.URL.Path; path == "/healthz" || path == "/readyz" {
+			// Keep frequent probes out of info logs
+			level = slog.LevelDebug
+		}
+		f.logger.Log(ginCtx.Request.Context(), level, "request",
+			"request_id", requestID,
+			"method", ginCtx.Request.Method,
+			"path", ginCtx.Request.URL.Path,
+			"status", ginCtx.Writer.Status(),
+			"duration_ms", time.Since(start).Milliseconds(),
+			"client_ip", ginCtx.ClientIP(),
+		)
+	}
+}
+
+// paymentLogAttrs returns the log fields identifying a payment
+func paymentLogAttrs(payload *types.PaymentPayload, requirements *types.PaymentRequirements) []any {
+	attrs := []any{
+		"scheme", requirements.Scheme,
+		"network", requirements.Network,
+	}
+	if auth, err := utils.ExtractExactAuthorization(payload); err == nil {
+		attrs = append(attrs, "payer", auth.From)
+	}
+	return attrs
+}
@@ -0,0 +1,43 @@
+package facilitator
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/vorpalengineering/x402-go/utils"
+)
+
+func TestRequestID(t *testing.T) {
+	testConfig := &FacilitatorConfig{
+		Networks: map[string]NetworkConfig{
+			"eip155:8453": {
+				RpcUrl: "http://127.0.0.1:1",
+			},
+		},
+		Log: LogConfig{
+			Level: "error",
+		},
+	}
+	f := NewFacilitator(testConfig)
+	defer f.Close()
+
+	// The caller's request ID is echoed back
+	req := httptest.NewRequest("GET", "/supported", nil)
+	req.Header.Set(utils.RequestIDHeader, "req-123")
+	recorder := httptest.NewRecorder()
+	f.router.ServeHTTP(recorder, req)
+	if recorder.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d", recorder.Code)
+	}
+	if got := recorder.Header().Get(utils.RequestIDHeader); got != "req-123" {
+		t.Errorf("Expected request ID req-123, got %q", got)
+	}
+
+	// Otherwise one is generated
+	recorder = httptest.NewRecorder()
+	f.router.ServeHTTP(recorder, httptest.NewRequest("GET", "/supported", nil))
+	if got := recorder.Header().Get(utils.RequestIDHeader); len(got) != 16 {
+		t.Errorf("Expected generated 16 character request ID, got %q", got)
+	}
+}
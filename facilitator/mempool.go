@@ -0,0 +1,130 @@
+package facilitator
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/ethclient"
+	"github.com/vorpalengineering/x402-go/types"
+)
+
+// maxMempoolCheckBlocks caps the recent blocks scanned per settlement
+const maxMempoolCheckBlocks = 32
+
+// MempoolCheckConfig looks for a competing transaction that uses the same
+// EIP-3009 authorization before settling, e.g. from another facilitator the
+// payer sent the payment to. The settlement then fails with a
+// settlement_race code instead of sending a transaction that reverts second.
+type MempoolCheckConfig struct {
+	Enabled bool `yaml:"enabled"`
+	// RecentBlocks also scans this many of the latest blocks, for tokens that
+	// don't report used nonces through authorizationState (max 32)
+	RecentBlocks int `yaml:"recent_blocks"`
+}
+
+func (mempoolCfg MempoolCheckConfig) validate() error {
+	if mempoolCfg.RecentBlocks < 0 || mempoolCfg.RecentBlocks > maxMempoolCheckBlocks {
+		return fmt.Errorf("recent_blocks must be between 0 and %d, got %d", maxMempoolCheckBlocks, mempoolCfg.RecentBlocks)
+	}
+	return nil
+}
+
+// authorizationSelectors are the EIP-3009 functions that consume an
+// authorization. All of them start with the same six static arguments:
+// from, to, value, validAfter, validBefore and nonce.
+var authorizationSelectors = [][]byte{
+	crypto.Keccak256([]byte("transferWithAuthorization(address,address,uint256,uint256,uint256,bytes32,uint8,bytes32,bytes32)"))[:4],
+	crypto.Keccak256([]byte("transferWithAuthorization(address,address,uint256,uint256,uint256,bytes32,bytes)"))[:4],
+	crypto.Keccak256([]byte("receiveWithAuthorization(address,address,uint256,uint256,uint256,bytes32,uint8,bytes32,bytes32)"))[:4],
+	crypto.Keccak256([]byte("receiveWithAuthorization(address,address,uint256,uint256,uint256,bytes32,bytes)"))[:4],
+}
+
+// blockTransactions is the part of an eth_getBlockByNumber result the check
+// reads. Transactions are left undecoded so chain specific types (e.g. OP
+// deposits) don't fail the scan.
+type blockTransactions struct {
+	Transactions []struct {
+		Hash  common.Hash     `json:"hash"`
+		To    *common.Address `json:"to"`
+		Input hexutil.Bytes   `json:"input"`
+	} `json:"transactions"`
+}
+
+// usesAuthorization reports whether calldata sent to the token consumes the
+// authorizer's nonce
+func usesAuthorization(input []byte, authorizer common.Address, nonce common.Hash) bool {
+	if len(input) < 4+6*32 {
+		return false
+	}
+	if !bytes.Equal(input[4+12:4+32], authorizer.Bytes()) || !bytes.Equal(input[4+5*32:4+6*32], nonce.Bytes()) {
+		return false
+	}
+	for _, selector := range authorizationSelectors {
+		if bytes.Equal(input[:4], selector) {
+			return true
+		}
+	}
+	return false
+}
+
+// findAuthorizationTx returns the hash of a transaction in the pending block,
+// or in the latest recentBlocks blocks, that consumes the authorization
+func findAuthorizationTx(ctx context.Context, client *ethclient.Client, token, authorizer common.Address, nonce common.Hash, recentBlocks int) (common.Hash, bool, error) {
+	blocks := []string{"pending"}
+	if recentBlocks > 0 {
+		latest, err := client.BlockNumber(ctx)
+		if err != nil {
+			return common.Hash{}, false, err
+		}
+		for i := 0; i < recentBlocks && uint64(i) <= latest; i++ {
+			blocks = append(blocks, hexutil.EncodeUint64(latest-uint64(i)))
+		}
+	}
+
+	for _, block := range blocks {
+		var result *blockTransactions
+		if err := client.Client().CallContext(ctx, &result, "eth_getBlockByNumber", block, true); err != nil {
+			return common.Hash{}, false, fmt.Errorf("failed to get %s block: %w", block, err)
+		}
+		if result == nil {
+			continue
+		}
+		for _, tx := range result.Transactions {
+			if tx.To != nil && *tx.To == token && usesAuthorization(tx.Input, authorizer, nonce) {
+				return tx.Hash, true, nil
+			}
+		}
+	}
+	return common.Hash{}, false, nil
+}
+
+// checkMempoolConflict fails with SettleErrSettlementRace if a pending or
+// recent transaction already uses the authorization. RPC failures let
+// settlement go ahead.
+func (f *Facilitator) checkMempoolConflict(ctx context.Context, client *ethclient.Client, auth *types.ExactEVMSchemeAuthorization, requirements *types.PaymentRequirements) error {
+	mempoolCfg := f.cfg().Transaction.MempoolCheck
+	if !mempoolCfg.Enabled {
+		return nil
+	}
+
+	token := common.HexToAddress(requirements.Asset)
+	authorizer := common.HexToAddress(auth.From)
+	var nonce common.Hash
+	copy(nonce[:], common.FromHex(auth.Nonce))
+	txHash, found, err := findAuthorizationTx(ctx, client, token, authorizer, nonce, mempoolCfg.RecentBlocks)
+	if err != nil {
+		f.log(ctx).Debug("skipping mempool conflict check", "network", requirements.Network, "error", err)
+		return nil
+	}
+	if found {
+		return &settleError{
+			code: SettleErrSettlementRace,
+			err:  fmt.Errorf("authorization already used by transaction %s", txHash.Hex()),
+		}
+	}
+	return nil
+}
@@ -0,0 +1,138 @@
+package facilitator
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/vorpalengineering/x402-go/types"
+)
+
+func TestSettleMempoolConflict(t *testing.T) {
+	asset := "0x833589fCD6eDb6E08f4c7C32D4f71b54bdA02913"
+	auth := &types.ExactEVMSchemeAuthorization{
+		From:        "0x70997970C51812dc3A010C7d01b50e0d17dc79C8",
+		To:          "0x3C44CdDdB6a900fa2b585dd299e03d12FA4293BC",
+		Value:       "1000000",
+		ValidBefore: 4102444800,
+		Nonce:       "0x" + strings.Repeat("01", 32),
+	}
+
+	// Calldata of a competing transferWithAuthorization(..., bytes signature)
+	competing := func(nonce string) string {
+		input := append([]byte{}, authorizationSelectors[1]...)
+		input = append(input, common.LeftPadBytes(common.HexToAddress(auth.From).Bytes(), 32)...)
+		input = append(input, common.LeftPadBytes(common.HexToAddress(auth.To).Bytes(), 32)...)
+		input = append(input, make([]byte, 3*32)...)
+		input = append(input, common.FromHex(nonce)...)
+		input = append(input, make([]byte, 2*32)...)
+		return hexutil.Encode(input)
+	}
+
+	// RPC with the competing transaction in the pending block or a mined one
+	var mu sync.Mutex
+	blocks := map[string]string{}
+	var sent int
+	rpcServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req struct {
+			ID     json.RawMessage `json:"id"`
+			Method string          `json:"method"`
+			Params []any           `json:"params"`
+		}
+		json.NewDecoder(r.Body).Decode(&req)
+		w.Header().Set("Content-Type", "application/json")
+		mu.Lock()
+		defer mu.Unlock()
+		switch req.Method {
+		case "eth_call":
+			// authorizationState reports the nonce unused
+			fmt.Fprintf(w, `{"jsonrpc":"2.0","id":%s,"result":"0x%064x"}`, req.ID, 0)
+			return
+		case "eth_blockNumber":
+			fmt.Fprintf(w, `{"jsonrpc":"2.0","id":%s,"result":"0x10"}`, req.ID)
+			return
+		case "eth_getBlockByNumber":
+			input, ok := blocks[req.Params[0].(string)]
+			if !ok {
+				fmt.Fprintf(w, `{"jsonrpc":"2.0","id":%s,"result":{"transactions":[]}}`, req.ID)
+				return
+			}
+			fmt.Fprintf(w, `{"jsonrpc":"2.0","id":%s,"result":{"transactions":[{"hash":"0x%064x","to":"%s","input":"%s","type":"0x7e"}]}}`, req.ID, 0xbeef, asset, input)
+			return
+		case "eth_sendRawTransaction":
+			sent++
+		}
+		fmt.Fprintf(w, `{"jsonrpc":"2.0","id":%s,"result":"0x1"}`, req.ID)
+	}))
+	defer rpcServer.Close()
+
+	key, _ := crypto.HexToECDSA("ac0974bec39a17e36ba4a6b4d238ff944bacb478cbed5efcae784d7bf4f2ff80")
+	f := NewFacilitator(&FacilitatorConfig{
+		Networks: map[string]NetworkConfig{
+			"eip155:8453": {RpcUrl: rpcServer.URL},
+		},
+		Transaction: TransactionConfig{
+			TimeoutSeconds: 5,
+			MaxGasPrice:    "100000000000",
+			MempoolCheck:   MempoolCheckConfig{Enabled: true},
+		},
+		Log:    LogConfig{Level: "error"},
+		Signer: SignerConfig{PrivateKey: key},
+	})
+	defer f.Close()
+
+	requirements := &types.PaymentRequirements{
+		Scheme:  "exact",
+		Network: "eip155:8453",
+		Amount:  "1000000",
+		PayTo:   auth.To,
+		Asset:   asset,
+	}
+	payload := &types.PaymentPayload{
+		Payload: map[string]any{
+			"signature":     "0x" + strings.Repeat("ab", 64) + "1b",
+			"authorization": auth,
+		},
+	}
+
+	// A pending transaction with the same nonce fails the settlement unsent
+	blocks["pending"] = competing(auth.Nonce)
+	resp := f.settleExactScheme(context.Background(), payload, requirements)
+	if resp.Success || resp.ErrorReason != SettleErrSettlementRace {
+		t.Errorf("Expected %s, got %+v", SettleErrSettlementRace, resp)
+	}
+	if !strings.Contains(resp.ErrorMessage, fmt.Sprintf("0x%064x", 0xbeef)) {
+		t.Errorf("Expected the competing transaction in the message, got %q", resp.ErrorMessage)
+	}
+	if sent != 0 {
+		t.Errorf("Expected no transaction to be sent, got %d", sent)
+	}
+
+	// Another nonce of the same payer is no conflict
+	client, _ := f.getRPCClient("eip155:8453")
+	blocks["pending"] = competing("0x" + strings.Repeat("02", 32))
+	if err := f.checkMempoolConflict(context.Background(), client, auth, requirements); err != nil {
+		t.Errorf("Expected no conflict for another nonce, got %v", err)
+	}
+
+	// Recent blocks are only scanned when configured
+	delete(blocks, "pending")
+	blocks["0xf"] = competing(auth.Nonce)
+	if err := f.checkMempoolConflict(context.Background(), client, auth, requirements); err != nil {
+		t.Errorf("Expected mined blocks to be skipped by default, got %v", err)
+	}
+	cfg := *f.cfg()
+	cfg.Transaction.MempoolCheck.RecentBlocks = 2
+	f.config.Store(&cfg)
+	if err := f.checkMempoolConflict(context.Background(), client, auth, requirements); err == nil {
+		t.Error("Expected a conflict in a recent block")
+	}
+}
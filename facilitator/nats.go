@@ -0,0 +1,235 @@
+package facilitator
+
+import (
+	"bufio"
+	"context"
+	"crypto/tls"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/vorpalengineering/x402-go/version"
+)
+
+const defaultNATSTimeout = 5 * time.Second
+
+// NATSConfig publishes events to a NATS server. Empty addr leaves publishing
+// to SetEventPublisher.
+type NATSConfig struct {
+	// Addr is the host:port of the NATS server
+	Addr     string `yaml:"addr"`
+	Username string `yaml:"username"`
+	Password string `yaml:"password"`
+	Token    string `yaml:"token"`
+	// TLS upgrades the connection, as it is also when the server requires it
+	TLS bool `yaml:"tls"`
+	// TimeoutSeconds bounds connecting and each publish (default 5)
+	TimeoutSeconds int `yaml:"timeout_seconds"`
+}
+
+func (natsCfg NATSConfig) enabled() bool {
+	return natsCfg.Addr != ""
+}
+
+func (natsCfg NATSConfig) validate() error {
+	if !natsCfg.enabled() {
+		return nil
+	}
+	if _, _, err := net.SplitHostPort(natsCfg.Addr); err != nil {
+		return fmt.Errorf("addr must be host:port: %w", err)
+	}
+	if natsCfg.TimeoutSeconds < 0 {
+		return fmt.Errorf("timeout_seconds cannot be negative")
+	}
+	if natsCfg.Token != "" && natsCfg.Username != "" {
+		return fmt.Errorf("set either token or username and password")
+	}
+	return nil
+}
+
+func (natsCfg NATSConfig) timeout() time.Duration {
+	if natsCfg.TimeoutSeconds > 0 {
+		return time.Duration(natsCfg.TimeoutSeconds) * time.Second
+	}
+	return defaultNATSTimeout
+}
+
+// natsInfo is the part of the server's INFO message the publisher reads
+type natsInfo struct {
+	TLSRequired bool `json:"tls_required"`
+	MaxPayload  int  `json:"max_payload"`
+}
+
+// NATSPublisher is an EventPublisher speaking the NATS client protocol over
+// one connection, dialed on first use and again after an error. Each publish
+// is followed by a PING, so it returns once the server has the message.
+type NATSPublisher struct {
+	cfg NATSConfig
+
+	mu         sync.Mutex
+	conn       net.Conn
+	r          *bufio.Reader
+	w          *bufio.Writer
+	maxPayload int
+}
+
+func NewNATSPublisher(cfg NATSConfig) *NATSPublisher {
+	return &NATSPublisher{cfg: cfg}
+}
+
+// Publish sends data to subject. NATS has no message keys, so key is ignored.
+func (p *NATSPublisher) Publish(ctx context.Context, subject string, key, data []byte) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.conn == nil {
+		if err := p.connect(ctx); err != nil {
+			return fmt.Errorf("nats connect: %w", err)
+		}
+	}
+	if p.maxPayload > 0 && len(data) > p.maxPayload {
+		return fmt.Errorf("nats: event of %d bytes exceeds max payload %d", len(data), p.maxPayload)
+	}
+
+	p.setDeadline(ctx)
+	fmt.Fprintf(p.w, "PUB %s %d\r\n", subject, len(data))
+	p.w.Write(data)
+	p.w.WriteString("\r\nPING\r\n")
+	err := p.w.Flush()
+	if err == nil {
+		err = p.awaitPong()
+	}
+	if err != nil {
+		// The connection state is unknown after an error
+		p.closeConn()
+		return fmt.Errorf("nats publish: %w", err)
+	}
+	return nil
+}
+
+// connect dials the server, reads its INFO, upgrades to TLS when asked to
+// and sends CONNECT. Callers must hold mu.
+func (p *NATSPublisher) connect(ctx context.Context) error {
+	dialer := &net.Dialer{Timeout: p.cfg.timeout()}
+	conn, err := dialer.DialContext(ctx, "tcp", p.cfg.Addr)
+	if err != nil {
+		return err
+	}
+	p.conn, p.r, p.w = conn, bufio.NewReader(conn), bufio.NewWriter(conn)
+	p.setDeadline(ctx)
+
+	// The server introduces itself first
+	line, err := p.readLine()
+	if err != nil {
+		p.closeConn()
+		return err
+	}
+	infoJSON, ok := strings.CutPrefix(line, "INFO ")
+	if !ok {
+		p.closeConn()
+		return fmt.Errorf("expected INFO, got %q", line)
+	}
+	var info natsInfo
+	if err := json.Unmarshal([]byte(infoJSON), &info); err != nil {
+		p.closeConn()
+		return fmt.Errorf("invalid INFO: %w", err)
+	}
+	p.maxPayload = info.MaxPayload
+
+	if p.cfg.TLS || info.TLSRequired {
+		host, _, _ := net.SplitHostPort(p.cfg.Addr)
+		tlsConn := tls.Client(conn, &tls.Config{ServerName: host})
+		if err := tlsConn.HandshakeContext(ctx); err != nil {
+			p.closeConn()
+			return fmt.Errorf("tls handshake: %w", err)
+		}
+		p.conn, p.r, p.w = tlsConn, bufio.NewReader(tlsConn), bufio.NewWriter(tlsConn)
+	}
+
+	// Authenticate, and PING to learn whether the server accepted us
+	connect := map[string]any{
+		"verbose":  false,
+		"pedantic": false,
+		"name":     "x402-facilitator",
+		"lang":     "go",
+		"version":  version.Get().Version,
+		"protocol": 1,
+	}
+	if p.cfg.Token != "" {
+		connect["auth_token"] = p.cfg.Token
+	}
+	if p.cfg.Username != "" {
+		connect["user"] = p.cfg.Username
+		connect["pass"] = p.cfg.Password
+	}
+	options, _ := json.Marshal(connect)
+	fmt.Fprintf(p.w, "CONNECT %s\r\nPING\r\n", options)
+	err = p.w.Flush()
+	if err == nil {
+		err = p.awaitPong()
+	}
+	if err != nil {
+		p.closeConn()
+		return err
+	}
+	return nil
+}
+
+// awaitPong reads until the reply to our PING, answering the server's own
+// PINGs. Callers must hold mu.
+func (p *NATSPublisher) awaitPong() error {
+	for {
+		line, err := p.readLine()
+		if err != nil {
+			return err
+		}
+		switch {
+		case line == "PONG":
+			return nil
+		case line == "PING":
+			p.w.WriteString("PONG\r\n")
+			if err := p.w.Flush(); err != nil {
+				return err
+			}
+		case strings.HasPrefix(line, "-ERR"):
+			return errors.New(strings.Trim(strings.TrimPrefix(line, "-ERR"), " '"))
+		}
+		// +OK and INFO updates need no answer
+	}
+}
+
+func (p *NATSPublisher) readLine() (string, error) {
+	line, err := p.r.ReadString('\n')
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimSuffix(line, "\r\n"), nil
+}
+
+func (p *NATSPublisher) setDeadline(ctx context.Context) {
+	deadline := time.Now().Add(p.cfg.timeout())
+	if ctxDeadline, ok := ctx.Deadline(); ok && ctxDeadline.Before(deadline) {
+		deadline = ctxDeadline
+	}
+	p.conn.SetDeadline(deadline)
+}
+
+// closeConn drops the connection, the next publish dials again. Callers must hold mu.
+func (p *NATSPublisher) closeConn() {
+	if p.conn != nil {
+		p.conn.Close()
+		p.conn = nil
+	}
+}
+
+// Close closes the connection to the server
+func (p *NATSPublisher) Close() error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.closeConn()
+	return nil
+}
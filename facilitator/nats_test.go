@@ -0,0 +1,134 @@
+package facilitator
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"strings"
+	"testing"
+)
+
+// fakeNATSServer accepts one connection, checks the token and records
+// published messages
+type fakeNATSServer struct {
+	listener net.Listener
+	connects chan map[string]any
+	messages chan [2]string
+}
+
+func newFakeNATSServer(t *testing.T, token string) *fakeNATSServer {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Failed to listen: %v", err)
+	}
+	s := &fakeNATSServer{listener: listener, connects: make(chan map[string]any, 4), messages: make(chan [2]string, 16)}
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			go s.serve(conn, token)
+		}
+	}()
+	return s
+}
+
+func (s *fakeNATSServer) serve(conn net.Conn, token string) {
+	defer conn.Close()
+	r := bufio.NewReader(conn)
+	fmt.Fprint(conn, "INFO {\"server_id\":\"test\",\"max_payload\":1048576}\r\n")
+	for {
+		line, err := r.ReadString('\n')
+		if err != nil {
+			return
+		}
+		line = strings.TrimSuffix(line, "\r\n")
+		switch {
+		case strings.HasPrefix(line, "CONNECT "):
+			var options map[string]any
+			json.Unmarshal([]byte(strings.TrimPrefix(line, "CONNECT ")), &options)
+			s.connects <- options
+			if token != "" && options["auth_token"] != token {
+				fmt.Fprint(conn, "-ERR 'Authorization Violation'\r\n")
+				return
+			}
+		case strings.HasPrefix(line, "PUB "):
+			var subject string
+			var size int
+			fmt.Sscanf(line, "PUB %s %d", &subject, &size)
+			payload := make([]byte, size+2)
+			if _, err := io.ReadFull(r, payload); err != nil {
+				return
+			}
+			s.messages <- [2]string{subject, string(payload[:size])}
+		case line == "PING":
+			fmt.Fprint(conn, "PONG\r\n")
+		}
+	}
+}
+
+func TestNATSPublisher(t *testing.T) {
+	server := newFakeNATSServer(t, "s3cret")
+	defer server.listener.Close()
+
+	publisher := NewNATSPublisher(NATSConfig{Addr: server.listener.Addr().String(), Token: "s3cret"})
+	defer publisher.Close()
+
+	for i := range 2 {
+		if err := publisher.Publish(context.Background(), "x402.settle", []byte("0xpayer"), []byte(fmt.Sprintf(`{"n":%d}`, i))); err != nil {
+			t.Fatalf("Failed to publish: %v", err)
+		}
+	}
+
+	// One connection carries every message
+	if len(server.connects) != 1 {
+		t.Errorf("Expected a single connection, got %d", len(server.connects))
+	}
+	for i := range 2 {
+		message := <-server.messages
+		if message[0] != "x402.settle" || message[1] != fmt.Sprintf(`{"n":%d}`, i) {
+			t.Errorf("Unexpected message %v", message)
+		}
+	}
+}
+
+func TestNATSPublisherAuthError(t *testing.T) {
+	server := newFakeNATSServer(t, "s3cret")
+	defer server.listener.Close()
+
+	publisher := NewNATSPublisher(NATSConfig{Addr: server.listener.Addr().String(), Token: "wrong"})
+	defer publisher.Close()
+
+	err := publisher.Publish(context.Background(), "x402.settle", nil, []byte("{}"))
+	if err == nil || !strings.Contains(err.Error(), "Authorization Violation") {
+		t.Errorf("Expected authorization error, got %v", err)
+	}
+}
+
+func TestEventsPublishedToConfiguredNATS(t *testing.T) {
+	server := newFakeNATSServer(t, "")
+	defer server.listener.Close()
+
+	f := NewFacilitator(&FacilitatorConfig{
+		Log: LogConfig{Level: "error"},
+		Events: EventsConfig{
+			SubjectPrefix: "payments",
+			NATS:          NATSConfig{Addr: server.listener.Addr().String()},
+		},
+	})
+	f.publishEvent(Event{Type: EventSettle, Payer: "0xpayer"})
+
+	// Close flushes buffered events
+	f.Close()
+
+	message := <-server.messages
+	var event Event
+	json.Unmarshal([]byte(message[1]), &event)
+	if message[0] != "payments.settle" || event.Type != EventSettle || event.Payer != "0xpayer" {
+		t.Errorf("Expected settle event on payments.settle, got %v", message)
+	}
+}
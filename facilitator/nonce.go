@@ -0,0 +1,232 @@
+package facilitator
+
+import (
+	"context"
+	"slices"
+	"strings"
+	"sync"
+
+	"github.com/ethereum/go-ethereum/common"
+)
+
+// nonceSource reads the next nonce of an account from the chain
+type nonceSource interface {
+	PendingNonceAt(ctx context.Context, account common.Address) (uint64, error)
+}
+
+// sharedNonces hands out the nonces of signers shared by facilitator
+// replicas, keeping the counter and released nonces outside the process
+type sharedNonces interface {
+	reserveNonce(ctx context.Context, network string, address common.Address, pending uint64) (uint64, error)
+	releaseNonce(ctx context.Context, network string, address common.Address, nonce uint64) error
+	resyncNonce(ctx context.Context, network string, address common.Address, pending uint64) error
+}
+
+type nonceKey struct {
+	network string
+	address common.Address
+}
+
+// nonceAccount is the nonce state of one signer on one network
+type nonceAccount struct {
+	mu       sync.Mutex
+	synced   bool
+	next     uint64
+	inFlight map[uint64]bool
+	// released nonces below next that were reserved but never sent
+	released []uint64
+}
+
+// nonceManager hands out transaction nonces so concurrent settlements from the
+// same signer don't collide. Nonces are reserved locally while transactions are
+// being built, reused if a transaction is never sent, and resynced with the
+// chain whenever an account is idle or the node rejects a nonce. With shared
+// state, the counter is kept there and the local accounts only track this
+// replica's nonces in flight.
+type nonceManager struct {
+	mu       sync.Mutex
+	accounts map[nonceKey]*nonceAccount
+	shared   sharedNonces
+}
+
+func newNonceManager() *nonceManager {
+	return &nonceManager{accounts: make(map[nonceKey]*nonceAccount)}
+}
+
+func (m *nonceManager) account(network string, address common.Address) *nonceAccount {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	key := nonceKey{network: network, address: address}
+	account, ok := m.accounts[key]
+	if !ok {
+		account = &nonceAccount{inFlight: make(map[uint64]bool)}
+		m.accounts[key] = account
+	}
+	return account
+}
+
+// reserve returns the next nonce to use. Every reserved nonce must be passed
+// to sent or release.
+func (m *nonceManager) reserve(ctx context.Context, source nonceSource, network string, address common.Address) (uint64, error) {
+	account := m.account(network, address)
+	account.mu.Lock()
+	defer account.mu.Unlock()
+
+	// Other replicas send from the same account, so every reservation moves
+	// the shared counter up to the chain's pending nonce
+	if m.shared != nil {
+		pending, err := source.PendingNonceAt(ctx, address)
+		if err != nil {
+			return 0, err
+		}
+		nonce, err := m.shared.reserveNonce(ctx, network, address, pending)
+		if err != nil {
+			return 0, err
+		}
+		account.inFlight[nonce] = true
+		account.synced = true
+		account.next = max(account.next, nonce+1)
+		return nonce, nil
+	}
+
+	// Fill gaps left by transactions that were never sent
+	if len(account.released) > 0 {
+		nonce := account.released[0]
+		account.released = account.released[1:]
+		account.inFlight[nonce] = true
+		return nonce, nil
+	}
+
+	// Catch up with the chain while nothing is in flight, in case other
+	// processes sent transactions from the same account
+	if len(account.inFlight) == 0 {
+		pending, err := source.PendingNonceAt(ctx, address)
+		if err != nil {
+			return 0, err
+		}
+		if !account.synced || pending > account.next {
+			account.next = pending
+			account.synced = true
+		}
+	}
+
+	nonce := account.next
+	account.next++
+	account.inFlight[nonce] = true
+	return nonce, nil
+}
+
+// sent marks a nonce as used by a broadcast transaction
+func (m *nonceManager) sent(network string, address common.Address, nonce uint64) {
+	account := m.account(network, address)
+	account.mu.Lock()
+	defer account.mu.Unlock()
+
+	delete(account.inFlight, nonce)
+}
+
+// release returns a nonce whose transaction was never broadcast. It fails
+// only when the shared state can't be reached, leaving a gap other
+// transactions of the account wait behind.
+func (m *nonceManager) release(network string, address common.Address, nonce uint64) error {
+	account := m.account(network, address)
+	account.mu.Lock()
+	defer account.mu.Unlock()
+
+	if !account.inFlight[nonce] {
+		return nil
+	}
+	delete(account.inFlight, nonce)
+	if m.shared != nil {
+		return m.shared.releaseNonce(context.Background(), network, address, nonce)
+	}
+	account.released = append(account.released, nonce)
+	slices.Sort(account.released)
+
+	// Shrink the counter rather than leave released nonces at the top
+	for len(account.released) > 0 && account.released[len(account.released)-1] == account.next-1 {
+		account.released = account.released[:len(account.released)-1]
+		account.next--
+	}
+	return nil
+}
+
+// resync discards a nonce the node rejected and moves the counter up to the
+// chain's pending nonce
+func (m *nonceManager) resync(ctx context.Context, source nonceSource, network string, address common.Address, nonce uint64) error {
+	account := m.account(network, address)
+	account.mu.Lock()
+	defer account.mu.Unlock()
+
+	delete(account.inFlight, nonce)
+	pending, err := source.PendingNonceAt(ctx, address)
+	if err != nil {
+		account.synced = false
+		return err
+	}
+	if pending > account.next {
+		account.next = pending
+	}
+	if m.shared != nil {
+		return m.shared.resyncNonce(ctx, network, address, pending)
+	}
+
+	// Drop released nonces the chain has already used
+	account.released = slices.DeleteFunc(account.released, func(released uint64) bool {
+		return released < pending
+	})
+	return nil
+}
+
+// NonceState is the nonce manager's view of one signer on one network
+type NonceState struct {
+	Network  string   `json:"network"`
+	Address  string   `json:"address"`
+	Next     uint64   `json:"next"`
+	InFlight []uint64 `json:"inFlight"`
+	Released []uint64 `json:"released"`
+}
+
+// snapshot returns the state of every account
+func (m *nonceManager) snapshot() []NonceState {
+	m.mu.Lock()
+	keys := make([]nonceKey, 0, len(m.accounts))
+	accounts := make([]*nonceAccount, 0, len(m.accounts))
+	for key, account := range m.accounts {
+		keys = append(keys, key)
+		accounts = append(accounts, account)
+	}
+	m.mu.Unlock()
+
+	states := make([]NonceState, 0, len(keys))
+	for i, account := range accounts {
+		account.mu.Lock()
+		state := NonceState{
+			Network:  keys[i].network,
+			Address:  keys[i].address.Hex(),
+			Next:     account.next,
+			InFlight: make([]uint64, 0, len(account.inFlight)),
+			Released: append([]uint64{}, account.released...),
+		}
+		for nonce := range account.inFlight {
+			state.InFlight = append(state.InFlight, nonce)
+		}
+		account.mu.Unlock()
+		slices.Sort(state.InFlight)
+		states = append(states, state)
+	}
+	slices.SortFunc(states, func(a, b NonceState) int {
+		return strings.Compare(a.Network+a.Address, b.Network+b.Address)
+	})
+	return states
+}
+
+// isNonceError reports whether a node rejected a transaction because its
+// nonce was already used
+func isNonceError(err error) bool {
+	msg := strings.ToLower(err.Error())
+	return strings.Contains(msg, "nonce too low") ||
+		strings.Contains(msg, "replacement transaction underpriced") ||
+		strings.Contains(msg, "invalid nonce")
+}
@@ -0,0 +1,131 @@
+package facilitator
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+)
+
+type fakeNonceSource struct {
+	pending atomic.Uint64
+	calls   atomic.Int64
+}
+
+func (s *fakeNonceSource) PendingNonceAt(ctx context.Context, account common.Address) (uint64, error) {
+	s.calls.Add(1)
+	return s.pending.Load(), nil
+}
+
+func TestNonceManagerConcurrent(t *testing.T) {
+	m := newNonceManager()
+	source := &fakeNonceSource{}
+	source.pending.Store(7)
+	address := common.HexToAddress("0x70997970C51812dc3A010C7d01b50e0d17dc79C8")
+
+	// Hold one reservation so the rest are handed out locally
+	first, _ := m.reserve(context.Background(), source, "eip155:8453", address)
+	if first != 7 {
+		t.Fatalf("Expected first nonce 7, got %d", first)
+	}
+
+	var mu sync.Mutex
+	seen := map[uint64]bool{first: true}
+	var wg sync.WaitGroup
+	for i := 0; i < 50; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			nonce, err := m.reserve(context.Background(), source, "eip155:8453", address)
+			if err != nil {
+				t.Errorf("Failed to reserve nonce: %v", err)
+				return
+			}
+			mu.Lock()
+			defer mu.Unlock()
+			if seen[nonce] {
+				t.Errorf("Nonce %d reserved twice", nonce)
+			}
+			seen[nonce] = true
+		}()
+	}
+	wg.Wait()
+	if len(seen) != 51 || !seen[57] {
+		t.Errorf("Expected nonces 7-57, got %d distinct", len(seen))
+	}
+	if source.calls.Load() != 1 {
+		t.Errorf("Expected a single chain lookup, got %d", source.calls.Load())
+	}
+
+	// Other networks are tracked separately
+	if nonce, _ := m.reserve(context.Background(), source, "eip155:1", address); nonce != 7 {
+		t.Errorf("Expected nonce 7 on another network, got %d", nonce)
+	}
+}
+
+func TestNonceManagerRelease(t *testing.T) {
+	m := newNonceManager()
+	source := &fakeNonceSource{}
+	source.pending.Store(10)
+	address := common.HexToAddress("0x70997970C51812dc3A010C7d01b50e0d17dc79C8")
+	ctx := context.Background()
+
+	n10, _ := m.reserve(ctx, source, "eip155:8453", address)
+	n11, _ := m.reserve(ctx, source, "eip155:8453", address)
+	n12, _ := m.reserve(ctx, source, "eip155:8453", address)
+
+	// An unsent nonce in the middle is reused before new ones
+	m.sent("eip155:8453", address, n10)
+	m.release("eip155:8453", address, n11)
+	if nonce, _ := m.reserve(ctx, source, "eip155:8453", address); nonce != n11 {
+		t.Errorf("Expected released nonce %d to be reused, got %d", n11, nonce)
+	}
+
+	// Releasing the highest nonce shrinks the counter
+	m.release("eip155:8453", address, n12)
+	if nonce, _ := m.reserve(ctx, source, "eip155:8453", address); nonce != n12 {
+		t.Errorf("Expected nonce %d after releasing the top, got %d", n12, nonce)
+	}
+
+	state := m.snapshot()
+	if len(state) != 1 || state[0].Next != 13 || len(state[0].InFlight) != 2 {
+		t.Errorf("Unexpected nonce state: %+v", state)
+	}
+}
+
+func TestNonceManagerResync(t *testing.T) {
+	m := newNonceManager()
+	source := &fakeNonceSource{}
+	source.pending.Store(3)
+	address := common.HexToAddress("0x70997970C51812dc3A010C7d01b50e0d17dc79C8")
+	ctx := context.Background()
+
+	// Idle accounts pick up transactions sent by someone else
+	n3, _ := m.reserve(ctx, source, "eip155:8453", address)
+	m.sent("eip155:8453", address, n3)
+	source.pending.Store(8)
+	if nonce, _ := m.reserve(ctx, source, "eip155:8453", address); nonce != 8 {
+		t.Errorf("Expected nonce 8 after external transactions, got %d", nonce)
+	}
+
+	// A rejected nonce moves the counter to the chain
+	source.pending.Store(12)
+	if err := m.resync(ctx, source, "eip155:8453", address, 8); err != nil {
+		t.Fatalf("Failed to resync: %v", err)
+	}
+	if nonce, _ := m.reserve(ctx, source, "eip155:8453", address); nonce != 12 {
+		t.Errorf("Expected nonce 12 after resync, got %d", nonce)
+	}
+}
+
+func TestIsNonceError(t *testing.T) {
+	if !isNonceError(errors.New("nonce too low: next nonce 5, tx nonce 4")) {
+		t.Error("Expected nonce too low to be a nonce error")
+	}
+	if isNonceError(errors.New("insufficient funds for gas * price + value")) {
+		t.Error("Expected insufficient funds not to be a nonce error")
+	}
+}
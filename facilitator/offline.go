@@ -0,0 +1,74 @@
+package facilitator
+
+import (
+	"fmt"
+	"math/big"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/vorpalengineering/x402-go/types"
+	"github.com/vorpalengineering/x402-go/utils"
+)
+
+// VerifyOffline runs the exact EVM checks that need no RPC connection, for
+// resource servers verifying payments in process: the payer's EOA signature,
+// amount, time window and recipient, stopping at the first failure. The
+// token's EIP-712 name and version must be in requirements.Extra. Balance,
+// facilitator fees, contract wallet signatures and simulation are left to
+// the facilitator settling the payment.
+func VerifyOffline(payload *types.PaymentPayload, requirements *types.PaymentRequirements, now time.Time) []types.VerifyFailure {
+	if payload.Accepted.Scheme != "exact" || utils.IsSolanaNetwork(requirements.Network) {
+		return []types.VerifyFailure{{
+			Check:  VerifyCheckPayload,
+			Code:   types.ErrCodeUnsupportedScheme,
+			Reason: fmt.Sprintf("offline verification is not supported for %s on %s", payload.Accepted.Scheme, requirements.Network),
+		}}
+	}
+
+	// Extract authorization from payload
+	auth, err := utils.ExtractExactAuthorization(payload)
+	if err != nil {
+		return []types.VerifyFailure{{Check: VerifyCheckPayload, Code: types.ErrCodeInvalidPayload, Reason: fmt.Sprintf("invalid authorization: %v", err)}}
+	}
+
+	checks := []struct {
+		name string
+		code string
+		run  func() (bool, string)
+	}{
+		{VerifyCheckSignature, types.ErrCodeInvalidSignature, func() (bool, string) {
+			hash, signature, reason := authorizationHash(auth, payload, requirements)
+			if reason != "" {
+				return false, reason
+			}
+			reason = recoverSignature(hash, signature, common.HexToAddress(auth.From))
+			return reason == "", reason
+		}},
+		{VerifyCheckAmount, types.ErrCodeInvalidAmount, func() (bool, string) { return checkAmount(auth, requirements) }},
+		{VerifyCheckTimeWindow, timeWindowCodeAt(auth.ValidAfter, now), func() (bool, string) { return checkTimeWindow(auth, now) }},
+		{VerifyCheckParameters, types.ErrCodeRecipientMismatch, func() (bool, string) { return checkParameters(auth, requirements) }},
+	}
+	for _, check := range checks {
+		if valid, reason := check.run(); !valid {
+			return []types.VerifyFailure{{Check: check.name, Code: check.code, Reason: reason}}
+		}
+	}
+
+	return nil
+}
+
+// checkAmount checks auth pays at least the required amount
+func checkAmount(auth *types.ExactEVMSchemeAuthorization, requirements *types.PaymentRequirements) (bool, string) {
+	paymentAmount, ok := new(big.Int).SetString(auth.Value, 10)
+	if !ok {
+		return false, "invalid payment amount format"
+	}
+	requiredAmount, ok := new(big.Int).SetString(requirements.Amount, 10)
+	if !ok {
+		return false, "invalid required amount format"
+	}
+	if paymentAmount.Cmp(requiredAmount) < 0 {
+		return false, fmt.Sprintf("insufficient amount: got %s, required %s", paymentAmount, requiredAmount)
+	}
+	return true, ""
+}
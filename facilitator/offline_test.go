@@ -0,0 +1,92 @@
+package facilitator
+
+import (
+	"bytes"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/vorpalengineering/x402-go/types"
+	"github.com/vorpalengineering/x402-go/utils"
+)
+
+func TestVerifyOffline(t *testing.T) {
+	payerKey, _ := crypto.HexToECDSA("59c6995e998f97a5a0044966f0945389dc9e86dae88c7a8412f4603b6b78690d")
+	now := time.Unix(1700000000, 0)
+	requirements := types.PaymentRequirements{
+		Scheme:  "exact",
+		Network: "eip155:8453",
+		Amount:  "1000",
+		Asset:   "0x833589fCD6eDb6E08f4c7C32D4f71b54bdA02913",
+		PayTo:   "0x3C44CdDdB6a900fa2b585dd299e03d12FA4293BC",
+		Extra:   map[string]any{"name": "USD Coin", "version": "2"},
+	}
+	payment := func(change func(auth *types.ExactEVMSchemeAuthorization)) *types.PaymentPayload {
+		auth := types.ExactEVMSchemeAuthorization{
+			From:        "0x70997970C51812dc3A010C7d01b50e0d17dc79C8",
+			To:          requirements.PayTo,
+			Value:       "1000",
+			ValidBefore: now.Add(time.Minute).Unix(),
+			Nonce:       hexutil.Encode(bytes.Repeat([]byte{1}, 32)),
+		}
+		signature, _ := utils.SignEIP3009(&auth, payerKey, requirements.Asset, "USD Coin", "2", 8453)
+		if change != nil {
+			change(&auth)
+		}
+		return &types.PaymentPayload{
+			X402Version: 2,
+			Accepted:    requirements,
+			Payload:     map[string]any{"signature": signature, "authorization": auth},
+		}
+	}
+
+	tests := []struct {
+		name          string
+		payload       *types.PaymentPayload
+		expectedCheck string
+		expectedCode  string
+	}{
+		{"valid", payment(nil), "", ""},
+		{"other signer", payment(func(auth *types.ExactEVMSchemeAuthorization) {
+			auth.From = "0x90F79bf6EB2c4f870365E785982E1f101E93b906"
+		}), VerifyCheckSignature, types.ErrCodeInvalidSignature},
+		{"tampered value", payment(func(auth *types.ExactEVMSchemeAuthorization) {
+			auth.Value = "999"
+		}), VerifyCheckSignature, types.ErrCodeInvalidSignature},
+		{"not yet valid", payment(func(auth *types.ExactEVMSchemeAuthorization) {
+			auth.ValidAfter = now.Add(time.Minute).Unix()
+		}), VerifyCheckSignature, types.ErrCodeInvalidSignature},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			failures := VerifyOffline(tt.payload, &requirements, now)
+			if tt.expectedCheck == "" {
+				if len(failures) != 0 {
+					t.Errorf("Expected valid payment, got %v", failures)
+				}
+				return
+			}
+			if len(failures) != 1 || failures[0].Check != tt.expectedCheck || failures[0].Code != tt.expectedCode {
+				t.Errorf("Expected %s failure %s, got %v", tt.expectedCheck, tt.expectedCode, failures)
+			}
+		})
+	}
+
+	// Checks after the signature see the signed authorization
+	if failures := VerifyOffline(payment(nil), &requirements, now.Add(time.Hour)); len(failures) != 1 || failures[0].Code != types.ErrCodeExpired {
+		t.Errorf("Expected expired payment, got %v", failures)
+	}
+	pricier := requirements
+	pricier.Amount = "2000"
+	if failures := VerifyOffline(payment(nil), &pricier, now); len(failures) != 1 || failures[0].Check != VerifyCheckAmount {
+		t.Errorf("Expected insufficient amount, got %v", failures)
+	}
+
+	// Other schemes need a facilitator
+	upto := *payment(nil)
+	upto.Accepted.Scheme = "upto"
+	if failures := VerifyOffline(&upto, &requirements, now); len(failures) != 1 || failures[0].Code != types.ErrCodeUnsupportedScheme {
+		t.Errorf("Expected unsupported scheme, got %v", failures)
+	}
+}
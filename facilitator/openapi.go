@@ -0,0 +1,417 @@
+package facilitator
+
+import (
+	"maps"
+	"net/http"
+	"reflect"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/vorpalengineering/x402-go/types"
+	"github.com/vorpalengineering/x402-go/version"
+)
+
+// openAPISpecVersion is the OpenAPI version of the served document
+const openAPISpecVersion = "3.0.3"
+
+// errorBody is the body of a request rejected before it is handled. Code is
+// set when the rejection has an error code.
+type errorBody struct {
+	Error string `json:"error"`
+	Code  string `json:"code,omitempty"`
+}
+
+// errorCodeFields are the JSON fields holding one of the types.ErrCode
+// constants, documented with the ErrorCode enum
+var errorCodeFields = map[string]bool{
+	"invalidReason": true,
+	"errorReason":   true,
+	"errorCode":     true,
+	"code":          true,
+}
+
+// openAPIParam is a query or path parameter of an operation
+type openAPIParam struct {
+	name     string
+	in       string
+	required bool
+	enum     []string
+	doc      string
+}
+
+// openAPIOperation documents a route. Bodies are zero values of the Go types
+// the handler decodes and encodes, nil when the route takes no body.
+type openAPIOperation struct {
+	method    string
+	path      string
+	id        string
+	summary   string
+	params    []openAPIParam
+	request   any
+	responses map[int]any
+}
+
+// openAPIOperations lists the routes registered by registerRoutes. Admin and
+// metrics routes are left out as they are for operators, not clients.
+func (f *Facilitator) openAPIOperations() []openAPIOperation {
+	paymentRejected := map[int]any{http.StatusBadRequest: errorBody{}}
+	if f.quotas != nil {
+		paymentRejected[http.StatusTooManyRequests] = errorBody{}
+	}
+	withRejections := func(responses map[int]any) map[int]any {
+		merged := maps.Clone(paymentRejected)
+		maps.Copy(merged, responses)
+		return merged
+	}
+
+	ops := []openAPIOperation{
+		{
+			method:  http.MethodPost,
+			path:    "/verify",
+			id:      "verify",
+			summary: "Verify a payment payload against requirements",
+			params: []openAPIParam{
+				{name: "report", in: "query", enum: []string{"full"}, doc: "Run every check and list all failures"},
+			},
+			request:   types.VerifyRequest{},
+			responses: withRejections(map[int]any{http.StatusOK: types.VerifyResponse{}}),
+		},
+		{
+			method:  http.MethodPost,
+			path:    "/settle",
+			id:      "settle",
+			summary: "Settle a payment on-chain",
+			params: []openAPIParam{
+				{name: "async", in: "query", enum: []string{"true"}, doc: "Queue the settlement and return a job to poll"},
+			},
+			request: types.SettleRequest{},
+			responses: withRejections(map[int]any{
+				http.StatusOK:                 types.SettleResponse{},
+				http.StatusAccepted:           types.SettleJob{},
+				http.StatusServiceUnavailable: errorBody{},
+			}),
+		},
+		{
+			method:  http.MethodGet,
+			path:    "/settle/{jobId}",
+			id:      "getSettleJob",
+			summary: "Get an asynchronous settlement job",
+			params: []openAPIParam{
+				{name: "jobId", in: "path", required: true},
+			},
+			responses: map[int]any{http.StatusOK: types.SettleJob{}, http.StatusNotFound: errorBody{}},
+		},
+		{
+			method:    http.MethodPost,
+			path:      "/simulate",
+			id:        "simulate",
+			summary:   "Simulate the settlement of a payment",
+			request:   types.VerifyRequest{},
+			responses: map[int]any{http.StatusOK: types.SimulateResponse{}, http.StatusBadRequest: errorBody{}},
+		},
+		{
+			method:    http.MethodGet,
+			path:      "/supported",
+			id:        "getSupported",
+			summary:   "List the supported scheme-network pairs",
+			responses: map[int]any{http.StatusOK: types.SupportedResponse{}},
+		},
+		{
+			method:    http.MethodGet,
+			path:      "/version",
+			id:        "getVersion",
+			summary:   "Get the facilitator build",
+			responses: map[int]any{http.StatusOK: types.VersionInfo{}},
+		},
+		{
+			method:    http.MethodGet,
+			path:      "/healthz",
+			id:        "healthz",
+			summary:   "Report the process is alive",
+			responses: map[int]any{http.StatusOK: types.HealthResponse{}},
+		},
+		{
+			method:    http.MethodGet,
+			path:      "/readyz",
+			id:        "readyz",
+			summary:   "Report whether every dependency is available",
+			responses: map[int]any{http.StatusOK: types.HealthResponse{}, http.StatusServiceUnavailable: types.HealthResponse{}},
+		},
+	}
+
+	if f.cfg().Batch.Enabled {
+		ops = append(ops, openAPIOperation{
+			method:    http.MethodPost,
+			path:      "/settle/batch",
+			id:        "settleBatch",
+			summary:   "Settle several payments in one transaction per network",
+			request:   types.BatchSettleRequest{},
+			responses: withRejections(map[int]any{http.StatusOK: types.BatchSettleResponse{}}),
+		})
+	}
+
+	if f.cfg().Streams.Enabled {
+		streamID := openAPIParam{name: "streamId", in: "path", required: true}
+		ops = append(ops,
+			openAPIOperation{
+				method:    http.MethodPost,
+				path:      "/streams",
+				id:        "openStream",
+				summary:   "Open a payment stream",
+				request:   types.StreamOpenRequest{},
+				responses: map[int]any{http.StatusCreated: types.PaymentStream{}, http.StatusBadRequest: errorBody{}},
+			},
+			openAPIOperation{
+				method:    http.MethodGet,
+				path:      "/streams/{streamId}",
+				id:        "getStream",
+				summary:   "Get a payment stream",
+				params:    []openAPIParam{streamID},
+				responses: map[int]any{http.StatusOK: types.PaymentStream{}, http.StatusNotFound: errorBody{}},
+			},
+			openAPIOperation{
+				method:  http.MethodPost,
+				path:    "/streams/{streamId}/advance",
+				id:      "advanceStream",
+				summary: "Add a signed increment to a payment stream",
+				params:  []openAPIParam{streamID},
+				request: types.StreamAdvanceRequest{},
+				responses: map[int]any{
+					http.StatusOK:         types.PaymentStream{},
+					http.StatusBadRequest: errorBody{},
+					http.StatusNotFound:   errorBody{},
+					http.StatusConflict:   errorBody{},
+				},
+			},
+			openAPIOperation{
+				method:    http.MethodPost,
+				path:      "/streams/{streamId}/close",
+				id:        "closeStream",
+				summary:   "Close a payment stream and settle its total",
+				params:    []openAPIParam{streamID},
+				responses: map[int]any{http.StatusOK: types.PaymentStream{}, http.StatusNotFound: errorBody{}},
+			},
+		)
+	}
+
+	if f.cfg().Statements.Enabled {
+		ops = append(ops,
+			openAPIOperation{
+				method:  http.MethodGet,
+				path:    "/statements/challenge",
+				id:      "getStatementChallenge",
+				summary: "Get a challenge to sign for a statement",
+				params: []openAPIParam{
+					{name: "payer", in: "query", required: true},
+				},
+				responses: map[int]any{http.StatusOK: types.StatementChallengeResponse{}, http.StatusBadRequest: errorBody{}},
+			},
+			openAPIOperation{
+				method:  http.MethodPost,
+				path:    "/statements",
+				id:      "getStatement",
+				summary: "Get the signed statement of a payer's settled payments",
+				request: types.StatementRequest{},
+				responses: map[int]any{
+					http.StatusOK:           types.Statement{},
+					http.StatusBadRequest:   errorBody{},
+					http.StatusUnauthorized: errorBody{},
+				},
+			},
+		)
+	}
+
+	if f.cfg().Refunds.Enabled && f.cfg().Admin.Enabled {
+		ops = append(ops, openAPIOperation{
+			method:  http.MethodPost,
+			path:    "/refund",
+			id:      "refund",
+			summary: "Send back all or part of a settled payment, with the admin token",
+			request: types.RefundRequest{},
+			responses: map[int]any{
+				http.StatusOK:           types.RefundResponse{},
+				http.StatusBadRequest:   errorBody{},
+				http.StatusUnauthorized: errorBody{},
+				http.StatusNotFound:     errorBody{},
+				http.StatusConflict:     errorBody{},
+			},
+		})
+	}
+
+	return ops
+}
+
+// openAPIDocument describes the routes served by the facilitator, with
+// schemas generated from the Go types they encode
+func (f *Facilitator) openAPIDocument() map[string]any {
+	schemas := newOpenAPISchemas()
+	paths := make(map[string]any)
+	for _, op := range f.openAPIOperations() {
+		operation := map[string]any{
+			"operationId": op.id,
+			"summary":     op.summary,
+		}
+
+		if len(op.params) > 0 {
+			params := make([]any, 0, len(op.params))
+			for _, p := range op.params {
+				schema := map[string]any{"type": "string"}
+				if len(p.enum) > 0 {
+					schema["enum"] = p.enum
+				}
+				param := map[string]any{
+					"name":     p.name,
+					"in":       p.in,
+					"required": p.required,
+					"schema":   schema,
+				}
+				if p.doc != "" {
+					param["description"] = p.doc
+				}
+				params = append(params, param)
+			}
+			operation["parameters"] = params
+		}
+
+		if op.request != nil {
+			operation["requestBody"] = map[string]any{
+				"required": true,
+				"content":  jsonContent(schemas.schema(reflect.TypeOf(op.request))),
+			}
+		}
+
+		responses := make(map[string]any, len(op.responses))
+		for status, body := range op.responses {
+			responses[strconv.Itoa(status)] = map[string]any{
+				"description": http.StatusText(status),
+				"content":     jsonContent(schemas.schema(reflect.TypeOf(body))),
+			}
+		}
+		operation["responses"] = responses
+
+		item, ok := paths[op.path].(map[string]any)
+		if !ok {
+			item = make(map[string]any)
+			paths[op.path] = item
+		}
+		item[strings.ToLower(op.method)] = operation
+	}
+
+	return map[string]any{
+		"openapi": openAPISpecVersion,
+		"info": map[string]any{
+			"title":       "x402 Facilitator",
+			"description": "Verifies and settles x402 payments",
+			"version":     version.Get().Version,
+		},
+		"paths": paths,
+		"components": map[string]any{
+			"schemas": schemas.components,
+		},
+	}
+}
+
+func jsonContent(schema map[string]any) map[string]any {
+	return map[string]any{
+		"application/json": map[string]any{"schema": schema},
+	}
+}
+
+// openAPISchemas builds JSON schemas of Go types. Named structs become
+// components referenced by name.
+type openAPISchemas struct {
+	components map[string]any
+}
+
+func newOpenAPISchemas() *openAPISchemas {
+	return &openAPISchemas{
+		components: map[string]any{
+			"ErrorCode": map[string]any{
+				"type": "string",
+				"enum": types.ErrorCodes,
+			},
+		},
+	}
+}
+
+func (s *openAPISchemas) schema(t reflect.Type) map[string]any {
+	switch t.Kind() {
+	case reflect.Pointer:
+		return s.schema(t.Elem())
+	case reflect.String:
+		return map[string]any{"type": "string"}
+	case reflect.Bool:
+		return map[string]any{"type": "boolean"}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32:
+		return map[string]any{"type": "integer"}
+	case reflect.Int64:
+		return map[string]any{"type": "integer", "format": "int64"}
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return map[string]any{"type": "integer", "format": "int64", "minimum": 0}
+	case reflect.Float32, reflect.Float64:
+		return map[string]any{"type": "number"}
+	case reflect.Slice, reflect.Array:
+		return map[string]any{"type": "array", "items": s.schema(t.Elem())}
+	case reflect.Map:
+		return map[string]any{"type": "object", "additionalProperties": s.schema(t.Elem())}
+	case reflect.Struct:
+		name := t.Name()
+		if name == "" {
+			return s.object(t)
+		}
+		if _, ok := s.components[name]; !ok {
+			// Reserve the name first so recursive types end in a reference
+			s.components[name] = map[string]any{}
+			s.components[name] = s.object(t)
+		}
+		return map[string]any{"$ref": "#/components/schemas/" + name}
+	default:
+		// Interfaces hold any JSON value
+		return map[string]any{}
+	}
+}
+
+// object builds the schema of a struct from its JSON tags. Fields without
+// omitempty are required.
+func (s *openAPISchemas) object(t reflect.Type) map[string]any {
+	properties := make(map[string]any)
+	required := []string{}
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		if !field.IsExported() {
+			continue
+		}
+		tag := field.Tag.Get("json")
+		if tag == "-" {
+			continue
+		}
+		name, opts, _ := strings.Cut(tag, ",")
+		if name == "" {
+			name = field.Name
+		}
+
+		if errorCodeFields[name] && field.Type.Kind() == reflect.String {
+			properties[name] = map[string]any{"$ref": "#/components/schemas/ErrorCode"}
+		} else {
+			properties[name] = s.schema(field.Type)
+		}
+		if !strings.Contains(opts, "omitempty") {
+			required = append(required, name)
+		}
+	}
+
+	object := map[string]any{
+		"type":       "object",
+		"properties": properties,
+	}
+	if len(required) > 0 {
+		object["required"] = required
+	}
+	return object
+}
+
+// handleOpenAPI serves the OpenAPI document of the facilitator's API
+func (f *Facilitator) handleOpenAPI(ctx *gin.Context) {
+	ctx.JSON(http.StatusOK, f.openAPIDocument())
+}
@@ -0,0 +1,123 @@
+package facilitator
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"slices"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/crypto"
+)
+
+func TestOpenAPIDocument(t *testing.T) {
+	privKey, _ := crypto.HexToECDSA("ac0974bec39a17e36ba4a6b4d238ff944bacb478cbed5efcae784d7bf4f2ff80")
+	testConfig := &FacilitatorConfig{
+		Networks: map[string]NetworkConfig{
+			"eip155:8453": {
+				RpcUrl: "http://127.0.0.1:1",
+			},
+		},
+		Log: LogConfig{
+			Level: "error",
+		},
+		Signer: SignerConfig{
+			Address:    crypto.PubkeyToAddress(privKey.PublicKey),
+			PrivateKey: privKey,
+		},
+		Batch: BatchConfig{
+			Enabled: true,
+		},
+	}
+	f := NewFacilitator(testConfig)
+	defer f.Close()
+
+	recorder := httptest.NewRecorder()
+	f.router.ServeHTTP(recorder, httptest.NewRequest("GET", "/openapi.json", nil))
+	if recorder.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d", recorder.Code)
+	}
+
+	type schema struct {
+		Ref        string            `json:"$ref"`
+		Type       string            `json:"type"`
+		Enum       []string          `json:"enum"`
+		Required   []string          `json:"required"`
+		Properties map[string]schema `json:"properties"`
+	}
+	type content struct {
+		JSON struct {
+			Schema schema `json:"schema"`
+		} `json:"application/json"`
+	}
+	type operation struct {
+		OperationID string `json:"operationId"`
+		RequestBody *struct {
+			Content content `json:"content"`
+		} `json:"requestBody"`
+		Responses map[string]struct {
+			Content content `json:"content"`
+		} `json:"responses"`
+	}
+	var doc struct {
+		OpenAPI    string                          `json:"openapi"`
+		Paths      map[string]map[string]operation `json:"paths"`
+		Components struct {
+			Schemas map[string]schema `json:"schemas"`
+		} `json:"components"`
+	}
+	if err := json.Unmarshal(recorder.Body.Bytes(), &doc); err != nil {
+		t.Fatalf("Failed to decode document: %v", err)
+	}
+	if doc.OpenAPI != openAPISpecVersion {
+		t.Errorf("Expected openapi %s, got %s", openAPISpecVersion, doc.OpenAPI)
+	}
+
+	// Routes follow the enabled features
+	verify, ok := doc.Paths["/verify"]["post"]
+	if !ok {
+		t.Fatal("Expected POST /verify to be documented")
+	}
+	if _, ok := doc.Paths["/settle/batch"]["post"]; !ok {
+		t.Error("Expected POST /settle/batch to be documented when batches are enabled")
+	}
+	if _, ok := doc.Paths["/streams"]; ok {
+		t.Error("Expected streams to be left out when disabled")
+	}
+	if _, ok := doc.Paths["/settle/{jobId}"]["get"]; !ok {
+		t.Error("Expected the job path parameter in OpenAPI syntax")
+	}
+
+	// Bodies reference schemas generated from the types
+	if ref := verify.RequestBody.Content.JSON.Schema.Ref; ref != "#/components/schemas/VerifyRequest" {
+		t.Errorf("Expected VerifyRequest body, got %q", ref)
+	}
+	if ref := verify.Responses["200"].Content.JSON.Schema.Ref; ref != "#/components/schemas/VerifyResponse" {
+		t.Errorf("Expected VerifyResponse, got %q", ref)
+	}
+	verifyResponse := doc.Components.Schemas["VerifyResponse"]
+	if verifyResponse.Properties["isValid"].Type != "boolean" {
+		t.Errorf("Expected boolean isValid, got %+v", verifyResponse.Properties["isValid"])
+	}
+	if verifyResponse.Properties["invalidReason"].Ref != "#/components/schemas/ErrorCode" {
+		t.Errorf("Expected invalidReason to use the error codes, got %+v", verifyResponse.Properties["invalidReason"])
+	}
+	if !slices.Contains(doc.Components.Schemas["ErrorCode"].Enum, "insufficient_funds") {
+		t.Error("Expected the error code enum to list insufficient_funds")
+	}
+
+	// Fields without omitempty are required
+	requirements := doc.Components.Schemas["PaymentRequirements"]
+	if !slices.Contains(requirements.Required, "amount") || slices.Contains(requirements.Required, "extra") {
+		t.Errorf("Expected amount required and extra optional, got %v", requirements.Required)
+	}
+
+	// Every reference resolves
+	refs := regexp.MustCompile(`"#/components/schemas/([A-Za-z]+)"`).FindAllStringSubmatch(recorder.Body.String(), -1)
+	for _, ref := range refs {
+		if _, ok := doc.Components.Schemas[ref[1]]; !ok {
+			t.Errorf("Unresolved reference to %s", ref[1])
+		}
+	}
+}
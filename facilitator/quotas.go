@@ -0,0 +1,364 @@
+package facilitator
+
+import (
+	"fmt"
+	"math"
+	"math/big"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/vorpalengineering/x402-go/types"
+)
+
+const (
+	defaultQuotaKeyHeader = "X-API-Key"
+
+	// Clients idle for longer than a daily window are forgotten
+	quotaIdleTimeout = 25 * time.Hour
+	quotaPruneEvery  = time.Hour
+
+	quotaClientKey = "x402_quota_client"
+)
+
+// QuotaStats is one client's usage, reported at /metrics/quotas
+type QuotaStats struct {
+	Requests uint64 `json:"requests"`
+	Limited  uint64 `json:"limited"`
+	// Settlements in the current hour
+	Settlements int `json:"settlements"`
+	// Value settled in the current UTC day, by asset
+	ValueSettled map[string]string `json:"valueSettled,omitempty"`
+}
+
+// quotaExceededError is returned when a client is over one of its limits
+type quotaExceededError struct {
+	limit      string
+	retryAfter time.Duration
+}
+
+func (e *quotaExceededError) Error() string {
+	return fmt.Sprintf("quota exceeded: %s", e.limit)
+}
+
+// quotaIdentity is a client as identified for one request
+type quotaIdentity struct {
+	id     string
+	limits QuotaLimits
+}
+
+type quotaClient struct {
+	tokens     float64
+	lastRefill time.Time
+	lastSeen   time.Time
+
+	hour        int64
+	settlements int
+	day         int64
+	value       map[string]*big.Int
+
+	requests uint64
+	limited  uint64
+}
+
+// quotaLimiter enforces per-client request rates and settlement quotas.
+// Settlement counts use fixed clock-hour windows and value uses UTC days.
+type quotaLimiter struct {
+	cfg       QuotasConfig
+	keyHeader string
+	now       func() time.Time
+
+	mu        sync.Mutex
+	clients   map[string]*quotaClient
+	lastPrune time.Time
+}
+
+func newQuotaLimiter(cfg QuotasConfig, now func() time.Time) *quotaLimiter {
+	keyHeader := cfg.KeyHeader
+	if keyHeader == "" {
+		keyHeader = defaultQuotaKeyHeader
+	}
+	return &quotaLimiter{
+		cfg:       cfg,
+		keyHeader: keyHeader,
+		now:       now,
+		clients:   make(map[string]*quotaClient),
+	}
+}
+
+// identify picks the client by API key, then by source IP, falling back to
+// the default limits keyed by source IP
+func (q *quotaLimiter) identify(ginCtx *gin.Context) quotaIdentity {
+	if key := ginCtx.GetHeader(q.keyHeader); key != "" {
+		if client, ok := q.cfg.Clients[key]; ok {
+			return q.identity(client, "key:"+maskAPIKey(key))
+		}
+	}
+	ip := ginCtx.ClientIP()
+	if client, ok := q.cfg.Clients[ip]; ok {
+		return q.identity(client, "ip:"+ip)
+	}
+	return quotaIdentity{id: "ip:" + ip, limits: q.cfg.Default}
+}
+
+func (q *quotaLimiter) identity(client ClientQuota, fallbackID string) quotaIdentity {
+	id := fallbackID
+	if client.Name != "" {
+		id = client.Name
+	}
+	return quotaIdentity{id: id, limits: q.cfg.Default.merge(client.QuotaLimits)}
+}
+
+// client returns the state of a client, creating it on first use. Callers must hold mu.
+func (q *quotaLimiter) client(id string, now time.Time) *quotaClient {
+	// Forget idle clients now and then
+	if now.Sub(q.lastPrune) >= quotaPruneEvery {
+		for clientID, c := range q.clients {
+			if now.Sub(c.lastSeen) > quotaIdleTimeout {
+				delete(q.clients, clientID)
+			}
+		}
+		q.lastPrune = now
+	}
+
+	c, ok := q.clients[id]
+	if !ok {
+		c = &quotaClient{lastRefill: now, value: make(map[string]*big.Int)}
+		c.tokens = math.Inf(1)
+		q.clients[id] = c
+	}
+	c.lastSeen = now
+	return c
+}
+
+// allowRequest takes a token from the client's request bucket
+func (q *quotaLimiter) allowRequest(identity quotaIdentity) error {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	now := q.now()
+	c := q.client(identity.id, now)
+	c.requests++
+
+	limits := identity.limits
+	if limits.RequestsPerSecond <= 0 {
+		return nil
+	}
+	burst := float64(limits.Burst)
+	if burst < 1 {
+		burst = math.Max(1, limits.RequestsPerSecond)
+	}
+
+	// Refill the bucket for the time elapsed
+	c.tokens = math.Min(burst, c.tokens+now.Sub(c.lastRefill).Seconds()*limits.RequestsPerSecond)
+	c.lastRefill = now
+	if c.tokens < 1 {
+		c.limited++
+		wait := time.Duration((1 - c.tokens) / limits.RequestsPerSecond * float64(time.Second))
+		return &quotaExceededError{limit: "requests_per_second", retryAfter: wait}
+	}
+	c.tokens--
+	return nil
+}
+
+// reserveSettlement counts a settlement and its value against the client's
+// hourly and daily quotas. The returned release undoes the reservation for
+// settlements that never reached the chain.
+func (q *quotaLimiter) reserveSettlement(identity quotaIdentity, requirements *types.PaymentRequirements) (func(), error) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	now := q.now()
+	c := q.client(identity.id, now)
+	limits := identity.limits
+
+	// Roll windows over
+	hour := now.Unix() / 3600
+	if c.hour != hour {
+		c.hour, c.settlements = hour, 0
+	}
+	day := now.UTC().Unix() / 86400
+	if c.day != day {
+		c.day, c.value = day, make(map[string]*big.Int)
+	}
+
+	// Check settlements this hour
+	if limits.SettlementsPerHour > 0 && c.settlements >= limits.SettlementsPerHour {
+		c.limited++
+		return nil, &quotaExceededError{
+			limit:      "settlements_per_hour",
+			retryAfter: time.Unix((hour+1)*3600, 0).Sub(now),
+		}
+	}
+
+	// Check value settled today in this asset
+	asset := strings.ToLower(requirements.Asset)
+	amount, ok := new(big.Int).SetString(requirements.Amount, 10)
+	if !ok {
+		amount = new(big.Int)
+	}
+	used := c.value[asset]
+	if used == nil {
+		used = new(big.Int)
+	}
+	if limit := limits.valueLimit(asset); limit != nil && new(big.Int).Add(used, amount).Cmp(limit) > 0 {
+		c.limited++
+		return nil, &quotaExceededError{
+			limit:      "value_per_day",
+			retryAfter: time.Unix((day+1)*86400, 0).Sub(now),
+		}
+	}
+
+	c.settlements++
+	c.value[asset] = new(big.Int).Add(used, amount)
+
+	var once sync.Once
+	release := func() {
+		once.Do(func() {
+			q.mu.Lock()
+			defer q.mu.Unlock()
+			if c.hour == hour && c.settlements > 0 {
+				c.settlements--
+			}
+			if c.day == day && c.value[asset] != nil {
+				c.value[asset].Sub(c.value[asset], amount)
+			}
+		})
+	}
+	return release, nil
+}
+
+// snapshot returns the usage of every known client
+func (q *quotaLimiter) snapshot() map[string]QuotaStats {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	now := q.now()
+	snapshot := make(map[string]QuotaStats, len(q.clients))
+	for id, c := range q.clients {
+		stats := QuotaStats{Requests: c.requests, Limited: c.limited}
+		if c.hour == now.Unix()/3600 {
+			stats.Settlements = c.settlements
+		}
+		if c.day == now.UTC().Unix()/86400 && len(c.value) > 0 {
+			stats.ValueSettled = make(map[string]string, len(c.value))
+			for asset, value := range c.value {
+				stats.ValueSettled[asset] = value.String()
+			}
+		}
+		snapshot[id] = stats
+	}
+	return snapshot
+}
+
+// merge returns the limits with non-zero fields of override taking precedence
+func (limits QuotaLimits) merge(override QuotaLimits) QuotaLimits {
+	if override.RequestsPerSecond != 0 {
+		limits.RequestsPerSecond = override.RequestsPerSecond
+	}
+	if override.Burst != 0 {
+		limits.Burst = override.Burst
+	}
+	if override.SettlementsPerHour != 0 {
+		limits.SettlementsPerHour = override.SettlementsPerHour
+	}
+	if len(override.ValuePerDay) > 0 {
+		merged := make(map[string]string, len(limits.ValuePerDay)+len(override.ValuePerDay))
+		for asset, value := range limits.ValuePerDay {
+			merged[strings.ToLower(asset)] = value
+		}
+		for asset, value := range override.ValuePerDay {
+			merged[strings.ToLower(asset)] = value
+		}
+		limits.ValuePerDay = merged
+	}
+	return limits
+}
+
+// valueLimit returns the daily limit for an asset, or nil if unlimited
+func (limits QuotaLimits) valueLimit(asset string) *big.Int {
+	for addr, value := range limits.ValuePerDay {
+		if strings.EqualFold(addr, asset) {
+			if limit, ok := new(big.Int).SetString(value, 10); ok {
+				return limit
+			}
+		}
+	}
+	return nil
+}
+
+func (limits QuotaLimits) validate() error {
+	if limits.RequestsPerSecond < 0 || limits.Burst < 0 || limits.SettlementsPerHour < 0 {
+		return fmt.Errorf("limits cannot be negative")
+	}
+	for asset, value := range limits.ValuePerDay {
+		limit, ok := new(big.Int).SetString(value, 10)
+		if !ok || limit.Sign() < 0 {
+			return fmt.Errorf("invalid value_per_day for %s: %s", asset, value)
+		}
+	}
+	return nil
+}
+
+// maskAPIKey keeps API keys out of metrics and logs
+func maskAPIKey(key string) string {
+	if len(key) <= 8 {
+		return "***"
+	}
+	return key[:4] + "***"
+}
+
+// quotaMiddleware identifies the client and enforces its request rate.
+// Health probes are exempt.
+func (f *Facilitator) quotaMiddleware() gin.HandlerFunc {
+	return func(ginCtx *gin.Context) {
+		path := ginCtx.Request.URL.Path
+		if path == "/healthz" || path == "/readyz" {
+			ginCtx.Next()
+			return
+		}
+
+		identity := f.quotas.identify(ginCtx)
+		ginCtx.Set(quotaClientKey, identity)
+		if err := f.quotas.allowRequest(identity); err != nil {
+			f.rejectQuota(ginCtx, identity, err)
+			return
+		}
+		ginCtx.Next()
+	}
+}
+
+// reserveSettlementQuota checks the settlement quotas of the requesting client.
+// It responds 429 and returns false when they are exceeded.
+func (f *Facilitator) reserveSettlementQuota(ginCtx *gin.Context, requirements *types.PaymentRequirements) (func(), bool) {
+	if f.quotas == nil {
+		return func() {}, true
+	}
+	identity := ginCtx.MustGet(quotaClientKey).(quotaIdentity)
+	release, err := f.quotas.reserveSettlement(identity, requirements)
+	if err != nil {
+		f.rejectQuota(ginCtx, identity, err)
+		return nil, false
+	}
+	return release, true
+}
+
+func (f *Facilitator) rejectQuota(ginCtx *gin.Context, identity quotaIdentity, err error) {
+	retryAfter := time.Second
+	if quotaErr, ok := err.(*quotaExceededError); ok && quotaErr.retryAfter > retryAfter {
+		retryAfter = quotaErr.retryAfter
+	}
+	f.log(ginCtx.Request.Context()).Warn("quota exceeded", "client", identity.id, "error", err)
+
+	ginCtx.Header("Retry-After", strconv.Itoa(int(math.Ceil(retryAfter.Seconds()))))
+	ginCtx.AbortWithStatusJSON(http.StatusTooManyRequests, gin.H{
+		"error": err.Error(),
+	})
+}
+
+func (f *Facilitator) handleQuotaMetrics(ctx *gin.Context) {
+	ctx.JSON(http.StatusOK, f.quotas.snapshot())
+}
@@ -0,0 +1,225 @@
+package facilitator
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/vorpalengineering/x402-go/types"
+)
+
+func TestQuotaRequestRate(t *testing.T) {
+	testConfig := &FacilitatorConfig{
+		Networks: map[string]NetworkConfig{
+			"eip155:8453": {
+				RpcUrl: "http://127.0.0.1:1",
+			},
+		},
+		Log: LogConfig{
+			Level: "error",
+		},
+		Quotas: QuotasConfig{
+			Enabled: true,
+			Default: QuotaLimits{RequestsPerSecond: 1, Burst: 2},
+			Clients: map[string]ClientQuota{
+				"partner-api-key": {Name: "partner", QuotaLimits: QuotaLimits{Burst: 10}},
+			},
+		},
+	}
+	f := NewFacilitator(testConfig)
+	defer f.Close()
+	now := time.Unix(1700000000, 0)
+	f.now = func() time.Time { return now }
+
+	get := func(path, apiKey string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest("GET", path, nil)
+		if apiKey != "" {
+			req.Header.Set("X-API-Key", apiKey)
+		}
+		recorder := httptest.NewRecorder()
+		f.router.ServeHTTP(recorder, req)
+		return recorder
+	}
+
+	// The burst is allowed, the next request is limited
+	for i := range 2 {
+		if recorder := get("/supported", ""); recorder.Code != http.StatusOK {
+			t.Fatalf("Expected request %d to be allowed, got %d", i, recorder.Code)
+		}
+	}
+	recorder := get("/supported", "")
+	if recorder.Code != http.StatusTooManyRequests {
+		t.Fatalf("Expected status 429, got %d", recorder.Code)
+	}
+	if recorder.Header().Get("Retry-After") != "1" {
+		t.Errorf("Expected Retry-After 1, got %q", recorder.Header().Get("Retry-After"))
+	}
+
+	// Probes are exempt and tokens refill over time
+	if recorder := get("/healthz", ""); recorder.Code != http.StatusOK {
+		t.Errorf("Expected health probe to be allowed, got %d", recorder.Code)
+	}
+	now = now.Add(time.Second)
+	if recorder := get("/supported", ""); recorder.Code != http.StatusOK {
+		t.Errorf("Expected request after refill to be allowed, got %d", recorder.Code)
+	}
+
+	// Known API keys get their own limits, unknown ones count as the source IP
+	for i := range 5 {
+		if recorder := get("/supported", "partner-api-key"); recorder.Code != http.StatusOK {
+			t.Fatalf("Expected partner request %d to be allowed, got %d", i, recorder.Code)
+		}
+	}
+	if recorder := get("/supported", "unknown-key"); recorder.Code != http.StatusTooManyRequests {
+		t.Errorf("Expected unknown key to share the IP limit, got %d", recorder.Code)
+	}
+
+	stats := f.quotas.snapshot()
+	if stats["partner"].Requests != 5 {
+		t.Errorf("Expected 5 partner requests, got %+v", stats["partner"])
+	}
+	if stats["ip:192.0.2.1"].Limited != 2 {
+		t.Errorf("Expected 2 limited requests from the IP, got %+v", stats["ip:192.0.2.1"])
+	}
+}
+
+func TestQuotaSettlements(t *testing.T) {
+	now := time.Unix(1700000000, 0)
+	q := newQuotaLimiter(QuotasConfig{Enabled: true}, func() time.Time { return now })
+	identity := quotaIdentity{id: "client", limits: QuotaLimits{
+		SettlementsPerHour: 2,
+		ValuePerDay:        map[string]string{"0x833589fCD6eDb6E08f4c7C32D4f71b54bdA02913": "1500000"},
+	}}
+	requirements := &types.PaymentRequirements{
+		Asset:  "0x833589fcd6edb6e08f4c7c32d4f71b54bda02913",
+		Amount: "1000000",
+	}
+
+	// Value is checked before it is used up
+	release, err := q.reserveSettlement(identity, requirements)
+	if err != nil {
+		t.Fatalf("Expected first settlement to be allowed, got %v", err)
+	}
+	if _, err := q.reserveSettlement(identity, requirements); err == nil || err.(*quotaExceededError).limit != "value_per_day" {
+		t.Errorf("Expected value quota error, got %v", err)
+	}
+
+	// Released reservations free the quota again
+	release()
+	release()
+	if _, err := q.reserveSettlement(identity, requirements); err != nil {
+		t.Fatalf("Expected settlement after release to be allowed, got %v", err)
+	}
+
+	// The hourly count is separate from the daily value
+	small := &types.PaymentRequirements{Asset: requirements.Asset, Amount: "100"}
+	if _, err := q.reserveSettlement(identity, small); err != nil {
+		t.Fatalf("Expected second settlement to be allowed, got %v", err)
+	}
+	if _, err := q.reserveSettlement(identity, small); err == nil || err.(*quotaExceededError).limit != "settlements_per_hour" {
+		t.Errorf("Expected hourly quota error, got %v", err)
+	}
+
+	// Windows roll over
+	now = now.Add(time.Hour)
+	if _, err := q.reserveSettlement(identity, small); err != nil {
+		t.Errorf("Expected settlement in the next hour to be allowed, got %v", err)
+	}
+	now = now.Add(24 * time.Hour)
+	if _, err := q.reserveSettlement(identity, requirements); err != nil {
+		t.Errorf("Expected value quota to reset the next day, got %v", err)
+	}
+}
+
+func TestQuotaFailedSettlementReleased(t *testing.T) {
+	testConfig := &FacilitatorConfig{
+		Networks: map[string]NetworkConfig{
+			"eip155:8453": {
+				RpcUrl: "http://127.0.0.1:1",
+			},
+		},
+		Supported: []types.SupportedKind{
+			{Scheme: "exact", Network: "eip155:8453"},
+		},
+		Log: LogConfig{
+			Level: "error",
+		},
+		Quotas: QuotasConfig{
+			Enabled: true,
+			Default: QuotaLimits{SettlementsPerHour: 1},
+		},
+	}
+	f := NewFacilitator(testConfig)
+	defer f.Close()
+
+	// Settlements failing before a transaction is sent don't use the quota
+	requirements := types.PaymentRequirements{
+		Scheme:  "exact",
+		Network: "eip155:8453",
+		Amount:  "1000000",
+		Asset:   "0x833589fCD6eDb6E08f4c7C32D4f71b54bdA02913",
+		PayTo:   "0x70997970C51812dc3A010C7d01b50e0d17dc79C8",
+	}
+	body, _ := json.Marshal(types.SettleRequest{
+		PaymentPayload: types.PaymentPayload{
+			X402Version: 2,
+			Accepted:    requirements,
+			Payload:     map[string]any{},
+		},
+		PaymentRequirements: requirements,
+	})
+	for i := range 3 {
+		req, _ := http.NewRequest("POST", "/settle", bytes.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		recorder := httptest.NewRecorder()
+		f.router.ServeHTTP(recorder, req)
+		if recorder.Code != http.StatusOK {
+			t.Fatalf("Expected settlement %d to reach the facilitator, got %d", i, recorder.Code)
+		}
+	}
+}
+
+func TestQuotaIgnoresSpoofedForwardedFor(t *testing.T) {
+	testConfig := &FacilitatorConfig{
+		Log: LogConfig{
+			Level: "error",
+		},
+		Quotas: QuotasConfig{
+			Enabled: true,
+			Default: QuotaLimits{RequestsPerSecond: 1, Burst: 1},
+			Clients: map[string]ClientQuota{
+				"10.0.0.12": {Name: "internal", QuotaLimits: QuotaLimits{Burst: 100}},
+			},
+		},
+	}
+	f := NewFacilitator(testConfig)
+	defer f.Close()
+	now := time.Unix(1700000000, 0)
+	f.now = func() time.Time { return now }
+
+	get := func(forwardedFor string) int {
+		req := httptest.NewRequest("GET", "/supported", nil)
+		req.RemoteAddr = "203.0.113.7:4321"
+		req.Header.Set("X-Forwarded-For", forwardedFor)
+		recorder := httptest.NewRecorder()
+		f.router.ServeHTTP(recorder, req)
+		return recorder.Code
+	}
+
+	// Neither a fresh IP nor a privileged one escapes the caller's own limit
+	if code := get("198.51.100.1"); code != http.StatusOK {
+		t.Fatalf("Expected first request to be allowed, got %d", code)
+	}
+	if code := get("198.51.100.2"); code != http.StatusTooManyRequests {
+		t.Errorf("Expected a fresh forwarded IP to be ignored, got %d", code)
+	}
+	if code := get("10.0.0.12"); code != http.StatusTooManyRequests {
+		t.Errorf("Expected a privileged forwarded IP to be ignored, got %d", code)
+	}
+	if _, ok := f.quotas.snapshot()["ip:203.0.113.7"]; !ok {
+		t.Errorf("Expected usage keyed by the connection's IP, got %+v", f.quotas.snapshot())
+	}
+}
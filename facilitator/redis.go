@@ -0,0 +1,403 @@
+package facilitator
+
+import (
+	"bufio"
+	"context"
+	"crypto/rand"
+	"crypto/tls"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/vorpalengineering/x402-go/types"
+)
+
+const (
+	defaultRedisKeyPrefix = "x402:"
+	defaultRedisTimeout   = 5 * time.Second
+	// redisPoolSize is how many idle connections are kept open
+	redisPoolSize = 16
+)
+
+// RedisConfig shares settlement results and signer nonces between facilitator
+// replicas through Redis, so replicas behind a load balancer don't settle the
+// same authorization twice or send transactions with the same nonce. Empty
+// addr keeps the state in memory.
+type RedisConfig struct {
+	// Addr is the host:port of the Redis server
+	Addr     string `yaml:"addr"`
+	Username string `yaml:"username"`
+	Password string `yaml:"password"`
+	DB       int    `yaml:"db"`
+	TLS      bool   `yaml:"tls"`
+	// KeyPrefix namespaces the keys, so facilitators of different
+	// deployments can share a server (default "x402:")
+	KeyPrefix string `yaml:"key_prefix"`
+	// TimeoutSeconds bounds connecting and each command (default 5)
+	TimeoutSeconds int `yaml:"timeout_seconds"`
+}
+
+func (redisCfg RedisConfig) enabled() bool {
+	return redisCfg.Addr != ""
+}
+
+func (redisCfg RedisConfig) validate() error {
+	if !redisCfg.enabled() {
+		return nil
+	}
+	if _, _, err := net.SplitHostPort(redisCfg.Addr); err != nil {
+		return fmt.Errorf("addr must be host:port: %w", err)
+	}
+	if redisCfg.DB < 0 || redisCfg.TimeoutSeconds < 0 {
+		return fmt.Errorf("db and timeout_seconds cannot be negative")
+	}
+	return nil
+}
+
+func (redisCfg RedisConfig) keyPrefix() string {
+	if redisCfg.KeyPrefix != "" {
+		return redisCfg.KeyPrefix
+	}
+	return defaultRedisKeyPrefix
+}
+
+func (redisCfg RedisConfig) timeout() time.Duration {
+	if redisCfg.TimeoutSeconds > 0 {
+		return time.Duration(redisCfg.TimeoutSeconds) * time.Second
+	}
+	return defaultRedisTimeout
+}
+
+// redisError is an error reply from the server
+type redisError string
+
+func (e redisError) Error() string {
+	return string(e)
+}
+
+type redisConn struct {
+	conn net.Conn
+	r    *bufio.Reader
+	w    *bufio.Writer
+}
+
+// redisClient speaks the subset of RESP the shared state needs over a small
+// pool of connections
+type redisClient struct {
+	cfg  RedisConfig
+	pool chan *redisConn
+}
+
+func newRedisClient(cfg RedisConfig) *redisClient {
+	return &redisClient{
+		cfg:  cfg,
+		pool: make(chan *redisConn, redisPoolSize),
+	}
+}
+
+func (c *redisClient) dial(ctx context.Context) (*redisConn, error) {
+	dialer := &net.Dialer{Timeout: c.cfg.timeout()}
+	var conn net.Conn
+	var err error
+	if c.cfg.TLS {
+		host, _, _ := net.SplitHostPort(c.cfg.Addr)
+		tlsDialer := &tls.Dialer{NetDialer: dialer, Config: &tls.Config{ServerName: host}}
+		conn, err = tlsDialer.DialContext(ctx, "tcp", c.cfg.Addr)
+	} else {
+		conn, err = dialer.DialContext(ctx, "tcp", c.cfg.Addr)
+	}
+	if err != nil {
+		return nil, err
+	}
+	rc := &redisConn{conn: conn, r: bufio.NewReader(conn), w: bufio.NewWriter(conn)}
+
+	// Authenticate and pick the database
+	if c.cfg.Password != "" {
+		args := []string{"AUTH", c.cfg.Password}
+		if c.cfg.Username != "" {
+			args = []string{"AUTH", c.cfg.Username, c.cfg.Password}
+		}
+		if _, err := c.roundTrip(ctx, rc, args); err != nil {
+			conn.Close()
+			return nil, fmt.Errorf("redis auth: %w", err)
+		}
+	}
+	if c.cfg.DB > 0 {
+		if _, err := c.roundTrip(ctx, rc, []string{"SELECT", strconv.Itoa(c.cfg.DB)}); err != nil {
+			conn.Close()
+			return nil, fmt.Errorf("redis select: %w", err)
+		}
+	}
+	return rc, nil
+}
+
+// do runs a command and returns its reply: a string, int64, []byte, nil or
+// []any. Error replies are returned as redisError.
+func (c *redisClient) do(ctx context.Context, args ...string) (any, error) {
+	var rc *redisConn
+	select {
+	case rc = <-c.pool:
+	default:
+		var err error
+		if rc, err = c.dial(ctx); err != nil {
+			return nil, err
+		}
+	}
+
+	reply, err := c.roundTrip(ctx, rc, args)
+	var replyErr redisError
+	if err != nil && !errors.As(err, &replyErr) {
+		// The connection state is unknown after an I/O error
+		rc.conn.Close()
+		return nil, err
+	}
+	select {
+	case c.pool <- rc:
+	default:
+		rc.conn.Close()
+	}
+	return reply, err
+}
+
+func (c *redisClient) roundTrip(ctx context.Context, rc *redisConn, args []string) (any, error) {
+	deadline := time.Now().Add(c.cfg.timeout())
+	if ctxDeadline, ok := ctx.Deadline(); ok && ctxDeadline.Before(deadline) {
+		deadline = ctxDeadline
+	}
+	rc.conn.SetDeadline(deadline)
+
+	// Commands are arrays of bulk strings
+	fmt.Fprintf(rc.w, "*%d\r\n", len(args))
+	for _, arg := range args {
+		fmt.Fprintf(rc.w, "$%d\r\n%s\r\n", len(arg), arg)
+	}
+	if err := rc.w.Flush(); err != nil {
+		return nil, err
+	}
+	return readRedisReply(rc.r)
+}
+
+func readRedisReply(r *bufio.Reader) (any, error) {
+	line, err := r.ReadString('\n')
+	if err != nil {
+		return nil, err
+	}
+	line = strings.TrimSuffix(line, "\r\n")
+	if line == "" {
+		return nil, fmt.Errorf("redis: empty reply")
+	}
+
+	switch line[0] {
+	case '+':
+		return line[1:], nil
+	case '-':
+		return nil, redisError(line[1:])
+	case ':':
+		return strconv.ParseInt(line[1:], 10, 64)
+	case '$':
+		n, err := strconv.Atoi(line[1:])
+		if err != nil {
+			return nil, fmt.Errorf("redis: invalid bulk length %q", line)
+		}
+		if n < 0 {
+			return nil, nil
+		}
+		buf := make([]byte, n+2)
+		if _, err := io.ReadFull(r, buf); err != nil {
+			return nil, err
+		}
+		return buf[:n], nil
+	case '*':
+		n, err := strconv.Atoi(line[1:])
+		if err != nil {
+			return nil, fmt.Errorf("redis: invalid array length %q", line)
+		}
+		if n < 0 {
+			return nil, nil
+		}
+		items := make([]any, n)
+		for i := range items {
+			// Keep reading past error elements so the connection stays in sync
+			item, err := readRedisReply(r)
+			var replyErr redisError
+			if err != nil && !errors.As(err, &replyErr) {
+				return nil, err
+			}
+			items[i] = item
+		}
+		return items, nil
+	default:
+		return nil, fmt.Errorf("redis: unexpected reply %q", line)
+	}
+}
+
+func (c *redisClient) close() {
+	for {
+		select {
+		case rc := <-c.pool:
+			rc.conn.Close()
+		default:
+			return
+		}
+	}
+}
+
+// Scripts run atomically on the server, so replicas never interleave their
+// reads and writes of a key
+
+// claimScript returns the stored result of a settlement, or claims it for
+// the caller. Reply: {1} claimed, {0, result} settled, {0} held by another.
+const claimScript = `
+local result = redis.call('GET', KEYS[1])
+if result then return {0, result} end
+if redis.call('SET', KEYS[2], ARGV[1], 'NX', 'PX', ARGV[2]) then return {1} end
+return {0}`
+
+// finishScript stores a successful result and drops the caller's claim
+const finishScript = `
+if ARGV[2] ~= '' then redis.call('SET', KEYS[1], ARGV[2], 'PX', ARGV[3]) end
+if redis.call('GET', KEYS[2]) == ARGV[1] then redis.call('DEL', KEYS[2]) end
+return 0`
+
+// reserveNonceScript hands out the lowest released nonce the chain hasn't
+// used, or the next one, moving the counter up to the pending nonce first
+const reserveNonceScript = `
+local pending = tonumber(ARGV[1])
+redis.call('ZREMRANGEBYSCORE', KEYS[2], '-inf', '(' .. pending)
+local released = redis.call('ZPOPMIN', KEYS[2])
+if #released > 0 then return tonumber(released[1]) end
+local next = tonumber(redis.call('GET', KEYS[1]) or '0')
+if pending > next then next = pending end
+redis.call('SET', KEYS[1], next + 1)
+return next`
+
+// releaseNonceScript returns a nonce that was never sent, shrinking the
+// counter rather than leave released nonces at the top
+const releaseNonceScript = `
+local nonce = tonumber(ARGV[1])
+local next = tonumber(redis.call('GET', KEYS[1]) or '0')
+if nonce >= next then return 0 end
+redis.call('ZADD', KEYS[2], nonce, nonce)
+while next > 0 and redis.call('ZSCORE', KEYS[2], next - 1) do
+  redis.call('ZREM', KEYS[2], next - 1)
+  next = next - 1
+end
+redis.call('SET', KEYS[1], next)
+return 0`
+
+// resyncNonceScript moves the counter up to the chain's pending nonce and
+// drops released nonces the chain has already used
+const resyncNonceScript = `
+local pending = tonumber(ARGV[1])
+local next = tonumber(redis.call('GET', KEYS[1]) or '0')
+if pending > next then redis.call('SET', KEYS[1], pending) end
+redis.call('ZREMRANGEBYSCORE', KEYS[2], '-inf', '(' .. pending)
+return 0`
+
+// redisState implements sharedSettlements and sharedNonces. Keys used by the
+// same script share a hash tag so they land on one Redis Cluster slot.
+type redisState struct {
+	client *redisClient
+	prefix string
+	// owner identifies this replica's settlement claims
+	owner string
+}
+
+func newRedisState(cfg RedisConfig) *redisState {
+	owner := make([]byte, 16)
+	rand.Read(owner)
+	return &redisState{
+		client: newRedisClient(cfg),
+		prefix: cfg.keyPrefix(),
+		owner:  hex.EncodeToString(owner),
+	}
+}
+
+func (s *redisState) eval(ctx context.Context, script string, keys []string, args ...string) (any, error) {
+	cmd := append([]string{"EVAL", script, strconv.Itoa(len(keys))}, keys...)
+	return s.client.do(ctx, append(cmd, args...)...)
+}
+
+func (s *redisState) settlementKeys(key string) []string {
+	tag := s.prefix + "settle:{" + key + "}"
+	return []string{tag + ":result", tag + ":claim"}
+}
+
+func (s *redisState) claim(ctx context.Context, key string, ttl time.Duration) (bool, *types.SettleResponse, error) {
+	reply, err := s.eval(ctx, claimScript, s.settlementKeys(key), s.owner, strconv.FormatInt(ttl.Milliseconds(), 10))
+	if err != nil {
+		return false, nil, err
+	}
+	items, ok := reply.([]any)
+	if !ok || len(items) == 0 {
+		return false, nil, fmt.Errorf("redis: unexpected claim reply %v", reply)
+	}
+	if claimed, _ := items[0].(int64); claimed == 1 {
+		return true, nil, nil
+	}
+	if len(items) < 2 {
+		return false, nil, nil
+	}
+	data, _ := items[1].([]byte)
+	var resp types.SettleResponse
+	if err := json.Unmarshal(data, &resp); err != nil {
+		return false, nil, fmt.Errorf("redis: invalid settlement result: %w", err)
+	}
+	return false, &resp, nil
+}
+
+func (s *redisState) finish(ctx context.Context, key string, resp *types.SettleResponse, ttl time.Duration) error {
+	var result []byte
+	if resp.Success {
+		var err error
+		if result, err = json.Marshal(resp); err != nil {
+			return err
+		}
+	}
+	_, err := s.eval(ctx, finishScript, s.settlementKeys(key), s.owner, string(result), strconv.FormatInt(ttl.Milliseconds(), 10))
+	return err
+}
+
+func (s *redisState) nonceKeys(network string, address common.Address) []string {
+	tag := s.prefix + "nonce:{" + network + ":" + strings.ToLower(address.Hex()) + "}"
+	return []string{tag + ":next", tag + ":released"}
+}
+
+func (s *redisState) reserveNonce(ctx context.Context, network string, address common.Address, pending uint64) (uint64, error) {
+	reply, err := s.eval(ctx, reserveNonceScript, s.nonceKeys(network, address), strconv.FormatUint(pending, 10))
+	if err != nil {
+		return 0, err
+	}
+	nonce, ok := reply.(int64)
+	if !ok || nonce < 0 {
+		return 0, fmt.Errorf("redis: unexpected nonce reply %v", reply)
+	}
+	return uint64(nonce), nil
+}
+
+func (s *redisState) releaseNonce(ctx context.Context, network string, address common.Address, nonce uint64) error {
+	_, err := s.eval(ctx, releaseNonceScript, s.nonceKeys(network, address), strconv.FormatUint(nonce, 10))
+	return err
+}
+
+func (s *redisState) resyncNonce(ctx context.Context, network string, address common.Address, pending uint64) error {
+	_, err := s.eval(ctx, resyncNonceScript, s.nonceKeys(network, address), strconv.FormatUint(pending, 10))
+	return err
+}
+
+// ping checks the server is reachable, for /readyz
+func (s *redisState) ping(ctx context.Context) error {
+	_, err := s.client.do(ctx, "PING")
+	return err
+}
+
+func (s *redisState) close() {
+	s.client.close()
+}
@@ -0,0 +1,284 @@
+package facilitator
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"net"
+	"slices"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/vorpalengineering/x402-go/types"
+)
+
+// fakeRedis speaks RESP and runs the shared state scripts in Go
+type fakeRedis struct {
+	mu       sync.Mutex
+	password string
+	strings  map[string]string
+	sets     map[string][]uint64
+	commands []string
+}
+
+func startFakeRedis(t *testing.T, password string) (*fakeRedis, string) {
+	t.Helper()
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Failed to listen: %v", err)
+	}
+	t.Cleanup(func() { listener.Close() })
+
+	fake := &fakeRedis{password: password, strings: make(map[string]string), sets: make(map[string][]uint64)}
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			go fake.serve(conn)
+		}
+	}()
+	return fake, listener.Addr().String()
+}
+
+func (fake *fakeRedis) serve(conn net.Conn) {
+	defer conn.Close()
+	r := bufio.NewReader(conn)
+	authed := fake.password == ""
+	for {
+		request, err := readRedisReply(r)
+		if err != nil {
+			return
+		}
+		items, _ := request.([]any)
+		args := make([]string, len(items))
+		for i, item := range items {
+			args[i] = string(item.([]byte))
+		}
+
+		var reply string
+		switch {
+		case args[0] == "AUTH":
+			authed = args[len(args)-1] == fake.password
+			reply = "+OK\r\n"
+			if !authed {
+				reply = "-WRONGPASS invalid password\r\n"
+			}
+		case !authed:
+			reply = "-NOAUTH Authentication required\r\n"
+		case args[0] == "PING":
+			reply = "+PONG\r\n"
+		case args[0] == "EVAL":
+			numKeys, _ := strconv.Atoi(args[2])
+			reply = fake.eval(args[1], args[3:3+numKeys], args[3+numKeys:])
+		default:
+			reply = "-ERR unknown command\r\n"
+		}
+		conn.Write([]byte(reply))
+	}
+}
+
+func (fake *fakeRedis) eval(script string, keys, args []string) string {
+	fake.mu.Lock()
+	defer fake.mu.Unlock()
+	fake.commands = append(fake.commands, keys...)
+
+	bulk := func(s string) string { return fmt.Sprintf("$%d\r\n%s\r\n", len(s), s) }
+	counter := func(key string) uint64 {
+		n, _ := strconv.ParseUint(fake.strings[key], 10, 64)
+		return n
+	}
+	dropBelow := func(key string, pending uint64) {
+		fake.sets[key] = slices.DeleteFunc(fake.sets[key], func(n uint64) bool { return n < pending })
+	}
+
+	switch script {
+	case claimScript:
+		if result, ok := fake.strings[keys[0]]; ok {
+			return "*2\r\n:0\r\n" + bulk(result)
+		}
+		if _, ok := fake.strings[keys[1]]; !ok {
+			fake.strings[keys[1]] = args[0]
+			return "*1\r\n:1\r\n"
+		}
+		return "*1\r\n:0\r\n"
+	case finishScript:
+		if args[1] != "" {
+			fake.strings[keys[0]] = args[1]
+		}
+		if fake.strings[keys[1]] == args[0] {
+			delete(fake.strings, keys[1])
+		}
+		return ":0\r\n"
+	case reserveNonceScript:
+		pending, _ := strconv.ParseUint(args[0], 10, 64)
+		dropBelow(keys[1], pending)
+		if released := fake.sets[keys[1]]; len(released) > 0 {
+			slices.Sort(released)
+			fake.sets[keys[1]] = released[1:]
+			return fmt.Sprintf(":%d\r\n", released[0])
+		}
+		next := max(counter(keys[0]), pending)
+		fake.strings[keys[0]] = strconv.FormatUint(next+1, 10)
+		return fmt.Sprintf(":%d\r\n", next)
+	case releaseNonceScript:
+		nonce, _ := strconv.ParseUint(args[0], 10, 64)
+		next := counter(keys[0])
+		if nonce >= next {
+			return ":0\r\n"
+		}
+		fake.sets[keys[1]] = append(fake.sets[keys[1]], nonce)
+		for next > 0 && slices.Contains(fake.sets[keys[1]], next-1) {
+			fake.sets[keys[1]] = slices.DeleteFunc(fake.sets[keys[1]], func(n uint64) bool { return n == next-1 })
+			next--
+		}
+		fake.strings[keys[0]] = strconv.FormatUint(next, 10)
+		return ":0\r\n"
+	case resyncNonceScript:
+		pending, _ := strconv.ParseUint(args[0], 10, 64)
+		if pending > counter(keys[0]) {
+			fake.strings[keys[0]] = args[0]
+		}
+		dropBelow(keys[1], pending)
+		return ":0\r\n"
+	}
+	return "-ERR unknown script\r\n"
+}
+
+func TestRedisSharedSettlements(t *testing.T) {
+	_, addr := startFakeRedis(t, "secret")
+	cfg := RedisConfig{Addr: addr, Password: "secret", KeyPrefix: "test:"}
+
+	// Two replicas settle the same authorization
+	replicas := []*settlementGroup{newSettlementGroup(), newSettlementGroup()}
+	for _, g := range replicas {
+		state := newRedisState(cfg)
+		defer state.close()
+		g.shared = state
+	}
+
+	var calls atomic.Int32
+	settle := func() *types.SettleResponse {
+		calls.Add(1)
+		time.Sleep(100 * time.Millisecond)
+		return &types.SettleResponse{Success: true, Transaction: "0xabc", Network: "eip155:8453"}
+	}
+
+	var wg sync.WaitGroup
+	results := make([]*types.SettleResponse, len(replicas))
+	shared := make([]bool, len(replicas))
+	for i, g := range replicas {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			results[i], shared[i] = g.do(context.Background(), "eip155:8453|0xasset|0xpayer|0x01", time.Now(), settle)
+		}()
+	}
+	wg.Wait()
+
+	if calls.Load() != 1 {
+		t.Fatalf("Expected one settlement across replicas, got %d", calls.Load())
+	}
+	for i, resp := range results {
+		if !resp.Success || resp.Transaction != "0xabc" {
+			t.Errorf("Replica %d: expected shared transaction, got %+v", i, resp)
+		}
+	}
+	if shared[0] == shared[1] {
+		t.Errorf("Expected exactly one replica to report a shared result, got %v", shared)
+	}
+
+	// A later duplicate on a fresh replica gets the stored result
+	lateState := newRedisState(cfg)
+	defer lateState.close()
+	late := newSettlementGroup()
+	late.shared = lateState
+	resp, fromReplica := late.do(context.Background(), "eip155:8453|0xasset|0xpayer|0x01", time.Now(), settle)
+	if !fromReplica || resp.Transaction != "0xabc" || calls.Load() != 1 {
+		t.Errorf("Expected stored result without settling, got %+v (shared %v, calls %d)", resp, fromReplica, calls.Load())
+	}
+}
+
+func TestRedisSharedSettlementsUnavailable(t *testing.T) {
+	listener, _ := net.Listen("tcp", "127.0.0.1:0")
+	addr := listener.Addr().String()
+	listener.Close()
+
+	g := newSettlementGroup()
+	g.shared = newRedisState(RedisConfig{Addr: addr, TimeoutSeconds: 1})
+	settled := false
+	resp, _ := g.do(context.Background(), "key", time.Now(), func() *types.SettleResponse {
+		settled = true
+		return &types.SettleResponse{Success: true}
+	})
+	if settled || resp.Success || resp.ErrorReason != types.ErrCodeSettlementFailed {
+		t.Errorf("Expected settlement to fail without the shared state, got %+v (settled %v)", resp, settled)
+	}
+}
+
+func TestRedisSharedNonces(t *testing.T) {
+	fake, addr := startFakeRedis(t, "")
+	address := common.HexToAddress("0x70997970C51812dc3A010C7d01b50e0d17dc79C8")
+	source := &fakeNonceSource{}
+	source.pending.Store(5)
+
+	// Two replicas reserve from the same signer concurrently
+	replicas := []*nonceManager{newNonceManager(), newNonceManager()}
+	for _, m := range replicas {
+		state := newRedisState(RedisConfig{Addr: addr})
+		defer state.close()
+		m.shared = state
+	}
+
+	var mu sync.Mutex
+	owners := make(map[uint64]int)
+	var wg sync.WaitGroup
+	for i := range 10 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			nonce, err := replicas[i%2].reserve(context.Background(), source, "eip155:8453", address)
+			if err != nil {
+				t.Errorf("Failed to reserve nonce: %v", err)
+				return
+			}
+			mu.Lock()
+			owners[nonce] = i % 2
+			mu.Unlock()
+		}()
+	}
+	wg.Wait()
+	for nonce := uint64(5); nonce < 15; nonce++ {
+		if _, ok := owners[nonce]; !ok {
+			t.Fatalf("Expected nonces 5-14 without duplicates, got %v", owners)
+		}
+	}
+
+	// A nonce released by one replica is reused by the other
+	if err := replicas[owners[7]].release("eip155:8453", address, 7); err != nil {
+		t.Fatalf("Failed to release nonce: %v", err)
+	}
+	other := 1 - owners[7]
+	if nonce, _ := replicas[other].reserve(context.Background(), source, "eip155:8453", address); nonce != 7 {
+		t.Errorf("Expected released nonce 7 to be reused, got %d", nonce)
+	}
+
+	// The counter follows the chain past nonces sent elsewhere
+	source.pending.Store(20)
+	if nonce, _ := replicas[0].reserve(context.Background(), source, "eip155:8453", address); nonce != 20 {
+		t.Errorf("Expected nonce 20 after the chain moved on, got %d", nonce)
+	}
+
+	// Keys carry the prefix and a hash tag per account
+	fake.mu.Lock()
+	defer fake.mu.Unlock()
+	want := "x402:nonce:{eip155:8453:0x70997970c51812dc3a010c7d01b50e0d17dc79c8}:next"
+	if !slices.Contains(fake.commands, want) {
+		t.Errorf("Expected key %s, got %v", want, fake.commands[:2])
+	}
+}
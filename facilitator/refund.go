@@ -0,0 +1,299 @@
+package facilitator
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/big"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/ethereum/go-ethereum/accounts/abi"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/ethclient"
+	"github.com/gin-gonic/gin"
+	"github.com/vorpalengineering/x402-go/types"
+	"github.com/vorpalengineering/x402-go/utils"
+)
+
+// RefundsConfig enables POST /refund, which sends back all or part of a
+// payment settled by this facilitator. Refunds are sent from the network's
+// signer, acting as treasury, unless payTo has a signer of its own.
+type RefundsConfig struct {
+	Enabled bool `yaml:"enabled"`
+	// PayeeSigners holds the keys of payTo addresses, by address. Refunds of
+	// payments to them are sent from payTo.
+	PayeeSigners map[string]*SignerConfig `yaml:"payee_signers"`
+}
+
+func (refundsCfg RefundsConfig) validate() error {
+	if !refundsCfg.Enabled {
+		return nil
+	}
+	for payTo, signerCfg := range refundsCfg.PayeeSigners {
+		if !common.IsHexAddress(payTo) {
+			return fmt.Errorf("payee signer %q must be keyed by a payTo address", payTo)
+		}
+		if signerCfg == nil || !signerCfg.loaded() {
+			return fmt.Errorf("private key must be set for payee %s", payTo)
+		}
+		if address := signerCfg.signer().Address(); address != common.HexToAddress(payTo) {
+			return fmt.Errorf("payee signer for %s has address %s", payTo, address.Hex())
+		}
+	}
+	return nil
+}
+
+// loadPayeeSigners loads the keys of the payee signers
+func (refundsCfg RefundsConfig) loadPayeeSigners() error {
+	for payTo, signerCfg := range refundsCfg.PayeeSigners {
+		if signerCfg == nil {
+			continue
+		}
+		if err := signerCfg.load(""); err != nil {
+			return fmt.Errorf("failed to load payee signer for %s: %w", payTo, err)
+		}
+	}
+	return nil
+}
+
+var (
+	// Settlements are kept in memory, so this is also the answer for payments
+	// settled before a restart, by another replica or dropped by retention
+	errRefundNotFound  = errors.New("no settlement of this transaction is known to this facilitator, only payments it settled since it started can be refunded")
+	errRefundAmbiguous = errors.New("transaction settled several payments, set payer")
+	errRefundExceeded  = errors.New("amount exceeds what is left to refund")
+)
+
+// refundRecord is a refund sent for a settlement
+type refundRecord struct {
+	timestamp   time.Time
+	payer       common.Address
+	network     string
+	asset       string
+	amount      *big.Int
+	transaction string
+	settlement  string
+	reason      string
+}
+
+// reserveRefund finds the settlement of a transaction, of payer when set,
+// and reserves amount of it until the refund is sent or released. It
+// returns the stored settlement and a copy of it.
+func (s *statementStore) reserveRefund(network, transaction string, payer *common.Address, amount *big.Int) (*settlementRecord, settlementRecord, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var found *settlementRecord
+	for _, rec := range s.byTransaction[transactionKey(network, transaction)] {
+		if payer != nil && rec.payer != *payer {
+			continue
+		}
+		if found != nil {
+			return nil, settlementRecord{}, errRefundAmbiguous
+		}
+		found = rec
+	}
+	if found == nil {
+		return nil, settlementRecord{}, errRefundNotFound
+	}
+
+	settled, ok := new(big.Int).SetString(found.amount, 10)
+	if !ok {
+		return nil, settlementRecord{}, fmt.Errorf("settled amount %q is not a number", found.amount)
+	}
+	left := settled.Sub(settled, found.refunded)
+	if amount.Cmp(left) > 0 {
+		return nil, settlementRecord{}, fmt.Errorf("%w: %s", errRefundExceeded, left.String())
+	}
+	found.refunded.Add(found.refunded, amount)
+	return found, *found, nil
+}
+
+// releaseRefund returns the reserved amount of a refund that was not sent
+func (s *statementStore) releaseRefund(rec *settlementRecord, amount *big.Int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	rec.refunded.Sub(rec.refunded, amount)
+}
+
+// recordRefund links a sent refund to its settlement and returns the
+// amount of the payment refunded so far
+func (s *statementStore) recordRefund(rec *settlementRecord, refund refundRecord) *big.Int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	rec.refunds = append(rec.refunds, refund)
+	return new(big.Int).Set(rec.refunded)
+}
+
+// refundSigner returns payTo's signer when configured, else the network's
+func (f *Facilitator) refundSigner(network, payTo string) (Signer, func(), error) {
+	if signer, ok := f.payees[common.HexToAddress(payTo)]; ok {
+		return signer, func() {}, nil
+	}
+	return f.signers.acquire(network)
+}
+
+// refund sends amount of a settled payment back to its payer
+func (f *Facilitator) refund(ctx context.Context, rec settlementRecord, amount *big.Int) *types.RefundResponse {
+	resp := &types.RefundResponse{
+		Settlement: rec.transaction,
+		Network:    rec.network,
+		Asset:      rec.asset,
+		Payer:      rec.payer.Hex(),
+		Amount:     amount.String(),
+	}
+	fail := func(err error, attempts int) *types.RefundResponse {
+		failed := settleFailure(err, attempts)
+		resp.ErrorReason, resp.ErrorMessage, resp.Attempts = failed.ErrorReason, failed.ErrorMessage, attempts
+		return resp
+	}
+
+	client, err := f.getRPCClient(rec.network)
+	if err != nil {
+		return fail(&settleError{code: types.ErrCodeNetworkError, err: fmt.Errorf("failed to connect to network: %w", err)}, 0)
+	}
+	ctx, cancel := f.withTransactionTimeout(ctx)
+	defer cancel()
+
+	signer, release, err := f.refundSigner(rec.network, rec.payTo)
+	if err != nil {
+		return fail(err, 0)
+	}
+	resp.From = signer.Address().Hex()
+	txHash, attempts, err := f.sendWithRetry(ctx, rec.network, func(attempt int) (string, error) {
+		return f.sendRefundTransfer(ctx, client, signer, rec, amount, attempt)
+	})
+	release()
+	if err != nil {
+		return fail(err, attempts)
+	}
+	resp.Success, resp.Transaction, resp.Attempts = true, txHash, attempts
+
+	// Wait for confirmations like settlements do
+	if f.cfg().Transaction.Confirmations > 0 {
+		confirmed := &types.SettleResponse{Success: true, Transaction: txHash}
+		f.confirmSettlement(ctx, client, txHash, confirmed)
+		resp.Success, resp.Transaction = confirmed.Success, confirmed.Transaction
+		resp.ErrorReason, resp.ErrorMessage = confirmed.ErrorReason, confirmed.ErrorMessage
+	}
+	return resp
+}
+
+// sendRefundTransfer sends transfer(payer, amount) on the asset
+func (f *Facilitator) sendRefundTransfer(
+	ctx context.Context,
+	client *ethclient.Client,
+	signer Signer,
+	rec settlementRecord,
+	amount *big.Int,
+	attempt int,
+) (string, error) {
+	transferABI, err := abi.JSON(strings.NewReader(utils.ERC20TransferABI))
+	if err != nil {
+		return "", fmt.Errorf("failed to parse ABI: %w", err)
+	}
+	callData, err := transferABI.Pack("transfer", rec.payer, amount)
+	if err != nil {
+		return "", fmt.Errorf("failed to encode call: %w", err)
+	}
+	return f.sendTokenCall(ctx, client, signer, rec.network, rec.asset, callData, attempt)
+}
+
+func (f *Facilitator) handleRefund(ginCtx *gin.Context) {
+	// Decode request
+	var req types.RefundRequest
+	if err := ginCtx.ShouldBindJSON(&req); err != nil {
+		ginCtx.JSON(http.StatusBadRequest, gin.H{
+			"error": err.Error(),
+		})
+		return
+	}
+	amount, ok := new(big.Int).SetString(req.Amount, 10)
+	if !ok || amount.Sign() <= 0 {
+		ginCtx.JSON(http.StatusBadRequest, gin.H{
+			"error": "amount must be a positive integer",
+		})
+		return
+	}
+	if utils.IsSolanaNetwork(req.Network) {
+		ginCtx.JSON(http.StatusBadRequest, gin.H{
+			"error": "refunds are only supported on EVM networks",
+		})
+		return
+	}
+	var payer *common.Address
+	if req.Payer != "" {
+		if !common.IsHexAddress(req.Payer) {
+			ginCtx.JSON(http.StatusBadRequest, gin.H{
+				"error": "payer must be a valid address",
+			})
+			return
+		}
+		address := common.HexToAddress(req.Payer)
+		payer = &address
+	}
+
+	// Reserve the amount against the settled payment
+	stored, rec, err := f.statements.reserveRefund(req.Network, req.Transaction, payer, amount)
+	if err != nil {
+		status := http.StatusBadRequest
+		switch {
+		case errors.Is(err, errRefundNotFound):
+			status = http.StatusNotFound
+		case errors.Is(err, errRefundExceeded):
+			status = http.StatusConflict
+		}
+		ginCtx.JSON(status, gin.H{
+			"error": err.Error(),
+		})
+		return
+	}
+
+	ctx := ginCtx.Request.Context()
+	resp := f.refund(ctx, rec, amount)
+	logger := f.log(ctx).With("network", rec.network, "asset", rec.asset, "payer", resp.Payer, "amount", resp.Amount, "settlement", rec.transaction)
+	if resp.Success {
+		refunded := f.statements.recordRefund(stored, refundRecord{
+			timestamp:   f.now(),
+			payer:       rec.payer,
+			network:     rec.network,
+			asset:       rec.asset,
+			amount:      amount,
+			transaction: resp.Transaction,
+			settlement:  rec.transaction,
+			reason:      req.Reason,
+		})
+		resp.Refunded = refunded.String()
+		logger.Info("payment refunded", "tx", resp.Transaction, "from", resp.From)
+	} else {
+		// A refund that may still be mined keeps its reservation
+		if resp.Transaction == "" || resp.ErrorReason == SettleErrTransactionReverted {
+			f.statements.releaseRefund(stored, amount)
+		}
+		logger.Warn("refund failed", "tx", resp.Transaction, "code", resp.ErrorReason, "reason", resp.ErrorMessage)
+	}
+
+	// Publish refund event
+	event := Event{
+		Type:        EventRefund,
+		Network:     rec.network,
+		Asset:       rec.asset,
+		PayTo:       rec.payTo,
+		Payer:       resp.Payer,
+		Amount:      resp.Amount,
+		Success:     resp.Success,
+		Code:        resp.ErrorReason,
+		Reason:      resp.ErrorMessage,
+		Transaction: resp.Transaction,
+		Settlement:  rec.transaction,
+	}
+	if resp.Success {
+		event.Reason = req.Reason
+	}
+	f.publishEvent(event)
+	f.auditEvent(requestIDFromContext(ctx), event)
+
+	ginCtx.JSON(http.StatusOK, resp)
+}
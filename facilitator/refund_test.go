@@ -0,0 +1,166 @@
+package facilitator
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum/accounts/abi"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	ethtypes "github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/vorpalengineering/x402-go/types"
+	"github.com/vorpalengineering/x402-go/utils"
+)
+
+func TestRefund(t *testing.T) {
+	var mu sync.Mutex
+	var sent []*ethtypes.Transaction
+	rpcServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req struct {
+			ID     json.RawMessage   `json:"id"`
+			Method string            `json:"method"`
+			Params []json.RawMessage `json:"params"`
+		}
+		json.NewDecoder(r.Body).Decode(&req)
+		result := `"0x1"`
+		switch req.Method {
+		case "eth_estimateGas":
+			result = `"0x10000"`
+		case "eth_gasPrice":
+			result = `"0x3b9aca00"`
+		case "eth_getTransactionCount":
+			result = `"0x0"`
+		case "eth_sendRawTransaction":
+			var raw hexutil.Bytes
+			json.Unmarshal(req.Params[0], &raw)
+			tx := new(ethtypes.Transaction)
+			tx.UnmarshalBinary(raw)
+			mu.Lock()
+			sent = append(sent, tx)
+			mu.Unlock()
+			result = fmt.Sprintf(`"%s"`, tx.Hash().Hex())
+		}
+		w.Header().Set("Content-Type", "application/json")
+		fmt.Fprintf(w, `{"jsonrpc":"2.0","id":%s,"result":%s}`, req.ID, result)
+	}))
+	defer rpcServer.Close()
+
+	treasuryKey, _ := crypto.HexToECDSA("ac0974bec39a17e36ba4a6b4d238ff944bacb478cbed5efcae784d7bf4f2ff80")
+	payeeKey, _ := crypto.HexToECDSA("59c6995e998f97a5a0044966f0945389dc9e86dae88c7a8412f4603b6b78690d")
+	payee := crypto.PubkeyToAddress(payeeKey.PublicKey)
+	f := NewFacilitator(&FacilitatorConfig{
+		Networks: map[string]NetworkConfig{
+			"eip155:8453": {RpcUrl: rpcServer.URL},
+		},
+		Transaction: TransactionConfig{TimeoutSeconds: 5, MaxGasPrice: "100000000000"},
+		Log:         LogConfig{Level: "error"},
+		Admin:       AdminConfig{Enabled: true, Token: "secret"},
+		Signer:      SignerConfig{PrivateKey: treasuryKey},
+		Refunds: RefundsConfig{
+			Enabled:      true,
+			PayeeSigners: map[string]*SignerConfig{payee.Hex(): {PrivateKey: payeeKey}},
+		},
+	})
+	defer f.Close()
+
+	// Two payments settled in one transaction, one of them to the payee with a key
+	asset := "0x833589fCD6eDb6E08f4c7C32D4f71b54bdA02913"
+	payerA := common.HexToAddress("0xf39Fd6e51aad88F6F4ce6aB8827279cffFb92266")
+	payerB := common.HexToAddress("0x70997970C51812dc3A010C7d01b50e0d17dc79C8")
+	settlement := "0x" + strings.Repeat("ab", 32)
+	f.now = func() time.Time { return time.Date(2025, 1, 15, 0, 0, 0, 0, time.UTC) }
+	f.statements.record(settlementRecord{
+		timestamp: f.now(), payer: payerA, network: "eip155:8453", asset: asset,
+		payTo: "0x742d35Cc6634C0532925a3b844Bc9e7595f0bEb", amount: "1000000", transaction: settlement,
+	})
+	f.statements.record(settlementRecord{
+		timestamp: f.now(), payer: payerB, network: "eip155:8453", asset: asset,
+		payTo: payee.Hex(), amount: "500000", transaction: settlement,
+	})
+
+	post := func(req types.RefundRequest, token string) *httptest.ResponseRecorder {
+		body, _ := json.Marshal(req)
+		httpReq := httptest.NewRequest("POST", "/refund", bytes.NewReader(body))
+		httpReq.Header.Set("Authorization", "Bearer "+token)
+		recorder := httptest.NewRecorder()
+		f.router.ServeHTTP(recorder, httpReq)
+		return recorder
+	}
+
+	// Refunds need the admin token
+	req := types.RefundRequest{Network: "eip155:8453", Transaction: settlement, Payer: payerA.Hex(), Amount: "400000", Reason: "service outage"}
+	if recorder := post(req, "wrong"); recorder.Code != http.StatusUnauthorized {
+		t.Errorf("Expected status 401, got %d", recorder.Code)
+	}
+
+	// A transaction settling several payments needs the payer, unknown ones are not found
+	if recorder := post(types.RefundRequest{Network: "eip155:8453", Transaction: settlement, Amount: "1"}, "secret"); recorder.Code != http.StatusBadRequest {
+		t.Errorf("Expected status 400 without payer, got %d", recorder.Code)
+	}
+	if recorder := post(types.RefundRequest{Network: "eip155:8453", Transaction: "0x" + strings.Repeat("cd", 32), Amount: "1"}, "secret"); recorder.Code != http.StatusNotFound {
+		t.Errorf("Expected status 404 for unknown transaction, got %d", recorder.Code)
+	}
+
+	// The treasury sends a partial refund to the payer
+	recorder := post(req, "secret")
+	if recorder.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d: %s", recorder.Code, recorder.Body.String())
+	}
+	var resp types.RefundResponse
+	json.Unmarshal(recorder.Body.Bytes(), &resp)
+	treasury := crypto.PubkeyToAddress(treasuryKey.PublicKey)
+	if !resp.Success || resp.Settlement != settlement || resp.From != treasury.Hex() || resp.Refunded != "400000" {
+		t.Fatalf("Unexpected refund response %+v", resp)
+	}
+	transferABI, _ := abi.JSON(strings.NewReader(utils.ERC20TransferABI))
+	if len(sent) != 1 || sent[0].Hash().Hex() != resp.Transaction || *sent[0].To() != common.HexToAddress(asset) {
+		t.Fatalf("Expected refund transaction to the asset, got %d transactions", len(sent))
+	}
+	args, err := transferABI.Methods["transfer"].Inputs.Unpack(sent[0].Data()[4:])
+	if err != nil || args[0].(common.Address) != payerA || args[1].(*big.Int).String() != "400000" {
+		t.Errorf("Expected transfer of 400000 to %s, got %v (%v)", payerA.Hex(), args, err)
+	}
+
+	// No more than what was paid is refunded
+	req.Amount = "600001"
+	if recorder := post(req, "secret"); recorder.Code != http.StatusConflict {
+		t.Errorf("Expected status 409 for refunding more than paid, got %d", recorder.Code)
+	}
+
+	// Payments to a payee with a key are refunded from payTo
+	recorder = post(types.RefundRequest{Network: "eip155:8453", Transaction: settlement, Payer: payerB.Hex(), Amount: "500000"}, "secret")
+	json.Unmarshal(recorder.Body.Bytes(), &resp)
+	if !resp.Success || resp.From != payee.Hex() {
+		t.Fatalf("Expected refund from payee %s, got %+v", payee.Hex(), resp)
+	}
+	if from, _ := ethtypes.Sender(ethtypes.LatestSignerForChainID(sent[1].ChainId()), sent[1]); from != payee {
+		t.Errorf("Expected refund signed by payee, got %s", from.Hex())
+	}
+
+	// The payer's statement links the refund to its settlement
+	from := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	statement := f.statements.statement(payerA, "2025-01", from, from.AddDate(0, 1, 0))
+	if len(statement.Refunds) != 1 || statement.Refunds[0].Settlement != settlement || statement.Refunds[0].Reason != "service outage" {
+		t.Errorf("Expected refund in statement, got %+v", statement.Refunds)
+	}
+	if len(statement.Totals) != 1 || statement.Totals[0].Amount != "1000000" || statement.Totals[0].Refunded != "400000" {
+		t.Errorf("Expected refunded total, got %+v", statement.Totals)
+	}
+
+	// After a restart the settlement is unknown, so it can't be refunded again
+	f.statements = newStatementStore(StatementsConfig{})
+	req.Amount = "1"
+	recorder = post(req, "secret")
+	if recorder.Code != http.StatusNotFound || !strings.Contains(recorder.Body.String(), "since it started") {
+		t.Errorf("Expected status 404 after a restart, got %d: %s", recorder.Code, recorder.Body.String())
+	}
+}
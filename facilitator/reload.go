@@ -0,0 +1,152 @@
+package facilitator
+
+import (
+	"fmt"
+	"reflect"
+	"slices"
+	"sort"
+
+	"github.com/ethereum/go-ethereum/common"
+)
+
+// cfg returns the current config. Take it once per operation so a reload
+// midway doesn't mix settings from two configs.
+func (f *Facilitator) cfg() *FacilitatorConfig {
+	return f.config.Load()
+}
+
+// Reload swaps in the networks, supported scheme-network pairs and transaction
+// settings of next without restarting. Other sections only take effect on
+// restart and are left as they are. Networks whose RPC endpoints changed are
+// re-dialed, others keep their connections. The current config is kept if
+// next is invalid.
+func (f *Facilitator) Reload(next *FacilitatorConfig) error {
+	// Validate new config
+	if err := next.Validate(); err != nil {
+		return fmt.Errorf("invalid config: %w", err)
+	}
+
+	// Take the reloadable sections from the new config
+	current := f.cfg()
+	merged := *current
+	merged.Networks = next.Networks
+	merged.Supported = next.Supported
+	merged.Transaction = next.Transaction
+
+	// Warn about changes that need a restart
+	if ignored := restartRequired(current, next); len(ignored) > 0 {
+		f.logger.Warn("config changes require a restart", "sections", ignored)
+	}
+
+	// Swap config and drop connections to networks whose endpoints changed
+	f.rpcClientsMu.Lock()
+	defer f.rpcClientsMu.Unlock()
+
+	f.config.Store(&merged)
+	var redialed []string
+	for network, client := range f.rpcClients {
+		networkCfg, ok := merged.Networks[network]
+		if ok && !rpcChanged(current.Networks[network], networkCfg) {
+			continue
+		}
+		client.Close()
+		delete(f.rpcClients, network)
+		if failover, ok := f.rpcFailovers[network]; ok {
+			failover.close()
+			delete(f.rpcFailovers, network)
+		}
+		if ok {
+			redialed = append(redialed, network)
+		}
+	}
+
+	// Solana clients are created again on first use
+	for network := range f.svmClients {
+		networkCfg, ok := merged.Networks[network]
+		if !ok || rpcChanged(current.Networks[network], networkCfg) {
+			delete(f.svmClients, network)
+		}
+	}
+
+	// Re-dial changed networks now, failures are retried on first use
+	for _, network := range redialed {
+		client, err := f.dialRPC(network, merged.Networks[network])
+		if err != nil {
+			f.logger.Warn("failed to re-dial RPC", "network", network, "error", err)
+			continue
+		}
+		f.rpcClients[network] = client
+	}
+
+	sort.Strings(redialed)
+	f.logger.Info("config reloaded",
+		"networks", len(merged.Networks),
+		"supported", merged.Supported,
+		"redialed", redialed,
+	)
+	return nil
+}
+
+// rpcChanged reports whether a network needs new RPC connections
+func rpcChanged(previous, next NetworkConfig) bool {
+	return !slices.Equal(previous.GetRpcUrls(), next.GetRpcUrls()) || previous.Failover != next.Failover
+}
+
+// restartRequired lists the sections that differ between configs but are not reloaded
+func restartRequired(current, next *FacilitatorConfig) []string {
+	sections := []struct {
+		name             string
+		current, updated any
+	}{
+		{"server", current.Server, next.Server},
+		{"log", current.Log, next.Log},
+		{"capture", current.Capture, next.Capture},
+		{"statements", current.Statements, next.Statements},
+		{"alerts", current.Alerts, next.Alerts},
+		{"events", current.Events, next.Events},
+		{"settle_jobs", current.SettleJobs, next.SettleJobs},
+		{"batch", current.Batch, next.Batch},
+		{"streams", current.Streams, next.Streams},
+		{"tokens", current.Tokens, next.Tokens},
+		{"fees", current.Fees, next.Fees},
+		{"audit", current.Audit, next.Audit},
+		{"quotas", current.Quotas, next.Quotas},
+		{"admin", current.Admin, next.Admin},
+		{"signer", current.Signer.Address, next.Signer.Address},
+		{"solana", current.Solana, next.Solana},
+		{"redis", current.Redis, next.Redis},
+		{"deferred", current.Deferred, next.Deferred},
+		{"refunds", current.Refunds.Enabled, next.Refunds.Enabled},
+		{"network signers", networkSigners(current), networkSigners(next)},
+		{"payee signers", payeeSigners(current), payeeSigners(next)},
+	}
+	var changed []string
+	for _, section := range sections {
+		if !reflect.DeepEqual(section.current, section.updated) {
+			changed = append(changed, section.name)
+		}
+	}
+	return changed
+}
+
+// networkSigners returns the addresses of per-network signers
+func networkSigners(config *FacilitatorConfig) map[string]common.Address {
+	signers := make(map[string]common.Address)
+	for network, networkCfg := range config.Networks {
+		if networkCfg.Signer != nil {
+			signers[network] = networkCfg.Signer.Address
+		}
+	}
+	return signers
+}
+
+// payeeSigners returns the addresses of refund payee signers
+func payeeSigners(config *FacilitatorConfig) map[string]common.Address {
+	signers := make(map[string]common.Address)
+	for payTo, signerCfg := range config.Refunds.PayeeSigners {
+		if signerCfg != nil {
+			signers[payTo] = signerCfg.Address
+		}
+	}
+	return signers
+}
@@ -0,0 +1,119 @@
+package facilitator
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/vorpalengineering/x402-go/types"
+)
+
+func reloadTestConfig(baseRPC string) *FacilitatorConfig {
+	privKey, _ := crypto.HexToECDSA("ac0974bec39a17e36ba4a6b4d238ff944bacb478cbed5efcae784d7bf4f2ff80")
+	return &FacilitatorConfig{
+		Server: ServerConfig{
+			Host: "localhost",
+			Port: 8080,
+		},
+		Networks: map[string]NetworkConfig{
+			"eip155:8453": {RpcUrl: baseRPC},
+			"eip155:1":    {RpcUrl: "http://127.0.0.1:1"},
+		},
+		Supported: []types.SupportedKind{
+			{Scheme: "exact", Network: "eip155:8453"},
+		},
+		Transaction: TransactionConfig{
+			TimeoutSeconds: 120,
+			MaxGasPrice:    "100000000000",
+		},
+		Log: LogConfig{
+			Level: "error",
+		},
+		Signer: SignerConfig{
+			Address:    crypto.PubkeyToAddress(privKey.PublicKey),
+			PrivateKey: privKey,
+		},
+	}
+}
+
+func TestReload(t *testing.T) {
+	f := NewFacilitator(reloadTestConfig("http://127.0.0.1:1"))
+	defer f.Close()
+	if err := f.DialRPCClients(); err != nil {
+		t.Fatalf("Failed to dial RPC clients: %v", err)
+	}
+	baseClient := f.rpcClients["eip155:8453"]
+	mainnetClient := f.rpcClients["eip155:1"]
+
+	// Change the Base RPC, supported pairs, transaction settings and port
+	next := reloadTestConfig("http://127.0.0.1:2")
+	next.Supported = append(next.Supported, types.SupportedKind{Scheme: "exact", Network: "eip155:1"})
+	next.Transaction.Confirmations = 2
+	next.Server.Port = 9090
+	if err := f.Reload(next); err != nil {
+		t.Fatalf("Expected reload to succeed, got %v", err)
+	}
+
+	// Only the changed network is re-dialed
+	if f.rpcClients["eip155:8453"] == baseClient {
+		t.Errorf("Expected eip155:8453 to be re-dialed")
+	}
+	if f.rpcClients["eip155:1"] != mainnetClient {
+		t.Errorf("Expected eip155:1 to keep its connection")
+	}
+
+	// Reloadable sections take effect, others wait for a restart
+	if !f.cfg().IsSupported("exact", "eip155:1") {
+		t.Errorf("Expected eip155:1 to be supported after reload")
+	}
+	if f.cfg().Transaction.Confirmations != 2 {
+		t.Errorf("Expected 2 confirmations, got %d", f.cfg().Transaction.Confirmations)
+	}
+	if f.cfg().Server.Port != 8080 {
+		t.Errorf("Expected port to stay 8080, got %d", f.cfg().Server.Port)
+	}
+
+	recorder := httptest.NewRecorder()
+	f.router.ServeHTTP(recorder, httptest.NewRequest("GET", "/supported", nil))
+	var res types.SupportedResponse
+	json.Unmarshal(recorder.Body.Bytes(), &res)
+	if recorder.Code != http.StatusOK || len(res.Kinds) != 2 {
+		t.Errorf("Expected 2 supported kinds, got %d (status %d)", len(res.Kinds), recorder.Code)
+	}
+}
+
+func TestReloadInvalidConfig(t *testing.T) {
+	f := NewFacilitator(reloadTestConfig("http://127.0.0.1:1"))
+	defer f.Close()
+
+	next := reloadTestConfig("http://127.0.0.1:1")
+	next.Networks = nil
+	if err := f.Reload(next); err == nil {
+		t.Errorf("Expected invalid config to be rejected")
+	}
+	if len(f.cfg().Networks) != 2 {
+		t.Errorf("Expected current networks to be kept, got %d", len(f.cfg().Networks))
+	}
+}
+
+func TestReloadRemovedNetwork(t *testing.T) {
+	f := NewFacilitator(reloadTestConfig("http://127.0.0.1:1"))
+	defer f.Close()
+	if err := f.DialRPCClients(); err != nil {
+		t.Fatalf("Failed to dial RPC clients: %v", err)
+	}
+
+	next := reloadTestConfig("http://127.0.0.1:1")
+	delete(next.Networks, "eip155:1")
+	if err := f.Reload(next); err != nil {
+		t.Fatalf("Expected reload to succeed, got %v", err)
+	}
+	if _, ok := f.rpcClients["eip155:1"]; ok {
+		t.Errorf("Expected connection to removed network to be closed")
+	}
+	if _, err := f.getRPCClient("eip155:1"); err == nil {
+		t.Errorf("Expected removed network to be unavailable")
+	}
+}
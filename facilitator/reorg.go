@@ -0,0 +1,258 @@
+package facilitator
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/common"
+	ethtypes "github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/ethclient"
+	"github.com/gin-gonic/gin"
+	"github.com/vorpalengineering/x402-go/types"
+)
+
+var (
+	errTransactionReorged   = errors.New("transaction dropped by a chain reorganization")
+	errTransactionNotMined  = errors.New("transaction not mined")
+	errReorgWatchingStopped = errors.New("reorg watch stopped")
+)
+
+// ReorgStats counts the settlements of a network dropped by reorganizations
+type ReorgStats struct {
+	Watching    int `json:"watching"`
+	Reorged     int `json:"reorged"`
+	Rebroadcast int `json:"rebroadcast"`
+	Lost        int `json:"lost"`
+}
+
+// reorgReader is the subset of RPC calls needed to watch a transaction
+type reorgReader interface {
+	receiptReader
+	TransactionByHash(ctx context.Context, txHash common.Hash) (*ethtypes.Transaction, bool, error)
+}
+
+// reorgWatcher tracks the settlements being watched after confirmation and
+// counts reorganizations per network
+type reorgWatcher struct {
+	stop     chan struct{}
+	stopOnce sync.Once
+	wg       sync.WaitGroup
+
+	mu    sync.Mutex
+	stats map[string]*ReorgStats
+}
+
+func newReorgWatcher() *reorgWatcher {
+	return &reorgWatcher{
+		stop:  make(chan struct{}),
+		stats: make(map[string]*ReorgStats),
+	}
+}
+
+func (w *reorgWatcher) record(network string, update func(stats *ReorgStats)) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	stats, ok := w.stats[network]
+	if !ok {
+		stats = &ReorgStats{}
+		w.stats[network] = stats
+	}
+	update(stats)
+}
+
+func (w *reorgWatcher) snapshot() map[string]ReorgStats {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	snapshot := make(map[string]ReorgStats, len(w.stats))
+	for network, stats := range w.stats {
+		snapshot[network] = *stats
+	}
+	return snapshot
+}
+
+// close stops watching and waits for the watches to return
+func (w *reorgWatcher) close() {
+	w.stopOnce.Do(func() { close(w.stop) })
+	w.wg.Wait()
+}
+
+// watchTransaction polls a transaction until it is buried under depth blocks,
+// counting its own block as the first. It returns errTransactionReorged if the
+// transaction disappears after being mined and isn't back in the mempool, and
+// errTransactionNotMined if it isn't mined within timeout.
+func watchTransaction(
+	ctx context.Context,
+	stop <-chan struct{},
+	reader reorgReader,
+	txHash common.Hash,
+	depth uint64,
+	interval time.Duration,
+	timeout time.Duration,
+) error {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	mined := false
+	deadline := time.Now().Add(timeout)
+	for {
+		receipt, err := reader.TransactionReceipt(ctx, txHash)
+		switch {
+		case err == nil:
+			mined = true
+			head, err := reader.BlockNumber(ctx)
+			if err == nil && head+1 >= receipt.BlockNumber.Uint64()+depth {
+				return nil
+			}
+		case errors.Is(err, ethereum.NotFound) && mined:
+			// A transaction back in the mempool will be mined again
+			if _, pending, err := reader.TransactionByHash(ctx, txHash); err == nil && pending {
+				mined = false
+				deadline = time.Now().Add(timeout)
+				break
+			} else if err != nil && !errors.Is(err, ethereum.NotFound) {
+				break
+			}
+			return errTransactionReorged
+		case !mined && time.Now().After(deadline):
+			return errTransactionNotMined
+		}
+
+		select {
+		case <-stop:
+			return errReorgWatchingStopped
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+		}
+	}
+}
+
+// watchForReorg keeps watching a confirmed exact scheme settlement for
+// transaction.reorg_watch_blocks blocks in the background, then reports it
+// as settle.confirmed. A settlement dropped by a reorganization is reported
+// as reorged, and sent again while its authorization is still valid and unused.
+func (f *Facilitator) watchForReorg(ctx context.Context, client *ethclient.Client, auth *types.ExactEVMSchemeAuthorization, requirements types.PaymentRequirements, signatureHex string, txHash string) {
+	depth := uint64(f.cfg().Transaction.ReorgWatchBlocks)
+	if depth == 0 {
+		return
+	}
+
+	f.reorgs.wg.Add(1)
+	f.reorgs.record(requirements.Network, func(stats *ReorgStats) { stats.Watching++ })
+	go func() {
+		defer f.reorgs.wg.Done()
+		defer f.reorgs.record(requirements.Network, func(stats *ReorgStats) { stats.Watching-- })
+
+		ctx := context.WithoutCancel(ctx)
+		logger := f.log(ctx).With("network", requirements.Network, "payer", auth.From)
+		timeout := time.Duration(f.cfg().Transaction.TimeoutSeconds) * time.Second
+		for {
+			reader := replacementReader{Client: client, tracker: f.replacements}
+			err := watchTransaction(ctx, f.reorgs.stop, reader, common.HexToHash(txHash), depth, f.confirmationPollInterval, timeout)
+			if err == nil {
+				f.publishEvent(Event{
+					Type:        EventSettleConfirmed,
+					Scheme:      requirements.Scheme,
+					Network:     requirements.Network,
+					Asset:       requirements.Asset,
+					PayTo:       requirements.PayTo,
+					Payer:       auth.From,
+					Amount:      auth.Value,
+					Success:     true,
+					Transaction: txHash,
+				})
+				return
+			}
+			if !errors.Is(err, errTransactionReorged) {
+				if errors.Is(err, errTransactionNotMined) {
+					logger.Warn("stopped watching settlement, not mined", "transaction", txHash)
+				}
+				return
+			}
+
+			replacement, err := f.settlementReorged(ctx, client, auth, &requirements, signatureHex, txHash)
+			if err != nil {
+				logger.Error("settlement lost to reorg", "transaction", txHash, "error", err)
+				return
+			}
+			logger.Warn("settlement sent again after reorg", "transaction", txHash, "replacement", replacement)
+			txHash = replacement
+		}
+	}()
+}
+
+// settlementReorged reports a settlement transaction dropped by a
+// reorganization and sends the authorization again if it is still valid.
+// It returns the replacement transaction.
+func (f *Facilitator) settlementReorged(ctx context.Context, client *ethclient.Client, auth *types.ExactEVMSchemeAuthorization, requirements *types.PaymentRequirements, signatureHex string, txHash string) (string, error) {
+	f.reorgs.record(requirements.Network, func(stats *ReorgStats) { stats.Reorged++ })
+
+	// Publish and alert
+	event := Event{
+		Type:        EventSettleReorged,
+		Scheme:      requirements.Scheme,
+		Network:     requirements.Network,
+		Asset:       requirements.Asset,
+		PayTo:       requirements.PayTo,
+		Payer:       auth.From,
+		Amount:      auth.Value,
+		Transaction: txHash,
+	}
+	if f.alerts != nil {
+		go f.alerts.send(context.Background(), Alert{
+			Type:      AlertSettlementReorged,
+			Severity:  AlertSeverityWarning,
+			Network:   requirements.Network,
+			Message:   fmt.Sprintf("Settlement %s of %s was dropped by a chain reorganization", txHash, auth.From),
+			Timestamp: f.now().Unix(),
+		}, f.now())
+	}
+
+	lost := func(err error) (string, error) {
+		f.reorgs.record(requirements.Network, func(stats *ReorgStats) { stats.Lost++ })
+		event.Code = SettleErrTransactionReorged
+		event.Reason = err.Error()
+		f.publishEvent(event)
+		f.jobs.reorged(txHash, "", err)
+		return "", err
+	}
+
+	// Only send an authorization that can still be used
+	if f.now().Unix() >= auth.ValidBefore {
+		return lost(errors.New("authorization expired"))
+	}
+	if err := f.checkAuthorizationState(ctx, client, auth, requirements); err != nil {
+		return lost(err)
+	}
+
+	signer, release, err := f.signers.acquire(requirements.Network)
+	if err != nil {
+		return lost(err)
+	}
+	replacement, _, err := f.sendWithRetry(ctx, requirements.Network, func(attempt int) (string, error) {
+		return f.sendTransferWithAuthorization(ctx, client, signer, auth, requirements, signatureHex, attempt)
+	})
+	release()
+	if err != nil {
+		return lost(err)
+	}
+
+	f.reorgs.record(requirements.Network, func(stats *ReorgStats) { stats.Rebroadcast++ })
+	event.Success = true
+	event.Reason = "sent again as " + replacement
+	f.publishEvent(event)
+	f.jobs.reorged(txHash, replacement, nil)
+	return replacement, nil
+}
+
+// handleReorgMetrics returns the reorganization counts of each network
+func (f *Facilitator) handleReorgMetrics(ctx *gin.Context) {
+	ctx.JSON(http.StatusOK, f.reorgs.snapshot())
+}
@@ -0,0 +1,95 @@
+package facilitator
+
+import (
+	"context"
+	"errors"
+	"math/big"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/common"
+	ethtypes "github.com/ethereum/go-ethereum/core/types"
+	"github.com/vorpalengineering/x402-go/types"
+)
+
+// fakeReorgChain returns its receipts in turn, nil meaning not found, and
+// repeats the last one
+type fakeReorgChain struct {
+	receipts []*ethtypes.Receipt
+	pending  bool
+	head     uint64
+	calls    int
+}
+
+func (c *fakeReorgChain) TransactionReceipt(ctx context.Context, txHash common.Hash) (*ethtypes.Receipt, error) {
+	receipt := c.receipts[min(c.calls, len(c.receipts)-1)]
+	c.calls++
+	if receipt == nil {
+		return nil, ethereum.NotFound
+	}
+	return receipt, nil
+}
+
+func (c *fakeReorgChain) BlockNumber(ctx context.Context) (uint64, error) {
+	return c.head, nil
+}
+
+func (c *fakeReorgChain) TransactionByHash(ctx context.Context, txHash common.Hash) (*ethtypes.Transaction, bool, error) {
+	if !c.pending {
+		return nil, false, ethereum.NotFound
+	}
+	return ethtypes.NewTx(&ethtypes.LegacyTx{}), true, nil
+}
+
+func TestWatchTransaction(t *testing.T) {
+	mined := &ethtypes.Receipt{Status: ethtypes.ReceiptStatusSuccessful, BlockNumber: big.NewInt(10)}
+	remined := &ethtypes.Receipt{Status: ethtypes.ReceiptStatusSuccessful, BlockNumber: big.NewInt(1)}
+
+	tests := []struct {
+		name     string
+		chain    *fakeReorgChain
+		expected error
+	}{
+		{"buried", &fakeReorgChain{receipts: []*ethtypes.Receipt{mined}, head: 14}, nil},
+		{"dropped", &fakeReorgChain{receipts: []*ethtypes.Receipt{mined, nil}, head: 10}, errTransactionReorged},
+		{"back in mempool", &fakeReorgChain{receipts: []*ethtypes.Receipt{mined, nil, remined}, head: 10, pending: true}, nil},
+		{"never mined", &fakeReorgChain{receipts: []*ethtypes.Receipt{nil}}, errTransactionNotMined},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := watchTransaction(context.Background(), make(chan struct{}), tt.chain, common.Hash{}, 5, time.Millisecond, 20*time.Millisecond)
+			if !errors.Is(err, tt.expected) {
+				t.Errorf("Expected %v, got %v", tt.expected, err)
+			}
+		})
+	}
+}
+
+func TestSettleJobsReorged(t *testing.T) {
+	jobs := newSettleJobs(SettleJobsConfig{Workers: 1}, time.Now, func(ctx context.Context, req *types.SettleRequest) *types.SettleResponse {
+		return &types.SettleResponse{Success: true, Transaction: "0xold", Status: types.SettleStatusConfirmed}
+	})
+	defer jobs.close(context.Background())
+
+	job, _ := jobs.submit(context.Background(), types.SettleRequest{}, nil)
+	for snapshot, _ := jobs.get(job.ID); snapshot.Status == types.SettleJobPending; snapshot, _ = jobs.get(job.ID) {
+		time.Sleep(time.Millisecond)
+	}
+
+	// A replacement keeps the job confirmed with the new transaction
+	jobs.reorged("0xold", "0xnew", nil)
+	snapshot, _ := jobs.get(job.ID)
+	if snapshot.Status != types.SettleJobConfirmed || snapshot.Response.Transaction != "0xnew" || snapshot.Response.Status != types.SettleStatusReorged {
+		t.Errorf("Expected confirmed job with the replacement, got %+v %+v", snapshot, snapshot.Response)
+	}
+
+	// A lost settlement fails the job
+	jobs.reorged("0xnew", "", errors.New("authorization expired"))
+	snapshot, _ = jobs.get(job.ID)
+	if snapshot.Status != types.SettleJobFailed || !strings.HasPrefix(snapshot.Response.ErrorReason, SettleErrTransactionReorged) {
+		t.Errorf("Expected failed job, got %+v %+v", snapshot, snapshot.Response)
+	}
+}
@@ -0,0 +1,319 @@
+package facilitator
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/big"
+	"sync"
+	"time"
+
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/common"
+	ethtypes "github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/ethclient"
+)
+
+const (
+	// minReplacementBumpPercent is the lowest gas price increase nodes accept
+	// for a transaction replacing another with the same nonce
+	minReplacementBumpPercent = 10
+
+	// defaultReplacementCheckInterval is how often pending settlement
+	// transactions are checked
+	defaultReplacementCheckInterval = 5 * time.Second
+)
+
+// ReplacementConfig resubmits settlement transactions stuck in the mempool
+// with the same nonce and a higher gas price. Zero values disable it.
+type ReplacementConfig struct {
+	// StuckAfterSeconds is how long a transaction may stay pending before it
+	// is replaced, and again after every replacement
+	StuckAfterSeconds int `yaml:"stuck_after_seconds"`
+
+	// BumpPercent raises the gas price on every replacement. Defaults to and
+	// can't be below 10, the minimum nodes accept.
+	BumpPercent int `yaml:"bump_percent"`
+
+	// MaxTotalFee caps the gas price times gas limit of a replacement in wei.
+	// Replacements never exceed max_gas_price either.
+	MaxTotalFee string `yaml:"max_total_fee"`
+}
+
+func (replaceCfg ReplacementConfig) validate() error {
+	if replaceCfg.StuckAfterSeconds < 0 || replaceCfg.BumpPercent < 0 {
+		return fmt.Errorf("replacement settings cannot be negative")
+	}
+	if replaceCfg.BumpPercent > 0 && replaceCfg.BumpPercent < minReplacementBumpPercent {
+		return fmt.Errorf("replacement bump_percent must be at least %d, got %d", minReplacementBumpPercent, replaceCfg.BumpPercent)
+	}
+	if replaceCfg.MaxTotalFee != "" {
+		if fee, ok := new(big.Int).SetString(replaceCfg.MaxTotalFee, 10); !ok || fee.Sign() <= 0 {
+			return fmt.Errorf("invalid replacement max_total_fee: %s", replaceCfg.MaxTotalFee)
+		}
+	}
+	return nil
+}
+
+// enabled reports whether stuck transactions are replaced
+func (replaceCfg ReplacementConfig) enabled() bool {
+	return replaceCfg.StuckAfterSeconds > 0
+}
+
+// bumpPercent returns the gas price increase of a replacement
+func (replaceCfg ReplacementConfig) bumpPercent() int {
+	return max(replaceCfg.BumpPercent, minReplacementBumpPercent)
+}
+
+// pendingTx is a settlement transaction waiting to be mined, with every
+// transaction sent with its nonce
+type pendingTx struct {
+	network string
+	client  *ethclient.Client
+	signer  Signer
+	chainID *big.Int
+	tx      *ethtypes.Transaction
+	hashes  []common.Hash
+	sentAt  time.Time
+	// capped is set once the fee cap leaves no room for another replacement
+	capped bool
+}
+
+// replacementTracker keeps the settlement transactions sent but not yet mined
+type replacementTracker struct {
+	mu      sync.Mutex
+	pending []*pendingTx
+	byHash  map[common.Hash]*pendingTx
+}
+
+func newReplacementTracker() *replacementTracker {
+	return &replacementTracker{byHash: make(map[common.Hash]*pendingTx)}
+}
+
+// track starts watching a sent settlement transaction
+func (t *replacementTracker) track(network string, client *ethclient.Client, signer Signer, chainID *big.Int, tx *ethtypes.Transaction, now time.Time) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	p := &pendingTx{
+		network: network,
+		client:  client,
+		signer:  signer,
+		chainID: chainID,
+		tx:      tx,
+		hashes:  []common.Hash{tx.Hash()},
+		sentAt:  now,
+	}
+	t.pending = append(t.pending, p)
+	t.byHash[tx.Hash()] = p
+}
+
+// replaced records a transaction sent in place of the latest one of p
+func (t *replacementTracker) replaced(p *pendingTx, tx *ethtypes.Transaction, now time.Time) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	p.tx = tx
+	p.hashes = append(p.hashes, tx.Hash())
+	p.sentAt = now
+	t.byHash[tx.Hash()] = p
+}
+
+// remove forgets p once its nonce is mined
+func (t *replacementTracker) remove(p *pendingTx) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	for i, pending := range t.pending {
+		if pending == p {
+			t.pending = append(t.pending[:i], t.pending[i+1:]...)
+			break
+		}
+	}
+	for _, hash := range p.hashes {
+		delete(t.byHash, hash)
+	}
+}
+
+// list returns the pending transactions
+func (t *replacementTracker) list() []*pendingTx {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return append([]*pendingTx(nil), t.pending...)
+}
+
+// hashes returns every transaction sent with the nonce of txHash, newest
+// first, or only txHash if it isn't pending
+func (t *replacementTracker) hashes(txHash common.Hash) []common.Hash {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	p, ok := t.byHash[txHash]
+	if !ok {
+		return []common.Hash{txHash}
+	}
+	hashes := make([]common.Hash, len(p.hashes))
+	for i, hash := range p.hashes {
+		hashes[len(p.hashes)-1-i] = hash
+	}
+	return hashes
+}
+
+// replacementReader reads a transaction or any of its replacements, so a
+// settlement is confirmed by whichever of them is mined
+type replacementReader struct {
+	*ethclient.Client
+	tracker *replacementTracker
+}
+
+func (r replacementReader) TransactionReceipt(ctx context.Context, txHash common.Hash) (*ethtypes.Receipt, error) {
+	for _, hash := range r.tracker.hashes(txHash) {
+		receipt, err := r.Client.TransactionReceipt(ctx, hash)
+		if !errors.Is(err, ethereum.NotFound) {
+			return receipt, err
+		}
+	}
+	return nil, ethereum.NotFound
+}
+
+func (r replacementReader) TransactionByHash(ctx context.Context, txHash common.Hash) (*ethtypes.Transaction, bool, error) {
+	for _, hash := range r.tracker.hashes(txHash) {
+		tx, pending, err := r.Client.TransactionByHash(ctx, hash)
+		if !errors.Is(err, ethereum.NotFound) {
+			return tx, pending, err
+		}
+	}
+	return nil, false, ethereum.NotFound
+}
+
+// monitorStuckTransactions replaces stuck settlement transactions until ctx
+// is done
+func (f *Facilitator) monitorStuckTransactions(ctx context.Context) {
+	ticker := time.NewTicker(defaultReplacementCheckInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			f.replaceStuckTransactions(ctx)
+		}
+	}
+}
+
+// replaceStuckTransactions forgets the pending settlement transactions whose
+// nonce was mined, and replaces those pending for longer than
+// transaction.replacement.stuck_after_seconds
+func (f *Facilitator) replaceStuckTransactions(ctx context.Context) {
+	replaceCfg := f.cfg().Transaction.Replacement
+	stuckAfter := time.Duration(replaceCfg.StuckAfterSeconds) * time.Second
+	for _, p := range f.replacements.list() {
+		// Any transaction with the nonce being mined settles it
+		from := p.signer.Address()
+		nonce, err := p.client.NonceAt(ctx, from, nil)
+		if err != nil {
+			f.log(ctx).Warn("failed to check settlement transaction", "network", p.network, "transaction", p.tx.Hash().Hex(), "error", err)
+			continue
+		}
+		if nonce > p.tx.Nonce() {
+			f.replacements.remove(p)
+			continue
+		}
+
+		if p.capped || f.now().Sub(p.sentAt) < stuckAfter {
+			continue
+		}
+		if err := f.replaceTransaction(ctx, p, replaceCfg); err != nil {
+			f.log(ctx).Warn("failed to replace stuck settlement transaction", "network", p.network, "transaction", p.tx.Hash().Hex(), "error", err)
+		}
+	}
+}
+
+// replaceTransaction sends the latest transaction of p again with the same
+// nonce and a gas price raised by the bump percent, or to the current
+// suggestion if that is higher, within the fee caps
+func (f *Facilitator) replaceTransaction(ctx context.Context, p *pendingTx, replaceCfg ReplacementConfig) error {
+	previous := p.tx
+	gasPrice := new(big.Int).Mul(previous.GasPrice(), big.NewInt(int64(100+replaceCfg.bumpPercent())))
+	gasPrice.Div(gasPrice, big.NewInt(100))
+
+	// Keep up with the network if it moved further
+	suggested, err := p.client.SuggestGasPrice(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to get gas price: %w", err)
+	}
+	minGasPrice := new(big.Int).Set(gasPrice)
+	if suggested.Cmp(gasPrice) > 0 {
+		gasPrice = suggested
+	}
+
+	// Cap at max_gas_price and the total fee
+	maxGasPrice, ok := new(big.Int).SetString(f.cfg().Transaction.MaxGasPrice, 10)
+	if !ok {
+		return fmt.Errorf("failed to parse max gas price: %s", f.cfg().Transaction.MaxGasPrice)
+	}
+	if replaceCfg.MaxTotalFee != "" {
+		maxTotalFee, _ := new(big.Int).SetString(replaceCfg.MaxTotalFee, 10)
+		feeGasPrice := new(big.Int).Div(maxTotalFee, new(big.Int).SetUint64(previous.Gas()))
+		if feeGasPrice.Cmp(maxGasPrice) < 0 {
+			maxGasPrice = feeGasPrice
+		}
+	}
+	if minGasPrice.Cmp(maxGasPrice) > 0 {
+		p.capped = true
+		return fmt.Errorf("gas price %s wei of a replacement exceeds the cap of %s wei", minGasPrice, maxGasPrice)
+	}
+	if gasPrice.Cmp(maxGasPrice) > 0 {
+		gasPrice = maxGasPrice
+	}
+
+	// Sign and send with the same nonce
+	signedTx, err := p.signer.SignTx(ctx, withGasPrice(previous, p.chainID, gasPrice), p.chainID)
+	if err != nil {
+		return fmt.Errorf("failed to sign transaction: %w", err)
+	}
+	if err := p.client.SendTransaction(ctx, signedTx); err != nil {
+		// The nonce was mined meanwhile, the next check forgets it
+		if isNonceError(err) {
+			return nil
+		}
+		return fmt.Errorf("failed to send transaction: %w", err)
+	}
+	f.replacements.replaced(p, signedTx, f.now())
+
+	replacement := signedTx.Hash().Hex()
+	f.log(ctx).Warn("replaced stuck settlement transaction",
+		"network", p.network,
+		"transaction", previous.Hash().Hex(),
+		"replacement", replacement,
+		"gasPrice", gasPrice.String(),
+	)
+	f.publishEvent(Event{
+		Type:        EventSettleReplaced,
+		Network:     p.network,
+		Success:     true,
+		Reason:      fmt.Sprintf("replaced %s with gas price %s wei", previous.Hash().Hex(), gasPrice),
+		Transaction: replacement,
+	})
+	f.jobs.replaced(previous.Hash().Hex(), replacement)
+	return nil
+}
+
+// withGasPrice copies an unsigned legacy or access list transaction with a
+// new gas price
+func withGasPrice(tx *ethtypes.Transaction, chainID *big.Int, gasPrice *big.Int) *ethtypes.Transaction {
+	if tx.Type() == ethtypes.AccessListTxType {
+		return ethtypes.NewTx(&ethtypes.AccessListTx{
+			ChainID:    chainID,
+			Nonce:      tx.Nonce(),
+			GasPrice:   gasPrice,
+			Gas:        tx.Gas(),
+			To:         tx.To(),
+			Value:      tx.Value(),
+			Data:       tx.Data(),
+			AccessList: tx.AccessList(),
+		})
+	}
+	return ethtypes.NewTransaction(tx.Nonce(), *tx.To(), tx.Value(), tx.Gas(), gasPrice, tx.Data())
+}
@@ -0,0 +1,123 @@
+package facilitator
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	ethtypes "github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/ethclient"
+)
+
+func TestReplaceStuckTransactions(t *testing.T) {
+	// RPC that accepts every transaction and reports the signer's nonce
+	var mu sync.Mutex
+	var sent []*ethtypes.Transaction
+	nonce := "0x3"
+	rpcServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req struct {
+			ID     json.RawMessage   `json:"id"`
+			Method string            `json:"method"`
+			Params []json.RawMessage `json:"params"`
+		}
+		json.NewDecoder(r.Body).Decode(&req)
+		w.Header().Set("Content-Type", "application/json")
+		mu.Lock()
+		defer mu.Unlock()
+		switch req.Method {
+		case "eth_getTransactionCount":
+			fmt.Fprintf(w, `{"jsonrpc":"2.0","id":%s,"result":"%s"}`, req.ID, nonce)
+		case "eth_gasPrice":
+			fmt.Fprintf(w, `{"jsonrpc":"2.0","id":%s,"result":"0x64"}`, req.ID)
+		case "eth_sendRawTransaction":
+			var raw string
+			json.Unmarshal(req.Params[0], &raw)
+			tx := new(ethtypes.Transaction)
+			tx.UnmarshalBinary(hexutil.MustDecode(raw))
+			sent = append(sent, tx)
+			fmt.Fprintf(w, `{"jsonrpc":"2.0","id":%s,"result":"%s"}`, req.ID, tx.Hash().Hex())
+		default:
+			fmt.Fprintf(w, `{"jsonrpc":"2.0","id":%s,"result":"0x"}`, req.ID)
+		}
+	}))
+	defer rpcServer.Close()
+
+	f := NewFacilitator(&FacilitatorConfig{
+		Transaction: TransactionConfig{
+			MaxGasPrice: "1000",
+			Replacement: ReplacementConfig{StuckAfterSeconds: 60, MaxTotalFee: "12000000"},
+		},
+		Log: LogConfig{Level: "error"},
+	})
+	defer f.Close()
+	now := time.Unix(1700000000, 0)
+	f.now = func() time.Time { return now }
+
+	client, err := ethclient.Dial(rpcServer.URL)
+	if err != nil {
+		t.Fatalf("Failed to dial RPC: %v", err)
+	}
+	defer client.Close()
+
+	// A settlement sent with a gas price of 100 wei
+	key, _ := crypto.GenerateKey()
+	signer := NewPrivateKeySigner(key)
+	chainID := big.NewInt(8453)
+	to := common.HexToAddress("0x833589fCD6eDb6E08f4c7C32D4f71b54bdA02913")
+	original, err := signer.SignTx(context.Background(), ethtypes.NewTransaction(3, to, big.NewInt(0), 100000, big.NewInt(100), []byte{1}), chainID)
+	if err != nil {
+		t.Fatalf("Failed to sign transaction: %v", err)
+	}
+	f.replacements.track("eip155:8453", client, signer, chainID, original, now)
+	sentTxs := func() []*ethtypes.Transaction {
+		mu.Lock()
+		defer mu.Unlock()
+		return append([]*ethtypes.Transaction(nil), sent...)
+	}
+
+	// Not stuck yet
+	f.replaceStuckTransactions(context.Background())
+	if sent := sentTxs(); len(sent) != 0 {
+		t.Fatalf("Expected no replacement before stuck_after_seconds, got %d", len(sent))
+	}
+
+	// Stuck, replaced with the same nonce and a 10% higher gas price
+	now = now.Add(61 * time.Second)
+	f.replaceStuckTransactions(context.Background())
+	txs := sentTxs()
+	if len(txs) != 1 {
+		t.Fatalf("Expected one replacement, got %d", len(txs))
+	}
+	if txs[0].Nonce() != 3 || txs[0].GasPrice().Int64() != 110 || txs[0].Gas() != 100000 {
+		t.Errorf("Expected nonce 3 with gas price 110, got nonce %d with gas price %s", txs[0].Nonce(), txs[0].GasPrice())
+	}
+	hashes := f.replacements.hashes(original.Hash())
+	if len(hashes) != 2 || hashes[0] != txs[0].Hash() || hashes[1] != original.Hash() {
+		t.Errorf("Expected the replacement then the original, got %v", hashes)
+	}
+
+	// The next bump to 121 would exceed the total fee cap of 120 per gas
+	now = now.Add(61 * time.Second)
+	f.replaceStuckTransactions(context.Background())
+	if sent := sentTxs(); len(sent) != 1 {
+		t.Errorf("Expected no replacement above max_total_fee, got %d", len(sent))
+	}
+
+	// Forgotten once the nonce is mined
+	mu.Lock()
+	nonce = "0x4"
+	mu.Unlock()
+	f.replaceStuckTransactions(context.Background())
+	if pending := f.replacements.list(); len(pending) != 0 {
+		t.Errorf("Expected no pending transactions, got %d", len(pending))
+	}
+}
@@ -0,0 +1,263 @@
+package facilitator
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"reflect"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/crypto"
+)
+
+// ReplayResult compares a replayed exchange against its captured response
+type ReplayResult struct {
+	Exchange CapturedExchange
+	Status   int
+	Response json.RawMessage
+	Match    bool
+}
+
+// Replay re-runs captured exchanges against a facilitator built from config.
+// RPC calls are answered from the captured responses instead of live networks,
+// and the facilitator clock is pinned to each exchange's capture time.
+func Replay(config *FacilitatorConfig, exchanges []CapturedExchange) ([]ReplayResult, error) {
+	// Start mock RPC server
+	mock := &replayRPCServer{}
+	srv := httptest.NewServer(mock)
+	defer srv.Close()
+
+	// Point every network at the mock server and disable capture
+	replayConfig := *config
+	replayConfig.Capture = CaptureConfig{}
+	replayConfig.Networks = make(map[string]NetworkConfig, len(config.Networks))
+	for network, networkCfg := range config.Networks {
+		networkCfg.RpcUrl = srv.URL + "/" + url.PathEscape(network)
+		networkCfg.RpcUrls = nil
+		replayConfig.Networks[network] = networkCfg
+	}
+
+	f := NewFacilitator(&replayConfig)
+	defer f.Close()
+
+	results := make([]ReplayResult, 0, len(exchanges))
+	for _, exchange := range exchanges {
+		// Load captured RPC responses and pin clock for this exchange
+		if err := mock.load(exchange.RPCCalls); err != nil {
+			return nil, err
+		}
+		timestamp := exchange.Timestamp
+		f.now = func() time.Time { return timestamp }
+
+		// Replay request
+		req := httptest.NewRequest(http.MethodPost, exchange.Path, bytes.NewReader(exchange.Request))
+		req.Header.Set("Content-Type", "application/json")
+		recorder := httptest.NewRecorder()
+		f.router.ServeHTTP(recorder, req)
+
+		// Compare with replayed transaction hashes swapped for the captured ones
+		response := json.RawMessage(recorder.Body.Bytes())
+		mock.mu.Lock()
+		compared := json.RawMessage(mock.captured(string(response)))
+		mock.mu.Unlock()
+		results = append(results, ReplayResult{
+			Exchange: exchange,
+			Status:   recorder.Code,
+			Response: response,
+			Match:    recorder.Code == exchange.Status && jsonEqual(compared, exchange.Response),
+		})
+	}
+
+	return results, nil
+}
+
+type replayRPCRequest struct {
+	ID     json.RawMessage `json:"id"`
+	Method string          `json:"method"`
+	Params json.RawMessage `json:"params"`
+}
+
+type replayRPCResponse struct {
+	ID     json.RawMessage `json:"id,omitempty"`
+	Result json.RawMessage `json:"result,omitempty"`
+	Error  json.RawMessage `json:"error,omitempty"`
+}
+
+// replayRPCServer answers JSON-RPC calls from captured responses, matching on
+// network, method and params. Repeated identical calls are answered in order.
+//
+// Transactions are matched on network and method alone: the facilitator
+// signs them again on replay, so they differ from the captured ones whenever
+// the capture was anonymized. The replayed transaction's hash stands in for
+// the captured one in later calls and in the compared response.
+type replayRPCServer struct {
+	mu        sync.Mutex
+	responses map[string][]replayRPCResponse
+	txHashes  map[string]string
+}
+
+func (s *replayRPCServer) load(calls []CapturedRPCCall) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.responses = make(map[string][]replayRPCResponse)
+	s.txHashes = make(map[string]string)
+	for _, call := range calls {
+		var reqs []replayRPCRequest
+		if err := unmarshalRPCBatch(call.Request, &reqs); err != nil {
+			return fmt.Errorf("failed to parse captured rpc request: %w", err)
+		}
+		var resps []replayRPCResponse
+		if err := unmarshalRPCBatch(call.Response, &resps); err != nil {
+			return fmt.Errorf("failed to parse captured rpc response: %w", err)
+		}
+
+		// Pair batched requests with their responses by id
+		for i, req := range reqs {
+			var resp replayRPCResponse
+			for _, candidate := range resps {
+				if bytes.Equal(candidate.ID, req.ID) {
+					resp = candidate
+					break
+				}
+			}
+			if resp.ID == nil && i < len(resps) {
+				resp = resps[i]
+			}
+			key := replayKey(call.Network, &req)
+			s.responses[key] = append(s.responses[key], resp)
+		}
+	}
+	return nil
+}
+
+func (s *replayRPCServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	network, _ := url.PathUnescape(strings.TrimPrefix(r.URL.Path, "/"))
+
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	var reqs []replayRPCRequest
+	if err := unmarshalRPCBatch(body, &reqs); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	out := make([]map[string]any, len(reqs))
+	for i := range reqs {
+		out[i] = s.answer(network, &reqs[i])
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if bytes.HasPrefix(bytes.TrimSpace(body), []byte("[")) {
+		json.NewEncoder(w).Encode(out)
+		return
+	}
+	json.NewEncoder(w).Encode(out[0])
+}
+
+// answer pops the next captured response for a call
+func (s *replayRPCServer) answer(network string, req *replayRPCRequest) map[string]any {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	// Look the call up as captured
+	req.Params = json.RawMessage(s.captured(string(req.Params)))
+	key := replayKey(network, req)
+	queue := s.responses[key]
+	var resp replayRPCResponse
+	found := len(queue) > 0
+	if found {
+		resp = queue[0]
+		s.responses[key] = queue[1:]
+	}
+
+	out := map[string]any{
+		"jsonrpc": "2.0",
+		"id":      req.ID,
+	}
+	switch {
+	case !found:
+		out["error"] = map[string]any{
+			"code":    -32000,
+			"message": fmt.Sprintf("no captured response for %s", req.Method),
+		}
+	case resp.Error != nil:
+		out["error"] = resp.Error
+	case req.Method == "eth_sendRawTransaction":
+		// Answer with the replayed transaction's hash
+		var capturedHash string
+		var params []hexutil.Bytes
+		json.Unmarshal(resp.Result, &capturedHash)
+		if err := json.Unmarshal(req.Params, &params); err == nil && len(params) == 1 {
+			txHash := crypto.Keccak256Hash(params[0]).Hex()
+			s.txHashes[txHash] = capturedHash
+			out["result"] = txHash
+		} else {
+			out["result"] = resp.Result
+		}
+	default:
+		out["result"] = json.RawMessage(s.replayed(string(resp.Result)))
+	}
+	return out
+}
+
+// captured replaces replayed transaction hashes with the captured ones
+func (s *replayRPCServer) captured(text string) string {
+	for txHash, capturedHash := range s.txHashes {
+		text = strings.ReplaceAll(text, txHash, capturedHash)
+	}
+	return text
+}
+
+// replayed replaces captured transaction hashes with the replayed ones
+func (s *replayRPCServer) replayed(text string) string {
+	for txHash, capturedHash := range s.txHashes {
+		text = strings.ReplaceAll(text, capturedHash, txHash)
+	}
+	return text
+}
+
+// unmarshalRPCBatch reads a single JSON-RPC message or a batch of them
+func unmarshalRPCBatch[T any](data []byte, out *[]T) error {
+	if bytes.HasPrefix(bytes.TrimSpace(data), []byte("[")) {
+		return json.Unmarshal(data, out)
+	}
+	var single T
+	if err := json.Unmarshal(data, &single); err != nil {
+		return err
+	}
+	*out = []T{single}
+	return nil
+}
+
+func replayKey(network string, req *replayRPCRequest) string {
+	if req.Method == "eth_sendRawTransaction" {
+		return network + "|" + req.Method
+	}
+	var params bytes.Buffer
+	if err := json.Compact(&params, req.Params); err != nil {
+		params.Write(req.Params)
+	}
+	return network + "|" + req.Method + "|" + params.String()
+}
+
+func jsonEqual(a, b json.RawMessage) bool {
+	var va, vb any
+	if err := json.Unmarshal(a, &va); err != nil {
+		return false
+	}
+	if err := json.Unmarshal(b, &vb); err != nil {
+		return false
+	}
+	return reflect.DeepEqual(va, vb)
+}
@@ -0,0 +1,261 @@
+package facilitator
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/vorpalengineering/x402-go/types"
+	"github.com/vorpalengineering/x402-go/utils"
+)
+
+func TestCaptureAndReplay(t *testing.T) {
+	captureDir := t.TempDir()
+	testConfig := &FacilitatorConfig{
+		Networks: map[string]NetworkConfig{
+			"eip155:8453": {
+				RpcUrl: "https://mainnet.base.org",
+			},
+		},
+		Supported: []types.SupportedKind{
+			{Scheme: "exact", Network: "eip155:8453"},
+		},
+		Log: LogConfig{
+			Level: "info",
+		},
+		Capture: CaptureConfig{
+			Enabled: true,
+			Dir:     captureDir,
+		},
+	}
+
+	// Create facilitator with capture enabled
+	f := NewFacilitator(testConfig)
+	defer f.Close()
+
+	// Send a verify request for an unsupported network (no RPC needed)
+	body, _ := json.Marshal(types.VerifyRequest{
+		PaymentRequirements: types.PaymentRequirements{
+			Scheme:  "exact",
+			Network: "eip155:1",
+		},
+	})
+	req, _ := http.NewRequest("POST", "/verify", bytes.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	recorder := httptest.NewRecorder()
+	f.router.ServeHTTP(recorder, req)
+
+	if recorder.Code != http.StatusOK {
+		t.Fatalf("Expected status code %d, got %d", http.StatusOK, recorder.Code)
+	}
+
+	// Load captured exchanges
+	exchanges, err := LoadCapturedExchanges(captureDir)
+	if err != nil {
+		t.Fatalf("Failed to load captured exchanges: %v", err)
+	}
+	if len(exchanges) != 1 {
+		t.Fatalf("Expected 1 captured exchange, got %d", len(exchanges))
+	}
+	if exchanges[0].Path != "/verify" {
+		t.Errorf("Expected captured path /verify, got %s", exchanges[0].Path)
+	}
+
+	// Replay against a fresh facilitator
+	results, err := Replay(testConfig, exchanges)
+	if err != nil {
+		t.Fatalf("Failed to replay: %v", err)
+	}
+	if len(results) != 1 {
+		t.Fatalf("Expected 1 replay result, got %d", len(results))
+	}
+	if !results[0].Match {
+		t.Errorf("Expected replayed response to match, got %d %s", results[0].Status, results[0].Response)
+	}
+}
+
+// chainRPC answers the calls of a verify and settle on Base, singly or batched
+func chainRPC() *httptest.Server {
+	type rpcRequest struct {
+		ID     json.RawMessage   `json:"id"`
+		Method string            `json:"method"`
+		Params []json.RawMessage `json:"params"`
+	}
+	answer := func(req rpcRequest) string {
+		result := `"0x1"`
+		switch req.Method {
+		case "eth_call":
+			// authorizationState reports the nonce unused, balanceOf a
+			// large balance and the simulation succeeds
+			result = fmt.Sprintf(`"0x%064x"`, 1<<40)
+			if bytes.Contains(req.Params[0], []byte("0xe94a0102")) {
+				result = fmt.Sprintf(`"0x%064x"`, 0)
+			}
+		case "eth_getCode":
+			result = `"0x"`
+		case "eth_chainId":
+			result = `"0x2105"`
+		case "eth_getTransactionCount":
+			result = `"0x7"`
+		case "eth_estimateGas":
+			result = `"0x186a0"`
+		case "eth_sendRawTransaction":
+			var raw hexutil.Bytes
+			json.Unmarshal(req.Params[0], &raw)
+			result = `"` + crypto.Keccak256Hash(raw).Hex() + `"`
+		}
+		return fmt.Sprintf(`{"jsonrpc":"2.0","id":%s,"result":%s}`, req.ID, result)
+	}
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		w.Header().Set("Content-Type", "application/json")
+		var batch []rpcRequest
+		if json.Unmarshal(body, &batch) == nil {
+			responses := make([]string, len(batch))
+			for i, req := range batch {
+				responses[i] = answer(req)
+			}
+			fmt.Fprintf(w, "[%s]", strings.Join(responses, ","))
+			return
+		}
+		var req rpcRequest
+		json.Unmarshal(body, &req)
+		fmt.Fprint(w, answer(req))
+	}))
+}
+
+func TestCaptureAndReplayRPC(t *testing.T) {
+	rpcServer := chainRPC()
+	defer rpcServer.Close()
+
+	captureDir := t.TempDir()
+	signerKey, _ := crypto.HexToECDSA("ac0974bec39a17e36ba4a6b4d238ff944bacb478cbed5efcae784d7bf4f2ff80")
+	testConfig := &FacilitatorConfig{
+		Networks: map[string]NetworkConfig{
+			"eip155:8453": {RpcUrl: rpcServer.URL},
+		},
+		Supported: []types.SupportedKind{
+			{Scheme: "exact", Network: "eip155:8453"},
+		},
+		Transaction: TransactionConfig{TimeoutSeconds: 5, MaxGasPrice: "100000000000"},
+		Log:         LogConfig{Level: "error"},
+		Signer:      SignerConfig{PrivateKey: signerKey},
+		Capture: CaptureConfig{
+			Enabled: true,
+			Dir:     captureDir,
+		},
+	}
+	f := NewFacilitator(testConfig)
+	defer f.Close()
+
+	// Payment signed by a well-known development key
+	requirements := types.PaymentRequirements{
+		Scheme:            "exact",
+		Network:           "eip155:8453",
+		Amount:            "1000000",
+		PayTo:             "0x3C44CdDdB6a900fa2b585dd299e03d12FA4293BC",
+		Asset:             "0x833589fCD6eDb6E08f4c7C32D4f71b54bdA02913",
+		MaxTimeoutSeconds: 60,
+		Extra:             map[string]any{"name": "USD Coin", "version": "2"},
+	}
+	payerKey, _ := crypto.HexToECDSA("59c6995e998f97a5a0044966f0945389dc9e86dae88c7a8412f4603b6b78690d")
+	auth := &types.ExactEVMSchemeAuthorization{
+		From:        "0x70997970C51812dc3A010C7d01b50e0d17dc79C8",
+		To:          requirements.PayTo,
+		Value:       "1000000",
+		ValidBefore: 4102444800,
+		Nonce:       crypto.Keccak256Hash([]byte("capture")).Hex(),
+	}
+	signature, err := utils.SignEIP3009(auth, payerKey, requirements.Asset, "USD Coin", "2", 8453)
+	if err != nil {
+		t.Fatalf("Failed to sign authorization: %v", err)
+	}
+	body, _ := json.Marshal(types.SettleRequest{
+		PaymentPayload: types.PaymentPayload{
+			X402Version: 2,
+			Accepted:    requirements,
+			Payload: map[string]any{
+				"signature":     signature,
+				"authorization": auth,
+			},
+		},
+		PaymentRequirements: requirements,
+	})
+
+	// Verify then settle, both making RPC calls
+	var settleResp types.SettleResponse
+	for _, path := range []string{"/verify", "/settle"} {
+		req, _ := http.NewRequest("POST", path, bytes.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		recorder := httptest.NewRecorder()
+		f.router.ServeHTTP(recorder, req)
+		if recorder.Code != http.StatusOK || !strings.Contains(recorder.Body.String(), "true") {
+			t.Fatalf("Expected %s to succeed, got %d: %s", path, recorder.Code, recorder.Body.String())
+		}
+		if path == "/settle" {
+			json.Unmarshal(recorder.Body.Bytes(), &settleResp)
+		}
+	}
+
+	// Captures are private and hold none of the payment's identifiers
+	files, _ := filepath.Glob(filepath.Join(captureDir, "*.json"))
+	if len(files) != 2 {
+		t.Fatalf("Expected 2 capture files, got %d", len(files))
+	}
+	for _, file := range files {
+		info, err := os.Stat(file)
+		if err != nil {
+			t.Fatalf("Failed to stat capture: %v", err)
+		}
+		if mode := info.Mode().Perm(); mode != 0600 {
+			t.Errorf("Expected capture mode 0600, got %o", mode)
+		}
+		data, _ := os.ReadFile(file)
+		lower := strings.ToLower(string(data))
+		for name, value := range map[string]string{
+			"payer":       auth.From,
+			"payee":       auth.To,
+			"nonce":       auth.Nonce,
+			"signature":   signature,
+			"transaction": settleResp.Transaction,
+		} {
+			if strings.Contains(lower, strings.ToLower(strings.TrimPrefix(value, "0x"))) {
+				t.Errorf("Expected %s to be anonymized in %s", name, filepath.Base(file))
+			}
+		}
+	}
+
+	// Load captured exchanges, which include the RPC calls
+	exchanges, err := LoadCapturedExchanges(captureDir)
+	if err != nil {
+		t.Fatalf("Failed to load captured exchanges: %v", err)
+	}
+	for _, exchange := range exchanges {
+		if len(exchange.RPCCalls) == 0 {
+			t.Errorf("Expected RPC calls captured for %s", exchange.Path)
+		}
+	}
+
+	// The anonymized exchanges replay with the same results
+	results, err := Replay(testConfig, exchanges)
+	if err != nil {
+		t.Fatalf("Failed to replay: %v", err)
+	}
+	if len(results) != 2 {
+		t.Fatalf("Expected 2 replay results, got %d", len(results))
+	}
+	for _, result := range results {
+		if !result.Match {
+			t.Errorf("Expected replayed %s to match, got %d %s, captured %s", result.Exchange.Path, result.Status, result.Response, result.Exchange.Response)
+		}
+	}
+}
@@ -0,0 +1,114 @@
+package facilitator
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/big"
+	"strings"
+	"time"
+)
+
+const (
+	defaultRetryInitialBackoff = 500 * time.Millisecond
+	defaultRetryMaxBackoff     = 10 * time.Second
+)
+
+// RetryConfig retries settlements that failed before a transaction was
+// broadcast, such as RPC timeouts, used nonces or gas price spikes.
+// Zero values disable retries.
+type RetryConfig struct {
+	// MaxAttempts is the total number of attempts, including the first
+	MaxAttempts int `yaml:"max_attempts"`
+
+	// InitialBackoffMs is the wait after the first failure, doubled after each
+	// further one. Defaults to 500.
+	InitialBackoffMs int `yaml:"initial_backoff_ms"`
+
+	// MaxBackoffMs caps the wait between attempts. Defaults to 10000.
+	MaxBackoffMs int `yaml:"max_backoff_ms"`
+
+	// GasBumpPercent raises the gas price by this much on every retry, up to
+	// max_gas_price
+	GasBumpPercent int `yaml:"gas_bump_percent"`
+}
+
+func (retryCfg RetryConfig) validate() error {
+	if retryCfg.MaxAttempts < 0 || retryCfg.InitialBackoffMs < 0 || retryCfg.MaxBackoffMs < 0 || retryCfg.GasBumpPercent < 0 {
+		return fmt.Errorf("retry settings cannot be negative")
+	}
+	return nil
+}
+
+// attempts returns the attempt budget, at least one
+func (retryCfg RetryConfig) attempts() int {
+	if retryCfg.MaxAttempts < 1 {
+		return 1
+	}
+	return retryCfg.MaxAttempts
+}
+
+// backoff returns the wait after the given failed attempt (1 for the first)
+func (retryCfg RetryConfig) backoff(attempt int) time.Duration {
+	delay := defaultRetryInitialBackoff
+	if retryCfg.InitialBackoffMs > 0 {
+		delay = time.Duration(retryCfg.InitialBackoffMs) * time.Millisecond
+	}
+	maxDelay := defaultRetryMaxBackoff
+	if retryCfg.MaxBackoffMs > 0 {
+		maxDelay = time.Duration(retryCfg.MaxBackoffMs) * time.Millisecond
+	}
+	for i := 1; i < attempt && delay < maxDelay; i++ {
+		delay *= 2
+	}
+	return min(delay, maxDelay)
+}
+
+// bumpGasPrice raises the suggested gas price for the given attempt,
+// compounding GasBumpPercent per retry and capping at maxGasPrice
+func (retryCfg RetryConfig) bumpGasPrice(gasPrice, maxGasPrice *big.Int, attempt int) *big.Int {
+	bumped := new(big.Int).Set(gasPrice)
+	for i := 1; i < attempt && retryCfg.GasBumpPercent > 0; i++ {
+		bumped.Mul(bumped, big.NewInt(int64(100+retryCfg.GasBumpPercent)))
+		bumped.Div(bumped, big.NewInt(100))
+	}
+	if bumped.Cmp(maxGasPrice) > 0 {
+		bumped.Set(maxGasPrice)
+	}
+	return bumped
+}
+
+// isTransientSettleError reports whether a settlement that failed before
+// broadcasting may succeed if tried again
+func isTransientSettleError(err error) bool {
+	var sErr *settleError
+	if errors.As(err, &sErr) && sErr.code == SettleErrTransactionReverted {
+		return false
+	}
+	if errors.Is(err, context.DeadlineExceeded) {
+		return true
+	}
+
+	msg := strings.ToLower(err.Error())
+	for _, transient := range []string{
+		"timeout",
+		"connection refused",
+		"connection reset",
+		"eof",
+		"too many requests",
+		"429",
+		"502",
+		"503",
+		"504",
+		"nonce too low",
+		"underpriced",
+		"gas price too high",
+		"fee cap less than block base fee",
+		"max fee per gas less than block base fee",
+	} {
+		if strings.Contains(msg, transient) {
+			return true
+		}
+	}
+	return false
+}
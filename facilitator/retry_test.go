@@ -0,0 +1,72 @@
+package facilitator
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/big"
+	"testing"
+	"time"
+)
+
+func TestRetryBackoff(t *testing.T) {
+	retryCfg := RetryConfig{MaxAttempts: 5, InitialBackoffMs: 100, MaxBackoffMs: 300}
+	expected := []time.Duration{100 * time.Millisecond, 200 * time.Millisecond, 300 * time.Millisecond, 300 * time.Millisecond}
+	for i, want := range expected {
+		if got := retryCfg.backoff(i + 1); got != want {
+			t.Errorf("Expected backoff %v after attempt %d, got %v", want, i+1, got)
+		}
+	}
+
+	// Defaults apply when unset
+	if got := (RetryConfig{}).backoff(1); got != defaultRetryInitialBackoff {
+		t.Errorf("Expected default backoff %v, got %v", defaultRetryInitialBackoff, got)
+	}
+	if got := (RetryConfig{}).attempts(); got != 1 {
+		t.Errorf("Expected a single attempt when retries are disabled, got %d", got)
+	}
+	if err := (RetryConfig{MaxAttempts: -1}).validate(); err == nil {
+		t.Error("Expected error for negative max attempts")
+	}
+}
+
+func TestRetryBumpGasPrice(t *testing.T) {
+	retryCfg := RetryConfig{GasBumpPercent: 10}
+	maxGasPrice := big.NewInt(125)
+
+	if got := retryCfg.bumpGasPrice(big.NewInt(100), maxGasPrice, 1); got.Int64() != 100 {
+		t.Errorf("Expected first attempt to use the suggested price, got %s", got)
+	}
+	if got := retryCfg.bumpGasPrice(big.NewInt(100), maxGasPrice, 3); got.Int64() != 121 {
+		t.Errorf("Expected compounded bump to 121, got %s", got)
+	}
+	if got := retryCfg.bumpGasPrice(big.NewInt(100), maxGasPrice, 4); got.Int64() != 125 {
+		t.Errorf("Expected bump capped at 125, got %s", got)
+	}
+}
+
+func TestIsTransientSettleError(t *testing.T) {
+	transient := []error{
+		context.DeadlineExceeded,
+		fmt.Errorf("failed to get nonce: %w", errors.New("dial tcp 127.0.0.1:1: connect: connection refused")),
+		errors.New("failed to send transaction: nonce too low"),
+		errors.New("failed to send transaction: replacement transaction underpriced"),
+		errors.New("429 Too Many Requests"),
+	}
+	for _, err := range transient {
+		if !isTransientSettleError(err) {
+			t.Errorf("Expected %q to be transient", err)
+		}
+	}
+
+	permanent := []error{
+		&settleError{code: SettleErrTransactionReverted, err: errors.New("execution timeout")},
+		errors.New("failed to sign transaction: invalid key"),
+		errors.New("insufficient funds for gas * price + value"),
+	}
+	for _, err := range permanent {
+		if isTransientSettleError(err) {
+			t.Errorf("Expected %q not to be transient", err)
+		}
+	}
+}
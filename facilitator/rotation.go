@@ -0,0 +1,394 @@
+package facilitator
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"maps"
+	"net/http"
+	"slices"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/gin-gonic/gin"
+	"github.com/vorpalengineering/x402-go/utils"
+)
+
+// Signer states reported at /admin/signers
+const (
+	SignerStateActive   = "active"
+	SignerStateDraining = "draining"
+	SignerStateRetired  = "retired"
+)
+
+const (
+	// defaultSignerNetwork keys the top-level signer used by networks without their own
+	defaultSignerNetwork = "*"
+
+	defaultSignerDrainInterval = 5 * time.Second
+)
+
+// SignerStatus is one signer as reported at /admin/signers
+type SignerStatus struct {
+	// Network is "*" for the top-level signer
+	Network  string    `json:"network"`
+	Address  string    `json:"address"`
+	State    string    `json:"state"`
+	InFlight int       `json:"inFlight"`
+	Since    time.Time `json:"since"`
+}
+
+// RotateSignerRequest selects the new key for a network, or for the top-level
+// signer when Network is empty. Keys are referenced, never sent.
+type RotateSignerRequest struct {
+	Network       string `json:"network"`
+	PrivateKeyEnv string `json:"privateKeyEnv"`
+	KeystoreFile  string `json:"keystoreFile"`
+	KeyFile       string `json:"keyFile"`
+	PassphraseEnv string `json:"passphraseEnv"`
+	KMSKeyID      string `json:"kmsKeyId"`
+	KMSRegion     string `json:"kmsRegion"`
+}
+
+type signerSlot struct {
+	network  string
+	signer   Signer
+	state    string
+	since    time.Time
+	inFlight int
+
+	// networks whose pending transactions must clear before retiring
+	networks []string
+}
+
+// signerRegistry holds the active signer of each network. Rotation swaps in a
+// new signer at once and keeps the old one draining until its in-flight
+// settlements finish and its pending transactions are mined.
+type signerRegistry struct {
+	now func() time.Time
+
+	mu       sync.Mutex
+	active   map[string]*signerSlot
+	retiring []*signerSlot
+
+	stop     chan struct{}
+	stopOnce sync.Once
+}
+
+func newSignerRegistry(config *FacilitatorConfig, now func() time.Time) *signerRegistry {
+	r := &signerRegistry{
+		now:    now,
+		active: make(map[string]*signerSlot),
+		stop:   make(chan struct{}),
+	}
+	if signer := config.Signer.signer(); signer != nil {
+		r.active[defaultSignerNetwork] = r.slot(defaultSignerNetwork, signer)
+	}
+	for network, networkCfg := range config.Networks {
+		if networkCfg.Signer == nil {
+			continue
+		}
+		if signer := networkCfg.Signer.signer(); signer != nil {
+			r.active[network] = r.slot(network, signer)
+		}
+	}
+	return r
+}
+
+// signer returns the Signer for a loaded config, or nil
+func (signerCfg *SignerConfig) signer() Signer {
+	if signerCfg.Backend != nil {
+		return signerCfg.Backend
+	}
+	if signerCfg.PrivateKey != nil {
+		return &privateKeySigner{key: signerCfg.PrivateKey, owned: signerCfg.ownsKey}
+	}
+	return nil
+}
+
+func (r *signerRegistry) slot(network string, signer Signer) *signerSlot {
+	return &signerSlot{network: network, signer: signer, state: SignerStateActive, since: r.now()}
+}
+
+// lookup returns the active slot for a network. Callers must hold mu.
+func (r *signerRegistry) lookup(network string) *signerSlot {
+	if slot, ok := r.active[network]; ok {
+		return slot
+	}
+	return r.active[defaultSignerNetwork]
+}
+
+// acquire returns the signer for a network, counted as in flight until release is called
+func (r *signerRegistry) acquire(network string) (Signer, func(), error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	slot := r.lookup(network)
+	if slot == nil {
+		return nil, nil, fmt.Errorf("no signer configured for %s", network)
+	}
+	slot.inFlight++
+
+	var once sync.Once
+	release := func() {
+		once.Do(func() {
+			r.mu.Lock()
+			slot.inFlight--
+			r.mu.Unlock()
+		})
+	}
+	return slot.signer, release, nil
+}
+
+// address returns the address that settles on a network
+func (r *signerRegistry) address(network string) (common.Address, bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	slot := r.lookup(network)
+	if slot == nil {
+		return common.Address{}, false
+	}
+	return slot.signer.Address(), true
+}
+
+// signers returns the active signers by network
+func (r *signerRegistry) signers() map[string]Signer {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	signers := make(map[string]Signer, len(r.active))
+	for network, slot := range r.active {
+		signers[network] = slot.signer
+	}
+	return signers
+}
+
+// rotate makes signer active for network and returns the slot it replaced,
+// now draining. A network on the top-level signer gets its own slot and
+// nothing is drained.
+func (r *signerRegistry) rotate(network string, signer Signer, networks []string) (*signerSlot, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	current := r.lookup(network)
+	if current != nil && current.signer.Address() == signer.Address() {
+		return nil, errors.New("new signer has the same address as the current one")
+	}
+	r.active[network] = r.slot(network, signer)
+
+	// Only drain a slot that no longer settles anywhere
+	if current == nil || current.network != network {
+		return nil, nil
+	}
+	current.state = SignerStateDraining
+	current.since = r.now()
+	current.networks = networks
+	r.retiring = append(r.retiring, current)
+	return current, nil
+}
+
+// drained reports whether a draining slot has no settlements in flight
+func (r *signerRegistry) drained(slot *signerSlot) bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return slot.inFlight == 0
+}
+
+func (r *signerRegistry) retire(slot *signerSlot) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	slot.state = SignerStateRetired
+	slot.since = r.now()
+}
+
+// status returns active signers followed by draining and retired ones
+func (r *signerRegistry) status() []SignerStatus {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	statuses := make([]SignerStatus, 0, len(r.active)+len(r.retiring))
+	for _, slot := range r.active {
+		statuses = append(statuses, slot.status())
+	}
+	sort.Slice(statuses, func(i, j int) bool {
+		return statuses[i].Network < statuses[j].Network
+	})
+	for _, slot := range r.retiring {
+		statuses = append(statuses, slot.status())
+	}
+	return statuses
+}
+
+func (slot *signerSlot) status() SignerStatus {
+	return SignerStatus{
+		Network:  slot.network,
+		Address:  slot.signer.Address().Hex(),
+		State:    slot.state,
+		InFlight: slot.inFlight,
+		Since:    slot.since,
+	}
+}
+
+// close stops draining and zeroes the keys the facilitator loaded
+func (r *signerRegistry) close() {
+	r.stopOnce.Do(func() {
+		close(r.stop)
+	})
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	slots := append(slices.Collect(maps.Values(r.active)), r.retiring...)
+	for _, slot := range slots {
+		if s, ok := slot.signer.(*privateKeySigner); ok {
+			s.wipe()
+		}
+	}
+}
+
+// rotateSigner switches a network, or the top-level signer when network is
+// empty, to a new signer and drains the old one in the background
+func (f *Facilitator) rotateSigner(network string, signer Signer) error {
+	// Networks settling with the replaced signer
+	var networks []string
+	key := network
+	if network == "" {
+		key = defaultSignerNetwork
+		active := f.signers.signers()
+		for configured := range f.cfg().Networks {
+			if _, ok := active[configured]; !ok && !utils.IsSolanaNetwork(configured) {
+				networks = append(networks, configured)
+			}
+		}
+	} else {
+		networks = []string{network}
+	}
+
+	old, err := f.signers.rotate(key, signer, networks)
+	if err != nil {
+		return err
+	}
+	f.logger.Info("signer rotated", "network", key, "address", signer.Address().Hex())
+	if old != nil {
+		f.logger.Info("draining signer", "network", key, "address", old.signer.Address().Hex())
+		go f.drainSigner(old)
+	}
+	return nil
+}
+
+// drainSigner retires a slot once its in-flight settlements finished and none
+// of its transactions are pending
+func (f *Facilitator) drainSigner(slot *signerSlot) {
+	ticker := time.NewTicker(f.signerDrainInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-f.signers.stop:
+			return
+		case <-ticker.C:
+		}
+		if !f.signers.drained(slot) {
+			continue
+		}
+		pending, err := f.pendingTransactions(slot)
+		if err != nil {
+			f.logger.Warn("failed to check pending transactions of draining signer", "address", slot.signer.Address().Hex(), "error", err)
+			continue
+		}
+		if pending > 0 {
+			continue
+		}
+		f.signers.retire(slot)
+		f.logger.Info("signer retired", "network", slot.network, "address", slot.signer.Address().Hex())
+		return
+	}
+}
+
+// pendingTransactions counts transactions of a signer not yet mined on its networks
+func (f *Facilitator) pendingTransactions(slot *signerSlot) (uint64, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), healthCheckTimeout)
+	defer cancel()
+
+	address := slot.signer.Address()
+	var total uint64
+	for _, network := range slot.networks {
+		client, err := f.getRPCClient(network)
+		if err != nil {
+			return 0, err
+		}
+		confirmed, err := client.NonceAt(ctx, address, nil)
+		if err != nil {
+			return 0, fmt.Errorf("failed to get nonce on %s: %w", network, err)
+		}
+		pending, err := client.PendingNonceAt(ctx, address)
+		if err != nil {
+			return 0, fmt.Errorf("failed to get pending nonce on %s: %w", network, err)
+		}
+		if pending > confirmed {
+			total += pending - confirmed
+		}
+	}
+	return total, nil
+}
+
+func (f *Facilitator) handleSigners(ctx *gin.Context) {
+	ctx.JSON(http.StatusOK, f.signers.status())
+}
+
+func (f *Facilitator) handleRotateSigner(ginCtx *gin.Context) {
+	// Decode request
+	var req RotateSignerRequest
+	if err := ginCtx.ShouldBindJSON(&req); err != nil {
+		ginCtx.JSON(http.StatusBadRequest, gin.H{
+			"error": err.Error(),
+		})
+		return
+	}
+
+	// Check network is a configured EVM network
+	if req.Network != "" {
+		if _, err := f.cfg().GetNetworkConfig(req.Network); err != nil {
+			ginCtx.JSON(http.StatusBadRequest, gin.H{
+				"error": err.Error(),
+			})
+			return
+		}
+		if utils.IsSolanaNetwork(req.Network) {
+			ginCtx.JSON(http.StatusBadRequest, gin.H{
+				"error": "solana fee payers cannot be rotated",
+			})
+			return
+		}
+	}
+
+	// Load the new key
+	signerCfg := SignerConfig{
+		PrivateKeyEnv: req.PrivateKeyEnv,
+		KeystoreFile:  req.KeystoreFile,
+		KeyFile:       req.KeyFile,
+		PassphraseEnv: req.PassphraseEnv,
+		KMS: KMSConfig{
+			KeyID:  req.KMSKeyID,
+			Region: req.KMSRegion,
+		},
+		// Nobody is at the terminal to answer a prompt
+		noPrompt: true,
+	}
+	if err := signerCfg.load(""); err != nil {
+		ginCtx.JSON(http.StatusBadRequest, gin.H{
+			"error": fmt.Sprintf("failed to load signer: %v", err),
+		})
+		return
+	}
+
+	if err := f.rotateSigner(req.Network, signerCfg.signer()); err != nil {
+		ginCtx.JSON(http.StatusConflict, gin.H{
+			"error": err.Error(),
+		})
+		return
+	}
+	ginCtx.JSON(http.StatusOK, f.signers.status())
+}
@@ -0,0 +1,139 @@
+package facilitator
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/vorpalengineering/x402-go/types"
+)
+
+func TestSignerRotation(t *testing.T) {
+	// RPC reporting nonce 5 mined and a configurable pending nonce
+	var pendingNonce atomic.Int64
+	pendingNonce.Store(6)
+	rpcServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req struct {
+			ID     json.RawMessage `json:"id"`
+			Method string          `json:"method"`
+			Params []any           `json:"params"`
+		}
+		json.NewDecoder(r.Body).Decode(&req)
+		nonce := int64(5)
+		if req.Method == "eth_getTransactionCount" && req.Params[1] == "pending" {
+			nonce = pendingNonce.Load()
+		}
+		w.Header().Set("Content-Type", "application/json")
+		fmt.Fprintf(w, `{"jsonrpc":"2.0","id":%s,"result":"0x%x"}`, req.ID, nonce)
+	}))
+	defer rpcServer.Close()
+
+	defaultKey, _ := crypto.HexToECDSA("ac0974bec39a17e36ba4a6b4d238ff944bacb478cbed5efcae784d7bf4f2ff80")
+	mainnetKey, _ := crypto.HexToECDSA("59c6995e998f97a5a0044966f0945389dc9e86dae88c7a8412f4603b6b78690d")
+	f := NewFacilitator(&FacilitatorConfig{
+		Networks: map[string]NetworkConfig{
+			"eip155:8453": {RpcUrl: rpcServer.URL},
+			"eip155:1": {
+				RpcUrl: rpcServer.URL,
+				Signer: &SignerConfig{PrivateKey: mainnetKey},
+			},
+		},
+		Log:    LogConfig{Level: "error"},
+		Admin:  AdminConfig{Enabled: true, Token: "secret"},
+		Signer: SignerConfig{PrivateKey: defaultKey},
+	})
+	defer f.Close()
+	f.signerDrainInterval = 10 * time.Millisecond
+
+	admin := func(method, path string, body any) *httptest.ResponseRecorder {
+		data, _ := json.Marshal(body)
+		req := httptest.NewRequest(method, path, bytes.NewReader(data))
+		req.Header.Set("Authorization", "Bearer secret")
+		recorder := httptest.NewRecorder()
+		f.router.ServeHTTP(recorder, req)
+		return recorder
+	}
+
+	// Admin routes require the token
+	recorder := httptest.NewRecorder()
+	f.router.ServeHTTP(recorder, httptest.NewRequest("GET", "/admin/signers", nil))
+	if recorder.Code != http.StatusUnauthorized {
+		t.Errorf("Expected status 401 without token, got %d", recorder.Code)
+	}
+
+	// Each network settles with its own signer
+	recorder = httptest.NewRecorder()
+	f.router.ServeHTTP(recorder, httptest.NewRequest("GET", "/supported", nil))
+	var supported types.SupportedResponse
+	json.Unmarshal(recorder.Body.Bytes(), &supported)
+	if supported.Signers["eip155:*"][0] != crypto.PubkeyToAddress(defaultKey.PublicKey).Hex() {
+		t.Errorf("Expected default signer for eip155:*, got %v", supported.Signers["eip155:*"])
+	}
+	if supported.Signers["eip155:1"][0] != crypto.PubkeyToAddress(mainnetKey.PublicKey).Hex() {
+		t.Errorf("Expected mainnet signer for eip155:1, got %v", supported.Signers["eip155:1"])
+	}
+
+	// Rotating to the same key is rejected
+	t.Setenv("TEST_NEW_SIGNER_KEY", "0x59c6995e998f97a5a0044966f0945389dc9e86dae88c7a8412f4603b6b78690d")
+	recorder = admin("POST", "/admin/signers/rotate", RotateSignerRequest{Network: "eip155:1", PrivateKeyEnv: "TEST_NEW_SIGNER_KEY"})
+	if recorder.Code != http.StatusConflict {
+		t.Errorf("Expected status 409 for same key, got %d", recorder.Code)
+	}
+
+	// Rotate the default signer while a settlement is in flight
+	_, release, err := f.signers.acquire("eip155:8453")
+	if err != nil {
+		t.Fatalf("Failed to acquire signer: %v", err)
+	}
+	t.Setenv("TEST_NEW_SIGNER_KEY", "0x5de4111afa1a4b94908f83103eb1f1706367c2e68ca870fc3fb9a804cdab365a")
+	recorder = admin("POST", "/admin/signers/rotate", RotateSignerRequest{PrivateKeyEnv: "TEST_NEW_SIGNER_KEY"})
+	if recorder.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d: %s", recorder.Code, recorder.Body.String())
+	}
+	newAddress, _ := f.signers.address("eip155:8453")
+	if newAddress.Hex() != "0x3C44CdDdB6a900fa2b585dd299e03d12FA4293BC" {
+		t.Errorf("Expected new settlements to use the new signer, got %s", newAddress.Hex())
+	}
+	if mainnetAddress, _ := f.signers.address("eip155:1"); mainnetAddress != crypto.PubkeyToAddress(mainnetKey.PublicKey) {
+		t.Errorf("Expected eip155:1 to keep its signer, got %s", mainnetAddress.Hex())
+	}
+
+	oldState := func() string {
+		var statuses []SignerStatus
+		json.Unmarshal(admin("GET", "/admin/signers", nil).Body.Bytes(), &statuses)
+		for _, status := range statuses {
+			if status.Address == crypto.PubkeyToAddress(defaultKey.PublicKey).Hex() {
+				return status.State
+			}
+		}
+		return ""
+	}
+	waitForState := func(state string) {
+		deadline := time.Now().Add(2 * time.Second)
+		for oldState() != state && time.Now().Before(deadline) {
+			time.Sleep(10 * time.Millisecond)
+		}
+	}
+
+	// Old signer drains until the settlement finishes and its transactions are mined
+	time.Sleep(50 * time.Millisecond)
+	if state := oldState(); state != SignerStateDraining {
+		t.Errorf("Expected old signer to be draining, got %s", state)
+	}
+	release()
+	time.Sleep(50 * time.Millisecond)
+	if state := oldState(); state != SignerStateDraining {
+		t.Errorf("Expected old signer to wait for pending transactions, got %s", state)
+	}
+	pendingNonce.Store(5)
+	waitForState(SignerStateRetired)
+	if state := oldState(); state != SignerStateRetired {
+		t.Errorf("Expected old signer to be retired, got %s", state)
+	}
+}
@@ -2,9 +2,11 @@ package facilitator
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"math/big"
 	"strings"
+	"time"
 
 	"github.com/ethereum/go-ethereum"
 	"github.com/ethereum/go-ethereum/accounts/abi"
@@ -15,16 +17,73 @@ import (
 	"github.com/vorpalengineering/x402-go/utils"
 )
 
-func (f *Facilitator) settlePayment(ctx context.Context, payload *types.PaymentPayload, requirements *types.PaymentRequirements) *types.SettleResponse {
+// Settlement error codes of SettleResponse.ErrorReason, so operators can
+// distinguish gas estimation problems from genuine reverts
+const (
+	SettleErrGasEstimationFailed = types.ErrCodeGasEstimationFailed
+	SettleErrTransactionReverted = types.ErrCodeTransactionReverted
+	SettleErrNotConfirmed        = types.ErrCodeNotConfirmed
+	SettleErrAuthorizationUsed   = types.ErrCodeNonceUsed
+	SettleErrTransactionReorged  = types.ErrCodeTransactionReorged
+	SettleErrSettlementRace      = types.ErrCodeSettlementRace
+)
+
+// settleError attaches a settlement error code to an underlying error
+type settleError struct {
+	code string
+	err  error
+}
+
+func (e *settleError) Error() string {
+	return fmt.Sprintf("%s: %v", e.code, e.err)
+}
+
+func (e *settleError) Unwrap() error {
+	return e.err
+}
+
+// fail marks resp as failed with the error's code and message
+func (e *settleError) fail(resp *types.SettleResponse) {
+	resp.Success = false
+	resp.ErrorReason = e.code
+	resp.ErrorMessage = e.err.Error()
+}
+
+// settleFailed is the response to a settlement that failed with code
+func settleFailed(code, message string) *types.SettleResponse {
+	return &types.SettleResponse{
+		Success:      false,
+		ErrorReason:  code,
+		ErrorMessage: message,
+	}
+}
+
+func (f *Facilitator) settlePayment(ctx context.Context, req *types.SettleRequest) *types.SettleResponse {
+	payload, requirements := &req.PaymentPayload, &req.PaymentRequirements
+
+	// Wait for a worker of the network
+	done, err := f.acquireSettleWorker(ctx, requirements.Network)
+	if err != nil {
+		return settleFailure(err, 0)
+	}
+	defer done()
+
+	// Bound the RPC calls of the settlement, background work after it
+	// outlives the deadline
+	ctx, cancel := f.withTransactionTimeout(ctx)
+	defer cancel()
+
 	// Settle based on scheme
 	switch payload.Accepted.Scheme {
 	case "exact":
+		if utils.IsSolanaNetwork(requirements.Network) {
+			return f.settleExactSVMScheme(ctx, payload, requirements)
+		}
 		return f.settleExactScheme(ctx, payload, requirements)
+	case "upto":
+		return f.settleUptoScheme(ctx, req)
 	default:
-		return &types.SettleResponse{
-			Success:     false,
-			ErrorReason: fmt.Sprintf("unsupported scheme: %s", payload.Accepted.Scheme),
-		}
+		return settleFailed(types.ErrCodeUnsupportedScheme, fmt.Sprintf("unsupported scheme: %s", payload.Accepted.Scheme))
 	}
 }
 
@@ -32,67 +91,215 @@ func (f *Facilitator) settleExactScheme(ctx context.Context, payload *types.Paym
 	// Extract signature from payload
 	signatureHex, ok := payload.Payload["signature"].(string)
 	if !ok || signatureHex == "" {
-		return &types.SettleResponse{
-			Success:     false,
-			ErrorReason: "missing signature",
-		}
+		return settleFailed(types.ErrCodeInvalidSignature, "missing signature")
 	}
 
 	// Extract authorization from payload
 	auth, err := utils.ExtractExactAuthorization(payload)
 	if err != nil {
-		return &types.SettleResponse{
-			Success:     false,
-			ErrorReason: fmt.Sprintf("invalid authorization: %v", err),
-		}
+		return settleFailed(types.ErrCodeInvalidPayload, fmt.Sprintf("invalid authorization: %v", err))
 	}
 
 	// Get RPC client
 	client, err := f.getRPCClient(requirements.Network)
 	if err != nil {
-		return &types.SettleResponse{
-			Success:     false,
-			ErrorReason: fmt.Sprintf("failed to connect to network: %v", err),
-		}
+		return settleFailed(types.ErrCodeNetworkError, fmt.Sprintf("failed to connect to network: %v", err))
+	}
+
+	// Don't burn gas on an authorization the token already consumed
+	if err := f.checkAuthorizationState(ctx, client, auth, requirements); err != nil {
+		return settleFailure(err, 0)
+	}
+
+	// Nor on one a pending transaction is about to consume
+	if err := f.checkMempoolConflict(ctx, client, auth, requirements); err != nil {
+		return settleFailure(err, 0)
+	}
+
+	// Get the network's signer, tracked as in flight while sending
+	signer, release, err := f.signers.acquire(requirements.Network)
+	if err != nil {
+		return settleFailed(types.ErrCodeSettlementFailed, err.Error())
 	}
 
 	// Build and send the transaction
-	txHash, err := f.sendTransferWithAuthorization(ctx, client, auth, requirements, signatureHex)
+	txHash, attempts, err := f.sendWithRetry(ctx, requirements.Network, func(attempt int) (string, error) {
+		return f.sendTransferWithAuthorization(ctx, client, signer, auth, requirements, signatureHex, attempt)
+	})
+	release()
 	if err != nil {
-		return &types.SettleResponse{
-			Success:     false,
-			ErrorReason: fmt.Sprintf("failed to settle payment: %v", err),
-		}
+		return settleFailure(err, attempts)
 	}
 
-	// Return success response
-	return &types.SettleResponse{
+	resp := &types.SettleResponse{
 		Success:     true,
 		Transaction: txHash,
 		Network:     requirements.Network,
 		Payer:       auth.From,
+		Attempts:    attempts,
+	}
+	if f.cfg().Transaction.Confirmations > 0 {
+		f.confirmSettlement(ctx, client, txHash, resp)
+	}
+
+	// Collect the facilitator fee from payTo and watch for reorgs in the background
+	if resp.Success {
+		f.sweepFeeAfter(ctx, client, *requirements, txHash)
+		f.watchForReorg(ctx, client, auth, *requirements, signatureHex, txHash)
+	}
+	return resp
+}
+
+// sendWithRetry calls send until it succeeds, retrying transient failures
+// with backoff. It returns the number of attempts when retries are enabled.
+func (f *Facilitator) sendWithRetry(ctx context.Context, network string, send func(attempt int) (string, error)) (string, int, error) {
+	retryCfg := f.cfg().Transaction.Retry
+	var txHash string
+	var err error
+	attempts := 0
+	for {
+		attempts++
+		txHash, err = send(attempts)
+		if err == nil || attempts >= retryCfg.attempts() || !isTransientSettleError(err) {
+			break
+		}
+		backoff := retryCfg.backoff(attempts)
+		f.log(ctx).Warn("settlement attempt failed, retrying",
+			"network", network, "attempt", attempts, "backoff", backoff, "error", err)
+		if !sleepContext(ctx, backoff) {
+			break
+		}
+	}
+	if retryCfg.attempts() == 1 {
+		attempts = 0
+	}
+	return txHash, attempts, err
+}
+
+// settleFailure is the response to a settlement transaction that wasn't sent
+func settleFailure(err error, attempts int) *types.SettleResponse {
+	resp := settleFailed(types.ErrCodeSettlementFailed, fmt.Sprintf("failed to settle payment: %v", err))
+	resp.Attempts = attempts
+	var sErr *settleError
+	if errors.As(err, &sErr) {
+		sErr.fail(resp)
+	}
+	return resp
+}
+
+// withTransactionTimeout bounds the RPC calls of a verification or settlement
+// by transaction.timeout_seconds on top of ctx, so a slow RPC endpoint can't
+// hang the request. An unset timeout leaves ctx as is.
+func (f *Facilitator) withTransactionTimeout(ctx context.Context) (context.Context, context.CancelFunc) {
+	timeout := f.cfg().Transaction.TimeoutSeconds
+	if timeout <= 0 {
+		return context.WithCancel(ctx)
+	}
+	return context.WithTimeout(ctx, time.Duration(timeout)*time.Second)
+}
+
+// confirmSettlement waits for the receipt and confirmations of a settlement
+// transaction within the transaction timeout, and fills in the outcome of
+// every response settled by it. The wait gets a timeout of its own rather
+// than what is left of ctx, whose deadline also covered sending the
+// transaction, so a mined transaction isn't reported unconfirmed.
+func (f *Facilitator) confirmSettlement(ctx context.Context, client *ethclient.Client, txHash string, resps ...*types.SettleResponse) {
+	waitCtx, cancel := context.WithTimeout(context.WithoutCancel(ctx), time.Duration(f.cfg().Transaction.TimeoutSeconds)*time.Second)
+	defer cancel()
+	receipt, err := waitForConfirmations(
+		waitCtx,
+		replacementReader{Client: client, tracker: f.replacements},
+		common.HexToHash(txHash),
+		uint64(f.cfg().Transaction.Confirmations),
+		f.confirmationPollInterval,
+	)
+	for _, resp := range resps {
+		if err != nil {
+			(&settleError{code: SettleErrNotConfirmed, err: err}).fail(resp)
+			continue
+		}
+		if receipt.TxHash != (common.Hash{}) {
+			resp.Transaction = receipt.TxHash.Hex()
+		}
+		resp.BlockNumber = receipt.BlockNumber.Uint64()
+		resp.GasUsed = receipt.GasUsed
+		resp.Status = types.SettleStatusConfirmed
+		if receipt.Status != ethtypes.ReceiptStatusSuccessful {
+			resp.Status = types.SettleStatusReverted
+			(&settleError{code: SettleErrTransactionReverted, err: errors.New("transaction failed on-chain")}).fail(resp)
+		}
 	}
 }
 
 func (f *Facilitator) sendTransferWithAuthorization(
 	ctx context.Context,
 	client *ethclient.Client,
+	signer Signer,
 	auth *types.ExactEVMSchemeAuthorization,
 	requirements *types.PaymentRequirements,
 	signatureHex string,
+	attempt int,
 ) (string, error) {
-	// Parse the EIP-3009 ABI
-	parsedABI, err := abi.JSON(strings.NewReader(utils.EIP3009TransferWithAuthABI))
+	// Encode both transferWithAuthorization overloads, or only the
+	// signature-bytes one for contract wallets
+	contract, err := isContractWallet(ctx, client, common.HexToAddress(auth.From))
+	if err != nil {
+		return "", fmt.Errorf("failed to check payer: %w", err)
+	}
+	candidates, err := encodeTransferWithAuthorization(auth, signatureHex, contract)
 	if err != nil {
-		return "", fmt.Errorf("failed to parse ABI: %w", err)
+		return "", err
 	}
 
-	// Extract v, r, s from signature
-	v, r, s, err := utils.ExtractVRS(signatureHex)
+	// Get gas price
+	gasPrice, err := client.SuggestGasPrice(ctx)
+	if err != nil {
+		return "", fmt.Errorf("failed to get gas price: %w", err)
+	}
+
+	// Check gas price against max gas price from config
+	maxGasPrice, ok := new(big.Int).SetString(f.cfg().Transaction.MaxGasPrice, 10)
+	if !ok {
+		return "", fmt.Errorf("failed to parse max gas price: %s", f.cfg().Transaction.MaxGasPrice)
+	}
+
+	if gasPrice.Cmp(maxGasPrice) > 0 {
+		return "", fmt.Errorf("gas price too high: suggested %s wei exceeds max %s wei", gasPrice.String(), maxGasPrice.String())
+	}
+
+	// Outbid the previous attempt on retries
+	gasPrice = f.cfg().Transaction.Retry.bumpGasPrice(gasPrice, maxGasPrice, attempt)
+
+	// Get chain ID
+	chainID, err := utils.GetChainID(requirements.Network)
+	if err != nil {
+		return "", fmt.Errorf("failed to get chain id: %w", err)
+	}
+
+	// Determine calldata, access list and gas limit
+	tokenAddress := common.HexToAddress(requirements.Asset)
+	call, err := f.prepareSettlementCall(ctx, client, requirements, ethereum.CallMsg{
+		From: signer.Address(),
+		To:   &tokenAddress,
+	}, candidates)
 	if err != nil {
-		return "", fmt.Errorf("failed to extract signature: %v", err)
+		return "", err
 	}
 
+	// Sign and send, retrying once with a fresh nonce if the node says it was used
+	txHash, err := f.sendSettlementTx(ctx, client, signer, requirements.Network, chainID, tokenAddress, gasPrice, call)
+	if err != nil && isNonceError(err) {
+		f.log(ctx).Warn("nonce already used, resynced and retrying", "network", requirements.Network, "error", err)
+		txHash, err = f.sendSettlementTx(ctx, client, signer, requirements.Network, chainID, tokenAddress, gasPrice, call)
+	}
+	return txHash, err
+}
+
+// encodeTransferWithAuthorization encodes an authorization as calls to both
+// transferWithAuthorization overloads, the v/r/s overload first. Contract
+// wallet signatures are only encoded for the signature-bytes overload, the
+// only one that checks them with ERC-1271.
+func encodeTransferWithAuthorization(auth *types.ExactEVMSchemeAuthorization, signatureHex string, contractWallet bool) ([]settlementCall, error) {
 	// Parse addresses and value
 	fromAddr := common.HexToAddress(auth.From)
 	toAddr := common.HexToAddress(auth.To)
@@ -103,10 +310,45 @@ func (f *Facilitator) sendTransferWithAuthorization(
 	var authNonce [32]byte
 	nonceBytes := common.FromHex(auth.Nonce)
 	if len(nonceBytes) != 32 {
-		return "", fmt.Errorf("invalid nonce length: expected 32 bytes, got %d", len(nonceBytes))
+		return nil, fmt.Errorf("invalid nonce length: expected 32 bytes, got %d", len(nonceBytes))
 	}
 	copy(authNonce[:], nonceBytes)
 
+	// Encode the signature-bytes overload for tokens that only or more cheaply support it
+	bytesABI, err := abi.JSON(strings.NewReader(utils.EIP3009TransferWithAuthBytesABI))
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse ABI: %w", err)
+	}
+	bytesCallData, err := bytesABI.Pack(
+		"transferWithAuthorization",
+		fromAddr,
+		toAddr,
+		value,
+		big.NewInt(auth.ValidAfter),
+		big.NewInt(auth.ValidBefore),
+		authNonce,
+		common.FromHex(signatureHex),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode call: %v", err)
+	}
+	bytesCall := settlementCall{overload: OverloadBytes, data: bytesCallData}
+	if contractWallet {
+		return []settlementCall{bytesCall}, nil
+	}
+
+	// Parse the EIP-3009 ABI
+	parsedABI, err := abi.JSON(strings.NewReader(utils.EIP3009TransferWithAuthABI))
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse ABI: %w", err)
+	}
+
+	// Extract v, r, s from signature
+	v, r, s, err := utils.ExtractVRS(signatureHex)
+	if err != nil {
+		return nil, fmt.Errorf("failed to extract signature: %v", err)
+	}
+
 	// Encode the transferWithAuthorization call
 	callData, err := parsedABI.Pack(
 		"transferWithAuthorization",
@@ -121,70 +363,161 @@ func (f *Facilitator) sendTransferWithAuthorization(
 		s,
 	)
 	if err != nil {
-		return "", fmt.Errorf("failed to encode call: %v", err)
+		return nil, fmt.Errorf("failed to encode call: %v", err)
 	}
+	return []settlementCall{{overload: OverloadVRS, data: callData}, bytesCall}, nil
+}
 
-	// Get nonce for facilitator address
-	nonce, err := client.PendingNonceAt(ctx, f.config.Signer.Address)
+// sendSettlementTx signs and broadcasts a settlement call with a nonce from
+// the nonce manager
+func (f *Facilitator) sendSettlementTx(
+	ctx context.Context,
+	client *ethclient.Client,
+	signer Signer,
+	network string,
+	chainID *big.Int,
+	to common.Address,
+	gasPrice *big.Int,
+	call *settlementCall,
+) (string, error) {
+	// Reserve nonce for facilitator address
+	from := signer.Address()
+	nonce, err := f.nonces.reserve(ctx, client, network, from)
 	if err != nil {
 		return "", fmt.Errorf("failed to get nonce: %w", err)
 	}
 
-	// Get gas price
-	gasPrice, err := client.SuggestGasPrice(ctx)
+	// Create transaction, as EIP-2930 when an access list is attached
+	var tx *ethtypes.Transaction
+	if len(call.accessList) > 0 {
+		tx = ethtypes.NewTx(&ethtypes.AccessListTx{
+			ChainID:    chainID,
+			Nonce:      nonce,
+			GasPrice:   gasPrice,
+			Gas:        call.gas,
+			To:         &to,
+			Value:      big.NewInt(0),
+			Data:       call.data,
+			AccessList: call.accessList,
+		})
+	} else {
+		tx = ethtypes.NewTransaction(
+			nonce,
+			to,
+			big.NewInt(0), // No ETH value, just calling contract
+			call.gas,
+			gasPrice,
+			call.data,
+		)
+	}
+
+	// Sign transaction
+	signedTx, err := signer.SignTx(ctx, tx, chainID)
 	if err != nil {
-		return "", fmt.Errorf("failed to get gas price: %w", err)
+		f.releaseNonce(ctx, network, from, nonce)
+		return "", fmt.Errorf("failed to sign transaction: %w", err)
 	}
 
-	// Check gas price against max gas price from config
-	maxGasPrice, ok := new(big.Int).SetString(f.config.Transaction.MaxGasPrice, 10)
-	if !ok {
-		return "", fmt.Errorf("failed to parse max gas price: %s", f.config.Transaction.MaxGasPrice)
+	// Send transaction
+	if err := client.SendTransaction(ctx, signedTx); err != nil {
+		if isNonceError(err) {
+			if syncErr := f.nonces.resync(ctx, client, network, from, nonce); syncErr != nil {
+				f.log(ctx).Warn("failed to resync nonce", "network", network, "error", syncErr)
+			}
+		} else {
+			f.releaseNonce(ctx, network, from, nonce)
+		}
+		return "", fmt.Errorf("failed to send transaction: %w", err)
+	}
+	f.nonces.sent(network, from, nonce)
+	if f.cfg().Transaction.Replacement.enabled() {
+		f.replacements.track(network, client, signer, chainID, signedTx, f.now())
 	}
 
-	if gasPrice.Cmp(maxGasPrice) > 0 {
-		return "", fmt.Errorf("gas price too high: suggested %s wei exceeds max %s wei", gasPrice.String(), maxGasPrice.String())
+	// Return transaction hash
+	return signedTx.Hash().Hex(), nil
+}
+
+// releaseNonce returns the nonce of a transaction that was never sent
+func (f *Facilitator) releaseNonce(ctx context.Context, network string, from common.Address, nonce uint64) {
+	if err := f.nonces.release(network, from, nonce); err != nil {
+		f.log(ctx).Warn("failed to release nonce", "network", network, "nonce", nonce, "error", err)
 	}
+}
 
-	// Estimate gas
-	tokenAddress := common.HexToAddress(requirements.Asset)
-	gasLimit, err := client.EstimateGas(ctx, ethereum.CallMsg{
-		From: f.config.Signer.Address,
-		To:   &tokenAddress,
-		Data: callData,
-	})
+// prepareSettlementCall picks the calldata and gas limit for a settlement
+// transaction. The first candidate (the v/r/s overload) is used unless gas
+// optimization is enabled. A configured override skips estimation, successful
+// estimates are scaled by the configured multiplier, and the fallback limit is
+// used when estimation fails for any reason other than the call reverting.
+func (f *Facilitator) prepareSettlementCall(
+	ctx context.Context,
+	client *ethclient.Client,
+	requirements *types.PaymentRequirements,
+	msg ethereum.CallMsg,
+	candidates []settlementCall,
+) (*settlementCall, error) {
+	// Resolve gas settings for this network and asset
+	networkCfg, err := f.cfg().GetNetworkConfig(requirements.Network)
 	if err != nil {
-		return "", fmt.Errorf("failed to estimate gas: %w", err)
+		return nil, err
 	}
+	gasCfg := networkCfg.GetGasConfig(requirements.Asset)
+	call := &candidates[0]
 
-	// Create transaction
-	tx := ethtypes.NewTransaction(
-		nonce,
-		tokenAddress,
-		big.NewInt(0), // No ETH value, just calling contract
-		gasLimit,
-		gasPrice,
-		callData,
-	)
-
-	// Get chain ID
-	chainID, err := utils.GetChainID(requirements.Network)
-	if err != nil {
-		return "", fmt.Errorf("failed to get chain id: %w", err)
+	// Manual override takes precedence over estimation
+	if gasCfg.LimitOverride > 0 {
+		call.gas = gasCfg.LimitOverride
+		return call, nil
 	}
 
-	// Sign transaction
-	signedTx, err := ethtypes.SignTx(tx, ethtypes.NewEIP155Signer(chainID), f.config.Signer.PrivateKey)
+	// Estimate gas, optimizing calldata and access list if enabled
+	if gasCfg.Optimize {
+		optimized, optErr := f.gasOptimizer.optimize(ctx, rpcGasEstimator{client}, requirements.Network, msg, candidates)
+		if optErr == nil {
+			call = optimized
+		}
+		err = optErr
+	} else {
+		msg.Data = call.data
+		call.gas, err = client.EstimateGas(ctx, msg)
+	}
 	if err != nil {
-		return "", fmt.Errorf("failed to sign transaction: %w", err)
+		// A revert means the transfer itself is invalid, falling back would only burn gas
+		if isRevertError(err) {
+			return nil, &settleError{code: SettleErrTransactionReverted, err: err}
+		}
+		if gasCfg.FallbackLimit > 0 {
+			f.log(ctx).Warn("gas estimation failed, using fallback limit",
+				"network", requirements.Network, "fallback_limit", gasCfg.FallbackLimit, "error", err)
+			call = &candidates[0]
+			call.gas = gasCfg.FallbackLimit
+			return call, nil
+		}
+		return nil, &settleError{code: SettleErrGasEstimationFailed, err: err}
 	}
 
-	// Send transaction
-	err = client.SendTransaction(ctx, signedTx)
-	if err != nil {
-		return "", fmt.Errorf("failed to send transaction: %w", err)
+	// Apply multiplier buffer
+	if gasCfg.Multiplier > 1 {
+		call.gas = uint64(float64(call.gas) * gasCfg.Multiplier)
 	}
 
-	// Return transaction hash
-	return signedTx.Hash().Hex(), nil
+	return call, nil
+}
+
+// isRevertError reports whether an RPC error was caused by the call reverting
+func isRevertError(err error) bool {
+	return strings.Contains(strings.ToLower(err.Error()), "execution reverted")
+}
+
+// sleepContext waits for d, returning false if ctx is done first
+func sleepContext(ctx context.Context, d time.Duration) bool {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return false
+	case <-timer.C:
+		return true
+	}
 }
@@ -0,0 +1,181 @@
+package facilitator
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/vorpalengineering/x402-go/types"
+	"github.com/vorpalengineering/x402-go/utils"
+)
+
+// settlementResultTTL is how long a successful settlement is remembered so a
+// duplicate that arrives just after it finished gets the same result
+const settlementResultTTL = 10 * time.Minute
+
+// sharedClaimTTL is how long a replica's claim on a settlement lasts. It
+// outlives settlements bounded by the transaction timeout and frees the
+// authorization if the replica dies while settling.
+const sharedClaimTTL = 5 * time.Minute
+
+// sharedSettlementPoll is how often a replica checks on a settlement claimed
+// by another replica
+const sharedSettlementPoll = 250 * time.Millisecond
+
+// sharedSettlements coordinates settlements of the same authorization across
+// facilitator replicas
+type sharedSettlements interface {
+	// claim takes the settlement of key for ttl. It returns the result of a
+	// completed settlement instead, or false and no result while another
+	// replica holds the claim.
+	claim(ctx context.Context, key string, ttl time.Duration) (bool, *types.SettleResponse, error)
+	// finish drops the claim, keeping a successful result for ttl
+	finish(ctx context.Context, key string, resp *types.SettleResponse, ttl time.Duration) error
+}
+
+type inflightSettlement struct {
+	done chan struct{}
+	resp *types.SettleResponse
+	// shared is set when the response came from another replica
+	shared bool
+}
+
+type completedSettlement struct {
+	resp      *types.SettleResponse
+	expiresAt time.Time
+}
+
+// settlementGroup makes concurrent settlements of the same authorization
+// (e.g. from two resource server replicas) share a single on-chain attempt.
+// Later callers wait for the first and receive its result. With shared state
+// the first caller of each replica also claims the settlement there.
+type settlementGroup struct {
+	mu        sync.Mutex
+	inflight  map[string]*inflightSettlement
+	completed map[string]completedSettlement
+	shared    sharedSettlements
+}
+
+func newSettlementGroup() *settlementGroup {
+	return &settlementGroup{
+		inflight:  make(map[string]*inflightSettlement),
+		completed: make(map[string]completedSettlement),
+	}
+}
+
+// settlementKey identifies an authorization by network, asset, payer and nonce,
+// or a Solana payment by its transaction. Returns "" when the payload has no
+// exact scheme authorization.
+func settlementKey(payload *types.PaymentPayload, requirements *types.PaymentRequirements) string {
+	// A Solana payment is identified by its transaction
+	if utils.IsSolanaNetwork(requirements.Network) {
+		transaction, err := utils.ExtractSVMTransaction(payload)
+		if err != nil {
+			return ""
+		}
+		hash := sha256.Sum256([]byte(transaction))
+		return strings.Join([]string{requirements.Network, hex.EncodeToString(hash[:])}, "|")
+	}
+
+	auth, err := utils.ExtractExactAuthorization(payload)
+	if err != nil || auth.Nonce == "" {
+		return ""
+	}
+	return strings.ToLower(strings.Join([]string{requirements.Network, requirements.Asset, auth.From, auth.Nonce}, "|"))
+}
+
+// inflightCount returns the number of settlements in progress
+func (g *settlementGroup) inflightCount() int {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	return len(g.inflight)
+}
+
+// do runs settle once per key. The second return value reports whether the
+// response was shared from another caller's settlement.
+func (g *settlementGroup) do(ctx context.Context, key string, now time.Time, settle func() *types.SettleResponse) (*types.SettleResponse, bool) {
+	if key == "" {
+		return settle(), false
+	}
+
+	g.mu.Lock()
+
+	// Recently settled
+	if entry, ok := g.completed[key]; ok {
+		if now.Before(entry.expiresAt) {
+			g.mu.Unlock()
+			return entry.resp, true
+		}
+		delete(g.completed, key)
+	}
+
+	// Settlement in progress, wait for it
+	if call, ok := g.inflight[key]; ok {
+		g.mu.Unlock()
+		select {
+		case <-call.done:
+			return call.resp, true
+		case <-ctx.Done():
+			return settleFailed(types.ErrCodeSettlementInProgress, "settlement already in progress"), true
+		}
+	}
+
+	call := &inflightSettlement{done: make(chan struct{})}
+	g.inflight[key] = call
+	g.mu.Unlock()
+
+	call.resp, call.shared = g.settleShared(ctx, key, settle)
+
+	g.mu.Lock()
+	delete(g.inflight, key)
+	if call.resp.Success {
+		// Drop expired results
+		for k, entry := range g.completed {
+			if !now.Before(entry.expiresAt) {
+				delete(g.completed, k)
+			}
+		}
+		g.completed[key] = completedSettlement{resp: call.resp, expiresAt: now.Add(settlementResultTTL)}
+	}
+	g.mu.Unlock()
+	close(call.done)
+
+	return call.resp, call.shared
+}
+
+// settleShared runs settle unless another replica settled or is settling the
+// key. The second return value reports whether the response came from
+// another replica.
+func (g *settlementGroup) settleShared(ctx context.Context, key string, settle func() *types.SettleResponse) (*types.SettleResponse, bool) {
+	if g.shared == nil {
+		return settle(), false
+	}
+
+	for {
+		claimed, result, err := g.shared.claim(ctx, key, sharedClaimTTL)
+		if err != nil {
+			// Without the claim another replica could settle the same authorization
+			return settleFailed(types.ErrCodeSettlementFailed, fmt.Sprintf("shared state unavailable: %v", err)), false
+		}
+		if result != nil {
+			return result, true
+		}
+		if claimed {
+			resp := settle()
+			// A claim that can't be dropped expires after sharedClaimTTL
+			g.shared.finish(context.WithoutCancel(ctx), key, resp, settlementResultTTL)
+			return resp, false
+		}
+
+		// Wait for the replica holding the claim
+		select {
+		case <-ctx.Done():
+			return settleFailed(types.ErrCodeSettlementInProgress, "settlement already in progress"), true
+		case <-time.After(sharedSettlementPoll):
+		}
+	}
+}
@@ -0,0 +1,86 @@
+package facilitator
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/vorpalengineering/x402-go/types"
+)
+
+func TestSettlementGroupSharesResult(t *testing.T) {
+	g := newSettlementGroup()
+	now := time.Now()
+
+	var calls atomic.Int32
+	release := make(chan struct{})
+	settle := func() *types.SettleResponse {
+		calls.Add(1)
+		<-release
+		return &types.SettleResponse{Success: true, Transaction: "0xabc"}
+	}
+
+	// Two concurrent settlements of the same authorization
+	var wg sync.WaitGroup
+	results := make([]*types.SettleResponse, 2)
+	shared := make([]bool, 2)
+	for i := range 2 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			results[i], shared[i] = g.do(context.Background(), "key", now, settle)
+		}()
+	}
+
+	// Let both callers reach the group before settling
+	for {
+		g.mu.Lock()
+		_, started := g.inflight["key"]
+		g.mu.Unlock()
+		if started {
+			break
+		}
+		time.Sleep(time.Millisecond)
+	}
+	time.Sleep(10 * time.Millisecond)
+	close(release)
+	wg.Wait()
+
+	if calls.Load() != 1 {
+		t.Errorf("Expected settle to run once, ran %d times", calls.Load())
+	}
+	for i, resp := range results {
+		if resp.Transaction != "0xabc" {
+			t.Errorf("Expected caller %d to get transaction 0xabc, got %s", i, resp.Transaction)
+		}
+	}
+	if shared[0] == shared[1] {
+		t.Errorf("Expected exactly one caller to share the result, got %v", shared)
+	}
+
+	// A late duplicate gets the remembered result
+	resp, wasShared := g.do(context.Background(), "key", now.Add(time.Minute), settle)
+	if !wasShared || resp.Transaction != "0xabc" || calls.Load() != 1 {
+		t.Errorf("Expected late duplicate to reuse the result")
+	}
+}
+
+func TestSettlementGroupRetriesFailure(t *testing.T) {
+	g := newSettlementGroup()
+	now := time.Now()
+
+	var calls int
+	settle := func() *types.SettleResponse {
+		calls++
+		return &types.SettleResponse{Success: false, ErrorReason: "failed"}
+	}
+
+	g.do(context.Background(), "key", now, settle)
+	g.do(context.Background(), "key", now, settle)
+
+	if calls != 2 {
+		t.Errorf("Expected failed settlement to be retried, ran %d times", calls)
+	}
+}
@@ -0,0 +1,168 @@
+package facilitator
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"sync"
+
+	"github.com/gin-gonic/gin"
+	"github.com/vorpalengineering/x402-go/types"
+)
+
+// defaultSettlePoolQueueSize is how many settlements may wait for a worker
+// of a network before new ones fail
+const defaultSettlePoolQueueSize = 256
+
+// SettleErrQueueFull is the error code of a settlement rejected because too
+// many others were waiting for a worker of its network
+const SettleErrQueueFull = types.ErrCodeSettlementQueueFull
+
+// SettlePoolConfig bounds how many settlements of a network run at once, so a
+// burst of requests doesn't exhaust RPC connections or trip provider rate
+// limits. Zero workers leave settlements unbounded.
+type SettlePoolConfig struct {
+	// Workers settling concurrently per network, overridden by a network's
+	// settle_workers
+	Workers int `yaml:"workers"`
+	// QueueSize is the settlements waiting per network before new ones fail
+	// (default 256)
+	QueueSize int `yaml:"queue_size"`
+}
+
+func (poolCfg SettlePoolConfig) validate() error {
+	if poolCfg.Workers < 0 || poolCfg.QueueSize < 0 {
+		return fmt.Errorf("settle pool settings cannot be negative")
+	}
+	return nil
+}
+
+// queueSize returns the settlements allowed to wait per network
+func (poolCfg SettlePoolConfig) queueSize() int {
+	if poolCfg.QueueSize > 0 {
+		return poolCfg.QueueSize
+	}
+	return defaultSettlePoolQueueSize
+}
+
+// SettlePoolStats is the settlement worker usage of a network. Workers is 0
+// when settlements are unbounded.
+type SettlePoolStats struct {
+	Workers  int    `json:"workers"`
+	Active   int    `json:"active"`
+	Queued   int    `json:"queued"`
+	Rejected uint64 `json:"rejected"`
+}
+
+// networkPool holds the worker slots of a network. Slots are replaced when the
+// worker count changes on reload, settlements holding an old slot return it
+// to the channel they took it from.
+type networkPool struct {
+	slots chan struct{}
+	stats SettlePoolStats
+}
+
+// settlePool limits concurrent settlements per network
+type settlePool struct {
+	mu       sync.Mutex
+	networks map[string]*networkPool
+}
+
+func newSettlePool() *settlePool {
+	return &settlePool{networks: make(map[string]*networkPool)}
+}
+
+// acquire waits for a worker of the network and returns the function that
+// gives it back. It fails once queueSize settlements are already waiting, or
+// when ctx is done first.
+func (p *settlePool) acquire(ctx context.Context, network string, workers, queueSize int) (func(), error) {
+	p.mu.Lock()
+	pool, ok := p.networks[network]
+	if !ok {
+		pool = &networkPool{}
+		p.networks[network] = pool
+	}
+	if pool.stats.Workers != workers {
+		pool.stats.Workers = workers
+		pool.slots = nil
+		if workers > 0 {
+			pool.slots = make(chan struct{}, workers)
+		}
+	}
+	slots := pool.slots
+	release := func() {
+		p.mu.Lock()
+		pool.stats.Active--
+		p.mu.Unlock()
+		if slots != nil {
+			<-slots
+		}
+	}
+
+	// Take a free worker right away
+	if slots == nil {
+		pool.stats.Active++
+		p.mu.Unlock()
+		return release, nil
+	}
+	select {
+	case slots <- struct{}{}:
+		pool.stats.Active++
+		p.mu.Unlock()
+		return release, nil
+	default:
+	}
+
+	// Or wait in the queue if there is room
+	if pool.stats.Queued >= queueSize {
+		pool.stats.Rejected++
+		p.mu.Unlock()
+		return nil, &settleError{
+			code: SettleErrQueueFull,
+			err:  fmt.Errorf("%d settlements already waiting on %s", queueSize, network),
+		}
+	}
+	pool.stats.Queued++
+	p.mu.Unlock()
+
+	select {
+	case slots <- struct{}{}:
+		p.mu.Lock()
+		pool.stats.Queued--
+		pool.stats.Active++
+		p.mu.Unlock()
+		return release, nil
+	case <-ctx.Done():
+		p.mu.Lock()
+		pool.stats.Queued--
+		p.mu.Unlock()
+		return nil, fmt.Errorf("waiting for a settlement worker: %w", ctx.Err())
+	}
+}
+
+func (p *settlePool) snapshot() map[string]SettlePoolStats {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	snapshot := make(map[string]SettlePoolStats, len(p.networks))
+	for network, pool := range p.networks {
+		snapshot[network] = pool.stats
+	}
+	return snapshot
+}
+
+// acquireSettleWorker waits for a settlement worker of the network, sized by
+// the network's settle_workers or settle_pool.workers
+func (f *Facilitator) acquireSettleWorker(ctx context.Context, network string) (func(), error) {
+	poolCfg := f.cfg().SettlePool
+	workers := poolCfg.Workers
+	if networkCfg, err := f.cfg().GetNetworkConfig(network); err == nil && networkCfg.SettleWorkers > 0 {
+		workers = networkCfg.SettleWorkers
+	}
+	return f.settlePool.acquire(ctx, network, workers, poolCfg.queueSize())
+}
+
+// handleSettlementMetrics returns the settlement worker usage of each network
+func (f *Facilitator) handleSettlementMetrics(ctx *gin.Context) {
+	ctx.JSON(http.StatusOK, f.settlePool.snapshot())
+}
@@ -0,0 +1,67 @@
+package facilitator
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestSettlePool(t *testing.T) {
+	pool := newSettlePool()
+
+	// Two workers are taken right away
+	first, err := pool.acquire(context.Background(), "eip155:8453", 2, 1)
+	if err != nil {
+		t.Fatalf("Expected a worker, got %v", err)
+	}
+	second, err := pool.acquire(context.Background(), "eip155:8453", 2, 1)
+	if err != nil {
+		t.Fatalf("Expected a worker, got %v", err)
+	}
+
+	// A third waits until one is released
+	acquired := make(chan func())
+	go func() {
+		done, err := pool.acquire(context.Background(), "eip155:8453", 2, 1)
+		if err != nil {
+			t.Errorf("Expected a worker after waiting, got %v", err)
+		}
+		acquired <- done
+	}()
+	for pool.snapshot()["eip155:8453"].Queued != 1 {
+		time.Sleep(time.Millisecond)
+	}
+
+	// A fourth finds the queue full
+	if _, err := pool.acquire(context.Background(), "eip155:8453", 2, 1); err == nil || !strings.HasPrefix(err.Error(), SettleErrQueueFull) {
+		t.Errorf("Expected %s, got %v", SettleErrQueueFull, err)
+	}
+
+	// Other networks have their own workers
+	other, err := pool.acquire(context.Background(), "eip155:1", 2, 1)
+	if err != nil {
+		t.Fatalf("Expected a worker of another network, got %v", err)
+	}
+	other()
+
+	first()
+	third := <-acquired
+	stats := pool.snapshot()["eip155:8453"]
+	if stats.Workers != 2 || stats.Active != 2 || stats.Queued != 0 || stats.Rejected != 1 {
+		t.Errorf("Expected 2 active and 1 rejected, got %+v", stats)
+	}
+
+	// Waiting stops when the context is done
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	if _, err := pool.acquire(ctx, "eip155:8453", 2, 1); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("Expected deadline exceeded, got %v", err)
+	}
+	second()
+	third()
+	if stats := pool.snapshot()["eip155:8453"]; stats.Active != 0 || stats.Queued != 0 {
+		t.Errorf("Expected an idle pool, got %+v", stats)
+	}
+}
@@ -0,0 +1,66 @@
+package facilitator
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"fmt"
+	"math/big"
+
+	"github.com/ethereum/go-ethereum/common"
+	ethtypes "github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/signer/core/apitypes"
+)
+
+// Signer signs on behalf of the facilitator. Implement it to settle with keys
+// held in hardware, a remote signing service or a secret manager.
+type Signer interface {
+	// Address is the account that sends settlement transactions
+	Address() common.Address
+
+	// SignTx signs a transaction for the given chain
+	SignTx(ctx context.Context, tx *ethtypes.Transaction, chainID *big.Int) (*ethtypes.Transaction, error)
+
+	// SignTypedData signs EIP-712 typed data, returning a 65-byte signature with V 27 or 28
+	SignTypedData(ctx context.Context, typedData apitypes.TypedData) ([]byte, error)
+}
+
+// privateKeySigner signs with a key held in memory
+type privateKeySigner struct {
+	key *ecdsa.PrivateKey
+	// owned keys were loaded by the facilitator and are zeroed on Close
+	owned bool
+}
+
+// NewPrivateKeySigner returns a Signer for an in-memory ECDSA key
+func NewPrivateKeySigner(key *ecdsa.PrivateKey) Signer {
+	return &privateKeySigner{key: key}
+}
+
+// wipe zeroes an owned key. The signer can't sign afterwards.
+func (s *privateKeySigner) wipe() {
+	if s.owned {
+		zeroKey(s.key)
+	}
+}
+
+func (s *privateKeySigner) Address() common.Address {
+	return crypto.PubkeyToAddress(s.key.PublicKey)
+}
+
+func (s *privateKeySigner) SignTx(ctx context.Context, tx *ethtypes.Transaction, chainID *big.Int) (*ethtypes.Transaction, error) {
+	return ethtypes.SignTx(tx, ethtypes.NewEIP2930Signer(chainID), s.key)
+}
+
+func (s *privateKeySigner) SignTypedData(ctx context.Context, typedData apitypes.TypedData) ([]byte, error) {
+	hash, _, err := apitypes.TypedDataAndHash(typedData)
+	if err != nil {
+		return nil, fmt.Errorf("failed to hash typed data: %w", err)
+	}
+	sig, err := crypto.Sign(hash, s.key)
+	if err != nil {
+		return nil, err
+	}
+	sig[64] += 27
+	return sig, nil
+}
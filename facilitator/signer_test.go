@@ -0,0 +1,103 @@
+package facilitator
+
+import (
+	"context"
+	"math/big"
+	"strings"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	ethtypes "github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/vorpalengineering/x402-go/types"
+	"github.com/vorpalengineering/x402-go/utils"
+)
+
+func TestPrivateKeySigner(t *testing.T) {
+	privKey, _ := crypto.HexToECDSA("ac0974bec39a17e36ba4a6b4d238ff944bacb478cbed5efcae784d7bf4f2ff80")
+	signer := NewPrivateKeySigner(privKey)
+	expected := crypto.PubkeyToAddress(privKey.PublicKey)
+	if signer.Address() != expected {
+		t.Errorf("Expected address %s, got %s", expected, signer.Address())
+	}
+
+	// Transactions recover to the signer
+	chainID := big.NewInt(8453)
+	tx := ethtypes.NewTransaction(0, common.HexToAddress("0x70997970C51812dc3A010C7d01b50e0d17dc79C8"), big.NewInt(0), 21000, big.NewInt(1), nil)
+	signedTx, err := signer.SignTx(context.Background(), tx, chainID)
+	if err != nil {
+		t.Fatalf("Failed to sign transaction: %v", err)
+	}
+	if sender, _ := ethtypes.Sender(ethtypes.NewEIP2930Signer(chainID), signedTx); sender != expected {
+		t.Errorf("Expected sender %s, got %s", expected, sender)
+	}
+
+	// Typed data signatures verify as EIP-3009 authorizations
+	auth := &types.ExactEVMSchemeAuthorization{
+		From:        expected.Hex(),
+		To:          "0x70997970C51812dc3A010C7d01b50e0d17dc79C8",
+		Value:       "1000000",
+		ValidAfter:  0,
+		ValidBefore: 1700003600,
+		Nonce:       "0x" + strings.Repeat("01", 32),
+	}
+	requirements := &types.PaymentRequirements{
+		Scheme:  "exact",
+		Network: "eip155:8453",
+		Amount:  "1000000",
+		PayTo:   "0x70997970C51812dc3A010C7d01b50e0d17dc79C8",
+		Asset:   "0x833589fCD6eDb6E08f4c7C32D4f71b54bdA02913",
+		Extra:   map[string]any{"name": "USD Coin", "version": "2"},
+	}
+	typedData, err := utils.BuildEIP712TypedData(auth, requirements)
+	if err != nil {
+		t.Fatalf("Failed to build typed data: %v", err)
+	}
+	sig, err := signer.SignTypedData(context.Background(), *typedData)
+	if err != nil {
+		t.Fatalf("Failed to sign typed data: %v", err)
+	}
+	recovered, err := utils.RecoverEIP3009Signer(auth, requirements, hexutil.Encode(sig))
+	if err != nil {
+		t.Fatalf("Failed to recover signer: %v", err)
+	}
+	if recovered != expected {
+		t.Errorf("Expected recovered signer %s, got %s", expected, recovered)
+	}
+}
+
+// staticSigner is a custom backend wrapping a key
+type staticSigner struct {
+	Signer
+}
+
+func TestSignerBackend(t *testing.T) {
+	privKey, _ := crypto.HexToECDSA("ac0974bec39a17e36ba4a6b4d238ff944bacb478cbed5efcae784d7bf4f2ff80")
+	backend := &staticSigner{Signer: NewPrivateKeySigner(privKey)}
+
+	// Custom backend takes precedence and sets the signer address
+	f := NewFacilitator(&FacilitatorConfig{
+		Networks: map[string]NetworkConfig{
+			"eip155:8453": {RpcUrl: "http://127.0.0.1:1"},
+		},
+		Log:    LogConfig{Level: "error"},
+		Signer: SignerConfig{Backend: backend},
+	})
+	defer f.Close()
+
+	signer, release, err := f.signers.acquire("eip155:8453")
+	if err != nil {
+		t.Fatalf("Failed to acquire signer: %v", err)
+	}
+	release()
+	if signer != backend {
+		t.Errorf("Expected backend to be used as signer")
+	}
+	if f.cfg().Signer.Address != backend.Address() {
+		t.Errorf("Expected signer address %s, got %s", backend.Address(), f.cfg().Signer.Address)
+	}
+	if err := f.checkSigner(context.Background()); err != nil {
+		t.Errorf("Expected backend signer to be ready, got %v", err)
+	}
+}
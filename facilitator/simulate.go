@@ -0,0 +1,241 @@
+package facilitator
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"math/big"
+	"net/http"
+	"strings"
+
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/accounts/abi"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/rpc"
+	"github.com/gin-gonic/gin"
+	"github.com/vorpalengineering/x402-go/types"
+	"github.com/vorpalengineering/x402-go/utils"
+)
+
+var (
+	// errorSelector and panicSelector prefix Error(string) and Panic(uint256) reverts
+	errorSelector = crypto.Keccak256([]byte("Error(string)"))[:4]
+	panicSelector = crypto.Keccak256([]byte("Panic(uint256)"))[:4]
+
+	abiString, _  = abi.NewType("string", "", nil)
+	abiUint256, _ = abi.NewType("uint256", "", nil)
+)
+
+// revertErrors are custom errors of ERC-20 (OpenZeppelin 5) and EIP-3009
+// tokens that simulations decode by name
+var revertErrors = parseRevertErrors(
+	"ERC20InsufficientBalance(address sender,uint256 balance,uint256 needed)",
+	"ERC20InsufficientAllowance(address spender,uint256 allowance,uint256 needed)",
+	"ERC20InvalidSender(address sender)",
+	"ERC20InvalidReceiver(address receiver)",
+	"ERC2612ExpiredSignature(uint256 deadline)",
+	"ERC2612InvalidSigner(address signer,address owner)",
+	"ECDSAInvalidSignature()",
+	"ECDSAInvalidSignatureLength(uint256 length)",
+	"ECDSAInvalidSignatureS(bytes32 s)",
+	"EnforcedPause()",
+	"AuthorizationAlreadyUsed(address authorizer,bytes32 nonce)",
+	"AuthorizationExpired(uint256 validBefore)",
+	"AuthorizationNotYetValid(uint256 validAfter)",
+	"InvalidSignature()",
+	"InvalidAuthorization()",
+)
+
+// panicCodes describe Solidity panic codes
+var panicCodes = map[uint64]string{
+	0x01: "assertion failed",
+	0x11: "arithmetic overflow or underflow",
+	0x12: "division by zero",
+	0x21: "invalid enum value",
+	0x32: "array index out of bounds",
+	0x41: "out of memory",
+	0x51: "call to uninitialized function",
+}
+
+// revertError is a custom error with its decoded argument names
+type revertError struct {
+	name     string
+	selector []byte
+	args     abi.Arguments
+}
+
+func parseRevertErrors(signatures ...string) []revertError {
+	var parsed []revertError
+	for _, signature := range signatures {
+		name, params, _ := strings.Cut(strings.TrimSuffix(signature, ")"), "(")
+		var typeNames []string
+		var args abi.Arguments
+		if params != "" {
+			for _, param := range strings.Split(params, ",") {
+				typeName, argName, _ := strings.Cut(param, " ")
+				argType, err := abi.NewType(typeName, "", nil)
+				if err != nil {
+					panic(fmt.Sprintf("invalid revert error %s: %v", signature, err))
+				}
+				typeNames = append(typeNames, typeName)
+				args = append(args, abi.Argument{Name: argName, Type: argType})
+			}
+		}
+		selector := crypto.Keccak256([]byte(name + "(" + strings.Join(typeNames, ",") + ")"))[:4]
+		parsed = append(parsed, revertError{name: name, selector: selector, args: args})
+	}
+	return parsed
+}
+
+// decodeRevert turns revert data into a readable reason, and the name of the
+// custom error if it is one of revertErrors
+func decodeRevert(data []byte) (reason, errorName string) {
+	if len(data) < 4 {
+		return "execution reverted", ""
+	}
+	selector, body := data[:4], data[4:]
+
+	switch {
+	case bytes.Equal(selector, errorSelector):
+		values, err := abi.Arguments{{Type: abiString}}.Unpack(body)
+		if err == nil {
+			return values[0].(string), ""
+		}
+	case bytes.Equal(selector, panicSelector):
+		values, err := abi.Arguments{{Type: abiUint256}}.Unpack(body)
+		if err == nil {
+			code := values[0].(*big.Int)
+			if description, ok := panicCodes[code.Uint64()]; ok && code.IsUint64() {
+				return fmt.Sprintf("panic 0x%02x: %s", code.Uint64(), description), ""
+			}
+			return fmt.Sprintf("panic 0x%x", code), ""
+		}
+	}
+
+	for _, known := range revertErrors {
+		if !bytes.Equal(selector, known.selector) {
+			continue
+		}
+		values, err := known.args.Unpack(body)
+		if err != nil {
+			break
+		}
+		parts := make([]string, len(values))
+		for i, value := range values {
+			if hash, ok := value.([32]byte); ok {
+				value = hexutil.Encode(hash[:])
+			}
+			parts[i] = fmt.Sprintf("%s=%v", known.args[i].Name, value)
+		}
+		return fmt.Sprintf("%s(%s)", known.name, strings.Join(parts, ", ")), known.name
+	}
+
+	return fmt.Sprintf("unknown error %s", hexutil.Encode(selector)), ""
+}
+
+// revertData returns the data of a reverted eth_call, if the node sent any
+func revertData(err error) []byte {
+	var dataErr rpc.DataError
+	if !errors.As(err, &dataErr) {
+		return nil
+	}
+	data, ok := dataErr.ErrorData().(string)
+	if !ok {
+		return nil
+	}
+	decoded, err := hexutil.Decode(data)
+	if err != nil {
+		return nil
+	}
+	return decoded
+}
+
+// simulateExact runs the settlement call of an exact EVM payment with eth_call
+// and explains a revert
+func (f *Facilitator) simulateExact(ctx context.Context, payload *types.PaymentPayload, requirements *types.PaymentRequirements) types.SimulateResponse {
+	signatureHex, ok := payload.Payload["signature"].(string)
+	if !ok || signatureHex == "" {
+		return types.SimulateResponse{Error: "missing signature"}
+	}
+	auth, err := utils.ExtractExactAuthorization(payload)
+	if err != nil {
+		return types.SimulateResponse{Error: fmt.Sprintf("invalid authorization: %v", err)}
+	}
+	res := types.SimulateResponse{Payer: auth.From}
+
+	// Get RPC client
+	client, err := f.getRPCClient(requirements.Network)
+	if err != nil {
+		res.Error = fmt.Sprintf("failed to connect to network: %v", err)
+		return res
+	}
+
+	// Simulate the overload settlement would use
+	contract, err := isContractWallet(ctx, client, common.HexToAddress(auth.From))
+	if err != nil {
+		res.Error = fmt.Sprintf("failed to check payer: %v", err)
+		return res
+	}
+	candidates, err := encodeTransferWithAuthorization(auth, signatureHex, contract)
+	if err != nil {
+		res.Error = err.Error()
+		return res
+	}
+	res.Overload = candidates[0].overload
+	token := common.HexToAddress(requirements.Asset)
+	_, err = client.CallContract(ctx, ethereum.CallMsg{To: &token, Data: candidates[0].data}, nil)
+	if err == nil {
+		res.Success = true
+		return res
+	}
+
+	// Decode the revert, or keep the node's message if it sent no data
+	data := revertData(err)
+	if data == nil {
+		if !strings.Contains(err.Error(), "revert") {
+			res.Error = fmt.Sprintf("failed to simulate: %v", err)
+			return res
+		}
+		res.RevertReason = strings.TrimPrefix(err.Error(), "execution reverted: ")
+		return res
+	}
+	res.RevertData = hexutil.Encode(data)
+	res.RevertReason, res.RevertError = decodeRevert(data)
+	return res
+}
+
+// handleSimulate runs only the transaction simulation of a payment and
+// returns the decoded revert reason, for debugging payments before settling
+func (f *Facilitator) handleSimulate(ginCtx *gin.Context) {
+	// Decode request
+	var req types.VerifyRequest
+	if err := ginCtx.ShouldBindJSON(&req); err != nil {
+		ginCtx.JSON(http.StatusBadRequest, gin.H{
+			"error": err.Error(),
+		})
+		return
+	}
+
+	// Only exact EVM payments settle through a simulated call
+	requirements := &req.PaymentRequirements
+	if !f.cfg().IsSupported(requirements.Scheme, requirements.Network) {
+		ginCtx.JSON(http.StatusBadRequest, gin.H{
+			"error": fmt.Sprintf("unsupported scheme-network: %s-%s", requirements.Scheme, requirements.Network),
+		})
+		return
+	}
+	if requirements.Scheme != "exact" || utils.IsSolanaNetwork(requirements.Network) {
+		ginCtx.JSON(http.StatusBadRequest, gin.H{
+			"error": "simulation is only available for the exact scheme on EVM networks",
+		})
+		return
+	}
+
+	ctx, cancel := f.withTransactionTimeout(ginCtx.Request.Context())
+	defer cancel()
+	res := f.simulateExact(ctx, &req.PaymentPayload, requirements)
+	ginCtx.JSON(http.StatusOK, res)
+}
@@ -0,0 +1,142 @@
+package facilitator
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/accounts/abi"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/vorpalengineering/x402-go/types"
+)
+
+// encodeRevert builds revert data for an error signature and its arguments
+func encodeRevert(signature string, argTypes []string, values ...any) []byte {
+	var args abi.Arguments
+	for _, argType := range argTypes {
+		t, _ := abi.NewType(argType, "", nil)
+		args = append(args, abi.Argument{Type: t})
+	}
+	packed, _ := args.Pack(values...)
+	return append(crypto.Keccak256([]byte(signature))[:4], packed...)
+}
+
+func TestDecodeRevert(t *testing.T) {
+	sender := common.HexToAddress("0x70997970C51812dc3A010C7d01b50e0d17dc79C8")
+	tests := []struct {
+		name          string
+		data          []byte
+		expected      string
+		expectedError string
+	}{
+		{"no data", nil, "execution reverted", ""},
+		{"error string", encodeRevert("Error(string)", []string{"string"}, "FiatTokenV2: invalid signature"), "FiatTokenV2: invalid signature", ""},
+		{"panic", encodeRevert("Panic(uint256)", []string{"uint256"}, big.NewInt(0x11)), "panic 0x11: arithmetic overflow or underflow", ""},
+		{
+			"custom error",
+			encodeRevert("ERC20InsufficientBalance(address,uint256,uint256)", []string{"address", "uint256", "uint256"}, sender, big.NewInt(5), big.NewInt(10)),
+			"ERC20InsufficientBalance(sender=" + sender.Hex() + ", balance=5, needed=10)",
+			"ERC20InsufficientBalance",
+		},
+		{"unknown error", []byte{0xde, 0xad, 0xbe, 0xef}, "unknown error 0xdeadbeef", ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			reason, errorName := decodeRevert(tt.data)
+			if reason != tt.expected || errorName != tt.expectedError {
+				t.Errorf("Expected %q (%q), got %q (%q)", tt.expected, tt.expectedError, reason, errorName)
+			}
+		})
+	}
+}
+
+func TestHandleSimulate(t *testing.T) {
+	// RPC where the simulation reverts with an already used authorization
+	payer := common.HexToAddress("0x70997970C51812dc3A010C7d01b50e0d17dc79C8")
+	nonce := [32]byte{1}
+	revert := encodeRevert("AuthorizationAlreadyUsed(address,bytes32)", []string{"address", "bytes32"}, payer, nonce)
+	rpcServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req struct {
+			ID     json.RawMessage `json:"id"`
+			Method string          `json:"method"`
+		}
+		json.NewDecoder(r.Body).Decode(&req)
+		w.Header().Set("Content-Type", "application/json")
+		if req.Method == "eth_call" {
+			fmt.Fprintf(w, `{"jsonrpc":"2.0","id":%s,"error":{"code":3,"message":"execution reverted","data":"%s"}}`, req.ID, hexutil.Encode(revert))
+			return
+		}
+		fmt.Fprintf(w, `{"jsonrpc":"2.0","id":%s,"result":"0x"}`, req.ID)
+	}))
+	defer rpcServer.Close()
+
+	f := NewFacilitator(&FacilitatorConfig{
+		Networks: map[string]NetworkConfig{
+			"eip155:8453": {RpcUrl: rpcServer.URL},
+		},
+		Supported: []types.SupportedKind{
+			{Scheme: "exact", Network: "eip155:8453"},
+		},
+		Log: LogConfig{Level: "error"},
+	})
+	defer f.Close()
+
+	requirements := types.PaymentRequirements{
+		Scheme:  "exact",
+		Network: "eip155:8453",
+		Amount:  "1000000",
+		PayTo:   "0x3C44CdDdB6a900fa2b585dd299e03d12FA4293BC",
+		Asset:   "0x833589fCD6eDb6E08f4c7C32D4f71b54bdA02913",
+	}
+	simulate := func(requirements types.PaymentRequirements) *httptest.ResponseRecorder {
+		body, _ := json.Marshal(types.VerifyRequest{
+			PaymentPayload: types.PaymentPayload{
+				X402Version: 2,
+				Accepted:    requirements,
+				Payload: map[string]any{
+					"signature": "0x" + strings.Repeat("ab", 32) + strings.Repeat("11", 32) + "1b",
+					"authorization": map[string]any{
+						"from":        payer.Hex(),
+						"to":          requirements.PayTo,
+						"value":       "1000000",
+						"validAfter":  0,
+						"validBefore": 4102444800,
+						"nonce":       hexutil.Encode(nonce[:]),
+					},
+				},
+			},
+			PaymentRequirements: requirements,
+		})
+		recorder := httptest.NewRecorder()
+		f.router.ServeHTTP(recorder, httptest.NewRequest("POST", "/simulate", bytes.NewReader(body)))
+		return recorder
+	}
+
+	// The revert is decoded by name
+	recorder := simulate(requirements)
+	if recorder.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d: %s", recorder.Code, recorder.Body.String())
+	}
+	var res types.SimulateResponse
+	json.Unmarshal(recorder.Body.Bytes(), &res)
+	if res.Success || res.RevertError != "AuthorizationAlreadyUsed" || res.Overload != OverloadVRS {
+		t.Errorf("Expected decoded AuthorizationAlreadyUsed revert, got %+v", res)
+	}
+	if !strings.Contains(res.RevertReason, "authorizer="+payer.Hex()) || res.RevertData != hexutil.Encode(revert) {
+		t.Errorf("Expected reason with arguments and raw data, got %+v", res)
+	}
+
+	// Unsupported pairs are rejected
+	requirements.Network = "eip155:1"
+	if recorder := simulate(requirements); recorder.Code != http.StatusBadRequest {
+		t.Errorf("Expected status 400 for an unsupported network, got %d", recorder.Code)
+	}
+}
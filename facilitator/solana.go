@@ -0,0 +1,334 @@
+package facilitator
+
+import (
+	"bytes"
+	"context"
+	"crypto/ed25519"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"strings"
+	"time"
+
+	"github.com/vorpalengineering/x402-go/svm"
+	"github.com/vorpalengineering/x402-go/types"
+	"github.com/vorpalengineering/x402-go/utils"
+)
+
+const (
+	defaultSolanaMaxComputeUnitLimit = 200_000
+	defaultSolanaMaxComputeUnitPrice = 5_000_000
+)
+
+// parseSolanaPrivateKey parses a base58 key or a solana-keygen JSON byte
+// array, holding either the 64 byte keypair or the 32 byte seed
+func parseSolanaPrivateKey(s string) (ed25519.PrivateKey, error) {
+	s = strings.TrimSpace(s)
+	var raw []byte
+	if strings.HasPrefix(s, "[") {
+		var values []int
+		if err := json.Unmarshal([]byte(s), &values); err != nil {
+			return nil, fmt.Errorf("invalid key array: %w", err)
+		}
+		for _, value := range values {
+			if value < 0 || value > 255 {
+				return nil, fmt.Errorf("invalid key byte: %d", value)
+			}
+			raw = append(raw, byte(value))
+		}
+	} else {
+		var err error
+		if raw, err = svm.DecodeBase58(s); err != nil {
+			return nil, err
+		}
+	}
+
+	switch len(raw) {
+	case ed25519.SeedSize:
+		return ed25519.NewKeyFromSeed(raw), nil
+	case ed25519.PrivateKeySize:
+		// The keypair holds the public key after the seed, check they match
+		key := ed25519.NewKeyFromSeed(raw[:ed25519.SeedSize])
+		if !bytes.Equal(key, raw) {
+			return nil, errors.New("public key does not match seed")
+		}
+		return key, nil
+	default:
+		return nil, fmt.Errorf("invalid key length %d", len(raw))
+	}
+}
+
+// solanaFeePayer returns the fee payer of solana:* networks, or nil
+func (f *Facilitator) solanaFeePayer() ed25519.PrivateKey {
+	return f.cfg().Solana.PrivateKey
+}
+
+func (f *Facilitator) getSVMClient(network string) (*svm.Client, error) {
+	// Acquire read lock
+	f.rpcClientsMu.RLock()
+	if client, exists := f.svmClients[network]; exists {
+		f.rpcClientsMu.RUnlock()
+		return client, nil
+	}
+	f.rpcClientsMu.RUnlock()
+
+	// Lazy creation, the client connects on each call
+	f.rpcClientsMu.Lock()
+	defer f.rpcClientsMu.Unlock()
+
+	if client, exists := f.svmClients[network]; exists {
+		return client, nil
+	}
+	networkCfg, err := f.cfg().GetNetworkConfig(network)
+	if err != nil {
+		return nil, err
+	}
+	rpcURLs := networkCfg.GetRpcUrls()
+	if len(rpcURLs) == 0 {
+		return nil, fmt.Errorf("no rpc url configured for %s", network)
+	}
+
+	client := svm.NewClient(rpcURLs[0])
+	f.svmClients[network] = client
+	return client, nil
+}
+
+// decodeSVMPayment decodes the transaction of an exact SVM payload and checks
+// it is a single token transfer
+func decodeSVMPayment(payload *types.PaymentPayload) (*svm.Transaction, *svm.TransferChecked, *svm.ComputeBudget, error) {
+	encoded, err := utils.ExtractSVMTransaction(payload)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	data, err := base64.StdEncoding.DecodeString(encoded)
+	if err != nil {
+		return nil, nil, nil, fmt.Errorf("invalid base64: %w", err)
+	}
+	tx, err := svm.ParseTransaction(data)
+	if err != nil {
+		return nil, nil, nil, fmt.Errorf("invalid transaction: %w", err)
+	}
+	transfer, budget, err := svm.DecodeExactPayment(tx)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	return tx, transfer, budget, nil
+}
+
+// checkSVMPayment runs the offline checks of an exact SVM payment, so the fee
+// payer never signs a transaction that does more than move the required
+// tokens to payTo
+func (f *Facilitator) checkSVMPayment(payload *types.PaymentPayload, requirements *types.PaymentRequirements, fullReport bool) (*svm.Transaction, *svm.TransferChecked, []types.VerifyFailure) {
+	// Decode transaction
+	tx, transfer, budget, err := decodeSVMPayment(payload)
+	if err != nil {
+		return nil, nil, []types.VerifyFailure{{Check: VerifyCheckPayload, Code: types.ErrCodeInvalidPayload, Reason: fmt.Sprintf("invalid transaction: %v", err)}}
+	}
+
+	checks := []struct {
+		name string
+		code string
+		run  func() (bool, string)
+	}{
+		// Step 1: Fee Payer and Compute Budget
+		{VerifyCheckParameters, types.ErrCodeInvalidPayload, func() (bool, string) { return f.verifySVMFeePayer(tx, budget) }},
+		// Step 2: Signature Validation
+		{VerifyCheckSignature, types.ErrCodeInvalidSignature, func() (bool, string) { return verifySVMSignatures(tx) }},
+		// Step 3: Amount Validation
+		{VerifyCheckAmount, types.ErrCodeInvalidAmount, func() (bool, string) { return verifySVMAmount(transfer, requirements) }},
+		// Step 4: Parameter Matching
+		{VerifyCheckParameters, types.ErrCodeRecipientMismatch, func() (bool, string) { return verifySVMParameters(transfer, requirements) }},
+	}
+
+	var failures []types.VerifyFailure
+	for _, check := range checks {
+		if valid, reason := check.run(); !valid {
+			failures = append(failures, types.VerifyFailure{Check: check.name, Code: check.code, Reason: reason})
+			if !fullReport {
+				break
+			}
+		}
+	}
+	return tx, transfer, failures
+}
+
+func (f *Facilitator) verifyExactSVMScheme(ctx context.Context, payload *types.PaymentPayload, requirements *types.PaymentRequirements, fullReport bool) []types.VerifyFailure {
+	tx, _, failures := f.checkSVMPayment(payload, requirements, fullReport)
+	if len(failures) > 0 {
+		return failures
+	}
+
+	// Step 5: Transaction Simulation
+	client, err := f.getSVMClient(requirements.Network)
+	if err != nil {
+		return []types.VerifyFailure{{Check: VerifyCheckSimulation, Code: types.ErrCodeNetworkError, Reason: fmt.Sprintf("failed to connect to network: %v", err)}}
+	}
+	if err := client.SimulateTransaction(ctx, tx); err != nil {
+		return []types.VerifyFailure{{Check: VerifyCheckSimulation, Code: types.ErrCodeSimulationFailed, Reason: fmt.Sprintf("transaction simulation failed: %v", err)}}
+	}
+	return nil
+}
+
+// verifySVMFeePayer checks the transaction is paid for by our fee payer, which
+// must not take part in any instruction, within the compute budget caps
+func (f *Facilitator) verifySVMFeePayer(tx *svm.Transaction, budget *svm.ComputeBudget) (bool, string) {
+	feePayer := f.solanaFeePayer()
+	if feePayer == nil {
+		return false, "solana fee payer not configured"
+	}
+	if tx.Message.AccountKeys[0] != svm.PublicKeyOf(feePayer) {
+		return false, fmt.Sprintf("fee payer mismatch: got %s, expected %s", tx.Message.AccountKeys[0], svm.PublicKeyOf(feePayer))
+	}
+	for _, instruction := range tx.Message.Instructions {
+		if instruction.ProgramIDIndex == 0 {
+			return false, "fee payer cannot be invoked as a program"
+		}
+		for _, account := range instruction.Accounts {
+			if account == 0 {
+				return false, "fee payer cannot be used by instructions"
+			}
+		}
+	}
+
+	maxLimit, maxPrice := f.cfg().Solana.MaxComputeUnitLimit, f.cfg().Solana.MaxComputeUnitPrice
+	if maxLimit == 0 {
+		maxLimit = defaultSolanaMaxComputeUnitLimit
+	}
+	if maxPrice == 0 {
+		maxPrice = defaultSolanaMaxComputeUnitPrice
+	}
+	if budget.HasUnitLimit && budget.UnitLimit > maxLimit {
+		return false, fmt.Sprintf("compute unit limit too high: %d > %d", budget.UnitLimit, maxLimit)
+	}
+	if budget.UnitPrice > maxPrice {
+		return false, fmt.Sprintf("compute unit price too high: %d > %d", budget.UnitPrice, maxPrice)
+	}
+	return true, ""
+}
+
+// verifySVMSignatures checks every signer except the fee payer has signed
+func verifySVMSignatures(tx *svm.Transaction) (bool, string) {
+	for i := 1; i < int(tx.Message.Header.NumRequiredSignatures); i++ {
+		if !tx.VerifySignature(i) {
+			return false, fmt.Sprintf("invalid signature for %s", tx.Message.AccountKeys[i])
+		}
+	}
+	return true, ""
+}
+
+func verifySVMAmount(transfer *svm.TransferChecked, requirements *types.PaymentRequirements) (bool, string) {
+	requiredAmount, ok := new(big.Int).SetString(requirements.Amount, 10)
+	if !ok {
+		return false, "invalid required amount format"
+	}
+
+	// Payment must be >= required amount
+	paymentAmount := new(big.Int).SetUint64(transfer.Amount)
+	if paymentAmount.Cmp(requiredAmount) < 0 {
+		return false, fmt.Sprintf("insufficient amount: got %s, required %s", paymentAmount, requirements.Amount)
+	}
+	return true, ""
+}
+
+// verifySVMParameters checks the transfer moves the required mint into the
+// associated token account of payTo
+func verifySVMParameters(transfer *svm.TransferChecked, requirements *types.PaymentRequirements) (bool, string) {
+	if transfer.Mint.String() != requirements.Asset {
+		return false, fmt.Sprintf("asset mismatch: got %s, expected %s", transfer.Mint, requirements.Asset)
+	}
+	payTo, err := svm.PublicKeyFromBase58(requirements.PayTo)
+	if err != nil {
+		return false, fmt.Sprintf("invalid payTo: %v", err)
+	}
+	destination, err := svm.FindAssociatedTokenAddress(payTo, transfer.Mint, transfer.ProgramID)
+	if err != nil {
+		return false, fmt.Sprintf("failed to derive payTo token account: %v", err)
+	}
+	if transfer.Destination != destination {
+		return false, fmt.Sprintf("recipient mismatch: got %s, expected token account %s of %s", transfer.Destination, destination, payTo)
+	}
+	return true, ""
+}
+
+func (f *Facilitator) settleExactSVMScheme(ctx context.Context, payload *types.PaymentPayload, requirements *types.PaymentRequirements) *types.SettleResponse {
+	// Re-check the transaction before signing it as fee payer
+	tx, transfer, failures := f.checkSVMPayment(payload, requirements, false)
+	if len(failures) > 0 {
+		return settleFailed(failures[0].Code, failures[0].Reason)
+	}
+
+	// Get RPC client
+	client, err := f.getSVMClient(requirements.Network)
+	if err != nil {
+		return settleFailed(types.ErrCodeNetworkError, fmt.Sprintf("failed to connect to network: %v", err))
+	}
+
+	// Complete and send the transaction
+	if err := tx.Sign(f.solanaFeePayer()); err != nil {
+		return settleFailed(types.ErrCodeSettlementFailed, fmt.Sprintf("failed to sign transaction: %v", err))
+	}
+	signature, err := client.SendTransaction(ctx, tx)
+	if err != nil {
+		return settleFailed(types.ErrCodeSettlementFailed, fmt.Sprintf("failed to settle payment: %v", err))
+	}
+
+	resp := &types.SettleResponse{
+		Success:     true,
+		Transaction: signature,
+		Network:     requirements.Network,
+		Payer:       transfer.Authority.String(),
+	}
+	if f.cfg().Transaction.Confirmations <= 0 {
+		return resp
+	}
+
+	f.confirmSVMSettlement(ctx, client, signature, resp)
+	return resp
+}
+
+// confirmSVMSettlement waits within the transaction timeout for a settlement
+// to reach the confirmed commitment level
+func (f *Facilitator) confirmSVMSettlement(ctx context.Context, client *svm.Client, signature string, resp *types.SettleResponse) {
+	waitCtx, cancel := context.WithTimeout(ctx, time.Duration(f.cfg().Transaction.TimeoutSeconds)*time.Second)
+	defer cancel()
+
+	ticker := time.NewTicker(f.confirmationPollInterval)
+	defer ticker.Stop()
+	for {
+		status, err := client.GetSignatureStatus(waitCtx, signature)
+		if err == nil && status != nil {
+			if status.Failed() {
+				resp.Status = types.SettleStatusReverted
+				resp.BlockNumber = status.Slot
+				(&settleError{code: SettleErrTransactionReverted, err: fmt.Errorf("transaction failed on-chain: %s", status.Err)}).fail(resp)
+				return
+			}
+			if status.Reached(svm.CommitmentConfirmed) {
+				resp.Status = types.SettleStatusConfirmed
+				resp.BlockNumber = status.Slot
+				return
+			}
+		}
+
+		select {
+		case <-waitCtx.Done():
+			(&settleError{code: SettleErrNotConfirmed, err: waitCtx.Err()}).fail(resp)
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// checkSVMRPC checks a Solana node reports itself healthy
+func (f *Facilitator) checkSVMRPC(ctx context.Context, network string) error {
+	client, err := f.getSVMClient(network)
+	if err != nil {
+		return err
+	}
+	if err := client.GetHealth(ctx); err != nil {
+		return fmt.Errorf("RPC unavailable: %w", err)
+	}
+	return nil
+}
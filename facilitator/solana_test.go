@@ -0,0 +1,193 @@
+package facilitator
+
+import (
+	"bytes"
+	"crypto/ed25519"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/vorpalengineering/x402-go/svm"
+	"github.com/vorpalengineering/x402-go/types"
+)
+
+func TestParseSolanaPrivateKey(t *testing.T) {
+	key := ed25519.NewKeyFromSeed(bytes.Repeat([]byte{1}, ed25519.SeedSize))
+
+	// Base58 keypair
+	parsed, err := parseSolanaPrivateKey(svm.EncodeBase58(key))
+	if err != nil || !parsed.Equal(key) {
+		t.Errorf("Expected base58 key to parse, got %v", err)
+	}
+
+	// solana-keygen JSON array
+	values := make([]string, len(key))
+	for i, b := range key {
+		values[i] = fmt.Sprint(b)
+	}
+	parsed, err = parseSolanaPrivateKey("[" + strings.Join(values, ",") + "]")
+	if err != nil || !parsed.Equal(key) {
+		t.Errorf("Expected JSON key to parse, got %v", err)
+	}
+
+	// Keypair with a mismatched public key
+	tampered := bytes.Clone(key)
+	tampered[63] ^= 1
+	if _, err := parseSolanaPrivateKey(svm.EncodeBase58(tampered)); err == nil {
+		t.Error("Expected error for mismatched public key")
+	}
+}
+
+func TestSolanaVerifyAndSettle(t *testing.T) {
+	feePayerKey := ed25519.NewKeyFromSeed(bytes.Repeat([]byte{1}, ed25519.SeedSize))
+	payerKey := ed25519.NewKeyFromSeed(bytes.Repeat([]byte{2}, ed25519.SeedSize))
+	payTo := svm.PublicKeyOf(ed25519.NewKeyFromSeed(bytes.Repeat([]byte{3}, ed25519.SeedSize)))
+	mint := svm.PublicKeyOf(ed25519.NewKeyFromSeed(bytes.Repeat([]byte{4}, ed25519.SeedSize)))
+
+	// Solana RPC accepting every transaction
+	var sent string
+	rpcServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req struct {
+			ID     json.RawMessage   `json:"id"`
+			Method string            `json:"method"`
+			Params []json.RawMessage `json:"params"`
+		}
+		json.NewDecoder(r.Body).Decode(&req)
+		result := `null`
+		switch req.Method {
+		case "getHealth":
+			result = `"ok"`
+		case "simulateTransaction":
+			result = `{"context":{"slot":1},"value":{"err":null,"logs":[]}}`
+		case "sendTransaction":
+			json.Unmarshal(req.Params[0], &sent)
+			data, _ := base64.StdEncoding.DecodeString(sent)
+			tx, _ := svm.ParseTransaction(data)
+			result = fmt.Sprintf("%q", tx.ID())
+		case "getSignatureStatuses":
+			result = `{"context":{"slot":2},"value":[{"slot":2,"err":null,"confirmationStatus":"confirmed"}]}`
+		}
+		w.Header().Set("Content-Type", "application/json")
+		fmt.Fprintf(w, `{"jsonrpc":"2.0","id":%s,"result":%s}`, req.ID, result)
+	}))
+	defer rpcServer.Close()
+
+	f := NewFacilitator(&FacilitatorConfig{
+		Networks: map[string]NetworkConfig{
+			"solana:devnet": {RpcUrl: rpcServer.URL},
+		},
+		Supported: []types.SupportedKind{
+			{Scheme: "exact", Network: "solana:devnet"},
+		},
+		Transaction: TransactionConfig{TimeoutSeconds: 5, Confirmations: 1},
+		Log:         LogConfig{Level: "error"},
+		Solana:      SolanaConfig{PrivateKey: feePayerKey},
+	})
+	f.confirmationPollInterval = time.Millisecond
+
+	// Clients learn the fee payer from /supported
+	recorder := httptest.NewRecorder()
+	f.router.ServeHTTP(recorder, httptest.NewRequest("GET", "/supported", nil))
+	var supported types.SupportedResponse
+	json.NewDecoder(recorder.Body).Decode(&supported)
+	feePayer := svm.PublicKeyOf(feePayerKey).String()
+	if len(supported.Kinds) != 1 || supported.Kinds[0].Extra["feePayer"] != feePayer {
+		t.Errorf("Expected fee payer %s in supported kinds, got %+v", feePayer, supported.Kinds)
+	}
+	if signers := supported.Signers["solana:*"]; len(signers) != 1 || signers[0] != feePayer {
+		t.Errorf("Expected solana signer %s, got %v", feePayer, signers)
+	}
+
+	requirements := types.PaymentRequirements{
+		Scheme:  "exact",
+		Network: "solana:devnet",
+		Amount:  "10000",
+		Asset:   mint.String(),
+		PayTo:   payTo.String(),
+	}
+	payment := svm.ExactPayment{
+		FeePayer:       svm.PublicKeyOf(feePayerKey),
+		Payer:          svm.PublicKeyOf(payerKey),
+		PayTo:          payTo,
+		Mint:           mint,
+		TokenProgramID: svm.TokenProgramID,
+		Amount:         10000,
+		Decimals:       6,
+	}
+	payload := func(payment svm.ExactPayment) types.PaymentPayload {
+		tx, _ := svm.NewExactPaymentTransaction(payment)
+		tx.Sign(payerKey)
+		return types.PaymentPayload{
+			X402Version: 2,
+			Accepted:    requirements,
+			Payload:     map[string]any{"transaction": base64.StdEncoding.EncodeToString(tx.Serialize())},
+		}
+	}
+	verify := func(payload types.PaymentPayload) types.VerifyResponse {
+		body, _ := json.Marshal(types.VerifyRequest{PaymentPayload: payload, PaymentRequirements: requirements})
+		recorder := httptest.NewRecorder()
+		f.router.ServeHTTP(recorder, httptest.NewRequest("POST", "/verify", bytes.NewReader(body)))
+		var res types.VerifyResponse
+		json.NewDecoder(recorder.Body).Decode(&res)
+		return res
+	}
+
+	// Valid payment
+	res := verify(payload(payment))
+	if !res.IsValid {
+		t.Fatalf("Expected payment to be valid, got %s", res.InvalidReason)
+	}
+	if res.Payer != payment.Payer.String() {
+		t.Errorf("Expected payer %s, got %s", payment.Payer, res.Payer)
+	}
+
+	// Invalid payments
+	underpaid := payment
+	underpaid.Amount = 9999
+	otherFeePayer := payment
+	otherFeePayer.FeePayer = payTo
+	otherRecipient := payment
+	otherRecipient.PayTo = payment.Payer
+	expensive := payment
+	expensive.ComputeUnitPrice = defaultSolanaMaxComputeUnitPrice + 1
+	for name, test := range map[string]struct {
+		payment svm.ExactPayment
+		reason  string
+	}{
+		"underpaid":       {underpaid, "insufficient amount"},
+		"other fee payer": {otherFeePayer, "fee payer mismatch"},
+		"other recipient": {otherRecipient, "recipient mismatch"},
+		"expensive":       {expensive, "compute unit price too high"},
+	} {
+		if res := verify(payload(test.payment)); res.IsValid || !strings.Contains(res.InvalidMessage, test.reason) {
+			t.Errorf("%s: expected %q, got %q", name, test.reason, res.InvalidMessage)
+		}
+	}
+
+	// Settle completes the transaction as fee payer
+	body, _ := json.Marshal(types.SettleRequest{PaymentPayload: payload(payment), PaymentRequirements: requirements})
+	recorder = httptest.NewRecorder()
+	f.router.ServeHTTP(recorder, httptest.NewRequest("POST", "/settle", bytes.NewReader(body)))
+	var settled types.SettleResponse
+	json.NewDecoder(recorder.Body).Decode(&settled)
+	if !settled.Success || settled.Status != types.SettleStatusConfirmed {
+		t.Fatalf("Expected confirmed settlement, got %+v", settled)
+	}
+
+	data, _ := base64.StdEncoding.DecodeString(sent)
+	tx, err := svm.ParseTransaction(data)
+	if err != nil {
+		t.Fatalf("Failed to parse sent transaction: %v", err)
+	}
+	if !tx.VerifySignature(0) || !tx.VerifySignature(1) {
+		t.Error("Expected sent transaction to be fully signed")
+	}
+	if settled.Transaction != tx.ID() {
+		t.Errorf("Expected transaction %s, got %s", tx.ID(), settled.Transaction)
+	}
+}
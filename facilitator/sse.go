@@ -0,0 +1,227 @@
+package facilitator
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	defaultSSEBufferSize     = 256
+	defaultSSEMaxSubscribers = 100
+	defaultSSEKeepalive      = 15 * time.Second
+)
+
+// SSEConfig serves events to dashboards as server-sent events at GET /events
+type SSEConfig struct {
+	Enabled bool `yaml:"enabled"`
+	// Events buffered per subscriber, slower subscribers are disconnected (default 256)
+	BufferSize int `yaml:"buffer_size"`
+	// Subscribers connected at once (default 100)
+	MaxSubscribers int `yaml:"max_subscribers"`
+	// Interval of keepalive comments on idle streams (default 15)
+	KeepaliveSeconds int `yaml:"keepalive_seconds"`
+	// RequireAdminToken requires the admin token as a bearer token
+	RequireAdminToken bool `yaml:"require_admin_token"`
+}
+
+func (sseCfg SSEConfig) validate() error {
+	if sseCfg.BufferSize < 0 || sseCfg.MaxSubscribers < 0 || sseCfg.KeepaliveSeconds < 0 {
+		return fmt.Errorf("buffer_size, max_subscribers and keepalive_seconds cannot be negative")
+	}
+	return nil
+}
+
+// sseFilter selects the events a subscriber receives. Empty fields match
+// every event.
+type sseFilter struct {
+	networks map[string]bool
+	types    map[string]bool
+	payer    string
+}
+
+func (filter sseFilter) matches(event Event) bool {
+	if len(filter.networks) > 0 && !filter.networks[event.Network] {
+		return false
+	}
+	if len(filter.types) > 0 && !filter.types[event.Type] {
+		return false
+	}
+	// EVM addresses match in any case, Solana's base58 ones exactly
+	if filter.payer == "" || filter.payer == event.Payer {
+		return true
+	}
+	return common.IsHexAddress(filter.payer) && strings.EqualFold(filter.payer, event.Payer)
+}
+
+type sseSubscriber struct {
+	filter sseFilter
+	events chan Event
+}
+
+// sseHub fans events out to the connected subscribers. Publishing never
+// blocks, a subscriber whose buffer is full is disconnected instead.
+type sseHub struct {
+	bufferSize     int
+	maxSubscribers int
+
+	mu          sync.Mutex
+	subscribers map[*sseSubscriber]struct{}
+	closed      bool
+}
+
+func newSSEHub(cfg SSEConfig) *sseHub {
+	hub := &sseHub{
+		bufferSize:     cfg.BufferSize,
+		maxSubscribers: cfg.MaxSubscribers,
+		subscribers:    make(map[*sseSubscriber]struct{}),
+	}
+	if hub.bufferSize <= 0 {
+		hub.bufferSize = defaultSSEBufferSize
+	}
+	if hub.maxSubscribers <= 0 {
+		hub.maxSubscribers = defaultSSEMaxSubscribers
+	}
+	return hub
+}
+
+func (h *sseHub) subscribe(filter sseFilter) (*sseSubscriber, error) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if h.closed {
+		return nil, fmt.Errorf("shutting down")
+	}
+	if len(h.subscribers) >= h.maxSubscribers {
+		return nil, fmt.Errorf("too many subscribers")
+	}
+	sub := &sseSubscriber{filter: filter, events: make(chan Event, h.bufferSize)}
+	h.subscribers[sub] = struct{}{}
+	return sub, nil
+}
+
+// unsubscribe removes a subscriber and closes its channel, if still subscribed
+func (h *sseHub) unsubscribe(sub *sseSubscriber) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if _, ok := h.subscribers[sub]; ok {
+		delete(h.subscribers, sub)
+		close(sub.events)
+	}
+}
+
+func (h *sseHub) publish(event Event) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	for sub := range h.subscribers {
+		if !sub.filter.matches(event) {
+			continue
+		}
+		select {
+		case sub.events <- event:
+		default:
+			delete(h.subscribers, sub)
+			close(sub.events)
+		}
+	}
+}
+
+// close disconnects every subscriber and refuses new ones
+func (h *sseHub) close() {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	h.closed = true
+	for sub := range h.subscribers {
+		delete(h.subscribers, sub)
+		close(sub.events)
+	}
+}
+
+// parseSSEFilter reads the network, payer and type query parameters.
+// Networks and types are repeatable or comma-separated.
+func parseSSEFilter(ginCtx *gin.Context) (sseFilter, error) {
+	values := func(name string) map[string]bool {
+		set := make(map[string]bool)
+		for _, param := range ginCtx.QueryArray(name) {
+			for _, value := range strings.Split(param, ",") {
+				if value = strings.TrimSpace(value); value != "" {
+					set[value] = true
+				}
+			}
+		}
+		return set
+	}
+
+	filter := sseFilter{networks: values("network"), types: values("type"), payer: ginCtx.Query("payer")}
+	if strings.HasPrefix(filter.payer, "0x") && !common.IsHexAddress(filter.payer) {
+		return sseFilter{}, fmt.Errorf("payer must be a valid address")
+	}
+	return filter, nil
+}
+
+// handleEvents streams matching events as server-sent events until the
+// client disconnects or the facilitator shuts down
+func (f *Facilitator) handleEvents(ginCtx *gin.Context) {
+	filter, err := parseSSEFilter(ginCtx)
+	if err != nil {
+		ginCtx.JSON(http.StatusBadRequest, gin.H{
+			"error": err.Error(),
+		})
+		return
+	}
+	sub, err := f.sse.subscribe(filter)
+	if err != nil {
+		ginCtx.JSON(http.StatusServiceUnavailable, gin.H{
+			"error": err.Error(),
+		})
+		return
+	}
+	defer f.sse.unsubscribe(sub)
+
+	header := ginCtx.Writer.Header()
+	header.Set("Content-Type", "text/event-stream")
+	header.Set("Cache-Control", "no-cache")
+	header.Set("Connection", "keep-alive")
+	header.Set("X-Accel-Buffering", "no")
+	ginCtx.Status(http.StatusOK)
+	ginCtx.Writer.Flush()
+
+	keepalive := defaultSSEKeepalive
+	if seconds := f.cfg().Events.SSE.KeepaliveSeconds; seconds > 0 {
+		keepalive = time.Duration(seconds) * time.Second
+	}
+	ticker := time.NewTicker(keepalive)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ginCtx.Request.Context().Done():
+			return
+		case <-ticker.C:
+			if _, err := fmt.Fprint(ginCtx.Writer, ": keepalive\n\n"); err != nil {
+				return
+			}
+		case event, ok := <-sub.events:
+			if !ok {
+				return
+			}
+			data, err := json.Marshal(event)
+			if err != nil {
+				continue
+			}
+			if _, err := fmt.Fprintf(ginCtx.Writer, "id: %s\nevent: %s\ndata: %s\n\n", event.ID, event.Type, data); err != nil {
+				return
+			}
+		}
+		ginCtx.Writer.Flush()
+	}
+}
@@ -0,0 +1,141 @@
+package facilitator
+
+import (
+	"bufio"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/vorpalengineering/x402-go/types"
+)
+
+func TestEventsStreamFiltersByNetworkAndPayer(t *testing.T) {
+	testConfig := &FacilitatorConfig{
+		Networks: map[string]NetworkConfig{
+			"eip155:8453": {
+				RpcUrl: "http://127.0.0.1:1",
+			},
+		},
+		Supported: []types.SupportedKind{
+			{Scheme: "exact", Network: "eip155:8453"},
+		},
+		Log: LogConfig{
+			Level: "info",
+		},
+		Events: EventsConfig{
+			SSE: SSEConfig{Enabled: true},
+		},
+	}
+	f := NewFacilitator(testConfig)
+	defer f.Close()
+	server := httptest.NewServer(f.router)
+	defer server.Close()
+
+	payer := "0xf39Fd6e51aad88F6F4ce6aB8827279cffFb92266"
+	res, err := http.Get(server.URL + "/events?network=eip155:8453&payer=" + strings.ToLower(payer))
+	if err != nil {
+		t.Fatalf("Failed to connect: %v", err)
+	}
+	defer res.Body.Close()
+	if res.StatusCode != http.StatusOK || res.Header.Get("Content-Type") != "text/event-stream" {
+		t.Fatalf("Expected event stream, got %d %s", res.StatusCode, res.Header.Get("Content-Type"))
+	}
+
+	// Wait for the subscription before publishing
+	deadline := time.Now().Add(time.Second)
+	for {
+		f.sse.mu.Lock()
+		subscribed := len(f.sse.subscribers) == 1
+		f.sse.mu.Unlock()
+		if subscribed {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("Subscriber was not registered")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	f.publishEvent(Event{Type: EventSettle, Network: "eip155:1", Payer: payer})
+	f.publishEvent(Event{Type: EventSettle, Network: "eip155:8453", Payer: "0x742d35Cc6634C0532925a3b844Bc9e7595f0bEb"})
+	f.publishEvent(Event{Type: EventSettleConfirmed, Network: "eip155:8453", Payer: payer, Transaction: "0xabc"})
+
+	reader := bufio.NewReader(res.Body)
+	var eventType, data string
+	for data == "" {
+		line, err := reader.ReadString('\n')
+		if err != nil {
+			t.Fatalf("Failed to read stream: %v", err)
+		}
+		if value, ok := strings.CutPrefix(line, "event: "); ok {
+			eventType = strings.TrimSpace(value)
+		}
+		if value, ok := strings.CutPrefix(line, "data: "); ok {
+			data = strings.TrimSpace(value)
+		}
+	}
+
+	var event Event
+	if err := json.Unmarshal([]byte(data), &event); err != nil {
+		t.Fatalf("Failed to decode event: %v", err)
+	}
+	if eventType != EventSettleConfirmed || event.Transaction != "0xabc" || event.ID == "" {
+		t.Errorf("Expected only the matching settle.confirmed event, got %s %+v", eventType, event)
+	}
+}
+
+func TestEventsStreamRequiresAdminToken(t *testing.T) {
+	testConfig := &FacilitatorConfig{
+		Log: LogConfig{
+			Level: "info",
+		},
+		Admin: AdminConfig{
+			Enabled: true,
+			Token:   "secret",
+		},
+		Events: EventsConfig{
+			SSE: SSEConfig{Enabled: true, RequireAdminToken: true},
+		},
+	}
+	f := NewFacilitator(testConfig)
+	defer f.Close()
+
+	recorder := httptest.NewRecorder()
+	f.router.ServeHTTP(recorder, httptest.NewRequest("GET", "/events", nil))
+	if recorder.Code != http.StatusUnauthorized {
+		t.Errorf("Expected 401 without token, got %d", recorder.Code)
+	}
+}
+
+func TestSSEHubDisconnectsSlowSubscriber(t *testing.T) {
+	hub := newSSEHub(SSEConfig{BufferSize: 1, MaxSubscribers: 1})
+
+	sub, err := hub.subscribe(sseFilter{types: map[string]bool{EventSettle: true}})
+	if err != nil {
+		t.Fatalf("Failed to subscribe: %v", err)
+	}
+	if _, err := hub.subscribe(sseFilter{}); err == nil {
+		t.Error("Expected subscriber limit to be enforced")
+	}
+
+	hub.publish(Event{Type: EventVerify})
+	hub.publish(Event{Type: EventSettle})
+	hub.publish(Event{Type: EventSettle})
+
+	if event, ok := <-sub.events; !ok || event.Type != EventSettle {
+		t.Errorf("Expected the buffered settle event, got %+v", event)
+	}
+	if _, ok := <-sub.events; ok {
+		t.Error("Expected slow subscriber to be disconnected")
+	}
+
+	// Unsubscribing after being dropped is a no-op
+	hub.unsubscribe(sub)
+	hub.close()
+	if _, err := hub.subscribe(sseFilter{}); err == nil {
+		t.Error("Expected closed hub to refuse subscribers")
+	}
+}
@@ -0,0 +1,376 @@
+package facilitator
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"math/big"
+	"net/http"
+	"slices"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/gin-gonic/gin"
+	"github.com/vorpalengineering/x402-go/types"
+	"github.com/vorpalengineering/x402-go/utils"
+)
+
+const (
+	defaultChallengeTTL            = 5 * time.Minute
+	defaultStatementRetentionDays  = 400
+	defaultStatementMaxRecords     = 1000000
+	maxStatementChallenges         = 10000
+	maxStatementChallengesPerPayer = 5
+)
+
+var errTooManyChallenges = errors.New("too many outstanding challenges, try again later")
+
+type settlementRecord struct {
+	timestamp   time.Time
+	payer       common.Address
+	network     string
+	asset       string
+	payTo       string
+	amount      string
+	transaction string
+	attempts    int
+	// refunded is the amount refunded or being refunded
+	refunded *big.Int
+	// refunds are the refunds sent for this settlement
+	refunds []refundRecord
+}
+
+type statementChallenge struct {
+	payer     common.Address
+	expiresAt time.Time
+}
+
+// statementStore keeps settled payments in memory for statement generation
+// and refunds. Records older than the retention, or beyond the maximum
+// count, are dropped oldest first, and nothing survives a restart.
+type statementStore struct {
+	retention  time.Duration
+	maxRecords int
+
+	mu sync.Mutex
+	// records are in the order they were settled
+	records       []*settlementRecord
+	byPayer       map[common.Address][]*settlementRecord
+	byTransaction map[string][]*settlementRecord
+	challenges    map[string]statementChallenge
+	// outstanding counts the unexpired challenges of each payer
+	outstanding map[common.Address]int
+}
+
+func newStatementStore(cfg StatementsConfig) *statementStore {
+	store := &statementStore{
+		retention:     time.Duration(cfg.RetentionDays) * 24 * time.Hour,
+		maxRecords:    cfg.MaxRecords,
+		byPayer:       make(map[common.Address][]*settlementRecord),
+		byTransaction: make(map[string][]*settlementRecord),
+		challenges:    make(map[string]statementChallenge),
+		outstanding:   make(map[common.Address]int),
+	}
+	if cfg.RetentionDays <= 0 {
+		store.retention = defaultStatementRetentionDays * 24 * time.Hour
+	}
+	if store.maxRecords <= 0 {
+		store.maxRecords = defaultStatementMaxRecords
+	}
+	return store
+}
+
+// transactionKey indexes the settlements of a transaction
+func transactionKey(network, transaction string) string {
+	return network + "|" + strings.ToLower(transaction)
+}
+
+func (s *statementStore) record(rec settlementRecord) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	rec.refunded = new(big.Int)
+	stored := &rec
+	s.records = append(s.records, stored)
+	s.byPayer[rec.payer] = append(s.byPayer[rec.payer], stored)
+	key := transactionKey(rec.network, rec.transaction)
+	s.byTransaction[key] = append(s.byTransaction[key], stored)
+
+	// Drop the oldest records past the retention or the maximum count
+	cutoff := rec.timestamp.Add(-s.retention)
+	for len(s.records) > 0 && (len(s.records) > s.maxRecords || s.records[0].timestamp.Before(cutoff)) {
+		s.drop(s.records[0])
+		s.records[0] = nil
+		s.records = s.records[1:]
+	}
+}
+
+// drop removes a record from the indexes. Callers must hold mu.
+func (s *statementStore) drop(rec *settlementRecord) {
+	s.byPayer[rec.payer] = removeRecord(s.byPayer[rec.payer], rec)
+	if len(s.byPayer[rec.payer]) == 0 {
+		delete(s.byPayer, rec.payer)
+	}
+	key := transactionKey(rec.network, rec.transaction)
+	s.byTransaction[key] = removeRecord(s.byTransaction[key], rec)
+	if len(s.byTransaction[key]) == 0 {
+		delete(s.byTransaction, key)
+	}
+}
+
+func removeRecord(records []*settlementRecord, rec *settlementRecord) []*settlementRecord {
+	for i, r := range records {
+		if r == rec {
+			return slices.Delete(records, i, i+1)
+		}
+	}
+	return records
+}
+
+func (s *statementStore) issueChallenge(payer common.Address, now time.Time, ttl time.Duration) (string, time.Time, error) {
+	var buf [32]byte
+	if _, err := rand.Read(buf[:]); err != nil {
+		return "", time.Time{}, fmt.Errorf("failed to generate challenge: %w", err)
+	}
+	challenge := "0x" + hex.EncodeToString(buf[:])
+	expiresAt := now.Add(ttl)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	// Drop expired challenges
+	for c, entry := range s.challenges {
+		if now.After(entry.expiresAt) {
+			s.removeChallenge(c, entry)
+		}
+	}
+	if len(s.challenges) >= maxStatementChallenges || s.outstanding[payer] >= maxStatementChallengesPerPayer {
+		return "", time.Time{}, errTooManyChallenges
+	}
+	s.challenges[challenge] = statementChallenge{payer: payer, expiresAt: expiresAt}
+	s.outstanding[payer]++
+
+	return challenge, expiresAt, nil
+}
+
+// removeChallenge forgets a challenge. Callers must hold mu.
+func (s *statementStore) removeChallenge(challenge string, entry statementChallenge) {
+	delete(s.challenges, challenge)
+	if s.outstanding[entry.payer]--; s.outstanding[entry.payer] <= 0 {
+		delete(s.outstanding, entry.payer)
+	}
+}
+
+// consumeChallenge removes a challenge and reports whether it was valid for payer
+func (s *statementStore) consumeChallenge(challenge string, payer common.Address, now time.Time) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	entry, ok := s.challenges[challenge]
+	if !ok {
+		return false
+	}
+	s.removeChallenge(challenge, entry)
+	return entry.payer == payer && !now.After(entry.expiresAt)
+}
+
+func (s *statementStore) statement(payer common.Address, period string, from, to time.Time) *types.Statement {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	statement := &types.Statement{
+		Payer:    payer.Hex(),
+		Period:   period,
+		From:     from.Unix(),
+		To:       to.Unix(),
+		Payments: []types.StatementLine{},
+		Refunds:  []types.StatementRefund{},
+		Totals:   []types.StatementTotal{},
+	}
+
+	// Collect payments and sum per network/asset
+	totals := make(map[string]*types.StatementTotal)
+	sums := make(map[string]*big.Int)
+	refunded := make(map[string]*big.Int)
+	var refunds []refundRecord
+	for _, rec := range s.byPayer[payer] {
+		refunds = append(refunds, rec.refunds...)
+		if rec.timestamp.Before(from) || !rec.timestamp.Before(to) {
+			continue
+		}
+		statement.Payments = append(statement.Payments, types.StatementLine{
+			Timestamp:   rec.timestamp.Unix(),
+			Network:     rec.network,
+			Asset:       rec.asset,
+			PayTo:       rec.payTo,
+			Amount:      rec.amount,
+			Transaction: rec.transaction,
+			Attempts:    rec.attempts,
+		})
+
+		key := rec.network + "|" + strings.ToLower(rec.asset)
+		if _, ok := totals[key]; !ok {
+			totals[key] = &types.StatementTotal{Network: rec.network, Asset: rec.asset}
+			sums[key] = new(big.Int)
+		}
+		if amount, ok := new(big.Int).SetString(rec.amount, 10); ok {
+			sums[key].Add(sums[key], amount)
+		}
+		totals[key].Count++
+	}
+
+	// Collect refunds and total them per network/asset
+	sort.SliceStable(refunds, func(i, j int) bool {
+		return refunds[i].timestamp.Before(refunds[j].timestamp)
+	})
+	for _, refund := range refunds {
+		if refund.timestamp.Before(from) || !refund.timestamp.Before(to) {
+			continue
+		}
+		statement.Refunds = append(statement.Refunds, types.StatementRefund{
+			Timestamp:   refund.timestamp.Unix(),
+			Network:     refund.network,
+			Asset:       refund.asset,
+			Amount:      refund.amount.String(),
+			Transaction: refund.transaction,
+			Settlement:  refund.settlement,
+			Reason:      refund.reason,
+		})
+
+		key := refund.network + "|" + strings.ToLower(refund.asset)
+		if _, ok := totals[key]; !ok {
+			totals[key] = &types.StatementTotal{Network: refund.network, Asset: refund.asset}
+			sums[key] = new(big.Int)
+		}
+		if _, ok := refunded[key]; !ok {
+			refunded[key] = new(big.Int)
+		}
+		refunded[key].Add(refunded[key], refund.amount)
+	}
+
+	// Emit totals in a stable order
+	keys := make([]string, 0, len(totals))
+	for key := range totals {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		total := totals[key]
+		total.Amount = sums[key].String()
+		if amount, ok := refunded[key]; ok {
+			total.Refunded = amount.String()
+		}
+		statement.Totals = append(statement.Totals, *total)
+	}
+
+	return statement
+}
+
+// recordSettlement adds a successful settlement to the statement store
+func (f *Facilitator) recordSettlement(payload *types.PaymentPayload, requirements *types.PaymentRequirements, resp *types.SettleResponse) {
+	// Statements are signed for by EVM payers
+	if f.statements == nil || !resp.Success || resp.Transaction == "" || utils.IsSolanaNetwork(requirements.Network) {
+		return
+	}
+
+	// Prefer the settled amount, then the authorized value, over the required amount
+	amount := requirements.Amount
+	if resp.Amount != "" {
+		amount = resp.Amount
+	} else if auth, err := utils.ExtractExactAuthorization(payload); err == nil && auth.Value != "" {
+		amount = auth.Value
+	}
+
+	f.statements.record(settlementRecord{
+		timestamp:   f.now(),
+		payer:       common.HexToAddress(resp.Payer),
+		network:     requirements.Network,
+		asset:       requirements.Asset,
+		payTo:       requirements.PayTo,
+		amount:      amount,
+		transaction: resp.Transaction,
+		attempts:    resp.Attempts,
+	})
+}
+
+func (f *Facilitator) handleStatementChallenge(ginCtx *gin.Context) {
+	payer := ginCtx.Query("payer")
+	if !common.IsHexAddress(payer) {
+		ginCtx.JSON(http.StatusBadRequest, gin.H{
+			"error": "payer must be a valid address",
+		})
+		return
+	}
+
+	ttl := defaultChallengeTTL
+	if f.cfg().Statements.ChallengeTTLSeconds > 0 {
+		ttl = time.Duration(f.cfg().Statements.ChallengeTTLSeconds) * time.Second
+	}
+
+	challenge, expiresAt, err := f.statements.issueChallenge(common.HexToAddress(payer), f.now(), ttl)
+	if errors.Is(err, errTooManyChallenges) {
+		ginCtx.JSON(http.StatusTooManyRequests, gin.H{
+			"error": err.Error(),
+		})
+		return
+	}
+	if err != nil {
+		ginCtx.JSON(http.StatusInternalServerError, gin.H{
+			"error": err.Error(),
+		})
+		return
+	}
+
+	ginCtx.JSON(http.StatusOK, types.StatementChallengeResponse{
+		Challenge: challenge,
+		ExpiresAt: expiresAt.Unix(),
+	})
+}
+
+func (f *Facilitator) handleStatement(ginCtx *gin.Context) {
+	// Decode request
+	var req types.StatementRequest
+	if err := ginCtx.ShouldBindJSON(&req); err != nil {
+		ginCtx.JSON(http.StatusBadRequest, gin.H{
+			"error": err.Error(),
+		})
+		return
+	}
+	if !common.IsHexAddress(req.Payer) {
+		ginCtx.JSON(http.StatusBadRequest, gin.H{
+			"error": "payer must be a valid address",
+		})
+		return
+	}
+
+	// Parse monthly period (e.g. "2025-01")
+	from, err := time.Parse("2006-01", req.Period)
+	if err != nil {
+		ginCtx.JSON(http.StatusBadRequest, gin.H{
+			"error": "period must be in YYYY-MM format",
+		})
+		return
+	}
+	to := from.AddDate(0, 1, 0)
+
+	// Authenticate payer
+	payer := common.HexToAddress(req.Payer)
+	signer, err := utils.RecoverPersonalSigner(utils.StatementMessage(req.Payer, req.Period, req.Challenge), req.Signature)
+	if err != nil || signer != payer {
+		ginCtx.JSON(http.StatusUnauthorized, gin.H{
+			"error": "signature does not match payer",
+		})
+		return
+	}
+	if !f.statements.consumeChallenge(req.Challenge, payer, f.now()) {
+		ginCtx.JSON(http.StatusUnauthorized, gin.H{
+			"error": "challenge is unknown or expired",
+		})
+		return
+	}
+
+	ginCtx.JSON(http.StatusOK, f.statements.statement(payer, req.Period, from, to))
+}
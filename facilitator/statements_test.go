@@ -0,0 +1,193 @@
+package facilitator
+
+import (
+	"bytes"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/vorpalengineering/x402-go/types"
+	"github.com/vorpalengineering/x402-go/utils"
+)
+
+func TestStatement(t *testing.T) {
+	privKey, _ := crypto.HexToECDSA("ac0974bec39a17e36ba4a6b4d238ff944bacb478cbed5efcae784d7bf4f2ff80")
+	payer := crypto.PubkeyToAddress(privKey.PublicKey)
+	testConfig := &FacilitatorConfig{
+		Log: LogConfig{
+			Level: "info",
+		},
+		Statements: StatementsConfig{
+			Enabled: true,
+		},
+	}
+
+	// Create facilitator
+	f := NewFacilitator(testConfig)
+	f.now = func() time.Time { return time.Date(2025, 1, 15, 0, 0, 0, 0, time.UTC) }
+
+	// Record two payments in January and one in February
+	asset := "0x833589fCD6eDb6E08f4c7C32D4f71b54bdA02913"
+	for _, rec := range []settlementRecord{
+		{timestamp: time.Date(2025, 1, 2, 0, 0, 0, 0, time.UTC), amount: "1000"},
+		{timestamp: time.Date(2025, 1, 20, 0, 0, 0, 0, time.UTC), amount: "2500"},
+		{timestamp: time.Date(2025, 2, 1, 0, 0, 0, 0, time.UTC), amount: "9999"},
+	} {
+		rec.payer = payer
+		rec.network = "eip155:8453"
+		rec.asset = asset
+		f.statements.record(rec)
+	}
+
+	// Request challenge
+	req, _ := http.NewRequest("GET", "/statements/challenge?payer="+payer.Hex(), nil)
+	recorder := httptest.NewRecorder()
+	f.router.ServeHTTP(recorder, req)
+	if recorder.Code != http.StatusOK {
+		t.Fatalf("Expected status code %d, got %d", http.StatusOK, recorder.Code)
+	}
+	var challenge types.StatementChallengeResponse
+	json.NewDecoder(recorder.Body).Decode(&challenge)
+
+	// Sign challenge
+	msg := utils.StatementMessage(payer.Hex(), "2025-01", challenge.Challenge)
+	sig, err := crypto.Sign(utils.HashPersonalMessage(msg).Bytes(), privKey)
+	if err != nil {
+		t.Fatalf("Failed to sign: %v", err)
+	}
+	sig[64] += 27
+	body, _ := json.Marshal(types.StatementRequest{
+		Payer:     payer.Hex(),
+		Period:    "2025-01",
+		Challenge: challenge.Challenge,
+		Signature: "0x" + hex.EncodeToString(sig),
+	})
+
+	// Fetch statement
+	req, _ = http.NewRequest("POST", "/statements", bytes.NewReader(body))
+	recorder = httptest.NewRecorder()
+	f.router.ServeHTTP(recorder, req)
+	if recorder.Code != http.StatusOK {
+		t.Fatalf("Expected status code %d, got %d: %s", http.StatusOK, recorder.Code, recorder.Body.String())
+	}
+	var statement types.Statement
+	json.NewDecoder(recorder.Body).Decode(&statement)
+
+	if len(statement.Payments) != 2 {
+		t.Errorf("Expected 2 payments, got %d", len(statement.Payments))
+	}
+	if len(statement.Totals) != 1 || statement.Totals[0].Amount != "3500" {
+		t.Errorf("Expected single total of 3500, got %+v", statement.Totals)
+	}
+
+	// Challenge cannot be reused
+	req, _ = http.NewRequest("POST", "/statements", bytes.NewReader(body))
+	recorder = httptest.NewRecorder()
+	f.router.ServeHTTP(recorder, req)
+	if recorder.Code != http.StatusUnauthorized {
+		t.Errorf("Expected status code %d for reused challenge, got %d", http.StatusUnauthorized, recorder.Code)
+	}
+}
+
+func TestStatementWrongSigner(t *testing.T) {
+	privKey, _ := crypto.HexToECDSA("ac0974bec39a17e36ba4a6b4d238ff944bacb478cbed5efcae784d7bf4f2ff80")
+	payer := common.HexToAddress("0x0000000000000000000000000000000000000001")
+	testConfig := &FacilitatorConfig{
+		Log: LogConfig{
+			Level: "info",
+		},
+		Statements: StatementsConfig{
+			Enabled: true,
+		},
+	}
+
+	f := NewFacilitator(testConfig)
+	challenge, _, _ := f.statements.issueChallenge(payer, time.Now(), time.Minute)
+
+	// Sign with a key that is not the payer's
+	msg := utils.StatementMessage(payer.Hex(), "2025-01", challenge)
+	sig, _ := crypto.Sign(utils.HashPersonalMessage(msg).Bytes(), privKey)
+	body, _ := json.Marshal(types.StatementRequest{
+		Payer:     payer.Hex(),
+		Period:    "2025-01",
+		Challenge: challenge,
+		Signature: "0x" + hex.EncodeToString(sig),
+	})
+
+	req, _ := http.NewRequest("POST", "/statements", bytes.NewReader(body))
+	recorder := httptest.NewRecorder()
+	f.router.ServeHTTP(recorder, req)
+	if recorder.Code != http.StatusUnauthorized {
+		t.Errorf("Expected status code %d, got %d", http.StatusUnauthorized, recorder.Code)
+	}
+}
+
+func TestStatementStoreDropsOldRecords(t *testing.T) {
+	store := newStatementStore(StatementsConfig{RetentionDays: 30, MaxRecords: 2})
+	payer := common.HexToAddress("0xf39Fd6e51aad88F6F4ce6aB8827279cffFb92266")
+	start := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	record := func(day int, transaction string) {
+		store.record(settlementRecord{
+			timestamp:   start.AddDate(0, 0, day),
+			payer:       payer,
+			network:     "eip155:8453",
+			amount:      "1000",
+			transaction: transaction,
+		})
+	}
+
+	// The oldest record goes once the maximum count is reached
+	record(0, "0x01")
+	record(1, "0x02")
+	record(2, "0x03")
+	if len(store.records) != 2 || len(store.byPayer[payer]) != 2 {
+		t.Fatalf("Expected 2 records kept, got %d", len(store.records))
+	}
+	if _, _, err := store.reserveRefund("eip155:8453", "0x01", nil, big.NewInt(1)); !errors.Is(err, errRefundNotFound) {
+		t.Errorf("Expected dropped settlement to be unknown, got %v", err)
+	}
+
+	// Records past the retention go too
+	record(40, "0x04")
+	if len(store.records) != 1 || store.records[0].transaction != "0x04" || len(store.byTransaction) != 1 {
+		t.Errorf("Expected only the latest record kept, got %d records", len(store.records))
+	}
+}
+
+func TestStatementChallengesPerPayerLimit(t *testing.T) {
+	store := newStatementStore(StatementsConfig{})
+	payer := common.HexToAddress("0xf39Fd6e51aad88F6F4ce6aB8827279cffFb92266")
+	now := time.Now()
+
+	var challenges []string
+	for range maxStatementChallengesPerPayer {
+		challenge, _, err := store.issueChallenge(payer, now, time.Minute)
+		if err != nil {
+			t.Fatalf("Expected challenge to be issued, got %v", err)
+		}
+		challenges = append(challenges, challenge)
+	}
+	if _, _, err := store.issueChallenge(payer, now, time.Minute); !errors.Is(err, errTooManyChallenges) {
+		t.Errorf("Expected the payer's challenges to be capped, got %v", err)
+	}
+	other := common.HexToAddress("0x70997970C51812dc3A010C7d01b50e0d17dc79C8")
+	if _, _, err := store.issueChallenge(other, now, time.Minute); err != nil {
+		t.Errorf("Expected other payers to be unaffected, got %v", err)
+	}
+
+	// Used and expired challenges free their slots
+	store.consumeChallenge(challenges[0], payer, now)
+	if _, _, err := store.issueChallenge(payer, now, time.Minute); err != nil {
+		t.Errorf("Expected a used challenge to free a slot, got %v", err)
+	}
+	if _, _, err := store.issueChallenge(payer, now.Add(2*time.Minute), time.Minute); err != nil {
+		t.Errorf("Expected expired challenges to free slots, got %v", err)
+	}
+}
@@ -0,0 +1,451 @@
+package facilitator
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"math/big"
+	"net/http"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/vorpalengineering/x402-go/types"
+	"github.com/vorpalengineering/x402-go/utils"
+)
+
+// Payment streams let a client pay for continuous consumption. The stream is
+// opened with requirements whose amount caps the running total. The client
+// then signs an exact scheme authorization for each increment as it consumes,
+// and the facilitator settles the increments received since the last
+// settlement at intervals and when the stream is closed. Increments of a
+// network are aggregated into one transaction like batch settlements.
+
+const (
+	defaultStreamSettleInterval = time.Minute
+	defaultStreamIdleTimeout    = 10 * time.Minute
+	defaultStreamRetention      = time.Hour
+	defaultStreamPollInterval   = time.Second
+)
+
+var (
+	errStreamNotFound = errors.New("payment stream not found")
+	errStreamClosed   = errors.New("payment stream is closed")
+)
+
+// streamIncrement is an accepted increment waiting to be settled
+type streamIncrement struct {
+	req   types.SettleRequest
+	value *big.Int
+}
+
+type paymentStream struct {
+	stream        types.PaymentStream
+	limit         *big.Int
+	total         *big.Int
+	settled       *big.Int
+	pending       []streamIncrement
+	authorized    map[string]bool
+	lastIncrement time.Time
+	lastSettle    time.Time
+
+	// settleMu serializes settlements of the stream
+	settleMu sync.Mutex
+}
+
+// streamStore keeps payment streams until the retention period after they close
+type streamStore struct {
+	settleInterval time.Duration
+	idleTimeout    time.Duration
+	retention      time.Duration
+
+	mu      sync.Mutex
+	streams map[string]*paymentStream
+}
+
+func newStreamStore(cfg StreamsConfig) *streamStore {
+	st := &streamStore{
+		settleInterval: defaultStreamSettleInterval,
+		idleTimeout:    defaultStreamIdleTimeout,
+		retention:      defaultStreamRetention,
+		streams:        make(map[string]*paymentStream),
+	}
+	if cfg.SettleIntervalSeconds > 0 {
+		st.settleInterval = time.Duration(cfg.SettleIntervalSeconds) * time.Second
+	}
+	if cfg.IdleTimeoutSeconds > 0 {
+		st.idleTimeout = time.Duration(cfg.IdleTimeoutSeconds) * time.Second
+	}
+	if cfg.RetentionSeconds > 0 {
+		st.retention = time.Duration(cfg.RetentionSeconds) * time.Second
+	}
+	return st
+}
+
+// open adds a stream for requirements, capped at limit
+func (st *streamStore) open(requirements types.PaymentRequirements, limit *big.Int, now time.Time) types.PaymentStream {
+	var id [16]byte
+	rand.Read(id[:])
+	s := &paymentStream{
+		stream: types.PaymentStream{
+			ID:                  hex.EncodeToString(id[:]),
+			Status:              types.StreamOpen,
+			PaymentRequirements: requirements,
+			Total:               "0",
+			Settled:             "0",
+			Transactions:        []string{},
+			CreatedAt:           now.Unix(),
+			UpdatedAt:           now.Unix(),
+		},
+		limit:         limit,
+		total:         new(big.Int),
+		settled:       new(big.Int),
+		authorized:    make(map[string]bool),
+		lastIncrement: now,
+		lastSettle:    now,
+	}
+
+	st.mu.Lock()
+	defer st.mu.Unlock()
+	st.prune(now)
+	st.streams[s.stream.ID] = s
+	return s.stream
+}
+
+func (st *streamStore) lookup(id string) (*paymentStream, bool) {
+	st.mu.Lock()
+	defer st.mu.Unlock()
+	s, ok := st.streams[id]
+	return s, ok
+}
+
+// get returns a snapshot of a stream
+func (st *streamStore) get(id string) (types.PaymentStream, bool) {
+	st.mu.Lock()
+	defer st.mu.Unlock()
+	s, ok := st.streams[id]
+	if !ok {
+		return types.PaymentStream{}, false
+	}
+	return s.snapshot(), true
+}
+
+// snapshot copies the stream's state. Callers must hold the store's mu.
+func (s *paymentStream) snapshot() types.PaymentStream {
+	stream := s.stream
+	stream.Transactions = slices.Clone(s.stream.Transactions)
+	return stream
+}
+
+// checkIncrement reports why an increment can't be added to the stream.
+// Callers must hold the store's mu.
+func (s *paymentStream) checkIncrement(key, payer string, value *big.Int) error {
+	if s.stream.Status != types.StreamOpen {
+		return errStreamClosed
+	}
+	if s.stream.Payer != "" && s.stream.Payer != payer {
+		return fmt.Errorf("payer mismatch: got %s, expected %s", payer, s.stream.Payer)
+	}
+	if s.authorized[key] {
+		return fmt.Errorf("authorization already added to stream")
+	}
+	if total := new(big.Int).Add(s.total, value); total.Cmp(s.limit) > 0 {
+		return fmt.Errorf("increment exceeds stream limit: total would be %s, limit %s", total, s.limit)
+	}
+	return nil
+}
+
+// advance adds a verified increment to the stream
+func (st *streamStore) advance(s *paymentStream, payer string, increment streamIncrement, now time.Time) (types.PaymentStream, error) {
+	key := settlementKey(&increment.req.PaymentPayload, &increment.req.PaymentRequirements)
+
+	st.mu.Lock()
+	defer st.mu.Unlock()
+	if err := s.checkIncrement(key, payer, increment.value); err != nil {
+		return types.PaymentStream{}, err
+	}
+	s.authorized[key] = true
+	s.pending = append(s.pending, increment)
+	s.total.Add(s.total, increment.value)
+	s.stream.Payer = payer
+	s.stream.Total = s.total.String()
+	s.stream.UpdatedAt = now.Unix()
+	s.lastIncrement = now
+	return s.snapshot(), nil
+}
+
+// close stops the stream from taking increments
+func (st *streamStore) close(s *paymentStream, now time.Time) {
+	st.mu.Lock()
+	defer st.mu.Unlock()
+	if s.stream.Status == types.StreamOpen {
+		s.stream.Status = types.StreamClosed
+		s.stream.UpdatedAt = now.Unix()
+	}
+}
+
+// due closes idle streams and returns the streams with increments to settle,
+// either because their interval passed or because they are closed
+func (st *streamStore) due(now time.Time) []*paymentStream {
+	st.mu.Lock()
+	defer st.mu.Unlock()
+	st.prune(now)
+
+	var due []*paymentStream
+	for _, s := range st.streams {
+		if s.stream.Status == types.StreamOpen && now.Sub(s.lastIncrement) >= st.idleTimeout {
+			s.stream.Status = types.StreamClosed
+			s.stream.UpdatedAt = now.Unix()
+		}
+		if len(s.pending) == 0 {
+			continue
+		}
+		if s.stream.Status == types.StreamClosed || now.Sub(s.lastSettle) >= st.settleInterval {
+			due = append(due, s)
+		}
+	}
+	return due
+}
+
+// all returns every stream
+func (st *streamStore) all() []*paymentStream {
+	st.mu.Lock()
+	defer st.mu.Unlock()
+	streams := make([]*paymentStream, 0, len(st.streams))
+	for _, s := range st.streams {
+		streams = append(streams, s)
+	}
+	return streams
+}
+
+// prune forgets settled streams closed past retention. Callers must hold mu.
+func (st *streamStore) prune(now time.Time) {
+	cutoff := now.Add(-st.retention).Unix()
+	for id, s := range st.streams {
+		if s.stream.Status == types.StreamClosed && len(s.pending) == 0 && s.stream.UpdatedAt < cutoff {
+			delete(st.streams, id)
+		}
+	}
+}
+
+// settleStream settles the stream's pending increments in one batch
+func (f *Facilitator) settleStream(ctx context.Context, s *paymentStream) {
+	s.settleMu.Lock()
+	defer s.settleMu.Unlock()
+
+	// Take the pending increments
+	f.streams.mu.Lock()
+	pending := s.pending
+	s.pending = nil
+	f.streams.mu.Unlock()
+	if len(pending) == 0 {
+		return
+	}
+
+	// Settle them together
+	items := make([]types.SettleRequest, len(pending))
+	for i, increment := range pending {
+		items[i] = increment.req
+	}
+	resp := f.settleBatch(ctx, items)
+
+	// Add the settled increments to the stream
+	f.streams.mu.Lock()
+	defer f.streams.mu.Unlock()
+	for i, result := range resp.Results {
+		if !result.Success {
+			f.log(ctx).Warn("stream increment settlement failed", "stream", s.stream.ID, "code", result.ErrorReason, "reason", result.ErrorMessage)
+			s.stream.ErrorReason = result.ErrorReason
+			s.stream.ErrorMessage = result.ErrorMessage
+			continue
+		}
+		s.settled.Add(s.settled, pending[i].value)
+		if !slices.Contains(s.stream.Transactions, result.Transaction) {
+			s.stream.Transactions = append(s.stream.Transactions, result.Transaction)
+		}
+	}
+	s.stream.Settled = s.settled.String()
+	s.stream.UpdatedAt = f.now().Unix()
+	s.lastSettle = f.now()
+}
+
+// settleDueStreams settles every stream whose interval passed or that closed
+func (f *Facilitator) settleDueStreams(ctx context.Context) {
+	for _, s := range f.streams.due(f.now()) {
+		f.settleStream(ctx, s)
+	}
+}
+
+// runStreams settles streams in the background until ctx is done
+func (f *Facilitator) runStreams(ctx context.Context) {
+	ticker := time.NewTicker(defaultStreamPollInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			f.settleDueStreams(ctx)
+		}
+	}
+}
+
+// closeStreams closes every stream and settles what is pending until ctx is done
+func (f *Facilitator) closeStreams(ctx context.Context) {
+	for _, s := range f.streams.all() {
+		f.streams.close(s, f.now())
+		f.settleStream(ctx, s)
+	}
+}
+
+func (f *Facilitator) handleOpenStream(ginCtx *gin.Context) {
+	// Decode request
+	var req types.StreamOpenRequest
+	if err := ginCtx.ShouldBindJSON(&req); err != nil {
+		ginCtx.JSON(http.StatusBadRequest, gin.H{
+			"error": err.Error(),
+		})
+		return
+	}
+	requirements := req.PaymentRequirements
+
+	// Check scheme-network pair is supported
+	if requirements.Scheme != "stream" || utils.IsSolanaNetwork(requirements.Network) || !f.cfg().IsSupported(requirements.Scheme, requirements.Network) {
+		ginCtx.JSON(http.StatusBadRequest, gin.H{
+			"error": fmt.Sprintf("unsupported scheme-network: %s-%s", requirements.Scheme, requirements.Network),
+		})
+		return
+	}
+
+	// Parse the stream's limit
+	limit, ok := new(big.Int).SetString(requirements.Amount, 10)
+	if !ok || limit.Sign() <= 0 {
+		ginCtx.JSON(http.StatusBadRequest, gin.H{
+			"error": fmt.Sprintf("invalid amount: %s", requirements.Amount),
+		})
+		return
+	}
+
+	stream := f.streams.open(requirements, limit, f.now())
+	f.log(ginCtx.Request.Context()).Info("payment stream opened", "stream", stream.ID, "network", requirements.Network, "limit", requirements.Amount)
+	ginCtx.JSON(http.StatusCreated, stream)
+}
+
+func (f *Facilitator) handleAdvanceStream(ginCtx *gin.Context) {
+	// Decode request
+	var req types.StreamAdvanceRequest
+	if err := ginCtx.ShouldBindJSON(&req); err != nil {
+		ginCtx.JSON(http.StatusBadRequest, gin.H{
+			"error": err.Error(),
+		})
+		return
+	}
+
+	// Find stream
+	s, ok := f.streams.lookup(ginCtx.Param("streamId"))
+	if !ok {
+		ginCtx.JSON(http.StatusNotFound, gin.H{
+			"error": errStreamNotFound.Error(),
+		})
+		return
+	}
+
+	// Extract authorization from payload
+	auth, err := utils.ExtractExactAuthorization(&req.PaymentPayload)
+	if err != nil {
+		ginCtx.JSON(http.StatusBadRequest, gin.H{
+			"error": fmt.Sprintf("invalid authorization: %v", err),
+		})
+		return
+	}
+	value, ok := new(big.Int).SetString(auth.Value, 10)
+	if !ok || value.Sign() <= 0 {
+		ginCtx.JSON(http.StatusBadRequest, gin.H{
+			"error": fmt.Sprintf("invalid increment value: %s", auth.Value),
+		})
+		return
+	}
+
+	// The increment is an exact scheme payment of its value
+	f.streams.mu.Lock()
+	requirements := s.stream.PaymentRequirements
+	f.streams.mu.Unlock()
+	requirements.Scheme = "exact"
+	requirements.Amount = value.String()
+	payload := req.PaymentPayload
+	payload.Accepted = requirements
+	increment := streamIncrement{
+		req:   types.SettleRequest{PaymentPayload: payload, PaymentRequirements: requirements},
+		value: value,
+	}
+
+	// Check against the stream before verifying on-chain
+	key := settlementKey(&payload, &requirements)
+	f.streams.mu.Lock()
+	err = s.checkIncrement(key, auth.From, value)
+	f.streams.mu.Unlock()
+	if err != nil {
+		f.respondStreamError(ginCtx, err)
+		return
+	}
+
+	// Verify increment
+	ctx := ginCtx.Request.Context()
+	if failures := f.verifyPaymentChecks(ctx, &payload, &requirements, false); len(failures) > 0 {
+		ginCtx.JSON(http.StatusBadRequest, gin.H{
+			"error": failures[0].Reason,
+			"code":  failures[0].Code,
+		})
+		return
+	}
+
+	stream, err := f.streams.advance(s, auth.From, increment, f.now())
+	if err != nil {
+		f.respondStreamError(ginCtx, err)
+		return
+	}
+	ginCtx.JSON(http.StatusOK, stream)
+}
+
+func (f *Facilitator) handleCloseStream(ginCtx *gin.Context) {
+	// Find stream
+	s, ok := f.streams.lookup(ginCtx.Param("streamId"))
+	if !ok {
+		ginCtx.JSON(http.StatusNotFound, gin.H{
+			"error": errStreamNotFound.Error(),
+		})
+		return
+	}
+
+	// Close and settle what is left
+	f.streams.close(s, f.now())
+	f.settleStream(ginCtx.Request.Context(), s)
+
+	stream, _ := f.streams.get(s.stream.ID)
+	f.log(ginCtx.Request.Context()).Info("payment stream closed", "stream", stream.ID, "payer", stream.Payer, "total", stream.Total, "settled", stream.Settled)
+	ginCtx.JSON(http.StatusOK, stream)
+}
+
+func (f *Facilitator) handleGetStream(ginCtx *gin.Context) {
+	stream, ok := f.streams.get(ginCtx.Param("streamId"))
+	if !ok {
+		ginCtx.JSON(http.StatusNotFound, gin.H{
+			"error": errStreamNotFound.Error(),
+		})
+		return
+	}
+	ginCtx.JSON(http.StatusOK, stream)
+}
+
+func (f *Facilitator) respondStreamError(ginCtx *gin.Context, err error) {
+	status := http.StatusBadRequest
+	if errors.Is(err, errStreamClosed) {
+		status = http.StatusConflict
+	}
+	ginCtx.JSON(status, gin.H{
+		"error": err.Error(),
+	})
+}
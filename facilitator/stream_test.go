@@ -0,0 +1,218 @@
+package facilitator
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum/accounts/abi"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	ethtypes "github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/vorpalengineering/x402-go/types"
+	"github.com/vorpalengineering/x402-go/utils"
+)
+
+func TestPaymentStream(t *testing.T) {
+	multicallABI, _ := abi.JSON(strings.NewReader(utils.Multicall3ABI))
+
+	// RPC where every transfer succeeds
+	var mu sync.Mutex
+	var sent []*ethtypes.Transaction
+	rpcServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req struct {
+			ID     json.RawMessage   `json:"id"`
+			Method string            `json:"method"`
+			Params []json.RawMessage `json:"params"`
+		}
+		json.NewDecoder(r.Body).Decode(&req)
+		result := `"0x1"`
+		switch req.Method {
+		case "eth_call":
+			var arg struct {
+				To    common.Address `json:"to"`
+				Input hexutil.Bytes  `json:"input"`
+			}
+			json.Unmarshal(req.Params[0], &arg)
+			output := common.LeftPadBytes(big.NewInt(1e12).Bytes(), 32)
+			if arg.To == common.HexToAddress(utils.Multicall3Address) {
+				args, _ := multicallABI.Methods["aggregate3"].Inputs.Unpack(arg.Input[4:])
+				calls := *abi.ConvertType(args[0], new([]multicallCall)).(*[]multicallCall)
+				results := make([]multicallResult, len(calls))
+				for i := range results {
+					results[i].Success = true
+				}
+				output, _ = multicallABI.Methods["aggregate3"].Outputs.Pack(results)
+			}
+			result = fmt.Sprintf(`"%s"`, hexutil.Encode(output))
+		case "eth_getCode":
+			result = `"0x"`
+		case "eth_estimateGas":
+			result = `"0x30000"`
+		case "eth_gasPrice":
+			result = `"0x3b9aca00"`
+		case "eth_getTransactionCount":
+			result = `"0x5"`
+		case "eth_sendRawTransaction":
+			var raw hexutil.Bytes
+			json.Unmarshal(req.Params[0], &raw)
+			tx := new(ethtypes.Transaction)
+			tx.UnmarshalBinary(raw)
+			mu.Lock()
+			sent = append(sent, tx)
+			mu.Unlock()
+			result = fmt.Sprintf(`"%s"`, tx.Hash().Hex())
+		}
+		w.Header().Set("Content-Type", "application/json")
+		fmt.Fprintf(w, `{"jsonrpc":"2.0","id":%s,"result":%s}`, req.ID, result)
+	}))
+	defer rpcServer.Close()
+
+	signerKey, _ := crypto.HexToECDSA("ac0974bec39a17e36ba4a6b4d238ff944bacb478cbed5efcae784d7bf4f2ff80")
+	payerKey, _ := crypto.HexToECDSA("59c6995e998f97a5a0044966f0945389dc9e86dae88c7a8412f4603b6b78690d")
+	payer := crypto.PubkeyToAddress(payerKey.PublicKey).Hex()
+
+	f := NewFacilitator(&FacilitatorConfig{
+		Networks: map[string]NetworkConfig{
+			"eip155:8453": {RpcUrl: rpcServer.URL},
+		},
+		Supported: []types.SupportedKind{
+			{Scheme: "stream", Network: "eip155:8453"},
+		},
+		Transaction: TransactionConfig{TimeoutSeconds: 5, MaxGasPrice: "100000000000"},
+		Log:         LogConfig{Level: "error"},
+		Signer:      SignerConfig{PrivateKey: signerKey},
+		Streams:     StreamsConfig{Enabled: true, SettleIntervalSeconds: 60},
+	})
+	defer f.Close()
+	now := time.Now()
+	f.now = func() time.Time { return now }
+
+	post := func(path string, body any) (int, types.PaymentStream, string) {
+		data, _ := json.Marshal(body)
+		recorder := httptest.NewRecorder()
+		f.router.ServeHTTP(recorder, httptest.NewRequest("POST", path, bytes.NewReader(data)))
+		var stream types.PaymentStream
+		json.Unmarshal(recorder.Body.Bytes(), &stream)
+		return recorder.Code, stream, recorder.Body.String()
+	}
+
+	requirements := types.PaymentRequirements{
+		Scheme:  "stream",
+		Network: "eip155:8453",
+		Amount:  "3000000",
+		PayTo:   "0x3C44CdDdB6a900fa2b585dd299e03d12FA4293BC",
+		Asset:   "0x833589fCD6eDb6E08f4c7C32D4f71b54bdA02913",
+		Extra:   map[string]any{"name": "USD Coin", "version": "2"},
+	}
+	increment := func(value string, nonce byte) types.StreamAdvanceRequest {
+		auth := types.ExactEVMSchemeAuthorization{
+			From:        payer,
+			To:          requirements.PayTo,
+			Value:       value,
+			ValidAfter:  0,
+			ValidBefore: now.Add(time.Hour).Unix(),
+			Nonce:       hexutil.Encode(bytes.Repeat([]byte{nonce}, 32)),
+		}
+		signature, _ := utils.SignEIP3009(&auth, payerKey, requirements.Asset, "USD Coin", "2", 8453)
+		return types.StreamAdvanceRequest{PaymentPayload: types.PaymentPayload{
+			X402Version: 2,
+			Accepted:    requirements,
+			Payload:     map[string]any{"signature": signature, "authorization": auth},
+		}}
+	}
+
+	// Only supported stream pairs can be opened
+	exact := requirements
+	exact.Scheme = "exact"
+	if code, _, _ := post("/streams", types.StreamOpenRequest{PaymentRequirements: exact}); code != http.StatusBadRequest {
+		t.Errorf("Expected status 400 for exact scheme stream, got %d", code)
+	}
+	code, stream, body := post("/streams", types.StreamOpenRequest{PaymentRequirements: requirements})
+	if code != http.StatusCreated || stream.Status != types.StreamOpen {
+		t.Fatalf("Expected open stream, got %d: %s", code, body)
+	}
+	advance := "/streams/" + stream.ID + "/advance"
+
+	// Increments add to the running total
+	if code, stream, body = post(advance, increment("1000000", 1)); code != http.StatusOK || stream.Total != "1000000" || stream.Payer != payer {
+		t.Fatalf("Expected total of 1000000, got %d: %s", code, body)
+	}
+
+	// Rejected increments
+	forged := increment("1", 4)
+	forged.PaymentPayload.Payload["signature"] = "0x" + strings.Repeat("11", 65)
+	for name, test := range map[string]struct {
+		req    types.StreamAdvanceRequest
+		reason string
+	}{
+		"reused":     {increment("1000000", 1), "already added"},
+		"over limit": {increment("2000001", 2), "exceeds stream limit"},
+		"zero":       {increment("0", 3), "invalid increment value"},
+		"forged":     {forged, "signature"},
+	} {
+		if code, _, body := post(advance, test.req); code != http.StatusBadRequest || !strings.Contains(body, test.reason) {
+			t.Errorf("%s: expected %q, got %d: %s", name, test.reason, code, body)
+		}
+	}
+
+	// Nothing is settled before the interval passes
+	f.settleDueStreams(t.Context())
+	if len(sent) != 0 {
+		t.Fatalf("Expected no settlement before the interval, got %d", len(sent))
+	}
+	now = now.Add(time.Minute)
+	f.settleDueStreams(t.Context())
+	if len(sent) != 1 || *sent[0].To() != common.HexToAddress(utils.Multicall3Address) {
+		t.Fatalf("Expected one multicall settlement, got %d", len(sent))
+	}
+	current, _ := f.streams.get(stream.ID)
+	if current.Settled != "1000000" || len(current.Transactions) != 1 {
+		t.Errorf("Expected 1000000 settled in one transaction, got %+v", current)
+	}
+
+	// Closing settles the rest
+	post(advance, increment("500000", 5))
+	code, stream, body = post("/streams/"+stream.ID+"/close", nil)
+	if code != http.StatusOK || stream.Status != types.StreamClosed || stream.Total != "1500000" || stream.Settled != "1500000" || len(stream.Transactions) != 2 {
+		t.Errorf("Expected closed stream with 1500000 settled in two transactions, got %d: %s", code, body)
+	}
+	if code, _, _ := post(advance, increment("1", 6)); code != http.StatusConflict {
+		t.Errorf("Expected status 409 for closed stream, got %d", code)
+	}
+
+	// Closed streams can still be polled
+	recorder := httptest.NewRecorder()
+	f.router.ServeHTTP(recorder, httptest.NewRequest("GET", "/streams/"+stream.ID, nil))
+	if recorder.Code != http.StatusOK {
+		t.Errorf("Expected status 200 for closed stream, got %d", recorder.Code)
+	}
+}
+
+func TestPaymentStreamIdleTimeout(t *testing.T) {
+	st := newStreamStore(StreamsConfig{IdleTimeoutSeconds: 60, RetentionSeconds: 60})
+	now := time.Unix(1700000000, 0)
+	stream := st.open(types.PaymentRequirements{Scheme: "stream"}, big.NewInt(1), now)
+
+	// Idle streams are closed, then forgotten after retention
+	st.due(now.Add(59 * time.Second))
+	if current, _ := st.get(stream.ID); current.Status != types.StreamOpen {
+		t.Errorf("Expected stream to be open, got %s", current.Status)
+	}
+	st.due(now.Add(time.Minute))
+	if current, _ := st.get(stream.ID); current.Status != types.StreamClosed {
+		t.Errorf("Expected idle stream to be closed, got %s", current.Status)
+	}
+	st.due(now.Add(3 * time.Minute))
+	if _, ok := st.get(stream.ID); ok {
+		t.Error("Expected stream to be pruned after retention")
+	}
+}
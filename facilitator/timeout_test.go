@@ -0,0 +1,124 @@
+package facilitator
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	ethtypes "github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/ethclient"
+	"github.com/vorpalengineering/x402-go/types"
+)
+
+func TestSettleTransactionTimeout(t *testing.T) {
+	// RPC that never answers, until the test ends
+	done := make(chan struct{})
+	rpcServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-r.Context().Done():
+		case <-done:
+		}
+	}))
+	defer rpcServer.Close()
+	defer close(done)
+
+	key, _ := crypto.HexToECDSA("ac0974bec39a17e36ba4a6b4d238ff944bacb478cbed5efcae784d7bf4f2ff80")
+	f := NewFacilitator(&FacilitatorConfig{
+		Networks: map[string]NetworkConfig{
+			"eip155:8453": {RpcUrl: rpcServer.URL},
+		},
+		Transaction: TransactionConfig{TimeoutSeconds: 1, MaxGasPrice: "100000000000"},
+		Log:         LogConfig{Level: "error"},
+		Signer:      SignerConfig{PrivateKey: key},
+	})
+	defer f.Close()
+
+	requirements := types.PaymentRequirements{
+		Scheme:  "exact",
+		Network: "eip155:8453",
+		Amount:  "1000000",
+		PayTo:   "0x3C44CdDdB6a900fa2b585dd299e03d12FA4293BC",
+		Asset:   "0x833589fCD6eDb6E08f4c7C32D4f71b54bdA02913",
+	}
+	req := &types.SettleRequest{
+		PaymentPayload: types.PaymentPayload{
+			Accepted: requirements,
+			Payload: map[string]any{
+				"signature": "0x" + strings.Repeat("ab", 64) + "1b",
+				"authorization": &types.ExactEVMSchemeAuthorization{
+					From:        "0x70997970C51812dc3A010C7d01b50e0d17dc79C8",
+					To:          requirements.PayTo,
+					Value:       "1000000",
+					ValidBefore: 4102444800,
+					Nonce:       "0x" + strings.Repeat("01", 32),
+				},
+			},
+		},
+		PaymentRequirements: requirements,
+	}
+
+	// The settlement gives up at the deadline instead of hanging
+	start := time.Now()
+	resp := f.settlePayment(context.Background(), req)
+	if elapsed := time.Since(start); elapsed > 5*time.Second {
+		t.Errorf("Expected the settlement to stop after about 1s, took %v", elapsed)
+	}
+	if resp.Success || !strings.Contains(resp.ErrorMessage, "deadline exceeded") {
+		t.Errorf("Expected a deadline failure, got %+v", resp)
+	}
+}
+
+func TestConfirmSettlementOutlivesSendDeadline(t *testing.T) {
+	txHash := common.HexToHash("0x" + strings.Repeat("cd", 32))
+	receipt, _ := json.Marshal(&ethtypes.Receipt{
+		Status:      ethtypes.ReceiptStatusSuccessful,
+		Logs:        []*ethtypes.Log{},
+		TxHash:      txHash,
+		BlockNumber: big.NewInt(1),
+		GasUsed:     52000,
+	})
+	rpcServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req struct {
+			ID     json.RawMessage `json:"id"`
+			Method string          `json:"method"`
+		}
+		json.NewDecoder(r.Body).Decode(&req)
+		result := `"0x5"`
+		if req.Method == "eth_getTransactionReceipt" {
+			result = string(receipt)
+		}
+		w.Header().Set("Content-Type", "application/json")
+		fmt.Fprintf(w, `{"jsonrpc":"2.0","id":%s,"result":%s}`, req.ID, result)
+	}))
+	defer rpcServer.Close()
+
+	f := NewFacilitator(&FacilitatorConfig{
+		Transaction: TransactionConfig{TimeoutSeconds: 5, Confirmations: 3},
+		Log:         LogConfig{Level: "error"},
+	})
+	defer f.Close()
+	client, err := ethclient.Dial(rpcServer.URL)
+	if err != nil {
+		t.Fatalf("Failed to dial: %v", err)
+	}
+	defer client.Close()
+
+	// The settlement deadline already passed while sending
+	ctx, cancel := context.WithTimeout(context.Background(), time.Nanosecond)
+	defer cancel()
+	<-ctx.Done()
+
+	resp := &types.SettleResponse{Success: true}
+	f.confirmSettlement(ctx, client, txHash.Hex(), resp)
+	if !resp.Success || resp.Status != types.SettleStatusConfirmed || resp.GasUsed != 52000 {
+		t.Errorf("Expected a confirmed settlement, got %+v", resp)
+	}
+}
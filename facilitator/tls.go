@@ -0,0 +1,70 @@
+package facilitator
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"os"
+)
+
+// TLSConfig enables HTTPS on the facilitator server. Setting ClientCAFile also
+// requires clients (resource servers) to present a certificate signed by one
+// of its CAs.
+type TLSConfig struct {
+	CertFile     string `yaml:"cert_file"`
+	KeyFile      string `yaml:"key_file"`
+	ClientCAFile string `yaml:"client_ca_file"`
+}
+
+// Enabled reports whether the server should terminate TLS itself
+func (tlsCfg TLSConfig) Enabled() bool {
+	return tlsCfg.CertFile != "" || tlsCfg.KeyFile != ""
+}
+
+func (tlsCfg TLSConfig) validate() error {
+	if !tlsCfg.Enabled() {
+		if tlsCfg.ClientCAFile != "" {
+			return fmt.Errorf("client_ca_file requires cert_file and key_file")
+		}
+		return nil
+	}
+	if tlsCfg.CertFile == "" || tlsCfg.KeyFile == "" {
+		return fmt.Errorf("cert_file and key_file must both be set")
+	}
+	return nil
+}
+
+// load reads the certificate, key and client CAs into a server TLS config
+func (tlsCfg TLSConfig) load() (*tls.Config, error) {
+	cert, err := tls.LoadX509KeyPair(tlsCfg.CertFile, tlsCfg.KeyFile)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load certificate: %w", err)
+	}
+	config := &tls.Config{
+		Certificates: []tls.Certificate{cert},
+		MinVersion:   tls.VersionTLS12,
+	}
+
+	// Require client certificates for mutual TLS
+	if tlsCfg.ClientCAFile != "" {
+		pool, err := loadCertPool(tlsCfg.ClientCAFile)
+		if err != nil {
+			return nil, err
+		}
+		config.ClientCAs = pool
+		config.ClientAuth = tls.RequireAndVerifyClientCert
+	}
+	return config, nil
+}
+
+func loadCertPool(caFile string) (*x509.CertPool, error) {
+	pem, err := os.ReadFile(caFile)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read CA file: %w", err)
+	}
+	pool := x509.NewCertPool()
+	if !pool.AppendCertsFromPEM(pem) {
+		return nil, fmt.Errorf("no certificates found in %s", caFile)
+	}
+	return pool, nil
+}
@@ -0,0 +1,118 @@
+package facilitator
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"net"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/vorpalengineering/x402-go/facilitator/client"
+)
+
+// writeTestCert issues a certificate signed by parent (self-signed when nil)
+// and writes it and its key as PEM files
+func writeTestCert(t *testing.T, dir, name string, isCA bool, parent *x509.Certificate, parentKey *ecdsa.PrivateKey) (*x509.Certificate, *ecdsa.PrivateKey) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("Failed to generate key: %v", err)
+	}
+	template := &x509.Certificate{
+		SerialNumber:          big.NewInt(time.Now().UnixNano()),
+		Subject:               pkix.Name{CommonName: name},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		IsCA:                  isCA,
+		BasicConstraintsValid: true,
+		KeyUsage:              x509.KeyUsageDigitalSignature | x509.KeyUsageCertSign,
+		ExtKeyUsage:           []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth, x509.ExtKeyUsageClientAuth},
+		IPAddresses:           []net.IP{net.ParseIP("127.0.0.1")},
+	}
+	if parent == nil {
+		parent, parentKey = template, key
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, parent, &key.PublicKey, parentKey)
+	if err != nil {
+		t.Fatalf("Failed to create certificate: %v", err)
+	}
+	keyDER, _ := x509.MarshalECPrivateKey(key)
+	os.WriteFile(filepath.Join(dir, name+".crt"), pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), 0600)
+	os.WriteFile(filepath.Join(dir, name+".key"), pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER}), 0600)
+
+	cert, _ := x509.ParseCertificate(der)
+	return cert, key
+}
+
+func TestMutualTLS(t *testing.T) {
+	dir := t.TempDir()
+	ca, caKey := writeTestCert(t, dir, "ca", true, nil, nil)
+	writeTestCert(t, dir, "server", false, ca, caKey)
+	writeTestCert(t, dir, "client", false, ca, caKey)
+
+	tlsCfg := TLSConfig{
+		CertFile:     filepath.Join(dir, "server.crt"),
+		KeyFile:      filepath.Join(dir, "server.key"),
+		ClientCAFile: filepath.Join(dir, "ca.crt"),
+	}
+	serverTLS, err := tlsCfg.load()
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	f := NewFacilitator(&FacilitatorConfig{
+		Networks: map[string]NetworkConfig{
+			"eip155:8453": {
+				RpcUrl: "http://127.0.0.1:1",
+			},
+		},
+		Log: LogConfig{
+			Level: "error",
+		},
+	})
+	defer f.Close()
+	srv := httptest.NewUnstartedServer(f.router)
+	srv.TLS = serverTLS
+	srv.StartTLS()
+	defer srv.Close()
+
+	// Clients without a certificate are refused
+	noCert, err := client.LoadTLSConfig("", "", filepath.Join(dir, "ca.crt"))
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	fc := client.NewFacilitatorClient(srv.URL)
+	fc.SetTLSConfig(noCert)
+	if _, err := fc.Supported(); err == nil {
+		t.Error("Expected handshake to fail without a client certificate")
+	}
+
+	// Clients with a certificate from the CA are accepted
+	withCert, err := client.LoadTLSConfig(filepath.Join(dir, "client.crt"), filepath.Join(dir, "client.key"), filepath.Join(dir, "ca.crt"))
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	fc.SetTLSConfig(withCert)
+	if _, err := fc.Supported(); err != nil {
+		t.Errorf("Expected mutual TLS request to succeed, got %v", err)
+	}
+}
+
+func TestValidateTLSConfig(t *testing.T) {
+	if err := (TLSConfig{CertFile: "server.crt"}).validate(); err == nil {
+		t.Error("Expected error for missing key file")
+	}
+	if err := (TLSConfig{ClientCAFile: "ca.crt"}).validate(); err == nil {
+		t.Error("Expected error for client CA without server certificate")
+	}
+	if err := (TLSConfig{}).validate(); err != nil {
+		t.Errorf("Expected disabled TLS to be valid, got %v", err)
+	}
+}
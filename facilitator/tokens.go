@@ -0,0 +1,175 @@
+package facilitator
+
+import (
+	"context"
+	"fmt"
+	"maps"
+	"math/big"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/accounts/abi"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/vorpalengineering/x402-go/types"
+	"github.com/vorpalengineering/x402-go/utils"
+)
+
+const defaultTokenCacheTTL = time.Hour
+
+// tokenMetadata is what the facilitator reads from an asset contract
+type tokenMetadata struct {
+	// Name and Version of the token's EIP-712 domain
+	Name    string
+	Version string
+	// Decimals is 0 if the token doesn't expose decimals()
+	Decimals uint8
+}
+
+type tokenCacheEntry struct {
+	metadata tokenMetadata
+	expires  time.Time
+}
+
+// tokenCache keeps token metadata per network and asset until the TTL passes
+type tokenCache struct {
+	ttl time.Duration
+
+	mu      sync.Mutex
+	entries map[string]tokenCacheEntry
+}
+
+func newTokenCache(cfg TokensConfig) *tokenCache {
+	ttl := defaultTokenCacheTTL
+	if cfg.CacheTTLSeconds > 0 {
+		ttl = time.Duration(cfg.CacheTTLSeconds) * time.Second
+	}
+	return &tokenCache{
+		ttl:     ttl,
+		entries: make(map[string]tokenCacheEntry),
+	}
+}
+
+func tokenCacheKey(network, asset string) string {
+	return network + ":" + strings.ToLower(asset)
+}
+
+func (c *tokenCache) get(network, asset string, now time.Time) (tokenMetadata, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	entry, ok := c.entries[tokenCacheKey(network, asset)]
+	if !ok || now.After(entry.expires) {
+		return tokenMetadata{}, false
+	}
+	return entry.metadata, true
+}
+
+func (c *tokenCache) put(network, asset string, metadata tokenMetadata, now time.Time) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.entries[tokenCacheKey(network, asset)] = tokenCacheEntry{
+		metadata: metadata,
+		expires:  now.Add(c.ttl),
+	}
+}
+
+// tokenMetadata returns the metadata of an asset, reading it from the chain
+// when it isn't cached. The EIP-712 domain comes from EIP-5267 eip712Domain()
+// if the token implements it, otherwise from name() and version().
+func (f *Facilitator) tokenMetadata(ctx context.Context, network, asset string) (tokenMetadata, error) {
+	if metadata, ok := f.tokens.get(network, asset, f.now()); ok {
+		return metadata, nil
+	}
+
+	// Get RPC client
+	client, err := f.getRPCClient(network)
+	if err != nil {
+		return tokenMetadata{}, fmt.Errorf("failed to connect to network: %w", err)
+	}
+	call := func(abiJSON, method string) ([]any, error) {
+		parsedABI, err := abi.JSON(strings.NewReader(abiJSON))
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse ABI: %w", err)
+		}
+		callData, err := parsedABI.Pack(method)
+		if err != nil {
+			return nil, fmt.Errorf("failed to encode %s call: %w", method, err)
+		}
+		assetAddress := common.HexToAddress(asset)
+		result, err := client.CallContract(ctx, ethereum.CallMsg{To: &assetAddress, Data: callData}, nil)
+		if err != nil {
+			return nil, fmt.Errorf("failed to call %s: %w", method, err)
+		}
+		values, err := parsedABI.Unpack(method, result)
+		if err != nil {
+			return nil, fmt.Errorf("failed to decode %s: %w", method, err)
+		}
+		return values, nil
+	}
+
+	// Read the EIP-712 domain, preferring EIP-5267
+	var metadata tokenMetadata
+	if values, err := call(utils.EIP5267DomainABI, "eip712Domain"); err == nil {
+		metadata.Name, _ = values[1].(string)
+		metadata.Version, _ = values[2].(string)
+	} else {
+		values, err := call(utils.ERC20MetadataABI, "name")
+		if err != nil {
+			return tokenMetadata{}, err
+		}
+		metadata.Name, _ = values[0].(string)
+		values, err = call(utils.ERC20MetadataABI, "version")
+		if err != nil {
+			return tokenMetadata{}, err
+		}
+		metadata.Version, _ = values[0].(string)
+	}
+
+	// Read decimals, optional in ERC-20
+	if values, err := call(utils.ERC20MetadataABI, "decimals"); err == nil {
+		metadata.Decimals, _ = values[0].(uint8)
+	}
+
+	f.tokens.put(network, asset, metadata, f.now())
+	return metadata, nil
+}
+
+// withTokenDomain fills in the EIP-712 domain name and version of the
+// requirements from the asset contract when extra doesn't carry them
+func (f *Facilitator) withTokenDomain(ctx context.Context, requirements *types.PaymentRequirements) *types.PaymentRequirements {
+	name, _ := requirements.Extra["name"].(string)
+	version, _ := requirements.Extra["version"].(string)
+	if name != "" && version != "" {
+		return requirements
+	}
+
+	metadata, err := f.tokenMetadata(ctx, requirements.Network, requirements.Asset)
+	if err != nil {
+		f.log(ctx).Debug("failed to read token metadata", "network", requirements.Network, "asset", requirements.Asset, "error", err)
+		return requirements
+	}
+
+	filled := *requirements
+	filled.Extra = maps.Clone(requirements.Extra)
+	if filled.Extra == nil {
+		filled.Extra = make(map[string]any)
+	}
+	if name == "" {
+		filled.Extra["name"] = metadata.Name
+	}
+	if version == "" {
+		filled.Extra["version"] = metadata.Version
+	}
+	return &filled
+}
+
+// formatTokenAmount formats an amount in atomic units for messages, adding
+// the amount in tokens when the asset's decimals are known
+func (f *Facilitator) formatTokenAmount(ctx context.Context, requirements *types.PaymentRequirements, amount *big.Int) string {
+	metadata, err := f.tokenMetadata(ctx, requirements.Network, requirements.Asset)
+	if err != nil || metadata.Decimals == 0 {
+		return amount.String()
+	}
+	return fmt.Sprintf("%s (%s)", amount.String(), utils.FormatAmount(amount, metadata.Decimals))
+}
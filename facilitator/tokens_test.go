@@ -0,0 +1,163 @@
+package facilitator
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum/accounts/abi"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/vorpalengineering/x402-go/types"
+	"github.com/vorpalengineering/x402-go/utils"
+)
+
+// tokenRPC serves a USDC-like token, with or without EIP-5267
+func tokenRPC(eip5267 bool, metadataCalls *atomic.Int32) *httptest.Server {
+	metadataABI, _ := abi.JSON(strings.NewReader(utils.ERC20MetadataABI))
+	domainABI, _ := abi.JSON(strings.NewReader(utils.EIP5267DomainABI))
+	balanceABI, _ := abi.JSON(strings.NewReader(utils.ERC20BalanceOfABI))
+
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req struct {
+			ID     json.RawMessage   `json:"id"`
+			Method string            `json:"method"`
+			Params []json.RawMessage `json:"params"`
+		}
+		json.NewDecoder(r.Body).Decode(&req)
+		result := `"0x1"`
+		switch req.Method {
+		case "eth_call":
+			var arg struct {
+				Input hexutil.Bytes `json:"input"`
+			}
+			json.Unmarshal(req.Params[0], &arg)
+			var output []byte
+			switch selector := arg.Input[:4]; {
+			case bytes.Equal(selector, domainABI.Methods["eip712Domain"].ID):
+				metadataCalls.Add(1)
+				if !eip5267 {
+					w.Header().Set("Content-Type", "application/json")
+					fmt.Fprintf(w, `{"jsonrpc":"2.0","id":%s,"error":{"code":3,"message":"execution reverted"}}`, req.ID)
+					return
+				}
+				output, _ = domainABI.Methods["eip712Domain"].Outputs.Pack(
+					[1]byte{0x0f}, "USD Coin", "2", big.NewInt(8453),
+					common.HexToAddress("0x833589fCD6eDb6E08f4c7C32D4f71b54bdA02913"), [32]byte{}, []*big.Int{},
+				)
+			case bytes.Equal(selector, metadataABI.Methods["name"].ID):
+				metadataCalls.Add(1)
+				output, _ = metadataABI.Methods["name"].Outputs.Pack("USD Coin")
+			case bytes.Equal(selector, metadataABI.Methods["version"].ID):
+				metadataCalls.Add(1)
+				output, _ = metadataABI.Methods["version"].Outputs.Pack("2")
+			case bytes.Equal(selector, metadataABI.Methods["decimals"].ID):
+				metadataCalls.Add(1)
+				output, _ = metadataABI.Methods["decimals"].Outputs.Pack(uint8(6))
+			case bytes.Equal(selector, balanceABI.Methods["balanceOf"].ID):
+				output, _ = balanceABI.Methods["balanceOf"].Outputs.Pack(big.NewInt(1e12))
+			}
+			result = fmt.Sprintf(`"%s"`, hexutil.Encode(output))
+		case "eth_getCode":
+			result = `"0x"`
+		}
+		w.Header().Set("Content-Type", "application/json")
+		fmt.Fprintf(w, `{"jsonrpc":"2.0","id":%s,"result":%s}`, req.ID, result)
+	}))
+}
+
+func TestTokenDomainDiscovery(t *testing.T) {
+	payerKey, _ := crypto.HexToECDSA("59c6995e998f97a5a0044966f0945389dc9e86dae88c7a8412f4603b6b78690d")
+	payer := crypto.PubkeyToAddress(payerKey.PublicKey).Hex()
+
+	for _, eip5267 := range []bool{true, false} {
+		var metadataCalls atomic.Int32
+		rpcServer := tokenRPC(eip5267, &metadataCalls)
+		defer rpcServer.Close()
+
+		f := NewFacilitator(&FacilitatorConfig{
+			Networks: map[string]NetworkConfig{
+				"eip155:8453": {RpcUrl: rpcServer.URL},
+			},
+			Supported: []types.SupportedKind{
+				{Scheme: "exact", Network: "eip155:8453"},
+			},
+			Log: LogConfig{Level: "error"},
+		})
+
+		// Requirements without the EIP-712 domain in extra
+		requirements := types.PaymentRequirements{
+			Scheme:  "exact",
+			Network: "eip155:8453",
+			Amount:  "1000000",
+			PayTo:   "0x3C44CdDdB6a900fa2b585dd299e03d12FA4293BC",
+			Asset:   "0x833589fCD6eDb6E08f4c7C32D4f71b54bdA02913",
+		}
+		verify := func(value string) types.VerifyResponse {
+			auth := types.ExactEVMSchemeAuthorization{
+				From:        payer,
+				To:          requirements.PayTo,
+				Value:       value,
+				ValidBefore: time.Now().Add(time.Hour).Unix(),
+				Nonce:       hexutil.Encode(bytes.Repeat([]byte{1}, 32)),
+			}
+			signature, _ := utils.SignEIP3009(&auth, payerKey, requirements.Asset, "USD Coin", "2", 8453)
+			body, _ := json.Marshal(types.VerifyRequest{
+				PaymentPayload: types.PaymentPayload{
+					X402Version: 2,
+					Accepted:    requirements,
+					Payload:     map[string]any{"signature": signature, "authorization": auth},
+				},
+				PaymentRequirements: requirements,
+			})
+			recorder := httptest.NewRecorder()
+			f.router.ServeHTTP(recorder, httptest.NewRequest("POST", "/verify", bytes.NewReader(body)))
+			var res types.VerifyResponse
+			json.NewDecoder(recorder.Body).Decode(&res)
+			return res
+		}
+
+		// The signature is checked against the token's on-chain domain
+		if res := verify("1000000"); !res.IsValid {
+			t.Errorf("eip5267=%v: expected payment to be valid, got %s", eip5267, res.InvalidReason)
+		}
+		calls := metadataCalls.Load()
+
+		// Amounts in messages are formatted with the cached decimals
+		res := verify("500000")
+		if expected := "insufficient amount: got 500000 (0.5), required 1000000 (1)"; res.InvalidMessage != expected {
+			t.Errorf("eip5267=%v: expected %q, got %q", eip5267, expected, res.InvalidMessage)
+		}
+		if metadataCalls.Load() != calls {
+			t.Errorf("eip5267=%v: expected metadata to be cached, got %d more calls", eip5267, metadataCalls.Load()-calls)
+		}
+	}
+}
+
+func TestFormatAmount(t *testing.T) {
+	tests := []struct {
+		amount   int64
+		decimals uint8
+		expected string
+	}{
+		{1500000, 6, "1.5"},
+		{1000000, 6, "1"},
+		{1, 6, "0.000001"},
+		{0, 6, "0"},
+		{42, 0, "42"},
+		{-2500, 3, "-2.5"},
+	}
+	for _, test := range tests {
+		if got := utils.FormatAmount(big.NewInt(test.amount), test.decimals); got != test.expected {
+			t.Errorf("Expected %d with %d decimals to format as %q, got %q", test.amount, test.decimals, test.expected, got)
+		}
+	}
+}
@@ -0,0 +1,419 @@
+package facilitator
+
+import (
+	"context"
+	"fmt"
+	"math/big"
+	"strings"
+
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/accounts/abi"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/ethclient"
+	"github.com/vorpalengineering/x402-go/types"
+	"github.com/vorpalengineering/x402-go/utils"
+)
+
+// The upto scheme lets a client authorize a maximum amount and the resource
+// server charge the metered amount afterwards. The client signs a Permit2
+// PermitTransferFrom of up to the maximum with the facilitator's signer as
+// spender. Settlement transfers the final amount to payTo in one
+// permitTransferFrom call.
+
+func (f *Facilitator) verifyUptoScheme(ctx context.Context, payload *types.PaymentPayload, requirements *types.PaymentRequirements, fullReport bool) []types.VerifyFailure {
+	// Extract signature from payload
+	signatureHex, ok := payload.Payload["signature"].(string)
+	if !ok || signatureHex == "" {
+		return []types.VerifyFailure{{Check: VerifyCheckSignature, Code: types.ErrCodeInvalidSignature, Reason: "missing signature"}}
+	}
+
+	// Extract authorization from payload
+	auth, err := utils.ExtractUptoAuthorization(payload)
+	if err != nil {
+		return []types.VerifyFailure{{Check: VerifyCheckPayload, Code: types.ErrCodeInvalidPayload, Reason: fmt.Sprintf("invalid authorization: %v", err)}}
+	}
+
+	checks := []struct {
+		name string
+		code string
+		run  func() (bool, string)
+	}{
+		// Step 1: Signature Validation
+		{VerifyCheckSignature, types.ErrCodeInvalidSignature, func() (bool, string) { return f.verifyUptoSignature(ctx, auth, requirements, signatureHex) }},
+		// Step 2: Balance and Permit2 Allowance
+		{VerifyCheckBalance, types.ErrCodeInsufficientFunds, func() (bool, string) { return f.verifyUptoBalance(ctx, auth, requirements) }},
+		// Step 3: Amount Ceiling
+		{VerifyCheckAmount, types.ErrCodeInvalidAmount, func() (bool, string) { return verifyUptoAmount(auth, requirements) }},
+		// Step 4: Deadline Check
+		{VerifyCheckTimeWindow, types.ErrCodeExpired, func() (bool, string) { return f.verifyUptoDeadline(auth) }},
+		// Step 5: Spender Matching
+		{VerifyCheckParameters, types.ErrCodeSpenderMismatch, func() (bool, string) { return f.verifyUptoSpender(auth, requirements) }},
+	}
+
+	var failures []types.VerifyFailure
+	for _, check := range checks {
+		if valid, reason := check.run(); !valid {
+			failures = append(failures, types.VerifyFailure{Check: check.name, Code: check.code, Reason: reason})
+			if !fullReport {
+				return failures
+			}
+		}
+	}
+
+	// Step 6: Transaction Simulation of a transfer of the full required amount
+	if len(failures) == 0 {
+		if valid, reason := f.simulateUptoTransfer(ctx, auth, requirements, signatureHex); !valid {
+			failures = append(failures, types.VerifyFailure{Check: VerifyCheckSimulation, Code: types.ErrCodeSimulationFailed, Reason: reason})
+		}
+	}
+
+	return failures
+}
+
+func (f *Facilitator) verifyUptoSignature(ctx context.Context, auth *types.UptoEVMSchemeAuthorization, requirements *types.PaymentRequirements, signatureHex string) (bool, string) {
+	// Decode hex signature
+	signature, err := hexutil.Decode("0x" + strings.TrimPrefix(signatureHex, "0x"))
+	if err != nil {
+		return false, fmt.Sprintf("invalid signature format: %v", err)
+	}
+
+	// Hash the Permit2 typed data
+	hash, err := utils.HashPermit2(auth, requirements)
+	if err != nil {
+		return false, fmt.Sprintf("failed to hash permit: %v", err)
+	}
+
+	return f.verifyPayerSignature(ctx, requirements.Network, common.HexToAddress(auth.From), hash, signature)
+}
+
+// verifyUptoBalance checks the payer holds the required amount and has
+// approved Permit2 to move it
+func (f *Facilitator) verifyUptoBalance(ctx context.Context, auth *types.UptoEVMSchemeAuthorization, requirements *types.PaymentRequirements) (bool, string) {
+	requiredAmount, ok := new(big.Int).SetString(requirements.Amount, 10)
+	if !ok {
+		return false, "invalid required amount format"
+	}
+
+	// Get RPC client for the network
+	client, err := f.getRPCClient(requirements.Network)
+	if err != nil {
+		return false, fmt.Sprintf("failed to connect to network: %v", err)
+	}
+
+	owner := common.HexToAddress(auth.From)
+	balance, err := callUint256(ctx, client, requirements.Asset, utils.ERC20BalanceOfABI, "balanceOf", owner)
+	if err != nil {
+		return false, err.Error()
+	}
+	if balance.Cmp(requiredAmount) < 0 {
+		return false, fmt.Sprintf("insufficient balance: has %s, needs %s", balance.String(), requiredAmount.String())
+	}
+
+	allowance, err := callUint256(ctx, client, requirements.Asset, utils.ERC20AllowanceABI, "allowance", owner, common.HexToAddress(utils.Permit2Address))
+	if err != nil {
+		return false, err.Error()
+	}
+	if allowance.Cmp(requiredAmount) < 0 {
+		return false, fmt.Sprintf("insufficient Permit2 allowance: has %s, needs %s", allowance.String(), requiredAmount.String())
+	}
+
+	return true, ""
+}
+
+// callUint256 calls a token view function returning a single uint256
+func callUint256(ctx context.Context, client *ethclient.Client, token string, abiJSON string, method string, args ...any) (*big.Int, error) {
+	// Parse the ABI
+	parsedABI, err := abi.JSON(strings.NewReader(abiJSON))
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse ABI: %v", err)
+	}
+
+	// Encode the call
+	callData, err := parsedABI.Pack(method, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode %s call: %v", method, err)
+	}
+
+	// Execute the call
+	tokenAddress := common.HexToAddress(token)
+	result, err := client.CallContract(ctx, ethereum.CallMsg{To: &tokenAddress, Data: callData}, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to call %s: %v", method, err)
+	}
+
+	// Decode the result
+	var value *big.Int
+	if err := parsedABI.UnpackIntoInterface(&value, method, result); err != nil {
+		return nil, fmt.Errorf("failed to decode %s: %v", method, err)
+	}
+	return value, nil
+}
+
+// verifyUptoAmount checks the authorized maximum covers the most the
+// resource server may charge
+func verifyUptoAmount(auth *types.UptoEVMSchemeAuthorization, requirements *types.PaymentRequirements) (bool, string) {
+	// Parse amounts as big.Int for safe comparison
+	maxAmount, ok := new(big.Int).SetString(auth.Value, 10)
+	if !ok {
+		return false, "invalid payment amount format"
+	}
+	requiredAmount, ok := new(big.Int).SetString(requirements.Amount, 10)
+	if !ok {
+		return false, "invalid required amount format"
+	}
+
+	// Authorized maximum must be >= required amount
+	if maxAmount.Cmp(requiredAmount) < 0 {
+		return false, fmt.Sprintf("insufficient amount: authorized up to %s, required up to %s", auth.Value, requirements.Amount)
+	}
+
+	return true, ""
+}
+
+func (f *Facilitator) verifyUptoDeadline(auth *types.UptoEVMSchemeAuthorization) (bool, string) {
+	if f.now().Unix() > auth.Deadline {
+		return false, fmt.Sprintf("payment expired (deadline %d)", auth.Deadline)
+	}
+	return true, ""
+}
+
+// verifyUptoSpender checks the permit can be spent by the network's signer
+func (f *Facilitator) verifyUptoSpender(auth *types.UptoEVMSchemeAuthorization, requirements *types.PaymentRequirements) (bool, string) {
+	signer, ok := f.signers.address(requirements.Network)
+	if !ok {
+		return false, fmt.Sprintf("no signer configured for %s", requirements.Network)
+	}
+	if common.HexToAddress(auth.Spender) != signer {
+		return false, fmt.Sprintf("spender mismatch: got %s, expected %s", auth.Spender, signer.Hex())
+	}
+	return true, ""
+}
+
+func (f *Facilitator) simulateUptoTransfer(ctx context.Context, auth *types.UptoEVMSchemeAuthorization, requirements *types.PaymentRequirements, signatureHex string) (bool, string) {
+	// Get RPC client
+	client, err := f.getRPCClient(requirements.Network)
+	if err != nil {
+		return false, fmt.Sprintf("failed to connect to network: %v", err)
+	}
+
+	// Encode the permitTransferFrom call
+	requiredAmount, _ := new(big.Int).SetString(requirements.Amount, 10)
+	callData, err := encodePermitTransferFrom(auth, requirements, signatureHex, requiredAmount)
+	if err != nil {
+		return false, err.Error()
+	}
+
+	// Simulate as the spender
+	permit2 := common.HexToAddress(utils.Permit2Address)
+	msg := ethereum.CallMsg{
+		From: common.HexToAddress(auth.Spender),
+		To:   &permit2,
+		Data: callData,
+	}
+	if _, err := client.CallContract(ctx, msg, nil); err != nil {
+		return false, fmt.Sprintf("transaction would fail: %v", err)
+	}
+
+	return true, ""
+}
+
+// encodePermitTransferFrom encodes a Permit2 permitTransferFrom moving amount
+// from the payer to payTo
+func encodePermitTransferFrom(auth *types.UptoEVMSchemeAuthorization, requirements *types.PaymentRequirements, signatureHex string, amount *big.Int) ([]byte, error) {
+	// Parse the Permit2 ABI
+	parsedABI, err := abi.JSON(strings.NewReader(utils.Permit2PermitTransferFromABI))
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse ABI: %w", err)
+	}
+
+	// Parse amounts
+	maxAmount, ok := new(big.Int).SetString(auth.Value, 10)
+	if !ok {
+		return nil, fmt.Errorf("invalid payment amount format")
+	}
+	nonce, ok := new(big.Int).SetString(auth.Nonce, 10)
+	if !ok {
+		return nil, fmt.Errorf("invalid nonce: %s", auth.Nonce)
+	}
+
+	type tokenPermissions struct {
+		Token  common.Address
+		Amount *big.Int
+	}
+	permit := struct {
+		Permitted tokenPermissions
+		Nonce     *big.Int
+		Deadline  *big.Int
+	}{
+		Permitted: tokenPermissions{Token: common.HexToAddress(requirements.Asset), Amount: maxAmount},
+		Nonce:     nonce,
+		Deadline:  big.NewInt(auth.Deadline),
+	}
+	transferDetails := struct {
+		To              common.Address
+		RequestedAmount *big.Int
+	}{
+		To:              common.HexToAddress(requirements.PayTo),
+		RequestedAmount: amount,
+	}
+
+	callData, err := parsedABI.Pack("permitTransferFrom", permit, transferDetails, common.HexToAddress(auth.From), common.FromHex(signatureHex))
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode call: %w", err)
+	}
+	return callData, nil
+}
+
+// uptoSettleAmount parses the metered amount of an upto settlement, which
+// must be within both the required and the authorized maximum
+func uptoSettleAmount(amountStr string, auth *types.UptoEVMSchemeAuthorization, requirements *types.PaymentRequirements) (*big.Int, error) {
+	if amountStr == "" {
+		return nil, fmt.Errorf("missing settle amount")
+	}
+	amount, ok := new(big.Int).SetString(amountStr, 10)
+	if !ok || amount.Sign() < 0 {
+		return nil, fmt.Errorf("invalid settle amount: %s", amountStr)
+	}
+	for _, ceiling := range []string{requirements.Amount, auth.Value} {
+		maxAmount, ok := new(big.Int).SetString(ceiling, 10)
+		if !ok {
+			return nil, fmt.Errorf("invalid amount format: %s", ceiling)
+		}
+		if amount.Cmp(maxAmount) > 0 {
+			return nil, fmt.Errorf("settle amount %s exceeds maximum %s", amount, maxAmount)
+		}
+	}
+	return amount, nil
+}
+
+func (f *Facilitator) settleUptoScheme(ctx context.Context, req *types.SettleRequest) *types.SettleResponse {
+	requirements := &req.PaymentRequirements
+
+	// Extract signature from payload
+	signatureHex, ok := req.PaymentPayload.Payload["signature"].(string)
+	if !ok || signatureHex == "" {
+		return settleFailed(types.ErrCodeInvalidSignature, "missing signature")
+	}
+
+	// Extract authorization from payload
+	auth, err := utils.ExtractUptoAuthorization(&req.PaymentPayload)
+	if err != nil {
+		return settleFailed(types.ErrCodeInvalidPayload, fmt.Sprintf("invalid authorization: %v", err))
+	}
+
+	// Check the metered amount against the ceilings
+	amount, err := uptoSettleAmount(req.Amount, auth, requirements)
+	if err != nil {
+		return settleFailed(types.ErrCodeInvalidAmount, err.Error())
+	}
+
+	// Nothing to charge, the permit is left unused
+	if amount.Sign() == 0 {
+		return &types.SettleResponse{
+			Success: true,
+			Network: requirements.Network,
+			Payer:   auth.From,
+			Amount:  "0",
+		}
+	}
+
+	// Get RPC client
+	client, err := f.getRPCClient(requirements.Network)
+	if err != nil {
+		return settleFailed(types.ErrCodeNetworkError, fmt.Sprintf("failed to connect to network: %v", err))
+	}
+
+	// Get the network's signer, which must be the permit's spender
+	signer, release, err := f.signers.acquire(requirements.Network)
+	if err != nil {
+		return settleFailed(types.ErrCodeSettlementFailed, err.Error())
+	}
+	if common.HexToAddress(auth.Spender) != signer.Address() {
+		release()
+		return settleFailed(types.ErrCodeSpenderMismatch, fmt.Sprintf("spender mismatch: got %s, expected %s", auth.Spender, signer.Address().Hex()))
+	}
+
+	// Build and send the transaction
+	txHash, attempts, err := f.sendWithRetry(ctx, requirements.Network, func(attempt int) (string, error) {
+		return f.sendPermitTransferFrom(ctx, client, signer, auth, requirements, signatureHex, amount, attempt)
+	})
+	release()
+	if err != nil {
+		return settleFailure(err, attempts)
+	}
+
+	resp := &types.SettleResponse{
+		Success:     true,
+		Transaction: txHash,
+		Network:     requirements.Network,
+		Payer:       auth.From,
+		Attempts:    attempts,
+		Amount:      amount.String(),
+	}
+	if f.cfg().Transaction.Confirmations <= 0 {
+		return resp
+	}
+
+	f.confirmSettlement(ctx, client, txHash, resp)
+	return resp
+}
+
+func (f *Facilitator) sendPermitTransferFrom(
+	ctx context.Context,
+	client *ethclient.Client,
+	signer Signer,
+	auth *types.UptoEVMSchemeAuthorization,
+	requirements *types.PaymentRequirements,
+	signatureHex string,
+	amount *big.Int,
+	attempt int,
+) (string, error) {
+	// Encode the permitTransferFrom call
+	callData, err := encodePermitTransferFrom(auth, requirements, signatureHex, amount)
+	if err != nil {
+		return "", err
+	}
+
+	// Get gas price
+	gasPrice, err := client.SuggestGasPrice(ctx)
+	if err != nil {
+		return "", fmt.Errorf("failed to get gas price: %w", err)
+	}
+
+	// Check gas price against max gas price from config
+	maxGasPrice, ok := new(big.Int).SetString(f.cfg().Transaction.MaxGasPrice, 10)
+	if !ok {
+		return "", fmt.Errorf("failed to parse max gas price: %s", f.cfg().Transaction.MaxGasPrice)
+	}
+	if gasPrice.Cmp(maxGasPrice) > 0 {
+		return "", fmt.Errorf("gas price too high: suggested %s wei exceeds max %s wei", gasPrice.String(), maxGasPrice.String())
+	}
+
+	// Outbid the previous attempt on retries
+	gasPrice = f.cfg().Transaction.Retry.bumpGasPrice(gasPrice, maxGasPrice, attempt)
+
+	// Get chain ID
+	chainID, err := utils.GetChainID(requirements.Network)
+	if err != nil {
+		return "", fmt.Errorf("failed to get chain id: %w", err)
+	}
+
+	// Determine gas limit
+	permit2 := common.HexToAddress(utils.Permit2Address)
+	call, err := f.prepareSettlementCall(ctx, client, requirements, ethereum.CallMsg{
+		From: signer.Address(),
+		To:   &permit2,
+	}, []settlementCall{{overload: OverloadBytes, data: callData}})
+	if err != nil {
+		return "", err
+	}
+
+	// Sign and send, retrying once with a fresh nonce if the node says it was used
+	txHash, err := f.sendSettlementTx(ctx, client, signer, requirements.Network, chainID, permit2, gasPrice, call)
+	if err != nil && isNonceError(err) {
+		f.log(ctx).Warn("nonce already used, resynced and retrying", "network", requirements.Network, "error", err)
+		txHash, err = f.sendSettlementTx(ctx, client, signer, requirements.Network, chainID, permit2, gasPrice, call)
+	}
+	return txHash, err
+}
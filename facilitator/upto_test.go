@@ -0,0 +1,204 @@
+package facilitator
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum/accounts/abi"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	ethtypes "github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/vorpalengineering/x402-go/types"
+	"github.com/vorpalengineering/x402-go/utils"
+)
+
+func TestUptoVerifyAndSettle(t *testing.T) {
+	// RPC where the payer has funds and approved Permit2
+	var mu sync.Mutex
+	var sent []*ethtypes.Transaction
+	rpcServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req struct {
+			ID     json.RawMessage   `json:"id"`
+			Method string            `json:"method"`
+			Params []json.RawMessage `json:"params"`
+		}
+		json.NewDecoder(r.Body).Decode(&req)
+		result := `"0x1"`
+		switch req.Method {
+		case "eth_call":
+			result = fmt.Sprintf(`"%s"`, hexutil.Encode(common.LeftPadBytes(big.NewInt(1e12).Bytes(), 32)))
+		case "eth_getCode":
+			result = `"0x"`
+		case "eth_estimateGas":
+			result = `"0x30000"`
+		case "eth_gasPrice":
+			result = `"0x3b9aca00"`
+		case "eth_getTransactionCount":
+			result = `"0x5"`
+		case "eth_sendRawTransaction":
+			var raw hexutil.Bytes
+			json.Unmarshal(req.Params[0], &raw)
+			tx := new(ethtypes.Transaction)
+			tx.UnmarshalBinary(raw)
+			mu.Lock()
+			sent = append(sent, tx)
+			mu.Unlock()
+			result = fmt.Sprintf(`"%s"`, tx.Hash().Hex())
+		}
+		w.Header().Set("Content-Type", "application/json")
+		fmt.Fprintf(w, `{"jsonrpc":"2.0","id":%s,"result":%s}`, req.ID, result)
+	}))
+	defer rpcServer.Close()
+
+	signerKey, _ := crypto.HexToECDSA("ac0974bec39a17e36ba4a6b4d238ff944bacb478cbed5efcae784d7bf4f2ff80")
+	payerKey, _ := crypto.HexToECDSA("59c6995e998f97a5a0044966f0945389dc9e86dae88c7a8412f4603b6b78690d")
+	spender := crypto.PubkeyToAddress(signerKey.PublicKey).Hex()
+	payer := crypto.PubkeyToAddress(payerKey.PublicKey).Hex()
+
+	f := NewFacilitator(&FacilitatorConfig{
+		Networks: map[string]NetworkConfig{
+			"eip155:8453": {RpcUrl: rpcServer.URL},
+		},
+		Supported: []types.SupportedKind{
+			{Scheme: "exact", Network: "eip155:8453"},
+			{Scheme: "upto", Network: "eip155:8453"},
+		},
+		Transaction: TransactionConfig{TimeoutSeconds: 5, MaxGasPrice: "100000000000"},
+		Log:         LogConfig{Level: "error"},
+		Signer:      SignerConfig{PrivateKey: signerKey},
+	})
+	defer f.Close()
+
+	// Clients learn the spender from /supported
+	recorder := httptest.NewRecorder()
+	f.router.ServeHTTP(recorder, httptest.NewRequest("GET", "/supported", nil))
+	var supported types.SupportedResponse
+	json.NewDecoder(recorder.Body).Decode(&supported)
+	if len(supported.Kinds) != 2 || supported.Kinds[0].Extra["spender"] != nil || supported.Kinds[1].Extra["spender"] != spender {
+		t.Errorf("Expected spender %s on upto kind only, got %+v", spender, supported.Kinds)
+	}
+
+	requirements := types.PaymentRequirements{
+		Scheme:  "upto",
+		Network: "eip155:8453",
+		Amount:  "1000000",
+		PayTo:   "0x3C44CdDdB6a900fa2b585dd299e03d12FA4293BC",
+		Asset:   "0x833589fCD6eDb6E08f4c7C32D4f71b54bdA02913",
+	}
+	authorization := types.UptoEVMSchemeAuthorization{
+		From:     payer,
+		Spender:  spender,
+		Value:    "1000000",
+		Nonce:    "1",
+		Deadline: time.Now().Add(time.Hour).Unix(),
+	}
+	payload := func(auth types.UptoEVMSchemeAuthorization) types.PaymentPayload {
+		signature, _ := utils.SignPermit2(&auth, payerKey, &requirements)
+		return types.PaymentPayload{
+			X402Version: 2,
+			Accepted:    requirements,
+			Payload:     map[string]any{"signature": signature, "authorization": auth},
+		}
+	}
+	verify := func(payload types.PaymentPayload) types.VerifyResponse {
+		body, _ := json.Marshal(types.VerifyRequest{PaymentPayload: payload, PaymentRequirements: requirements})
+		recorder := httptest.NewRecorder()
+		f.router.ServeHTTP(recorder, httptest.NewRequest("POST", "/verify", bytes.NewReader(body)))
+		var res types.VerifyResponse
+		json.NewDecoder(recorder.Body).Decode(&res)
+		return res
+	}
+	settle := func(payload types.PaymentPayload, amount string) types.SettleResponse {
+		body, _ := json.Marshal(types.SettleRequest{PaymentPayload: payload, PaymentRequirements: requirements, Amount: amount})
+		recorder := httptest.NewRecorder()
+		f.router.ServeHTTP(recorder, httptest.NewRequest("POST", "/settle", bytes.NewReader(body)))
+		var res types.SettleResponse
+		json.NewDecoder(recorder.Body).Decode(&res)
+		return res
+	}
+
+	// Valid permit
+	if res := verify(payload(authorization)); !res.IsValid {
+		t.Fatalf("Expected payment to be valid, got %s", res.InvalidReason)
+	}
+
+	// Invalid permits
+	lowCeiling := authorization
+	lowCeiling.Value = "999999"
+	otherSpender := authorization
+	otherSpender.Spender = payer
+	expired := authorization
+	expired.Deadline = time.Now().Add(-time.Minute).Unix()
+	for name, test := range map[string]struct {
+		auth   types.UptoEVMSchemeAuthorization
+		reason string
+	}{
+		"low ceiling":   {lowCeiling, "insufficient amount"},
+		"other spender": {otherSpender, "spender mismatch"},
+		"expired":       {expired, "payment expired"},
+	} {
+		if res := verify(payload(test.auth)); res.IsValid || !strings.Contains(res.InvalidMessage, test.reason) {
+			t.Errorf("%s: expected %q, got %q", name, test.reason, res.InvalidMessage)
+		}
+	}
+
+	// Settle amounts are bounded by the maximum
+	for name, test := range map[string]struct {
+		amount string
+		reason string
+	}{
+		"missing":   {"", "missing settle amount"},
+		"negative":  {"-1", "invalid settle amount"},
+		"too large": {"1000001", "exceeds maximum"},
+	} {
+		if res := settle(payload(authorization), test.amount); res.Success || !strings.Contains(res.ErrorMessage, test.reason) {
+			t.Errorf("%s: expected %q, got %+v", name, test.reason, res)
+		}
+	}
+
+	// Nothing metered settles without a transaction
+	unused := authorization
+	unused.Nonce = "2"
+	if res := settle(payload(unused), "0"); !res.Success || res.Transaction != "" || res.Amount != "0" {
+		t.Errorf("Expected zero settlement without transaction, got %+v", res)
+	}
+
+	// Metered amount is transferred through Permit2
+	res := settle(payload(authorization), "400000")
+	if !res.Success || res.Amount != "400000" || res.Payer != payer {
+		t.Fatalf("Expected settlement of 400000, got %+v", res)
+	}
+	if len(sent) != 1 {
+		t.Fatalf("Expected 1 transaction, got %d", len(sent))
+	}
+	if *sent[0].To() != common.HexToAddress(utils.Permit2Address) {
+		t.Errorf("Expected transaction to Permit2, got %s", sent[0].To().Hex())
+	}
+	if res.Transaction != sent[0].Hash().Hex() {
+		t.Errorf("Expected transaction %s, got %s", sent[0].Hash().Hex(), res.Transaction)
+	}
+	permit2ABI, _ := abi.JSON(strings.NewReader(utils.Permit2PermitTransferFromABI))
+	args, err := permit2ABI.Methods["permitTransferFrom"].Inputs.Unpack(sent[0].Data()[4:])
+	if err != nil {
+		t.Fatalf("Failed to decode permitTransferFrom call: %v", err)
+	}
+	details := *abi.ConvertType(args[1], new(struct {
+		To              common.Address
+		RequestedAmount *big.Int
+	})).(*struct {
+		To              common.Address
+		RequestedAmount *big.Int
+	})
+	if details.To != common.HexToAddress(requirements.PayTo) || details.RequestedAmount.Int64() != 400000 {
+		t.Errorf("Expected transfer of 400000 to %s, got %+v", requirements.PayTo, details)
+	}
+}
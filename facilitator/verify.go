@@ -4,6 +4,7 @@ import (
 	"context"
 	"fmt"
 	"math/big"
+	"slices"
 	"strings"
 	"time"
 
@@ -16,67 +17,154 @@ import (
 	"github.com/vorpalengineering/x402-go/utils"
 )
 
-func (f *Facilitator) verifyPayment(ctx context.Context, payload *types.PaymentPayload, requirements *types.PaymentRequirements) (bool, string) {
+// Verification check names reported in full report mode
+const (
+	VerifyCheckPayload    = "payload"
+	VerifyCheckSignature  = "signature"
+	VerifyCheckBalance    = "balance"
+	VerifyCheckAmount     = "amount"
+	VerifyCheckTimeWindow = "time_window"
+	VerifyCheckParameters = "parameters"
+	VerifyCheckSimulation = "simulation"
+)
+
+// verifyPaymentChecks runs the verification checks for the payload's scheme.
+// By default it stops at the first failure. With fullReport every check is run
+// and all failures are returned.
+func (f *Facilitator) verifyPaymentChecks(ctx context.Context, payload *types.PaymentPayload, requirements *types.PaymentRequirements, fullReport bool) []types.VerifyFailure {
+	ctx, cancel := f.withTransactionTimeout(ctx)
+	defer cancel()
+
+	// Hold requirements to the assets and limits advertised in /supported
+	if kind, ok := f.cfg().GetSupportedKind(requirements.Scheme, requirements.Network); ok {
+		if failure := checkSupportedTerms(kind, requirements); failure.Check != "" {
+			return []types.VerifyFailure{failure}
+		}
+	}
+
 	// Verify based on scheme
 	switch payload.Accepted.Scheme {
 	case "exact":
-		return f.verifyExactScheme(ctx, payload, requirements)
+		if utils.IsSolanaNetwork(requirements.Network) {
+			return f.verifyExactSVMScheme(ctx, payload, requirements, fullReport)
+		}
+		return f.verifyExactScheme(ctx, payload, requirements, fullReport)
+	case "upto":
+		if utils.IsSolanaNetwork(requirements.Network) {
+			return []types.VerifyFailure{{
+				Check:  VerifyCheckPayload,
+				Code:   types.ErrCodeUnsupportedScheme,
+				Reason: fmt.Sprintf("unsupported scheme on %s: upto", requirements.Network),
+			}}
+		}
+		return f.verifyUptoScheme(ctx, payload, requirements, fullReport)
 	default:
-		return false, fmt.Sprintf("unsupported scheme: %s", requirements.Scheme)
+		return []types.VerifyFailure{{
+			Check:  VerifyCheckPayload,
+			Code:   types.ErrCodeUnsupportedScheme,
+			Reason: fmt.Sprintf("unsupported scheme: %s", requirements.Scheme),
+		}}
 	}
 }
 
-func (f *Facilitator) verifyExactScheme(ctx context.Context, payload *types.PaymentPayload, requirements *types.PaymentRequirements) (bool, string) {
-	// Extract signature from payload (we need it for multiple steps)
-	signatureHex, ok := payload.Payload["signature"].(string)
-	if !ok || signatureHex == "" {
-		return false, "missing signature"
+// checkSupportedTerms checks requirements against the accepted assets and
+// amount limits of their supported kind, returning the failure if any
+func checkSupportedTerms(kind types.SupportedKind, requirements *types.PaymentRequirements) types.VerifyFailure {
+	if len(kind.Assets) > 0 && !slices.ContainsFunc(kind.Assets, func(asset string) bool {
+		return strings.EqualFold(asset, requirements.Asset)
+	}) {
+		return types.VerifyFailure{Check: VerifyCheckParameters, Code: types.ErrCodeUnsupportedAsset, Reason: fmt.Sprintf("unsupported asset: %s", requirements.Asset)}
 	}
-
-	// Extract authorization from payload
-	auth, err := utils.ExtractExactAuthorization(payload)
-	if err != nil {
-		return false, fmt.Sprintf("invalid authorization: %v", err)
+	if kind.MinAmount == "" && kind.MaxAmount == "" {
+		return types.VerifyFailure{}
 	}
 
-	// Step 1: Signature Validation
-	if valid, reason := f.verifySignature(auth, payload, requirements); !valid {
-		return false, reason
+	amount, ok := new(big.Int).SetString(requirements.Amount, 10)
+	if !ok {
+		return types.VerifyFailure{Check: VerifyCheckAmount, Code: types.ErrCodeInvalidAmount, Reason: fmt.Sprintf("invalid required amount: %s", requirements.Amount)}
 	}
-
-	// Step 2: Balance Verification
-	if valid, reason := f.verifyBalance(ctx, auth, requirements); !valid {
-		return false, reason
+	if minAmount, ok := new(big.Int).SetString(kind.MinAmount, 10); ok && amount.Cmp(minAmount) < 0 {
+		return types.VerifyFailure{Check: VerifyCheckAmount, Code: types.ErrCodeInvalidAmount, Reason: fmt.Sprintf("amount below facilitator minimum: %s < %s", amount, minAmount)}
 	}
-
-	// Step 3: Amount Validation
-	if valid, reason := f.verifyAmount(auth, requirements); !valid {
-		return false, reason
+	if maxAmount, ok := new(big.Int).SetString(kind.MaxAmount, 10); ok && amount.Cmp(maxAmount) > 0 {
+		return types.VerifyFailure{Check: VerifyCheckAmount, Code: types.ErrCodeInvalidAmount, Reason: fmt.Sprintf("amount above facilitator maximum: %s > %s", amount, maxAmount)}
 	}
+	return types.VerifyFailure{}
+}
 
-	// Step 4: Time Window Check
-	if valid, reason := f.verifyTimeWindow(auth); !valid {
-		return false, reason
+func (f *Facilitator) verifyExactScheme(ctx context.Context, payload *types.PaymentPayload, requirements *types.PaymentRequirements, fullReport bool) []types.VerifyFailure {
+	// Extract signature from payload (we need it for multiple steps)
+	signatureHex, ok := payload.Payload["signature"].(string)
+	if !ok || signatureHex == "" {
+		return []types.VerifyFailure{{Check: VerifyCheckSignature, Code: types.ErrCodeInvalidSignature, Reason: "missing signature"}}
 	}
 
-	// Step 5: Parameter Matching
-	if valid, reason := f.verifyParameters(auth, requirements); !valid {
-		return false, reason
-	}
+	// Extract authorization from payload
+	auth, err := utils.ExtractExactAuthorization(payload)
+	if err != nil {
+		return []types.VerifyFailure{{Check: VerifyCheckPayload, Code: types.ErrCodeInvalidPayload, Reason: fmt.Sprintf("invalid authorization: %v", err)}}
+	}
+
+	// Fetch the balance, payer code and simulation in one round trip
+	calls := f.batchVerifyCalls(ctx, auth, requirements, signatureHex)
+
+	checks := []struct {
+		name string
+		code string
+		run  func() (bool, string)
+	}{
+		// Step 1: Signature Validation
+		{VerifyCheckSignature, types.ErrCodeInvalidSignature, func() (bool, string) { return f.verifySignature(ctx, auth, payload, requirements) }},
+		// Step 2: Balance Verification
+		{VerifyCheckBalance, types.ErrCodeInsufficientFunds, func() (bool, string) { return f.verifyBalance(ctx, auth, requirements, calls) }},
+		// Step 3: Amount Validation
+		{VerifyCheckAmount, types.ErrCodeInvalidAmount, func() (bool, string) { return f.verifyAmount(ctx, auth, requirements) }},
+		// Step 4: Time Window Check
+		{VerifyCheckTimeWindow, f.timeWindowCode(auth.ValidAfter), func() (bool, string) { return f.verifyTimeWindow(auth) }},
+		// Step 5: Parameter Matching
+		{VerifyCheckParameters, types.ErrCodeRecipientMismatch, func() (bool, string) { return f.verifyParameters(auth, requirements) }},
+	}
+
+	var failures []types.VerifyFailure
+	for _, check := range checks {
+		if valid, reason := check.run(); !valid {
+			failures = append(failures, types.VerifyFailure{Check: check.name, Code: check.code, Reason: reason})
+			if !fullReport {
+				return failures
+			}
+		}
+	}
+
+	// Step 6: Transaction Simulation (only once every other check passed,
+	// otherwise it just repeats their failures as a revert)
+	if len(failures) == 0 {
+		if valid, reason := f.simulateTransaction(ctx, auth, requirements, signatureHex, calls); !valid {
+			failures = append(failures, types.VerifyFailure{Check: VerifyCheckSimulation, Code: types.ErrCodeSimulationFailed, Reason: reason})
+		}
+	}
+
+	return failures
+}
 
-	// Step 6: Transaction Simulation
-	if valid, reason := f.simulateTransaction(ctx, auth, requirements, signatureHex); !valid {
+func (f *Facilitator) verifySignature(ctx context.Context, auth *types.ExactEVMSchemeAuthorization, payload *types.PaymentPayload, requirements *types.PaymentRequirements) (bool, string) {
+	// Build the signed hash, with the token's domain read on-chain if the
+	// requirements don't carry it
+	hash, signature, reason := authorizationHash(auth, payload, f.withTokenDomain(ctx, requirements))
+	if reason != "" {
 		return false, reason
 	}
 
-	return true, ""
+	// Check the payer signed the hash
+	return f.verifyPayerSignature(ctx, requirements.Network, common.HexToAddress(auth.From), hash, signature)
 }
 
-func (f *Facilitator) verifySignature(auth *types.ExactEVMSchemeAuthorization, payload *types.PaymentPayload, requirements *types.PaymentRequirements) (bool, string) {
+// authorizationHash returns the EIP-712 hash of auth and the payload's
+// signature of it, or the reason they cannot be read
+func authorizationHash(auth *types.ExactEVMSchemeAuthorization, payload *types.PaymentPayload, requirements *types.PaymentRequirements) (common.Hash, []byte, string) {
 	// Step 1: Extract signature from payload
 	signatureHex, ok := payload.Payload["signature"].(string)
 	if !ok || signatureHex == "" {
-		return false, "missing signature"
+		return common.Hash{}, nil, "missing signature"
 	}
 
 	// Remove 0x prefix if present
@@ -87,96 +175,129 @@ func (f *Facilitator) verifySignature(auth *types.ExactEVMSchemeAuthorization, p
 	// Decode hex signature
 	signature, err := hexutil.Decode("0x" + signatureHex)
 	if err != nil {
-		return false, fmt.Sprintf("invalid signature format: %v", err)
-	}
-
-	// Signature should be 65 bytes (r: 32, s: 32, v: 1)
-	if len(signature) != 65 {
-		return false, fmt.Sprintf("invalid signature length: expected 65, got %d", len(signature))
+		return common.Hash{}, nil, fmt.Sprintf("invalid signature format: %v", err)
 	}
 
 	// Step 2: Build EIP-712 typed data
 	typedData, err := utils.BuildEIP712TypedData(auth, requirements)
 	if err != nil {
-		return false, fmt.Sprintf("failed to build EIP712 typed data: %v", err)
+		return common.Hash{}, nil, fmt.Sprintf("failed to build EIP712 typed data: %v", err)
 	}
 
 	// Step 3: Hash the typed data according to EIP-712
 	domainSeparator, err := typedData.HashStruct("EIP712Domain", typedData.Domain.Map())
 	if err != nil {
-		return false, fmt.Sprintf("failed to hash domain: %v", err)
+		return common.Hash{}, nil, fmt.Sprintf("failed to hash domain: %v", err)
 	}
 
 	messageHash, err := typedData.HashStruct(typedData.PrimaryType, typedData.Message)
 	if err != nil {
-		return false, fmt.Sprintf("failed to hash message: %v", err)
+		return common.Hash{}, nil, fmt.Sprintf("failed to hash message: %v", err)
 	}
 
 	// EIP-712 final hash: keccak256("\x19\x01" ‖ domainSeparator ‖ messageHash)
 	rawData := []byte(fmt.Sprintf("\x19\x01%s%s", string(domainSeparator), string(messageHash)))
-	hash := crypto.Keccak256Hash(rawData)
+	return crypto.Keccak256Hash(rawData), signature, ""
+}
 
-	// Step 4: Adjust v value (Ethereum uses 27/28, but ecrecover expects 0/1)
-	if signature[64] == 27 || signature[64] == 28 {
-		signature[64] -= 27
+// verifyPayerSignature checks an EOA signature of hash by expectedAddr, falling
+// back to ERC-1271 when the payer is a smart contract wallet
+func (f *Facilitator) verifyPayerSignature(ctx context.Context, network string, expectedAddr common.Address, hash common.Hash, signature []byte) (bool, string) {
+	// Recover the signer of an EOA signature
+	reason := recoverSignature(hash, signature, expectedAddr)
+	if reason == "" {
+		return true, ""
 	}
 
-	// Step 5: Recover the public key from the signature
-	pubKey, err := crypto.SigToPub(hash.Bytes(), signature)
+	// Ask the wallet contract otherwise
+	client, err := f.getRPCClient(network)
+	if err != nil {
+		return false, fmt.Sprintf("failed to connect to network: %v", err)
+	}
+	contract, err := isContractWallet(ctx, client, expectedAddr)
+	if err != nil {
+		return false, fmt.Sprintf("failed to check payer: %v", err)
+	}
+	if !contract {
+		return false, reason
+	}
+	valid, err := verifyERC1271Signature(ctx, client, expectedAddr, hash, signature)
 	if err != nil {
-		return false, fmt.Sprintf("failed to recover public key: %v", err)
+		return false, fmt.Sprintf("failed to verify contract wallet signature: %v", err)
+	}
+	if !valid {
+		return false, fmt.Sprintf("signature rejected by contract wallet %s", expectedAddr.Hex())
 	}
 
-	// Step 6: Get the address from the public key
-	recoveredAddr := crypto.PubkeyToAddress(*pubKey)
+	return true, ""
+}
+
+// recoverSignature checks that a 65 byte signature of hash was made by
+// expected, returning the reason if not
+func recoverSignature(hash common.Hash, signature []byte, expected common.Address) string {
+	// Recover the signer, rejecting malleable signatures
+	recoveredAddr, err := utils.RecoverAddress(hash.Bytes(), signature)
+	if err != nil {
+		return err.Error()
+	}
 
-	// Step 7: Verify the recovered address matches auth.From
-	expectedAddr := common.HexToAddress(auth.From)
-	if recoveredAddr != expectedAddr {
-		return false, fmt.Sprintf("signature mismatch: recovered %s, expected %s",
-			recoveredAddr.Hex(), expectedAddr.Hex())
+	// Verify the recovered address matches
+	if recoveredAddr != expected {
+		return fmt.Sprintf("signature mismatch: recovered %s, expected %s",
+			recoveredAddr.Hex(), expected.Hex())
 	}
 
-	return true, ""
+	return ""
 }
 
-func (f *Facilitator) verifyBalance(ctx context.Context, auth *types.ExactEVMSchemeAuthorization, requirements *types.PaymentRequirements) (bool, string) {
+// verifyBalance checks the payer holds the payment amount, using the batched
+// balanceOf result if calls is set
+func (f *Facilitator) verifyBalance(ctx context.Context, auth *types.ExactEVMSchemeAuthorization, requirements *types.PaymentRequirements, calls *verifyCalls) (bool, string) {
 	// Parse the payment amount
 	paymentAmount, ok := new(big.Int).SetString(auth.Value, 10)
 	if !ok {
 		return false, "invalid payment amount format"
 	}
 
-	// Get RPC client for the network
-	client, err := f.getRPCClient(requirements.Network)
-	if err != nil {
-		return false, fmt.Sprintf("failed to connect to network: %v", err)
-	}
-
 	// Parse the ERC-20 ABI
 	parsedABI, err := abi.JSON(strings.NewReader(utils.ERC20BalanceOfABI))
 	if err != nil {
 		return false, fmt.Sprintf("failed to parse ABI: %v", err)
 	}
 
-	// Encode the balanceOf call
-	fromAddress := common.HexToAddress(auth.From)
-	callData, err := parsedABI.Pack("balanceOf", fromAddress)
-	if err != nil {
-		return false, fmt.Sprintf("failed to encode balanceOf call: %v", err)
-	}
-
-	// Create the call message
-	tokenAddress := common.HexToAddress(requirements.Asset)
-	msg := ethereum.CallMsg{
-		To:   &tokenAddress,
-		Data: callData,
-	}
-
-	// Execute the call with context
-	result, err := client.CallContract(ctx, msg, nil) // nil = latest block
-	if err != nil {
-		return false, fmt.Sprintf("failed to call balanceOf: %v", err)
+	var result []byte
+	if calls != nil {
+		// Take the batched result
+		if calls.balanceErr != nil {
+			return false, fmt.Sprintf("failed to call balanceOf: %v", calls.balanceErr)
+		}
+		result = calls.balance
+	} else {
+		// Get RPC client for the network
+		client, err := f.getRPCClient(requirements.Network)
+		if err != nil {
+			return false, fmt.Sprintf("failed to connect to network: %v", err)
+		}
+
+		// Encode the balanceOf call
+		fromAddress := common.HexToAddress(auth.From)
+		callData, err := parsedABI.Pack("balanceOf", fromAddress)
+		if err != nil {
+			return false, fmt.Sprintf("failed to encode balanceOf call: %v", err)
+		}
+
+		// Create the call message
+		tokenAddress := common.HexToAddress(requirements.Asset)
+		msg := ethereum.CallMsg{
+			To:   &tokenAddress,
+			Data: callData,
+		}
+
+		// Execute the call with context
+		result, err = client.CallContract(ctx, msg, nil) // nil = latest block
+		if err != nil {
+			return false, fmt.Sprintf("failed to call balanceOf: %v", err)
+		}
 	}
 
 	// Decode the result
@@ -188,13 +309,13 @@ func (f *Facilitator) verifyBalance(ctx context.Context, auth *types.ExactEVMSch
 
 	// Check if balance is sufficient
 	if balance.Cmp(paymentAmount) < 0 {
-		return false, fmt.Sprintf("insufficient balance: has %s, needs %s", balance.String(), paymentAmount.String())
+		return false, fmt.Sprintf("insufficient balance: has %s, needs %s", f.formatTokenAmount(ctx, requirements, balance), f.formatTokenAmount(ctx, requirements, paymentAmount))
 	}
 
 	return true, ""
 }
 
-func (f *Facilitator) verifyAmount(auth *types.ExactEVMSchemeAuthorization, requirements *types.PaymentRequirements) (bool, string) {
+func (f *Facilitator) verifyAmount(ctx context.Context, auth *types.ExactEVMSchemeAuthorization, requirements *types.PaymentRequirements) (bool, string) {
 	// Parse amounts as big.Int for safe comparison
 	paymentAmount, ok := new(big.Int).SetString(auth.Value, 10)
 	if !ok {
@@ -208,29 +329,58 @@ func (f *Facilitator) verifyAmount(auth *types.ExactEVMSchemeAuthorization, requ
 
 	// Payment must be >= required amount
 	if paymentAmount.Cmp(requiredAmount) < 0 {
-		return false, fmt.Sprintf("insufficient amount: got %s, required %s", auth.Value, requirements.Amount)
+		return false, fmt.Sprintf("insufficient amount: got %s, required %s", f.formatTokenAmount(ctx, requirements, paymentAmount), f.formatTokenAmount(ctx, requirements, requiredAmount))
+	}
+
+	// And cover the facilitator fee on top of it
+	if fee := f.requiredFee(requirements); fee.Sign() > 0 {
+		withFee := new(big.Int).Add(requiredAmount, fee)
+		if paymentAmount.Cmp(withFee) < 0 {
+			return false, fmt.Sprintf("insufficient amount: got %s, required %s plus fee %s", f.formatTokenAmount(ctx, requirements, paymentAmount), f.formatTokenAmount(ctx, requirements, requiredAmount), f.formatTokenAmount(ctx, requirements, fee))
+		}
 	}
 
 	return true, ""
 }
 
 func (f *Facilitator) verifyTimeWindow(auth *types.ExactEVMSchemeAuthorization) (bool, string) {
-	now := time.Now().Unix()
+	return checkTimeWindow(auth, f.now())
+}
 
+// checkTimeWindow checks auth is valid at now
+func checkTimeWindow(auth *types.ExactEVMSchemeAuthorization, now time.Time) (bool, string) {
 	// Check validAfter
-	if now < auth.ValidAfter {
+	if now.Unix() < auth.ValidAfter {
 		return false, fmt.Sprintf("payment not yet valid (valid after %d)", auth.ValidAfter)
 	}
 
 	// Check validBefore
-	if now > auth.ValidBefore {
+	if now.Unix() > auth.ValidBefore {
 		return false, fmt.Sprintf("payment expired (valid before %d)", auth.ValidBefore)
 	}
 
 	return true, ""
 }
 
+// timeWindowCode is the error code of an authorization outside its time
+// window, not yet valid before validAfter and expired after
+func (f *Facilitator) timeWindowCode(validAfter int64) string {
+	return timeWindowCodeAt(validAfter, f.now())
+}
+
+func timeWindowCodeAt(validAfter int64, now time.Time) string {
+	if now.Unix() < validAfter {
+		return types.ErrCodeNotYetValid
+	}
+	return types.ErrCodeExpired
+}
+
 func (f *Facilitator) verifyParameters(auth *types.ExactEVMSchemeAuthorization, requirements *types.PaymentRequirements) (bool, string) {
+	return checkParameters(auth, requirements)
+}
+
+// checkParameters checks auth pays the recipient of requirements
+func checkParameters(auth *types.ExactEVMSchemeAuthorization, requirements *types.PaymentRequirements) (bool, string) {
 	// Verify recipient address matches
 	if auth.To != requirements.PayTo {
 		return false, fmt.Sprintf("recipient mismatch: got %s, expected %s", auth.To, requirements.PayTo)
@@ -241,55 +391,40 @@ func (f *Facilitator) verifyParameters(auth *types.ExactEVMSchemeAuthorization,
 	return true, ""
 }
 
-func (f *Facilitator) simulateTransaction(ctx context.Context, auth *types.ExactEVMSchemeAuthorization, requirements *types.PaymentRequirements, signatureHex string) (bool, string) {
+// simulateTransaction runs the settlement call with eth_call, using the
+// batched simulation if calls is set and the payer is not a contract wallet
+func (f *Facilitator) simulateTransaction(ctx context.Context, auth *types.ExactEVMSchemeAuthorization, requirements *types.PaymentRequirements, signatureHex string, calls *verifyCalls) (bool, string) {
 	// Get RPC client
 	client, err := f.getRPCClient(requirements.Network)
 	if err != nil {
 		return false, fmt.Sprintf("failed to connect to network: %v", err)
 	}
 
-	// Parse the EIP-3009 ABI
-	parsedABI, err := abi.JSON(strings.NewReader(utils.EIP3009TransferWithAuthABI))
-	if err != nil {
-		return false, fmt.Sprintf("failed to parse ABI: %v", err)
+	// Contract wallets can only settle through the signature-bytes overload
+	var contract bool
+	if calls != nil {
+		contract, err = calls.contractWallet()
+	} else {
+		contract, err = isContractWallet(ctx, client, common.HexToAddress(auth.From))
 	}
-
-	// Extract v, r, s from signature
-	v, r, s, err := utils.ExtractVRS(signatureHex)
 	if err != nil {
-		return false, fmt.Sprintf("failed to extract signature components: %v", err)
+		return false, fmt.Sprintf("failed to check payer: %v", err)
 	}
 
-	// Parse addresses and value
-	fromAddr := common.HexToAddress(auth.From)
-	toAddr := common.HexToAddress(auth.To)
-	value := new(big.Int)
-	value.SetString(auth.Value, 10)
-
-	// Parse nonce (should be bytes32)
-	var nonce [32]byte
-	nonceBytes, err := hexutil.Decode(auth.Nonce)
-	if err != nil {
-		return false, fmt.Sprintf("invalid nonce format: %v", err)
+	// The batch already simulated the EOA overload
+	if calls != nil && !contract {
+		if calls.simulationErr != nil {
+			return false, fmt.Sprintf("transaction would fail: %v", calls.simulationErr)
+		}
+		return true, ""
 	}
-	copy(nonce[:], nonceBytes)
 
 	// Encode the transferWithAuthorization call
-	callData, err := parsedABI.Pack(
-		"transferWithAuthorization",
-		fromAddr,
-		toAddr,
-		value,
-		big.NewInt(auth.ValidAfter),
-		big.NewInt(auth.ValidBefore),
-		nonce,
-		v,
-		r,
-		s,
-	)
+	candidates, err := encodeTransferWithAuthorization(auth, signatureHex, contract)
 	if err != nil {
-		return false, fmt.Sprintf("failed to encode call: %v", err)
+		return false, err.Error()
 	}
+	callData := candidates[0].data
 
 	// Create the call message
 	tokenAddress := common.HexToAddress(requirements.Asset)
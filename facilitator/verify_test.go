@@ -0,0 +1,288 @@
+package facilitator
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/vorpalengineering/x402-go/types"
+)
+
+func TestVerifyFullReport(t *testing.T) {
+	testConfig := &FacilitatorConfig{
+		Networks: map[string]NetworkConfig{
+			"eip155:8453": {
+				RpcUrl: "http://127.0.0.1:1",
+			},
+		},
+		Supported: []types.SupportedKind{
+			{Scheme: "exact", Network: "eip155:8453"},
+		},
+		Log: LogConfig{
+			Level: "info",
+		},
+	}
+
+	// Create facilitator
+	f := NewFacilitator(testConfig)
+	f.now = func() time.Time { return time.Unix(1700010000, 0) }
+
+	// Payment with a bad amount, an expired window and the wrong payee
+	requirements := types.PaymentRequirements{
+		Scheme:  "exact",
+		Network: "eip155:8453",
+		Amount:  "1000000",
+		PayTo:   "0x742d35Cc6634C0532925a3b844Bc9e7595f0bEb",
+		Asset:   "0x833589fCD6eDb6E08f4c7C32D4f71b54bdA02913",
+		Extra:   map[string]any{"name": "USD Coin", "version": "2"},
+	}
+	body, _ := json.Marshal(types.VerifyRequest{
+		PaymentPayload: types.PaymentPayload{
+			X402Version: 2,
+			Accepted:    requirements,
+			Payload: map[string]any{
+				"signature": "0x" + strings.Repeat("11", 65),
+				"authorization": map[string]any{
+					"from":        "0xf39Fd6e51aad88F6F4ce6aB8827279cffFb92266",
+					"to":          "0x0000000000000000000000000000000000000001",
+					"value":       "500000",
+					"validAfter":  1700000000,
+					"validBefore": 1700003600,
+					"nonce":       "0x" + strings.Repeat("00", 32),
+				},
+			},
+		},
+		PaymentRequirements: requirements,
+	})
+
+	// Default mode stops at the first failure
+	req, _ := http.NewRequest("POST", "/verify", bytes.NewReader(body))
+	recorder := httptest.NewRecorder()
+	f.router.ServeHTTP(recorder, req)
+	var resp types.VerifyResponse
+	json.NewDecoder(recorder.Body).Decode(&resp)
+	if resp.IsValid {
+		t.Fatalf("Expected payment to be invalid")
+	}
+	if len(resp.Failures) != 0 {
+		t.Errorf("Expected no failures list in default mode, got %+v", resp.Failures)
+	}
+
+	// Full report lists every failed check
+	req, _ = http.NewRequest("POST", "/verify?report=full", bytes.NewReader(body))
+	recorder = httptest.NewRecorder()
+	f.router.ServeHTTP(recorder, req)
+	resp = types.VerifyResponse{}
+	json.NewDecoder(recorder.Body).Decode(&resp)
+	if resp.IsValid {
+		t.Fatalf("Expected payment to be invalid")
+	}
+
+	checks := make(map[string]bool)
+	for _, failure := range resp.Failures {
+		checks[failure.Check] = true
+		if failure.Check == VerifyCheckAmount && failure.Code != types.ErrCodeInvalidAmount {
+			t.Errorf("Expected %s code for the amount check, got %s", types.ErrCodeInvalidAmount, failure.Code)
+		}
+	}
+	for _, check := range []string{VerifyCheckAmount, VerifyCheckTimeWindow, VerifyCheckParameters} {
+		if !checks[check] {
+			t.Errorf("Expected %s failure in report, got %+v", check, resp.Failures)
+		}
+	}
+	if checks[VerifyCheckSimulation] {
+		t.Errorf("Expected simulation to be skipped when other checks fail")
+	}
+	if resp.InvalidReason != resp.Failures[0].Code || resp.InvalidMessage != resp.Failures[0].Reason {
+		t.Errorf("Expected invalidReason and invalidMessage of the first failure, got %s: %s", resp.InvalidReason, resp.InvalidMessage)
+	}
+}
+
+func TestVerifySignatureERC1271(t *testing.T) {
+	// RPC where the payer is a contract wallet accepting one signature
+	validSignature := "0x" + strings.Repeat("ab", 130)
+	isContract := true
+	rpcServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req struct {
+			ID     json.RawMessage   `json:"id"`
+			Method string            `json:"method"`
+			Params []json.RawMessage `json:"params"`
+		}
+		json.NewDecoder(r.Body).Decode(&req)
+		result := `"0x"`
+		switch req.Method {
+		case "eth_getCode":
+			if isContract {
+				result = `"0x6080"`
+			}
+		case "eth_call":
+			var arg struct {
+				Input hexutil.Bytes `json:"input"`
+			}
+			json.Unmarshal(req.Params[0], &arg)
+			if strings.Contains(hexutil.Encode(arg.Input), validSignature[2:]) {
+				result = `"0x1626ba7e00000000000000000000000000000000000000000000000000000000"`
+			} else {
+				result = `"0x0000000000000000000000000000000000000000000000000000000000000000"`
+			}
+		}
+		w.Header().Set("Content-Type", "application/json")
+		fmt.Fprintf(w, `{"jsonrpc":"2.0","id":%s,"result":%s}`, req.ID, result)
+	}))
+	defer rpcServer.Close()
+
+	f := NewFacilitator(&FacilitatorConfig{
+		Networks: map[string]NetworkConfig{
+			"eip155:8453": {RpcUrl: rpcServer.URL},
+		},
+		Log: LogConfig{Level: "error"},
+	})
+	requirements := &types.PaymentRequirements{
+		Scheme:  "exact",
+		Network: "eip155:8453",
+		Asset:   "0x833589fCD6eDb6E08f4c7C32D4f71b54bdA02913",
+		Extra:   map[string]any{"name": "USD Coin", "version": "2"},
+	}
+	auth := &types.ExactEVMSchemeAuthorization{
+		From:        "0x70997970C51812dc3A010C7d01b50e0d17dc79C8",
+		To:          "0x3C44CdDdB6a900fa2b585dd299e03d12FA4293BC",
+		Value:       "1000000",
+		ValidBefore: 4102444800,
+		Nonce:       "0x" + strings.Repeat("00", 32),
+	}
+	verify := func(signature string) (bool, string) {
+		payload := &types.PaymentPayload{Payload: map[string]any{"signature": signature}}
+		return f.verifySignature(context.Background(), auth, payload, requirements)
+	}
+
+	// Contract wallets are asked to validate the signature
+	if valid, reason := verify(validSignature); !valid {
+		t.Errorf("Expected contract wallet signature to be valid, got %s", reason)
+	}
+	if valid, reason := verify("0x" + strings.Repeat("cd", 65)); valid || !strings.Contains(reason, "rejected by contract wallet") {
+		t.Errorf("Expected signature to be rejected by the wallet, got %s", reason)
+	}
+
+	// EOAs keep the ecrecover result
+	isContract = false
+	if valid, reason := verify(validSignature); valid || !strings.Contains(reason, "invalid signature length") {
+		t.Errorf("Expected invalid length for an EOA, got %s", reason)
+	}
+}
+
+func TestCheckSupportedTerms(t *testing.T) {
+	kind := types.SupportedKind{
+		Scheme:    "exact",
+		Network:   "eip155:8453",
+		Assets:    []string{"0x833589fCD6eDb6E08f4c7C32D4f71b54bdA02913"},
+		MinAmount: "1000",
+		MaxAmount: "5000",
+	}
+
+	tests := []struct {
+		name          string
+		kind          types.SupportedKind
+		asset         string
+		amount        string
+		expectedCheck string
+	}{
+		{"within terms", kind, "0x833589fcd6edb6e08f4c7c32d4f71b54bda02913", "2000", ""},
+		{"no terms", types.SupportedKind{}, "0xdAC17F958D2ee523a2206206994597C13D831ec7", "1", ""},
+		{"other asset", kind, "0xdAC17F958D2ee523a2206206994597C13D831ec7", "2000", VerifyCheckParameters},
+		{"below minimum", kind, "0x833589fCD6eDb6E08f4c7C32D4f71b54bdA02913", "999", VerifyCheckAmount},
+		{"above maximum", kind, "0x833589fCD6eDb6E08f4c7C32D4f71b54bdA02913", "5001", VerifyCheckAmount},
+		{"invalid amount", kind, "0x833589fCD6eDb6E08f4c7C32D4f71b54bdA02913", "lots", VerifyCheckAmount},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			requirements := &types.PaymentRequirements{Asset: tt.asset, Amount: tt.amount}
+			failure := checkSupportedTerms(tt.kind, requirements)
+			if failure.Check != tt.expectedCheck {
+				t.Errorf("Expected check %q, got %q (%s)", tt.expectedCheck, failure.Check, failure.Reason)
+			}
+		})
+	}
+}
+
+func TestBatchVerifyCalls(t *testing.T) {
+	// RPC answering batches, where the simulation reverts
+	var requests, batches int
+	acceptBatches := true
+	rpcServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		var batch []struct {
+			ID     json.RawMessage `json:"id"`
+			Method string          `json:"method"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&batch); err != nil || !acceptBatches {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		batches++
+		responses := make([]string, len(batch))
+		for i, req := range batch {
+			switch {
+			case req.Method == "eth_getCode":
+				responses[i] = fmt.Sprintf(`{"jsonrpc":"2.0","id":%s,"result":"0x"}`, req.ID)
+			case i == 0:
+				// balanceOf returns 5000000
+				responses[i] = fmt.Sprintf(`{"jsonrpc":"2.0","id":%s,"result":"0x%064x"}`, req.ID, 5000000)
+			default:
+				responses[i] = fmt.Sprintf(`{"jsonrpc":"2.0","id":%s,"error":{"code":3,"message":"execution reverted: FiatTokenV2: invalid signature"}}`, req.ID)
+			}
+		}
+		w.Header().Set("Content-Type", "application/json")
+		fmt.Fprintf(w, "[%s]", strings.Join(responses, ","))
+	}))
+	defer rpcServer.Close()
+
+	f := NewFacilitator(&FacilitatorConfig{
+		Networks: map[string]NetworkConfig{
+			"eip155:8453": {RpcUrl: rpcServer.URL},
+		},
+		Log: LogConfig{Level: "error"},
+	})
+	defer f.Close()
+	requirements := &types.PaymentRequirements{
+		Scheme:  "exact",
+		Network: "eip155:8453",
+		Asset:   "0x833589fCD6eDb6E08f4c7C32D4f71b54bdA02913",
+	}
+	auth := &types.ExactEVMSchemeAuthorization{
+		From:        "0x70997970C51812dc3A010C7d01b50e0d17dc79C8",
+		To:          "0x3C44CdDdB6a900fa2b585dd299e03d12FA4293BC",
+		Value:       "1000000",
+		ValidBefore: 4102444800,
+		Nonce:       "0x" + strings.Repeat("00", 32),
+	}
+	signature := "0x" + strings.Repeat("ab", 32) + strings.Repeat("11", 32) + "1b"
+
+	// Balance, code and simulation come back in a single request
+	calls := f.batchVerifyCalls(context.Background(), auth, requirements, signature)
+	if calls == nil {
+		t.Fatal("Expected batched calls")
+	}
+	if valid, reason := f.verifyBalance(context.Background(), auth, requirements, calls); !valid {
+		t.Errorf("Expected balance to cover the payment, got %s", reason)
+	}
+	if valid, reason := f.simulateTransaction(context.Background(), auth, requirements, signature, calls); valid || !strings.Contains(reason, "invalid signature") {
+		t.Errorf("Expected the batched revert, got %s", reason)
+	}
+	if requests != 1 || batches != 1 {
+		t.Errorf("Expected one batch request, got %d requests", requests)
+	}
+
+	// Endpoints rejecting batches fall back to single calls
+	acceptBatches = false
+	if calls := f.batchVerifyCalls(context.Background(), auth, requirements, signature); calls != nil {
+		t.Errorf("Expected no batched calls, got %+v", calls)
+	}
+}
@@ -0,0 +1,84 @@
+package facilitator
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/ethereum/go-ethereum/accounts/abi"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/rpc"
+	"github.com/vorpalengineering/x402-go/types"
+	"github.com/vorpalengineering/x402-go/utils"
+)
+
+// verifyCalls are the results of the chain reads of an exact scheme
+// verification, fetched together in one JSON-RPC batch
+type verifyCalls struct {
+	balance    hexutil.Bytes
+	balanceErr error
+	code       hexutil.Bytes
+	codeErr    error
+	// simulationErr is the result of simulating the EOA overload. Contract
+	// wallets are simulated again with the signature-bytes overload.
+	simulationErr error
+}
+
+// batchVerifyCalls sends the payer's balanceOf, its code and the settlement
+// simulation as one JSON-RPC batch, instead of three round trips. It returns
+// nil if the batch can't be sent, e.g. because the endpoint doesn't accept
+// batches, and the checks then make their own calls.
+func (f *Facilitator) batchVerifyCalls(ctx context.Context, auth *types.ExactEVMSchemeAuthorization, requirements *types.PaymentRequirements, signatureHex string) *verifyCalls {
+	client, err := f.getRPCClient(requirements.Network)
+	if err != nil {
+		return nil
+	}
+
+	// Encode the balanceOf and transferWithAuthorization calls
+	parsedABI, err := abi.JSON(strings.NewReader(utils.ERC20BalanceOfABI))
+	if err != nil {
+		return nil
+	}
+	from := common.HexToAddress(auth.From)
+	balanceData, err := parsedABI.Pack("balanceOf", from)
+	if err != nil {
+		return nil
+	}
+	candidates, err := encodeTransferWithAuthorization(auth, signatureHex, false)
+	if err != nil {
+		return nil
+	}
+	token := common.HexToAddress(requirements.Asset)
+
+	calls := &verifyCalls{}
+	batch := []rpc.BatchElem{
+		{Method: "eth_call", Args: []any{callArg(token, balanceData), "latest"}, Result: &calls.balance},
+		{Method: "eth_getCode", Args: []any{from, "latest"}, Result: &calls.code},
+		{Method: "eth_call", Args: []any{callArg(token, candidates[0].data), "latest"}, Result: new(hexutil.Bytes)},
+	}
+	if err := client.Client().BatchCallContext(ctx, batch); err != nil {
+		f.logger.Debug("rpc batch failed, sending verify calls one by one", "network", requirements.Network, "error", err)
+		return nil
+	}
+	calls.balanceErr = batch[0].Error
+	calls.codeErr = batch[1].Error
+	calls.simulationErr = batch[2].Error
+	return calls
+}
+
+// callArg is the eth_call transaction object of a call to contract
+func callArg(contract common.Address, data []byte) map[string]any {
+	return map[string]any{
+		"to":    contract,
+		"input": hexutil.Bytes(data),
+	}
+}
+
+// contractWallet reports whether the fetched payer code belongs to a contract
+func (calls *verifyCalls) contractWallet() (bool, error) {
+	if calls.codeErr != nil {
+		return false, fmt.Errorf("failed to get code: %w", calls.codeErr)
+	}
+	return len(calls.code) > 0, nil
+}
@@ -0,0 +1,129 @@
+package facilitator
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+
+	"github.com/gin-gonic/gin"
+	"github.com/vorpalengineering/x402-go/types"
+	"github.com/vorpalengineering/x402-go/utils"
+)
+
+// paymentRequest is a /verify or /settle body in either wire format. v2
+// clients send a PaymentPayload, v1 clients a PaymentPayloadV1 in
+// paymentPayload or base64 encoded in paymentHeader.
+type paymentRequest struct {
+	X402Version         int             `json:"x402Version"`
+	PaymentHeader       string          `json:"paymentHeader"`
+	PaymentPayload      json.RawMessage `json:"paymentPayload"`
+	PaymentRequirements json.RawMessage `json:"paymentRequirements"`
+	Amount              string          `json:"amount"`
+}
+
+// bindPaymentRequest decodes a /verify or /settle body, converting v1
+// requests to the v2 types. It returns the protocol version the client spoke.
+func bindPaymentRequest(ginCtx *gin.Context) (*types.SettleRequest, int, error) {
+	var wire paymentRequest
+	if err := ginCtx.ShouldBindJSON(&wire); err != nil {
+		return nil, 0, err
+	}
+	return wire.normalize()
+}
+
+// version detects v1 requests by their version field, either at the top
+// level or in the payload, or by a paymentHeader
+func (wire *paymentRequest) version() int {
+	if wire.X402Version == types.X402VersionV1 || wire.PaymentHeader != "" {
+		return types.X402VersionV1
+	}
+	var payload struct {
+		X402Version int `json:"x402Version"`
+	}
+	if len(wire.PaymentPayload) > 0 && json.Unmarshal(wire.PaymentPayload, &payload) == nil && payload.X402Version == types.X402VersionV1 {
+		return types.X402VersionV1
+	}
+	return types.X402VersionV2
+}
+
+func (wire *paymentRequest) normalize() (*types.SettleRequest, int, error) {
+	req := &types.SettleRequest{Amount: wire.Amount}
+	version := wire.version()
+	if version == types.X402VersionV2 {
+		if len(wire.PaymentPayload) > 0 {
+			if err := json.Unmarshal(wire.PaymentPayload, &req.PaymentPayload); err != nil {
+				return nil, 0, fmt.Errorf("invalid paymentPayload: %w", err)
+			}
+		}
+		if len(wire.PaymentRequirements) > 0 {
+			if err := json.Unmarshal(wire.PaymentRequirements, &req.PaymentRequirements); err != nil {
+				return nil, 0, fmt.Errorf("invalid paymentRequirements: %w", err)
+			}
+		}
+		return req, version, nil
+	}
+
+	// Decode the v1 payload from whichever field carries it
+	var payload types.PaymentPayloadV1
+	payloadJSON := []byte(wire.PaymentPayload)
+	if wire.PaymentHeader != "" {
+		decoded, err := base64.StdEncoding.DecodeString(wire.PaymentHeader)
+		if err != nil {
+			return nil, 0, fmt.Errorf("invalid paymentHeader: %w", err)
+		}
+		payloadJSON = decoded
+	}
+	if len(payloadJSON) == 0 {
+		return nil, 0, fmt.Errorf("paymentPayload or paymentHeader is required")
+	}
+	if err := json.Unmarshal(payloadJSON, &payload); err != nil {
+		return nil, 0, fmt.Errorf("invalid paymentPayload: %w", err)
+	}
+	var requirements types.PaymentRequirementsV1
+	if len(wire.PaymentRequirements) > 0 {
+		if err := json.Unmarshal(wire.PaymentRequirements, &requirements); err != nil {
+			return nil, 0, fmt.Errorf("invalid paymentRequirements: %w", err)
+		}
+	}
+
+	req.PaymentRequirements = types.PaymentRequirements{
+		Scheme:            requirements.Scheme,
+		Network:           utils.NetworkFromV1(requirements.Network),
+		Amount:            requirements.MaxAmountRequired,
+		Asset:             requirements.Asset,
+		PayTo:             requirements.PayTo,
+		MaxTimeoutSeconds: requirements.MaxTimeoutSeconds,
+		Extra:             requirements.Extra,
+	}
+
+	// The payload accepts the requirements with its own scheme and network,
+	// so a mismatch fails verification as it does in v2
+	accepted := req.PaymentRequirements
+	accepted.Scheme = payload.Scheme
+	accepted.Network = utils.NetworkFromV1(payload.Network)
+	req.PaymentPayload = types.PaymentPayload{
+		X402Version: types.X402VersionV1,
+		Accepted:    accepted,
+		Payload:     payload.Payload,
+	}
+	if requirements.Resource != "" {
+		req.PaymentPayload.Resource = &types.ResourceInfo{
+			URL:         requirements.Resource,
+			Description: requirements.Description,
+			MimeType:    requirements.MimeType,
+		}
+	}
+	return req, version, nil
+}
+
+// versionedSettleResponse copies a settlement response for a client of the
+// given version, naming the network the way that version does. The response
+// may be shared with duplicate settlements, so it is not changed in place.
+func versionedSettleResponse(resp *types.SettleResponse, version int) *types.SettleResponse {
+	out := *resp
+	out.X402Version = version
+	if version == types.X402VersionV1 && out.Network != "" {
+		out.Network = utils.NetworkToV1(out.Network)
+	}
+	return &out
+}
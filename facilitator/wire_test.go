@@ -0,0 +1,147 @@
+package facilitator
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/vorpalengineering/x402-go/types"
+)
+
+const v1Requirements = `{
+	"scheme": "exact",
+	"network": "base-sepolia",
+	"maxAmountRequired": "1000000",
+	"resource": "https://api.example.com/data",
+	"description": "Data",
+	"mimeType": "application/json",
+	"payTo": "0x70997970C51812dc3A010C7d01b50e0d17dc79C8",
+	"maxTimeoutSeconds": 60,
+	"asset": "0x036CbD53842c5426634e7929541eC2318f3dCF7e",
+	"extra": {"name": "USDC", "version": "2"}
+}`
+
+const v1Payload = `{
+	"x402Version": 1,
+	"scheme": "exact",
+	"network": "base-sepolia",
+	"payload": {"signature": "0xabc", "authorization": {"from": "0x1", "value": "1000000"}}
+}`
+
+func TestNormalizePaymentRequest(t *testing.T) {
+	header := base64.StdEncoding.EncodeToString([]byte(v1Payload))
+	tests := []struct {
+		name string
+		body string
+	}{
+		{"v1 payload", `{"x402Version": 1, "paymentPayload": ` + v1Payload + `, "paymentRequirements": ` + v1Requirements + `}`},
+		{"v1 header", `{"x402Version": 1, "paymentHeader": "` + header + `", "paymentRequirements": ` + v1Requirements + `}`},
+		{"v1 payload version only", `{"paymentPayload": ` + v1Payload + `, "paymentRequirements": ` + v1Requirements + `}`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var wire paymentRequest
+			if err := json.Unmarshal([]byte(tt.body), &wire); err != nil {
+				t.Fatalf("Failed to decode body: %v", err)
+			}
+			req, version, err := wire.normalize()
+			if err != nil {
+				t.Fatalf("Expected request to normalize, got %v", err)
+			}
+			if version != types.X402VersionV1 {
+				t.Errorf("Expected version 1, got %d", version)
+			}
+			requirements := req.PaymentRequirements
+			if requirements.Network != "eip155:84532" || requirements.Amount != "1000000" || requirements.Extra["name"] != "USDC" {
+				t.Errorf("Unexpected requirements: %+v", requirements)
+			}
+			if req.PaymentPayload.Accepted.Scheme != "exact" || req.PaymentPayload.Accepted.Network != "eip155:84532" {
+				t.Errorf("Expected payload to accept exact on eip155:84532, got %+v", req.PaymentPayload.Accepted)
+			}
+			if req.PaymentPayload.Payload["signature"] != "0xabc" {
+				t.Errorf("Expected scheme payload to be kept, got %v", req.PaymentPayload.Payload)
+			}
+			if req.PaymentPayload.Resource == nil || req.PaymentPayload.Resource.URL != "https://api.example.com/data" {
+				t.Errorf("Expected resource from requirements, got %+v", req.PaymentPayload.Resource)
+			}
+		})
+	}
+
+	// v2 requests decode unchanged
+	var wire paymentRequest
+	json.Unmarshal([]byte(`{"paymentPayload": {"x402Version": 2, "accepted": {"scheme": "exact", "network": "eip155:8453"}}, "paymentRequirements": {"scheme": "exact", "network": "eip155:8453", "amount": "5"}, "amount": "3"}`), &wire)
+	req, version, err := wire.normalize()
+	if err != nil {
+		t.Fatalf("Expected v2 request to decode, got %v", err)
+	}
+	if version != types.X402VersionV2 || req.PaymentRequirements.Amount != "5" || req.Amount != "3" || req.PaymentPayload.Accepted.Network != "eip155:8453" {
+		t.Errorf("Unexpected v2 request: version %d, %+v", version, req)
+	}
+
+	// A bad header is rejected
+	json.Unmarshal([]byte(`{"x402Version": 1, "paymentHeader": "not base64!"}`), &wire)
+	if _, _, err := wire.normalize(); err == nil {
+		t.Error("Expected invalid paymentHeader to fail")
+	}
+}
+
+func TestVerifyEchoesWireVersion(t *testing.T) {
+	privKey, _ := crypto.HexToECDSA("ac0974bec39a17e36ba4a6b4d238ff944bacb478cbed5efcae784d7bf4f2ff80")
+	f := NewFacilitator(&FacilitatorConfig{
+		Networks: map[string]NetworkConfig{},
+		Log: LogConfig{
+			Level: "error",
+		},
+		Signer: SignerConfig{
+			Address:    crypto.PubkeyToAddress(privKey.PublicKey),
+			PrivateKey: privKey,
+		},
+	})
+	defer f.Close()
+
+	tests := []struct {
+		name    string
+		body    string
+		version int
+	}{
+		{"v1", `{"x402Version": 1, "paymentPayload": ` + v1Payload + `, "paymentRequirements": ` + v1Requirements + `}`, types.X402VersionV1},
+		{"v2", `{"paymentPayload": {"x402Version": 2}, "paymentRequirements": {"scheme": "exact", "network": "eip155:84532"}}`, types.X402VersionV2},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			recorder := httptest.NewRecorder()
+			f.router.ServeHTTP(recorder, httptest.NewRequest("POST", "/verify", strings.NewReader(tt.body)))
+			if recorder.Code != http.StatusOK {
+				t.Fatalf("Expected status 200, got %d: %s", recorder.Code, recorder.Body.String())
+			}
+			var res types.VerifyResponse
+			json.Unmarshal(recorder.Body.Bytes(), &res)
+			if res.X402Version != tt.version {
+				t.Errorf("Expected x402Version %d, got %d", tt.version, res.X402Version)
+			}
+			if res.IsValid || res.InvalidReason != types.ErrCodeUnsupportedNetwork {
+				t.Errorf("Expected unsupported network, got %+v", res)
+			}
+		})
+	}
+}
+
+func TestVersionedSettleResponse(t *testing.T) {
+	resp := &types.SettleResponse{Success: true, Network: "eip155:84532", Transaction: "0x1"}
+
+	v1 := versionedSettleResponse(resp, types.X402VersionV1)
+	if v1.X402Version != types.X402VersionV1 || v1.Network != "base-sepolia" {
+		t.Errorf("Expected v1 response on base-sepolia, got %+v", v1)
+	}
+	v2 := versionedSettleResponse(resp, types.X402VersionV2)
+	if v2.X402Version != types.X402VersionV2 || v2.Network != "eip155:84532" {
+		t.Errorf("Expected v2 response on eip155:84532, got %+v", v2)
+	}
+	if resp.X402Version != 0 || resp.Network != "eip155:84532" {
+		t.Errorf("Expected shared response to be unchanged, got %+v", resp)
+	}
+}
@@ -17,18 +17,28 @@ import (
 	"github.com/ethereum/go-ethereum/crypto"
 	"github.com/vorpalengineering/x402-go/types"
 	"github.com/vorpalengineering/x402-go/utils"
+	"github.com/vorpalengineering/x402-go/version"
 )
 
 type ResourceClient struct {
-	httpClient *http.Client
-	privateKey *ecdsa.PrivateKey
-	address    common.Address
+	httpClient        *http.Client
+	privateKey        *ecdsa.PrivateKey
+	address           common.Address
+	paymentHeaderName string
+	latency           *latencyTracker
+	validityMargin    time.Duration
+	privacy           *PrivacyOptions
+	requestHeaders    http.Header
+	solana            *solanaSigner
 }
 
 func NewResourceClient(privateKey *ecdsa.PrivateKey) *ResourceClient {
 	rc := &ResourceClient{
-		httpClient: &http.Client{},
-		privateKey: privateKey,
+		httpClient:        &http.Client{Transport: version.NewTransport("x402-go")},
+		privateKey:        privateKey,
+		paymentHeaderName: utils.DefaultPaymentHeaderName,
+		latency:           &latencyTracker{},
+		validityMargin:    defaultValidityMargin,
 	}
 
 	// Only derive address if we have a private key
@@ -39,6 +49,23 @@ func NewResourceClient(privateKey *ecdsa.PrivateKey) *ResourceClient {
 	return rc
 }
 
+// SetPaymentHeaderName sets the request header Pay() sends the payment payload in.
+// Use utils.GetPaymentHeaderName() to pick up the name advertised by a 402 response.
+// An empty name resets to the default PAYMENT-SIGNATURE header.
+func (rc *ResourceClient) SetPaymentHeaderName(name string) {
+	if name == "" {
+		name = utils.DefaultPaymentHeaderName
+	}
+	rc.paymentHeaderName = name
+}
+
+// SetRequestHeaders sets extra headers sent with every Check() and Pay()
+// request (e.g. API keys). They are applied after Content-Type, so a
+// Content-Type header here overrides the one passed to those methods.
+func (rc *ResourceClient) SetRequestHeaders(headers http.Header) {
+	rc.requestHeaders = headers.Clone()
+}
+
 func (rc *ResourceClient) Browse(baseURL string) (*types.DiscoveryResponse, error) {
 	// Build discovery URL
 	discoveryURL := strings.TrimSuffix(baseURL, "/") + "/.well-known/x402"
@@ -78,6 +105,7 @@ func (rc *ResourceClient) Check(
 	if contentType != "" {
 		req.Header.Set("Content-Type", contentType)
 	}
+	rc.applyRequestHeaders(req)
 
 	resp, err := rc.httpClient.Do(req)
 	if err != nil {
@@ -160,36 +188,63 @@ func (rc *ResourceClient) Pay(
 	if contentType != "" {
 		req.Header.Set("Content-Type", contentType)
 	}
-	req.Header.Set("PAYMENT-SIGNATURE", paymentHeader)
+	rc.applyRequestHeaders(req)
+	req.Header.Set(rc.paymentHeaderName, paymentHeader)
 
+	// Measure round trip to tune validity of future authorizations
+	start := time.Now()
 	resp, err := rc.httpClient.Do(req)
 	if err != nil {
 		return nil, fmt.Errorf("request with payment failed: %w", err)
 	}
+	rc.latency.record(time.Since(start))
 
 	return resp, nil
 }
 
+func (rc *ResourceClient) applyRequestHeaders(req *http.Request) {
+	for name, values := range rc.requestHeaders {
+		req.Header.Del(name)
+		for _, value := range values {
+			req.Header.Add(name, value)
+		}
+	}
+}
+
 // Payload generates a signed payment payload for the given requirements.
 // Returns the raw PaymentPayload struct. Use utils.EncodePaymentHeader() to get
-// the base64-encoded string for the PAYMENT-SIGNATURE header.
+// the base64-encoded string for the payment header.
 func (rc *ResourceClient) Payload(requirements *types.PaymentRequirements) (*types.PaymentPayload, error) {
-	// Check that we have a private key for payment generation
-	if rc.privateKey == nil {
-		return nil, fmt.Errorf("cannot generate payment: client was created without a private key")
-	}
-
 	// Validate scheme
-	if requirements.Scheme != "exact" {
-		return nil, fmt.Errorf("unsupported payment scheme: %s (only 'exact' is supported)", requirements.Scheme)
+	if requirements.Scheme != "exact" && requirements.Scheme != "upto" {
+		return nil, fmt.Errorf("unsupported payment scheme: %s (only 'exact' and 'upto' are supported)", requirements.Scheme)
 	}
 
 	// Parse amount
-	value, ok := new(big.Int).SetString(requirements.Amount, 10)
+	amount, ok := new(big.Int).SetString(requirements.Amount, 10)
 	if !ok {
 		return nil, fmt.Errorf("invalid amount: %s", requirements.Amount)
 	}
 
+	// Apply rounding and padding if enabled
+	value, err := rc.paymentValue(amount)
+	if err != nil {
+		return nil, err
+	}
+
+	// Solana payments are signed transactions
+	if utils.IsSolanaNetwork(requirements.Network) {
+		if requirements.Scheme != "exact" {
+			return nil, fmt.Errorf("unsupported payment scheme on %s: %s", requirements.Network, requirements.Scheme)
+		}
+		return rc.solanaPayload(requirements, value)
+	}
+
+	// Upto payments are Permit2 signatures
+	if requirements.Scheme == "upto" {
+		return rc.uptoPayload(requirements, value)
+	}
+
 	// Parse recipient address
 	toAddress := common.HexToAddress(requirements.PayTo)
 	if toAddress == (common.Address{}) {
@@ -208,14 +263,23 @@ func (rc *ResourceClient) Payload(requirements *types.PaymentRequirements) (*typ
 		return nil, fmt.Errorf("failed to get chain id: %s", err)
 	}
 
-	// Generate EIP-3009 authorization
-	auth, err := CreateEIP3009Authorization(
-		rc.privateKey,
-		rc.address,
+	// Pick the signing key (per-payment address if enabled)
+	privateKey, from, err := rc.payerKey(requirements)
+	if err != nil {
+		return nil, err
+	}
+
+	// Generate EIP-3009 authorization valid for the tuned window
+	now := time.Now()
+	auth, err := createEIP3009Authorization(
+		privateKey,
+		from,
 		toAddress,
 		value,
 		assetAddress,
 		chainID.Int64(),
+		big.NewInt(now.Add(-1*time.Hour).Unix()),
+		big.NewInt(now.Add(rc.validityWindow(requirements)).Unix()),
 	)
 	if err != nil {
 		return nil, fmt.Errorf("failed to create EIP-3009 authorization: %w", err)
@@ -241,6 +305,36 @@ func (rc *ResourceClient) Payload(requirements *types.PaymentRequirements) (*typ
 	return payload, nil
 }
 
+// BuildExactEVMPayload assembles an exact-scheme payment payload from an
+// authorization and an externally produced signature (e.g. from a hardware
+// wallet), skipping local signing. The signature must recover to auth.From.
+func BuildExactEVMPayload(
+	requirements *types.PaymentRequirements,
+	auth *types.ExactEVMSchemeAuthorization,
+	signature string,
+) (*types.PaymentPayload, error) {
+	// Validate signer
+	signer, err := utils.RecoverEIP3009Signer(auth, requirements, signature)
+	if err != nil {
+		return nil, fmt.Errorf("invalid signature: %w", err)
+	}
+	if signer != common.HexToAddress(auth.From) {
+		return nil, fmt.Errorf("signature mismatch: recovered %s, expected %s", signer.Hex(), common.HexToAddress(auth.From).Hex())
+	}
+
+	// Build payment payload
+	payload := &types.PaymentPayload{
+		X402Version: 2,
+		Accepted:    *requirements,
+		Payload: map[string]any{
+			"signature":     signature,
+			"authorization": *auth,
+		},
+	}
+
+	return payload, nil
+}
+
 // TODO: make this a generic function for all tokens
 func createDomainSeparator(verifyingContract common.Address, chainID *big.Int, name string, version string) common.Hash {
 	// EIP-712 Domain typeHash
@@ -292,6 +386,23 @@ func CreateEIP3009Authorization(
 	value *big.Int,
 	usdcContract common.Address,
 	chainID int64,
+) (*types.EIP3009Authorization, error) {
+	// Set validity period (valid from 1 hour ago to 1 hour from now)
+	validAfter := big.NewInt(time.Now().Add(-1 * time.Hour).Unix())
+	validBefore := big.NewInt(time.Now().Add(1 * time.Hour).Unix())
+
+	return createEIP3009Authorization(privateKey, from, to, value, usdcContract, chainID, validAfter, validBefore)
+}
+
+func createEIP3009Authorization(
+	privateKey *ecdsa.PrivateKey,
+	from common.Address,
+	to common.Address,
+	value *big.Int,
+	usdcContract common.Address,
+	chainID int64,
+	validAfter *big.Int,
+	validBefore *big.Int,
 ) (*types.EIP3009Authorization, error) {
 	// Generate nonce
 	nonce, err := generateNonce()
@@ -299,10 +410,6 @@ func CreateEIP3009Authorization(
 		return nil, err
 	}
 
-	// Set validity period (valid from 1 hour ago to 1 hour from now)
-	validAfter := big.NewInt(time.Now().Add(-1 * time.Hour).Unix())
-	validBefore := big.NewInt(time.Now().Add(1 * time.Hour).Unix())
-
 	// EIP-712 Domain Separator
 	domainSeparator := createDomainSeparator(usdcContract, big.NewInt(chainID), "USDC", "2")
 
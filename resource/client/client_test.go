@@ -0,0 +1,146 @@
+package client
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/vorpalengineering/x402-go/types"
+	"github.com/vorpalengineering/x402-go/utils"
+)
+
+// testKey is the first development account, 0xf39Fd6e51aad88F6F4ce6aB8827279cffFb92266
+const testKey = "ac0974bec39a17e36ba4a6b4d238ff944bacb478cbed5efcae784d7bf4f2ff80"
+
+var testRequirements = types.PaymentRequirements{
+	Scheme:            "exact",
+	Network:           "eip155:8453",
+	Amount:            "1000000",
+	PayTo:             "0x70997970C51812dc3A010C7d01b50e0d17dc79C8",
+	Asset:             "0x833589fCD6eDb6E08f4c7C32D4f71b54bdA02913",
+	MaxTimeoutSeconds: 60,
+	Extra:             map[string]any{"name": "USD Coin", "version": "2"},
+}
+
+func TestBuildExactEVMPayload(t *testing.T) {
+	key, _ := crypto.HexToECDSA(testKey)
+	auth := types.ExactEVMSchemeAuthorization{
+		From:        crypto.PubkeyToAddress(key.PublicKey).Hex(),
+		To:          testRequirements.PayTo,
+		Value:       testRequirements.Amount,
+		ValidAfter:  0,
+		ValidBefore: 1900000000,
+		Nonce:       "0x" + strings.Repeat("01", 32),
+	}
+
+	// Signed as an external wallet would
+	signature, err := utils.SignEIP3009(&auth, key, testRequirements.Asset, "USD Coin", "2", 8453)
+	if err != nil {
+		t.Fatalf("Failed to sign: %v", err)
+	}
+
+	otherPayer := auth
+	otherPayer.From = "0x3C44CdDdB6a900fa2b585dd299e03d12FA4293BC"
+
+	tests := []struct {
+		name      string
+		auth      types.ExactEVMSchemeAuthorization
+		signature string
+		err       string
+	}{
+		{"matching signature", auth, signature, ""},
+		{"signature without prefix", auth, strings.TrimPrefix(signature, "0x"), ""},
+		{"wrong payer", otherPayer, signature, "signature mismatch"},
+		{"malformed signature", auth, "0xnothex", "invalid signature"},
+		{"truncated signature", auth, signature[:66], "invalid signature"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			payload, err := BuildExactEVMPayload(&testRequirements, &tt.auth, tt.signature)
+			if tt.err != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.err) {
+					t.Fatalf("Expected error containing %q, got %v", tt.err, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Expected payload, got %v", err)
+			}
+
+			// The payload carries the signature and authorization as given
+			extracted, err := utils.ExtractExactAuthorization(payload)
+			if err != nil {
+				t.Fatalf("Failed to extract authorization: %v", err)
+			}
+			if *extracted != tt.auth {
+				t.Errorf("Expected authorization %+v, got %+v", tt.auth, *extracted)
+			}
+			if payload.Payload["signature"] != tt.signature {
+				t.Errorf("Expected signature %s, got %v", tt.signature, payload.Payload["signature"])
+			}
+		})
+	}
+}
+
+func TestSetPaymentHeaderName(t *testing.T) {
+	// The server asks for payment in X-PAYMENT and records the header each
+	// payment arrives in
+	var received []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		for _, name := range []string{utils.DefaultPaymentHeaderName, "X-PAYMENT"} {
+			if r.Header.Get(name) != "" {
+				received = append(received, name)
+				w.WriteHeader(http.StatusOK)
+				return
+			}
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusPaymentRequired)
+		json.NewEncoder(w).Encode(types.PaymentRequired{
+			X402Version: 2,
+			Accepts:     []types.PaymentRequirements{testRequirements},
+			Extensions: map[string]types.Extension{
+				utils.PaymentHeaderExtension: {Info: map[string]any{"name": "X-PAYMENT"}},
+			},
+		})
+	}))
+	defer server.Close()
+
+	key, _ := crypto.HexToECDSA(testKey)
+	rc := NewResourceClient(key)
+	pay := func() {
+		t.Helper()
+		resp, err := rc.Pay("GET", server.URL, "", nil, &testRequirements)
+		if err != nil {
+			t.Fatalf("Failed to pay: %v", err)
+		}
+		resp.Body.Close()
+	}
+
+	// Payments go in the default header until told otherwise
+	pay()
+
+	// The name advertised by the server is picked up from the 402
+	_, paymentRequired, err := rc.Check("GET", server.URL, "", nil)
+	if err != nil || paymentRequired == nil {
+		t.Fatalf("Expected 402, got %v", err)
+	}
+	rc.SetPaymentHeaderName(utils.GetPaymentHeaderName(paymentRequired))
+	pay()
+
+	// An explicit override wins, and an empty name resets to the default
+	rc.SetPaymentHeaderName(utils.DefaultPaymentHeaderName)
+	pay()
+	rc.SetPaymentHeaderName("X-PAYMENT")
+	rc.SetPaymentHeaderName("")
+	pay()
+
+	expected := []string{utils.DefaultPaymentHeaderName, "X-PAYMENT", utils.DefaultPaymentHeaderName, utils.DefaultPaymentHeaderName}
+	if strings.Join(received, ",") != strings.Join(expected, ",") {
+		t.Errorf("Expected payments in %v, got %v", expected, received)
+	}
+}
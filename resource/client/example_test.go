@@ -0,0 +1,104 @@
+package client_test
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"net/http/httptest"
+
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/vorpalengineering/x402-go/resource/client"
+	"github.com/vorpalengineering/x402-go/types"
+	"github.com/vorpalengineering/x402-go/utils"
+)
+
+var exampleRequirements = types.PaymentRequirements{
+	Scheme:            "exact",
+	Network:           "eip155:8453",
+	Amount:            "1000000",
+	PayTo:             "0x70997970C51812dc3A010C7d01b50e0d17dc79C8",
+	Asset:             "0x833589fCD6eDb6E08f4c7C32D4f71b54bdA02913",
+	MaxTimeoutSeconds: 60,
+	Extra:             map[string]any{"name": "USD Coin", "version": "2"},
+}
+
+func ExampleResourceClient_Pay() {
+	// Stand-in resource server that charges for every request
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		payload, err := utils.DecodePaymentHeader(r.Header.Get("PAYMENT-SIGNATURE"))
+		if err != nil {
+			w.WriteHeader(http.StatusPaymentRequired)
+			json.NewEncoder(w).Encode(types.PaymentRequired{
+				X402Version: 2,
+				Accepts:     []types.PaymentRequirements{exampleRequirements},
+			})
+			return
+		}
+		auth, _ := utils.ExtractExactAuthorization(payload)
+		fmt.Fprintf(w, "paid %s", auth.Value)
+	}))
+	defer server.Close()
+
+	privateKey, err := crypto.HexToECDSA("ac0974bec39a17e36ba4a6b4d238ff944bacb478cbed5efcae784d7bf4f2ff80")
+	if err != nil {
+		log.Fatal(err)
+	}
+	rc := client.NewResourceClient(privateKey)
+
+	// Fetch the requirements, then pay with the first option
+	requirements, err := rc.Requirements("GET", server.URL+"/data", "", nil, 0)
+	if err != nil {
+		log.Fatal(err)
+	}
+	resp, err := rc.Pay("GET", server.URL+"/data", "", nil, requirements)
+	if err != nil {
+		log.Fatal(err)
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		log.Fatal(err)
+	}
+	fmt.Println(resp.StatusCode, string(body))
+
+	// Output:
+	// 200 paid 1000000
+}
+
+func ExampleBuildExactEVMPayload() {
+	privateKey, err := crypto.HexToECDSA("ac0974bec39a17e36ba4a6b4d238ff944bacb478cbed5efcae784d7bf4f2ff80")
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	auth := &types.ExactEVMSchemeAuthorization{
+		From:        crypto.PubkeyToAddress(privateKey.PublicKey).Hex(),
+		To:          exampleRequirements.PayTo,
+		Value:       exampleRequirements.Amount,
+		ValidAfter:  1700000000,
+		ValidBefore: 1700003600,
+		Nonce:       "0x0000000000000000000000000000000000000000000000000000000000000001",
+	}
+
+	// Sign elsewhere (hardware wallet, KMS, ...); a local key stands in here
+	signature, err := utils.SignEIP3009(auth, privateKey, exampleRequirements.Asset, "USD Coin", "2", 8453)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	payload, err := client.BuildExactEVMPayload(&exampleRequirements, auth, signature)
+	if err != nil {
+		log.Fatal(err)
+	}
+	header, err := utils.EncodePaymentHeader(payload)
+	if err != nil {
+		log.Fatal(err)
+	}
+	fmt.Println(payload.Accepted.Network, header != "")
+
+	// Output:
+	// eip155:8453 true
+}
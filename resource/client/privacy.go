@@ -0,0 +1,97 @@
+package client
+
+import (
+	"crypto/ecdsa"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"math/big"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/vorpalengineering/x402-go/types"
+	"github.com/vorpalengineering/x402-go/wallet"
+)
+
+// PrivacyOptions reduce how easily an agent's payments can be linked across
+// resources. Every option costs something: padding and rounding overpay, and
+// fresh payer addresses each need funding.
+type PrivacyOptions struct {
+	// RoundTo rounds the paid amount up to the next multiple (smallest unit),
+	// so payments for differently priced resources look alike.
+	RoundTo *big.Int
+
+	// MaxPadding overpays by a random amount between 0 and MaxPadding
+	// (smallest unit), applied after rounding. The exact scheme accepts any
+	// value at or above the required amount.
+	MaxPadding *big.Int
+
+	// Wallet derives the payer key for each payment instead of using the
+	// client's private key.
+	Wallet *wallet.HDWallet
+
+	// PayerLabel picks the wallet label for a payment. Payments with the same
+	// label share an address. Nil gives every payment a fresh address.
+	PayerLabel func(requirements *types.PaymentRequirements) string
+}
+
+// SetPrivacyOptions enables amount padding/rounding and per-payment payer
+// addresses for Payload() and Pay(). Nil disables them.
+func (rc *ResourceClient) SetPrivacyOptions(opts *PrivacyOptions) {
+	rc.privacy = opts
+}
+
+// paymentValue returns the amount to authorize for the required amount
+func (rc *ResourceClient) paymentValue(required *big.Int) (*big.Int, error) {
+	value := new(big.Int).Set(required)
+	if rc.privacy == nil {
+		return value, nil
+	}
+
+	// Round up to the next multiple
+	if roundTo := rc.privacy.RoundTo; roundTo != nil && roundTo.Sign() > 0 {
+		remainder := new(big.Int).Mod(value, roundTo)
+		if remainder.Sign() > 0 {
+			value.Add(value, new(big.Int).Sub(roundTo, remainder))
+		}
+	}
+
+	// Add random padding in [0, MaxPadding]
+	if maxPadding := rc.privacy.MaxPadding; maxPadding != nil && maxPadding.Sign() > 0 {
+		padding, err := rand.Int(rand.Reader, new(big.Int).Add(maxPadding, big.NewInt(1)))
+		if err != nil {
+			return nil, fmt.Errorf("failed to generate padding: %w", err)
+		}
+		value.Add(value, padding)
+	}
+
+	return value, nil
+}
+
+// payerKey returns the key and address to sign a payment with
+func (rc *ResourceClient) payerKey(requirements *types.PaymentRequirements) (*ecdsa.PrivateKey, common.Address, error) {
+	if rc.privacy == nil || rc.privacy.Wallet == nil {
+		if rc.privateKey == nil {
+			return nil, common.Address{}, fmt.Errorf("cannot generate payment: client was created without a private key")
+		}
+		return rc.privateKey, rc.address, nil
+	}
+
+	// Pick label, fresh unless the caller groups payments
+	var label string
+	if rc.privacy.PayerLabel != nil {
+		label = rc.privacy.PayerLabel(requirements)
+	} else {
+		var buf [16]byte
+		if _, err := rand.Read(buf[:]); err != nil {
+			return nil, common.Address{}, fmt.Errorf("failed to generate payer label: %w", err)
+		}
+		label = "payment-" + hex.EncodeToString(buf[:])
+	}
+
+	key, err := rc.privacy.Wallet.KeyFor(label)
+	if err != nil {
+		return nil, common.Address{}, fmt.Errorf("failed to derive payer key: %w", err)
+	}
+	return key, crypto.PubkeyToAddress(key.PublicKey), nil
+}
@@ -0,0 +1,105 @@
+package client
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/vorpalengineering/x402-go/types"
+	"github.com/vorpalengineering/x402-go/wallet"
+)
+
+func TestPaymentValueRounding(t *testing.T) {
+	tests := []struct {
+		name     string
+		required int64
+		roundTo  int64
+		expected int64
+	}{
+		{"rounds up", 1001, 1000, 2000},
+		{"exact multiple unchanged", 3000, 1000, 3000},
+		{"below one unit", 1, 1000, 1000},
+		{"zero disables rounding", 1001, 0, 1001},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rc := NewResourceClient(nil)
+			rc.SetPrivacyOptions(&PrivacyOptions{RoundTo: big.NewInt(tt.roundTo)})
+
+			value, err := rc.paymentValue(big.NewInt(tt.required))
+			if err != nil {
+				t.Fatalf("Failed to compute value: %v", err)
+			}
+			if value.Int64() != tt.expected {
+				t.Errorf("Expected %d, got %s", tt.expected, value)
+			}
+		})
+	}
+}
+
+func TestPaymentValuePadding(t *testing.T) {
+	rc := NewResourceClient(nil)
+	rc.SetPrivacyOptions(&PrivacyOptions{RoundTo: big.NewInt(100), MaxPadding: big.NewInt(5)})
+
+	// Padding is added after rounding 150 up to 200
+	seen := map[int64]bool{}
+	for range 500 {
+		value, err := rc.paymentValue(big.NewInt(150))
+		if err != nil {
+			t.Fatalf("Failed to compute value: %v", err)
+		}
+		if value.Int64() < 200 || value.Int64() > 205 {
+			t.Fatalf("Expected value in [200, 205], got %s", value)
+		}
+		seen[value.Int64()] = true
+	}
+	if len(seen) < 2 {
+		t.Errorf("Expected padding to vary, got %v", seen)
+	}
+
+	// The required amount is not modified
+	required := big.NewInt(150)
+	rc.paymentValue(required)
+	if required.Int64() != 150 {
+		t.Errorf("Expected required amount to be untouched, got %s", required)
+	}
+}
+
+func TestPayerKey(t *testing.T) {
+	key, _ := crypto.HexToECDSA(testKey)
+	hd, err := wallet.NewHDWallet("test test test test test test test test test test test junk", "", "", nil)
+	if err != nil {
+		t.Fatalf("Failed to create wallet: %v", err)
+	}
+
+	// Without a wallet the client's own key pays
+	rc := NewResourceClient(key)
+	if _, address, err := rc.payerKey(&testRequirements); err != nil || address != crypto.PubkeyToAddress(key.PublicKey) {
+		t.Errorf("Expected client address, got %s (%v)", address.Hex(), err)
+	}
+
+	// Nil PayerLabel gives every payment its own address
+	rc.SetPrivacyOptions(&PrivacyOptions{Wallet: hd})
+	_, first, err := rc.payerKey(&testRequirements)
+	if err != nil {
+		t.Fatalf("Failed to derive payer: %v", err)
+	}
+	_, second, _ := rc.payerKey(&testRequirements)
+	if first == second {
+		t.Errorf("Expected fresh address per payment, got %s twice", first.Hex())
+	}
+
+	// A PayerLabel groups payments under one address
+	rc.SetPrivacyOptions(&PrivacyOptions{
+		Wallet: hd,
+		PayerLabel: func(requirements *types.PaymentRequirements) string {
+			return requirements.PayTo
+		},
+	})
+	_, first, _ = rc.payerKey(&testRequirements)
+	_, second, _ = rc.payerKey(&testRequirements)
+	if first != second {
+		t.Errorf("Expected same address for same label, got %s and %s", first.Hex(), second.Hex())
+	}
+}
@@ -0,0 +1,94 @@
+package client
+
+import (
+	"context"
+	"crypto/ed25519"
+	"encoding/base64"
+	"fmt"
+	"math/big"
+	"time"
+
+	"github.com/vorpalengineering/x402-go/svm"
+	"github.com/vorpalengineering/x402-go/types"
+)
+
+// solanaRPCTimeout bounds the RPC calls made to build a Solana payment
+const solanaRPCTimeout = 10 * time.Second
+
+// solanaSigner pays on solana:* networks
+type solanaSigner struct {
+	key    ed25519.PrivateKey
+	client *svm.Client
+}
+
+// SetSolanaSigner sets the key paying on solana:* networks and the RPC
+// endpoint used to look up the mint and a recent blockhash
+func (rc *ResourceClient) SetSolanaSigner(key ed25519.PrivateKey, rpcURL string) {
+	rc.solana = &solanaSigner{key: key, client: svm.NewClient(rpcURL)}
+}
+
+// solanaPayload builds an exact scheme payment as a TransferChecked
+// transaction, signed by the payer and left for the facilitator's fee payer
+// (extra.feePayer) to complete
+func (rc *ResourceClient) solanaPayload(requirements *types.PaymentRequirements, value *big.Int) (*types.PaymentPayload, error) {
+	if rc.solana == nil {
+		return nil, fmt.Errorf("solana signer not set")
+	}
+	if !value.IsUint64() {
+		return nil, fmt.Errorf("amount out of range: %s", value)
+	}
+
+	// Parse accounts
+	feePayerStr, _ := requirements.Extra["feePayer"].(string)
+	feePayer, err := svm.PublicKeyFromBase58(feePayerStr)
+	if err != nil {
+		return nil, fmt.Errorf("invalid fee payer: %w", err)
+	}
+	payTo, err := svm.PublicKeyFromBase58(requirements.PayTo)
+	if err != nil {
+		return nil, fmt.Errorf("invalid recipient address: %w", err)
+	}
+	mint, err := svm.PublicKeyFromBase58(requirements.Asset)
+	if err != nil {
+		return nil, fmt.Errorf("invalid asset address: %w", err)
+	}
+
+	// Look up the mint's token program and decimals, and a recent blockhash
+	ctx, cancel := context.WithTimeout(context.Background(), solanaRPCTimeout)
+	defer cancel()
+	tokenProgramID, decimals, err := rc.solana.client.GetMint(ctx, mint)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get mint: %w", err)
+	}
+	blockhash, err := rc.solana.client.GetLatestBlockhash(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get blockhash: %w", err)
+	}
+
+	// Build and sign the transfer
+	tx, err := svm.NewExactPaymentTransaction(svm.ExactPayment{
+		FeePayer:        feePayer,
+		Payer:           svm.PublicKeyOf(rc.solana.key),
+		PayTo:           payTo,
+		Mint:            mint,
+		TokenProgramID:  tokenProgramID,
+		Amount:          value.Uint64(),
+		Decimals:        decimals,
+		RecentBlockhash: blockhash,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to build transaction: %w", err)
+	}
+	if err := tx.Sign(rc.solana.key); err != nil {
+		return nil, fmt.Errorf("failed to sign transaction: %w", err)
+	}
+
+	// Build payment payload
+	return &types.PaymentPayload{
+		X402Version: 2,
+		Accepted:    *requirements,
+		Payload: map[string]any{
+			"transaction": base64.StdEncoding.EncodeToString(tx.Serialize()),
+		},
+	}, nil
+}
@@ -0,0 +1,63 @@
+package client
+
+import (
+	"crypto/rand"
+	"fmt"
+	"math/big"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/vorpalengineering/x402-go/types"
+	"github.com/vorpalengineering/x402-go/utils"
+)
+
+// uptoPayload builds an upto scheme payment as a Permit2 PermitTransferFrom
+// of up to value, spendable by the facilitator's signer (extra.spender). The
+// payer must have approved Permit2 for the asset beforehand.
+func (rc *ResourceClient) uptoPayload(requirements *types.PaymentRequirements, value *big.Int) (*types.PaymentPayload, error) {
+	// Parse spender address
+	spender, _ := requirements.Extra["spender"].(string)
+	if !common.IsHexAddress(spender) {
+		return nil, fmt.Errorf("invalid spender address: %q", spender)
+	}
+
+	// Parse asset (token contract) address
+	if common.HexToAddress(requirements.Asset) == (common.Address{}) {
+		return nil, fmt.Errorf("invalid asset address: %s", requirements.Asset)
+	}
+
+	// Pick the signing key (per-payment address if enabled)
+	privateKey, from, err := rc.payerKey(requirements)
+	if err != nil {
+		return nil, err
+	}
+
+	// Permit2 nonces are unordered, a random one is never reused in practice
+	nonce, err := rand.Int(rand.Reader, new(big.Int).Lsh(big.NewInt(1), 256))
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate nonce: %w", err)
+	}
+
+	// Sign the permit valid for the tuned window
+	auth := types.UptoEVMSchemeAuthorization{
+		From:     from.Hex(),
+		Spender:  common.HexToAddress(spender).Hex(),
+		Value:    value.String(),
+		Nonce:    nonce.String(),
+		Deadline: time.Now().Add(rc.validityWindow(requirements)).Unix(),
+	}
+	signature, err := utils.SignPermit2(&auth, privateKey, requirements)
+	if err != nil {
+		return nil, fmt.Errorf("failed to sign permit: %w", err)
+	}
+
+	// Build payment payload
+	return &types.PaymentPayload{
+		X402Version: 2,
+		Accepted:    *requirements,
+		Payload: map[string]any{
+			"signature":     signature,
+			"authorization": auth,
+		},
+	}, nil
+}
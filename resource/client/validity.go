@@ -0,0 +1,74 @@
+package client
+
+import (
+	"sync"
+	"time"
+
+	"github.com/vorpalengineering/x402-go/types"
+)
+
+const (
+	// defaultValidityWindow is used before any latency has been measured
+	defaultValidityWindow = 10 * time.Minute
+
+	// defaultValidityMargin is added on top of the measured latency
+	defaultValidityMargin = 30 * time.Second
+
+	// latencyFactor leaves room for a paid request that is slower than average
+	latencyFactor = 3
+
+	// latencySmoothing is the weight of a new sample in the moving average
+	latencySmoothing = 0.2
+)
+
+// latencyTracker keeps an exponential moving average of paid request latency
+// (verify + handler + settlement as seen by the client)
+type latencyTracker struct {
+	mu      sync.Mutex
+	average time.Duration
+	samples int
+}
+
+func (t *latencyTracker) record(d time.Duration) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if t.samples == 0 {
+		t.average = d
+	} else {
+		t.average = time.Duration(latencySmoothing*float64(d) + (1-latencySmoothing)*float64(t.average))
+	}
+	t.samples++
+}
+
+func (t *latencyTracker) get() (time.Duration, bool) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.average, t.samples > 0
+}
+
+// SetValidityMargin sets the safety margin added to the measured latency
+// when computing validBefore for new authorizations.
+func (rc *ResourceClient) SetValidityMargin(margin time.Duration) {
+	rc.validityMargin = margin
+}
+
+// validityWindow returns how long a new authorization should remain valid.
+// Once latency has been measured the window is a multiple of it plus the
+// safety margin, otherwise a fixed default is used. Either way the window
+// never exceeds the requirements' MaxTimeoutSeconds.
+func (rc *ResourceClient) validityWindow(requirements *types.PaymentRequirements) time.Duration {
+	window := defaultValidityWindow
+	if average, ok := rc.latency.get(); ok {
+		window = latencyFactor*average + rc.validityMargin
+	}
+
+	if requirements.MaxTimeoutSeconds > 0 {
+		maxWindow := time.Duration(requirements.MaxTimeoutSeconds) * time.Second
+		if window > maxWindow {
+			window = maxWindow
+		}
+	}
+
+	return window
+}
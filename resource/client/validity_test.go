@@ -0,0 +1,59 @@
+package client
+
+import (
+	"testing"
+	"time"
+
+	"github.com/vorpalengineering/x402-go/types"
+)
+
+func TestValidityWindow(t *testing.T) {
+	rc := NewResourceClient(nil)
+	unbounded := &types.PaymentRequirements{}
+
+	// Before any paid request the default applies
+	if window := rc.validityWindow(unbounded); window != defaultValidityWindow {
+		t.Errorf("Expected default window %s, got %s", defaultValidityWindow, window)
+	}
+
+	// First sample is taken as the average
+	rc.latency.record(2 * time.Second)
+	if window := rc.validityWindow(unbounded); window != 3*2*time.Second+defaultValidityMargin {
+		t.Errorf("Expected 36s, got %s", window)
+	}
+
+	// Later samples move the average by the smoothing weight: 0.2*7s + 0.8*2s = 3s
+	rc.latency.record(7 * time.Second)
+	rc.SetValidityMargin(10 * time.Second)
+	if window := rc.validityWindow(unbounded); window != 3*3*time.Second+10*time.Second {
+		t.Errorf("Expected 19s, got %s", window)
+	}
+}
+
+func TestValidityWindowCappedByMaxTimeout(t *testing.T) {
+	tests := []struct {
+		name              string
+		latency           time.Duration
+		maxTimeoutSeconds int
+		expected          time.Duration
+	}{
+		{"default capped", 0, 60, 60 * time.Second},
+		{"measured capped", 20 * time.Second, 60, 60 * time.Second},
+		{"measured below cap", 5 * time.Second, 60, 45 * time.Second},
+		{"no cap", 20 * time.Second, 0, 90 * time.Second},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rc := NewResourceClient(nil)
+			if tt.latency > 0 {
+				rc.latency.record(tt.latency)
+			}
+
+			window := rc.validityWindow(&types.PaymentRequirements{MaxTimeoutSeconds: tt.maxTimeoutSeconds})
+			if window != tt.expected {
+				t.Errorf("Expected %s, got %s", tt.expected, window)
+			}
+		})
+	}
+}
@@ -0,0 +1,154 @@
+package middleware
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/vorpalengineering/x402-go/types"
+)
+
+func TestAccepts(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	// Record the requirements each payment is verified against
+	var mu sync.Mutex
+	var verified []types.PaymentRequirements
+	facilitator := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		switch r.URL.Path {
+		case "/verify":
+			var req types.VerifyRequest
+			json.NewDecoder(r.Body).Decode(&req)
+			mu.Lock()
+			verified = append(verified, req.PaymentRequirements)
+			mu.Unlock()
+			json.NewEncoder(w).Encode(types.VerifyResponse{IsValid: true})
+		case "/settle":
+			json.NewEncoder(w).Encode(types.SettleResponse{Success: true, Transaction: "0xabc"})
+		}
+	}))
+	defer facilitator.Close()
+
+	baseSepoliaUSDC := types.PaymentRequirements{
+		Scheme:  "exact",
+		Network: "eip155:84532",
+		Amount:  "1000",
+		Asset:   "0x036CbD53842c5426634e7929541eC2318f3dCF7e",
+		PayTo:   "0x3C44CdDdB6a900fa2b585dd299e03d12FA4293BC",
+		Extra:   map[string]any{"name": "USDC", "version": "2"},
+	}
+	baseUSDC := types.PaymentRequirements{
+		Scheme:  "exact",
+		Network: "eip155:8453",
+		Amount:  "1000",
+		Asset:   "0x833589fCD6eDb6E08f4c7C32D4f71b54bdA02913",
+		PayTo:   "0x3C44CdDdB6a900fa2b585dd299e03d12FA4293BC",
+		Extra:   map[string]any{"name": "USD Coin", "version": "2"},
+	}
+	premium := baseUSDC
+	premium.Amount = "5000"
+
+	cfg := &MiddlewareConfig{
+		FacilitatorURL: facilitator.URL,
+		DefaultAccepts: []types.PaymentRequirements{baseSepoliaUSDC, baseUSDC},
+		RouteAccepts: map[string][]types.PaymentRequirements{
+			"/premium": {premium},
+		},
+		ProtectedPaths: []string{"/*"},
+	}
+	if err := cfg.Validate(); err != nil {
+		t.Fatalf("Expected valid config, got %v", err)
+	}
+	router := gin.New()
+	router.Use(NewX402Middleware(cfg).Handler())
+	router.GET("/:name", func(ctx *gin.Context) {
+		ctx.String(http.StatusOK, "data")
+	})
+
+	// The 402 lists every option of the route
+	t.Run("payment required", func(t *testing.T) {
+		tests := []struct {
+			path     string
+			expected []types.PaymentRequirements
+		}{
+			{"/data", []types.PaymentRequirements{baseSepoliaUSDC, baseUSDC}},
+			{"/premium", []types.PaymentRequirements{premium}},
+		}
+		for _, tt := range tests {
+			recorder := httptest.NewRecorder()
+			router.ServeHTTP(recorder, httptest.NewRequest("GET", tt.path, nil))
+			if recorder.Code != http.StatusPaymentRequired {
+				t.Fatalf("Expected status 402 for %s, got %d", tt.path, recorder.Code)
+			}
+			var response types.PaymentRequired
+			json.Unmarshal(recorder.Body.Bytes(), &response)
+			if len(response.Accepts) != len(tt.expected) {
+				t.Fatalf("Expected %d options for %s, got %+v", len(tt.expected), tt.path, response.Accepts)
+			}
+			for i, expected := range tt.expected {
+				if got := response.Accepts[i]; got.Network != expected.Network || got.Asset != expected.Asset || got.Amount != expected.Amount {
+					t.Errorf("Expected option %d for %s to be %+v, got %+v", i, tt.path, expected, got)
+				}
+			}
+		}
+	})
+
+	// Payments are verified against the option the client chose, and refused
+	// when they match none
+	tests := []struct {
+		name     string
+		path     string
+		chosen   types.PaymentRequirements
+		status   int
+		verified *types.PaymentRequirements
+	}{
+		{"first default option", "/data", baseSepoliaUSDC, http.StatusOK, &baseSepoliaUSDC},
+		{"second default option", "/data", baseUSDC, http.StatusOK, &baseUSDC},
+		{"route option", "/premium", premium, http.StatusOK, &premium},
+		{"default option on route", "/premium", baseUSDC, http.StatusPaymentRequired, nil},
+		{"unknown network", "/data", func() types.PaymentRequirements {
+			other := baseUSDC
+			other.Network = "eip155:1"
+			return other
+		}(), http.StatusPaymentRequired, nil},
+		{"asset of another option", "/data", func() types.PaymentRequirements {
+			other := baseUSDC
+			other.Asset = baseSepoliaUSDC.Asset
+			return other
+		}(), http.StatusPaymentRequired, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mu.Lock()
+			verified = nil
+			mu.Unlock()
+
+			req := httptest.NewRequest("GET", tt.path, nil)
+			req.Header.Set("PAYMENT-SIGNATURE", paidHeader(tt.chosen))
+			recorder := httptest.NewRecorder()
+			router.ServeHTTP(recorder, req)
+
+			if recorder.Code != tt.status {
+				t.Fatalf("Expected status %d, got %d: %s", tt.status, recorder.Code, recorder.Body.String())
+			}
+			mu.Lock()
+			defer mu.Unlock()
+			if tt.verified == nil {
+				if len(verified) != 0 {
+					t.Errorf("Expected payment to be refused before verification, got %+v", verified)
+				}
+				return
+			}
+			if len(verified) != 1 {
+				t.Fatalf("Expected 1 verification, got %d", len(verified))
+			}
+			if got := verified[0]; got.Network != tt.verified.Network || got.Asset != tt.verified.Asset || got.Amount != tt.verified.Amount {
+				t.Errorf("Expected verification against %+v, got %+v", *tt.verified, got)
+			}
+		})
+	}
+}
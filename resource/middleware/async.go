@@ -0,0 +1,253 @@
+package middleware
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/vorpalengineering/x402-go/types"
+)
+
+// Async settlement defaults
+const (
+	DefaultAsyncWorkers      = 4
+	DefaultAsyncQueueSize    = 1000
+	DefaultAsyncMaxAttempts  = 3
+	DefaultAsyncRetryBackoff = time.Second
+)
+
+// AsyncSettlementConfig tunes the background queue of SettleAsync routes
+type AsyncSettlementConfig struct {
+	// Workers is the number of concurrent settle calls. 0 uses
+	// DefaultAsyncWorkers.
+	Workers int `json:"workers,omitempty" toml:"workers"`
+
+	// QueueSize is the number of payments waiting to settle. Requests that
+	// find the queue full settle before their handler runs instead. 0 uses
+	// DefaultAsyncQueueSize.
+	QueueSize int `json:"queueSize,omitempty" toml:"queue_size"`
+
+	// MaxAttempts is how often a settlement is tried when the facilitator is
+	// unreachable or busy. 0 uses DefaultAsyncMaxAttempts.
+	MaxAttempts int `json:"maxAttempts,omitempty" toml:"max_attempts"`
+
+	// RetryBackoff is the wait before the first retry, doubled for each
+	// further one. 0 uses DefaultAsyncRetryBackoff.
+	RetryBackoff time.Duration `json:"retryBackoff,omitempty" toml:"retry_backoff"`
+}
+
+func (c *AsyncSettlementConfig) validate() error {
+	if c.Workers < 0 || c.QueueSize < 0 || c.MaxAttempts < 0 || c.RetryBackoff < 0 {
+		return errors.New("workers, queue size, max attempts and retry backoff cannot be negative")
+	}
+	return nil
+}
+
+// asyncSettlement is a served payment waiting to settle
+type asyncSettlement struct {
+	// id tracks the settlement, so shutdown waits for it or persists it
+	id      uint64
+	payment *Payment
+	request *types.SettleRequest
+	record  UsageRecord
+	logger  *slog.Logger
+}
+
+// asyncQueue holds payments of SettleAsync routes for background workers.
+// Requests reserve a slot before their handler runs, so the queue never
+// fills up after a response was sent.
+type asyncQueue struct {
+	cfg       AsyncSettlementConfig
+	slots     chan struct{}
+	queue     chan asyncSettlement
+	stop      chan struct{}
+	closeOnce sync.Once
+}
+
+func newAsyncQueue(cfg AsyncSettlementConfig) *asyncQueue {
+	if cfg.Workers == 0 {
+		cfg.Workers = DefaultAsyncWorkers
+	}
+	if cfg.QueueSize == 0 {
+		cfg.QueueSize = DefaultAsyncQueueSize
+	}
+	if cfg.MaxAttempts == 0 {
+		cfg.MaxAttempts = DefaultAsyncMaxAttempts
+	}
+	if cfg.RetryBackoff == 0 {
+		cfg.RetryBackoff = DefaultAsyncRetryBackoff
+	}
+	return &asyncQueue{
+		cfg:   cfg,
+		slots: make(chan struct{}, cfg.QueueSize),
+		queue: make(chan asyncSettlement, cfg.QueueSize),
+		stop:  make(chan struct{}),
+	}
+}
+
+// reserve takes a queue slot, returning false if the queue is full
+func (q *asyncQueue) reserve() bool {
+	if q == nil {
+		return false
+	}
+	select {
+	case q.slots <- struct{}{}:
+		return true
+	default:
+		return false
+	}
+}
+
+// unreserve gives back a slot taken by reserve
+func (q *asyncQueue) unreserve() {
+	<-q.slots
+}
+
+// close stops the workers. Settlements still queued are left to Shutdown.
+func (q *asyncQueue) close() {
+	q.closeOnce.Do(func() { close(q.stop) })
+}
+
+// usesAsyncSettlement reports whether any route settles asynchronously
+func usesAsyncSettlement(cfg *MiddlewareConfig) bool {
+	for _, order := range cfg.RouteSettlement {
+		if order == SettleAsync {
+			return true
+		}
+	}
+	return false
+}
+
+// settleAsync runs the handler on a verified payment and queues its
+// settlement once the handler has succeeded. The caller reserved a slot.
+func (m *X402Middleware) settleAsync(ctx *gin.Context, logger *slog.Logger, payment *Payment) {
+	// Track the settlement from now, so shutdown waits for it
+	id, ok := m.settlements.start(PendingSettlement{
+		Route:    payment.Route,
+		QueuedAt: time.Now().Unix(),
+		Request:  *settleRequest(payment, ""),
+	})
+	if !ok {
+		m.async.unreserve()
+		m.releasePayment(ctx.Request.Context(), payment)
+		m.refuse(ctx, &PaymentError{Status: http.StatusServiceUnavailable, Message: ErrShuttingDown.Error()})
+		return
+	}
+
+	m.next(ctx, payment.Route)
+
+	// Unsettled payments can be presented again
+	if status := ctx.Writer.Status(); status < 200 || status >= 300 {
+		m.settlements.finish(id)
+		m.async.unreserve()
+		m.releasePayment(ctx.Request.Context(), payment)
+		return
+	}
+
+	settleReq := settleRequest(payment, ctx.GetString(SettleAmountKey))
+	m.settlements.update(id, *settleReq)
+	item := asyncSettlement{
+		id:      id,
+		payment: payment,
+		request: settleReq,
+		logger:  logger,
+	}
+	if len(m.exporters) > 0 {
+		item.record = newUsageRecord(ctx, payment.Requirements, payment.exact, &types.SettleResponse{})
+	}
+	m.async.queue <- item
+	logger.Info("payment queued for settlement")
+}
+
+// runAsyncSettlements settles queued payments until the queue is closed
+func (m *X402Middleware) runAsyncSettlements() {
+	for {
+		select {
+		case <-m.async.stop:
+			return
+		case item := <-m.async.queue:
+			m.settleQueued(item)
+			m.async.unreserve()
+		}
+	}
+}
+
+// settleQueued settles a queued payment, retrying while the facilitator is
+// unreachable or busy. Payments that fail for good are written to the
+// SettlementRetryQueue when configured.
+func (m *X402Middleware) settleQueued(item asyncSettlement) {
+	logger := item.logger
+	backoff := m.async.cfg.RetryBackoff
+	var lastError string
+	for attempt := 1; ; attempt++ {
+		start := time.Now()
+		settleResp, err := m.facilitator.SettleContext(context.Background(), item.request)
+		m.config.Metrics.observeSettlement(m.metricsRoute(item.payment.Route), time.Since(start), item.payment, item.request, settleResp, err)
+		if err == nil && settleResp.Success {
+			m.settlements.finish(item.id)
+			if len(m.exporters) > 0 {
+				record := item.record
+				record.Timestamp = time.Now().Unix()
+				record.Transaction = settleResp.Transaction
+				if settleResp.Payer != "" {
+					record.Payer = settleResp.Payer
+				}
+				if settleResp.Amount != "" {
+					record.Amount = settleResp.Amount
+				}
+				m.exportUsage(logger, record)
+			}
+			logger.Info("queued payment settled", "tx", settleResp.Transaction, "payer", settleResp.Payer, "attempts", attempt)
+			return
+		}
+
+		retryable := true
+		if err != nil {
+			lastError = err.Error()
+		} else {
+			lastError = settleResp.ErrorReason + ": " + settleResp.ErrorMessage
+			retryable = retryableSettlement(settleResp.ErrorReason)
+		}
+		if !retryable || attempt >= m.async.cfg.MaxAttempts {
+			break
+		}
+		logger.Warn("queued settlement failed, retrying", "attempt", attempt, "error", lastError)
+
+		// Leave the settlement to Shutdown once it has begun
+		select {
+		case <-m.async.stop:
+			return
+		case <-time.After(backoff):
+		}
+		backoff *= 2
+	}
+
+	m.releasePayment(context.Background(), item.payment)
+	logger.Error("queued payment failed to settle after it was served", "route", item.payment.Route, "error", lastError)
+	if m.config.SettlementRetryQueue != nil {
+		pending := PendingSettlement{
+			Route:     item.payment.Route,
+			QueuedAt:  time.Now().Unix(),
+			Request:   *item.request,
+			LastError: lastError,
+		}
+		if err := m.config.SettlementRetryQueue.Enqueue(pending); err != nil {
+			logger.Error("failed to persist settlement", "error", err)
+		}
+	}
+	m.settlements.finish(item.id)
+}
+
+// retryableSettlement reports whether a settlement failing with code may
+// succeed when tried again
+func retryableSettlement(code string) bool {
+	switch code {
+	case types.ErrCodeNetworkError, types.ErrCodeSettlementQueueFull, types.ErrCodeSettlementInProgress:
+		return true
+	}
+	return false
+}
@@ -0,0 +1,167 @@
+package middleware
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/vorpalengineering/x402-go/types"
+)
+
+func TestAsyncSettlement(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	// The first settle attempt finds the network down
+	var settleCalls atomic.Int32
+	facilitator := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		switch r.URL.Path {
+		case "/verify":
+			json.NewEncoder(w).Encode(types.VerifyResponse{IsValid: true})
+		case "/settle":
+			if settleCalls.Add(1) == 1 {
+				json.NewEncoder(w).Encode(types.SettleResponse{ErrorReason: types.ErrCodeNetworkError})
+				return
+			}
+			json.NewEncoder(w).Encode(types.SettleResponse{Success: true, Transaction: "0xabc"})
+		}
+	}))
+	defer facilitator.Close()
+
+	requirements := types.PaymentRequirements{
+		Scheme:  "exact",
+		Network: "eip155:84532",
+		Amount:  "1000",
+		Asset:   "0x036CbD53842c5426634e7929541eC2318f3dCF7e",
+		PayTo:   "0x3C44CdDdB6a900fa2b585dd299e03d12FA4293BC",
+	}
+	records := make(chan UsageRecord, 1)
+	cfg := &MiddlewareConfig{
+		FacilitatorURL:      facilitator.URL,
+		DefaultRequirements: requirements,
+		ProtectedPaths:      []string{"/data", "/fail"},
+		RouteSettlement:     map[string]SettlementOrder{"/data": SettleAsync, "/fail": SettleAsync},
+		AsyncSettlement:     AsyncSettlementConfig{RetryBackoff: time.Millisecond},
+		UsageExporters: []UsageExporter{UsageExporterFunc(func(ctx context.Context, record UsageRecord) error {
+			records <- record
+			return nil
+		})},
+	}
+	if err := cfg.Validate(); err != nil {
+		t.Fatalf("Expected valid config, got %v", err)
+	}
+	m := NewX402Middleware(cfg)
+	router := gin.New()
+	router.Use(m.Handler())
+	router.GET("/data", func(ctx *gin.Context) {
+		ctx.String(http.StatusOK, "data")
+	})
+	router.GET("/fail", func(ctx *gin.Context) {
+		ctx.String(http.StatusInternalServerError, "failed")
+	})
+
+	// Failed requests are not queued
+	req := httptest.NewRequest("GET", "/fail", nil)
+	req.Header.Set("PAYMENT-SIGNATURE", paidHeader(requirements))
+	recorder := httptest.NewRecorder()
+	router.ServeHTTP(recorder, req)
+	if recorder.Code != http.StatusInternalServerError {
+		t.Fatalf("Expected status 500, got %d", recorder.Code)
+	}
+
+	// Successful ones are served before settling
+	req = httptest.NewRequest("GET", "/data", nil)
+	req.Header.Set("PAYMENT-SIGNATURE", paidHeader(requirements))
+	recorder = httptest.NewRecorder()
+	router.ServeHTTP(recorder, req)
+	if recorder.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d: %s", recorder.Code, recorder.Body.String())
+	}
+	if recorder.Header().Get("PAYMENT-RESPONSE") != "" {
+		t.Error("Expected no PAYMENT-RESPONSE header before settlement")
+	}
+
+	select {
+	case record := <-records:
+		if record.Transaction != "0xabc" || record.Route != "/data" {
+			t.Errorf("Expected usage record of settled /data request, got %+v", record)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("Expected queued payment to settle")
+	}
+	if calls := settleCalls.Load(); calls != 2 {
+		t.Errorf("Expected 2 settle attempts, got %d", calls)
+	}
+
+	// Nothing is left in flight
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+	if err := m.Shutdown(ctx); err != nil {
+		t.Errorf("Expected clean shutdown, got %v", err)
+	}
+}
+
+func TestAsyncSettlementPersistsFailures(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	facilitator := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		switch r.URL.Path {
+		case "/verify":
+			json.NewEncoder(w).Encode(types.VerifyResponse{IsValid: true})
+		case "/settle":
+			json.NewEncoder(w).Encode(types.SettleResponse{ErrorReason: types.ErrCodeSettlementQueueFull})
+		}
+	}))
+	defer facilitator.Close()
+
+	requirements := types.PaymentRequirements{
+		Scheme:  "exact",
+		Network: "eip155:84532",
+		Amount:  "1000",
+		Asset:   "0x036CbD53842c5426634e7929541eC2318f3dCF7e",
+		PayTo:   "0x3C44CdDdB6a900fa2b585dd299e03d12FA4293BC",
+	}
+	queue := NewFileSettlementRetryQueue(filepath.Join(t.TempDir(), "retry.jsonl"))
+	m := NewX402Middleware(&MiddlewareConfig{
+		FacilitatorURL:       facilitator.URL,
+		DefaultRequirements:  requirements,
+		ProtectedPaths:       []string{"/data"},
+		RouteSettlement:      map[string]SettlementOrder{"/data": SettleAsync},
+		AsyncSettlement:      AsyncSettlementConfig{MaxAttempts: 2, RetryBackoff: time.Millisecond},
+		SettlementRetryQueue: queue,
+	})
+	router := gin.New()
+	router.Use(m.Handler())
+	router.GET("/data", func(ctx *gin.Context) {
+		ctx.String(http.StatusOK, "data")
+	})
+
+	req := httptest.NewRequest("GET", "/data", nil)
+	req.Header.Set("PAYMENT-SIGNATURE", paidHeader(requirements))
+	recorder := httptest.NewRecorder()
+	router.ServeHTTP(recorder, req)
+	if recorder.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d: %s", recorder.Code, recorder.Body.String())
+	}
+
+	// Shutdown waits for the retries to give up
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	if err := m.Shutdown(ctx); err != nil {
+		t.Fatalf("Expected clean shutdown, got %v", err)
+	}
+	pending, err := queue.Load()
+	if err != nil {
+		t.Fatalf("Failed to load queue: %v", err)
+	}
+	if len(pending) != 1 || pending[0].Route != "/data" || pending[0].LastError == "" {
+		t.Errorf("Expected the failed settlement to be persisted, got %+v", pending)
+	}
+}
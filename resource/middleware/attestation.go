@@ -0,0 +1,176 @@
+package middleware
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/vorpalengineering/x402-go/types"
+	"github.com/vorpalengineering/x402-go/version"
+)
+
+// Attestation error codes returned in the "code" field of gate refusals
+const (
+	AttestationErrNotAttested = "payer_not_attested"
+	AttestationErrCheckFailed = "attestation_check_failed"
+)
+
+// AttestationChecker reports whether a payer holds the attestation required
+// to pay for a resource (e.g. an EAS attestation or a compliance API lookup).
+type AttestationChecker interface {
+	IsAttested(ctx context.Context, payer string, requirements types.PaymentRequirements) (bool, error)
+}
+
+// AttestationGateConfig refuses payments from unattested payers before the
+// handler runs and before anything is settled.
+type AttestationGateConfig struct {
+	// Checker performs the attestation lookup
+	Checker AttestationChecker `json:"-" toml:"-"`
+
+	// Routes limits the gate to these path patterns. Empty gates every protected path.
+	Routes []string `json:"routes,omitempty" toml:"routes"`
+
+	// MinAmount only gates payments of at least this amount (smallest unit).
+	// Empty gates payments of any amount.
+	MinAmount string `json:"minAmount,omitempty" toml:"min_amount"`
+
+	// CacheTTL caches checker results per payer. 0 disables caching.
+	// Expired results are swept at most once a minute.
+	CacheTTL time.Duration `json:"cacheTtl,omitempty" toml:"cache_ttl"`
+}
+
+type attestationCacheEntry struct {
+	attested  bool
+	expiresAt time.Time
+}
+
+// attestationGate applies an AttestationGateConfig with result caching
+type attestationGate struct {
+	config  *AttestationGateConfig
+	mu      sync.Mutex
+	results map[string]attestationCacheEntry
+	pruneAt time.Time
+}
+
+func newAttestationGate(cfg *AttestationGateConfig) *attestationGate {
+	return &attestationGate{
+		config:  cfg,
+		results: make(map[string]attestationCacheEntry),
+	}
+}
+
+// applies reports whether the gate covers this path and amount
+func (g *attestationGate) applies(path string, requirements types.PaymentRequirements) bool {
+	if len(g.config.Routes) > 0 && !matchesAny(g.config.Routes, path) {
+		return false
+	}
+
+	if g.config.MinAmount != "" {
+		minAmount, ok := new(big.Int).SetString(g.config.MinAmount, 10)
+		amount, ok2 := new(big.Int).SetString(requirements.Amount, 10)
+		if ok && ok2 && amount.Cmp(minAmount) < 0 {
+			return false
+		}
+	}
+
+	return true
+}
+
+// check returns the (possibly cached) attestation status of payer. A payer
+// that could not be determined is never attested.
+func (g *attestationGate) check(ctx context.Context, payer string, requirements types.PaymentRequirements) (bool, error) {
+	if payer == "" {
+		return false, nil
+	}
+	key := strings.ToLower(payer)
+
+	// Serve from cache
+	if g.config.CacheTTL > 0 {
+		g.mu.Lock()
+		entry, ok := g.results[key]
+		g.mu.Unlock()
+		if ok && time.Now().Before(entry.expiresAt) {
+			return entry.attested, nil
+		}
+	}
+
+	attested, err := g.config.Checker.IsAttested(ctx, payer, requirements)
+	if err != nil {
+		return false, err
+	}
+
+	// Cache result, forgetting expired results at most once a minute
+	if g.config.CacheTTL > 0 {
+		g.mu.Lock()
+		now := time.Now()
+		if !now.Before(g.pruneAt) {
+			for k, entry := range g.results {
+				if !now.Before(entry.expiresAt) {
+					delete(g.results, k)
+				}
+			}
+			g.pruneAt = now.Add(time.Minute)
+		}
+		g.results[key] = attestationCacheEntry{attested: attested, expiresAt: now.Add(g.config.CacheTTL)}
+		g.mu.Unlock()
+	}
+
+	return attested, nil
+}
+
+// HTTPAttestationChecker queries an external compliance API with
+// GET <URL>?address=<payer> and expects {"attested": bool} in response.
+type HTTPAttestationChecker struct {
+	URL        string
+	HTTPClient *http.Client
+}
+
+func NewHTTPAttestationChecker(checkURL string) *HTTPAttestationChecker {
+	return &HTTPAttestationChecker{
+		URL:        checkURL,
+		HTTPClient: &http.Client{Timeout: 10 * time.Second, Transport: version.NewTransport("x402-go")},
+	}
+}
+
+func (c *HTTPAttestationChecker) IsAttested(ctx context.Context, payer string, requirements types.PaymentRequirements) (bool, error) {
+	// Build lookup url
+	lookupURL, err := url.Parse(c.URL)
+	if err != nil {
+		return false, fmt.Errorf("invalid attestation url: %w", err)
+	}
+	query := lookupURL.Query()
+	query.Set("address", payer)
+	lookupURL.RawQuery = query.Encode()
+
+	// Make request
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, lookupURL.String(), nil)
+	if err != nil {
+		return false, fmt.Errorf("failed to create request: %w", err)
+	}
+	resp, err := c.HTTPClient.Do(req)
+	if err != nil {
+		return false, fmt.Errorf("failed to send request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	// Check response
+	if resp.StatusCode != http.StatusOK {
+		return false, fmt.Errorf("unexpected status code: %d", resp.StatusCode)
+	}
+
+	// Decode response
+	var result struct {
+		Attested bool `json:"attested"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return false, fmt.Errorf("failed to decode response: %w", err)
+	}
+
+	return result.Attested, nil
+}
@@ -0,0 +1,190 @@
+package middleware
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/vorpalengineering/x402-go/types"
+)
+
+// fakeAttestationChecker attests the payers in attested, failing with err
+// when set, and counts its lookups
+type fakeAttestationChecker struct {
+	attested map[string]bool
+	err      error
+	calls    atomic.Int32
+}
+
+func (c *fakeAttestationChecker) IsAttested(ctx context.Context, payer string, requirements types.PaymentRequirements) (bool, error) {
+	c.calls.Add(1)
+	if c.err != nil {
+		return false, c.err
+	}
+	return c.attested[strings.ToLower(payer)], nil
+}
+
+func TestAttestationGateApplies(t *testing.T) {
+	requirements := types.PaymentRequirements{Amount: "1000"}
+
+	tests := []struct {
+		name     string
+		config   AttestationGateConfig
+		path     string
+		amount   string
+		expected bool
+	}{
+		{"every path", AttestationGateConfig{}, "/data", "1000", true},
+		{"matching route", AttestationGateConfig{Routes: []string{"/api/*"}}, "/api/data", "1000", true},
+		{"other route", AttestationGateConfig{Routes: []string{"/api/*"}}, "/data", "1000", false},
+		{"at min amount", AttestationGateConfig{MinAmount: "1000"}, "/data", "1000", true},
+		{"above min amount", AttestationGateConfig{MinAmount: "1000"}, "/data", "5000", true},
+		{"below min amount", AttestationGateConfig{MinAmount: "1000"}, "/data", "999", false},
+		{"unparseable amount", AttestationGateConfig{MinAmount: "1000"}, "/data", "lots", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			requirements.Amount = tt.amount
+			gate := newAttestationGate(&tt.config)
+			if applies := gate.applies(tt.path, requirements); applies != tt.expected {
+				t.Errorf("Expected applies %v, got %v", tt.expected, applies)
+			}
+		})
+	}
+}
+
+func TestAttestationGateCache(t *testing.T) {
+	payer := "0x70997970C51812dc3A010C7d01b50e0d17dc79C8"
+	checker := &fakeAttestationChecker{attested: map[string]bool{strings.ToLower(payer): true}}
+	gate := newAttestationGate(&AttestationGateConfig{Checker: checker, CacheTTL: time.Minute})
+
+	// Results are cached per payer, whatever the case of the address
+	for _, p := range []string{payer, strings.ToLower(payer)} {
+		attested, err := gate.check(context.Background(), p, types.PaymentRequirements{})
+		if err != nil || !attested {
+			t.Fatalf("Expected %s to be attested, got %v and %v", p, attested, err)
+		}
+	}
+	if calls := checker.calls.Load(); calls != 1 {
+		t.Errorf("Expected 1 lookup, got %d", calls)
+	}
+
+	// An unknown payer is refused without a lookup
+	attested, err := gate.check(context.Background(), "", types.PaymentRequirements{})
+	if err != nil || attested {
+		t.Errorf("Expected unknown payer to be refused, got %v and %v", attested, err)
+	}
+	if calls := checker.calls.Load(); calls != 1 {
+		t.Errorf("Expected no lookup for unknown payer, got %d lookups", calls)
+	}
+
+	// Expired results are looked up again and swept from the cache
+	gate.mu.Lock()
+	gate.results["0xexpired"] = attestationCacheEntry{expiresAt: time.Now().Add(-time.Second)}
+	gate.results[strings.ToLower(payer)] = attestationCacheEntry{attested: true, expiresAt: time.Now().Add(-time.Second)}
+	gate.pruneAt = time.Time{}
+	gate.mu.Unlock()
+	if _, err := gate.check(context.Background(), payer, types.PaymentRequirements{}); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if calls := checker.calls.Load(); calls != 2 {
+		t.Errorf("Expected expired result to be looked up again, got %d lookups", calls)
+	}
+	if _, ok := gate.results["0xexpired"]; ok || len(gate.results) != 1 {
+		t.Errorf("Expected expired results to be swept, got %v", gate.results)
+	}
+}
+
+func TestAttestationGate(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	var settleCalls atomic.Int32
+	facilitator := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		switch r.URL.Path {
+		case "/verify":
+			json.NewEncoder(w).Encode(types.VerifyResponse{IsValid: true, Payer: "0x70997970C51812dc3A010C7d01b50e0d17dc79C8"})
+		case "/settle":
+			settleCalls.Add(1)
+			json.NewEncoder(w).Encode(types.SettleResponse{Success: true, Transaction: "0xabc"})
+		}
+	}))
+	defer facilitator.Close()
+
+	requirements := types.PaymentRequirements{
+		Scheme:  "exact",
+		Network: "eip155:84532",
+		Amount:  "1000",
+		Asset:   "0x036CbD53842c5426634e7929541eC2318f3dCF7e",
+		PayTo:   "0x3C44CdDdB6a900fa2b585dd299e03d12FA4293BC",
+	}
+
+	tests := []struct {
+		name        string
+		attested    bool
+		err         error
+		status      int
+		code        string
+		settleCalls int32
+	}{
+		{"attested payer", true, nil, http.StatusOK, "", 1},
+		{"unattested payer", false, nil, http.StatusForbidden, AttestationErrNotAttested, 0},
+		{"checker error", false, errors.New("compliance api down"), http.StatusBadGateway, AttestationErrCheckFailed, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			settleCalls.Store(0)
+			checker := &fakeAttestationChecker{
+				attested: map[string]bool{"0x70997970c51812dc3a010c7d01b50e0d17dc79c8": tt.attested},
+				err:      tt.err,
+			}
+			cfg := &MiddlewareConfig{
+				FacilitatorURL:      facilitator.URL,
+				DefaultRequirements: requirements,
+				ProtectedPaths:      []string{"/data"},
+				AttestationGate:     &AttestationGateConfig{Checker: checker},
+			}
+			if err := cfg.Validate(); err != nil {
+				t.Fatalf("Expected valid config, got %v", err)
+			}
+			served := false
+			router := gin.New()
+			router.Use(NewX402Middleware(cfg).Handler())
+			router.GET("/data", func(ctx *gin.Context) {
+				served = true
+				ctx.String(http.StatusOK, "data")
+			})
+
+			req := httptest.NewRequest("GET", "/data", nil)
+			req.Header.Set("PAYMENT-SIGNATURE", paidHeader(requirements))
+			recorder := httptest.NewRecorder()
+			router.ServeHTTP(recorder, req)
+
+			if recorder.Code != tt.status {
+				t.Fatalf("Expected status %d, got %d: %s", tt.status, recorder.Code, recorder.Body.String())
+			}
+			if served != (tt.status == http.StatusOK) {
+				t.Errorf("Expected handler to run only for attested payers, served %v", served)
+			}
+			if tt.code != "" {
+				var body struct {
+					Code string `json:"code"`
+				}
+				json.Unmarshal(recorder.Body.Bytes(), &body)
+				if body.Code != tt.code {
+					t.Errorf("Expected code %s, got %s", tt.code, body.Code)
+				}
+			}
+			if calls := settleCalls.Load(); calls != tt.settleCalls {
+				t.Errorf("Expected %d settle calls, got %d", tt.settleCalls, calls)
+			}
+		})
+	}
+}
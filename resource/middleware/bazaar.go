@@ -0,0 +1,147 @@
+package middleware
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/vorpalengineering/x402-go/types"
+)
+
+const (
+	// DefaultBazaarPath is where the Bazaar discovery list is served
+	DefaultBazaarPath = "/discovery/resources"
+	// DefaultBazaarLimit is the page size when the request has no limit
+	DefaultBazaarLimit = 20
+	// MaxBazaarLimit caps the page size a request can ask for
+	MaxBazaarLimit = 100
+)
+
+// BazaarConfig lists routes in the Bazaar discovery list format, so x402
+// marketplaces can index them
+type BazaarConfig struct {
+	// Path serves the list. Defaults to DefaultBazaarPath.
+	Path string `json:"path,omitempty" toml:"path"`
+
+	// Routes are the endpoint paths to list, opted in one by one. They must
+	// be protected and are combined with BaseURL into resource URLs.
+	Routes []string `json:"routes" toml:"routes"`
+
+	// Metadata is extra marketplace metadata per route (e.g. category,
+	// tags). The route's description and MIME type from RouteResources are
+	// always included.
+	Metadata map[string]map[string]any `json:"metadata,omitempty" toml:"metadata"`
+}
+
+func (c *BazaarConfig) validate(protectedPaths []string) error {
+	if len(c.Routes) == 0 {
+		return errors.New("at least one route must be listed")
+	}
+	for _, route := range c.Routes {
+		if !matchesAny(protectedPaths, route) {
+			return errors.New("listed route " + route + " is not protected")
+		}
+	}
+	for route := range c.Metadata {
+		if !matchesAny(c.Routes, route) {
+			return errors.New("metadata for route " + route + " which is not listed")
+		}
+	}
+	return nil
+}
+
+func (c *BazaarConfig) path() string {
+	if c.Path == "" {
+		return DefaultBazaarPath
+	}
+	return c.Path
+}
+
+// BazaarResources returns every listed resource with its payment
+// requirements, in the order the routes were configured
+func (m *X402Middleware) BazaarResources() []types.DiscoveryResource {
+	cfg := m.config.BazaarListing
+	if cfg == nil {
+		return nil
+	}
+
+	resources := make([]types.DiscoveryResource, 0, len(cfg.Routes))
+	for _, route := range cfg.Routes {
+		metadata := make(map[string]any)
+		if r, exists := m.config.RouteResources[route]; exists && r != nil {
+			if r.Description != "" {
+				metadata["description"] = r.Description
+			}
+			if r.MimeType != "" {
+				metadata["mimeType"] = r.MimeType
+			}
+		}
+		for key, value := range cfg.Metadata[route] {
+			metadata[key] = value
+		}
+		if len(metadata) == 0 {
+			metadata = nil
+		}
+
+		resources = append(resources, types.DiscoveryResource{
+			Resource:    m.config.BaseURL + route,
+			Type:        "http",
+			X402Version: 2,
+			Accepts:     m.getRequirements(route),
+			LastUpdated: m.started.Unix(),
+			Metadata:    metadata,
+		})
+	}
+	return resources
+}
+
+// serveBazaar serves a page of the listed resources. The query takes
+// "type" (only "http" resources are listed), "limit" and "offset".
+func (m *X402Middleware) serveBazaar(ctx *gin.Context) {
+	defer ctx.Abort()
+
+	limit, err := queryInt(ctx, "limit", DefaultBazaarLimit)
+	if err != nil || limit < 1 {
+		ctx.JSON(http.StatusBadRequest, gin.H{
+			"error": "limit must be a positive integer",
+		})
+		return
+	}
+	limit = min(limit, MaxBazaarLimit)
+	offset, err := queryInt(ctx, "offset", 0)
+	if err != nil || offset < 0 {
+		ctx.JSON(http.StatusBadRequest, gin.H{
+			"error": "offset must be a non-negative integer",
+		})
+		return
+	}
+
+	var resources []types.DiscoveryResource
+	if kind := ctx.Query("type"); kind == "" || kind == "http" {
+		resources = m.BazaarResources()
+	}
+	page := []types.DiscoveryResource{}
+	if offset < len(resources) {
+		page = resources[offset:min(offset+limit, len(resources))]
+	}
+
+	ctx.JSON(http.StatusOK, types.DiscoveryResourcesResponse{
+		X402Version: 2,
+		Items:       page,
+		Pagination: types.DiscoveryPagination{
+			Limit:  limit,
+			Offset: offset,
+			Total:  len(resources),
+		},
+	})
+}
+
+// queryInt parses an integer query parameter, returning fallback if unset
+func queryInt(ctx *gin.Context, name string, fallback int) (int, error) {
+	value := ctx.Query(name)
+	if value == "" {
+		return fallback, nil
+	}
+	return strconv.Atoi(value)
+}
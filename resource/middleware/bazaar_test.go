@@ -0,0 +1,114 @@
+package middleware
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/vorpalengineering/x402-go/types"
+)
+
+func TestBazaarListing(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	requirements := types.PaymentRequirements{
+		Scheme:  "exact",
+		Network: "eip155:8453",
+		Amount:  "1000000",
+		PayTo:   "0x742d35Cc6634C0532925a3b844Bc9e7595f0bEb",
+		Asset:   "0x833589fCD6eDb6E08f4c7C32D4f71b54bdA02913",
+	}
+	cfg := &MiddlewareConfig{
+		FacilitatorURL:      "http://localhost:4020",
+		DefaultRequirements: requirements,
+		ProtectedPaths:      []string{"/api/*"},
+		BaseURL:             "https://example.com",
+		RouteResources: map[string]*types.ResourceInfo{
+			"/api/weather": {Description: "Weather", MimeType: "application/json"},
+		},
+		BazaarListing: &BazaarConfig{
+			Routes:   []string{"/api/weather", "/api/news", "/api/quotes"},
+			Metadata: map[string]map[string]any{"/api/weather": {"category": "data"}},
+		},
+	}
+	if err := cfg.Validate(); err != nil {
+		t.Fatalf("Expected valid config, got %v", err)
+	}
+	router := gin.New()
+	router.Use(NewX402Middleware(cfg).Handler())
+
+	tests := []struct {
+		name   string
+		query  string
+		status int
+		items  []string
+	}{
+		{"all", "", http.StatusOK, []string{"/api/weather", "/api/news", "/api/quotes"}},
+		{"page", "?limit=1&offset=1", http.StatusOK, []string{"/api/news"}},
+		{"past the end", "?offset=5", http.StatusOK, []string{}},
+		{"other type", "?type=mcp", http.StatusOK, []string{}},
+		{"invalid limit", "?limit=0", http.StatusBadRequest, nil},
+		{"invalid offset", "?offset=-1", http.StatusBadRequest, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			recorder := httptest.NewRecorder()
+			router.ServeHTTP(recorder, httptest.NewRequest("GET", "/discovery/resources"+tt.query, nil))
+			if recorder.Code != tt.status {
+				t.Fatalf("Expected status %d, got %d: %s", tt.status, recorder.Code, recorder.Body.String())
+			}
+			if tt.status != http.StatusOK {
+				return
+			}
+
+			var resp types.DiscoveryResourcesResponse
+			if err := json.Unmarshal(recorder.Body.Bytes(), &resp); err != nil {
+				t.Fatalf("Invalid response: %v", err)
+			}
+			if len(resp.Items) != len(tt.items) {
+				t.Fatalf("Expected %d items, got %+v", len(tt.items), resp.Items)
+			}
+			for i, route := range tt.items {
+				if resp.Items[i].Resource != "https://example.com"+route {
+					t.Errorf("Expected resource %s, got %s", route, resp.Items[i].Resource)
+				}
+			}
+		})
+	}
+
+	// Items carry requirements and metadata
+	recorder := httptest.NewRecorder()
+	router.ServeHTTP(recorder, httptest.NewRequest("GET", "/discovery/resources?limit=1", nil))
+	var resp types.DiscoveryResourcesResponse
+	json.Unmarshal(recorder.Body.Bytes(), &resp)
+	if resp.Pagination.Total != 3 || resp.Pagination.Limit != 1 {
+		t.Errorf("Expected pagination of 3 items by 1, got %+v", resp.Pagination)
+	}
+	item := resp.Items[0]
+	if item.Type != "http" || len(item.Accepts) != 1 || item.Accepts[0].Amount != "1000000" || item.LastUpdated == 0 {
+		t.Errorf("Expected listed requirements, got %+v", item)
+	}
+	if item.Metadata["description"] != "Weather" || item.Metadata["category"] != "data" {
+		t.Errorf("Expected merged metadata, got %v", item.Metadata)
+	}
+}
+
+func TestBazaarListingValidation(t *testing.T) {
+	cfg := &MiddlewareConfig{
+		FacilitatorURL: "http://localhost:4020",
+		DefaultRequirements: types.PaymentRequirements{
+			Scheme:  "exact",
+			Network: "eip155:8453",
+			Amount:  "1000000",
+			PayTo:   "0x742d35Cc6634C0532925a3b844Bc9e7595f0bEb",
+			Asset:   "0x833589fCD6eDb6E08f4c7C32D4f71b54bdA02913",
+		},
+		ProtectedPaths: []string{"/api/*"},
+		BazaarListing:  &BazaarConfig{Routes: []string{"/public/docs"}},
+	}
+	if err := cfg.Validate(); err == nil {
+		t.Error("Expected error listing an unprotected route")
+	}
+}
@@ -4,6 +4,7 @@ import (
 	"bytes"
 	"fmt"
 	"net/http"
+	"sync"
 
 	"github.com/gin-gonic/gin"
 )
@@ -11,11 +12,13 @@ import (
 // bufferedWriter captures the response so we can settle payment before sending to client
 type bufferedWriter struct {
 	gin.ResponseWriter
+	mu       sync.Mutex
 	body     *bytes.Buffer
 	status   int
 	header   http.Header
 	maxSize  int
 	overflow bool
+	expired  bool
 }
 
 func newBufferedWriter(w gin.ResponseWriter, maxSize int) *bufferedWriter {
@@ -29,6 +32,13 @@ func newBufferedWriter(w gin.ResponseWriter, maxSize int) *bufferedWriter {
 }
 
 func (w *bufferedWriter) Write(data []byte) (int, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	// Drop writes of handlers still running past their timeout
+	if w.expired {
+		return 0, http.ErrHandlerTimeout
+	}
 	if w.maxSize > 0 && w.body.Len()+len(data) > w.maxSize {
 		w.overflow = true
 		return 0, fmt.Errorf("response exceeds max buffer size (%d bytes)", w.maxSize)
@@ -36,19 +46,46 @@ func (w *bufferedWriter) Write(data []byte) (int, error) {
 	return w.body.Write(data)
 }
 
+func (w *bufferedWriter) WriteString(s string) (int, error) {
+	return w.Write([]byte(s))
+}
+
 func (w *bufferedWriter) WriteHeader(status int) {
-	w.status = status
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if !w.expired {
+		w.status = status
+	}
 }
 
 func (w *bufferedWriter) Header() http.Header {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	// Headers set past the timeout go nowhere
+	if w.expired {
+		return make(http.Header)
+	}
 	return w.header
 }
 
 func (w *bufferedWriter) Status() int {
+	w.mu.Lock()
+	defer w.mu.Unlock()
 	return w.status
 }
 
+// expire drops every later write, once the handler has run out of time
+func (w *bufferedWriter) expire() {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.expired = true
+}
+
 func (w *bufferedWriter) flush() error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
 	// Copy buffered headers to real response
 	for k, v := range w.header {
 		for _, val := range v {
@@ -1,33 +1,167 @@
 package middleware
 
 import (
+	"crypto/tls"
 	"errors"
+	"log/slog"
+	"net/http"
 
+	"github.com/vorpalengineering/x402-go/facilitator"
 	"github.com/vorpalengineering/x402-go/types"
+	"github.com/vorpalengineering/x402-go/utils"
+	"github.com/vorpalengineering/x402-go/webhook"
+)
+
+// SettlementOrder is when a route's payment is settled relative to its handler
+type SettlementOrder string
+
+const (
+	// SettleAfter settles once the handler has succeeded and holds its
+	// response until then, so payers are never charged for failed requests
+	SettleAfter SettlementOrder = "after"
+
+	// SettleBefore settles before the handler runs, so expensive handlers
+	// never run for payments that fail to settle
+	SettleBefore SettlementOrder = "before"
+
+	// VerifyOnly runs the handler on a verified payment without settling it.
+	// The payment is settled out of band, e.g. by passing the PaymentKey
+	// context value to SettlePayment.
+	VerifyOnly SettlementOrder = "verify-only"
+
+	// SettleAsync responds right after verification and settles on a
+	// background queue with retries once the handler has succeeded, for
+	// low-value, high-throughput routes. A payment that then fails to settle
+	// has still been served.
+	SettleAsync SettlementOrder = "async"
 )
 
 type MiddlewareConfig struct {
 	// FacilitatorURL is the base URL of the x402 facilitator service
 	FacilitatorURL string `json:"facilitatorUrl" toml:"facilitator_url"`
 
+	// FacilitatorTLS sets the TLS settings used to reach the facilitator, e.g. a
+	// client certificate when it requires mutual TLS. See client.LoadTLSConfig.
+	FacilitatorTLS *tls.Config `json:"-" toml:"-"`
+
+	// FacilitatorHeaders are sent with every request to the facilitator, e.g.
+	// {"X-API-Key": "..."} when it enforces per-client quotas
+	FacilitatorHeaders map[string]string `json:"facilitatorHeaders,omitempty" toml:"facilitator_headers"`
+
+	// LocalVerification verifies exact EVM payments in process instead of
+	// calling the facilitator's /verify, which is then only called for
+	// settlement. The payer's balance is not checked until settlement, and
+	// signatures that do not recover to the payer (e.g. contract wallets) are
+	// still sent to the facilitator. Exact EVM requirements must carry the
+	// token's EIP-712 "name" and "version" in Extra.
+	LocalVerification bool `json:"localVerification,omitempty" toml:"local_verification"`
+
+	// SelfSettle verifies and settles payments on-chain in process with this
+	// facilitator instead of calling FacilitatorURL, for operators running
+	// their own key. Create it with facilitator.NewFacilitator and Start it
+	// before serving; the caller closes it after Shutdown.
+	SelfSettle *facilitator.Facilitator `json:"-" toml:"-"`
+
 	// DefaultRequirements specifies the default payment requirements
 	// for protected routes that don't have specific requirements
 	DefaultRequirements types.PaymentRequirements `json:"defaultRequirements" toml:"default_requirements"`
 
 	// ProtectedPaths is a list of path patterns that require payment
-	// Supports glob patterns like "/api/*" or exact paths like "/data"
+	// Supports exact paths like "/data", glob segments like "/api/*",
+	// parameters like "/users/:id", any depth like "/api/**" and regular
+	// expressions matching the whole path like "re:/v[0-9]+/items"
 	ProtectedPaths []string `json:"protectedPaths" toml:"protected_paths"`
 
 	// RouteRequirements maps specific routes to custom payment requirements
-	// If a route matches multiple patterns, the most specific match is used
+	// If a route matches multiple patterns, the most specific match is used:
+	// an exact path, then "re:" expressions, then patterns compared segment
+	// by segment, literal before glob before parameter before "**"
 	// Routes not in this map will use DefaultRequirements
 	RouteRequirements map[string]types.PaymentRequirements `json:"routeRequirements,omitempty" toml:"route_requirements"`
 
+	// PricingFunc prices each request, e.g. by query parameters, body size or
+	// the authenticated user. Its requirements replace the route's static
+	// ones, unless it returns the zero value. An error rejects the request
+	// with a 400.
+	PricingFunc func(*http.Request) (types.PaymentRequirements, error) `json:"-" toml:"-"`
+
+	// DefaultAccepts lists every payment option offered on protected routes
+	// without specific requirements. Takes precedence over DefaultRequirements.
+	DefaultAccepts []types.PaymentRequirements `json:"defaultAccepts,omitempty" toml:"default_accepts"`
+
+	// RouteAccepts maps routes to every payment option they accept (e.g. the
+	// same price in several assets or networks). All entries are advertised in
+	// the 402 response and the payment must match one of them.
+	// Takes precedence over RouteRequirements.
+	RouteAccepts map[string][]types.PaymentRequirements `json:"routeAccepts,omitempty" toml:"route_accepts"`
+
 	// RouteResources maps a specific route to its ResourceInfo
 	RouteResources map[string]*types.ResourceInfo `json:"routeResources,omitempty" toml:"route_resources"`
 
+	// DeprecatedRoutes maps route patterns to their deprecation schedule.
+	// Deprecated routes get Deprecation/Sunset headers until the sunset date,
+	// after which they respond 410 Gone without charging.
+	DeprecatedRoutes map[string]RouteDeprecation `json:"deprecatedRoutes,omitempty" toml:"deprecated_routes"`
+
+	// AttestationGate optionally refuses payments from payers without a
+	// required attestation (KYC/compliance) before the handler runs
+	AttestationGate *AttestationGateConfig `json:"attestationGate,omitempty" toml:"attestation_gate"`
+
+	// Sessions issues a session token with each settled payment, granting
+	// access to the route for a period without paying per request
+	Sessions *SessionConfig `json:"sessions,omitempty" toml:"sessions"`
+
+	// Credits turns overpayment into a prepaid balance that later requests
+	// draw down instead of paying on-chain each time
+	Credits *CreditConfig `json:"credits,omitempty" toml:"credits"`
+
+	// FreeAccess lets allowlisted API keys and payer addresses use protected
+	// routes without paying
+	FreeAccess *FreeAccessConfig `json:"freeAccess,omitempty" toml:"free_access"`
+
+	// FreeTier serves a number of requests per client and day for free
+	// before asking for payment
+	FreeTier *FreeTierConfig `json:"freeTier,omitempty" toml:"free_tier"`
+
+	// UsageExporters receive a normalized usage record after every
+	// successful settlement, for blending x402 revenue into billing pipelines
+	UsageExporters []UsageExporter `json:"-" toml:"-"`
+
+	// SettlementWebhooks receive each usage record as a JSON POST after
+	// every successful settlement, signed when the endpoint has secrets
+	SettlementWebhooks []webhook.Endpoint `json:"settlementWebhooks,omitempty" toml:"settlement_webhooks"`
+
+	// ReplayStore remembers accepted payment headers until they expire and
+	// refuses them on other requests before calling the facilitator, e.g.
+	// NewMemoryReplayStore(). Nil disables the replay cache.
+	ReplayStore ReplayStore `json:"-" toml:"-"`
+
+	// SettlementRetryQueue persists settlements still in flight when
+	// Shutdown's deadline passes, so they can be retried after restart
+	SettlementRetryQueue SettlementRetryQueue `json:"-" toml:"-"`
+
+	// Metrics counts 402s, verifications, settlements, their latency and
+	// revenue for Prometheus, e.g. NewMetrics(). Nil disables them.
+	Metrics *Metrics `json:"-" toml:"-"`
+
+	// LogLevel is "debug", "info" (default), "warn" or "error"
+	LogLevel string `json:"logLevel,omitempty" toml:"log_level"`
+
+	// LogFormat is "text" (default) or "json"
+	LogFormat string `json:"logFormat,omitempty" toml:"log_format"`
+
+	// Logger overrides the logger built from LogLevel and LogFormat
+	Logger *slog.Logger `json:"-" toml:"-"`
+
+	// X402Versions are the x402 protocol versions spoken, most preferred
+	// first: 2 (default) and/or 1. v1 clients pay in the X-PAYMENT header and
+	// get v1 402 bodies and X-PAYMENT-RESPONSE headers. Requests without a
+	// payment get a 402 body in the preferred version.
+	X402Versions []int `json:"x402Versions,omitempty" toml:"x402_versions"`
+
 	// PaymentHeaderName is the name of the HTTP header containing the payment signature
-	// Defaults to "PAYMENT-SIGNATURE" if not specified
+	// Defaults to "PAYMENT-SIGNATURE" if not specified. A custom name is advertised
+	// to clients in the 402 response under the "paymentHeader" extension.
 	PaymentHeaderName string `json:"paymentHeaderName,omitempty" toml:"payment_header_name"`
 
 	// MaxBufferSize is the maximum response buffer size in bytes.
@@ -35,6 +169,50 @@ type MiddlewareConfig struct {
 	// 0 means unlimited.
 	MaxBufferSize int `json:"maxBufferSize,omitempty" toml:"max_buffer_size"`
 
+	// RouteLimits maps route patterns to their own buffer size and handler
+	// timeout, so one large or slow route does not set them for all
+	RouteLimits map[string]RouteLimit `json:"routeLimits,omitempty" toml:"route_limits"`
+
+	// StreamingRoutes are path patterns whose responses are written straight
+	// to the client instead of buffered (SSE, chunked downloads, large files).
+	// Their payment is settled before the handler runs, so it is charged even
+	// if the handler then fails. MaxBufferSize does not apply to them.
+	StreamingRoutes []string `json:"streamingRoutes,omitempty" toml:"streaming_routes"`
+
+	// RouteSettlement maps route patterns to when their payment is settled.
+	// Routes not in this map use SettleAfter, or SettleBefore when streaming.
+	RouteSettlement map[string]SettlementOrder `json:"routeSettlement,omitempty" toml:"route_settlement"`
+
+	// RouteSettlementFailure maps route patterns to what they do when their
+	// payment fails to settle. Routes not in this map fail closed.
+	RouteSettlementFailure map[string]SettlementFailurePolicy `json:"routeSettlementFailure,omitempty" toml:"route_settlement_failure"`
+
+	// SettlementRetry retries settlements failing transiently, with
+	// jittered backoff, before the route's failure policy applies
+	SettlementRetry SettlementRetryConfig `json:"settlementRetry,omitempty" toml:"settlement_retry"`
+
+	// AsyncSettlement tunes the background queue settling payments of
+	// SettleAsync routes
+	AsyncSettlement AsyncSettlementConfig `json:"asyncSettlement,omitempty" toml:"async_settlement"`
+
+	// MaxPaymentHeaderSize is the maximum decoded payment header size in bytes.
+	// Larger headers are rejected before being parsed.
+	// 0 uses DefaultMaxPaymentHeaderSize (16 KB).
+	MaxPaymentHeaderSize int `json:"maxPaymentHeaderSize,omitempty" toml:"max_payment_header_size"`
+
+	// CORS lets browser clients on other origins pay for protected routes,
+	// answering preflight requests and exposing the x402 headers. OPTIONS
+	// requests are never charged, with or without it.
+	CORS *CORSConfig `json:"cors,omitempty" toml:"cors"`
+
+	// FreeHeadRequests lets HEAD requests to protected routes through
+	// without payment
+	FreeHeadRequests bool `json:"freeHeadRequests,omitempty" toml:"free_head_requests"`
+
+	// Paywall answers browsers (Accept preferring text/html) with an HTML
+	// payment page instead of the JSON 402 response
+	Paywall *PaywallConfig `json:"paywall,omitempty" toml:"paywall"`
+
 	// DiscoveryEnabled enables serving the /.well-known/x402 discovery endpoint
 	DiscoveryEnabled bool `json:"discoveryEnabled,omitempty" toml:"discovery_enabled"`
 
@@ -47,6 +225,10 @@ type MiddlewareConfig struct {
 	// Included in the /.well-known/x402 discovery response if non-empty.
 	Instructions string `json:"instructions,omitempty" toml:"instructions"`
 
+	// Contact is optional operator contact information
+	// included in the /.well-known/x402 discovery response.
+	Contact *types.DiscoveryContact `json:"contact,omitempty" toml:"contact"`
+
 	// BaseURL is the public base URL of the server (e.g., "https://api.example.com")
 	// Used to construct full endpoint URLs in the discovery response.
 	BaseURL string `json:"baseUrl,omitempty" toml:"base_url"`
@@ -55,22 +237,51 @@ type MiddlewareConfig struct {
 	// to advertise in the /.well-known/x402 discovery response.
 	// These are combined with BaseURL to form full URLs (e.g., BaseURL + "/api/data").
 	DiscoverableEndpoints []string `json:"discoverableEndpoints,omitempty" toml:"discoverable_endpoints"`
+
+	// BazaarListing serves opted-in routes in the Bazaar discovery list
+	// format (paginated GET /discovery/resources) for x402 marketplaces
+	BazaarListing *BazaarConfig `json:"bazaarListing,omitempty" toml:"bazaar_listing"`
 }
 
 func (c *MiddlewareConfig) Validate() error {
 	// Check required variables
-	if c.FacilitatorURL == "" {
-		return errors.New("facilitator URL is required")
+	if c.FacilitatorURL == "" && c.SelfSettle == nil {
+		return errors.New("facilitator URL or self-settling facilitator is required")
 	}
 	if len(c.ProtectedPaths) == 0 {
 		return errors.New("at least one protected path must be specified")
 	}
 
+	// Validate route patterns, compiling regular expressions
+	for _, pattern := range c.routePatterns() {
+		if err := validateRoutePattern(pattern); err != nil {
+			return err
+		}
+	}
+
 	// Validate default requirements
-	if err := validatePaymentRequirements(&c.DefaultRequirements); err != nil {
+	if len(c.DefaultAccepts) > 0 {
+		for i := range c.DefaultAccepts {
+			if err := validatePaymentRequirements(&c.DefaultAccepts[i]); err != nil {
+				return errors.New("invalid default accepts: " + err.Error())
+			}
+		}
+	} else if err := validatePaymentRequirements(&c.DefaultRequirements); err != nil {
 		return errors.New("invalid default requirements: " + err.Error())
 	}
 
+	// Validate multi-option route requirements
+	for route, accepts := range c.RouteAccepts {
+		if len(accepts) == 0 {
+			return errors.New("route accepts for " + route + " cannot be empty")
+		}
+		for i := range accepts {
+			if err := validatePaymentRequirements(&accepts[i]); err != nil {
+				return errors.New("invalid accepts for route " + route + ": " + err.Error())
+			}
+		}
+	}
+
 	// Validate route-specific requirements
 	for route, req := range c.RouteRequirements {
 		if err := validatePaymentRequirements(&req); err != nil {
@@ -78,12 +289,186 @@ func (c *MiddlewareConfig) Validate() error {
 		}
 	}
 
+	// Validate local verification, which cannot read the token's domain on-chain
+	if c.LocalVerification {
+		for _, requirements := range c.staticRequirements() {
+			if requirements.Scheme != "exact" || utils.IsSolanaNetwork(requirements.Network) {
+				continue
+			}
+			name, _ := requirements.Extra["name"].(string)
+			version, _ := requirements.Extra["version"].(string)
+			if name == "" || version == "" {
+				return errors.New("local verification requires extra name and version for asset " + requirements.Asset + " on " + requirements.Network)
+			}
+		}
+	}
+
+	// Validate free access
+	if c.FreeAccess != nil {
+		if err := c.FreeAccess.validate(); err != nil {
+			return errors.New("invalid free access: " + err.Error())
+		}
+	}
+
+	// Validate free tier
+	if c.FreeTier != nil {
+		if err := c.FreeTier.validate(); err != nil {
+			return errors.New("invalid free tier: " + err.Error())
+		}
+	}
+
+	// Validate route limits
+	for route, limit := range c.RouteLimits {
+		if err := limit.validate(); err != nil {
+			return errors.New("invalid limits for route " + route + ": " + err.Error())
+		}
+	}
+
+	// Validate protocol versions
+	if err := validateX402Versions(c.X402Versions); err != nil {
+		return err
+	}
+
+	// Validate CORS
+	if c.CORS != nil {
+		if err := c.CORS.validate(); err != nil {
+			return errors.New("invalid cors: " + err.Error())
+		}
+	}
+
+	// Validate Bazaar listing
+	if c.BazaarListing != nil {
+		if err := c.BazaarListing.validate(c.ProtectedPaths); err != nil {
+			return errors.New("invalid bazaar listing: " + err.Error())
+		}
+	}
+
+	// Validate settlement webhooks
+	for _, endpoint := range c.SettlementWebhooks {
+		if endpoint.URL == "" {
+			return errors.New("settlement webhook URL is required")
+		}
+	}
+
+	// Validate attestation gate
+	if c.AttestationGate != nil && c.AttestationGate.Checker == nil {
+		return errors.New("attestation gate requires a checker")
+	}
+
+	// Validate sessions
+	if c.Sessions != nil {
+		if err := c.Sessions.validate(); err != nil {
+			return errors.New("invalid sessions: " + err.Error())
+		}
+	}
+
+	// Validate credits
+	if c.Credits != nil && c.Credits.Store == nil {
+		return errors.New("credits require a store")
+	}
+
+	// Validate settlement order
+	for route, order := range c.RouteSettlement {
+		switch order {
+		case SettleAfter:
+			if matchesAny(c.StreamingRoutes, route) {
+				return errors.New("streaming route " + route + " cannot settle after the handler")
+			}
+		case SettleBefore, VerifyOnly, SettleAsync:
+		default:
+			return errors.New("invalid settlement order for route " + route + ": " + string(order) + " (must be after, before, verify-only, or async)")
+		}
+	}
+	for route, policy := range c.RouteSettlementFailure {
+		if err := policy.validate(); err != nil {
+			return errors.New("invalid settlement failure policy for route " + route + ": " + err.Error())
+		}
+	}
+	if err := c.SettlementRetry.validate(); err != nil {
+		return errors.New("invalid settlement retry: " + err.Error())
+	}
+	if err := c.AsyncSettlement.validate(); err != nil {
+		return errors.New("invalid async settlement: " + err.Error())
+	}
+
+	// Validate logging
+	switch c.LogLevel {
+	case "", "debug", "info", "warn", "error":
+	default:
+		return errors.New("invalid log level: " + c.LogLevel + " (must be debug, info, warn, or error)")
+	}
+	if c.LogFormat != "" && c.LogFormat != "text" && c.LogFormat != "json" {
+		return errors.New("invalid log format: " + c.LogFormat + " (must be text or json)")
+	}
+
+	// Validate deprecation schedules
+	for route, dep := range c.DeprecatedRoutes {
+		if !dep.DeprecatedAt.IsZero() && !dep.Sunset.IsZero() && dep.Sunset.Before(dep.DeprecatedAt) {
+			return errors.New("invalid deprecation for route " + route + ": sunset is before deprecation")
+		}
+	}
+
 	return nil
 }
 
+// staticRequirements returns every payment requirements in the config
+func (c *MiddlewareConfig) staticRequirements() []types.PaymentRequirements {
+	requirements := append([]types.PaymentRequirements{}, c.DefaultAccepts...)
+	if len(c.DefaultAccepts) == 0 {
+		requirements = append(requirements, c.DefaultRequirements)
+	}
+	for _, req := range c.RouteRequirements {
+		requirements = append(requirements, req)
+	}
+	for _, accepts := range c.RouteAccepts {
+		requirements = append(requirements, accepts...)
+	}
+	return requirements
+}
+
+// routePatterns returns every route pattern in the config
+func (c *MiddlewareConfig) routePatterns() []string {
+	patterns := append([]string{}, c.ProtectedPaths...)
+	patterns = append(patterns, c.StreamingRoutes...)
+	for pattern := range c.RouteRequirements {
+		patterns = append(patterns, pattern)
+	}
+	for pattern := range c.RouteAccepts {
+		patterns = append(patterns, pattern)
+	}
+	for pattern := range c.RouteSettlement {
+		patterns = append(patterns, pattern)
+	}
+	for pattern := range c.RouteLimits {
+		patterns = append(patterns, pattern)
+	}
+	for pattern := range c.RouteSettlementFailure {
+		patterns = append(patterns, pattern)
+	}
+	for pattern := range c.DeprecatedRoutes {
+		patterns = append(patterns, pattern)
+	}
+	if c.Sessions != nil {
+		patterns = append(patterns, c.Sessions.Routes...)
+	}
+	if c.Credits != nil {
+		patterns = append(patterns, c.Credits.Routes...)
+	}
+	if c.AttestationGate != nil {
+		patterns = append(patterns, c.AttestationGate.Routes...)
+	}
+	if c.FreeAccess != nil {
+		patterns = append(patterns, c.FreeAccess.Routes...)
+	}
+	if c.FreeTier != nil {
+		patterns = append(patterns, c.FreeTier.Routes...)
+	}
+	return patterns
+}
+
 func (c *MiddlewareConfig) GetPaymentHeaderName() string {
 	if c.PaymentHeaderName == "" {
-		return "PAYMENT-SIGNATURE"
+		return utils.DefaultPaymentHeaderName
 	}
 	return c.PaymentHeaderName
 }
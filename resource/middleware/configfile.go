@@ -0,0 +1,341 @@
+package middleware
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/pelletier/go-toml/v2"
+	"gopkg.in/yaml.v3"
+)
+
+// envConfigPrefix starts the environment variables overriding a loaded config
+const envConfigPrefix = "X402_MIDDLEWARE_"
+
+var (
+	durationType = reflect.TypeOf(time.Duration(0))
+	timeType     = reflect.TypeOf(time.Time{})
+)
+
+// LoadConfig reads a middleware config from a YAML, TOML or JSON file, picked
+// by its extension. Keys are the snake_case TOML keys (e.g. facilitator_url,
+// route_requirements), JSON also accepts the camelCase ones, and durations
+// are strings like "30s". X402_MIDDLEWARE_* environment variables then
+// override the file, and the result is validated.
+//
+// Settings that are Go values (PricingFunc, stores, exporters, the
+// attestation checker) are set on the returned config. Free tier and
+// credits sections get in-memory stores until then.
+func LoadConfig(configPath string) (*MiddlewareConfig, error) {
+	data, err := os.ReadFile(configPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read config file: %w", err)
+	}
+
+	// Decode the file into a document of maps, lists and scalars
+	var document map[string]any
+	switch strings.ToLower(filepath.Ext(configPath)) {
+	case ".yaml", ".yml":
+		err = yaml.Unmarshal(data, &document)
+	case ".toml":
+		err = toml.Unmarshal(data, &document)
+	case ".json":
+		decoder := json.NewDecoder(bytes.NewReader(data))
+		decoder.UseNumber()
+		err = decoder.Decode(&document)
+	default:
+		return nil, fmt.Errorf("unsupported config format %q (must be .yaml, .yml, .toml or .json)", filepath.Ext(configPath))
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse config: %w", err)
+	}
+
+	// Rename keys to the JSON ones and decode the config from JSON
+	normalized, err := normalizeConfig(document, reflect.TypeOf(MiddlewareConfig{}), "")
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse config: %w", err)
+	}
+	encoded, err := json.Marshal(normalized)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse config: %w", err)
+	}
+	var config MiddlewareConfig
+	if err := json.Unmarshal(encoded, &config); err != nil {
+		return nil, fmt.Errorf("failed to parse config: %w", err)
+	}
+
+	// Apply environment variable overrides
+	if err := applyEnvOverrides(&config, os.Environ()); err != nil {
+		return nil, fmt.Errorf("failed to load env config: %w", err)
+	}
+
+	// Sections a file can enable but not back with a store
+	if config.FreeTier != nil && config.FreeTier.Store == nil {
+		config.FreeTier.Store = NewMemoryFreeTierStore()
+	}
+	if config.Credits != nil && config.Credits.Store == nil {
+		config.Credits.Store = NewMemoryCreditStore()
+	}
+
+	if err := config.Validate(); err != nil {
+		return nil, fmt.Errorf("invalid config: %w", err)
+	}
+	return &config, nil
+}
+
+// normalizeConfig rewrites a decoded document for decoding into t as JSON.
+// Struct keys are matched against the toml, yaml and json tags and field
+// name, and renamed to the json key. Map keys, like route patterns, are kept.
+// Durations are parsed and numbers given for strings are formatted. Errors
+// name the offending key by its path, e.g. route_requirements["/api"].amount.
+func normalizeConfig(value any, t reflect.Type, path string) (any, error) {
+	if value == nil {
+		return nil, nil
+	}
+	if t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+
+	switch {
+	case t == durationType:
+		if s, ok := value.(string); ok {
+			d, err := time.ParseDuration(s)
+			if err != nil {
+				return nil, fmt.Errorf("%s: invalid duration %q", path, s)
+			}
+			return int64(d), nil
+		}
+		return value, nil
+	case t == timeType:
+		return value, nil
+	}
+
+	switch t.Kind() {
+	case reflect.Struct:
+		table, ok := value.(map[string]any)
+		if !ok {
+			return nil, fmt.Errorf("%s: expected a table, got %v", path, value)
+		}
+		normalized := make(map[string]any, len(table))
+		for key, item := range table {
+			field, name, found := configField(t, key)
+			if !found {
+				return nil, fmt.Errorf("%s: unknown setting", joinConfigPath(path, key))
+			}
+			item, err := normalizeConfig(item, field.Type, joinConfigPath(path, key))
+			if err != nil {
+				return nil, err
+			}
+			normalized[name] = item
+		}
+		return normalized, nil
+	case reflect.Map:
+		table, ok := value.(map[string]any)
+		if !ok {
+			return nil, fmt.Errorf("%s: expected a table, got %v", path, value)
+		}
+		normalized := make(map[string]any, len(table))
+		for key, item := range table {
+			item, err := normalizeConfig(item, t.Elem(), path+"["+strconv.Quote(key)+"]")
+			if err != nil {
+				return nil, err
+			}
+			normalized[key] = item
+		}
+		return normalized, nil
+	case reflect.Slice:
+		list, ok := value.([]any)
+		if !ok {
+			return nil, fmt.Errorf("%s: expected a list, got %v", path, value)
+		}
+		normalized := make([]any, len(list))
+		for i, item := range list {
+			item, err := normalizeConfig(item, t.Elem(), fmt.Sprintf("%s[%d]", path, i))
+			if err != nil {
+				return nil, err
+			}
+			normalized[i] = item
+		}
+		return normalized, nil
+	case reflect.String:
+		// Amounts are strings, but unquoted numbers are what people write
+		switch v := value.(type) {
+		case string:
+			return v, nil
+		case json.Number:
+			return v.String(), nil
+		case int:
+			return strconv.Itoa(v), nil
+		case int64:
+			return strconv.FormatInt(v, 10), nil
+		case uint64:
+			return strconv.FormatUint(v, 10), nil
+		case float64:
+			return strconv.FormatFloat(v, 'f', -1, 64), nil
+		}
+		return nil, fmt.Errorf("%s: expected a string, got %v", path, value)
+	case reflect.Bool:
+		if _, ok := value.(bool); !ok {
+			return nil, fmt.Errorf("%s: expected true or false, got %v", path, value)
+		}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64,
+		reflect.Float32, reflect.Float64:
+		switch value.(type) {
+		case json.Number, int, int64, uint64, float64:
+		default:
+			return nil, fmt.Errorf("%s: expected a number, got %v", path, value)
+		}
+	}
+	return value, nil
+}
+
+// configField finds the field of struct t a config key sets, returning it
+// with its json key. Fields without a json key are not configurable.
+func configField(t reflect.Type, key string) (reflect.StructField, string, bool) {
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		name, _, _ := strings.Cut(field.Tag.Get("json"), ",")
+		if name == "-" || !field.IsExported() {
+			continue
+		}
+		if name == "" {
+			name = field.Name
+		}
+		for _, tag := range []string{"toml", "yaml"} {
+			if alias, _, _ := strings.Cut(field.Tag.Get(tag), ","); alias != "" && alias == key {
+				return field, name, true
+			}
+		}
+		if key == name || strings.EqualFold(key, field.Name) {
+			return field, name, true
+		}
+	}
+	return reflect.StructField{}, "", false
+}
+
+func joinConfigPath(path, key string) string {
+	if path == "" {
+		return key
+	}
+	return path + "." + key
+}
+
+// applyEnvOverrides sets config fields from X402_MIDDLEWARE_* variables in
+// environ. Names are the upper-cased TOML keys joined by "_" (e.g.
+// X402_MIDDLEWARE_FACILITATOR_URL, X402_MIDDLEWARE_SESSIONS_SECRET,
+// X402_MIDDLEWARE_DEFAULT_REQUIREMENTS_PAY_TO). Lists are comma-separated.
+// Maps and lists of tables are left to the file.
+func applyEnvOverrides(config *MiddlewareConfig, environ []string) error {
+	vars := make(map[string]string)
+	for _, entry := range environ {
+		name, value, ok := strings.Cut(entry, "=")
+		if ok && strings.HasPrefix(name, envConfigPrefix) {
+			vars[name] = value
+		}
+	}
+	_, err := applyEnv(reflect.ValueOf(config).Elem(), envConfigPrefix, vars)
+	return err
+}
+
+// applyEnv sets the scalar and string list fields of the struct v from vars,
+// recursing into sections. Unset sections are created when a variable sets
+// one of their fields. Reports whether anything was set.
+func applyEnv(v reflect.Value, prefix string, vars map[string]string) (bool, error) {
+	t := v.Type()
+	set := false
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		key, _, _ := strings.Cut(field.Tag.Get("toml"), ",")
+		if key == "" {
+			key, _, _ = strings.Cut(field.Tag.Get("yaml"), ",")
+		}
+		if key == "" || key == "-" || !field.IsExported() {
+			continue
+		}
+		name := prefix + strings.ToUpper(key)
+
+		// Sections
+		fieldType := field.Type
+		if fieldType.Kind() == reflect.Pointer && fieldType.Elem().Kind() == reflect.Struct && fieldType.Elem() != timeType {
+			section := reflect.New(fieldType.Elem())
+			if !v.Field(i).IsNil() {
+				section = v.Field(i)
+			}
+			sectionSet, err := applyEnv(section.Elem(), name+"_", vars)
+			if err != nil {
+				return false, err
+			}
+			if sectionSet {
+				v.Field(i).Set(section)
+				set = true
+			}
+			continue
+		}
+		if fieldType.Kind() == reflect.Struct && fieldType != timeType {
+			sectionSet, err := applyEnv(v.Field(i), name+"_", vars)
+			if err != nil {
+				return false, err
+			}
+			set = set || sectionSet
+			continue
+		}
+
+		value, ok := vars[name]
+		if !ok {
+			continue
+		}
+		if err := setEnvValue(v.Field(i), value); err != nil {
+			return false, fmt.Errorf("%s: %w", name, err)
+		}
+		set = true
+	}
+	return set, nil
+}
+
+func setEnvValue(v reflect.Value, value string) error {
+	if v.Type() == durationType {
+		d, err := time.ParseDuration(value)
+		if err != nil {
+			return fmt.Errorf("invalid duration %q", value)
+		}
+		v.SetInt(int64(d))
+		return nil
+	}
+	switch v.Kind() {
+	case reflect.String:
+		v.SetString(value)
+	case reflect.Bool:
+		b, err := strconv.ParseBool(value)
+		if err != nil {
+			return fmt.Errorf("invalid boolean %q", value)
+		}
+		v.SetBool(b)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		n, err := strconv.ParseInt(value, 10, v.Type().Bits())
+		if err != nil {
+			return fmt.Errorf("invalid integer %q", value)
+		}
+		v.SetInt(n)
+	case reflect.Slice:
+		if v.Type().Elem().Kind() != reflect.String {
+			return fmt.Errorf("cannot be set from the environment")
+		}
+		items := reflect.MakeSlice(v.Type(), 0, 0)
+		for _, item := range strings.Split(value, ",") {
+			if item = strings.TrimSpace(item); item != "" {
+				items = reflect.Append(items, reflect.ValueOf(item).Convert(v.Type().Elem()))
+			}
+		}
+		v.Set(items)
+	default:
+		return fmt.Errorf("cannot be set from the environment")
+	}
+	return nil
+}
@@ -0,0 +1,164 @@
+package middleware
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestLoadConfig(t *testing.T) {
+	tests := []struct {
+		name    string
+		file    string
+		content string
+	}{
+		{"yaml", "config.yaml", `
+facilitator_url: "http://localhost:4020"
+protected_paths: ["/api/*"]
+default_requirements:
+  scheme: exact
+  network: "eip155:84532"
+  amount: 10000
+  asset: "0x036CbD53842c5426634e7929541eC2318f3dCF7e"
+  pay_to: "0x3C44CdDdB6a900fa2b585dd299e03d12FA4293BC"
+route_settlement:
+  "/api/render": before
+free_tier:
+  requests: 5
+  window: 1h
+`},
+		{"toml", "config.toml", `
+facilitator_url = "http://localhost:4020"
+protected_paths = ["/api/*"]
+
+[default_requirements]
+scheme = "exact"
+network = "eip155:84532"
+amount = "10000"
+asset = "0x036CbD53842c5426634e7929541eC2318f3dCF7e"
+pay_to = "0x3C44CdDdB6a900fa2b585dd299e03d12FA4293BC"
+
+[route_settlement]
+"/api/render" = "before"
+
+[free_tier]
+requests = 5
+window = "1h"
+`},
+		{"json", "config.json", `{
+  "facilitatorUrl": "http://localhost:4020",
+  "protectedPaths": ["/api/*"],
+  "defaultRequirements": {
+    "scheme": "exact",
+    "network": "eip155:84532",
+    "amount": "10000",
+    "asset": "0x036CbD53842c5426634e7929541eC2318f3dCF7e",
+    "payTo": "0x3C44CdDdB6a900fa2b585dd299e03d12FA4293BC"
+  },
+  "route_settlement": {"/api/render": "before"},
+  "freeTier": {"requests": 5, "window": "1h"}
+}`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			configPath := filepath.Join(t.TempDir(), tt.file)
+			os.WriteFile(configPath, []byte(tt.content), 0600)
+
+			config, err := LoadConfig(configPath)
+			if err != nil {
+				t.Fatalf("Expected config to load, got error: %v", err)
+			}
+			if config.FacilitatorURL != "http://localhost:4020" || len(config.ProtectedPaths) != 1 {
+				t.Errorf("Expected top-level settings, got %+v", config)
+			}
+			if config.DefaultRequirements.Amount != "10000" || config.DefaultRequirements.PayTo != "0x3C44CdDdB6a900fa2b585dd299e03d12FA4293BC" {
+				t.Errorf("Expected default requirements, got %+v", config.DefaultRequirements)
+			}
+			if config.RouteSettlement["/api/render"] != SettleBefore {
+				t.Errorf("Expected route settlement by pattern, got %v", config.RouteSettlement)
+			}
+			if config.FreeTier == nil || config.FreeTier.Window != time.Hour || config.FreeTier.Store == nil {
+				t.Errorf("Expected free tier with an hour window and a store, got %+v", config.FreeTier)
+			}
+		})
+	}
+}
+
+func TestLoadConfigExample(t *testing.T) {
+	config, err := LoadConfig("config.example.yaml")
+	if err != nil {
+		t.Fatalf("Expected example config to load, got error: %v", err)
+	}
+	if config.RouteRequirements["/premium/**"].Amount != "100000" {
+		t.Errorf("Expected premium route requirements, got %+v", config.RouteRequirements)
+	}
+}
+
+func TestLoadConfigEnvOverrides(t *testing.T) {
+	t.Setenv("X402_MIDDLEWARE_FACILITATOR_URL", "https://facilitator.example.com")
+	t.Setenv("X402_MIDDLEWARE_PROTECTED_PATHS", "/api/*, /premium/**")
+	t.Setenv("X402_MIDDLEWARE_DEFAULT_REQUIREMENTS_AMOUNT", "20000")
+	t.Setenv("X402_MIDDLEWARE_SESSIONS_SECRET", strings.Repeat("s", 32))
+	t.Setenv("X402_MIDDLEWARE_SESSIONS_DURATION", "10m")
+
+	config, err := LoadConfig("config.example.yaml")
+	if err != nil {
+		t.Fatalf("Expected config to load, got error: %v", err)
+	}
+	if config.FacilitatorURL != "https://facilitator.example.com" || len(config.ProtectedPaths) != 2 {
+		t.Errorf("Expected settings from env, got %+v", config)
+	}
+	if config.DefaultRequirements.Amount != "20000" || config.DefaultRequirements.Network != "eip155:84532" {
+		t.Errorf("Expected amount from env and network from the file, got %+v", config.DefaultRequirements)
+	}
+	if config.Sessions == nil || config.Sessions.Duration != 10*time.Minute {
+		t.Errorf("Expected sessions created from env, got %+v", config.Sessions)
+	}
+
+	// Invalid values name the variable
+	t.Setenv("X402_MIDDLEWARE_MAX_BUFFER_SIZE", "10MB")
+	if _, err := LoadConfig("config.example.yaml"); err == nil || !strings.Contains(err.Error(), "X402_MIDDLEWARE_MAX_BUFFER_SIZE") {
+		t.Errorf("Expected error naming X402_MIDDLEWARE_MAX_BUFFER_SIZE, got %v", err)
+	}
+}
+
+func TestLoadConfigErrors(t *testing.T) {
+	base := `
+facilitator_url: "http://localhost:4020"
+protected_paths: ["/api/*"]
+default_requirements:
+  scheme: exact
+  network: "eip155:84532"
+  amount: "10000"
+  asset: "0x036CbD53842c5426634e7929541eC2318f3dCF7e"
+  pay_to: "0x3C44CdDdB6a900fa2b585dd299e03d12FA4293BC"
+`
+	tests := []struct {
+		name     string
+		file     string
+		content  string
+		expected string
+	}{
+		{"unknown setting", "config.yaml", base + "protected_path: [\"/other\"]\n", "protected_path: unknown setting"},
+		{"unknown route setting", "config.yaml", base + "route_requirements:\n  \"/api/data\":\n    amout: \"5\"\n", `route_requirements["/api/data"].amout: unknown setting`},
+		{"wrong type", "config.yaml", base + "route_requirements:\n  \"/api/data\":\n    max_timeout_seconds: soon\n", `route_requirements["/api/data"].max_timeout_seconds: expected a number`},
+		{"invalid duration", "config.yaml", base + "free_tier:\n  requests: 5\n  window: daily\n", `free_tier.window: invalid duration`},
+		{"invalid route requirements", "config.yaml", base + "route_requirements:\n  \"/api/data\":\n    scheme: exact\n", "invalid requirements for route /api/data"},
+		{"invalid settlement order", "config.yaml", base + "route_settlement:\n  \"/api/data\": later\n", "invalid settlement order for route /api/data"},
+		{"syntax error", "config.json", `{"facilitatorUrl": "http://localhost:4020",}`, "invalid character"},
+		{"unsupported format", "config.ini", base, "unsupported config format"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			configPath := filepath.Join(t.TempDir(), tt.file)
+			os.WriteFile(configPath, []byte(tt.content), 0600)
+
+			_, err := LoadConfig(configPath)
+			if err == nil || !strings.Contains(err.Error(), tt.expected) {
+				t.Errorf("Expected error containing %q, got %v", tt.expected, err)
+			}
+		})
+	}
+}
@@ -0,0 +1,118 @@
+package middleware
+
+import (
+	"cmp"
+	"errors"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/vorpalengineering/x402-go/types"
+	"github.com/vorpalengineering/x402-go/utils"
+)
+
+// CORSConfig lets browser clients on other origins pay for protected routes.
+// The x402 headers are allowed and exposed, so scripts can send payments and
+// read PAYMENT-REQUIRED and PAYMENT-RESPONSE.
+type CORSConfig struct {
+	// AllowedOrigins are the origins allowed to call protected routes, or
+	// "*" for any origin
+	AllowedOrigins []string `json:"allowedOrigins" toml:"allowed_origins"`
+
+	// AllowedHeaders are request headers allowed besides the payment,
+	// session, credit and API key headers and Content-Type
+	AllowedHeaders []string `json:"allowedHeaders,omitempty" toml:"allowed_headers"`
+
+	// ExposedHeaders are response headers exposed besides the x402 ones
+	ExposedHeaders []string `json:"exposedHeaders,omitempty" toml:"exposed_headers"`
+
+	// AllowCredentials lets requests carry cookies and HTTP auth
+	AllowCredentials bool `json:"allowCredentials,omitempty" toml:"allow_credentials"`
+
+	// MaxAge is how long browsers may cache preflight responses. 0 leaves
+	// it to the browser.
+	MaxAge time.Duration `json:"maxAge,omitempty" toml:"max_age"`
+}
+
+func (c *CORSConfig) validate() error {
+	if len(c.AllowedOrigins) == 0 {
+		return errors.New("at least one allowed origin is required")
+	}
+	if c.MaxAge < 0 {
+		return errors.New("max age cannot be negative")
+	}
+	return nil
+}
+
+// allowsOrigin reports whether requests from origin are allowed
+func (c *CORSConfig) allowsOrigin(origin string) bool {
+	return slices.Contains(c.AllowedOrigins, "*") || slices.Contains(c.AllowedOrigins, origin)
+}
+
+// setCORSHeaders allows the request's origin to read the response. Preflight
+// requests are answered, returning true.
+func (m *X402Middleware) setCORSHeaders(ctx *gin.Context) bool {
+	cfg := m.config.CORS
+	origin := ctx.GetHeader("Origin")
+	if cfg == nil || origin == "" || !cfg.allowsOrigin(origin) {
+		return false
+	}
+
+	ctx.Header("Vary", "Origin")
+	if slices.Contains(cfg.AllowedOrigins, "*") && !cfg.AllowCredentials {
+		ctx.Header("Access-Control-Allow-Origin", "*")
+	} else {
+		ctx.Header("Access-Control-Allow-Origin", origin)
+	}
+	if cfg.AllowCredentials {
+		ctx.Header("Access-Control-Allow-Credentials", "true")
+	}
+
+	// Preflight requests ask which methods and headers are allowed
+	if ctx.Request.Method != http.MethodOptions || ctx.GetHeader("Access-Control-Request-Method") == "" {
+		ctx.Header("Access-Control-Expose-Headers", strings.Join(m.exposedHeaders(), ", "))
+		return false
+	}
+	ctx.Header("Access-Control-Allow-Methods", "GET, HEAD, POST, PUT, PATCH, DELETE, OPTIONS")
+	ctx.Header("Access-Control-Allow-Headers", strings.Join(m.allowedHeaders(), ", "))
+	if cfg.MaxAge > 0 {
+		ctx.Header("Access-Control-Max-Age", strconv.Itoa(int(cfg.MaxAge.Seconds())))
+	}
+	ctx.AbortWithStatus(http.StatusNoContent)
+	return true
+}
+
+// allowedHeaders are the request headers browsers may send
+func (m *X402Middleware) allowedHeaders() []string {
+	headers := []string{"Content-Type", m.config.GetPaymentHeaderName(), SessionHeader, CreditHeader, utils.RequestIDHeader}
+	if m.speaks(types.X402VersionV1) {
+		headers = append(headers, V1PaymentHeader)
+	}
+	if m.config.FreeAccess != nil {
+		headers = append(headers, cmp.Or(m.config.FreeAccess.APIKeyHeader, DefaultAPIKeyHeader))
+	}
+	return append(headers, m.config.CORS.AllowedHeaders...)
+}
+
+// exposedHeaders are the response headers scripts may read
+func (m *X402Middleware) exposedHeaders() []string {
+	headers := []string{
+		"PAYMENT-REQUIRED",
+		"PAYMENT-RESPONSE",
+		SessionHeader,
+		CreditHeader,
+		CreditBalanceHeader,
+		FreeTierRemainingHeader,
+		SettlementStatusHeader,
+		"Deprecation",
+		"Sunset",
+		utils.RequestIDHeader,
+	}
+	if m.speaks(types.X402VersionV1) {
+		headers = append(headers, V1PaymentResponseHeader)
+	}
+	return append(headers, m.config.CORS.ExposedHeaders...)
+}
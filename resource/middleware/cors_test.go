@@ -0,0 +1,128 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/vorpalengineering/x402-go/types"
+)
+
+func TestCORS(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	cfg := &MiddlewareConfig{
+		FacilitatorURL: "http://localhost:4020",
+		DefaultRequirements: types.PaymentRequirements{
+			Scheme:  "exact",
+			Network: "eip155:84532",
+			Amount:  "1000",
+			Asset:   "0x036CbD53842c5426634e7929541eC2318f3dCF7e",
+			PayTo:   "0x3C44CdDdB6a900fa2b585dd299e03d12FA4293BC",
+		},
+		ProtectedPaths: []string{"/data"},
+		CORS: &CORSConfig{
+			AllowedOrigins: []string{"https://app.example.com"},
+			AllowedHeaders: []string{"Authorization"},
+			MaxAge:         time.Hour,
+		},
+	}
+	if err := cfg.Validate(); err != nil {
+		t.Fatalf("Expected valid config, got %v", err)
+	}
+	router := gin.New()
+	router.Use(NewX402Middleware(cfg).Handler())
+	router.GET("/data", func(ctx *gin.Context) {
+		ctx.String(http.StatusOK, "data")
+	})
+
+	// Preflight requests are answered without payment
+	req := httptest.NewRequest("OPTIONS", "/data", nil)
+	req.Header.Set("Origin", "https://app.example.com")
+	req.Header.Set("Access-Control-Request-Method", "GET")
+	recorder := httptest.NewRecorder()
+	router.ServeHTTP(recorder, req)
+	if recorder.Code != http.StatusNoContent {
+		t.Fatalf("Expected status 204, got %d", recorder.Code)
+	}
+	if origin := recorder.Header().Get("Access-Control-Allow-Origin"); origin != "https://app.example.com" {
+		t.Errorf("Expected allowed origin, got %q", origin)
+	}
+	allowed := recorder.Header().Get("Access-Control-Allow-Headers")
+	for _, header := range []string{"PAYMENT-SIGNATURE", "Authorization"} {
+		if !strings.Contains(allowed, header) {
+			t.Errorf("Expected %s to be allowed, got %q", header, allowed)
+		}
+	}
+	if maxAge := recorder.Header().Get("Access-Control-Max-Age"); maxAge != "3600" {
+		t.Errorf("Expected max age 3600, got %q", maxAge)
+	}
+
+	// 402 responses expose the x402 headers to scripts
+	req = httptest.NewRequest("GET", "/data", nil)
+	req.Header.Set("Origin", "https://app.example.com")
+	recorder = httptest.NewRecorder()
+	router.ServeHTTP(recorder, req)
+	if recorder.Code != http.StatusPaymentRequired {
+		t.Fatalf("Expected status 402, got %d", recorder.Code)
+	}
+	exposed := recorder.Header().Get("Access-Control-Expose-Headers")
+	for _, header := range []string{"PAYMENT-REQUIRED", "PAYMENT-RESPONSE"} {
+		if !strings.Contains(exposed, header) {
+			t.Errorf("Expected %s to be exposed, got %q", header, exposed)
+		}
+	}
+
+	// Other origins get no CORS headers
+	req = httptest.NewRequest("GET", "/data", nil)
+	req.Header.Set("Origin", "https://evil.example.com")
+	recorder = httptest.NewRecorder()
+	router.ServeHTTP(recorder, req)
+	if origin := recorder.Header().Get("Access-Control-Allow-Origin"); origin != "" {
+		t.Errorf("Expected no allowed origin, got %q", origin)
+	}
+}
+
+func TestExemptMethods(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	tests := []struct {
+		name             string
+		method           string
+		freeHeadRequests bool
+		status           int
+	}{
+		{"options", "OPTIONS", false, http.StatusOK},
+		{"head", "HEAD", false, http.StatusPaymentRequired},
+		{"free head", "HEAD", true, http.StatusOK},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			router := gin.New()
+			router.Use(NewX402Middleware(&MiddlewareConfig{
+				FacilitatorURL: "http://localhost:4020",
+				DefaultRequirements: types.PaymentRequirements{
+					Scheme:  "exact",
+					Network: "eip155:84532",
+					Amount:  "1000",
+					Asset:   "0x036CbD53842c5426634e7929541eC2318f3dCF7e",
+					PayTo:   "0x3C44CdDdB6a900fa2b585dd299e03d12FA4293BC",
+				},
+				ProtectedPaths:   []string{"/data"},
+				FreeHeadRequests: tt.freeHeadRequests,
+			}).Handler())
+			router.Handle(tt.method, "/data", func(ctx *gin.Context) {
+				ctx.Status(http.StatusOK)
+			})
+
+			recorder := httptest.NewRecorder()
+			router.ServeHTTP(recorder, httptest.NewRequest(tt.method, "/data", nil))
+			if recorder.Code != tt.status {
+				t.Errorf("Expected status %d, got %d", tt.status, recorder.Code)
+			}
+		})
+	}
+}
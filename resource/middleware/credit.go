@@ -0,0 +1,195 @@
+package middleware
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"math/big"
+	"strings"
+	"sync"
+
+	"github.com/gin-gonic/gin"
+	"github.com/vorpalengineering/x402-go/types"
+)
+
+// CreditHeader carries the credit account of a client to and from the server
+const CreditHeader = "PAYMENT-CREDIT"
+
+// CreditBalanceHeader reports the account's remaining balance in the paid
+// asset, in atomic units
+const CreditBalanceHeader = "PAYMENT-CREDIT-BALANCE"
+
+// CreditAccountKey is the context key holding the credit account a request
+// was paid from
+const CreditAccountKey = "x402_credit_account"
+
+// CreditStore keeps prepaid balances. Keys identify an account's balance in
+// one asset on one network.
+type CreditStore interface {
+	// Add credits amount to key
+	Add(ctx context.Context, key string, amount *big.Int) (*big.Int, error)
+
+	// Spend deducts amount from key if its balance covers it, returning the
+	// remaining balance and whether it was deducted
+	Spend(ctx context.Context, key string, amount *big.Int) (*big.Int, bool, error)
+}
+
+// CreditConfig turns the part of an exact payment above the price into a
+// balance that later requests draw down instead of paying again
+type CreditConfig struct {
+	// Store keeps balances, e.g. NewMemoryCreditStore()
+	Store CreditStore `json:"-" toml:"-"`
+
+	// Routes limits credits to these path patterns. Empty uses credits on
+	// every protected path.
+	Routes []string `json:"routes,omitempty" toml:"routes"`
+}
+
+// creditKey identifies account's balance in the asset of requirements
+func creditKey(account string, requirements types.PaymentRequirements) string {
+	return account + "|" + requirements.Network + "|" + strings.ToLower(requirements.Asset)
+}
+
+// newCreditAccount returns a random, unguessable account ID
+func newCreditAccount() (string, error) {
+	var id [16]byte
+	if _, err := rand.Read(id[:]); err != nil {
+		return "", fmt.Errorf("failed to generate credit account: %w", err)
+	}
+	return hex.EncodeToString(id[:]), nil
+}
+
+// validCreditAccount reports whether account looks like an ID we issued
+func validCreditAccount(account string) bool {
+	if len(account) != 32 {
+		return false
+	}
+	_, err := hex.DecodeString(account)
+	return err == nil
+}
+
+// credits reports whether credits apply to route
+func (m *X402Middleware) credits(route string) bool {
+	return m.config.Credits != nil && (len(m.config.Credits.Routes) == 0 || matchesAny(m.config.Credits.Routes, route))
+}
+
+// spendCredits pays for a request from the presented credit account. Returns
+// false when the request must be paid for instead.
+func (m *X402Middleware) spendCredits(ctx *gin.Context, route string, accepts []types.PaymentRequirements) (bool, error) {
+	account := ctx.GetHeader(CreditHeader)
+	if !validCreditAccount(account) {
+		return false, nil
+	}
+
+	for _, requirements := range accepts {
+		if requirements.Scheme != "exact" {
+			continue
+		}
+		price, ok := new(big.Int).SetString(requirements.Amount, 10)
+		if !ok {
+			continue
+		}
+		key := creditKey(account, requirements)
+		balance, spent, err := m.config.Credits.Store.Spend(ctx.Request.Context(), key, price)
+		if err != nil {
+			return false, err
+		}
+		if !spent {
+			continue
+		}
+
+		ctx.Set(CreditAccountKey, account)
+		ctx.Set("x402_payment_requirements", requirements)
+		ctx.Header(CreditHeader, account)
+		ctx.Header(CreditBalanceHeader, balance.String())
+		ctx.Next()
+
+		// Give the credits back when the request fails, as a failed paid
+		// request is not settled
+		if status := ctx.Writer.Status(); status < 200 || status >= 300 {
+			if _, err := m.config.Credits.Store.Add(ctx.Request.Context(), key, price); err != nil {
+				m.logger.Error("failed to refund credits", "route", route, "error", err)
+			}
+		}
+		return true, nil
+	}
+
+	return false, nil
+}
+
+// creditOverpayment adds the part of a settled exact payment above the price
+// to the payer's credit account
+func (m *X402Middleware) creditOverpayment(ctx *gin.Context, payment *Payment) error {
+	if payment.exact == nil {
+		return nil
+	}
+	value, ok := new(big.Int).SetString(payment.exact.Authorization.Value, 10)
+	price, ok2 := new(big.Int).SetString(payment.Requirements.Amount, 10)
+	if !ok || !ok2 || value.Cmp(price) <= 0 {
+		return nil
+	}
+
+	// Top up the presented account, or open one
+	account := ctx.GetHeader(CreditHeader)
+	if !validCreditAccount(account) {
+		var err error
+		if account, err = newCreditAccount(); err != nil {
+			return err
+		}
+	}
+
+	balance, err := m.config.Credits.Store.Add(ctx.Request.Context(), creditKey(account, payment.Requirements), value.Sub(value, price))
+	if err != nil {
+		return err
+	}
+	ctx.Header(CreditHeader, account)
+	ctx.Header(CreditBalanceHeader, balance.String())
+	return nil
+}
+
+// MemoryCreditStore keeps balances in memory, lost on restart
+type MemoryCreditStore struct {
+	mu       sync.Mutex
+	balances map[string]*big.Int
+}
+
+func NewMemoryCreditStore() *MemoryCreditStore {
+	return &MemoryCreditStore{balances: make(map[string]*big.Int)}
+}
+
+func (s *MemoryCreditStore) Add(ctx context.Context, key string, amount *big.Int) (*big.Int, error) {
+	if amount.Sign() < 0 {
+		return nil, errors.New("cannot add a negative amount")
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	balance, ok := s.balances[key]
+	if !ok {
+		balance = new(big.Int)
+		s.balances[key] = balance
+	}
+	balance.Add(balance, amount)
+	return new(big.Int).Set(balance), nil
+}
+
+func (s *MemoryCreditStore) Spend(ctx context.Context, key string, amount *big.Int) (*big.Int, bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	balance, ok := s.balances[key]
+	if !ok {
+		return new(big.Int), false, nil
+	}
+	if balance.Cmp(amount) < 0 {
+		return new(big.Int).Set(balance), false, nil
+	}
+	balance.Sub(balance, amount)
+	remaining := new(big.Int).Set(balance)
+	if balance.Sign() == 0 {
+		delete(s.balances, key)
+	}
+	return remaining, true, nil
+}
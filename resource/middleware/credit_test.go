@@ -0,0 +1,96 @@
+package middleware
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/vorpalengineering/x402-go/types"
+)
+
+func TestCredits(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	settlements := 0
+	facilitator := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		switch r.URL.Path {
+		case "/verify":
+			json.NewEncoder(w).Encode(types.VerifyResponse{IsValid: true})
+		case "/settle":
+			settlements++
+			json.NewEncoder(w).Encode(types.SettleResponse{Success: true, Transaction: "0xabc"})
+		}
+	}))
+	defer facilitator.Close()
+
+	requirements := types.PaymentRequirements{
+		Scheme:  "exact",
+		Network: "eip155:84532",
+		Amount:  "1000",
+		Asset:   "0x036CbD53842c5426634e7929541eC2318f3dCF7e",
+		PayTo:   "0x3C44CdDdB6a900fa2b585dd299e03d12FA4293BC",
+	}
+	cfg := &MiddlewareConfig{
+		FacilitatorURL:      facilitator.URL,
+		DefaultRequirements: requirements,
+		ProtectedPaths:      []string{"/api/*"},
+		Credits:             &CreditConfig{Store: NewMemoryCreditStore()},
+	}
+	if err := cfg.Validate(); err != nil {
+		t.Fatalf("Expected valid config, got %v", err)
+	}
+	router := gin.New()
+	router.Use(NewX402Middleware(cfg).Handler())
+	router.GET("/api/data", func(ctx *gin.Context) {
+		ctx.String(http.StatusOK, "content")
+	})
+	router.GET("/api/fail", func(ctx *gin.Context) {
+		ctx.String(http.StatusInternalServerError, "failed")
+	})
+
+	request := func(path, header, value string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest("GET", path, nil)
+		req.Header.Set(header, value)
+		recorder := httptest.NewRecorder()
+		router.ServeHTTP(recorder, req)
+		return recorder
+	}
+
+	// Paying three times the price leaves credits for two more requests
+	paid := request("/api/data", "PAYMENT-SIGNATURE", paidHeaderWithValue(requirements, "3000"))
+	account := paid.Header().Get(CreditHeader)
+	if paid.Code != http.StatusOK || account == "" || paid.Header().Get(CreditBalanceHeader) != "2000" {
+		t.Fatalf("Expected credit account with 2000, got %d %v", paid.Code, paid.Header())
+	}
+
+	// Failed requests give their credits back
+	if res := request("/api/fail", CreditHeader, account); res.Code != http.StatusInternalServerError || res.Header().Get(CreditBalanceHeader) != "1000" {
+		t.Errorf("Expected failed request drawn from credits, got %d %v", res.Code, res.Header())
+	}
+
+	for _, expected := range []string{"1000", "0"} {
+		res := request("/api/data", CreditHeader, account)
+		if res.Code != http.StatusOK || res.Header().Get(CreditBalanceHeader) != expected {
+			t.Errorf("Expected balance %s, got %d %v", expected, res.Code, res.Header())
+		}
+	}
+	if settlements != 1 {
+		t.Errorf("Expected a single settlement, got %d", settlements)
+	}
+
+	// Exhausted and unknown accounts pay again
+	if res := request("/api/data", CreditHeader, account); res.Code != http.StatusPaymentRequired {
+		t.Errorf("Expected 402 once credits run out, got %d", res.Code)
+	}
+	if res := request("/api/data", CreditHeader, "0123456789abcdef0123456789abcdef"); res.Code != http.StatusPaymentRequired {
+		t.Errorf("Expected 402 for unknown account, got %d", res.Code)
+	}
+
+	// Paying the exact price opens no account
+	if res := request("/api/data", "PAYMENT-SIGNATURE", paidHeader(requirements)); res.Code != http.StatusOK || res.Header().Get(CreditHeader) != "" {
+		t.Errorf("Expected no credits for exact price, got %d %v", res.Code, res.Header())
+	}
+}
@@ -0,0 +1,112 @@
+package middleware
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"sync"
+
+	"github.com/vorpalengineering/x402-go/types"
+	"github.com/vorpalengineering/x402-go/utils"
+)
+
+// DefaultMaxPaymentHeaderSize caps the decoded payment header. A typical
+// exact scheme payload is well under 1 KB.
+const DefaultMaxPaymentHeaderSize = 16 * 1024
+
+// decodeBuffers holds the scratch space reused across requests
+type decodeBuffers struct {
+	src []byte
+	dst []byte
+}
+
+// rawPaymentPayload mirrors types.PaymentPayload but leaves the scheme
+// payload undecoded so it can be parsed into typed structs. v1 payloads
+// name their scheme and network at the top level instead of accepted.
+type rawPaymentPayload struct {
+	X402Version int                       `json:"x402Version"`
+	Resource    *types.ResourceInfo       `json:"resource,omitempty"`
+	Accepted    types.PaymentRequirements `json:"accepted"`
+	Scheme      string                    `json:"scheme,omitempty"`
+	Network     string                    `json:"network,omitempty"`
+	Payload     json.RawMessage           `json:"payload"`
+	Extensions  map[string]any            `json:"extensions,omitempty"`
+}
+
+// paymentHeaderDecoder decodes payment headers with pooled buffers and a
+// size cap, parsing exact scheme payloads straight into typed structs.
+type paymentHeaderDecoder struct {
+	maxSize int
+	pool    sync.Pool
+}
+
+func newPaymentHeaderDecoder(maxSize int) *paymentHeaderDecoder {
+	if maxSize <= 0 {
+		maxSize = DefaultMaxPaymentHeaderSize
+	}
+	return &paymentHeaderDecoder{
+		maxSize: maxSize,
+		pool: sync.Pool{
+			New: func() any { return &decodeBuffers{} },
+		},
+	}
+}
+
+// decode returns the payment payload and, for the exact scheme, its typed
+// form. exact is nil when the scheme is not exact or its payload is malformed.
+func (d *paymentHeaderDecoder) decode(header string) (payload *types.PaymentPayload, exact *types.ExactEVMSchemePayload, err error) {
+	// Reject oversized headers before decoding anything
+	if base64.StdEncoding.DecodedLen(len(header)) > d.maxSize {
+		return nil, nil, fmt.Errorf("payment header exceeds %d bytes", d.maxSize)
+	}
+
+	bufs := d.pool.Get().(*decodeBuffers)
+	defer d.pool.Put(bufs)
+
+	// Decode base64 into pooled buffer
+	bufs.src = append(bufs.src[:0], header...)
+	if need := base64.StdEncoding.DecodedLen(len(bufs.src)); cap(bufs.dst) < need {
+		bufs.dst = make([]byte, need)
+	}
+	n, err := base64.StdEncoding.Decode(bufs.dst[:cap(bufs.dst)], bufs.src)
+	if err != nil {
+		return nil, nil, fmt.Errorf("invalid base64: %w", err)
+	}
+
+	// Parse envelope, keeping the scheme payload raw
+	var raw rawPaymentPayload
+	if err := json.Unmarshal(bufs.dst[:n], &raw); err != nil {
+		return nil, nil, fmt.Errorf("invalid JSON: %w", err)
+	}
+	if raw.X402Version == types.X402VersionV1 {
+		raw.Accepted.Scheme = raw.Scheme
+		raw.Accepted.Network = utils.NetworkFromV1(raw.Network)
+	}
+	payload = &types.PaymentPayload{
+		X402Version: raw.X402Version,
+		Resource:    raw.Resource,
+		Accepted:    raw.Accepted,
+		Extensions:  raw.Extensions,
+	}
+	if len(raw.Payload) > 0 {
+		if err := json.Unmarshal(raw.Payload, &payload.Payload); err != nil {
+			return nil, nil, fmt.Errorf("invalid JSON: %w", err)
+		}
+	}
+
+	// Typed exact scheme payload
+	if raw.Accepted.Scheme == "exact" && len(raw.Payload) > 0 {
+		var exactPayload struct {
+			Signature     string                             `json:"signature"`
+			Authorization *types.ExactEVMSchemeAuthorization `json:"authorization"`
+		}
+		if err := json.Unmarshal(raw.Payload, &exactPayload); err == nil && exactPayload.Authorization != nil {
+			exact = &types.ExactEVMSchemePayload{
+				Signature:     exactPayload.Signature,
+				Authorization: *exactPayload.Authorization,
+			}
+		}
+	}
+
+	return payload, exact, nil
+}
@@ -0,0 +1,106 @@
+package middleware
+
+import (
+	"encoding/base64"
+	"strings"
+	"testing"
+
+	"github.com/vorpalengineering/x402-go/types"
+	"github.com/vorpalengineering/x402-go/utils"
+)
+
+func testPaymentHeader(t testing.TB) string {
+	header, err := utils.EncodePaymentHeader(&types.PaymentPayload{
+		X402Version: 2,
+		Accepted: types.PaymentRequirements{
+			Scheme:  "exact",
+			Network: "eip155:8453",
+			Amount:  "1000000",
+			PayTo:   "0x742d35Cc6634C0532925a3b844Bc9e7595f0bEb",
+			Asset:   "0x833589fCD6eDb6E08f4c7C32D4f71b54bdA02913",
+			Extra:   map[string]any{"name": "USD Coin", "version": "2"},
+		},
+		Payload: map[string]any{
+			"signature": "0x" + strings.Repeat("11", 65),
+			"authorization": map[string]any{
+				"from":        "0xf39Fd6e51aad88F6F4ce6aB8827279cffFb92266",
+				"to":          "0x742d35Cc6634C0532925a3b844Bc9e7595f0bEb",
+				"value":       "1000000",
+				"validAfter":  1700000000,
+				"validBefore": 1700003600,
+				"nonce":       "0x" + strings.Repeat("ab", 32),
+			},
+		},
+	})
+	if err != nil {
+		t.Fatalf("Failed to encode header: %v", err)
+	}
+	return header
+}
+
+func TestPaymentHeaderDecoder(t *testing.T) {
+	decoder := newPaymentHeaderDecoder(0)
+
+	payload, exact, err := decoder.decode(testPaymentHeader(t))
+	if err != nil {
+		t.Fatalf("Failed to decode header: %v", err)
+	}
+	if payload.Accepted.Network != "eip155:8453" {
+		t.Errorf("Expected network eip155:8453, got %s", payload.Accepted.Network)
+	}
+	if _, ok := payload.Payload["authorization"]; !ok {
+		t.Errorf("Expected authorization in payload map")
+	}
+	if exact == nil || exact.Authorization.ValidBefore != 1700003600 {
+		t.Errorf("Expected typed authorization with validBefore 1700003600, got %+v", exact)
+	}
+
+	// Oversized headers are rejected before decoding
+	small := newPaymentHeaderDecoder(64)
+	if _, _, err := small.decode(testPaymentHeader(t)); err == nil {
+		t.Errorf("Expected error for oversized header")
+	}
+	if _, _, err := decoder.decode(base64.StdEncoding.EncodeToString([]byte("{"))); err == nil {
+		t.Errorf("Expected error for invalid JSON")
+	}
+}
+
+// BenchmarkDecodePaymentHeader measures the previous decode path: generic
+// decode followed by a marshal round trip to extract the authorization.
+func BenchmarkDecodePaymentHeader(b *testing.B) {
+	header := testPaymentHeader(b)
+	b.ReportAllocs()
+	for b.Loop() {
+		payload, err := utils.DecodePaymentHeader(header)
+		if err != nil {
+			b.Fatal(err)
+		}
+		if _, err := utils.ExtractExactAuthorization(payload); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkPaymentHeaderDecoder(b *testing.B) {
+	header := testPaymentHeader(b)
+	decoder := newPaymentHeaderDecoder(0)
+	b.ReportAllocs()
+	for b.Loop() {
+		if _, _, err := decoder.decode(header); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkPaymentHeaderDecoderParallel(b *testing.B) {
+	header := testPaymentHeader(b)
+	decoder := newPaymentHeaderDecoder(0)
+	b.ReportAllocs()
+	b.RunParallel(func(pb *testing.PB) {
+		for pb.Next() {
+			if _, _, err := decoder.decode(header); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+}
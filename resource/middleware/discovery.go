@@ -0,0 +1,147 @@
+package middleware
+
+import (
+	"net/http"
+	"strings"
+	"sync"
+
+	"github.com/gin-gonic/gin"
+	"github.com/vorpalengineering/x402-go/types"
+)
+
+// DiscoveryDocument builds the /.well-known/x402 document for this middleware's
+// discoverable endpoints, including their payment requirements
+func (m *X402Middleware) DiscoveryDocument() *types.DiscoveryResponse {
+	discovery := &types.DiscoveryResponse{
+		Version:         1,
+		Resources:       make([]string, 0, len(m.config.DiscoverableEndpoints)),
+		OwnershipProofs: m.config.OwnershipProofs,
+		Instructions:    m.config.Instructions,
+		Contact:         m.config.Contact,
+	}
+
+	// Build full URLs from BaseURL + DiscoverableEndpoints
+	for _, endpoint := range m.config.DiscoverableEndpoints {
+		resourceURL := m.config.BaseURL + endpoint
+		discovery.Resources = append(discovery.Resources, resourceURL)
+
+		info := types.DiscoveryEndpoint{URL: resourceURL}
+		if m.isProtectedPath(endpoint) {
+			info.Accepts = m.getRequirements(endpoint)
+		}
+		if r, exists := m.config.RouteResources[endpoint]; exists && r != nil {
+			info.Description = r.Description
+			info.MimeType = r.MimeType
+		}
+		discovery.Endpoints = append(discovery.Endpoints, info)
+	}
+
+	return discovery
+}
+
+// MergeDiscovery combines discovery documents, e.g. from several routers
+// mounted on one server. Resources and proofs are de-duplicated, instructions
+// are joined and the first contact wins.
+func MergeDiscovery(docs ...*types.DiscoveryResponse) *types.DiscoveryResponse {
+	merged := &types.DiscoveryResponse{
+		Version:   1,
+		Resources: []string{},
+	}
+
+	seenResources := make(map[string]bool)
+	seenProofs := make(map[string]bool)
+	var instructions []string
+	for _, doc := range docs {
+		if doc == nil {
+			continue
+		}
+		if doc.Version > merged.Version {
+			merged.Version = doc.Version
+		}
+
+		for _, resource := range doc.Resources {
+			if !seenResources[resource] {
+				seenResources[resource] = true
+				merged.Resources = append(merged.Resources, resource)
+			}
+		}
+
+		// Keep the first description of each endpoint
+		for _, endpoint := range doc.Endpoints {
+			duplicate := false
+			for _, existing := range merged.Endpoints {
+				if existing.URL == endpoint.URL {
+					duplicate = true
+					break
+				}
+			}
+			if !duplicate {
+				merged.Endpoints = append(merged.Endpoints, endpoint)
+			}
+		}
+
+		for _, proof := range doc.OwnershipProofs {
+			if !seenProofs[proof] {
+				seenProofs[proof] = true
+				merged.OwnershipProofs = append(merged.OwnershipProofs, proof)
+			}
+		}
+
+		if doc.Instructions != "" {
+			instructions = append(instructions, doc.Instructions)
+		}
+		if merged.Contact == nil && doc.Contact != nil {
+			merged.Contact = doc.Contact
+		}
+	}
+	merged.Instructions = strings.Join(instructions, "\n\n")
+
+	return merged
+}
+
+// DiscoveryRegistry serves one /.well-known/x402 document for several
+// middlewares (e.g. one per router group) and static documents
+type DiscoveryRegistry struct {
+	mu          sync.RWMutex
+	middlewares []*X402Middleware
+	documents   []*types.DiscoveryResponse
+}
+
+func NewDiscoveryRegistry() *DiscoveryRegistry {
+	return &DiscoveryRegistry{}
+}
+
+// Add includes a middleware's discoverable endpoints in the merged document
+func (r *DiscoveryRegistry) Add(m *X402Middleware) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.middlewares = append(r.middlewares, m)
+}
+
+// AddDocument includes a fixed discovery document in the merged document
+func (r *DiscoveryRegistry) AddDocument(doc *types.DiscoveryResponse) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.documents = append(r.documents, doc)
+}
+
+// Document returns the merged discovery document
+func (r *DiscoveryRegistry) Document() *types.DiscoveryResponse {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	docs := make([]*types.DiscoveryResponse, 0, len(r.middlewares)+len(r.documents))
+	for _, m := range r.middlewares {
+		docs = append(docs, m.DiscoveryDocument())
+	}
+	docs = append(docs, r.documents...)
+
+	return MergeDiscovery(docs...)
+}
+
+// Handler serves the merged document. Mount it at /.well-known/x402.
+func (r *DiscoveryRegistry) Handler() gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		ctx.JSON(http.StatusOK, r.Document())
+	}
+}
@@ -0,0 +1,60 @@
+package middleware
+
+import (
+	"testing"
+
+	"github.com/vorpalengineering/x402-go/types"
+)
+
+func TestDiscoveryRegistry(t *testing.T) {
+	requirements := types.PaymentRequirements{
+		Scheme:  "exact",
+		Network: "eip155:8453",
+		Amount:  "1000000",
+		PayTo:   "0x742d35Cc6634C0532925a3b844Bc9e7595f0bEb",
+		Asset:   "0x833589fCD6eDb6E08f4c7C32D4f71b54bdA02913",
+	}
+
+	// Two routers mounted on the same server
+	api := NewX402Middleware(&MiddlewareConfig{
+		DefaultRequirements:   requirements,
+		ProtectedPaths:        []string{"/api/*"},
+		BaseURL:               "https://example.com",
+		DiscoverableEndpoints: []string{"/api/data"},
+		RouteResources: map[string]*types.ResourceInfo{
+			"/api/data": {Description: "Data feed", MimeType: "application/json"},
+		},
+		OwnershipProofs: []string{"0xproof"},
+		Instructions:    "API instructions",
+		Contact:         &types.DiscoveryContact{Email: "ops@example.com"},
+	})
+	files := NewX402Middleware(&MiddlewareConfig{
+		DefaultRequirements:   requirements,
+		ProtectedPaths:        []string{"/files/*"},
+		BaseURL:               "https://example.com",
+		DiscoverableEndpoints: []string{"/files/report", "/api/data"},
+		OwnershipProofs:       []string{"0xproof"},
+		Instructions:          "File instructions",
+	})
+
+	registry := NewDiscoveryRegistry()
+	registry.Add(api)
+	registry.Add(files)
+	doc := registry.Document()
+
+	if len(doc.Resources) != 2 {
+		t.Errorf("Expected 2 de-duplicated resources, got %v", doc.Resources)
+	}
+	if len(doc.OwnershipProofs) != 1 {
+		t.Errorf("Expected 1 de-duplicated proof, got %v", doc.OwnershipProofs)
+	}
+	if doc.Instructions != "API instructions\n\nFile instructions" {
+		t.Errorf("Expected joined instructions, got %q", doc.Instructions)
+	}
+	if doc.Contact == nil || doc.Contact.Email != "ops@example.com" {
+		t.Errorf("Expected contact from first document, got %+v", doc.Contact)
+	}
+	if len(doc.Endpoints) != 2 || doc.Endpoints[0].Description != "Data feed" || len(doc.Endpoints[0].Accepts) != 1 {
+		t.Errorf("Expected endpoint details with requirements, got %+v", doc.Endpoints)
+	}
+}
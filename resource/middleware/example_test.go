@@ -0,0 +1,78 @@
+package middleware_test
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"net/http/httptest"
+
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/gin-gonic/gin"
+	"github.com/vorpalengineering/x402-go/resource/client"
+	"github.com/vorpalengineering/x402-go/resource/middleware"
+	"github.com/vorpalengineering/x402-go/types"
+)
+
+func ExampleNewX402Middleware() {
+	gin.SetMode(gin.ReleaseMode)
+
+	// Stand-in facilitator that accepts every payment
+	facilitator := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/verify":
+			json.NewEncoder(w).Encode(types.VerifyResponse{IsValid: true})
+		case "/settle":
+			json.NewEncoder(w).Encode(types.SettleResponse{Success: true, Transaction: "0xabc", Network: "eip155:8453"})
+		}
+	}))
+	defer facilitator.Close()
+
+	// Protect /api/* with a 1 USDC price
+	x402 := middleware.NewX402Middleware(&middleware.MiddlewareConfig{
+		FacilitatorURL: facilitator.URL,
+		ProtectedPaths: []string{"/api/*"},
+		DefaultRequirements: types.PaymentRequirements{
+			Scheme:            "exact",
+			Network:           "eip155:8453",
+			Amount:            "1000000",
+			PayTo:             "0x70997970C51812dc3A010C7d01b50e0d17dc79C8",
+			Asset:             "0x833589fCD6eDb6E08f4c7C32D4f71b54bdA02913",
+			MaxTimeoutSeconds: 60,
+			Extra:             map[string]any{"name": "USD Coin", "version": "2"},
+		},
+	})
+
+	router := gin.New()
+	router.Use(x402.Handler())
+	router.GET("/api/data", func(ctx *gin.Context) {
+		ctx.String(http.StatusOK, "paid content")
+	})
+	server := httptest.NewServer(router)
+	defer server.Close()
+
+	// Unpaid requests get the payment requirements
+	privateKey, err := crypto.HexToECDSA("ac0974bec39a17e36ba4a6b4d238ff944bacb478cbed5efcae784d7bf4f2ff80")
+	if err != nil {
+		log.Fatal(err)
+	}
+	rc := client.NewResourceClient(privateKey)
+	resp, paymentRequired, err := rc.Check("GET", server.URL+"/api/data", "", nil)
+	if err != nil {
+		log.Fatal(err)
+	}
+	resp.Body.Close()
+	fmt.Println(resp.StatusCode, paymentRequired.Accepts[0].Amount)
+
+	// Paid requests are verified, served and settled
+	resp, err = rc.Pay("GET", server.URL+"/api/data", "", nil, &paymentRequired.Accepts[0])
+	if err != nil {
+		log.Fatal(err)
+	}
+	resp.Body.Close()
+	fmt.Println(resp.StatusCode, resp.Header.Get("PAYMENT-RESPONSE") != "")
+
+	// Output:
+	// 402 1000000
+	// 200 true
+}
@@ -0,0 +1,116 @@
+package middleware
+
+import (
+	"crypto/subtle"
+	"errors"
+	"log/slog"
+	"strings"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/gin-gonic/gin"
+)
+
+// FreeAccessKey is the context key holding the *FreeAccess of a request
+// served without payment
+const FreeAccessKey = "x402_free_access"
+
+// DefaultAPIKeyHeader carries API keys when FreeAccessConfig.APIKeyHeader is unset
+const DefaultAPIKeyHeader = "X-API-Key"
+
+// Free access reasons
+const (
+	FreeAccessAPIKey = "api_key"
+	FreeAccessPayer  = "payer"
+)
+
+// FreeAccessConfig lets internal services, monitoring and partners use
+// protected routes without paying
+type FreeAccessConfig struct {
+	// APIKeys maps keys accepted in the APIKeyHeader to the client name
+	// recorded in the context and logs
+	APIKeys map[string]string `json:"apiKeys,omitempty" toml:"api_keys"`
+
+	// APIKeyHeader carries API keys. Empty uses DefaultAPIKeyHeader.
+	APIKeyHeader string `json:"apiKeyHeader,omitempty" toml:"api_key_header"`
+
+	// Payers are addresses whose payments are verified, proving the payer
+	// signed them, but never settled
+	Payers []string `json:"payers,omitempty" toml:"payers"`
+
+	// Routes limits free access to these path patterns. Empty allows it on
+	// every protected path.
+	Routes []string `json:"routes,omitempty" toml:"routes"`
+}
+
+func (c *FreeAccessConfig) validate() error {
+	for key, name := range c.APIKeys {
+		if key == "" {
+			return errors.New("API key for " + name + " cannot be empty")
+		}
+	}
+	for _, payer := range c.Payers {
+		if !common.IsHexAddress(payer) {
+			return errors.New("invalid payer address: " + payer)
+		}
+	}
+	return nil
+}
+
+// FreeAccess records why a request was served without payment
+type FreeAccess struct {
+	// Reason is FreeAccessAPIKey or FreeAccessPayer
+	Reason string
+
+	// Client is the API key's name or the payer's address
+	Client string
+}
+
+// freeAccess returns the free access granted to a request by its API key
+func (m *X402Middleware) freeAccess(ctx *gin.Context, route string) *FreeAccess {
+	cfg := m.config.FreeAccess
+	if cfg == nil || len(cfg.APIKeys) == 0 || !m.freeRoute(route) {
+		return nil
+	}
+	header := cfg.APIKeyHeader
+	if header == "" {
+		header = DefaultAPIKeyHeader
+	}
+	presented := ctx.GetHeader(header)
+	if presented == "" {
+		return nil
+	}
+
+	// Compare every key in constant time
+	var access *FreeAccess
+	for key, name := range cfg.APIKeys {
+		if subtle.ConstantTimeCompare([]byte(presented), []byte(key)) == 1 {
+			access = &FreeAccess{Reason: FreeAccessAPIKey, Client: name}
+		}
+	}
+	return access
+}
+
+// freePayer returns the free access granted to a verified payment's payer
+func (m *X402Middleware) freePayer(route, payer string) *FreeAccess {
+	cfg := m.config.FreeAccess
+	if cfg == nil || payer == "" || !m.freeRoute(route) {
+		return nil
+	}
+	for _, allowed := range cfg.Payers {
+		if strings.EqualFold(allowed, payer) {
+			return &FreeAccess{Reason: FreeAccessPayer, Client: payer}
+		}
+	}
+	return nil
+}
+
+func (m *X402Middleware) freeRoute(route string) bool {
+	return len(m.config.FreeAccess.Routes) == 0 || matchesAny(m.config.FreeAccess.Routes, route)
+}
+
+// serveFree runs the handler without payment
+func (m *X402Middleware) serveFree(ctx *gin.Context, logger *slog.Logger, access *FreeAccess) {
+	ctx.Set(FreeAccessKey, access)
+	logger.Info("free access granted", "reason", access.Reason, "client", access.Client)
+	ctx.Next()
+}
@@ -0,0 +1,114 @@
+package middleware
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/vorpalengineering/x402-go/types"
+)
+
+func TestFreeAccess(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	var calls []string
+	facilitator := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls = append(calls, r.URL.Path)
+		w.Header().Set("Content-Type", "application/json")
+		switch r.URL.Path {
+		case "/verify":
+			json.NewEncoder(w).Encode(types.VerifyResponse{IsValid: true, Payer: "0x70997970C51812dc3A010C7d01b50e0d17dc79C8"})
+		case "/settle":
+			json.NewEncoder(w).Encode(types.SettleResponse{Success: true, Transaction: "0xabc"})
+		}
+	}))
+	defer facilitator.Close()
+
+	requirements := types.PaymentRequirements{
+		Scheme:  "exact",
+		Network: "eip155:84532",
+		Amount:  "1000",
+		Asset:   "0x036CbD53842c5426634e7929541eC2318f3dCF7e",
+		PayTo:   "0x3C44CdDdB6a900fa2b585dd299e03d12FA4293BC",
+	}
+	cfg := &MiddlewareConfig{
+		FacilitatorURL:      facilitator.URL,
+		DefaultRequirements: requirements,
+		ProtectedPaths:      []string{"/api/*", "/other"},
+		FreeAccess: &FreeAccessConfig{
+			APIKeys: map[string]string{"monitoring-key": "monitoring"},
+			Payers:  []string{"0x70997970c51812dc3a010c7d01b50e0d17dc79c8"},
+			Routes:  []string{"/api/*"},
+		},
+	}
+	if err := cfg.Validate(); err != nil {
+		t.Fatalf("Expected valid config, got %v", err)
+	}
+	router := gin.New()
+	router.Use(NewX402Middleware(cfg).Handler())
+	handler := func(ctx *gin.Context) {
+		if value, ok := ctx.Get(FreeAccessKey); ok {
+			access := value.(*FreeAccess)
+			ctx.String(http.StatusOK, "free "+access.Reason+" "+access.Client)
+			return
+		}
+		ctx.String(http.StatusOK, "paid")
+	}
+	router.GET("/api/data", handler)
+	router.GET("/other", handler)
+
+	tests := []struct {
+		name          string
+		path          string
+		headers       map[string]string
+		expectedCode  int
+		expectedBody  string
+		expectedCalls int
+	}{
+		{"api key", "/api/data", map[string]string{DefaultAPIKeyHeader: "monitoring-key"}, http.StatusOK, "free api_key monitoring", 0},
+		{"unknown api key", "/api/data", map[string]string{DefaultAPIKeyHeader: "other-key"}, http.StatusPaymentRequired, "", 0},
+		{"api key outside routes", "/other", map[string]string{DefaultAPIKeyHeader: "monitoring-key"}, http.StatusPaymentRequired, "", 0},
+		{"allowlisted payer", "/api/data", map[string]string{"PAYMENT-SIGNATURE": paidHeader(requirements)}, http.StatusOK, "free payer 0x70997970C51812dc3A010C7d01b50e0d17dc79C8", 1},
+		{"payer outside routes", "/other", map[string]string{"PAYMENT-SIGNATURE": paidHeader(requirements)}, http.StatusOK, "paid", 2},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			calls = nil
+			req := httptest.NewRequest("GET", tt.path, nil)
+			for name, value := range tt.headers {
+				req.Header.Set(name, value)
+			}
+			recorder := httptest.NewRecorder()
+			router.ServeHTTP(recorder, req)
+
+			if recorder.Code != tt.expectedCode {
+				t.Fatalf("Expected status %d, got %d: %s", tt.expectedCode, recorder.Code, recorder.Body.String())
+			}
+			if tt.expectedBody != "" && recorder.Body.String() != tt.expectedBody {
+				t.Errorf("Expected body %q, got %q", tt.expectedBody, recorder.Body.String())
+			}
+			if len(calls) != tt.expectedCalls {
+				t.Errorf("Expected %d facilitator calls, got %v", tt.expectedCalls, calls)
+			}
+		})
+	}
+}
+
+func TestFreeAccessValidation(t *testing.T) {
+	tests := []struct {
+		name   string
+		config FreeAccessConfig
+	}{
+		{"empty api key", FreeAccessConfig{APIKeys: map[string]string{"": "monitoring"}}},
+		{"invalid payer", FreeAccessConfig{Payers: []string{"not-an-address"}}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := tt.config.validate(); err == nil {
+				t.Error("Expected validation error")
+			}
+		})
+	}
+}
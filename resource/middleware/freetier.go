@@ -0,0 +1,142 @@
+package middleware
+
+import (
+	"context"
+	"errors"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// FreeTierRemainingHeader reports how many free requests are left in the
+// current window
+const FreeTierRemainingHeader = "PAYMENT-FREE-REMAINING"
+
+// FreeAccessFreeTier is the free access reason of requests within the free tier
+const FreeAccessFreeTier = "free_tier"
+
+// Free tier client identification
+const (
+	FreeTierByIP    = "ip"
+	FreeTierByPayer = "payer"
+)
+
+// FreeTierStore counts free requests
+type FreeTierStore interface {
+	// Increment counts a request against key, forgotten at expiresAt, and
+	// returns the count including it
+	Increment(ctx context.Context, key string, expiresAt time.Time) (int, error)
+}
+
+// FreeTierConfig serves a number of requests per client and window for free
+// before asking for payment
+type FreeTierConfig struct {
+	// Store counts requests, e.g. NewMemoryFreeTierStore()
+	Store FreeTierStore `json:"-" toml:"-"`
+
+	// By identifies clients by FreeTierByIP (default) or FreeTierByPayer.
+	// Payers still send a payment, which is verified but not settled while
+	// they are within the free tier.
+	By string `json:"by,omitempty" toml:"by"`
+
+	// Requests is the number of free requests per window
+	Requests int `json:"requests" toml:"requests"`
+
+	// Window is the quota period. 0 is a day.
+	Window time.Duration `json:"window,omitempty" toml:"window"`
+
+	// Routes limits the free tier to these path patterns. Empty applies it
+	// to every protected path.
+	Routes []string `json:"routes,omitempty" toml:"routes"`
+}
+
+func (c *FreeTierConfig) validate() error {
+	if c.Store == nil {
+		return errors.New("free tier requires a store")
+	}
+	if c.By != "" && c.By != FreeTierByIP && c.By != FreeTierByPayer {
+		return errors.New("invalid free tier client: " + c.By + " (must be ip or payer)")
+	}
+	if c.Requests <= 0 {
+		return errors.New("free tier requests must be positive")
+	}
+	if c.Window < 0 {
+		return errors.New("free tier window cannot be negative")
+	}
+	return nil
+}
+
+// freeTier reports whether the free tier identifies clients by and applies to route
+func (m *X402Middleware) freeTier(by, route string) bool {
+	cfg := m.config.FreeTier
+	if cfg == nil {
+		return false
+	}
+	if cfg.By != by && !(cfg.By == "" && by == FreeTierByIP) {
+		return false
+	}
+	return len(cfg.Routes) == 0 || matchesAny(cfg.Routes, route)
+}
+
+// takeFreeRequest counts a request of client against the free tier,
+// returning the free access granted if it is within it
+func (m *X402Middleware) takeFreeRequest(ctx *gin.Context, client string) (*FreeAccess, error) {
+	cfg := m.config.FreeTier
+	window := cfg.Window
+	if window == 0 {
+		window = 24 * time.Hour
+	}
+
+	// Count per fixed window, so every client's quota resets together
+	start := time.Now().Truncate(window)
+	key := strings.ToLower(client) + "|" + strconv.FormatInt(start.Unix(), 10)
+	count, err := cfg.Store.Increment(ctx.Request.Context(), key, start.Add(window))
+	if err != nil {
+		return nil, err
+	}
+	ctx.Header(FreeTierRemainingHeader, strconv.Itoa(max(cfg.Requests-count, 0)))
+	if count > cfg.Requests {
+		return nil, nil
+	}
+	return &FreeAccess{Reason: FreeAccessFreeTier, Client: client}, nil
+}
+
+// MemoryFreeTierStore counts requests in memory, lost on restart
+type MemoryFreeTierStore struct {
+	mu      sync.Mutex
+	counts  map[string]freeTierCount
+	pruneAt time.Time
+}
+
+type freeTierCount struct {
+	requests  int
+	expiresAt time.Time
+}
+
+func NewMemoryFreeTierStore() *MemoryFreeTierStore {
+	return &MemoryFreeTierStore{counts: make(map[string]freeTierCount)}
+}
+
+func (s *MemoryFreeTierStore) Increment(ctx context.Context, key string, expiresAt time.Time) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	// Forget counts of past windows, at most once a minute
+	if now := time.Now(); !now.Before(s.pruneAt) {
+		for k, count := range s.counts {
+			if !now.Before(count.expiresAt) {
+				delete(s.counts, k)
+			}
+		}
+		s.pruneAt = now.Add(time.Minute)
+	}
+
+	count := s.counts[key]
+	count.requests++
+	count.expiresAt = expiresAt
+	s.counts[key] = count
+	return count.requests, nil
+}
@@ -0,0 +1,114 @@
+package middleware
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/vorpalengineering/x402-go/types"
+)
+
+func TestFreeTier(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	settlements := 0
+	facilitator := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		switch r.URL.Path {
+		case "/verify":
+			json.NewEncoder(w).Encode(types.VerifyResponse{IsValid: true, Payer: "0x70997970C51812dc3A010C7d01b50e0d17dc79C8"})
+		case "/settle":
+			settlements++
+			json.NewEncoder(w).Encode(types.SettleResponse{Success: true, Transaction: "0xabc"})
+		}
+	}))
+	defer facilitator.Close()
+
+	requirements := types.PaymentRequirements{
+		Scheme:  "exact",
+		Network: "eip155:84532",
+		Amount:  "1000",
+		Asset:   "0x036CbD53842c5426634e7929541eC2318f3dCF7e",
+		PayTo:   "0x3C44CdDdB6a900fa2b585dd299e03d12FA4293BC",
+	}
+
+	tests := []struct {
+		name    string
+		by      string
+		headers map[string]string
+		remote  string
+		// expected status of each request
+		expectedCodes       []int
+		expectedSettlements int
+	}{
+		{"per ip", FreeTierByIP, nil, "192.0.2.1:1234", []int{200, 200, 402}, 0},
+		{"per ip paid after quota", "", map[string]string{"PAYMENT-SIGNATURE": paidHeader(requirements)}, "192.0.2.2:1234", []int{200, 200, 200}, 1},
+		{"per payer", FreeTierByPayer, map[string]string{"PAYMENT-SIGNATURE": paidHeader(requirements)}, "192.0.2.3:1234", []int{200, 200, 200}, 1},
+		{"per payer without payment", FreeTierByPayer, nil, "192.0.2.4:1234", []int{402}, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			settlements = 0
+			cfg := &MiddlewareConfig{
+				FacilitatorURL:      facilitator.URL,
+				DefaultRequirements: requirements,
+				ProtectedPaths:      []string{"/data"},
+				FreeTier: &FreeTierConfig{
+					Store:    NewMemoryFreeTierStore(),
+					By:       tt.by,
+					Requests: 2,
+				},
+			}
+			if err := cfg.Validate(); err != nil {
+				t.Fatalf("Expected valid config, got %v", err)
+			}
+			router := gin.New()
+			router.Use(NewX402Middleware(cfg).Handler())
+			router.GET("/data", func(ctx *gin.Context) {
+				ctx.String(http.StatusOK, "data")
+			})
+
+			for i, expected := range tt.expectedCodes {
+				req := httptest.NewRequest("GET", "/data", nil)
+				req.RemoteAddr = tt.remote
+				for name, value := range tt.headers {
+					req.Header.Set(name, value)
+				}
+				recorder := httptest.NewRecorder()
+				router.ServeHTTP(recorder, req)
+				if recorder.Code != expected {
+					t.Errorf("Request %d: expected status %d, got %d", i+1, expected, recorder.Code)
+				}
+			}
+			if settlements != tt.expectedSettlements {
+				t.Errorf("Expected %d settlements, got %d", tt.expectedSettlements, settlements)
+			}
+		})
+	}
+}
+
+func TestMemoryFreeTierStore(t *testing.T) {
+	store := NewMemoryFreeTierStore()
+	ctx := context.Background()
+
+	for i := 1; i <= 3; i++ {
+		if count, _ := store.Increment(ctx, "a", time.Now().Add(time.Hour)); count != i {
+			t.Errorf("Expected count %d, got %d", i, count)
+		}
+	}
+	if count, _ := store.Increment(ctx, "b", time.Now().Add(time.Hour)); count != 1 {
+		t.Errorf("Expected separate count per key, got %d", count)
+	}
+
+	// Expired counts are forgotten
+	store.counts["old"] = freeTierCount{requests: 5, expiresAt: time.Now().Add(-time.Second)}
+	store.pruneAt = time.Time{}
+	store.Increment(ctx, "a", time.Now().Add(time.Hour))
+	if _, exists := store.counts["old"]; exists {
+		t.Error("Expected expired count to be pruned")
+	}
+}
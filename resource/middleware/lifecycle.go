@@ -0,0 +1,71 @@
+package middleware
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// RouteDeprecation describes the retirement schedule of a priced route
+type RouteDeprecation struct {
+	// DeprecatedAt is when the route was deprecated, sent in the Deprecation header
+	DeprecatedAt time.Time `json:"deprecatedAt,omitempty" toml:"deprecated_at"`
+
+	// Sunset is when the route stops accepting payments, sent in the Sunset header.
+	// After this time the route responds 410 Gone without charging.
+	Sunset time.Time `json:"sunset" toml:"sunset"`
+
+	// Link points clients at a replacement resource or migration guide
+	Link string `json:"link,omitempty" toml:"link"`
+
+	// Message is returned in the 410 response body after sunset
+	Message string `json:"message,omitempty" toml:"message"`
+}
+
+func (m *X402Middleware) getDeprecation(path string) (RouteDeprecation, bool) {
+	return bestRoute(m.config.DeprecatedRoutes, path)
+}
+
+// applyDeprecation sets lifecycle headers for deprecated routes and responds
+// 410 Gone once the sunset has passed. Returns true if the request was handled.
+func (m *X402Middleware) applyDeprecation(ctx *gin.Context, path string) bool {
+	dep, ok := m.getDeprecation(path)
+	if !ok {
+		return false
+	}
+
+	// Route is archived, refuse without charging
+	if !dep.Sunset.IsZero() && !time.Now().Before(dep.Sunset) {
+		body := gin.H{
+			"error":  "This resource has been retired",
+			"sunset": dep.Sunset.UTC().Format(time.RFC3339),
+		}
+		if dep.Message != "" {
+			body["message"] = dep.Message
+		}
+		if dep.Link != "" {
+			body["link"] = dep.Link
+			ctx.Header("Link", "<"+dep.Link+">; rel=\"successor-version\"")
+		}
+		ctx.JSON(http.StatusGone, body)
+		ctx.Abort()
+		return true
+	}
+
+	// Route is deprecated but still served
+	if !dep.DeprecatedAt.IsZero() {
+		ctx.Header("Deprecation", "@"+strconv.FormatInt(dep.DeprecatedAt.Unix(), 10))
+	} else {
+		ctx.Header("Deprecation", "true")
+	}
+	if !dep.Sunset.IsZero() {
+		ctx.Header("Sunset", dep.Sunset.UTC().Format(http.TimeFormat))
+	}
+	if dep.Link != "" {
+		ctx.Header("Link", "<"+dep.Link+">; rel=\"deprecation\"")
+	}
+
+	return false
+}
@@ -0,0 +1,114 @@
+package middleware
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/vorpalengineering/x402-go/types"
+)
+
+func TestRouteDeprecation(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	var settleCalls atomic.Int32
+	facilitator := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		switch r.URL.Path {
+		case "/verify":
+			json.NewEncoder(w).Encode(types.VerifyResponse{IsValid: true})
+		case "/settle":
+			settleCalls.Add(1)
+			json.NewEncoder(w).Encode(types.SettleResponse{Success: true, Transaction: "0xabc"})
+		}
+	}))
+	defer facilitator.Close()
+
+	requirements := types.PaymentRequirements{
+		Scheme:  "exact",
+		Network: "eip155:84532",
+		Amount:  "1000",
+		Asset:   "0x036CbD53842c5426634e7929541eC2318f3dCF7e",
+		PayTo:   "0x3C44CdDdB6a900fa2b585dd299e03d12FA4293BC",
+	}
+	deprecatedAt := time.Now().Add(-24 * time.Hour).Truncate(time.Second)
+	sunset := time.Now().Add(24 * time.Hour).Truncate(time.Second)
+	retiredAt := time.Now().Add(-time.Hour).Truncate(time.Second)
+
+	cfg := &MiddlewareConfig{
+		FacilitatorURL:      facilitator.URL,
+		DefaultRequirements: requirements,
+		ProtectedPaths:      []string{"/*"},
+		DeprecatedRoutes: map[string]RouteDeprecation{
+			"/old":     {DeprecatedAt: deprecatedAt, Sunset: sunset, Link: "https://example.com/new"},
+			"/legacy":  {},
+			"/retired": {Sunset: retiredAt, Link: "https://example.com/new", Message: "Use /new"},
+		},
+	}
+	if err := cfg.Validate(); err != nil {
+		t.Fatalf("Expected valid config, got %v", err)
+	}
+	router := gin.New()
+	router.Use(NewX402Middleware(cfg).Handler())
+	router.GET("/:name", func(ctx *gin.Context) {
+		ctx.String(http.StatusOK, "data")
+	})
+
+	tests := []struct {
+		name        string
+		path        string
+		paid        bool
+		status      int
+		deprecation string
+		sunset      string
+		link        string
+		settleCalls int32
+	}{
+		{"deprecated unpaid", "/old", false, http.StatusPaymentRequired, "@" + strconv.FormatInt(deprecatedAt.Unix(), 10), sunset.UTC().Format(http.TimeFormat), `<https://example.com/new>; rel="deprecation"`, 0},
+		{"deprecated paid", "/old", true, http.StatusOK, "@" + strconv.FormatInt(deprecatedAt.Unix(), 10), sunset.UTC().Format(http.TimeFormat), `<https://example.com/new>; rel="deprecation"`, 1},
+		{"deprecated without schedule", "/legacy", true, http.StatusOK, "true", "", "", 1},
+		{"after sunset", "/retired", true, http.StatusGone, "", "", `<https://example.com/new>; rel="successor-version"`, 0},
+		{"not deprecated", "/current", true, http.StatusOK, "", "", "", 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			settleCalls.Store(0)
+			req := httptest.NewRequest("GET", tt.path, nil)
+			if tt.paid {
+				req.Header.Set("PAYMENT-SIGNATURE", paidHeader(requirements))
+			}
+			recorder := httptest.NewRecorder()
+			router.ServeHTTP(recorder, req)
+
+			if recorder.Code != tt.status {
+				t.Fatalf("Expected status %d, got %d: %s", tt.status, recorder.Code, recorder.Body.String())
+			}
+			if got := recorder.Header().Get("Deprecation"); got != tt.deprecation {
+				t.Errorf("Expected Deprecation %q, got %q", tt.deprecation, got)
+			}
+			if got := recorder.Header().Get("Sunset"); got != tt.sunset {
+				t.Errorf("Expected Sunset %q, got %q", tt.sunset, got)
+			}
+			if got := recorder.Header().Get("Link"); got != tt.link {
+				t.Errorf("Expected Link %q, got %q", tt.link, got)
+			}
+			if calls := settleCalls.Load(); calls != tt.settleCalls {
+				t.Errorf("Expected %d settle calls, got %d", tt.settleCalls, calls)
+			}
+		})
+	}
+
+	// Retired routes explain themselves
+	recorder := httptest.NewRecorder()
+	router.ServeHTTP(recorder, httptest.NewRequest("GET", "/retired", nil))
+	var body map[string]string
+	json.Unmarshal(recorder.Body.Bytes(), &body)
+	if body["message"] != "Use /new" || body["link"] != "https://example.com/new" || body["sunset"] != retiredAt.UTC().Format(time.RFC3339) {
+		t.Errorf("Expected retirement details, got %v", body)
+	}
+}
@@ -0,0 +1,74 @@
+package middleware
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// RouteLimit overrides how much of a route's response is buffered and bounds
+// how long its handler may run
+type RouteLimit struct {
+	// MaxBufferSize replaces MaxBufferSize for the route. 0 keeps
+	// MaxBufferSize, a negative size lifts the limit.
+	MaxBufferSize int `json:"maxBufferSize,omitempty" toml:"max_buffer_size"`
+
+	// Timeout cancels the request context of the handler once it passes.
+	// Routes settling after the handler then respond 504 without settling.
+	// 0 leaves the handler unbounded.
+	//
+	// The timeout is cooperative: the handler is not interrupted, and the
+	// response waits until it returns. Handlers must watch
+	// ctx.Request.Context() to stop early. Writes made after the deadline
+	// by handlers ignoring it are dropped.
+	Timeout time.Duration `json:"timeout,omitempty" toml:"timeout"`
+}
+
+func (l RouteLimit) validate() error {
+	if l.Timeout < 0 {
+		return errors.New("timeout cannot be negative")
+	}
+	return nil
+}
+
+// maxBufferSize returns how many bytes of route's response may be buffered,
+// 0 for no limit
+func (m *X402Middleware) maxBufferSize(route string) int {
+	limit, exists := bestRoute(m.config.RouteLimits, route)
+	if !exists || limit.MaxBufferSize == 0 {
+		return m.config.MaxBufferSize
+	}
+	return max(limit.MaxBufferSize, 0)
+}
+
+// next runs the handler, bounded by route's timeout if it has one. Handlers
+// see the deadline on ctx.Request.Context(), and a buffered response drops
+// their writes once it passes. It reports whether the handler ran past its
+// deadline.
+func (m *X402Middleware) next(ctx *gin.Context, route string) bool {
+	limit, exists := bestRoute(m.config.RouteLimits, route)
+	if !exists || limit.Timeout == 0 {
+		ctx.Next()
+		return false
+	}
+
+	parent := ctx.Request.Context()
+	timeoutCtx, cancel := context.WithTimeout(parent, limit.Timeout)
+	defer cancel()
+	ctx.Request = ctx.Request.WithContext(timeoutCtx)
+	if buffered, ok := ctx.Writer.(*bufferedWriter); ok {
+		stop := context.AfterFunc(timeoutCtx, func() {
+			if errors.Is(timeoutCtx.Err(), context.DeadlineExceeded) {
+				buffered.expire()
+			}
+		})
+		defer stop()
+	}
+	ctx.Next()
+
+	// Later work on the request, e.g. releasing the payment, is not bounded
+	ctx.Request = ctx.Request.WithContext(parent)
+	return errors.Is(timeoutCtx.Err(), context.DeadlineExceeded)
+}
@@ -0,0 +1,164 @@
+package middleware
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/vorpalengineering/x402-go/types"
+)
+
+func TestRouteLimits(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	var settleCalls atomic.Int32
+	facilitator := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		switch r.URL.Path {
+		case "/verify":
+			json.NewEncoder(w).Encode(types.VerifyResponse{IsValid: true})
+		case "/settle":
+			settleCalls.Add(1)
+			json.NewEncoder(w).Encode(types.SettleResponse{Success: true, Transaction: "0xabc"})
+		}
+	}))
+	defer facilitator.Close()
+
+	requirements := types.PaymentRequirements{
+		Scheme:  "exact",
+		Network: "eip155:84532",
+		Amount:  "1000",
+		Asset:   "0x036CbD53842c5426634e7929541eC2318f3dCF7e",
+		PayTo:   "0x3C44CdDdB6a900fa2b585dd299e03d12FA4293BC",
+	}
+	cfg := &MiddlewareConfig{
+		FacilitatorURL:      facilitator.URL,
+		DefaultRequirements: requirements,
+		ProtectedPaths:      []string{"/*"},
+		MaxBufferSize:       10,
+		RouteLimits: map[string]RouteLimit{
+			"/large":     {MaxBufferSize: 100},
+			"/unlimited": {MaxBufferSize: -1},
+			"/slow":      {Timeout: 10 * time.Millisecond},
+		},
+	}
+	if err := cfg.Validate(); err != nil {
+		t.Fatalf("Expected valid config, got %v", err)
+	}
+	router := gin.New()
+	router.Use(NewX402Middleware(cfg).Handler())
+	router.GET("/slow", func(ctx *gin.Context) {
+		select {
+		case <-ctx.Request.Context().Done():
+		case <-time.After(time.Second):
+		}
+		ctx.String(http.StatusOK, "late")
+	})
+	router.GET("/:name", func(ctx *gin.Context) {
+		ctx.String(http.StatusOK, strings.Repeat("x", 50))
+	})
+
+	tests := []struct {
+		name        string
+		path        string
+		status      int
+		settleCalls int32
+	}{
+		{"global buffer size", "/small", http.StatusInternalServerError, 0},
+		{"larger buffer", "/large", http.StatusOK, 1},
+		{"unlimited buffer", "/unlimited", http.StatusOK, 1},
+		{"handler timeout", "/slow", http.StatusGatewayTimeout, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			settleCalls.Store(0)
+
+			req := httptest.NewRequest("GET", tt.path, nil)
+			req.Header.Set("PAYMENT-SIGNATURE", paidHeader(requirements))
+			recorder := httptest.NewRecorder()
+			router.ServeHTTP(recorder, req)
+
+			if recorder.Code != tt.status {
+				t.Errorf("Expected status %d, got %d: %s", tt.status, recorder.Code, recorder.Body.String())
+			}
+			if calls := settleCalls.Load(); calls != tt.settleCalls {
+				t.Errorf("Expected %d settlements, got %d", tt.settleCalls, calls)
+			}
+		})
+	}
+}
+
+func TestRouteTimeoutDropsLateWrites(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	var settleCalls atomic.Int32
+	facilitator := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		switch r.URL.Path {
+		case "/verify":
+			json.NewEncoder(w).Encode(types.VerifyResponse{IsValid: true})
+		case "/settle":
+			settleCalls.Add(1)
+			json.NewEncoder(w).Encode(types.SettleResponse{Success: true, Transaction: "0xabc"})
+		}
+	}))
+	defer facilitator.Close()
+
+	requirements := types.PaymentRequirements{
+		Scheme:  "exact",
+		Network: "eip155:84532",
+		Amount:  "1000",
+		Asset:   "0x036CbD53842c5426634e7929541eC2318f3dCF7e",
+		PayTo:   "0x3C44CdDdB6a900fa2b585dd299e03d12FA4293BC",
+	}
+	cfg := &MiddlewareConfig{
+		FacilitatorURL:      facilitator.URL,
+		DefaultRequirements: requirements,
+		ProtectedPaths:      []string{"/stubborn"},
+		RouteLimits: map[string]RouteLimit{
+			"/stubborn": {Timeout: 10 * time.Millisecond},
+		},
+	}
+	if err := cfg.Validate(); err != nil {
+		t.Fatalf("Expected valid config, got %v", err)
+	}
+
+	// The handler ignores its context, writing once in time and once late
+	var lateErr error
+	router := gin.New()
+	router.Use(NewX402Middleware(cfg).Handler())
+	router.GET("/stubborn", func(ctx *gin.Context) {
+		ctx.Writer.WriteString("early ")
+		time.Sleep(50 * time.Millisecond)
+		ctx.Header("X-Late", "true")
+		ctx.Status(http.StatusAccepted)
+		_, lateErr = ctx.Writer.WriteString("late")
+	})
+
+	req := httptest.NewRequest("GET", "/stubborn", nil)
+	req.Header.Set("PAYMENT-SIGNATURE", paidHeader(requirements))
+	recorder := httptest.NewRecorder()
+	router.ServeHTTP(recorder, req)
+
+	if recorder.Code != http.StatusGatewayTimeout {
+		t.Fatalf("Expected status 504, got %d: %s", recorder.Code, recorder.Body.String())
+	}
+	if body := recorder.Body.String(); strings.Contains(body, "early") || strings.Contains(body, "late") {
+		t.Errorf("Expected the handler's response to be discarded, got %s", body)
+	}
+	if recorder.Header().Get("X-Late") != "" {
+		t.Error("Expected headers set after the deadline to be dropped")
+	}
+	if !errors.Is(lateErr, http.ErrHandlerTimeout) {
+		t.Errorf("Expected late write to fail with ErrHandlerTimeout, got %v", lateErr)
+	}
+	if calls := settleCalls.Load(); calls != 0 {
+		t.Errorf("Expected no settlement, got %d", calls)
+	}
+}
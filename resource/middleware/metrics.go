@@ -0,0 +1,262 @@
+package middleware
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"maps"
+	"math/big"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/vorpalengineering/x402-go/types"
+)
+
+// Verification and settlement results reported in metrics
+const (
+	MetricResultValid   = "valid"
+	MetricResultInvalid = "invalid"
+	MetricResultSuccess = "success"
+	MetricResultFailed  = "failed"
+	MetricResultError   = "error"
+)
+
+// settlementDurationBuckets are the upper bounds in seconds of the settlement
+// latency histogram, from a cached result to a slow confirmation
+var settlementDurationBuckets = []float64{0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10, 30, 60}
+
+// Metrics counts the payments handled by the middleware. It is an
+// http.Handler serving them in the Prometheus text format, to mount next to
+// promhttp.Handler() (e.g. router.GET("/metrics/x402", gin.WrapH(metrics))).
+// Routes are labeled by the protected path pattern they matched, so label
+// values stay bounded.
+type Metrics struct {
+	mu                 sync.Mutex
+	paymentRequired    *counterVec
+	verifications      *counterVec
+	settlements        *counterVec
+	settlementDuration *histogramVec
+	revenue            *counterVec
+	facilitatorErrors  *counterVec
+}
+
+func NewMetrics() *Metrics {
+	return &Metrics{
+		paymentRequired:    newCounterVec("x402_payment_required_total", "402 responses sent, asking for a payment or refusing one.", "route"),
+		verifications:      newCounterVec("x402_verifications_total", "Payments verified, by result (valid, invalid, error).", "route", "result"),
+		settlements:        newCounterVec("x402_settlements_total", "Payments settled, by result (success, failed, error).", "route", "result"),
+		settlementDuration: newHistogramVec("x402_settlement_duration_seconds", "Latency of settle calls.", settlementDurationBuckets, "route"),
+		revenue:            newCounterVec("x402_revenue_total", "Settled amounts in atomic units of the asset.", "route", "network", "asset"),
+		facilitatorErrors:  newCounterVec("x402_facilitator_errors_total", "Facilitator calls that failed to complete, by operation (verify, settle).", "operation"),
+	}
+}
+
+// observePaymentRequired counts a 402 response on route
+func (m *Metrics) observePaymentRequired(route string) {
+	if m == nil {
+		return
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.paymentRequired.add(1, route)
+}
+
+// observeVerification counts a verification on route
+func (m *Metrics) observeVerification(route, result string) {
+	if m == nil {
+		return
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.verifications.add(1, route, result)
+	if result == MetricResultError {
+		m.facilitatorErrors.add(1, "verify")
+	}
+}
+
+// observeSettlement counts a settle call on route that took duration, and
+// the revenue of successful ones
+func (m *Metrics) observeSettlement(route string, duration time.Duration, payment *Payment, settleReq *types.SettleRequest, settleResp *types.SettleResponse, err error) {
+	if m == nil {
+		return
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.settlementDuration.observe(duration.Seconds(), route)
+	switch {
+	case err != nil:
+		m.settlements.add(1, route, MetricResultError)
+		m.facilitatorErrors.add(1, "settle")
+	case !settleResp.Success:
+		m.settlements.add(1, route, MetricResultFailed)
+	default:
+		m.settlements.add(1, route, MetricResultSuccess)
+		if amount, ok := new(big.Int).SetString(chargedAmount(payment, settleReq, settleResp), 10); ok {
+			value, _ := new(big.Float).SetInt(amount).Float64()
+			m.revenue.add(value, route, payment.Requirements.Network, payment.Requirements.Asset)
+		}
+	}
+}
+
+// chargedAmount is what a settlement charged: the metered amount of upto
+// payments, the authorized value of exact ones, or the price
+func chargedAmount(payment *Payment, settleReq *types.SettleRequest, settleResp *types.SettleResponse) string {
+	if settleResp.Amount != "" {
+		return settleResp.Amount
+	}
+	if settleReq.Amount != "" {
+		return settleReq.Amount
+	}
+	if payment.exact != nil && payment.exact.Authorization.Value != "" {
+		return payment.exact.Authorization.Value
+	}
+	return payment.Requirements.Amount
+}
+
+// ServeHTTP writes the metrics in the Prometheus text exposition format
+func (m *Metrics) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+	m.WriteTo(w)
+}
+
+// WriteTo writes the metrics in the Prometheus text exposition format
+func (m *Metrics) WriteTo(w io.Writer) (int64, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	counted := &countingWriter{w: w}
+	buffered := bufio.NewWriter(counted)
+	m.paymentRequired.write(buffered)
+	m.verifications.write(buffered)
+	m.settlements.write(buffered)
+	m.settlementDuration.write(buffered)
+	m.revenue.write(buffered)
+	m.facilitatorErrors.write(buffered)
+	err := buffered.Flush()
+	return counted.n, err
+}
+
+// metricsRoute is the protected path pattern route is labeled by
+func (m *X402Middleware) metricsRoute(route string) string {
+	if m.config.Metrics == nil {
+		return ""
+	}
+	if pattern, ok := bestRoute(m.protectedPatterns, route); ok {
+		return pattern
+	}
+	return route
+}
+
+// counterVec is a counter with one series per set of label values
+type counterVec struct {
+	name, help string
+	labels     []string
+	values     map[string]float64
+}
+
+func newCounterVec(name, help string, labels ...string) *counterVec {
+	return &counterVec{name: name, help: help, labels: labels, values: make(map[string]float64)}
+}
+
+func (c *counterVec) add(value float64, labelValues ...string) {
+	c.values[seriesKey(labelValues)] += value
+}
+
+func (c *counterVec) write(w *bufio.Writer) {
+	fmt.Fprintf(w, "# HELP %s %s\n# TYPE %s counter\n", c.name, c.help, c.name)
+	for _, key := range slices.Sorted(maps.Keys(c.values)) {
+		fmt.Fprintf(w, "%s%s %s\n", c.name, formatLabels(c.labels, key, ""), formatValue(c.values[key]))
+	}
+}
+
+// histogramVec is a histogram with one series per set of label values
+type histogramVec struct {
+	name, help string
+	labels     []string
+	buckets    []float64
+	series     map[string]*histogram
+}
+
+type histogram struct {
+	counts []uint64
+	sum    float64
+	count  uint64
+}
+
+func newHistogramVec(name, help string, buckets []float64, labels ...string) *histogramVec {
+	return &histogramVec{name: name, help: help, labels: labels, buckets: buckets, series: make(map[string]*histogram)}
+}
+
+func (h *histogramVec) observe(value float64, labelValues ...string) {
+	key := seriesKey(labelValues)
+	series, exists := h.series[key]
+	if !exists {
+		series = &histogram{counts: make([]uint64, len(h.buckets))}
+		h.series[key] = series
+	}
+	for i, bound := range h.buckets {
+		if value <= bound {
+			series.counts[i]++
+		}
+	}
+	series.sum += value
+	series.count++
+}
+
+func (h *histogramVec) write(w *bufio.Writer) {
+	fmt.Fprintf(w, "# HELP %s %s\n# TYPE %s histogram\n", h.name, h.help, h.name)
+	for _, key := range slices.Sorted(maps.Keys(h.series)) {
+		series := h.series[key]
+		for i, bound := range h.buckets {
+			le := `le="` + formatValue(bound) + `"`
+			fmt.Fprintf(w, "%s_bucket%s %d\n", h.name, formatLabels(h.labels, key, le), series.counts[i])
+		}
+		fmt.Fprintf(w, "%s_bucket%s %d\n", h.name, formatLabels(h.labels, key, `le="+Inf"`), series.count)
+		fmt.Fprintf(w, "%s_sum%s %s\n", h.name, formatLabels(h.labels, key, ""), formatValue(series.sum))
+		fmt.Fprintf(w, "%s_count%s %d\n", h.name, formatLabels(h.labels, key, ""), series.count)
+	}
+}
+
+// seriesKey joins label values with a byte that is never valid UTF-8
+func seriesKey(labelValues []string) string {
+	return strings.Join(labelValues, "\xff")
+}
+
+var labelEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+
+// formatLabels renders the labels of a series, with an extra pair appended
+func formatLabels(names []string, key, extra string) string {
+	values := strings.Split(key, "\xff")
+	pairs := make([]string, 0, len(names)+1)
+	for i, name := range names {
+		pairs = append(pairs, name+`="`+labelEscaper.Replace(values[i])+`"`)
+	}
+	if extra != "" {
+		pairs = append(pairs, extra)
+	}
+	if len(pairs) == 0 {
+		return ""
+	}
+	return "{" + strings.Join(pairs, ",") + "}"
+}
+
+func formatValue(value float64) string {
+	return strconv.FormatFloat(value, 'g', -1, 64)
+}
+
+// countingWriter counts the bytes written through it
+type countingWriter struct {
+	w io.Writer
+	n int64
+}
+
+func (c *countingWriter) Write(p []byte) (int, error) {
+	n, err := c.w.Write(p)
+	c.n += int64(n)
+	return n, err
+}
@@ -0,0 +1,97 @@
+package middleware
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/vorpalengineering/x402-go/types"
+)
+
+func TestMetrics(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	facilitator := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		switch r.URL.Path {
+		case "/verify":
+			json.NewEncoder(w).Encode(types.VerifyResponse{IsValid: true})
+		case "/settle":
+			json.NewEncoder(w).Encode(types.SettleResponse{Success: true, Transaction: "0xabc"})
+		}
+	}))
+	defer facilitator.Close()
+
+	requirements := types.PaymentRequirements{
+		Scheme:  "exact",
+		Network: "eip155:84532",
+		Amount:  "1000",
+		Asset:   "0x036CbD53842c5426634e7929541eC2318f3dCF7e",
+		PayTo:   "0x3C44CdDdB6a900fa2b585dd299e03d12FA4293BC",
+	}
+	metrics := NewMetrics()
+	router := gin.New()
+	router.Use(NewX402Middleware(&MiddlewareConfig{
+		FacilitatorURL:      facilitator.URL,
+		DefaultRequirements: requirements,
+		ProtectedPaths:      []string{"/items/:id"},
+		Metrics:             metrics,
+	}).Handler())
+	router.GET("/items/:id", func(ctx *gin.Context) {
+		ctx.String(http.StatusOK, "item")
+	})
+	router.GET("/metrics", gin.WrapH(metrics))
+
+	for _, header := range []string{"", paidHeader(requirements)} {
+		req := httptest.NewRequest("GET", "/items/1", nil)
+		if header != "" {
+			req.Header.Set("PAYMENT-SIGNATURE", header)
+		}
+		router.ServeHTTP(httptest.NewRecorder(), req)
+	}
+
+	recorder := httptest.NewRecorder()
+	router.ServeHTTP(recorder, httptest.NewRequest("GET", "/metrics", nil))
+	if contentType := recorder.Header().Get("Content-Type"); !strings.HasPrefix(contentType, "text/plain; version=0.0.4") {
+		t.Errorf("Expected Prometheus content type, got %s", contentType)
+	}
+	for _, expected := range []string{
+		"# TYPE x402_payment_required_total counter",
+		`x402_payment_required_total{route="/items/:id"} 1`,
+		`x402_verifications_total{route="/items/:id",result="valid"} 1`,
+		`x402_settlements_total{route="/items/:id",result="success"} 1`,
+		`x402_settlement_duration_seconds_bucket{route="/items/:id",le="+Inf"} 1`,
+		`x402_settlement_duration_seconds_count{route="/items/:id"} 1`,
+		`x402_revenue_total{route="/items/:id",network="eip155:84532",asset="0x036CbD53842c5426634e7929541eC2318f3dCF7e"} 1000`,
+		"# TYPE x402_facilitator_errors_total counter",
+	} {
+		if !strings.Contains(recorder.Body.String(), expected) {
+			t.Errorf("Expected metrics to contain %q, got:\n%s", expected, recorder.Body.String())
+		}
+	}
+}
+
+func TestMetricsFacilitatorErrors(t *testing.T) {
+	metrics := NewMetrics()
+	metrics.observeVerification("/data", MetricResultError)
+	metrics.observeSettlement("/data", 0, &Payment{}, &types.SettleRequest{}, nil, http.ErrHandlerTimeout)
+
+	var out strings.Builder
+	metrics.WriteTo(&out)
+	for _, expected := range []string{
+		`x402_facilitator_errors_total{operation="verify"} 1`,
+		`x402_facilitator_errors_total{operation="settle"} 1`,
+		`x402_settlements_total{route="/data",result="error"} 1`,
+	} {
+		if !strings.Contains(out.String(), expected) {
+			t.Errorf("Expected metrics to contain %q, got:\n%s", expected, out.String())
+		}
+	}
+
+	// Metrics are optional
+	var disabled *Metrics
+	disabled.observePaymentRequired("/data")
+}
@@ -1,11 +1,13 @@
 package middleware
 
 import (
+	"context"
 	"encoding/base64"
 	"encoding/json"
-	"log"
+	"errors"
+	"log/slog"
 	"net/http"
-	"path/filepath"
+	"time"
 
 	"github.com/gin-gonic/gin"
 	"github.com/vorpalengineering/x402-go/facilitator/client"
@@ -13,16 +15,92 @@ import (
 	"github.com/vorpalengineering/x402-go/utils"
 )
 
+// RequestIDKey is the gin context key holding the ID of a protected request,
+// also sent back in the X-Request-ID header
+const RequestIDKey = "x402_request_id"
+
+// SettleAmountKey is the context key handlers of "upto" scheme routes set (as
+// a string in atomic units) to charge the metered amount. It must not exceed
+// the route's price, which is charged when the key is unset.
+const SettleAmountKey = "x402_settle_amount"
+
+// PaymentKey is the context key holding the verified *Payment of a request,
+// for VerifyOnly routes to settle out of band
+const PaymentKey = "x402_payment"
+
+// RoutePatternKey is the context key adapters set to the pattern of the
+// router's route (e.g. "/users/{id}"). Protected paths and route
+// requirements are then matched against it instead of the request path.
+const RoutePatternKey = "x402_route_pattern"
+
+// facilitatorBackend verifies and settles payments, over HTTP with the
+// facilitator client or in process when self-settling
+type facilitatorBackend interface {
+	VerifyContext(ctx context.Context, req *types.VerifyRequest) (*types.VerifyResponse, error)
+	SettleContext(ctx context.Context, req *types.SettleRequest) (*types.SettleResponse, error)
+}
+
 type X402Middleware struct {
-	config      *MiddlewareConfig
-	facilitator *client.FacilitatorClient
+	config            *MiddlewareConfig
+	protectedPatterns map[string]string
+	facilitator       facilitatorBackend
+	attestationGate   *attestationGate
+	decoder           *paymentHeaderDecoder
+	settlements       *settlementTracker
+	sessions          *sessionManager
+	async             *asyncQueue
+	exporters         []UsageExporter
+	logger            *slog.Logger
+	started           time.Time
 }
 
 func NewX402Middleware(cfg *MiddlewareConfig) *X402Middleware {
-	return &X402Middleware{
+	m := &X402Middleware{
 		config:      cfg,
-		facilitator: client.NewFacilitatorClient(cfg.FacilitatorURL),
+		decoder:     newPaymentHeaderDecoder(cfg.MaxPaymentHeaderSize),
+		settlements: newSettlementTracker(),
+		exporters:   usageExporters(cfg),
+		logger:      cfg.Logger,
+		started:     time.Now(),
+	}
+	if cfg.SelfSettle != nil {
+		m.facilitator = selfSettler{cfg.SelfSettle}
+	} else {
+		facilitator := client.NewFacilitatorClient(cfg.FacilitatorURL)
+		if cfg.FacilitatorTLS != nil {
+			facilitator.SetTLSConfig(cfg.FacilitatorTLS)
+		}
+		if len(cfg.FacilitatorHeaders) > 0 {
+			headers := make(http.Header, len(cfg.FacilitatorHeaders))
+			for name, value := range cfg.FacilitatorHeaders {
+				headers.Set(name, value)
+			}
+			facilitator.SetRequestHeaders(headers)
+		}
+		m.facilitator = facilitator
+	}
+	if m.logger == nil {
+		m.logger = utils.NewLogger(cfg.LogLevel, cfg.LogFormat)
+	}
+	if cfg.AttestationGate != nil && cfg.AttestationGate.Checker != nil {
+		m.attestationGate = newAttestationGate(cfg.AttestationGate)
+	}
+	if cfg.Sessions != nil {
+		m.sessions = newSessionManager(cfg.Sessions)
 	}
+	if cfg.Metrics != nil {
+		m.protectedPatterns = make(map[string]string, len(cfg.ProtectedPaths))
+		for _, pattern := range cfg.ProtectedPaths {
+			m.protectedPatterns[pattern] = pattern
+		}
+	}
+	if usesAsyncSettlement(cfg) {
+		m.async = newAsyncQueue(cfg.AsyncSettlement)
+		for range m.async.cfg.Workers {
+			go m.runAsyncSettlements()
+		}
+	}
+	return m
 }
 
 func (m *X402Middleware) Handler() gin.HandlerFunc {
@@ -32,81 +110,191 @@ func (m *X402Middleware) Handler() gin.HandlerFunc {
 			m.serveDiscovery(ctx)
 			return
 		}
+		if m.config.BazaarListing != nil && ctx.Request.Method == http.MethodGet && ctx.Request.URL.Path == m.config.BazaarListing.path() {
+			m.serveBazaar(ctx)
+			return
+		}
 
 		// Check if the current path requires payment
-		if !m.isProtectedPath(ctx.Request.URL.Path) {
+		route := routePath(ctx)
+		if !m.isProtectedPath(route) {
 			ctx.Next()
 			return
 		}
 
-		// Extract payment header
-		headerName := m.config.GetPaymentHeaderName()
-		paymentHeader := ctx.GetHeader(headerName)
+		// Browsers send preflight requests without payment, and HEAD
+		// requests may be let through as they carry no body
+		if m.setCORSHeaders(ctx) {
+			return
+		}
+		if ctx.Request.Method == http.MethodOptions || (ctx.Request.Method == http.MethodHead && m.config.FreeHeadRequests) {
+			ctx.Next()
+			return
+		}
 
-		// If no payment header is present, return 402 Payment Required
-		if paymentHeader == "" {
-			m.sendPaymentRequired(ctx, ctx.Request.URL.Path)
+		// Correlate logs of this request, reusing the caller's request ID if set
+		requestID := ctx.GetHeader(utils.RequestIDHeader)
+		if requestID == "" || len(requestID) > 128 {
+			requestID = utils.NewRequestID()
+		}
+		ctx.Header(utils.RequestIDHeader, requestID)
+		ctx.Set(RequestIDKey, requestID)
+		logger := m.logger.With("request_id", requestID, "path", ctx.Request.URL.Path)
+
+		// Apply route lifecycle (deprecation headers or 410 after sunset)
+		if m.applyDeprecation(ctx, route) {
 			return
 		}
 
-		// Decode payment header into PaymentPayload
-		paymentPayload, err := utils.DecodePaymentHeader(paymentHeader)
-		if err != nil {
-			ctx.JSON(http.StatusBadRequest, gin.H{
-				"error": "Invalid payment header: " + err.Error(),
-			})
-			ctx.Abort()
+		// Serve allowlisted API keys without a payment
+		if access := m.freeAccess(ctx, route); access != nil {
+			m.serveFree(ctx, logger, access)
 			return
 		}
 
-		// Get payment requirements for this route
-		requirements := m.getRequirements(ctx.Request.URL.Path)
+		// Serve requests presenting a valid session without a payment
+		if m.sessions != nil {
+			if token := m.sessions.sessionToken(ctx); token != "" {
+				session, err := m.sessions.redeem(token, route)
+				if err == nil {
+					ctx.Set(SessionKey, session)
+					logger.Info("served under session", "session", session.ID, "payer", session.Payer)
+					ctx.Next()
+					return
+				}
+				logger.Info("session refused", "error", err)
+			}
+		}
 
-		// Verify payment with facilitator
-		verifyReq := &types.VerifyRequest{
-			PaymentPayload:      *paymentPayload,
-			PaymentRequirements: requirements,
+		// Serve clients within the free tier without a payment
+		if m.freeTier(FreeTierByIP, route) {
+			access, err := m.takeFreeRequest(ctx, ctx.ClientIP())
+			if err != nil {
+				logger.Error("failed to count free request", "error", err)
+			}
+			if access != nil {
+				m.serveFree(ctx, logger, access)
+				return
+			}
 		}
 
-		verifyResp, err := m.facilitator.Verify(verifyReq)
+		// Price the request
+		accepts, err := m.requirementsFor(ctx.Request, route)
 		if err != nil {
-			// Facilitator communication error
-			ctx.JSON(http.StatusBadGateway, gin.H{
-				"error": "Failed to verify payment: " + err.Error(),
+			logger.Info("failed to price request", "error", err)
+			ctx.JSON(http.StatusBadRequest, gin.H{
+				"error": "Failed to price request: " + err.Error(),
 			})
 			ctx.Abort()
 			return
 		}
 
-		// Check if payment is valid
-		if !verifyResp.IsValid {
-			// Payment is invalid, return 402 with reason
-			response := types.PaymentRequired{
-				X402Version: 2,
-				Accepts:     []types.PaymentRequirements{requirements},
-				Error:       verifyResp.InvalidReason,
+		// Pay from prepaid credits while they last
+		if m.credits(route) {
+			served, err := m.spendCredits(ctx, route, accepts)
+			if err != nil {
+				logger.Error("failed to spend credits", "error", err)
 			}
-			setPaymentRequiredHeader(ctx, &response)
-			ctx.JSON(http.StatusPaymentRequired, response)
-			ctx.Abort()
+			if served {
+				logger.Info("paid from credits", "account", ctx.GetString(CreditAccountKey))
+				return
+			}
+		}
+
+		// Extract payment header, from v1 clients too if enabled
+		paymentHeader, _ := m.paymentHeader(ctx)
+
+		// If no payment header is present, return 402 Payment Required
+		if paymentHeader == "" {
+			m.sendPaymentRequired(ctx, route, accepts)
+			return
+		}
+
+		// Verify the payment before the handler runs
+		payment, refusal := m.verifyPayment(ctx.Request.Context(), logger, route, paymentHeader, accepts)
+		if refusal != nil {
+			m.refuse(ctx, refusal)
 			return
 		}
+		requirements := payment.Requirements
+
+		// Allowlisted payers proved who they are, their payment is not settled
+		if access := m.freePayer(route, payment.Payer); access != nil {
+			m.releasePayment(ctx.Request.Context(), payment)
+			m.serveFree(ctx, logger, access)
+			return
+		}
+		if m.freeTier(FreeTierByPayer, route) && payment.Payer != "" {
+			access, err := m.takeFreeRequest(ctx, payment.Payer)
+			if err != nil {
+				logger.Error("failed to count free request", "error", err)
+			}
+			if access != nil {
+				m.releasePayment(ctx.Request.Context(), payment)
+				m.serveFree(ctx, logger, access)
+				return
+			}
+		}
 
 		// Payment is valid, store payment info in context for downstream handlers
 		ctx.Set("x402_payment_verified", true)
 		ctx.Set("x402_payment_header", paymentHeader)
 		ctx.Set("x402_payment_requirements", requirements)
+		ctx.Set(PaymentKey, payment)
+
+		// Only routes settling after the handler need its response held back
+		switch m.settlementOrder(route) {
+		case SettleAsync:
+			if m.async.reserve() {
+				m.settleAsync(ctx, logger, payment)
+				return
+			}
+			logger.Warn("settlement queue full, settling before the handler")
+			fallthrough
+		case SettleBefore:
+			settleResp, refusal := m.settleServed(ctx, logger, payment, "")
+			if refusal != nil {
+				m.refuse(ctx, refusal)
+				return
+			}
+			if settleResp != nil {
+				m.recordSettlement(ctx, logger, payment, settleResp)
+			}
+			m.next(ctx, route)
+			return
+		case VerifyOnly:
+			logger.Info("payment verified, settlement left out of band")
+			m.next(ctx, route)
+			return
+		}
 
 		// Replace response writer with buffered version to capture response
-		buffered := newBufferedWriter(ctx.Writer, m.config.MaxBufferSize)
+		buffered := newBufferedWriter(ctx.Writer, m.maxBufferSize(route))
 		ctx.Writer = buffered
 
 		// STEP 2: Fulfill request (handler executes)
-		ctx.Next()
+		timedOut := m.next(ctx, route)
+
+		// Unsettled payments can be presented again
+		if timedOut || buffered.overflow || buffered.Status() < 200 || buffered.Status() >= 300 {
+			m.releasePayment(ctx.Request.Context(), payment)
+		}
+
+		// Discard responses of handlers that ran out of time
+		if timedOut {
+			logger.Warn("handler timed out, payment not settled")
+			ctx.Writer = buffered.ResponseWriter
+			ctx.JSON(http.StatusGatewayTimeout, gin.H{
+				"error": "Request timed out, payment was not settled",
+			})
+			ctx.Abort()
+			return
+		}
 
 		// Check for buffer overflow
 		if buffered.overflow {
-			log.Printf("Response exceeded max buffer size (%d bytes), aborting", m.config.MaxBufferSize)
+			logger.Error("response exceeded max buffer size, aborting", "max_buffer_size", m.maxBufferSize(route))
+			ctx.Writer = buffered.ResponseWriter
 			ctx.JSON(http.StatusInternalServerError, gin.H{
 				"error": "Response too large to process payment",
 			})
@@ -116,107 +304,226 @@ func (m *X402Middleware) Handler() gin.HandlerFunc {
 
 		// STEP 3: Settle payment if handler succeeded (2xx status)
 		if buffered.Status() >= 200 && buffered.Status() < 300 {
-			settleReq := &types.SettleRequest{
-				PaymentPayload:      *paymentPayload,
-				PaymentRequirements: requirements,
-			}
-
-			settleResp, err := m.facilitator.Settle(settleReq)
-			if err != nil {
+			settleResp, refusal := m.settleServed(ctx, logger, payment, ctx.GetString(SettleAmountKey))
+			if refusal != nil {
 				// Settlement failed, don't send the buffered response
-				ctx.JSON(http.StatusBadGateway, gin.H{
-					"error": "Failed to settle payment: " + err.Error(),
-				})
-				ctx.Abort()
+				ctx.Writer = buffered.ResponseWriter
+				m.refuse(ctx, refusal)
 				return
 			}
 
-			if !settleResp.Success {
-				// Settlement unsuccessful
-				ctx.JSON(http.StatusPaymentRequired, gin.H{
-					"error": "Payment settlement failed: " + settleResp.ErrorReason,
-				})
-				ctx.Abort()
-				return
+			if settleResp != nil {
+				m.recordSettlement(ctx, logger, payment, settleResp)
 			}
+		}
 
-			// Store settlement info in context
-			ctx.Set("x402_settlement_tx", settleResp.Transaction)
-			ctx.Set("x402_settlement_network", settleResp.Network)
-			ctx.Set("x402_settlement_payer", settleResp.Payer)
+		// STEP 4: Send response to client (only after successful settlement,
+		// unless the route serves failed settlements anyway)
+		buffered.flush()
+	}
+}
 
-			// Set PAYMENT-RESPONSE header with settlement details
-			setPaymentResponseHeader(ctx, settleResp)
+// recordSettlement exposes a settlement to handlers and the client, and
+// exports its usage
+func (m *X402Middleware) recordSettlement(ctx *gin.Context, logger *slog.Logger, payment *Payment, settleResp *types.SettleResponse) {
+	// Store settlement info in context
+	ctx.Set("x402_settlement_tx", settleResp.Transaction)
+	ctx.Set("x402_settlement_network", settleResp.Network)
+	ctx.Set("x402_settlement_payer", settleResp.Payer)
+
+	// Set PAYMENT-RESPONSE header with settlement details
+	if payment.version == types.X402VersionV1 {
+		m.setV1PaymentResponseHeader(ctx, settleResp)
+	} else {
+		m.setPaymentResponseHeader(ctx, settleResp)
+	}
 
-			log.Printf("Payment settled: tx=%s, network=%s, payer=%s",
-				settleResp.Transaction, settleResp.Network, settleResp.Payer)
+	// Grant a session on the paid route
+	if m.sessions != nil {
+		payer := settleResp.Payer
+		if payer == "" {
+			payer = payment.Payer
+		}
+		token, session, err := m.sessions.issue(payment.Route, payer, settleResp.Transaction)
+		if err != nil {
+			logger.Error("failed to issue session", "error", err)
+		} else if session != nil {
+			m.sessions.sendSession(ctx, token, session)
 		}
+	}
 
-		// STEP 4: Send response to client (only after successful settlement)
-		buffered.flush()
+	// Keep any overpayment as credits
+	if m.credits(payment.Route) {
+		if err := m.creditOverpayment(ctx, payment); err != nil {
+			logger.Error("failed to add credits", "error", err)
+		}
+	}
+
+	// Export usage to billing systems
+	if len(m.exporters) > 0 {
+		m.exportUsage(logger, newUsageRecord(ctx, payment.Requirements, payment.exact, settleResp))
+	}
+
+	logger.Info("payment settled", "tx", settleResp.Transaction, "payer", settleResp.Payer)
+}
+
+// settlementOrder returns when the payment for route is settled
+func (m *X402Middleware) settlementOrder(route string) SettlementOrder {
+	if order, exists := bestRoute(m.config.RouteSettlement, route); exists {
+		return order
 	}
+
+	// Streaming responses cannot be held back
+	if matchesAny(m.config.StreamingRoutes, route) {
+		return SettleBefore
+	}
+	return SettleAfter
+}
+
+// routePath returns the route requirements are looked up by
+func routePath(ctx *gin.Context) string {
+	if pattern := ctx.GetString(RoutePatternKey); pattern != "" {
+		return pattern
+	}
+	return ctx.Request.URL.Path
 }
 
 func (m *X402Middleware) isProtectedPath(path string) bool {
-	for _, pattern := range m.config.ProtectedPaths {
-		matched, err := filepath.Match(pattern, path)
-		if err != nil {
-			// Invalid pattern, skip
-			continue
-		}
-		if matched {
+	return matchesAny(m.config.ProtectedPaths, path)
+}
+
+// matchesAny reports whether path matches one of patterns
+func matchesAny(patterns []string, path string) bool {
+	for _, pattern := range patterns {
+		if matchRoute(pattern, path) {
 			return true
 		}
 	}
 	return false
 }
 
-func (m *X402Middleware) getRequirements(path string) types.PaymentRequirements {
-	// Check for exact route match first
-	if req, exists := m.config.RouteRequirements[path]; exists {
-		return req
+// requirementsFor returns every payment option accepted for a request to route
+func (m *X402Middleware) requirementsFor(req *http.Request, route string) ([]types.PaymentRequirements, error) {
+	if m.config.PricingFunc != nil {
+		requirements, err := m.config.PricingFunc(req)
+		if err != nil {
+			return nil, err
+		}
+		if requirements.Scheme != "" {
+			if err := validatePaymentRequirements(&requirements); err != nil {
+				return nil, errors.New("invalid price: " + err.Error())
+			}
+			return []types.PaymentRequirements{requirements}, nil
+		}
 	}
+	return m.getRequirements(route), nil
+}
 
-	// Check for pattern matches in route requirements
-	for pattern, req := range m.config.RouteRequirements {
-		matched, err := filepath.Match(pattern, path)
-		if err == nil && matched {
-			return req
-		}
+// getRequirements returns every payment option accepted for path
+func (m *X402Middleware) getRequirements(path string) []types.PaymentRequirements {
+	// Multi-option routes take precedence, most specific match first
+	if accepts, exists := bestRoute(m.config.RouteAccepts, path); exists {
+		return accepts
+	}
+	if req, exists := bestRoute(m.config.RouteRequirements, path); exists {
+		return []types.PaymentRequirements{req}
 	}
 
-	return m.config.DefaultRequirements
+	if len(m.config.DefaultAccepts) > 0 {
+		return m.config.DefaultAccepts
+	}
+	return []types.PaymentRequirements{m.config.DefaultRequirements}
 }
 
-func (m *X402Middleware) sendPaymentRequired(ctx *gin.Context, path string) {
-	requirements := m.getRequirements(path)
-	headerName := m.config.GetPaymentHeaderName()
+func (m *X402Middleware) sendPaymentRequired(ctx *gin.Context, path string, accepts []types.PaymentRequirements) {
+	response := m.paymentRequired(path, ctx.Request.URL.Path, accepts)
+	m.setPaymentRequiredHeader(ctx, response)
+	m.config.Metrics.observePaymentRequired(m.metricsRoute(path))
+
+	// Show browsers a payment page rather than JSON
+	if m.config.Paywall != nil && prefersHTML(ctx.GetHeader("Accept")) {
+		m.sendPaywall(ctx, response)
+		return
+	}
+	if m.clientVersion(ctx) == types.X402VersionV1 {
+		v1 := *response
+		v1.Error = V1PaymentHeader + " header is required"
+		response = &v1
+	}
+	m.sendPaymentRequiredBody(ctx, http.StatusPaymentRequired, response)
+}
 
+// paymentRequired is the 402 response asking for a payment for route, naming
+// resourceURL as the resource
+func (m *X402Middleware) paymentRequired(route, resourceURL string, accepts []types.PaymentRequirements) *types.PaymentRequired {
 	resource := &types.ResourceInfo{
-		URL: path,
+		URL: resourceURL,
 	}
-	if r, exists := m.config.RouteResources[path]; exists {
+	if r, exists := m.config.RouteResources[route]; exists {
 		resource.Description = r.Description
 		resource.MimeType = r.MimeType
 	}
 
-	response := types.PaymentRequired{
+	return &types.PaymentRequired{
 		X402Version: 2,
-		Error:       headerName + " header is required",
+		Error:       m.config.GetPaymentHeaderName() + " header is required",
 		Resource:    resource,
-		Accepts:     []types.PaymentRequirements{requirements},
+		Accepts:     accepts,
+		Extensions:  m.paymentRequiredExtensions(),
+	}
+}
+
+// refuse responds with a payment error and aborts the request
+func (m *X402Middleware) refuse(ctx *gin.Context, refusal *PaymentError) {
+	if refusal.Status == http.StatusPaymentRequired {
+		m.config.Metrics.observePaymentRequired(m.metricsRoute(routePath(ctx)))
+	}
+	if refusal.PaymentRequired != nil {
+		m.setPaymentRequiredHeader(ctx, refusal.PaymentRequired)
+		m.sendPaymentRequiredBody(ctx, refusal.Status, refusal.PaymentRequired)
+		return
 	}
-	setPaymentRequiredHeader(ctx, &response)
-	ctx.JSON(http.StatusPaymentRequired, response)
+
+	body := gin.H{"error": refusal.Message}
+	if refusal.Code != "" {
+		body["code"] = refusal.Code
+	}
+	ctx.JSON(refusal.Status, body)
 	ctx.Abort()
 }
 
+// paymentRequiredExtensions advertises a custom payment header name so clients
+// know where to send the payment payload. Returns nil for the default header.
+func (m *X402Middleware) paymentRequiredExtensions() map[string]types.Extension {
+	headerName := m.config.GetPaymentHeaderName()
+	if headerName == utils.DefaultPaymentHeaderName {
+		return nil
+	}
+	return map[string]types.Extension{
+		utils.PaymentHeaderExtension: {
+			Info: map[string]any{
+				"name": headerName,
+			},
+			Schema: map[string]any{
+				"type": "object",
+				"properties": map[string]any{
+					"name": map[string]any{"type": "string"},
+				},
+				"required": []string{"name"},
+			},
+		},
+	}
+}
+
 // setPaymentRequiredHeader encodes the PaymentRequired response as base64 JSON
 // and sets it as the PAYMENT-REQUIRED response header.
-func setPaymentRequiredHeader(ctx *gin.Context, response *types.PaymentRequired) {
+func (m *X402Middleware) setPaymentRequiredHeader(ctx *gin.Context, response *types.PaymentRequired) {
+	if !m.speaks(types.X402VersionV2) {
+		return
+	}
 	data, err := json.Marshal(response)
 	if err != nil {
-		log.Printf("Failed to encode PAYMENT-REQUIRED header: %v", err)
+		m.logger.Error("failed to encode PAYMENT-REQUIRED header", "error", err)
 		return
 	}
 	ctx.Header("PAYMENT-REQUIRED", base64.StdEncoding.EncodeToString(data))
@@ -224,28 +531,16 @@ func setPaymentRequiredHeader(ctx *gin.Context, response *types.PaymentRequired)
 
 // setPaymentResponseHeader encodes the SettleResponse as base64 JSON
 // and sets it as the PAYMENT-RESPONSE response header.
-func setPaymentResponseHeader(ctx *gin.Context, response *types.SettleResponse) {
+func (m *X402Middleware) setPaymentResponseHeader(ctx *gin.Context, response *types.SettleResponse) {
 	data, err := json.Marshal(response)
 	if err != nil {
-		log.Printf("Failed to encode PAYMENT-RESPONSE header: %v", err)
+		m.logger.Error("failed to encode PAYMENT-RESPONSE header", "error", err)
 		return
 	}
 	ctx.Header("PAYMENT-RESPONSE", base64.StdEncoding.EncodeToString(data))
 }
 
 func (m *X402Middleware) serveDiscovery(ctx *gin.Context) {
-	// Build full URLs from BaseURL + DiscoverableEndpoints
-	resources := make([]string, len(m.config.DiscoverableEndpoints))
-	for i, endpoint := range m.config.DiscoverableEndpoints {
-		resources[i] = m.config.BaseURL + endpoint
-	}
-
-	discovery := types.DiscoveryResponse{
-		Version:         1,
-		Resources:       resources,
-		OwnershipProofs: m.config.OwnershipProofs,
-		Instructions:    m.config.Instructions,
-	}
-	ctx.JSON(http.StatusOK, discovery)
+	ctx.JSON(http.StatusOK, m.DiscoveryDocument())
 	ctx.Abort()
 }
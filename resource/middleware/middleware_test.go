@@ -0,0 +1,447 @@
+package middleware
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/vorpalengineering/x402-go/types"
+	"github.com/vorpalengineering/x402-go/utils"
+)
+
+func TestErrorsAfterHandlerReachClient(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	// Facilitator accepting every payment and failing every settlement
+	facilitator := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		switch r.URL.Path {
+		case "/verify":
+			json.NewEncoder(w).Encode(types.VerifyResponse{IsValid: true})
+		case "/settle":
+			json.NewEncoder(w).Encode(types.SettleResponse{Success: false, ErrorReason: "insufficient_funds"})
+		}
+	}))
+	defer facilitator.Close()
+
+	requirements := types.PaymentRequirements{
+		Scheme:            "exact",
+		Network:           "eip155:84532",
+		Amount:            "1000",
+		Asset:             "0x036CbD53842c5426634e7929541eC2318f3dCF7e",
+		PayTo:             "0x3C44CdDdB6a900fa2b585dd299e03d12FA4293BC",
+		MaxTimeoutSeconds: 60,
+	}
+	payload, _ := json.Marshal(types.PaymentPayload{
+		X402Version: 2,
+		Accepted:    requirements,
+		Payload: map[string]any{
+			"signature": "0x" + strings.Repeat("11", 65),
+			"authorization": map[string]any{
+				"from":        "0x70997970C51812dc3A010C7d01b50e0d17dc79C8",
+				"to":          requirements.PayTo,
+				"value":       requirements.Amount,
+				"validAfter":  0,
+				"validBefore": time.Now().Add(time.Minute).Unix(),
+				"nonce":       "0x" + strings.Repeat("01", 32),
+			},
+		},
+	})
+	header := base64.StdEncoding.EncodeToString(payload)
+
+	tests := []struct {
+		name           string
+		maxBufferSize  int
+		expectedStatus int
+	}{
+		{"settlement failure", 0, http.StatusPaymentRequired},
+		{"buffer overflow", 4, http.StatusInternalServerError},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m := NewX402Middleware(&MiddlewareConfig{
+				FacilitatorURL:      facilitator.URL,
+				DefaultRequirements: requirements,
+				ProtectedPaths:      []string{"/paid"},
+				MaxBufferSize:       tt.maxBufferSize,
+			})
+			router := gin.New()
+			router.Use(m.Handler())
+			router.GET("/paid", func(ctx *gin.Context) {
+				ctx.String(http.StatusOK, "paid content")
+			})
+
+			req := httptest.NewRequest("GET", "/paid", nil)
+			req.Header.Set("PAYMENT-SIGNATURE", header)
+			recorder := httptest.NewRecorder()
+			router.ServeHTTP(recorder, req)
+
+			// The error is sent instead of the buffered response
+			body := recorder.Body.String()
+			if recorder.Code != tt.expectedStatus || !strings.Contains(body, `"error"`) {
+				t.Errorf("Expected status %d with an error, got %d: %s", tt.expectedStatus, recorder.Code, body)
+			}
+			if strings.Contains(body, "paid content") {
+				t.Errorf("Expected the handler's response to be withheld, got %s", body)
+			}
+		})
+	}
+}
+
+func TestStreamingRouteSettlesFirst(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	var calls []string
+	settleSuccess := true
+	facilitator := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls = append(calls, r.URL.Path)
+		w.Header().Set("Content-Type", "application/json")
+		switch r.URL.Path {
+		case "/verify":
+			json.NewEncoder(w).Encode(types.VerifyResponse{IsValid: true})
+		case "/settle":
+			json.NewEncoder(w).Encode(types.SettleResponse{Success: settleSuccess, Transaction: "0xabc", ErrorReason: "insufficient_funds"})
+		}
+	}))
+	defer facilitator.Close()
+
+	requirements := types.PaymentRequirements{
+		Scheme:  "exact",
+		Network: "eip155:84532",
+		Amount:  "1000",
+		Asset:   "0x036CbD53842c5426634e7929541eC2318f3dCF7e",
+		PayTo:   "0x3C44CdDdB6a900fa2b585dd299e03d12FA4293BC",
+	}
+	header := paidHeader(requirements)
+
+	m := NewX402Middleware(&MiddlewareConfig{
+		FacilitatorURL:      facilitator.URL,
+		DefaultRequirements: requirements,
+		ProtectedPaths:      []string{"/stream"},
+		StreamingRoutes:     []string{"/stream"},
+		MaxBufferSize:       4,
+	})
+	router := gin.New()
+	router.Use(m.Handler())
+	router.GET("/stream", func(ctx *gin.Context) {
+		calls = append(calls, "handler")
+		ctx.Header("Content-Type", "text/event-stream")
+		for i := range 3 {
+			fmt.Fprintf(ctx.Writer, "data: %d\n\n", i)
+			ctx.Writer.Flush()
+		}
+	})
+
+	req := httptest.NewRequest("GET", "/stream", nil)
+	req.Header.Set("PAYMENT-SIGNATURE", header)
+	recorder := httptest.NewRecorder()
+	router.ServeHTTP(recorder, req)
+
+	// Written through despite exceeding the buffer size
+	if recorder.Code != http.StatusOK || recorder.Body.String() != "data: 0\n\ndata: 1\n\ndata: 2\n\n" {
+		t.Errorf("Expected streamed body, got %d: %q", recorder.Code, recorder.Body.String())
+	}
+	if recorder.Header().Get("PAYMENT-RESPONSE") == "" || !recorder.Flushed {
+		t.Error("Expected PAYMENT-RESPONSE header and flushed writes")
+	}
+	if strings.Join(calls, ",") != "/verify,/settle,handler" {
+		t.Errorf("Expected settlement before the handler, got %v", calls)
+	}
+
+	// A failed settlement never reaches the handler
+	calls = nil
+	settleSuccess = false
+	recorder = httptest.NewRecorder()
+	router.ServeHTTP(recorder, req)
+	if recorder.Code != http.StatusPaymentRequired || strings.Contains(strings.Join(calls, ","), "handler") {
+		t.Errorf("Expected 402 without running the handler, got %d after %v", recorder.Code, calls)
+	}
+}
+
+// paidHeader encodes an exact payment matching requirements
+func paidHeader(requirements types.PaymentRequirements) string {
+	return paidHeaderWithValue(requirements, requirements.Amount)
+}
+
+// paidHeaderWithValue encodes an exact payment authorizing value
+func paidHeaderWithValue(requirements types.PaymentRequirements, value string) string {
+	payload, _ := json.Marshal(types.PaymentPayload{
+		X402Version: 2,
+		Accepted:    requirements,
+		Payload: map[string]any{
+			"signature": "0x" + strings.Repeat("11", 65),
+			"authorization": map[string]any{
+				"from":        "0x70997970C51812dc3A010C7d01b50e0d17dc79C8",
+				"to":          requirements.PayTo,
+				"value":       value,
+				"validAfter":  0,
+				"validBefore": time.Now().Add(time.Minute).Unix(),
+				"nonce":       "0x" + strings.Repeat("01", 32),
+			},
+		},
+	})
+	return base64.StdEncoding.EncodeToString(payload)
+}
+
+func TestSettlementOrder(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	var calls []string
+	facilitator := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls = append(calls, r.URL.Path)
+		w.Header().Set("Content-Type", "application/json")
+		switch r.URL.Path {
+		case "/verify":
+			json.NewEncoder(w).Encode(types.VerifyResponse{IsValid: true})
+		case "/settle":
+			json.NewEncoder(w).Encode(types.SettleResponse{Success: true, Transaction: "0xabc"})
+		}
+	}))
+	defer facilitator.Close()
+
+	requirements := types.PaymentRequirements{
+		Scheme:  "exact",
+		Network: "eip155:84532",
+		Amount:  "1000",
+		Asset:   "0x036CbD53842c5426634e7929541eC2318f3dCF7e",
+		PayTo:   "0x3C44CdDdB6a900fa2b585dd299e03d12FA4293BC",
+	}
+
+	tests := []struct {
+		name          string
+		order         SettlementOrder
+		handlerStatus int
+		expectedCalls string
+	}{
+		{"after", SettleAfter, http.StatusOK, "/verify,handler,/settle"},
+		{"after with failing handler", SettleAfter, http.StatusInternalServerError, "/verify,handler"},
+		{"before", SettleBefore, http.StatusOK, "/verify,/settle,handler"},
+		{"before with failing handler", SettleBefore, http.StatusInternalServerError, "/verify,/settle,handler"},
+		{"verify only", VerifyOnly, http.StatusOK, "/verify,handler"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := &MiddlewareConfig{
+				FacilitatorURL:      facilitator.URL,
+				DefaultRequirements: requirements,
+				ProtectedPaths:      []string{"/paid/*"},
+				RouteSettlement:     map[string]SettlementOrder{"/paid/*": tt.order},
+			}
+			if err := cfg.Validate(); err != nil {
+				t.Fatalf("Expected valid config, got %v", err)
+			}
+			router := gin.New()
+			router.Use(NewX402Middleware(cfg).Handler())
+			router.GET("/paid/data", func(ctx *gin.Context) {
+				calls = append(calls, "handler")
+				if _, ok := ctx.Get(PaymentKey); !ok {
+					t.Error("Expected the verified payment in context")
+				}
+				ctx.String(tt.handlerStatus, "content")
+			})
+
+			calls = nil
+			req := httptest.NewRequest("GET", "/paid/data", nil)
+			req.Header.Set("PAYMENT-SIGNATURE", paidHeader(requirements))
+			recorder := httptest.NewRecorder()
+			router.ServeHTTP(recorder, req)
+
+			if recorder.Code != tt.handlerStatus || recorder.Body.String() != "content" {
+				t.Errorf("Expected handler response %d, got %d: %s", tt.handlerStatus, recorder.Code, recorder.Body.String())
+			}
+			if strings.Join(calls, ",") != tt.expectedCalls {
+				t.Errorf("Expected calls %s, got %v", tt.expectedCalls, calls)
+			}
+		})
+	}
+
+	// Streaming responses cannot be held back for settlement
+	cfg := &MiddlewareConfig{
+		FacilitatorURL:      facilitator.URL,
+		DefaultRequirements: requirements,
+		ProtectedPaths:      []string{"/paid/*"},
+		StreamingRoutes:     []string{"/paid/*"},
+		RouteSettlement:     map[string]SettlementOrder{"/paid/*": SettleAfter},
+	}
+	if err := cfg.Validate(); err == nil {
+		t.Error("Expected streaming route settling after the handler to be invalid")
+	}
+}
+
+func TestPricingFunc(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	facilitator := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		switch r.URL.Path {
+		case "/verify":
+			json.NewEncoder(w).Encode(types.VerifyResponse{IsValid: true})
+		case "/settle":
+			json.NewEncoder(w).Encode(types.SettleResponse{Success: true, Transaction: "0xabc"})
+		}
+	}))
+	defer facilitator.Close()
+
+	requirements := types.PaymentRequirements{
+		Scheme:  "exact",
+		Network: "eip155:84532",
+		Amount:  "1000",
+		Asset:   "0x036CbD53842c5426634e7929541eC2318f3dCF7e",
+		PayTo:   "0x3C44CdDdB6a900fa2b585dd299e03d12FA4293BC",
+	}
+
+	// Price per requested row, static price without a row count
+	m := NewX402Middleware(&MiddlewareConfig{
+		FacilitatorURL:      facilitator.URL,
+		DefaultRequirements: requirements,
+		ProtectedPaths:      []string{"/rows"},
+		PricingFunc: func(req *http.Request) (types.PaymentRequirements, error) {
+			rows := req.URL.Query().Get("rows")
+			if rows == "" {
+				return types.PaymentRequirements{}, nil
+			}
+			n, err := strconv.Atoi(rows)
+			if err != nil || n <= 0 {
+				return types.PaymentRequirements{}, fmt.Errorf("invalid rows %q", rows)
+			}
+			priced := requirements
+			priced.Amount = strconv.Itoa(n * 100)
+			return priced, nil
+		},
+	})
+	router := gin.New()
+	router.Use(m.Handler())
+	router.GET("/rows", func(ctx *gin.Context) {
+		ctx.String(http.StatusOK, "rows")
+	})
+
+	tests := []struct {
+		name           string
+		query          string
+		expectedStatus int
+		expectedAmount string
+	}{
+		{"priced", "?rows=25", http.StatusPaymentRequired, "2500"},
+		{"static", "", http.StatusPaymentRequired, "1000"},
+		{"invalid", "?rows=many", http.StatusBadRequest, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			recorder := httptest.NewRecorder()
+			router.ServeHTTP(recorder, httptest.NewRequest("GET", "/rows"+tt.query, nil))
+
+			var response types.PaymentRequired
+			json.Unmarshal(recorder.Body.Bytes(), &response)
+			if recorder.Code != tt.expectedStatus {
+				t.Fatalf("Expected status %d, got %d: %s", tt.expectedStatus, recorder.Code, recorder.Body.String())
+			}
+			if tt.expectedAmount != "" && (len(response.Accepts) != 1 || response.Accepts[0].Amount != tt.expectedAmount) {
+				t.Errorf("Expected price %s, got %+v", tt.expectedAmount, response.Accepts)
+			}
+		})
+	}
+
+	// The payment must match the computed price
+	priced := requirements
+	priced.Amount = "2500"
+	for header, expectedStatus := range map[string]int{
+		paidHeader(priced):       http.StatusOK,
+		paidHeader(requirements): http.StatusPaymentRequired,
+	} {
+		req := httptest.NewRequest("GET", "/rows?rows=25", nil)
+		req.Header.Set("PAYMENT-SIGNATURE", header)
+		recorder := httptest.NewRecorder()
+		router.ServeHTTP(recorder, req)
+		if recorder.Code != expectedStatus {
+			t.Errorf("Expected status %d, got %d: %s", expectedStatus, recorder.Code, recorder.Body.String())
+		}
+	}
+}
+
+func TestPaymentHeaderName(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	facilitator := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		switch r.URL.Path {
+		case "/verify":
+			json.NewEncoder(w).Encode(types.VerifyResponse{IsValid: true})
+		case "/settle":
+			json.NewEncoder(w).Encode(types.SettleResponse{Success: true, Transaction: "0xabc"})
+		}
+	}))
+	defer facilitator.Close()
+
+	requirements := types.PaymentRequirements{
+		Scheme:  "exact",
+		Network: "eip155:84532",
+		Amount:  "1000",
+		Asset:   "0x036CbD53842c5426634e7929541eC2318f3dCF7e",
+		PayTo:   "0x3C44CdDdB6a900fa2b585dd299e03d12FA4293BC",
+	}
+
+	tests := []struct {
+		name       string
+		configured string
+		advertised string
+	}{
+		{"default header", "", ""},
+		{"explicit default header", utils.DefaultPaymentHeaderName, ""},
+		{"custom header", "X-PAYMENT", "X-PAYMENT"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := &MiddlewareConfig{
+				FacilitatorURL:      facilitator.URL,
+				DefaultRequirements: requirements,
+				ProtectedPaths:      []string{"/data"},
+				PaymentHeaderName:   tt.configured,
+			}
+			if err := cfg.Validate(); err != nil {
+				t.Fatalf("Expected valid config, got %v", err)
+			}
+			router := gin.New()
+			router.Use(NewX402Middleware(cfg).Handler())
+			router.GET("/data", func(ctx *gin.Context) {
+				ctx.String(http.StatusOK, "data")
+			})
+
+			// Only a custom header is advertised in the 402
+			recorder := httptest.NewRecorder()
+			router.ServeHTTP(recorder, httptest.NewRequest("GET", "/data", nil))
+			var response types.PaymentRequired
+			json.Unmarshal(recorder.Body.Bytes(), &response)
+			if tt.advertised == "" && response.Extensions != nil {
+				t.Errorf("Expected no extensions for the default header, got %v", response.Extensions)
+			}
+			if name := utils.GetPaymentHeaderName(&response); name != cfg.GetPaymentHeaderName() {
+				t.Errorf("Expected clients to read header %s, got %s", cfg.GetPaymentHeaderName(), name)
+			}
+
+			// Payments are read from the configured header only
+			for _, header := range []string{utils.DefaultPaymentHeaderName, "X-PAYMENT"} {
+				req := httptest.NewRequest("GET", "/data", nil)
+				req.Header.Set(header, paidHeader(requirements))
+				recorder := httptest.NewRecorder()
+				router.ServeHTTP(recorder, req)
+				expected := http.StatusPaymentRequired
+				if header == cfg.GetPaymentHeaderName() {
+					expected = http.StatusOK
+				}
+				if recorder.Code != expected {
+					t.Errorf("Expected status %d for payment in %s, got %d", expected, header, recorder.Code)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,309 @@
+package middleware
+
+import (
+	"context"
+	"log/slog"
+	"net/http"
+	"time"
+
+	"github.com/vorpalengineering/x402-go/facilitator"
+	"github.com/vorpalengineering/x402-go/types"
+	"github.com/vorpalengineering/x402-go/utils"
+)
+
+// Payment is a verified payment awaiting settlement
+type Payment struct {
+	// Route the payment was verified for
+	Route string
+
+	// Header is the encoded payment as received
+	Header string
+
+	Payload      *types.PaymentPayload
+	Requirements types.PaymentRequirements
+
+	// Payer is the address the payment is drawn from, when known
+	Payer string
+
+	exact *types.ExactEVMSchemePayload
+
+	// replayKey is the payment's claim in the replay cache
+	replayKey string
+
+	// version is the x402 version the client paid with
+	version int
+}
+
+// PaymentError refuses a request, with the HTTP status the middleware
+// responds with
+type PaymentError struct {
+	Status  int
+	Code    string
+	Message string
+
+	// PaymentRequired is the body of 402 responses asking for a payment,
+	// also sent in the PAYMENT-REQUIRED header
+	PaymentRequired *types.PaymentRequired
+}
+
+func (e *PaymentError) Error() string {
+	return e.Message
+}
+
+// Protects reports whether route requires payment
+func (m *X402Middleware) Protects(route string) bool {
+	return m.isProtectedPath(route)
+}
+
+// VerifyPayment runs the middleware's checks on an encoded payment for route
+// without an HTTP request, for transports the middleware does not adapt
+// (e.g. gRPC metadata). PricingFunc does not apply, as there is no request to
+// price. Refusals are *PaymentError. Settle the payment with
+// SettlePayment once the request has been served.
+func (m *X402Middleware) VerifyPayment(ctx context.Context, route, header string) (*Payment, error) {
+	if header == "" {
+		return nil, &PaymentError{
+			Status:          http.StatusPaymentRequired,
+			Message:         m.config.GetPaymentHeaderName() + " header is required",
+			PaymentRequired: m.paymentRequired(route, route, m.getRequirements(route)),
+		}
+	}
+	payment, refusal := m.verifyPayment(ctx, m.logger.With("path", route), route, header, m.getRequirements(route))
+	if refusal != nil {
+		return nil, refusal
+	}
+	return payment, nil
+}
+
+// SettlePayment settles a payment returned by VerifyPayment, abandoning the
+// facilitator request when ctx is done. amount charges "upto" payments less
+// than the route's price, "" charges the price. Failed settlements are
+// *PaymentError.
+func (m *X402Middleware) SettlePayment(ctx context.Context, payment *Payment, amount string) (*types.SettleResponse, error) {
+	settleResp, refusal := m.settlePayment(ctx, m.logger.With("path", payment.Route), payment, amount)
+	if refusal != nil {
+		return nil, refusal
+	}
+	return settleResp, nil
+}
+
+// ReleasePayment gives up a payment returned by VerifyPayment without
+// settling it, e.g. when the request failed, so the client can present it
+// again despite the replay cache
+func (m *X402Middleware) ReleasePayment(ctx context.Context, payment *Payment) {
+	m.releasePayment(ctx, payment)
+}
+
+// verifyPayment decodes, prechecks and verifies a payment header against
+// accepts, then applies the attestation gate
+func (m *X402Middleware) verifyPayment(ctx context.Context, logger *slog.Logger, route, header string, accepts []types.PaymentRequirements) (*Payment, *PaymentError) {
+	// Refuse paid requests once shutdown has begun
+	if m.settlements.isDraining() {
+		return nil, &PaymentError{Status: http.StatusServiceUnavailable, Message: ErrShuttingDown.Error()}
+	}
+
+	// Decode payment header into PaymentPayload
+	paymentPayload, exactPayload, err := m.decoder.decode(header)
+	if err != nil {
+		logger.Info("invalid payment header", "error", err)
+		return nil, &PaymentError{Status: http.StatusBadRequest, Message: "Invalid payment header: " + err.Error()}
+	}
+	version := paymentPayload.X402Version
+	if version == types.X402VersionV1 && m.speaks(types.X402VersionV1) {
+		upgradeV1Payload(paymentPayload, exactPayload, accepts)
+	}
+
+	// Reject obvious mismatches locally before calling the facilitator
+	requirements, code, reason := precheckPayment(paymentPayload, exactPayload, accepts, time.Now())
+	logger = logger.With("scheme", paymentPayload.Accepted.Scheme, "network", paymentPayload.Accepted.Network)
+	if exactPayload != nil {
+		logger = logger.With("payer", exactPayload.Authorization.From)
+	}
+	if code != "" {
+		logger.Info("payment rejected", "code", code, "reason", reason)
+		return nil, m.paymentRejected(accepts, code, reason)
+	}
+
+	payment := &Payment{
+		Route:        route,
+		Header:       header,
+		Payload:      paymentPayload,
+		Requirements: requirements,
+		exact:        exactPayload,
+		version:      version,
+	}
+
+	// Refuse headers already paying for another request, before calling
+	// the facilitator
+	if m.config.ReplayStore != nil {
+		key := replayKey(paymentPayload, exactPayload, requirements)
+		claimed, err := m.config.ReplayStore.Claim(ctx, key, replayExpiry(exactPayload))
+		if err != nil {
+			logger.Error("failed to check replay cache", "error", err)
+		} else if !claimed {
+			logger.Info("payment replayed")
+			return nil, m.paymentRejected(accepts, types.ErrCodeNonceUsed, "payment already used")
+		} else {
+			payment.replayKey = key
+		}
+	}
+
+	// Verify payment in process or with facilitator
+	verifyResp, err := m.verify(ctx, paymentPayload, exactPayload, requirements)
+	if err != nil {
+		// Facilitator communication error
+		logger.Error("failed to verify payment", "error", err)
+		m.config.Metrics.observeVerification(m.metricsRoute(route), MetricResultError)
+		m.releasePayment(ctx, payment)
+		return nil, &PaymentError{Status: http.StatusBadGateway, Message: "Failed to verify payment: " + err.Error()}
+	}
+
+	// Check if payment is valid
+	if !verifyResp.IsValid {
+		logger.Info("payment invalid", "code", verifyResp.InvalidReason, "reason", verifyResp.InvalidMessage)
+		m.config.Metrics.observeVerification(m.metricsRoute(route), MetricResultInvalid)
+		m.releasePayment(ctx, payment)
+		return nil, m.paymentRejected(accepts, verifyResp.InvalidReason, verifyResp.InvalidMessage)
+	}
+
+	m.config.Metrics.observeVerification(m.metricsRoute(route), MetricResultValid)
+	payment.Payer = verifyResp.Payer
+	if payment.Payer == "" && exactPayload != nil {
+		payment.Payer = exactPayload.Authorization.From
+	}
+
+	// Refuse unattested payers before fulfilling or settling
+	if m.attestationGate != nil && m.attestationGate.applies(route, requirements) {
+		attested, err := m.attestationGate.check(ctx, payment.Payer, requirements)
+		if err != nil {
+			logger.Error("failed to check payer attestation", "error", err)
+			m.releasePayment(ctx, payment)
+			return nil, &PaymentError{
+				Status:  http.StatusBadGateway,
+				Code:    AttestationErrCheckFailed,
+				Message: "Failed to check payer attestation: " + err.Error(),
+			}
+		}
+		if !attested {
+			logger.Info("payer not attested", "payer", payment.Payer)
+			m.releasePayment(ctx, payment)
+			message := "Payer " + payment.Payer + " is not attested for this resource"
+			if payment.Payer == "" {
+				message = "Payer could not be determined, so it cannot be attested for this resource"
+			}
+			return nil, &PaymentError{
+				Status:  http.StatusForbidden,
+				Code:    AttestationErrNotAttested,
+				Message: message,
+			}
+		}
+	}
+
+	return payment, nil
+}
+
+// verify checks a prechecked payment against requirements, in process when
+// LocalVerification is set and the payment allows it, with the facilitator
+// otherwise
+func (m *X402Middleware) verify(ctx context.Context, payload *types.PaymentPayload, exact *types.ExactEVMSchemePayload, requirements types.PaymentRequirements) (*types.VerifyResponse, error) {
+	if m.config.LocalVerification && exact != nil && !utils.IsSolanaNetwork(requirements.Network) {
+		failures := facilitator.VerifyOffline(payload, &requirements, time.Now())
+		if len(failures) == 0 {
+			return &types.VerifyResponse{X402Version: 2, IsValid: true, Payer: exact.Authorization.From}, nil
+		}
+		// Contract wallets can only be checked on-chain
+		if failures[0].Check != facilitator.VerifyCheckSignature {
+			return &types.VerifyResponse{
+				X402Version:    2,
+				InvalidReason:  failures[0].Code,
+				InvalidMessage: failures[0].Reason,
+				Payer:          exact.Authorization.From,
+			}, nil
+		}
+	}
+
+	return m.facilitator.VerifyContext(ctx, &types.VerifyRequest{
+		PaymentPayload:      *payload,
+		PaymentRequirements: requirements,
+	})
+}
+
+// paymentRejected is the 402 response to a payment that does not satisfy accepts
+func (m *X402Middleware) paymentRejected(accepts []types.PaymentRequirements, code, reason string) *PaymentError {
+	return &PaymentError{
+		Status:  http.StatusPaymentRequired,
+		Code:    code,
+		Message: reason,
+		PaymentRequired: &types.PaymentRequired{
+			X402Version: 2,
+			Accepts:     accepts,
+			Error:       reason,
+			ErrorCode:   code,
+			Extensions:  m.paymentRequiredExtensions(),
+		},
+	}
+}
+
+// settlePayment settles payment, retrying as its route's failure policy
+// allows. A payment that fails to settle can be presented again.
+func (m *X402Middleware) settlePayment(ctx context.Context, logger *slog.Logger, payment *Payment, amount string) (*types.SettleResponse, *PaymentError) {
+	settleResp, refusal := m.settleWithRetries(ctx, logger, payment, amount)
+	if refusal != nil {
+		m.releasePayment(context.WithoutCancel(ctx), payment)
+	}
+	return settleResp, refusal
+}
+
+// trySettle settles payment once, reporting whether a failure may succeed
+// when tried again
+func (m *X402Middleware) trySettle(ctx context.Context, logger *slog.Logger, payment *Payment, amount string) (*types.SettleResponse, *PaymentError, bool) {
+	settleReq := settleRequest(payment, amount)
+
+	// Track the settlement so shutdown waits for it
+	settlementID, ok := m.settlements.start(PendingSettlement{
+		Route:    payment.Route,
+		QueuedAt: time.Now().Unix(),
+		Request:  *settleReq,
+	})
+	if !ok {
+		return nil, &PaymentError{Status: http.StatusServiceUnavailable, Message: ErrShuttingDown.Error()}, false
+	}
+
+	start := time.Now()
+	settleResp, err := m.facilitator.SettleContext(ctx, settleReq)
+	m.settlements.finish(settlementID)
+	m.config.Metrics.observeSettlement(m.metricsRoute(payment.Route), time.Since(start), payment, settleReq, settleResp, err)
+	if err != nil {
+		logger.Error("failed to settle payment", "error", err)
+		return nil, &PaymentError{Status: http.StatusBadGateway, Message: "Failed to settle payment: " + err.Error()}, transientSettleError(err)
+	}
+
+	if !settleResp.Success {
+		logger.Warn("payment settlement failed", "tx", settleResp.Transaction, "code", settleResp.ErrorReason, "reason", settleResp.ErrorMessage)
+		return nil, &PaymentError{
+			Status:  http.StatusPaymentRequired,
+			Code:    settleResp.ErrorReason,
+			Message: "Payment settlement failed: " + settleResp.ErrorMessage,
+		}, retryableSettlement(settleResp.ErrorReason)
+	}
+
+	return settleResp, nil, false
+}
+
+// settleRequest builds the settle request of payment, charging amount on
+// "upto" routes
+func settleRequest(payment *Payment, amount string) *types.SettleRequest {
+	settleReq := &types.SettleRequest{
+		PaymentPayload:      *payment.Payload,
+		PaymentRequirements: payment.Requirements,
+	}
+
+	// Charge the metered amount of upto payments
+	if payment.Requirements.Scheme == "upto" {
+		settleReq.Amount = payment.Requirements.Amount
+		if amount != "" {
+			settleReq.Amount = amount
+		}
+	}
+	return settleReq
+}
@@ -0,0 +1,181 @@
+package middleware
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/vorpalengineering/x402-go/types"
+	"github.com/vorpalengineering/x402-go/utils"
+)
+
+func TestVerifyAndSettlePayment(t *testing.T) {
+	settleSuccess := true
+	facilitator := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		switch r.URL.Path {
+		case "/verify":
+			json.NewEncoder(w).Encode(types.VerifyResponse{IsValid: true, Payer: "0x70997970C51812dc3A010C7d01b50e0d17dc79C8"})
+		case "/settle":
+			if !settleSuccess {
+				json.NewEncoder(w).Encode(types.SettleResponse{Success: false, ErrorReason: "insufficient_funds"})
+				return
+			}
+			json.NewEncoder(w).Encode(types.SettleResponse{Success: true, Transaction: "0xabc"})
+		}
+	}))
+	defer facilitator.Close()
+
+	requirements := types.PaymentRequirements{
+		Scheme:  "exact",
+		Network: "eip155:84532",
+		Amount:  "1000",
+		Asset:   "0x036CbD53842c5426634e7929541eC2318f3dCF7e",
+		PayTo:   "0x3C44CdDdB6a900fa2b585dd299e03d12FA4293BC",
+	}
+	payload, _ := json.Marshal(types.PaymentPayload{
+		X402Version: 2,
+		Accepted:    requirements,
+		Payload: map[string]any{
+			"signature": "0x" + strings.Repeat("11", 65),
+			"authorization": map[string]any{
+				"from":        "0x70997970C51812dc3A010C7d01b50e0d17dc79C8",
+				"to":          requirements.PayTo,
+				"value":       requirements.Amount,
+				"validAfter":  0,
+				"validBefore": time.Now().Add(time.Minute).Unix(),
+				"nonce":       "0x" + strings.Repeat("01", 32),
+			},
+		},
+	})
+	header := base64.StdEncoding.EncodeToString(payload)
+
+	m := NewX402Middleware(&MiddlewareConfig{
+		FacilitatorURL:      facilitator.URL,
+		DefaultRequirements: requirements,
+		ProtectedPaths:      []string{"/pkg.Service/*"},
+	})
+
+	if !m.Protects("/pkg.Service/Get") || m.Protects("/pkg.Other/Get") {
+		t.Error("Expected only the service's methods to be protected")
+	}
+
+	// Without a payment the requirements are returned
+	var paymentErr *PaymentError
+	_, err := m.VerifyPayment(context.Background(), "/pkg.Service/Get", "")
+	if !errors.As(err, &paymentErr) || paymentErr.Status != http.StatusPaymentRequired || paymentErr.PaymentRequired == nil {
+		t.Fatalf("Expected 402 with requirements, got %v", err)
+	}
+	if accepts := paymentErr.PaymentRequired.Accepts; len(accepts) != 1 || accepts[0].Amount != "1000" {
+		t.Errorf("Expected route requirements, got %+v", accepts)
+	}
+
+	// Malformed payments are refused
+	_, err = m.VerifyPayment(context.Background(), "/pkg.Service/Get", "not base64!")
+	if !errors.As(err, &paymentErr) || paymentErr.Status != http.StatusBadRequest {
+		t.Errorf("Expected 400 for malformed payment, got %v", err)
+	}
+
+	payment, err := m.VerifyPayment(context.Background(), "/pkg.Service/Get", header)
+	if err != nil {
+		t.Fatalf("Expected payment to verify, got %v", err)
+	}
+	if payment.Payer != "0x70997970C51812dc3A010C7d01b50e0d17dc79C8" || payment.Requirements.Amount != "1000" {
+		t.Errorf("Unexpected payment %+v", payment)
+	}
+
+	settleResp, err := m.SettlePayment(context.Background(), payment, "")
+	if err != nil || settleResp.Transaction != "0xabc" {
+		t.Errorf("Expected settlement, got %+v (%v)", settleResp, err)
+	}
+
+	settleSuccess = false
+	_, err = m.SettlePayment(context.Background(), payment, "")
+	if !errors.As(err, &paymentErr) || paymentErr.Status != http.StatusPaymentRequired || paymentErr.Code != "insufficient_funds" {
+		t.Errorf("Expected 402 settlement failure, got %v", err)
+	}
+
+	// Settlement is abandoned with the caller's context
+	settleSuccess = true
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	_, err = m.SettlePayment(ctx, payment, "")
+	if !errors.As(err, &paymentErr) || paymentErr.Status != http.StatusBadGateway || !strings.Contains(err.Error(), "context canceled") {
+		t.Errorf("Expected cancelled settlement, got %v", err)
+	}
+}
+
+func TestLocalVerification(t *testing.T) {
+	var calls []string
+	facilitator := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls = append(calls, r.URL.Path)
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(types.VerifyResponse{IsValid: true})
+	}))
+	defer facilitator.Close()
+
+	requirements := types.PaymentRequirements{
+		Scheme:  "exact",
+		Network: "eip155:84532",
+		Amount:  "1000",
+		Asset:   "0x036CbD53842c5426634e7929541eC2318f3dCF7e",
+		PayTo:   "0x3C44CdDdB6a900fa2b585dd299e03d12FA4293BC",
+	}
+	cfg := &MiddlewareConfig{
+		FacilitatorURL:      facilitator.URL,
+		DefaultRequirements: requirements,
+		ProtectedPaths:      []string{"/data"},
+		LocalVerification:   true,
+	}
+	if err := cfg.Validate(); err == nil || !strings.Contains(err.Error(), "name and version") {
+		t.Errorf("Expected error for requirements without domain, got %v", err)
+	}
+	requirements.Extra = map[string]any{"name": "USDC", "version": "2"}
+	cfg.DefaultRequirements = requirements
+	if err := cfg.Validate(); err != nil {
+		t.Fatalf("Expected valid config, got %v", err)
+	}
+	m := NewX402Middleware(cfg)
+
+	key, _ := crypto.HexToECDSA("ac0974bec39a17e36ba4a6b4d238ff944bacb478cbed5efcae784d7bf4f2ff80")
+	signed := func(value string) string {
+		auth := types.ExactEVMSchemeAuthorization{
+			From:        "0xf39Fd6e51aad88F6F4ce6aB8827279cffFb92266",
+			To:          requirements.PayTo,
+			Value:       value,
+			ValidBefore: time.Now().Add(time.Minute).Unix(),
+			Nonce:       "0x" + strings.Repeat("01", 32),
+		}
+		signature, _ := utils.SignEIP3009(&auth, key, requirements.Asset, "USDC", "2", 84532)
+		payload, _ := json.Marshal(types.PaymentPayload{
+			X402Version: 2,
+			Accepted:    requirements,
+			Payload:     map[string]any{"signature": signature, "authorization": auth},
+		})
+		return base64.StdEncoding.EncodeToString(payload)
+	}
+
+	// Valid signatures are accepted without calling the facilitator
+	payment, err := m.VerifyPayment(context.Background(), "/data", signed("1000"))
+	if err != nil || payment.Payer != "0xf39Fd6e51aad88F6F4ce6aB8827279cffFb92266" {
+		t.Fatalf("Expected verified payment, got %+v %v", payment, err)
+	}
+	if len(calls) != 0 {
+		t.Errorf("Expected no facilitator calls, got %v", calls)
+	}
+
+	// Signatures that do not recover to the payer may be contract wallets
+	if _, err := m.VerifyPayment(context.Background(), "/data", paidHeader(requirements)); err != nil {
+		t.Errorf("Expected facilitator to accept the payment, got %v", err)
+	}
+	if len(calls) != 1 || calls[0] != "/verify" {
+		t.Errorf("Expected a facilitator verify call, got %v", calls)
+	}
+}
@@ -0,0 +1,206 @@
+package middleware
+
+import (
+	"bytes"
+	"html/template"
+	"math/big"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/vorpalengineering/x402-go/types"
+	"github.com/vorpalengineering/x402-go/utils"
+)
+
+// PaywallConfig answers browsers with an HTML payment page instead of the
+// JSON 402 response. The PAYMENT-REQUIRED header is still set, so x402
+// clients sending Accept: text/html are served as usual.
+type PaywallConfig struct {
+	// Template renders the page from a PaywallData. Nil uses a built-in page.
+	Template *template.Template `json:"-" toml:"-"`
+
+	// Title heads the page. Empty uses "Payment Required".
+	Title string `json:"title,omitempty" toml:"title"`
+
+	// Assets names tokens by address, so prices show as "0.01 USDC" instead
+	// of atomic units of an address
+	Assets map[string]PaywallAsset `json:"assets,omitempty" toml:"assets"`
+}
+
+// PaywallAsset is how a token is shown on the payment page
+type PaywallAsset struct {
+	Symbol   string `json:"symbol" toml:"symbol"`
+	Decimals uint8  `json:"decimals" toml:"decimals"`
+}
+
+// PaywallData is what the payment page template renders
+type PaywallData struct {
+	Title    string
+	Resource *types.ResourceInfo
+	Options  []PaywallOption
+
+	// PaymentRequired is the base64 PAYMENT-REQUIRED header value, for
+	// scripts paying from a browser wallet
+	PaymentRequired string
+}
+
+// PaywallOption is one accepted payment
+type PaywallOption struct {
+	Requirements types.PaymentRequirements
+
+	// Amount is the price in tokens when the asset is known, in atomic
+	// units otherwise
+	Amount string
+
+	// Asset is the token's symbol, or its address when unknown
+	Asset string
+
+	// Network is the x402 v1 name of the network when it has one (e.g.
+	// "base-sepolia"), the CAIP-2 id otherwise
+	Network string
+
+	// PaymentURI opens a wallet to transfer the price: an EIP-681 URI on EVM
+	// networks, a Solana Pay URI on Solana when the asset's decimals are
+	// known. Empty otherwise. Encode it as a QR code to pay from a phone.
+	PaymentURI template.URL
+}
+
+var defaultPaywallTemplate = template.Must(template.New("paywall").Parse(`<!DOCTYPE html>
+<html lang="en">
+<head>
+<meta charset="utf-8">
+<meta name="viewport" content="width=device-width, initial-scale=1">
+<title>{{.Title}}</title>
+<style>
+body { font-family: system-ui, sans-serif; max-width: 36rem; margin: 4rem auto; padding: 0 1rem; color: #222; }
+.option { border: 1px solid #ddd; border-radius: 8px; padding: 1rem; margin: 1rem 0; }
+.price { font-size: 1.5rem; font-weight: 600; }
+.meta { color: #666; font-size: 0.9rem; word-break: break-all; }
+a.pay { display: inline-block; margin-top: 0.75rem; padding: 0.5rem 1rem; border-radius: 6px; background: #1652f0; color: #fff; text-decoration: none; }
+</style>
+</head>
+<body>
+<h1>{{.Title}}</h1>
+{{with .Resource}}<p>{{if .Description}}{{.Description}}{{else}}{{.URL}}{{end}} requires a payment.</p>{{end}}
+<p>Pay with an x402 client, or with your wallet:</p>
+{{range .Options}}<div class="option">
+<div class="price">{{.Amount}} {{.Asset}}</div>
+<div class="meta">on {{.Network}} to {{.Requirements.PayTo}}</div>
+{{if .PaymentURI}}<a class="pay" href="{{.PaymentURI}}">Open in wallet</a>{{end}}
+</div>
+{{end}}</body>
+</html>
+`))
+
+// prefersHTML reports whether an Accept header ranks text/html above JSON,
+// as browsers do and API clients sending */* do not
+func prefersHTML(accept string) bool {
+	html, json := 0.0, 0.0
+	for _, part := range strings.Split(accept, ",") {
+		mediaType, params, _ := strings.Cut(strings.TrimSpace(part), ";")
+		q := 1.0
+		for _, param := range strings.Split(params, ";") {
+			if value, ok := strings.CutPrefix(strings.TrimSpace(param), "q="); ok {
+				if parsed, err := strconv.ParseFloat(value, 64); err == nil {
+					q = parsed
+				}
+			}
+		}
+		switch strings.ToLower(strings.TrimSpace(mediaType)) {
+		case "text/html":
+			html = max(html, q)
+		case "application/json":
+			json = max(json, q)
+		case "*/*":
+			html = max(html, q*0.99)
+			json = max(json, q)
+		}
+	}
+	return html > json
+}
+
+// sendPaywall responds with the payment page for response, falling back to
+// JSON if it cannot be rendered
+func (m *X402Middleware) sendPaywall(ctx *gin.Context, response *types.PaymentRequired) {
+	cfg := m.config.Paywall
+	data := PaywallData{
+		Title:           cfg.Title,
+		Resource:        response.Resource,
+		PaymentRequired: ctx.Writer.Header().Get("PAYMENT-REQUIRED"),
+	}
+	if data.Title == "" {
+		data.Title = "Payment Required"
+	}
+	for _, requirements := range response.Accepts {
+		data.Options = append(data.Options, cfg.option(requirements))
+	}
+
+	tmpl := cfg.Template
+	if tmpl == nil {
+		tmpl = defaultPaywallTemplate
+	}
+	var page bytes.Buffer
+	if err := tmpl.Execute(&page, data); err != nil {
+		m.logger.Error("failed to render paywall", "error", err)
+		ctx.JSON(http.StatusPaymentRequired, response)
+		ctx.Abort()
+		return
+	}
+	ctx.Data(http.StatusPaymentRequired, "text/html; charset=utf-8", page.Bytes())
+	ctx.Abort()
+}
+
+// option describes requirements for the payment page
+func (c *PaywallConfig) option(requirements types.PaymentRequirements) PaywallOption {
+	option := PaywallOption{
+		Requirements: requirements,
+		Amount:       requirements.Amount,
+		Asset:        requirements.Asset,
+		Network:      utils.NetworkToV1(requirements.Network),
+	}
+	asset, known := c.asset(requirements.Asset)
+	amount, ok := new(big.Int).SetString(requirements.Amount, 10)
+	if known {
+		option.Asset = asset.Symbol
+		if ok {
+			option.Amount = utils.FormatAmount(amount, asset.Decimals)
+		}
+	}
+	if !ok {
+		return option
+	}
+
+	switch {
+	case utils.IsSolanaNetwork(requirements.Network):
+		if known {
+			option.PaymentURI = template.URL("solana:" + requirements.PayTo + "?" + url.Values{
+				"amount":    {option.Amount},
+				"spl-token": {requirements.Asset},
+			}.Encode())
+		}
+	case strings.HasPrefix(requirements.Network, "eip155:"):
+		chainID, err := utils.GetChainID(requirements.Network)
+		if err == nil {
+			option.PaymentURI = template.URL("ethereum:" + requirements.Asset + "@" + chainID.String() + "/transfer?" + url.Values{
+				"address": {requirements.PayTo},
+				"uint256": {amount.String()},
+			}.Encode())
+		}
+	}
+	return option
+}
+
+// asset looks up a token by address, ignoring case on EVM addresses
+func (c *PaywallConfig) asset(address string) (PaywallAsset, bool) {
+	if asset, ok := c.Assets[address]; ok {
+		return asset, true
+	}
+	for candidate, asset := range c.Assets {
+		if strings.HasPrefix(address, "0x") && strings.EqualFold(candidate, address) {
+			return asset, true
+		}
+	}
+	return PaywallAsset{}, false
+}
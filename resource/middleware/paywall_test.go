@@ -0,0 +1,112 @@
+package middleware
+
+import (
+	"html/template"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/vorpalengineering/x402-go/types"
+)
+
+func TestPaywall(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	requirements := types.PaymentRequirements{
+		Scheme:  "exact",
+		Network: "eip155:84532",
+		Amount:  "1000",
+		Asset:   "0x036CbD53842c5426634e7929541eC2318f3dCF7e",
+		PayTo:   "0x3C44CdDdB6a900fa2b585dd299e03d12FA4293BC",
+	}
+	newRouter := func(paywall *PaywallConfig) *gin.Engine {
+		router := gin.New()
+		router.Use(NewX402Middleware(&MiddlewareConfig{
+			FacilitatorURL:      "http://localhost:4020",
+			DefaultRequirements: requirements,
+			ProtectedPaths:      []string{"/data"},
+			Paywall:             paywall,
+		}).Handler())
+		router.GET("/data", func(ctx *gin.Context) {
+			ctx.String(http.StatusOK, "data")
+		})
+		return router
+	}
+	paywall := &PaywallConfig{
+		Assets: map[string]PaywallAsset{
+			"0x036cbd53842c5426634e7929541ec2318f3dcf7e": {Symbol: "USDC", Decimals: 6},
+		},
+	}
+
+	tests := []struct {
+		name         string
+		paywall      *PaywallConfig
+		accept       string
+		expectedType string
+		expectedBody []string
+	}{
+		{"browser", paywall, "text/html,application/xhtml+xml,application/xml;q=0.9,*/*;q=0.8", "text/html", []string{
+			"0.001 USDC",
+			"on base-sepolia to 0x3C44CdDdB6a900fa2b585dd299e03d12FA4293BC",
+			`href="ethereum:0x036CbD53842c5426634e7929541eC2318f3dCF7e@84532/transfer?address=0x3C44CdDdB6a900fa2b585dd299e03d12FA4293BC&amp;uint256=1000"`,
+		}},
+		{"api client", paywall, "*/*", "application/json", []string{`"x402Version":2`}},
+		{"no accept", paywall, "", "application/json", nil},
+		{"paywall disabled", nil, "text/html", "application/json", nil},
+		{"custom template", &PaywallConfig{
+			Template: template.Must(template.New("page").Parse(`{{range .Options}}Pay {{.Amount}} of {{.Asset}}{{end}}`)),
+		}, "text/html", "text/html", []string{"Pay 1000 of 0x036CbD53842c5426634e7929541eC2318f3dCF7e"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest("GET", "/data", nil)
+			if tt.accept != "" {
+				req.Header.Set("Accept", tt.accept)
+			}
+			recorder := httptest.NewRecorder()
+			newRouter(tt.paywall).ServeHTTP(recorder, req)
+
+			if recorder.Code != http.StatusPaymentRequired {
+				t.Fatalf("Expected status 402, got %d", recorder.Code)
+			}
+			if recorder.Header().Get("PAYMENT-REQUIRED") == "" {
+				t.Error("Expected PAYMENT-REQUIRED header")
+			}
+			if contentType := recorder.Header().Get("Content-Type"); !strings.HasPrefix(contentType, tt.expectedType) {
+				t.Errorf("Expected content type %s, got %s", tt.expectedType, contentType)
+			}
+			for _, expected := range tt.expectedBody {
+				if !strings.Contains(recorder.Body.String(), expected) {
+					t.Errorf("Expected body to contain %q, got %s", expected, recorder.Body.String())
+				}
+			}
+		})
+	}
+}
+
+func TestPaywallSolanaURI(t *testing.T) {
+	paywall := &PaywallConfig{
+		Assets: map[string]PaywallAsset{
+			"4zMMC9srt5Ri5X14GAgXhaHii3GnPAEERYPJgZJDncDU": {Symbol: "USDC", Decimals: 6},
+		},
+	}
+	option := paywall.option(types.PaymentRequirements{
+		Scheme:  "exact",
+		Network: "solana:devnet",
+		Amount:  "1500000",
+		Asset:   "4zMMC9srt5Ri5X14GAgXhaHii3GnPAEERYPJgZJDncDU",
+		PayTo:   "9xQeWvG816bUx9EPjHmaT23yvVM2ZWbrrpZb9PusVFin",
+	})
+	expected := "solana:9xQeWvG816bUx9EPjHmaT23yvVM2ZWbrrpZb9PusVFin?amount=1.5&spl-token=4zMMC9srt5Ri5X14GAgXhaHii3GnPAEERYPJgZJDncDU"
+	if string(option.PaymentURI) != expected {
+		t.Errorf("Expected %s, got %s", expected, option.PaymentURI)
+	}
+
+	// Without decimals the amount cannot be given in tokens
+	unknown := (&PaywallConfig{}).option(types.PaymentRequirements{Network: "solana:devnet", Amount: "1500000"})
+	if unknown.PaymentURI != "" {
+		t.Errorf("Expected no payment URI for unknown asset, got %s", unknown.PaymentURI)
+	}
+}
@@ -0,0 +1,114 @@
+package middleware
+
+import (
+	"fmt"
+	"math/big"
+	"strings"
+	"time"
+
+	"github.com/vorpalengineering/x402-go/types"
+	"github.com/vorpalengineering/x402-go/utils"
+)
+
+// precheckPayment validates a decoded payload locally before the facilitator
+// is called, so obvious mismatches are rejected without a network round trip.
+// It returns the accepted entry the payload was made for, or a reason the
+// payment cannot be valid. exact is the typed payload from the decoder, if any.
+func precheckPayment(payload *types.PaymentPayload, exact *types.ExactEVMSchemePayload, accepts []types.PaymentRequirements, now time.Time) (types.PaymentRequirements, string, string) {
+	chosen := payload.Accepted
+
+	// Narrow accepted entries field by field for a precise reason
+	candidates := filterRequirements(accepts, func(req types.PaymentRequirements) bool {
+		return req.Scheme == chosen.Scheme
+	})
+	if len(candidates) == 0 {
+		return types.PaymentRequirements{}, types.ErrCodeUnsupportedScheme, fmt.Sprintf("unsupported scheme: %s", chosen.Scheme)
+	}
+	candidates = filterRequirements(candidates, func(req types.PaymentRequirements) bool {
+		return req.Network == chosen.Network
+	})
+	if len(candidates) == 0 {
+		return types.PaymentRequirements{}, types.ErrCodeUnsupportedNetwork, fmt.Sprintf("unsupported network: %s", chosen.Network)
+	}
+	candidates = filterRequirements(candidates, func(req types.PaymentRequirements) bool {
+		return strings.EqualFold(req.Asset, chosen.Asset)
+	})
+	if len(candidates) == 0 {
+		return types.PaymentRequirements{}, types.ErrCodeUnsupportedAsset, fmt.Sprintf("unsupported asset: %s", chosen.Asset)
+	}
+	candidates = filterRequirements(candidates, func(req types.PaymentRequirements) bool {
+		return strings.EqualFold(req.PayTo, chosen.PayTo)
+	})
+	if len(candidates) == 0 {
+		return types.PaymentRequirements{}, types.ErrCodeRecipientMismatch, fmt.Sprintf("recipient mismatch: got %s", chosen.PayTo)
+	}
+	candidates = filterRequirements(candidates, func(req types.PaymentRequirements) bool {
+		return req.Amount == chosen.Amount
+	})
+	if len(candidates) == 0 {
+		return types.PaymentRequirements{}, types.ErrCodeInvalidAmount, fmt.Sprintf("amount mismatch: got %s", chosen.Amount)
+	}
+	requirements := candidates[0]
+
+	// Scheme specific checks, Solana transactions are left to the facilitator
+	if requirements.Scheme == "exact" && utils.IsSolanaNetwork(requirements.Network) {
+		if _, err := utils.ExtractSVMTransaction(payload); err != nil {
+			return types.PaymentRequirements{}, types.ErrCodeInvalidPayload, fmt.Sprintf("invalid transaction: %v", err)
+		}
+	} else if requirements.Scheme == "exact" {
+		if code, reason := precheckExactAuthorization(payload, exact, &requirements, now); code != "" {
+			return types.PaymentRequirements{}, code, reason
+		}
+	}
+
+	return requirements, "", ""
+}
+
+// precheckExactAuthorization checks the signed authorization against the
+// requirements and the current time, returning the error code and reason of a
+// rejection. Signature and balance are left to the facilitator.
+func precheckExactAuthorization(payload *types.PaymentPayload, exact *types.ExactEVMSchemePayload, requirements *types.PaymentRequirements, now time.Time) (string, string) {
+	if exact == nil {
+		// Extract again only to report why the payload could not be parsed
+		if _, err := utils.ExtractExactAuthorization(payload); err != nil {
+			return types.ErrCodeInvalidPayload, fmt.Sprintf("invalid authorization: %v", err)
+		}
+		return types.ErrCodeInvalidPayload, "invalid exact payload"
+	}
+	auth := &exact.Authorization
+
+	// Check recipient
+	if !strings.EqualFold(auth.To, requirements.PayTo) {
+		return types.ErrCodeRecipientMismatch, fmt.Sprintf("recipient mismatch: got %s, expected %s", auth.To, requirements.PayTo)
+	}
+
+	// Check amount
+	paymentAmount, ok := new(big.Int).SetString(auth.Value, 10)
+	if !ok {
+		return types.ErrCodeInvalidAmount, "invalid payment amount format"
+	}
+	requiredAmount, ok := new(big.Int).SetString(requirements.Amount, 10)
+	if ok && paymentAmount.Cmp(requiredAmount) < 0 {
+		return types.ErrCodeInvalidAmount, fmt.Sprintf("insufficient amount: got %s, required %s", auth.Value, requirements.Amount)
+	}
+
+	// Check time window
+	if now.Unix() < auth.ValidAfter {
+		return types.ErrCodeNotYetValid, fmt.Sprintf("payment not yet valid (valid after %d)", auth.ValidAfter)
+	}
+	if now.Unix() > auth.ValidBefore {
+		return types.ErrCodeExpired, fmt.Sprintf("payment expired (valid before %d)", auth.ValidBefore)
+	}
+
+	return "", ""
+}
+
+func filterRequirements(accepts []types.PaymentRequirements, keep func(types.PaymentRequirements) bool) []types.PaymentRequirements {
+	var out []types.PaymentRequirements
+	for _, req := range accepts {
+		if keep(req) {
+			out = append(out, req)
+		}
+	}
+	return out
+}
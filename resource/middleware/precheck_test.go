@@ -0,0 +1,123 @@
+package middleware
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/vorpalengineering/x402-go/types"
+)
+
+func TestPrecheckPayment(t *testing.T) {
+	now := time.Unix(1700000000, 0)
+	usdc := types.PaymentRequirements{
+		Scheme:  "exact",
+		Network: "eip155:8453",
+		Amount:  "1000",
+		Asset:   "0x833589fCD6eDb6E08f4c7C32D4f71b54bdA02913",
+		PayTo:   "0x3C44CdDdB6a900fa2b585dd299e03d12FA4293BC",
+	}
+	accepts := []types.PaymentRequirements{usdc}
+
+	// payment builds a payload for requirements, adjusted by edit
+	payment := func(requirements types.PaymentRequirements, edit func(auth *types.ExactEVMSchemeAuthorization)) (*types.PaymentPayload, *types.ExactEVMSchemePayload) {
+		exact := &types.ExactEVMSchemePayload{
+			Signature: "0x" + strings.Repeat("11", 65),
+			Authorization: types.ExactEVMSchemeAuthorization{
+				From:        "0x70997970C51812dc3A010C7d01b50e0d17dc79C8",
+				To:          requirements.PayTo,
+				Value:       requirements.Amount,
+				ValidAfter:  now.Unix() - 60,
+				ValidBefore: now.Unix() + 60,
+				Nonce:       "0x" + strings.Repeat("01", 32),
+			},
+		}
+		if edit != nil {
+			edit(&exact.Authorization)
+		}
+		return &types.PaymentPayload{X402Version: 2, Accepted: requirements}, exact
+	}
+	with := func(edit func(req *types.PaymentRequirements)) types.PaymentRequirements {
+		req := usdc
+		edit(&req)
+		return req
+	}
+
+	tests := []struct {
+		name         string
+		accepted     types.PaymentRequirements
+		edit         func(auth *types.ExactEVMSchemeAuthorization)
+		noExact      bool
+		expectedCode string
+	}{
+		{"valid", usdc, nil, false, ""},
+		{"unsupported scheme", with(func(req *types.PaymentRequirements) { req.Scheme = "upto" }), nil, false, types.ErrCodeUnsupportedScheme},
+		{"unsupported network", with(func(req *types.PaymentRequirements) { req.Network = "eip155:1" }), nil, false, types.ErrCodeUnsupportedNetwork},
+		{"unsupported asset", with(func(req *types.PaymentRequirements) { req.Asset = "0x036CbD53842c5426634e7929541eC2318f3dCF7e" }), nil, false, types.ErrCodeUnsupportedAsset},
+		{"accepted payTo mismatch", with(func(req *types.PaymentRequirements) { req.PayTo = "0x90F79bf6EB2c4f870365E785982E1f101E93b906" }), nil, false, types.ErrCodeRecipientMismatch},
+		{"accepted amount mismatch", with(func(req *types.PaymentRequirements) { req.Amount = "999" }), nil, false, types.ErrCodeInvalidAmount},
+		{"malformed exact payload", usdc, nil, true, types.ErrCodeInvalidPayload},
+		{"authorization to another address", usdc, func(auth *types.ExactEVMSchemeAuthorization) { auth.To = "0x90F79bf6EB2c4f870365E785982E1f101E93b906" }, false, types.ErrCodeRecipientMismatch},
+		{"invalid value", usdc, func(auth *types.ExactEVMSchemeAuthorization) { auth.Value = "lots" }, false, types.ErrCodeInvalidAmount},
+		{"insufficient value", usdc, func(auth *types.ExactEVMSchemeAuthorization) { auth.Value = "999" }, false, types.ErrCodeInvalidAmount},
+		{"not yet valid", usdc, func(auth *types.ExactEVMSchemeAuthorization) { auth.ValidAfter = now.Unix() + 1 }, false, types.ErrCodeNotYetValid},
+		{"expired", usdc, func(auth *types.ExactEVMSchemeAuthorization) { auth.ValidBefore = now.Unix() - 1 }, false, types.ErrCodeExpired},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			payload, exact := payment(tt.accepted, tt.edit)
+			if tt.noExact {
+				exact = nil
+			}
+			requirements, code, reason := precheckPayment(payload, exact, accepts, now)
+			if code != tt.expectedCode {
+				t.Fatalf("Expected code %q, got %q (%s)", tt.expectedCode, code, reason)
+			}
+			if code == "" && !reflect.DeepEqual(requirements, usdc) {
+				t.Errorf("Expected the accepted requirements, got %+v", requirements)
+			}
+			if code != "" && reason == "" {
+				t.Errorf("Expected a reason with code %q", code)
+			}
+		})
+	}
+}
+
+func TestPrecheckPaymentChoosesAcceptedEntry(t *testing.T) {
+	now := time.Unix(1700000000, 0)
+	base := types.PaymentRequirements{
+		Scheme:  "exact",
+		Network: "eip155:8453",
+		Amount:  "1000",
+		Asset:   "0x833589fCD6eDb6E08f4c7C32D4f71b54bdA02913",
+		PayTo:   "0x3C44CdDdB6a900fa2b585dd299e03d12FA4293BC",
+	}
+	otherNetwork := base
+	otherNetwork.Network = "eip155:84532"
+	otherAsset := base
+	otherAsset.Asset = "0xfde4C96c8593536E31F229EA8f37b2ADa2699bb2"
+	otherAsset.Amount = "2000"
+	accepts := []types.PaymentRequirements{base, otherNetwork, otherAsset}
+
+	// The payload names the second asset, in another case
+	accepted := otherAsset
+	accepted.Asset = strings.ToLower(otherAsset.Asset)
+	payload := &types.PaymentPayload{X402Version: 2, Accepted: accepted}
+	exact := &types.ExactEVMSchemePayload{
+		Authorization: types.ExactEVMSchemeAuthorization{
+			To:          otherAsset.PayTo,
+			Value:       otherAsset.Amount,
+			ValidBefore: now.Unix() + 60,
+		},
+	}
+
+	requirements, code, reason := precheckPayment(payload, exact, accepts, now)
+	if code != "" {
+		t.Fatalf("Expected payment to be accepted, got %s (%s)", code, reason)
+	}
+	if !reflect.DeepEqual(requirements, otherAsset) {
+		t.Errorf("Expected the entry of the paid asset, got %+v", requirements)
+	}
+}
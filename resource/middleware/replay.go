@@ -0,0 +1,115 @@
+package middleware
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/vorpalengineering/x402-go/types"
+)
+
+// DefaultReplayTTL is how long payments without a validity window of their
+// own are remembered by the replay cache
+const DefaultReplayTTL = time.Hour
+
+// ReplayStore remembers accepted payment headers, so a signed header cannot
+// pay for several requests while its settlement is pending
+type ReplayStore interface {
+	// Claim records key until expiresAt, returning false if it is already
+	// recorded
+	Claim(ctx context.Context, key string, expiresAt time.Time) (bool, error)
+
+	// Release forgets key, for payments that were not settled
+	Release(ctx context.Context, key string) error
+}
+
+// replayKey identifies a payment in the replay cache by what it pays with,
+// so the same authorization encoded another way is still recognized. Exact
+// payments are keyed by network, asset, payer and nonce, other payloads by
+// their canonical JSON encoding.
+func replayKey(payload *types.PaymentPayload, exact *types.ExactEVMSchemePayload, requirements types.PaymentRequirements) string {
+	var identity string
+	if exact != nil {
+		nonce := strings.TrimPrefix(strings.ToLower(exact.Authorization.Nonce), "0x")
+		if decoded, err := hex.DecodeString(nonce); err == nil {
+			nonce = hex.EncodeToString(common.LeftPadBytes(decoded, 32))
+		}
+		identity = strings.Join([]string{
+			requirements.Network,
+			strings.ToLower(requirements.Asset),
+			strings.ToLower(exact.Authorization.From),
+			nonce,
+		}, "|")
+	} else {
+		// Marshaling sorts map keys and drops insignificant whitespace
+		encoded, _ := json.Marshal(payload.Payload)
+		identity = requirements.Network + "|" + string(encoded)
+	}
+	hash := sha256.Sum256([]byte(identity))
+	return hex.EncodeToString(hash[:])
+}
+
+// replayExpiry is when a payment can no longer be replayed, as it expires
+func replayExpiry(exact *types.ExactEVMSchemePayload) time.Time {
+	if exact != nil && exact.Authorization.ValidBefore > 0 {
+		return time.Unix(exact.Authorization.ValidBefore+1, 0)
+	}
+	return time.Now().Add(DefaultReplayTTL)
+}
+
+// releasePayment forgets an unsettled payment in the replay cache, so the
+// client can present it again
+func (m *X402Middleware) releasePayment(ctx context.Context, payment *Payment) {
+	if payment == nil || payment.replayKey == "" {
+		return
+	}
+	if err := m.config.ReplayStore.Release(ctx, payment.replayKey); err != nil {
+		m.logger.Error("failed to release payment from replay cache", "route", payment.Route, "error", err)
+	}
+	payment.replayKey = ""
+}
+
+// MemoryReplayStore remembers payment headers in memory, per instance
+type MemoryReplayStore struct {
+	mu      sync.Mutex
+	claims  map[string]time.Time
+	pruneAt time.Time
+}
+
+func NewMemoryReplayStore() *MemoryReplayStore {
+	return &MemoryReplayStore{claims: make(map[string]time.Time)}
+}
+
+func (s *MemoryReplayStore) Claim(ctx context.Context, key string, expiresAt time.Time) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	// Forget expired claims, at most once a minute
+	now := time.Now()
+	if !now.Before(s.pruneAt) {
+		for k, expiry := range s.claims {
+			if !now.Before(expiry) {
+				delete(s.claims, k)
+			}
+		}
+		s.pruneAt = now.Add(time.Minute)
+	}
+
+	if expiry, exists := s.claims[key]; exists && now.Before(expiry) {
+		return false, nil
+	}
+	s.claims[key] = expiresAt
+	return true, nil
+}
+
+func (s *MemoryReplayStore) Release(ctx context.Context, key string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.claims, key)
+	return nil
+}
@@ -0,0 +1,189 @@
+package middleware
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/vorpalengineering/x402-go/types"
+)
+
+func TestReplayCache(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	var calls []string
+	facilitator := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls = append(calls, r.URL.Path)
+		w.Header().Set("Content-Type", "application/json")
+		switch r.URL.Path {
+		case "/verify":
+			json.NewEncoder(w).Encode(types.VerifyResponse{IsValid: true})
+		case "/settle":
+			json.NewEncoder(w).Encode(types.SettleResponse{Success: true, Transaction: "0xabc"})
+		}
+	}))
+	defer facilitator.Close()
+
+	requirements := types.PaymentRequirements{
+		Scheme:  "exact",
+		Network: "eip155:84532",
+		Amount:  "1000",
+		Asset:   "0x036CbD53842c5426634e7929541eC2318f3dCF7e",
+		PayTo:   "0x3C44CdDdB6a900fa2b585dd299e03d12FA4293BC",
+	}
+	cfg := &MiddlewareConfig{
+		FacilitatorURL:      facilitator.URL,
+		DefaultRequirements: requirements,
+		ProtectedPaths:      []string{"/data", "/fail"},
+		ReplayStore:         NewMemoryReplayStore(),
+	}
+	router := gin.New()
+	router.Use(NewX402Middleware(cfg).Handler())
+	router.GET("/data", func(ctx *gin.Context) {
+		ctx.String(http.StatusOK, "data")
+	})
+	router.GET("/fail", func(ctx *gin.Context) {
+		ctx.String(http.StatusInternalServerError, "failed")
+	})
+
+	request := func(path, header string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest("GET", path, nil)
+		req.Header.Set("PAYMENT-SIGNATURE", header)
+		recorder := httptest.NewRecorder()
+		router.ServeHTTP(recorder, req)
+		return recorder
+	}
+
+	// An unsettled payment can be presented again
+	header := paidHeader(requirements)
+	if res := request("/fail", header); res.Code != http.StatusInternalServerError {
+		t.Fatalf("Expected status 500, got %d", res.Code)
+	}
+	if res := request("/data", header); res.Code != http.StatusOK {
+		t.Fatalf("Expected status 200 after failed request, got %d: %s", res.Code, res.Body.String())
+	}
+
+	// A settled one is refused on every route, without calling the facilitator
+	calls = nil
+	for _, path := range []string{"/data", "/fail"} {
+		res := request(path, header)
+		if res.Code != http.StatusPaymentRequired {
+			t.Fatalf("Expected status 402 for replay on %s, got %d", path, res.Code)
+		}
+		encoded, _ := base64.StdEncoding.DecodeString(res.Header().Get("PAYMENT-REQUIRED"))
+		var paymentRequired types.PaymentRequired
+		json.Unmarshal(encoded, &paymentRequired)
+		if paymentRequired.ErrorCode != types.ErrCodeNonceUsed {
+			t.Errorf("Expected error code %s, got %s", types.ErrCodeNonceUsed, paymentRequired.ErrorCode)
+		}
+	}
+	if len(calls) != 0 {
+		t.Errorf("Expected no facilitator calls for replays, got %v", calls)
+	}
+}
+
+func TestReplayKeyDecodesPayment(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	facilitator := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		switch r.URL.Path {
+		case "/verify":
+			json.NewEncoder(w).Encode(types.VerifyResponse{IsValid: true})
+		case "/settle":
+			json.NewEncoder(w).Encode(types.SettleResponse{Success: true, Transaction: "0xabc"})
+		}
+	}))
+	defer facilitator.Close()
+
+	requirements := types.PaymentRequirements{
+		Scheme:  "exact",
+		Network: "eip155:84532",
+		Amount:  "1000",
+		Asset:   "0x036CbD53842c5426634e7929541eC2318f3dCF7e",
+		PayTo:   "0x3C44CdDdB6a900fa2b585dd299e03d12FA4293BC",
+	}
+	cfg := &MiddlewareConfig{
+		FacilitatorURL:      facilitator.URL,
+		DefaultRequirements: requirements,
+		ProtectedPaths:      []string{"/data"},
+		ReplayStore:         NewMemoryReplayStore(),
+	}
+	router := gin.New()
+	router.Use(NewX402Middleware(cfg).Handler())
+	router.GET("/data", func(ctx *gin.Context) {
+		ctx.String(http.StatusOK, "data")
+	})
+
+	request := func(header string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest("GET", "/data", nil)
+		req.Header.Set("PAYMENT-SIGNATURE", header)
+		recorder := httptest.NewRecorder()
+		router.ServeHTTP(recorder, req)
+		return recorder
+	}
+
+	// Pay once with the usual encoding
+	if res := request(paidHeader(requirements)); res.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d: %s", res.Code, res.Body.String())
+	}
+
+	// The same authorization with other whitespace, field order and hex case
+	accepted, _ := json.Marshal(requirements)
+	reencoded := fmt.Sprintf(`{
+		"payload": {
+			"authorization": {
+				"nonce": "0x%s",
+				"validBefore": %d,
+				"validAfter": 0,
+				"value": "1000",
+				"to": "%s",
+				"from": "0x70997970c51812dc3a010c7d01b50e0d17dc79c8"
+			},
+			"signature": "0x%s"
+		},
+		"accepted": %s,
+		"x402Version": 2
+	}`, strings.Repeat("01", 32), time.Now().Add(time.Minute).Unix(), strings.ToLower(requirements.PayTo), strings.Repeat("11", 65), accepted)
+	res := request(base64.StdEncoding.EncodeToString([]byte(reencoded)))
+	if res.Code != http.StatusPaymentRequired {
+		t.Fatalf("Expected status 402 for re-encoded replay, got %d: %s", res.Code, res.Body.String())
+	}
+	encoded, _ := base64.StdEncoding.DecodeString(res.Header().Get("PAYMENT-REQUIRED"))
+	var paymentRequired types.PaymentRequired
+	json.Unmarshal(encoded, &paymentRequired)
+	if paymentRequired.ErrorCode != types.ErrCodeNonceUsed {
+		t.Errorf("Expected error code %s, got %s", types.ErrCodeNonceUsed, paymentRequired.ErrorCode)
+	}
+}
+
+func TestMemoryReplayStore(t *testing.T) {
+	store := NewMemoryReplayStore()
+	ctx := context.Background()
+
+	if claimed, _ := store.Claim(ctx, "a", time.Now().Add(time.Hour)); !claimed {
+		t.Error("Expected first claim to succeed")
+	}
+	if claimed, _ := store.Claim(ctx, "a", time.Now().Add(time.Hour)); claimed {
+		t.Error("Expected second claim to fail")
+	}
+	store.Release(ctx, "a")
+	if claimed, _ := store.Claim(ctx, "a", time.Now().Add(time.Hour)); !claimed {
+		t.Error("Expected claim after release to succeed")
+	}
+
+	// Expired claims can be claimed again
+	if claimed, _ := store.Claim(ctx, "b", time.Now().Add(-time.Second)); !claimed {
+		t.Error("Expected first claim to succeed")
+	}
+	if claimed, _ := store.Claim(ctx, "b", time.Now().Add(time.Hour)); !claimed {
+		t.Error("Expected claim of expired key to succeed")
+	}
+}
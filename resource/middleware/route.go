@@ -0,0 +1,166 @@
+package middleware
+
+import (
+	"fmt"
+	"path"
+	"regexp"
+	"strings"
+	"sync"
+)
+
+// Route patterns are matched segment by segment. A segment matches as in
+// path.Match ("*", "?", "[a-z]" within the segment), except that ":name" and
+// "{name}" match any one segment and "**" matches any number of segments,
+// including none.
+//
+// Patterns prefixed with "re:" are regular expressions that must match the
+// whole path, e.g. "re:/api/v[0-9]+/users/[0-9a-f]{24}".
+
+// regexpPrefix marks a route pattern as a regular expression
+const regexpPrefix = "re:"
+
+// routeRegexps caches compiled "re:" patterns by pattern
+var routeRegexps sync.Map
+
+// routeRegexp returns the compiled expression of a "re:" pattern
+func routeRegexp(pattern string) (*regexp.Regexp, error) {
+	if re, ok := routeRegexps.Load(pattern); ok {
+		return re.(*regexp.Regexp), nil
+	}
+	re, err := regexp.Compile("^(?:" + strings.TrimPrefix(pattern, regexpPrefix) + ")$")
+	if err != nil {
+		return nil, err
+	}
+	routeRegexps.Store(pattern, re)
+	return re, nil
+}
+
+// Segment kinds, from least to most specific
+const (
+	segmentAny = iota
+	segmentParam
+	segmentGlob
+	segmentLiteral
+)
+
+func segmentKind(segment string) int {
+	switch {
+	case segment == "**":
+		return segmentAny
+	case strings.HasPrefix(segment, ":"), strings.HasPrefix(segment, "{") && strings.HasSuffix(segment, "}"):
+		return segmentParam
+	case strings.ContainsAny(segment, `*?[\`):
+		return segmentGlob
+	}
+	return segmentLiteral
+}
+
+// matchRoute reports whether urlPath matches pattern
+func matchRoute(pattern, urlPath string) bool {
+	if strings.HasPrefix(pattern, regexpPrefix) {
+		re, err := routeRegexp(pattern)
+		return err == nil && re.MatchString(urlPath)
+	}
+	return matchSegments(strings.Split(pattern, "/"), strings.Split(urlPath, "/"))
+}
+
+func matchSegments(pattern, segments []string) bool {
+	for len(pattern) > 0 {
+		switch segmentKind(pattern[0]) {
+		case segmentAny:
+			// Try every number of segments, fewest first
+			for i := 0; i <= len(segments); i++ {
+				if matchSegments(pattern[1:], segments[i:]) {
+					return true
+				}
+			}
+			return false
+		case segmentParam:
+			if len(segments) == 0 || segments[0] == "" {
+				return false
+			}
+		default:
+			if len(segments) == 0 {
+				return false
+			}
+			if matched, err := path.Match(pattern[0], segments[0]); err != nil || !matched {
+				return false
+			}
+		}
+		pattern, segments = pattern[1:], segments[1:]
+	}
+	return len(segments) == 0
+}
+
+// moreSpecific reports whether pattern a is more specific than b. Regular
+// expressions come before segment patterns, the longer expression first.
+// Segments are compared left to right, literal before glob before parameter
+// before "**". Longer patterns win ties.
+func moreSpecific(a, b string) bool {
+	aRegexp, bRegexp := strings.HasPrefix(a, regexpPrefix), strings.HasPrefix(b, regexpPrefix)
+	if aRegexp != bRegexp {
+		return aRegexp
+	}
+	if aRegexp {
+		if len(a) != len(b) {
+			return len(a) > len(b)
+		}
+		return a < b
+	}
+
+	aSegments, bSegments := strings.Split(a, "/"), strings.Split(b, "/")
+	for i := 0; i < len(aSegments) && i < len(bSegments); i++ {
+		aKind, bKind := segmentKind(aSegments[i]), segmentKind(bSegments[i])
+		if aKind != bKind {
+			return aKind > bKind
+		}
+	}
+	if len(aSegments) != len(bSegments) {
+		return len(aSegments) > len(bSegments)
+	}
+	if len(a) != len(b) {
+		return len(a) > len(b)
+	}
+	return a < b
+}
+
+// bestRoute returns the value of the most specific pattern in routes
+// matching urlPath
+func bestRoute[V any](routes map[string]V, urlPath string) (V, bool) {
+	// An exact entry always wins
+	if value, exists := routes[urlPath]; exists {
+		return value, true
+	}
+
+	best, found := "", false
+	for pattern := range routes {
+		if matchRoute(pattern, urlPath) && (!found || moreSpecific(pattern, best)) {
+			best, found = pattern, true
+		}
+	}
+	if !found {
+		var zero V
+		return zero, false
+	}
+	return routes[best], true
+}
+
+// validateRoutePattern checks every glob segment of pattern, and compiles
+// "re:" patterns ahead of the first request
+func validateRoutePattern(pattern string) error {
+	if strings.HasPrefix(pattern, regexpPrefix) {
+		if _, err := routeRegexp(pattern); err != nil {
+			return fmt.Errorf("invalid route pattern %q: %w", pattern, err)
+		}
+		return nil
+	}
+	for _, segment := range strings.Split(pattern, "/") {
+		if segmentKind(segment) != segmentGlob {
+			continue
+		}
+		if _, err := path.Match(segment, ""); err != nil {
+			return fmt.Errorf("invalid route pattern %q: %w", pattern, err)
+		}
+	}
+	return nil
+}
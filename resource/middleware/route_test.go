@@ -0,0 +1,95 @@
+package middleware
+
+import "testing"
+
+func TestMatchRoute(t *testing.T) {
+	tests := []struct {
+		pattern string
+		path    string
+		matched bool
+	}{
+		{"/data", "/data", true},
+		{"/data", "/data/x", false},
+		{"/api/*", "/api/data", true},
+		{"/api/*", "/api/data/x", false},
+		{"/api/*.json", "/api/data.json", true},
+		{"/api/v?", "/api/v2", true},
+		{"/api/**", "/api", true},
+		{"/api/**", "/api/a/b/c", true},
+		{"/api/**", "/apix/a", false},
+		{"/api/**/raw", "/api/a/b/raw", true},
+		{"/api/**/raw", "/api/raw", true},
+		{"/api/**/raw", "/api/a/b", false},
+		{"/users/:id", "/users/42", true},
+		{"/users/:id", "/users/", false},
+		{"/users/:id", "/users/42/posts", false},
+		{"/users/{id}/posts", "/users/42/posts", true},
+		{"/users/[0-9]*", "/users/42", true},
+		{"/users/[0-9]*", "/users/me", false},
+		{"re:/v[0-9]+/items", "/v12/items", true},
+		{"re:/v[0-9]+/items", "/v12/items/x", false},
+		{"re:/v[0-9]+/items", "/api/v12/items", false},
+		{"re:/a|/b", "/b", true},
+		{"re:/a|/b", "/ab", false},
+	}
+
+	for _, tt := range tests {
+		if matched := matchRoute(tt.pattern, tt.path); matched != tt.matched {
+			t.Errorf("matchRoute(%q, %q): expected %v, got %v", tt.pattern, tt.path, tt.matched, matched)
+		}
+	}
+}
+
+func TestBestRoute(t *testing.T) {
+	routes := map[string]string{
+		"/api/**":            "any",
+		"/api/*":             "glob",
+		"/api/users/:id":     "param",
+		"/api/users/me":      "literal",
+		"/api/users/**":      "users",
+		"/api/*/settings":    "settings",
+		"/api/:kind/:id/raw": "raw",
+		"re:/api/[0-9]+":     "numeric",
+		"re:/api/[0-9]+|/x":  "numeric or x",
+	}
+
+	tests := []struct {
+		path     string
+		expected string
+	}{
+		{"/api/users/me", "literal"},
+		{"/api/users/42", "param"},
+		{"/api/users/42/posts", "users"},
+		{"/api/orders", "glob"},
+		{"/api/orders/42/items", "any"},
+		{"/api/users/settings", "param"},
+		{"/api/orders/settings", "settings"},
+		{"/api/orders/42/raw", "raw"},
+		{"/api/42", "numeric or x"},
+	}
+
+	for _, tt := range tests {
+		value, ok := bestRoute(routes, tt.path)
+		if !ok || value != tt.expected {
+			t.Errorf("bestRoute(%q): expected %s, got %s", tt.path, tt.expected, value)
+		}
+	}
+
+	if _, ok := bestRoute(routes, "/other"); ok {
+		t.Error("Expected no match outside the patterns")
+	}
+}
+
+func TestValidateRoutePattern(t *testing.T) {
+	for _, pattern := range []string{"/api/**", "/users/:id", "/files/[a-z]*", "re:/files/[a-z]+"} {
+		if err := validateRoutePattern(pattern); err != nil {
+			t.Errorf("Expected %s to be valid, got %v", pattern, err)
+		}
+	}
+	if err := validateRoutePattern("/files/[a-z"); err == nil {
+		t.Error("Expected unterminated class to be invalid")
+	}
+	if err := validateRoutePattern("re:/files/(a"); err == nil {
+		t.Error("Expected invalid regular expression to be invalid")
+	}
+}
@@ -0,0 +1,21 @@
+package middleware
+
+import (
+	"context"
+
+	"github.com/vorpalengineering/x402-go/facilitator"
+	"github.com/vorpalengineering/x402-go/types"
+)
+
+// selfSettler verifies and settles with a facilitator embedded in the server
+type selfSettler struct {
+	facilitator *facilitator.Facilitator
+}
+
+func (s selfSettler) VerifyContext(ctx context.Context, req *types.VerifyRequest) (*types.VerifyResponse, error) {
+	return s.facilitator.Verify(ctx, req), nil
+}
+
+func (s selfSettler) SettleContext(ctx context.Context, req *types.SettleRequest) (*types.SettleResponse, error) {
+	return s.facilitator.Settle(ctx, req)
+}
@@ -0,0 +1,60 @@
+package middleware
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/vorpalengineering/x402-go/facilitator"
+	"github.com/vorpalengineering/x402-go/types"
+)
+
+func TestSelfSettle(t *testing.T) {
+	embedded := facilitator.NewFacilitator(&facilitator.FacilitatorConfig{
+		Networks: map[string]facilitator.NetworkConfig{},
+		Log:      facilitator.LogConfig{Level: "error"},
+	})
+	gin.SetMode(gin.TestMode)
+
+	requirements := types.PaymentRequirements{
+		Scheme:  "exact",
+		Network: "eip155:84532",
+		Amount:  "1000",
+		Asset:   "0x036CbD53842c5426634e7929541eC2318f3dCF7e",
+		PayTo:   "0x3C44CdDdB6a900fa2b585dd299e03d12FA4293BC",
+	}
+	cfg := &MiddlewareConfig{
+		SelfSettle:          embedded,
+		DefaultRequirements: requirements,
+		ProtectedPaths:      []string{"/data"},
+	}
+	if err := cfg.Validate(); err != nil {
+		t.Fatalf("Expected self-settling config without facilitator URL to be valid, got %v", err)
+	}
+
+	router := gin.New()
+	router.Use(NewX402Middleware(cfg).Handler())
+	router.GET("/data", func(ctx *gin.Context) {
+		ctx.String(http.StatusOK, "paid")
+	})
+
+	// The embedded facilitator verifies the payment, refusing the network it
+	// has no configuration for
+	req := httptest.NewRequest("GET", "/data", nil)
+	req.Header.Set("PAYMENT-SIGNATURE", paidHeader(requirements))
+	recorder := httptest.NewRecorder()
+	router.ServeHTTP(recorder, req)
+
+	if recorder.Code != http.StatusPaymentRequired {
+		t.Fatalf("Expected status 402, got %d: %s", recorder.Code, recorder.Body.String())
+	}
+	encoded, _ := base64.StdEncoding.DecodeString(recorder.Header().Get("PAYMENT-REQUIRED"))
+	var paymentRequired types.PaymentRequired
+	json.Unmarshal(encoded, &paymentRequired)
+	if paymentRequired.ErrorCode != types.ErrCodeUnsupportedNetwork {
+		t.Errorf("Expected error code %s, got %s", types.ErrCodeUnsupportedNetwork, paymentRequired.ErrorCode)
+	}
+}
@@ -0,0 +1,213 @@
+package middleware
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// SessionHeader carries session tokens to and from clients
+const SessionHeader = "PAYMENT-SESSION"
+
+// SessionKey is the context key holding the *Session a request was served
+// under instead of a payment
+const SessionKey = "x402_session"
+
+// SessionConfig issues a signed session token with every settled payment.
+// Presenting the token grants access to the paid route for a period or a
+// number of requests, without paying again.
+type SessionConfig struct {
+	// Secret signs session tokens. Tokens stay valid across restarts and
+	// instances sharing the secret, request counts do not.
+	Secret string `json:"secret" toml:"secret"`
+
+	// Routes limits sessions to these path patterns. A token is valid on
+	// every path matching the pattern it was issued under. Empty issues
+	// sessions on every protected path, valid for the paid route only.
+	Routes []string `json:"routes,omitempty" toml:"routes"`
+
+	// Duration is how long a session lasts
+	Duration time.Duration `json:"duration" toml:"duration"`
+
+	// MaxRequests caps the requests served under a session. 0 is unlimited.
+	MaxRequests int `json:"maxRequests,omitempty" toml:"max_requests"`
+
+	// CookieName also issues the token as a cookie of this name, and accepts
+	// it from the cookie. Empty uses the PAYMENT-SESSION header only.
+	CookieName string `json:"cookieName,omitempty" toml:"cookie_name"`
+}
+
+func (c *SessionConfig) validate() error {
+	if len(c.Secret) < 32 {
+		return errors.New("session secret must be at least 32 bytes")
+	}
+	if c.Duration <= 0 {
+		return errors.New("session duration must be positive")
+	}
+	if c.MaxRequests < 0 {
+		return errors.New("session max requests cannot be negative")
+	}
+	return nil
+}
+
+// Session is the access granted by a settled payment
+type Session struct {
+	ID          string `json:"id"`
+	Route       string `json:"route"`
+	Payer       string `json:"payer,omitempty"`
+	Transaction string `json:"tx,omitempty"`
+	ExpiresAt   int64  `json:"exp"`
+	MaxRequests int    `json:"max,omitempty"`
+}
+
+// sessionManager issues and checks session tokens, counting requests per session
+type sessionManager struct {
+	config *SessionConfig
+	mu     sync.Mutex
+	used   map[string]sessionUse
+}
+
+type sessionUse struct {
+	requests  int
+	expiresAt int64
+}
+
+func newSessionManager(cfg *SessionConfig) *sessionManager {
+	return &sessionManager{
+		config: cfg,
+		used:   make(map[string]sessionUse),
+	}
+}
+
+// scope returns the route a session for route is valid on, false when
+// sessions are not issued for it
+func (s *sessionManager) scope(route string) (string, bool) {
+	if len(s.config.Routes) == 0 {
+		return route, true
+	}
+	for _, pattern := range s.config.Routes {
+		if matchesAny([]string{pattern}, route) {
+			return pattern, true
+		}
+	}
+	return "", false
+}
+
+// issue creates a signed token for a payment settled on route
+func (s *sessionManager) issue(route, payer, transaction string) (string, *Session, error) {
+	scope, ok := s.scope(route)
+	if !ok {
+		return "", nil, nil
+	}
+
+	var id [16]byte
+	if _, err := rand.Read(id[:]); err != nil {
+		return "", nil, fmt.Errorf("failed to generate session id: %w", err)
+	}
+	session := &Session{
+		ID:          hex.EncodeToString(id[:]),
+		Route:       scope,
+		Payer:       payer,
+		Transaction: transaction,
+		ExpiresAt:   time.Now().Add(s.config.Duration).Unix(),
+		MaxRequests: s.config.MaxRequests,
+	}
+	claims, err := json.Marshal(session)
+	if err != nil {
+		return "", nil, fmt.Errorf("failed to encode session: %w", err)
+	}
+
+	// Forget the request counts of expired sessions
+	now := time.Now().Unix()
+	s.mu.Lock()
+	for id, use := range s.used {
+		if now >= use.expiresAt {
+			delete(s.used, id)
+		}
+	}
+	s.mu.Unlock()
+
+	payload := base64.RawURLEncoding.EncodeToString(claims)
+	return payload + "." + s.sign(payload), session, nil
+}
+
+// redeem checks a token for route and counts the request against it
+func (s *sessionManager) redeem(token, route string) (*Session, error) {
+	payload, signature, ok := strings.Cut(token, ".")
+	if !ok || !hmac.Equal([]byte(signature), []byte(s.sign(payload))) {
+		return nil, errors.New("invalid session signature")
+	}
+	claims, err := base64.RawURLEncoding.DecodeString(payload)
+	if err != nil {
+		return nil, fmt.Errorf("invalid session encoding: %w", err)
+	}
+	var session Session
+	if err := json.Unmarshal(claims, &session); err != nil {
+		return nil, fmt.Errorf("invalid session: %w", err)
+	}
+
+	// Tokens are only valid where they were issued
+	if scope, ok := s.scope(route); !ok || scope != session.Route {
+		return nil, errors.New("session is for another route")
+	}
+	now := time.Now().Unix()
+	if now >= session.ExpiresAt {
+		return nil, errors.New("session expired")
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	use := s.used[session.ID]
+	if session.MaxRequests > 0 && use.requests >= session.MaxRequests {
+		return nil, errors.New("session request limit reached")
+	}
+	s.used[session.ID] = sessionUse{requests: use.requests + 1, expiresAt: session.ExpiresAt}
+
+	return &session, nil
+}
+
+func (s *sessionManager) sign(payload string) string {
+	mac := hmac.New(sha256.New, []byte(s.config.Secret))
+	mac.Write([]byte(payload))
+	return base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
+
+// sessionToken returns the token presented with a request
+func (s *sessionManager) sessionToken(ctx *gin.Context) string {
+	if token := ctx.GetHeader(SessionHeader); token != "" {
+		return token
+	}
+	if s.config.CookieName != "" {
+		if cookie, err := ctx.Cookie(s.config.CookieName); err == nil {
+			return cookie
+		}
+	}
+	return ""
+}
+
+// sendSession hands a new session token to the client
+func (s *sessionManager) sendSession(ctx *gin.Context, token string, session *Session) {
+	ctx.Header(SessionHeader, token)
+	if s.config.CookieName != "" {
+		http.SetCookie(ctx.Writer, &http.Cookie{
+			Name:     s.config.CookieName,
+			Value:    token,
+			Path:     "/",
+			Expires:  time.Unix(session.ExpiresAt, 0),
+			HttpOnly: true,
+			Secure:   ctx.Request.TLS != nil,
+			SameSite: http.SameSiteLaxMode,
+		})
+	}
+}
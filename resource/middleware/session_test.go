@@ -0,0 +1,139 @@
+package middleware
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/vorpalengineering/x402-go/types"
+)
+
+func TestSessions(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	settlements := 0
+	facilitator := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		switch r.URL.Path {
+		case "/verify":
+			json.NewEncoder(w).Encode(types.VerifyResponse{IsValid: true})
+		case "/settle":
+			settlements++
+			json.NewEncoder(w).Encode(types.SettleResponse{Success: true, Transaction: "0xabc", Payer: "0x70997970C51812dc3A010C7d01b50e0d17dc79C8"})
+		}
+	}))
+	defer facilitator.Close()
+
+	requirements := types.PaymentRequirements{
+		Scheme:  "exact",
+		Network: "eip155:84532",
+		Amount:  "1000",
+		Asset:   "0x036CbD53842c5426634e7929541eC2318f3dCF7e",
+		PayTo:   "0x3C44CdDdB6a900fa2b585dd299e03d12FA4293BC",
+	}
+	cfg := &MiddlewareConfig{
+		FacilitatorURL:      facilitator.URL,
+		DefaultRequirements: requirements,
+		ProtectedPaths:      []string{"/api/*", "/other"},
+		Sessions: &SessionConfig{
+			Secret:      strings.Repeat("s", 32),
+			Routes:      []string{"/api/*"},
+			Duration:    time.Minute,
+			MaxRequests: 2,
+			CookieName:  "x402_session",
+		},
+	}
+	if err := cfg.Validate(); err != nil {
+		t.Fatalf("Expected valid config, got %v", err)
+	}
+	router := gin.New()
+	router.Use(NewX402Middleware(cfg).Handler())
+	handler := func(ctx *gin.Context) {
+		if value, ok := ctx.Get(SessionKey); ok {
+			ctx.String(http.StatusOK, "session "+value.(*Session).Payer)
+			return
+		}
+		ctx.String(http.StatusOK, "paid")
+	}
+	router.GET("/api/a", handler)
+	router.GET("/api/b", handler)
+	router.GET("/other", handler)
+
+	request := func(path string, headers map[string]string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest("GET", path, nil)
+		for name, value := range headers {
+			req.Header.Set(name, value)
+		}
+		recorder := httptest.NewRecorder()
+		router.ServeHTTP(recorder, req)
+		return recorder
+	}
+
+	// Paying issues a session as header and cookie
+	paid := request("/api/a", map[string]string{"PAYMENT-SIGNATURE": paidHeader(requirements)})
+	token := paid.Header().Get(SessionHeader)
+	if paid.Code != http.StatusOK || token == "" {
+		t.Fatalf("Expected paid response with session, got %d %v", paid.Code, paid.Header())
+	}
+	if cookie := paid.Result().Cookies(); len(cookie) != 1 || cookie[0].Value != token || !cookie[0].HttpOnly {
+		t.Errorf("Expected session cookie, got %v", cookie)
+	}
+
+	// The session covers its pattern, without settling again
+	if res := request("/api/b", map[string]string{SessionHeader: token}); res.Code != http.StatusOK || res.Body.String() != "session 0x70997970C51812dc3A010C7d01b50e0d17dc79C8" {
+		t.Errorf("Expected access under session, got %d: %s", res.Code, res.Body.String())
+	}
+	if res := request("/api/a", map[string]string{"Cookie": "x402_session=" + token}); res.Code != http.StatusOK {
+		t.Errorf("Expected access with session cookie, got %d", res.Code)
+	}
+	if settlements != 1 {
+		t.Errorf("Expected a single settlement, got %d", settlements)
+	}
+
+	tests := []struct {
+		name  string
+		path  string
+		token string
+	}{
+		{"request limit reached", "/api/a", token},
+		{"other route", "/other", token},
+		{"tampered", "/api/a", strings.Replace(token, ".", "x.", 1)},
+		{"garbage", "/api/a", "not-a-token"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if res := request(tt.path, map[string]string{SessionHeader: tt.token}); res.Code != http.StatusPaymentRequired {
+				t.Errorf("Expected 402, got %d: %s", res.Code, res.Body.String())
+			}
+		})
+	}
+}
+
+func TestSessionExpiry(t *testing.T) {
+	sessions := newSessionManager(&SessionConfig{Secret: strings.Repeat("s", 32), Duration: time.Minute})
+
+	token, session, err := sessions.issue("/api/a", "0xpayer", "0xabc")
+	if err != nil || session.Route != "/api/a" {
+		t.Fatalf("Failed to issue session: %+v %v", session, err)
+	}
+	if _, err := sessions.redeem(token, "/api/b"); err == nil {
+		t.Error("Expected session without routes to be limited to the paid route")
+	}
+
+	// Expired sessions are refused, and forgotten at the next issue
+	sessions.config.Duration = -time.Second
+	expired, _, _ := sessions.issue("/api/a", "0xpayer", "0xabc")
+	if _, err := sessions.redeem(expired, "/api/a"); err == nil || !strings.Contains(err.Error(), "expired") {
+		t.Errorf("Expected expired session, got %v", err)
+	}
+	sessions.redeem(token, "/api/a")
+	sessions.used[session.ID] = sessionUse{requests: 1, expiresAt: time.Now().Unix() - 1}
+	sessions.issue("/api/a", "0xpayer", "0xabc")
+	if len(sessions.used) != 0 {
+		t.Errorf("Expected expired session counts to be pruned, got %d", len(sessions.used))
+	}
+}
@@ -0,0 +1,201 @@
+package middleware
+
+import (
+	"cmp"
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+	"math/rand/v2"
+	"net"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/vorpalengineering/x402-go/facilitator/client"
+	"github.com/vorpalengineering/x402-go/types"
+)
+
+// SettlementFailureAction is what a route does when its payment fails to settle
+type SettlementFailureAction string
+
+const (
+	// FailClosed refuses the request, discarding the handler's response, so
+	// nothing is served without payment (default)
+	FailClosed SettlementFailureAction = "fail-closed"
+
+	// ServeAnyway serves the request despite the failed settlement, flagged
+	// with the X-Payment-Settlement header, for cheap routes where
+	// availability matters more than guaranteed payment
+	ServeAnyway SettlementFailureAction = "serve-anyway"
+
+	// RetryThenFail retries transient settlement failures before refusing
+	// the request
+	RetryThenFail SettlementFailureAction = "retry"
+)
+
+// DefaultSettlementRetries is how often RetryThenFail retries by default
+const DefaultSettlementRetries = 3
+
+// SettlementStatusHeader is set to "failed" on responses served despite a
+// failed settlement
+const SettlementStatusHeader = "X-Payment-Settlement"
+
+// SettlementErrorKey is the context key holding why the payment of a request
+// served with ServeAnyway failed to settle
+const SettlementErrorKey = "x402_settlement_error"
+
+// SettlementFailurePolicy is how a route handles failed settlements
+type SettlementFailurePolicy struct {
+	// Action is FailClosed (default), ServeAnyway or RetryThenFail
+	Action SettlementFailureAction `json:"action" toml:"action"`
+
+	// Retries is how often RetryThenFail retries after the first attempt.
+	// 0 uses DefaultSettlementRetries.
+	Retries int `json:"retries,omitempty" toml:"retries"`
+}
+
+func (p SettlementFailurePolicy) validate() error {
+	switch p.Action {
+	case "", FailClosed, ServeAnyway, RetryThenFail:
+	default:
+		return errors.New("invalid action: " + string(p.Action) + " (must be fail-closed, serve-anyway, or retry)")
+	}
+	if p.Retries < 0 {
+		return errors.New("retries cannot be negative")
+	}
+	if p.Retries > 0 && p.Action != RetryThenFail {
+		return errors.New("retries require the retry action")
+	}
+	return nil
+}
+
+// attempts is how often a settlement is tried under the policy, given the
+// retries configured for every route
+func (p SettlementFailurePolicy) attempts(retries int) int {
+	if p.Action != RetryThenFail {
+		return 1 + retries
+	}
+	if p.Retries == 0 {
+		return 1 + DefaultSettlementRetries
+	}
+	return 1 + p.Retries
+}
+
+// settlementFailurePolicy returns how route handles failed settlements
+func (m *X402Middleware) settlementFailurePolicy(route string) SettlementFailurePolicy {
+	policy, _ := bestRoute(m.config.RouteSettlementFailure, route)
+	return policy
+}
+
+// Settlement retry defaults
+const (
+	DefaultSettlementBackoff    = 100 * time.Millisecond
+	DefaultSettlementMaxBackoff = 2 * time.Second
+)
+
+// SettlementRetryConfig retries settlements that fail transiently (the
+// facilitator is unreachable, times out or answers 5xx) on every route
+type SettlementRetryConfig struct {
+	// MaxRetries is how often a transient failure is retried after the
+	// first attempt. 0 disables retries, except on RetryThenFail routes.
+	MaxRetries int `json:"maxRetries,omitempty" toml:"max_retries"`
+
+	// Backoff is the wait before the first retry, doubled after each retry
+	// and jittered. 0 uses DefaultSettlementBackoff.
+	Backoff time.Duration `json:"backoff,omitempty" toml:"backoff"`
+
+	// MaxBackoff caps the wait between retries. 0 uses
+	// DefaultSettlementMaxBackoff.
+	MaxBackoff time.Duration `json:"maxBackoff,omitempty" toml:"max_backoff"`
+}
+
+func (c *SettlementRetryConfig) validate() error {
+	if c.MaxRetries < 0 {
+		return errors.New("max retries cannot be negative")
+	}
+	if c.Backoff < 0 || c.MaxBackoff < 0 {
+		return errors.New("backoff cannot be negative")
+	}
+	if c.Backoff > 0 && c.MaxBackoff > 0 && c.Backoff > c.MaxBackoff {
+		return errors.New("backoff cannot exceed max backoff")
+	}
+	return nil
+}
+
+// backoff returns the jittered wait before retry number retry (from 1),
+// between half and all of the exponential backoff
+func (c *SettlementRetryConfig) backoff(retry int) time.Duration {
+	wait := cmp.Or(c.Backoff, DefaultSettlementBackoff)
+	maxWait := cmp.Or(c.MaxBackoff, DefaultSettlementMaxBackoff)
+	for i := 1; i < retry && wait < maxWait; i++ {
+		wait *= 2
+	}
+	wait = min(wait, maxWait)
+	return wait/2 + rand.N(wait/2+1)
+}
+
+// settleWithRetries settles payment, retrying transient failures with
+// jittered backoff as its route's policy allows
+func (m *X402Middleware) settleWithRetries(ctx context.Context, logger *slog.Logger, payment *Payment, amount string) (*types.SettleResponse, *PaymentError) {
+	retry := &m.config.SettlementRetry
+	attempts := m.settlementFailurePolicy(payment.Route).attempts(retry.MaxRetries)
+	for attempt := 1; ; attempt++ {
+		settleResp, refusal, retryable := m.trySettle(ctx, logger, payment, amount)
+		if refusal == nil || !retryable || attempt >= attempts {
+			return settleResp, refusal
+		}
+		wait := retry.backoff(attempt)
+		logger.Warn("settlement failed, retrying", "attempt", attempt, "backoff", wait, "error", refusal.Message)
+		select {
+		case <-ctx.Done():
+			return nil, refusal
+		case <-time.After(wait):
+		}
+	}
+}
+
+// transientSettleError reports whether a settle call failing with err may
+// succeed when sent again
+func transientSettleError(err error) bool {
+	var statusErr *client.StatusError
+	if errors.As(err, &statusErr) {
+		return statusErr.Temporary()
+	}
+	var netErr net.Error
+	return errors.As(err, &netErr) || errors.Is(err, io.ErrUnexpectedEOF)
+}
+
+// settleServed settles the payment of a request being served. It returns a
+// refusal only if the request must be refused; under ServeAnyway a failed
+// settlement returns neither a response nor a refusal, and the request is
+// flagged instead.
+func (m *X402Middleware) settleServed(ctx *gin.Context, logger *slog.Logger, payment *Payment, amount string) (*types.SettleResponse, *PaymentError) {
+	settleResp, refusal := m.settleWithRetries(ctx.Request.Context(), logger, payment, amount)
+	if refusal == nil {
+		return settleResp, nil
+	}
+	if m.settlementFailurePolicy(payment.Route).Action != ServeAnyway {
+		m.releasePayment(ctx.Request.Context(), payment)
+		return nil, refusal
+	}
+
+	// The payment stays spent in the replay cache, so its header cannot be
+	// replayed for free
+	logger.Warn("payment failed to settle, serving anyway", "payer", payment.Payer, "error", refusal.Message)
+	ctx.Header(SettlementStatusHeader, "failed")
+	ctx.Set(SettlementErrorKey, refusal.Message)
+
+	// Keep the settlement for collection out of band
+	if m.config.SettlementRetryQueue != nil {
+		pending := PendingSettlement{
+			Route:     payment.Route,
+			QueuedAt:  time.Now().Unix(),
+			Request:   *settleRequest(payment, amount),
+			LastError: refusal.Message,
+		}
+		if err := m.config.SettlementRetryQueue.Enqueue(pending); err != nil {
+			logger.Error("failed to persist failed settlement", "error", err)
+		}
+	}
+	return nil, nil
+}
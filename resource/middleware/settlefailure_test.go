@@ -0,0 +1,216 @@
+package middleware
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/vorpalengineering/x402-go/types"
+)
+
+func TestSettlementFailurePolicy(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	// Every settlement fails
+	var settleCalls atomic.Int32
+	var errorCode atomic.Value
+	facilitator := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		switch r.URL.Path {
+		case "/verify":
+			json.NewEncoder(w).Encode(types.VerifyResponse{IsValid: true})
+		case "/settle":
+			settleCalls.Add(1)
+			json.NewEncoder(w).Encode(types.SettleResponse{ErrorReason: errorCode.Load().(string)})
+		}
+	}))
+	defer facilitator.Close()
+
+	requirements := types.PaymentRequirements{
+		Scheme:  "exact",
+		Network: "eip155:84532",
+		Amount:  "1000",
+		Asset:   "0x036CbD53842c5426634e7929541eC2318f3dCF7e",
+		PayTo:   "0x3C44CdDdB6a900fa2b585dd299e03d12FA4293BC",
+	}
+	cfg := &MiddlewareConfig{
+		FacilitatorURL:      facilitator.URL,
+		DefaultRequirements: requirements,
+		ProtectedPaths:      []string{"/*"},
+		RouteSettlement:     map[string]SettlementOrder{"/before": SettleBefore},
+		SettlementRetry:     SettlementRetryConfig{Backoff: time.Millisecond},
+		RouteSettlementFailure: map[string]SettlementFailurePolicy{
+			"/serve":  {Action: ServeAnyway},
+			"/before": {Action: ServeAnyway},
+			"/retry":  {Action: RetryThenFail, Retries: 2},
+		},
+	}
+	if err := cfg.Validate(); err != nil {
+		t.Fatalf("Expected valid config, got %v", err)
+	}
+	router := gin.New()
+	router.Use(NewX402Middleware(cfg).Handler())
+	router.GET("/:name", func(ctx *gin.Context) {
+		ctx.String(http.StatusOK, "data")
+	})
+
+	tests := []struct {
+		name        string
+		path        string
+		code        string
+		status      int
+		settleCalls int32
+		flagged     bool
+	}{
+		{"fail closed", "/closed", types.ErrCodeNetworkError, http.StatusPaymentRequired, 1, false},
+		{"serve anyway", "/serve", types.ErrCodeNetworkError, http.StatusOK, 1, true},
+		{"serve anyway before handler", "/before", types.ErrCodeNetworkError, http.StatusOK, 1, true},
+		{"retry transient failures", "/retry", types.ErrCodeNetworkError, http.StatusPaymentRequired, 3, false},
+		{"no retry of permanent failures", "/retry", types.ErrCodeInsufficientFunds, http.StatusPaymentRequired, 1, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			settleCalls.Store(0)
+			errorCode.Store(tt.code)
+
+			req := httptest.NewRequest("GET", tt.path, nil)
+			req.Header.Set("PAYMENT-SIGNATURE", paidHeader(requirements))
+			recorder := httptest.NewRecorder()
+			router.ServeHTTP(recorder, req)
+
+			if recorder.Code != tt.status {
+				t.Errorf("Expected status %d, got %d: %s", tt.status, recorder.Code, recorder.Body.String())
+			}
+			if calls := settleCalls.Load(); calls != tt.settleCalls {
+				t.Errorf("Expected %d settle attempts, got %d", tt.settleCalls, calls)
+			}
+			if flagged := recorder.Header().Get(SettlementStatusHeader) == "failed"; flagged != tt.flagged {
+				t.Errorf("Expected flagged %v, got headers %v", tt.flagged, recorder.Header())
+			}
+			if tt.status == http.StatusOK && recorder.Body.String() != "data" {
+				t.Errorf("Expected handler response, got %s", recorder.Body.String())
+			}
+		})
+	}
+}
+
+func TestSettlementFailurePolicyValidation(t *testing.T) {
+	tests := []struct {
+		name   string
+		policy SettlementFailurePolicy
+		valid  bool
+	}{
+		{"default", SettlementFailurePolicy{}, true},
+		{"retry", SettlementFailurePolicy{Action: RetryThenFail, Retries: 5}, true},
+		{"unknown action", SettlementFailurePolicy{Action: "ignore"}, false},
+		{"negative retries", SettlementFailurePolicy{Action: RetryThenFail, Retries: -1}, false},
+		{"retries without retry", SettlementFailurePolicy{Action: ServeAnyway, Retries: 2}, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := tt.policy.validate(); (err == nil) != tt.valid {
+				t.Errorf("Expected valid %v, got %v", tt.valid, err)
+			}
+		})
+	}
+}
+
+func TestSettlementRetry(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	// The facilitator fails with failStatus until failures run out
+	var settleCalls, failures atomic.Int32
+	var failStatus atomic.Int32
+	facilitator := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		switch r.URL.Path {
+		case "/verify":
+			json.NewEncoder(w).Encode(types.VerifyResponse{IsValid: true})
+		case "/settle":
+			settleCalls.Add(1)
+			if failures.Add(-1) >= 0 {
+				w.WriteHeader(int(failStatus.Load()))
+				return
+			}
+			json.NewEncoder(w).Encode(types.SettleResponse{Success: true, Transaction: "0xabc"})
+		}
+	}))
+	defer facilitator.Close()
+
+	requirements := types.PaymentRequirements{
+		Scheme:  "exact",
+		Network: "eip155:84532",
+		Amount:  "1000",
+		Asset:   "0x036CbD53842c5426634e7929541eC2318f3dCF7e",
+		PayTo:   "0x3C44CdDdB6a900fa2b585dd299e03d12FA4293BC",
+	}
+	cfg := &MiddlewareConfig{
+		FacilitatorURL:      facilitator.URL,
+		DefaultRequirements: requirements,
+		ProtectedPaths:      []string{"/data"},
+		SettlementRetry:     SettlementRetryConfig{MaxRetries: 2, Backoff: time.Millisecond},
+	}
+	if err := cfg.Validate(); err != nil {
+		t.Fatalf("Expected valid config, got %v", err)
+	}
+	router := gin.New()
+	router.Use(NewX402Middleware(cfg).Handler())
+	router.GET("/data", func(ctx *gin.Context) {
+		ctx.String(http.StatusOK, "data")
+	})
+
+	tests := []struct {
+		name        string
+		failStatus  int
+		failures    int32
+		status      int
+		settleCalls int32
+	}{
+		{"recovers from bad gateway", http.StatusBadGateway, 2, http.StatusOK, 3},
+		{"gives up after max retries", http.StatusServiceUnavailable, 5, http.StatusBadGateway, 3},
+		{"no retry of client errors", http.StatusBadRequest, 1, http.StatusBadGateway, 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			settleCalls.Store(0)
+			failures.Store(tt.failures)
+			failStatus.Store(int32(tt.failStatus))
+
+			req := httptest.NewRequest("GET", "/data", nil)
+			req.Header.Set("PAYMENT-SIGNATURE", paidHeader(requirements))
+			recorder := httptest.NewRecorder()
+			router.ServeHTTP(recorder, req)
+
+			if recorder.Code != tt.status {
+				t.Errorf("Expected status %d, got %d: %s", tt.status, recorder.Code, recorder.Body.String())
+			}
+			if calls := settleCalls.Load(); calls != tt.settleCalls {
+				t.Errorf("Expected %d settle attempts, got %d", tt.settleCalls, calls)
+			}
+		})
+	}
+}
+
+func TestSettlementRetryBackoff(t *testing.T) {
+	cfg := SettlementRetryConfig{Backoff: 100 * time.Millisecond, MaxBackoff: time.Second}
+	tests := []struct {
+		retry    int
+		min, max time.Duration
+	}{
+		{1, 50 * time.Millisecond, 100 * time.Millisecond},
+		{2, 100 * time.Millisecond, 200 * time.Millisecond},
+		{3, 200 * time.Millisecond, 400 * time.Millisecond},
+		{10, 500 * time.Millisecond, time.Second},
+	}
+	for _, tt := range tests {
+		for range 20 {
+			if wait := cfg.backoff(tt.retry); wait < tt.min || wait > tt.max {
+				t.Errorf("Expected retry %d to wait between %v and %v, got %v", tt.retry, tt.min, tt.max, wait)
+			}
+		}
+	}
+}
@@ -0,0 +1,218 @@
+package middleware
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/vorpalengineering/x402-go/types"
+)
+
+// ErrShuttingDown is returned for paid requests that arrive while draining
+var ErrShuttingDown = errors.New("server is shutting down")
+
+// PendingSettlement is a settlement that had not completed at shutdown
+type PendingSettlement struct {
+	Route     string              `json:"route"`
+	QueuedAt  int64               `json:"queuedAt"`
+	Request   types.SettleRequest `json:"request"`
+	LastError string              `json:"lastError,omitempty"`
+}
+
+// SettlementRetryQueue persists settlements that could not complete so they
+// can be retried after restart
+type SettlementRetryQueue interface {
+	Enqueue(pending PendingSettlement) error
+}
+
+// settlementTracker counts in-flight settle calls so shutdown can wait for them
+type settlementTracker struct {
+	mu       sync.Mutex
+	draining bool
+	nextID   uint64
+	inflight map[uint64]PendingSettlement
+	idle     chan struct{}
+}
+
+func newSettlementTracker() *settlementTracker {
+	return &settlementTracker{
+		inflight: make(map[uint64]PendingSettlement),
+	}
+}
+
+// start registers a settlement. Returns false once draining has begun.
+func (t *settlementTracker) start(pending PendingSettlement) (uint64, bool) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if t.draining {
+		return 0, false
+	}
+	t.nextID++
+	t.inflight[t.nextID] = pending
+	return t.nextID, true
+}
+
+// update replaces the request of a registered settlement, e.g. once the
+// metered amount of a queued settlement is known
+func (t *settlementTracker) update(id uint64, req types.SettleRequest) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if pending, exists := t.inflight[id]; exists {
+		pending.Request = req
+		t.inflight[id] = pending
+	}
+}
+
+func (t *settlementTracker) isDraining() bool {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.draining
+}
+
+func (t *settlementTracker) finish(id uint64) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	delete(t.inflight, id)
+	if t.draining && len(t.inflight) == 0 && t.idle != nil {
+		close(t.idle)
+		t.idle = nil
+	}
+}
+
+// drain stops new settlements and waits for in-flight ones until ctx is done.
+// Returns the settlements still in flight at the deadline.
+func (t *settlementTracker) drain(ctx context.Context) []PendingSettlement {
+	t.mu.Lock()
+	t.draining = true
+	if len(t.inflight) == 0 {
+		t.mu.Unlock()
+		return nil
+	}
+	idle := make(chan struct{})
+	t.idle = idle
+	t.mu.Unlock()
+
+	select {
+	case <-idle:
+		return nil
+	case <-ctx.Done():
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	remaining := make([]PendingSettlement, 0, len(t.inflight))
+	for _, pending := range t.inflight {
+		remaining = append(remaining, pending)
+	}
+	return remaining
+}
+
+// Shutdown stops accepting paid requests and waits for in-flight settlements
+// to finish or ctx to expire. Settlements still running at the deadline are
+// written to the configured retry queue. Call it after http.Server.Shutdown
+// returns, with the same deadline.
+func (m *X402Middleware) Shutdown(ctx context.Context) error {
+	remaining := m.settlements.drain(ctx)
+
+	// Queued settlements not done by now are persisted below instead
+	if m.async != nil {
+		m.async.close()
+	}
+	if len(remaining) == 0 {
+		return nil
+	}
+
+	if m.config.SettlementRetryQueue == nil {
+		return fmt.Errorf("%d settlements still in flight at shutdown deadline", len(remaining))
+	}
+
+	var errs []error
+	for _, pending := range remaining {
+		pending.LastError = "in flight at shutdown"
+		if err := m.config.SettlementRetryQueue.Enqueue(pending); err != nil {
+			errs = append(errs, fmt.Errorf("failed to persist settlement for %s: %w", pending.Route, err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// RetrySettlement settles a persisted payment again. A payment that actually
+// settled before shutdown reports a failure or the facilitator's cached result.
+func (m *X402Middleware) RetrySettlement(pending PendingSettlement) (*types.SettleResponse, error) {
+	return m.facilitator.SettleContext(context.Background(), &pending.Request)
+}
+
+// FileSettlementRetryQueue appends pending settlements as JSON lines to a file
+type FileSettlementRetryQueue struct {
+	mu   sync.Mutex
+	path string
+}
+
+func NewFileSettlementRetryQueue(path string) *FileSettlementRetryQueue {
+	return &FileSettlementRetryQueue{path: path}
+}
+
+func (q *FileSettlementRetryQueue) Enqueue(pending PendingSettlement) error {
+	if pending.QueuedAt == 0 {
+		pending.QueuedAt = time.Now().Unix()
+	}
+	line, err := json.Marshal(pending)
+	if err != nil {
+		return fmt.Errorf("failed to marshal pending settlement: %w", err)
+	}
+
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	file, err := os.OpenFile(q.path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0600)
+	if err != nil {
+		return fmt.Errorf("failed to open retry queue: %w", err)
+	}
+	defer file.Close()
+
+	if _, err := file.Write(append(line, '\n')); err != nil {
+		return fmt.Errorf("failed to write retry queue: %w", err)
+	}
+	return nil
+}
+
+// Load returns every queued settlement
+func (q *FileSettlementRetryQueue) Load() ([]PendingSettlement, error) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	file, err := os.Open(q.path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to open retry queue: %w", err)
+	}
+	defer file.Close()
+
+	var pending []PendingSettlement
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		if len(scanner.Bytes()) == 0 {
+			continue
+		}
+		var entry PendingSettlement
+		if err := json.Unmarshal(scanner.Bytes(), &entry); err != nil {
+			return nil, fmt.Errorf("failed to parse retry queue: %w", err)
+		}
+		pending = append(pending, entry)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read retry queue: %w", err)
+	}
+	return pending, nil
+}
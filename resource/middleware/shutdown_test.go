@@ -0,0 +1,47 @@
+package middleware
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/vorpalengineering/x402-go/types"
+)
+
+func TestShutdownPersistsInflightSettlements(t *testing.T) {
+	queue := NewFileSettlementRetryQueue(filepath.Join(t.TempDir(), "retry.jsonl"))
+	m := NewX402Middleware(&MiddlewareConfig{
+		SettlementRetryQueue: queue,
+	})
+
+	// One settlement finishes during drain, one never does
+	done, _ := m.settlements.start(PendingSettlement{Route: "/api/fast"})
+	m.settlements.start(PendingSettlement{
+		Route:   "/api/slow",
+		Request: types.SettleRequest{PaymentRequirements: types.PaymentRequirements{Amount: "1000000"}},
+	})
+	go func() {
+		time.Sleep(10 * time.Millisecond)
+		m.settlements.finish(done)
+	}()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	if err := m.Shutdown(ctx); err != nil {
+		t.Fatalf("Failed to shut down: %v", err)
+	}
+
+	// New settlements are refused after shutdown
+	if _, ok := m.settlements.start(PendingSettlement{}); ok {
+		t.Errorf("Expected settlements to be refused while draining")
+	}
+
+	pending, err := queue.Load()
+	if err != nil {
+		t.Fatalf("Failed to load queue: %v", err)
+	}
+	if len(pending) != 1 || pending[0].Route != "/api/slow" || pending[0].Request.PaymentRequirements.Amount != "1000000" {
+		t.Errorf("Expected only the slow settlement to be persisted, got %+v", pending)
+	}
+}
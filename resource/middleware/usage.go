@@ -0,0 +1,223 @@
+package middleware
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/vorpalengineering/x402-go/types"
+	"github.com/vorpalengineering/x402-go/version"
+	"github.com/vorpalengineering/x402-go/webhook"
+)
+
+// UsageUnitsKey is the context key handlers set (as int64) to report how many
+// billable units a paid request consumed. Defaults to 1.
+const UsageUnitsKey = "x402_usage_units"
+
+const usageExportTimeout = 10 * time.Second
+
+// UsageRecord is a normalized record of a settled payment for billing systems
+type UsageRecord struct {
+	Timestamp   int64  `json:"timestamp"`
+	Payer       string `json:"payer"`
+	Method      string `json:"method"`
+	Route       string `json:"route"`
+	Path        string `json:"path"`
+	Units       int64  `json:"units"`
+	Amount      string `json:"amount"`
+	Asset       string `json:"asset"`
+	Network     string `json:"network"`
+	PayTo       string `json:"payTo"`
+	Transaction string `json:"transaction"`
+}
+
+// UsageExporter receives a usage record after every successful settlement
+type UsageExporter interface {
+	Export(ctx context.Context, record UsageRecord) error
+}
+
+// UsageExporterFunc adapts a function to a UsageExporter, e.g. a settlement
+// callback into a billing or analytics system
+type UsageExporterFunc func(ctx context.Context, record UsageRecord) error
+
+func (f UsageExporterFunc) Export(ctx context.Context, record UsageRecord) error {
+	return f(ctx, record)
+}
+
+// usageExporters returns the configured exporters, with one HTTP exporter per
+// settlement webhook
+func usageExporters(cfg *MiddlewareConfig) []UsageExporter {
+	exporters := append([]UsageExporter{}, cfg.UsageExporters...)
+	for _, endpoint := range cfg.SettlementWebhooks {
+		exporters = append(exporters, NewHTTPUsageExporter(endpoint))
+	}
+	return exporters
+}
+
+// exportUsage sends record to every configured exporter in the background.
+// Export failures are logged and never affect the paid response.
+func (m *X402Middleware) exportUsage(logger *slog.Logger, record UsageRecord) {
+	for _, exporter := range m.exporters {
+		go func(exporter UsageExporter) {
+			ctx, cancel := context.WithTimeout(context.Background(), usageExportTimeout)
+			defer cancel()
+			if err := exporter.Export(ctx, record); err != nil {
+				logger.Error("failed to export usage record", "tx", record.Transaction, "error", err)
+			}
+		}(exporter)
+	}
+}
+
+// newUsageRecord builds a usage record for a settled request
+func newUsageRecord(ctx *gin.Context, requirements types.PaymentRequirements, exact *types.ExactEVMSchemePayload, settleResp *types.SettleResponse) UsageRecord {
+	record := UsageRecord{
+		Timestamp:   time.Now().Unix(),
+		Payer:       settleResp.Payer,
+		Method:      ctx.Request.Method,
+		Route:       routePath(ctx),
+		Path:        ctx.Request.URL.Path,
+		Units:       1,
+		Amount:      requirements.Amount,
+		Asset:       requirements.Asset,
+		Network:     requirements.Network,
+		PayTo:       requirements.PayTo,
+		Transaction: settleResp.Transaction,
+	}
+
+	// Prefer the route pattern over the raw path
+	if fullPath := ctx.FullPath(); fullPath != "" {
+		record.Route = fullPath
+	}
+
+	// Record what was actually authorized (may exceed the price)
+	if exact != nil && exact.Authorization.Value != "" {
+		record.Amount = exact.Authorization.Value
+	}
+	if exact != nil && record.Payer == "" {
+		record.Payer = exact.Authorization.From
+	}
+
+	// Record what was charged for metered payments
+	if settleResp.Amount != "" {
+		record.Amount = settleResp.Amount
+	}
+
+	// Handler reported units
+	if units, ok := ctx.Get(UsageUnitsKey); ok {
+		if n, ok := units.(int64); ok && n > 0 {
+			record.Units = n
+		}
+	}
+
+	return record
+}
+
+// HTTPUsageExporter posts each record as JSON. If the endpoint has secrets
+// the request is signed like other outbound webhooks.
+type HTTPUsageExporter struct {
+	Endpoint   webhook.Endpoint
+	HTTPClient *http.Client
+}
+
+func NewHTTPUsageExporter(endpoint webhook.Endpoint) *HTTPUsageExporter {
+	return &HTTPUsageExporter{
+		Endpoint:   endpoint,
+		HTTPClient: &http.Client{Timeout: usageExportTimeout, Transport: version.NewTransport("x402-go")},
+	}
+}
+
+func (e *HTTPUsageExporter) Export(ctx context.Context, record UsageRecord) error {
+	body, err := json.Marshal(record)
+	if err != nil {
+		return fmt.Errorf("failed to marshal usage record: %w", err)
+	}
+
+	// Build request, signed if secrets are configured
+	var req *http.Request
+	if len(e.Endpoint.Secrets) > 0 {
+		req, err = webhook.NewRequest(ctx, e.Endpoint, body)
+		if err != nil {
+			return err
+		}
+	} else {
+		req, err = http.NewRequestWithContext(ctx, http.MethodPost, e.Endpoint.URL, bytes.NewReader(body))
+		if err != nil {
+			return fmt.Errorf("failed to create request: %w", err)
+		}
+		req.Header.Set("Content-Type", "application/json")
+	}
+
+	resp, err := e.HTTPClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to send request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("unexpected status code: %d", resp.StatusCode)
+	}
+	return nil
+}
+
+// FileUsageExporter appends each record as a JSON line to a file
+type FileUsageExporter struct {
+	mu   sync.Mutex
+	file *os.File
+}
+
+func NewFileUsageExporter(path string) (*FileUsageExporter, error) {
+	file, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open usage file: %w", err)
+	}
+	return &FileUsageExporter{file: file}, nil
+}
+
+func (e *FileUsageExporter) Export(ctx context.Context, record UsageRecord) error {
+	line, err := json.Marshal(record)
+	if err != nil {
+		return fmt.Errorf("failed to marshal usage record: %w", err)
+	}
+
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if _, err := e.file.Write(append(line, '\n')); err != nil {
+		return fmt.Errorf("failed to write usage record: %w", err)
+	}
+	return nil
+}
+
+func (e *FileUsageExporter) Close() error {
+	return e.file.Close()
+}
+
+// KafkaProducer is the subset of a Kafka client the Kafka exporter needs.
+// Wrap the client of your choice (e.g. segmentio/kafka-go, franz-go) to satisfy it.
+type KafkaProducer interface {
+	Produce(ctx context.Context, topic string, key, value []byte) error
+}
+
+// KafkaUsageExporter produces each record as JSON to a topic, keyed by payer
+// so a payer's records stay in order within a partition
+type KafkaUsageExporter struct {
+	Producer KafkaProducer
+	Topic    string
+}
+
+func (e *KafkaUsageExporter) Export(ctx context.Context, record UsageRecord) error {
+	value, err := json.Marshal(record)
+	if err != nil {
+		return fmt.Errorf("failed to marshal usage record: %w", err)
+	}
+	if err := e.Producer.Produce(ctx, e.Topic, []byte(record.Payer), value); err != nil {
+		return fmt.Errorf("failed to produce usage record: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,142 @@
+package middleware
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/vorpalengineering/x402-go/types"
+	"github.com/vorpalengineering/x402-go/webhook"
+)
+
+type fakeProducer struct {
+	topic string
+	key   []byte
+	value []byte
+}
+
+func (p *fakeProducer) Produce(ctx context.Context, topic string, key, value []byte) error {
+	p.topic, p.key, p.value = topic, key, value
+	return nil
+}
+
+func TestUsageExporters(t *testing.T) {
+	record := UsageRecord{
+		Payer:       "0xf39Fd6e51aad88F6F4ce6aB8827279cffFb92266",
+		Route:       "/api/data",
+		Units:       3,
+		Amount:      "1000000",
+		Transaction: "0xabc",
+	}
+
+	// File exporter appends JSON lines
+	path := filepath.Join(t.TempDir(), "usage.jsonl")
+	fileExporter, err := NewFileUsageExporter(path)
+	if err != nil {
+		t.Fatalf("Failed to create file exporter: %v", err)
+	}
+	fileExporter.Export(context.Background(), record)
+	fileExporter.Export(context.Background(), record)
+	fileExporter.Close()
+
+	data, _ := os.ReadFile(path)
+	lines := strings.Split(strings.TrimSpace(string(data)), "\n")
+	if len(lines) != 2 {
+		t.Fatalf("Expected 2 lines, got %d", len(lines))
+	}
+	var decoded UsageRecord
+	if err := json.Unmarshal([]byte(lines[0]), &decoded); err != nil || decoded.Units != 3 {
+		t.Errorf("Expected record with 3 units, got %+v (%v)", decoded, err)
+	}
+
+	// Kafka exporter keys by payer
+	producer := &fakeProducer{}
+	kafkaExporter := &KafkaUsageExporter{Producer: producer, Topic: "x402-usage"}
+	if err := kafkaExporter.Export(context.Background(), record); err != nil {
+		t.Fatalf("Failed to export: %v", err)
+	}
+	if producer.topic != "x402-usage" || string(producer.key) != record.Payer {
+		t.Errorf("Expected record on x402-usage keyed by payer, got %s/%s", producer.topic, producer.key)
+	}
+}
+
+func TestSettlementWebhooks(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	facilitator := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		switch r.URL.Path {
+		case "/verify":
+			json.NewEncoder(w).Encode(types.VerifyResponse{IsValid: true})
+		case "/settle":
+			json.NewEncoder(w).Encode(types.SettleResponse{Success: true, Transaction: "0xabc"})
+		}
+	}))
+	defer facilitator.Close()
+
+	// The webhook checks the signature of each delivery
+	delivered := make(chan UsageRecord, 1)
+	receiver := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, err := webhook.VerifyRequest(r, []string{"whsec"}, webhook.DefaultTolerance)
+		if err != nil {
+			t.Errorf("Expected signed delivery, got %v", err)
+		}
+		var record UsageRecord
+		json.Unmarshal(body, &record)
+		delivered <- record
+	}))
+	defer receiver.Close()
+
+	called := make(chan UsageRecord, 1)
+	requirements := types.PaymentRequirements{
+		Scheme:  "exact",
+		Network: "eip155:84532",
+		Amount:  "1000",
+		Asset:   "0x036CbD53842c5426634e7929541eC2318f3dCF7e",
+		PayTo:   "0x3C44CdDdB6a900fa2b585dd299e03d12FA4293BC",
+	}
+	cfg := &MiddlewareConfig{
+		FacilitatorURL:      facilitator.URL,
+		DefaultRequirements: requirements,
+		ProtectedPaths:      []string{"/items/:id"},
+		UsageExporters: []UsageExporter{UsageExporterFunc(func(ctx context.Context, record UsageRecord) error {
+			called <- record
+			return nil
+		})},
+		SettlementWebhooks: []webhook.Endpoint{{URL: receiver.URL, Secrets: []string{"whsec"}}},
+	}
+	if err := cfg.Validate(); err != nil {
+		t.Fatalf("Expected valid config, got %v", err)
+	}
+	router := gin.New()
+	router.Use(NewX402Middleware(cfg).Handler())
+	router.GET("/items/:id", func(ctx *gin.Context) {
+		ctx.String(http.StatusOK, "item")
+	})
+
+	req := httptest.NewRequest("GET", "/items/42", nil)
+	req.Header.Set("PAYMENT-SIGNATURE", paidHeader(requirements))
+	recorder := httptest.NewRecorder()
+	router.ServeHTTP(recorder, req)
+	if recorder.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d", recorder.Code)
+	}
+
+	for name, records := range map[string]chan UsageRecord{"callback": called, "webhook": delivered} {
+		select {
+		case record := <-records:
+			if record.Path != "/items/42" || record.Route != "/items/:id" || record.Transaction != "0xabc" || record.Payer != "0x70997970C51812dc3A010C7d01b50e0d17dc79C8" || record.Amount != "1000" {
+				t.Errorf("Unexpected %s record: %+v", name, record)
+			}
+		case <-time.After(5 * time.Second):
+			t.Fatalf("Expected %s to receive the settlement", name)
+		}
+	}
+}
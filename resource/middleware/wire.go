@@ -0,0 +1,153 @@
+package middleware
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/vorpalengineering/x402-go/types"
+	"github.com/vorpalengineering/x402-go/utils"
+)
+
+// V1PaymentHeader carries the payments of x402 v1 clients
+const V1PaymentHeader = "X-PAYMENT"
+
+// V1PaymentResponseHeader carries settlement details to x402 v1 clients
+const V1PaymentResponseHeader = "X-PAYMENT-RESPONSE"
+
+// validateX402Versions checks the configured protocol versions
+func validateX402Versions(versions []int) error {
+	for i, version := range versions {
+		if version != types.X402VersionV1 && version != types.X402VersionV2 {
+			return errors.New("unsupported x402 version " + strconv.Itoa(version) + " (must be 1 or 2)")
+		}
+		if slices.Contains(versions[:i], version) {
+			return errors.New("duplicate x402 version " + strconv.Itoa(version))
+		}
+	}
+	return nil
+}
+
+// speaks reports whether the middleware accepts payments of an x402 version
+func (m *X402Middleware) speaks(version int) bool {
+	if len(m.config.X402Versions) == 0 {
+		return version == types.X402VersionV2
+	}
+	return slices.Contains(m.config.X402Versions, version)
+}
+
+// paymentHeader returns the request's payment and the x402 version of the
+// header it came in, or "" if it carries none
+func (m *X402Middleware) paymentHeader(ctx *gin.Context) (string, int) {
+	if header := ctx.GetHeader(m.config.GetPaymentHeaderName()); header != "" && m.speaks(types.X402VersionV2) {
+		return header, types.X402VersionV2
+	}
+	if header := ctx.GetHeader(V1PaymentHeader); header != "" && m.speaks(types.X402VersionV1) {
+		return header, types.X402VersionV1
+	}
+	return "", 0
+}
+
+// clientVersion is the x402 version to answer a request in: the version of
+// its payment header, or the preferred version when it has none
+func (m *X402Middleware) clientVersion(ctx *gin.Context) int {
+	if _, version := m.paymentHeader(ctx); version != 0 {
+		return version
+	}
+	if len(m.config.X402Versions) > 0 {
+		return m.config.X402Versions[0]
+	}
+	return types.X402VersionV2
+}
+
+// upgradeV1Payload completes a v1 payload, which names only the scheme and
+// network it pays with, with the matching accepted requirements, so it is
+// checked, verified and settled as v2. The recipient of an exact payload's
+// authorization, and the asset when the payload names one, narrow the match.
+// Payloads matching no requirements, or several, keep what they named for
+// precheck to refuse precisely.
+func upgradeV1Payload(payload *types.PaymentPayload, exact *types.ExactEVMSchemePayload, accepts []types.PaymentRequirements) {
+	payload.X402Version = types.X402VersionV2
+	if exact != nil {
+		payload.Accepted.PayTo = exact.Authorization.To
+	}
+
+	var matched []types.PaymentRequirements
+	for _, requirements := range accepts {
+		if requirements.Scheme != payload.Accepted.Scheme || requirements.Network != payload.Accepted.Network {
+			continue
+		}
+		if payload.Accepted.PayTo != "" && !strings.EqualFold(requirements.PayTo, payload.Accepted.PayTo) {
+			continue
+		}
+		if payload.Accepted.Asset != "" && !strings.EqualFold(requirements.Asset, payload.Accepted.Asset) {
+			continue
+		}
+		matched = append(matched, requirements)
+	}
+	if len(matched) == 1 {
+		payload.Accepted = matched[0]
+	}
+}
+
+// paymentRequiredV1 converts a 402 response for a v1 client. Requirements on
+// networks v1 has no name for keep their CAIP-2 id.
+func (m *X402Middleware) paymentRequiredV1(ctx *gin.Context, response *types.PaymentRequired) *types.PaymentRequiredV1 {
+	resource := response.Resource
+	if resource == nil {
+		resource = m.paymentRequired(routePath(ctx), ctx.Request.URL.Path, nil).Resource
+	}
+
+	v1 := &types.PaymentRequiredV1{
+		X402Version: types.X402VersionV1,
+		Error:       response.Error,
+		Accepts:     make([]types.PaymentRequirementsV1, 0, len(response.Accepts)),
+	}
+	for _, requirements := range response.Accepts {
+		v1.Accepts = append(v1.Accepts, types.PaymentRequirementsV1{
+			Scheme:            requirements.Scheme,
+			Network:           utils.NetworkToV1(requirements.Network),
+			MaxAmountRequired: requirements.Amount,
+			Resource:          resource.URL,
+			Description:       resource.Description,
+			MimeType:          resource.MimeType,
+			PayTo:             requirements.PayTo,
+			MaxTimeoutSeconds: requirements.MaxTimeoutSeconds,
+			Asset:             requirements.Asset,
+			Extra:             requirements.Extra,
+		})
+	}
+	return v1
+}
+
+// sendPaymentRequiredBody responds with a 402 body in the client's version.
+// The PAYMENT-REQUIRED header stays v2, so v2 clients reading it can pay
+// whatever the body's version.
+func (m *X402Middleware) sendPaymentRequiredBody(ctx *gin.Context, status int, response *types.PaymentRequired) {
+	if m.clientVersion(ctx) == types.X402VersionV1 {
+		ctx.JSON(status, m.paymentRequiredV1(ctx, response))
+	} else {
+		ctx.JSON(status, response)
+	}
+	ctx.Abort()
+}
+
+// setV1PaymentResponseHeader sets the X-PAYMENT-RESPONSE header of a v1
+// client, naming the network the way v1 does
+func (m *X402Middleware) setV1PaymentResponseHeader(ctx *gin.Context, response *types.SettleResponse) {
+	v1 := *response
+	v1.X402Version = types.X402VersionV1
+	if v1.Network != "" {
+		v1.Network = utils.NetworkToV1(v1.Network)
+	}
+	data, err := json.Marshal(&v1)
+	if err != nil {
+		m.logger.Error("failed to encode X-PAYMENT-RESPONSE header", "error", err)
+		return
+	}
+	ctx.Header(V1PaymentResponseHeader, base64.StdEncoding.EncodeToString(data))
+}
@@ -0,0 +1,305 @@
+package middleware
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/vorpalengineering/x402-go/types"
+)
+
+// v1PaidHeader encodes a v1 exact payment on a v1 network name
+func v1PaidHeader(requirements types.PaymentRequirements, network string) string {
+	payload, _ := json.Marshal(types.PaymentPayloadV1{
+		X402Version: types.X402VersionV1,
+		Scheme:      "exact",
+		Network:     network,
+		Payload: map[string]any{
+			"signature": "0x" + strings.Repeat("11", 65),
+			"authorization": map[string]any{
+				"from":        "0x70997970C51812dc3A010C7d01b50e0d17dc79C8",
+				"to":          requirements.PayTo,
+				"value":       requirements.Amount,
+				"validAfter":  0,
+				"validBefore": time.Now().Add(time.Minute).Unix(),
+				"nonce":       "0x" + strings.Repeat("01", 32),
+			},
+		},
+	})
+	return base64.StdEncoding.EncodeToString(payload)
+}
+
+func TestX402Versions(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	var settled []types.SettleRequest
+	facilitator := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		switch r.URL.Path {
+		case "/verify":
+			json.NewEncoder(w).Encode(types.VerifyResponse{IsValid: true})
+		case "/settle":
+			var req types.SettleRequest
+			json.NewDecoder(r.Body).Decode(&req)
+			settled = append(settled, req)
+			json.NewEncoder(w).Encode(types.SettleResponse{Success: true, Transaction: "0xabc", Network: req.PaymentRequirements.Network})
+		}
+	}))
+	defer facilitator.Close()
+
+	requirements := types.PaymentRequirements{
+		Scheme:            "exact",
+		Network:           "eip155:84532",
+		Amount:            "1000",
+		Asset:             "0x036CbD53842c5426634e7929541eC2318f3dCF7e",
+		PayTo:             "0x3C44CdDdB6a900fa2b585dd299e03d12FA4293BC",
+		MaxTimeoutSeconds: 60,
+	}
+	newRouter := func(versions []int) *gin.Engine {
+		cfg := &MiddlewareConfig{
+			FacilitatorURL:      facilitator.URL,
+			DefaultRequirements: requirements,
+			ProtectedPaths:      []string{"/data"},
+			X402Versions:        versions,
+		}
+		if err := cfg.Validate(); err != nil {
+			t.Fatalf("Expected valid config, got %v", err)
+		}
+		router := gin.New()
+		router.Use(NewX402Middleware(cfg).Handler())
+		router.GET("/data", func(ctx *gin.Context) {
+			ctx.String(http.StatusOK, "data")
+		})
+		return router
+	}
+	dual := newRouter([]int{1, 2})
+
+	// Unpaid requests get a v1 body and the v2 header
+	recorder := httptest.NewRecorder()
+	dual.ServeHTTP(recorder, httptest.NewRequest("GET", "/data", nil))
+	var v1Body types.PaymentRequiredV1
+	json.Unmarshal(recorder.Body.Bytes(), &v1Body)
+	if recorder.Code != http.StatusPaymentRequired || v1Body.X402Version != 1 || len(v1Body.Accepts) != 1 {
+		t.Fatalf("Expected v1 402 body, got %d: %s", recorder.Code, recorder.Body.String())
+	}
+	if accepted := v1Body.Accepts[0]; accepted.Network != "base-sepolia" || accepted.MaxAmountRequired != "1000" || accepted.Resource != "/data" {
+		t.Errorf("Expected v1 requirements, got %+v", accepted)
+	}
+	if v1Body.Error != "X-PAYMENT header is required" {
+		t.Errorf("Expected v1 header in error, got %q", v1Body.Error)
+	}
+	if recorder.Header().Get("PAYMENT-REQUIRED") == "" {
+		t.Error("Expected PAYMENT-REQUIRED header for v2 clients")
+	}
+
+	// v1 payments are settled as v2 and answered in v1
+	req := httptest.NewRequest("GET", "/data", nil)
+	req.Header.Set(V1PaymentHeader, v1PaidHeader(requirements, "base-sepolia"))
+	recorder = httptest.NewRecorder()
+	dual.ServeHTTP(recorder, req)
+	if recorder.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d: %s", recorder.Code, recorder.Body.String())
+	}
+	if len(settled) != 1 || settled[0].PaymentPayload.X402Version != 2 || settled[0].PaymentPayload.Accepted.Amount != "1000" {
+		t.Errorf("Expected upgraded v2 settlement, got %+v", settled)
+	}
+	var settleResp types.SettleResponse
+	decoded, _ := base64.StdEncoding.DecodeString(recorder.Header().Get(V1PaymentResponseHeader))
+	json.Unmarshal(decoded, &settleResp)
+	if settleResp.X402Version != 1 || settleResp.Network != "base-sepolia" || settleResp.Transaction != "0xabc" {
+		t.Errorf("Expected v1 payment response, got %+v", settleResp)
+	}
+	if recorder.Header().Get("PAYMENT-RESPONSE") != "" {
+		t.Error("Expected no v2 payment response for a v1 client")
+	}
+
+	// v2 payments are answered in v2
+	req = httptest.NewRequest("GET", "/data", nil)
+	req.Header.Set("PAYMENT-SIGNATURE", paidHeader(requirements))
+	recorder = httptest.NewRecorder()
+	dual.ServeHTTP(recorder, req)
+	if recorder.Code != http.StatusOK || recorder.Header().Get("PAYMENT-RESPONSE") == "" {
+		t.Errorf("Expected v2 payment response, got %d: %v", recorder.Code, recorder.Header())
+	}
+
+	// Rejected v1 payments get a v1 body
+	req = httptest.NewRequest("GET", "/data", nil)
+	req.Header.Set(V1PaymentHeader, v1PaidHeader(requirements, "base"))
+	recorder = httptest.NewRecorder()
+	dual.ServeHTTP(recorder, req)
+	v1Body = types.PaymentRequiredV1{}
+	json.Unmarshal(recorder.Body.Bytes(), &v1Body)
+	if recorder.Code != http.StatusPaymentRequired || v1Body.X402Version != 1 || !strings.Contains(v1Body.Error, "eip155:8453") {
+		t.Errorf("Expected v1 rejection, got %d: %s", recorder.Code, recorder.Body.String())
+	}
+
+	// v1 is off by default
+	req = httptest.NewRequest("GET", "/data", nil)
+	req.Header.Set(V1PaymentHeader, v1PaidHeader(requirements, "base-sepolia"))
+	recorder = httptest.NewRecorder()
+	newRouter(nil).ServeHTTP(recorder, req)
+	var v2Body types.PaymentRequired
+	json.Unmarshal(recorder.Body.Bytes(), &v2Body)
+	if recorder.Code != http.StatusPaymentRequired || v2Body.X402Version != 2 {
+		t.Errorf("Expected v2 402 body, got %d: %s", recorder.Code, recorder.Body.String())
+	}
+}
+
+func TestUpgradeV1Payload(t *testing.T) {
+	usdc := types.PaymentRequirements{
+		Scheme:  "exact",
+		Network: "eip155:84532",
+		Amount:  "1000",
+		Asset:   "0x036CbD53842c5426634e7929541eC2318f3dCF7e",
+		PayTo:   "0x3C44CdDdB6a900fa2b585dd299e03d12FA4293BC",
+	}
+	eurc := types.PaymentRequirements{
+		Scheme:  "exact",
+		Network: "eip155:84532",
+		Amount:  "900",
+		Asset:   "0x808456652fdb597867f38412077A9182bf77359F",
+		PayTo:   "0x90F79bf6EB2c4f870365E785982E1f101E93b906",
+	}
+	sharedPayTo := eurc
+	sharedPayTo.PayTo = usdc.PayTo
+
+	tests := []struct {
+		name     string
+		accepts  []types.PaymentRequirements
+		to       string
+		asset    string
+		expected types.PaymentRequirements
+	}{
+		{"first recipient", []types.PaymentRequirements{usdc, eurc}, usdc.PayTo, "", usdc},
+		{"second recipient", []types.PaymentRequirements{usdc, eurc}, strings.ToLower(eurc.PayTo), "", eurc},
+		{"unknown recipient", []types.PaymentRequirements{usdc, eurc}, "0x15d34AAf54267DB7D7c367839AAf71A00a2C6A65", "", types.PaymentRequirements{}},
+		{"ambiguous recipient", []types.PaymentRequirements{usdc, sharedPayTo}, usdc.PayTo, "", types.PaymentRequirements{}},
+		{"asset disambiguates", []types.PaymentRequirements{usdc, sharedPayTo}, usdc.PayTo, sharedPayTo.Asset, sharedPayTo},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			payload := &types.PaymentPayload{
+				X402Version: types.X402VersionV1,
+				Accepted:    types.PaymentRequirements{Scheme: "exact", Network: "eip155:84532", Asset: tt.asset},
+			}
+			exact := &types.ExactEVMSchemePayload{Authorization: types.ExactEVMSchemeAuthorization{To: tt.to}}
+			upgradeV1Payload(payload, exact, tt.accepts)
+
+			if payload.X402Version != types.X402VersionV2 {
+				t.Errorf("Expected v2 payload, got v%d", payload.X402Version)
+			}
+			if tt.expected.Asset == "" {
+				// Unmatched payloads keep what they named
+				if payload.Accepted.Amount != "" || payload.Accepted.Asset != tt.asset || payload.Accepted.PayTo != tt.to {
+					t.Errorf("Expected payload not to be upgraded, got %+v", payload.Accepted)
+				}
+				return
+			}
+			if payload.Accepted.Asset != tt.expected.Asset || payload.Accepted.PayTo != tt.expected.PayTo || payload.Accepted.Amount != tt.expected.Amount {
+				t.Errorf("Expected %+v, got %+v", tt.expected, payload.Accepted)
+			}
+		})
+	}
+
+	// Payloads without an authorization match on scheme and network alone
+	payload := &types.PaymentPayload{
+		X402Version: types.X402VersionV1,
+		Accepted:    types.PaymentRequirements{Scheme: "exact", Network: "eip155:84532"},
+	}
+	upgradeV1Payload(payload, nil, []types.PaymentRequirements{usdc, eurc})
+	if payload.Accepted.Asset != "" {
+		t.Errorf("Expected ambiguous payload not to be upgraded, got %+v", payload.Accepted)
+	}
+	upgradeV1Payload(payload, nil, []types.PaymentRequirements{eurc})
+	if payload.Accepted.Asset != eurc.Asset {
+		t.Errorf("Expected single match to be upgraded, got %+v", payload.Accepted)
+	}
+}
+
+func TestV1PaymentsOnSharedNetwork(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	var settled []types.SettleRequest
+	facilitator := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		switch r.URL.Path {
+		case "/verify":
+			json.NewEncoder(w).Encode(types.VerifyResponse{IsValid: true})
+		case "/settle":
+			var req types.SettleRequest
+			json.NewDecoder(r.Body).Decode(&req)
+			settled = append(settled, req)
+			json.NewEncoder(w).Encode(types.SettleResponse{Success: true, Transaction: "0xabc", Network: req.PaymentRequirements.Network})
+		}
+	}))
+	defer facilitator.Close()
+
+	// Two accepted entries on the same network, paying different recipients
+	usdc := types.PaymentRequirements{
+		Scheme:            "exact",
+		Network:           "eip155:84532",
+		Amount:            "1000",
+		Asset:             "0x036CbD53842c5426634e7929541eC2318f3dCF7e",
+		PayTo:             "0x3C44CdDdB6a900fa2b585dd299e03d12FA4293BC",
+		MaxTimeoutSeconds: 60,
+	}
+	eurc := types.PaymentRequirements{
+		Scheme:            "exact",
+		Network:           "eip155:84532",
+		Amount:            "900",
+		Asset:             "0x808456652fdb597867f38412077A9182bf77359F",
+		PayTo:             "0x90F79bf6EB2c4f870365E785982E1f101E93b906",
+		MaxTimeoutSeconds: 60,
+	}
+	cfg := &MiddlewareConfig{
+		FacilitatorURL: facilitator.URL,
+		DefaultAccepts: []types.PaymentRequirements{usdc, eurc},
+		ProtectedPaths: []string{"/data"},
+		X402Versions:   []int{1, 2},
+	}
+	if err := cfg.Validate(); err != nil {
+		t.Fatalf("Expected valid config, got %v", err)
+	}
+	router := gin.New()
+	router.Use(NewX402Middleware(cfg).Handler())
+	router.GET("/data", func(ctx *gin.Context) {
+		ctx.String(http.StatusOK, "data")
+	})
+
+	// A v1 payment to the second entry's recipient settles with that entry
+	req := httptest.NewRequest("GET", "/data", nil)
+	req.Header.Set(V1PaymentHeader, v1PaidHeader(eurc, "base-sepolia"))
+	recorder := httptest.NewRecorder()
+	router.ServeHTTP(recorder, req)
+	if recorder.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d: %s", recorder.Code, recorder.Body.String())
+	}
+	if len(settled) != 1 || settled[0].PaymentRequirements.Asset != eurc.Asset || settled[0].PaymentPayload.Accepted.Amount != "900" {
+		t.Errorf("Expected settlement with the second entry, got %+v", settled)
+	}
+}
+
+func TestX402VersionsValidation(t *testing.T) {
+	tests := []struct {
+		name     string
+		versions []int
+		valid    bool
+	}{
+		{"default", nil, true},
+		{"dual", []int{2, 1}, true},
+		{"unknown", []int{3}, false},
+		{"duplicate", []int{2, 2}, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := validateX402Versions(tt.versions); (err == nil) != tt.valid {
+				t.Errorf("Expected valid %v, got %v", tt.valid, err)
+			}
+		})
+	}
+}
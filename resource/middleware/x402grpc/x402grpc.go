@@ -0,0 +1,202 @@
+// Package x402grpc adapts the x402 payment middleware to gRPC servers. The
+// interceptors verify the payment sent in the call's metadata before the
+// handler runs and settle it once the handler has succeeded, with the full
+// method name (e.g. "/pkg.Service/Method") as the route. Declare protected
+// paths and route requirements with method names, or a service's methods
+// with "/pkg.Service/*".
+package x402grpc
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"github.com/vorpalengineering/x402-go/resource/middleware"
+	"github.com/vorpalengineering/x402-go/types"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+const (
+	// PaymentMetadataKey is the metadata key clients send the encoded
+	// payment in, as the PAYMENT-SIGNATURE header over HTTP
+	PaymentMetadataKey = "payment-signature"
+
+	// PaymentRequiredMetadataKey is the trailer holding the base64 JSON
+	// requirements of calls refused for a missing or rejected payment
+	PaymentRequiredMetadataKey = "payment-required"
+
+	// PaymentResponseMetadataKey is the trailer holding the base64 JSON
+	// settlement of paid calls
+	PaymentResponseMetadataKey = "payment-response"
+)
+
+// paidCall is the state of a paid call, kept in the handler's context
+type paidCall struct {
+	payment *middleware.Payment
+	amount  string
+}
+
+type paidCallKey struct{}
+
+// UnaryServerInterceptor charges for unary calls to protected methods. The
+// payment is released if the handler fails or panics, so the client can
+// present it again, and settled otherwise before the response is sent.
+func UnaryServerInterceptor(m *middleware.X402Middleware) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+		if !m.Protects(info.FullMethod) {
+			return handler(ctx, req)
+		}
+		setTrailer := func(md metadata.MD) { grpc.SetTrailer(ctx, md) }
+
+		// Verify the payment before the handler runs
+		payment, err := m.VerifyPayment(ctx, info.FullMethod, paymentHeader(ctx))
+		if err != nil {
+			return nil, refuse(err, setTrailer)
+		}
+
+		// Serve the call, releasing the payment if it fails
+		call := &paidCall{payment: payment}
+		var resp any
+		err = serve(ctx, m, payment, func() error {
+			var err error
+			resp, err = handler(context.WithValue(ctx, paidCallKey{}, call), req)
+			return err
+		})
+		if err != nil {
+			return nil, err
+		}
+
+		// Settle before the response is sent
+		settled, err := m.SettlePayment(ctx, payment, call.amount)
+		if err != nil {
+			return nil, refuse(err, setTrailer)
+		}
+		setTrailer(receipt(settled))
+		return resp, nil
+	}
+}
+
+// StreamServerInterceptor charges for streaming calls to protected methods.
+// The payment is released if the handler fails or panics, and settled
+// otherwise once the handler returns. Messages the handler sent have then
+// already reached the client, so a failed settlement only ends the call
+// with an error status.
+func StreamServerInterceptor(m *middleware.X402Middleware) grpc.StreamServerInterceptor {
+	return func(srv any, stream grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		if !m.Protects(info.FullMethod) {
+			return handler(srv, stream)
+		}
+		ctx := stream.Context()
+
+		// Verify the payment before the handler runs
+		payment, err := m.VerifyPayment(ctx, info.FullMethod, paymentHeader(ctx))
+		if err != nil {
+			return refuse(err, stream.SetTrailer)
+		}
+
+		// Serve the call, releasing the payment if it fails
+		call := &paidCall{payment: payment}
+		paidStream := &serverStream{ServerStream: stream, ctx: context.WithValue(ctx, paidCallKey{}, call)}
+		if err := serve(ctx, m, payment, func() error { return handler(srv, paidStream) }); err != nil {
+			return err
+		}
+
+		// Settle once the handler is done
+		settled, err := m.SettlePayment(ctx, payment, call.amount)
+		if err != nil {
+			return refuse(err, stream.SetTrailer)
+		}
+		stream.SetTrailer(receipt(settled))
+		return nil
+	}
+}
+
+// Payment returns the verified payment of the call ctx belongs to, false
+// for calls that are not paid
+func Payment(ctx context.Context) (*middleware.Payment, bool) {
+	call, ok := ctx.Value(paidCallKey{}).(*paidCall)
+	if !ok {
+		return nil, false
+	}
+	return call.payment, true
+}
+
+// SetSettleAmount charges amount (in atomic units) for an "upto" scheme call
+// instead of the method's price, which it must not exceed
+func SetSettleAmount(ctx context.Context, amount string) {
+	if call, ok := ctx.Value(paidCallKey{}).(*paidCall); ok {
+		call.amount = amount
+	}
+}
+
+// serve runs handler, releasing payment if it returns an error or panics
+func serve(ctx context.Context, m *middleware.X402Middleware, payment *middleware.Payment, handler func() error) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			m.ReleasePayment(context.WithoutCancel(ctx), payment)
+			panic(r)
+		}
+	}()
+	if err = handler(); err != nil {
+		m.ReleasePayment(context.WithoutCancel(ctx), payment)
+	}
+	return err
+}
+
+// serverStream overrides the context handlers see
+type serverStream struct {
+	grpc.ServerStream
+	ctx context.Context
+}
+
+func (s *serverStream) Context() context.Context {
+	return s.ctx
+}
+
+// paymentHeader returns the encoded payment in the call's metadata
+func paymentHeader(ctx context.Context) string {
+	md, ok := metadata.FromIncomingContext(ctx)
+	if !ok {
+		return ""
+	}
+	if values := md.Get(PaymentMetadataKey); len(values) > 0 {
+		return values[0]
+	}
+	return ""
+}
+
+// receipt is the trailer carrying a settlement
+func receipt(settled *types.SettleResponse) metadata.MD {
+	encoded, _ := json.Marshal(settled)
+	return metadata.Pairs(PaymentResponseMetadataKey, base64.StdEncoding.EncodeToString(encoded))
+}
+
+// refuse maps a refusal to a gRPC status, sending the requirements of calls
+// asking for a payment in the trailer
+func refuse(err error, setTrailer func(metadata.MD)) error {
+	var paymentErr *middleware.PaymentError
+	if !errors.As(err, &paymentErr) {
+		return status.Error(codes.Internal, err.Error())
+	}
+	if paymentErr.PaymentRequired != nil {
+		encoded, _ := json.Marshal(paymentErr.PaymentRequired)
+		setTrailer(metadata.Pairs(PaymentRequiredMetadataKey, base64.StdEncoding.EncodeToString(encoded)))
+	}
+
+	switch paymentErr.Status {
+	case http.StatusPaymentRequired, http.StatusForbidden:
+		return status.Error(codes.PermissionDenied, paymentErr.Error())
+	case http.StatusBadRequest:
+		return status.Error(codes.InvalidArgument, paymentErr.Error())
+	case http.StatusServiceUnavailable, http.StatusBadGateway:
+		return status.Error(codes.Unavailable, paymentErr.Error())
+	case http.StatusGatewayTimeout:
+		return status.Error(codes.DeadlineExceeded, paymentErr.Error())
+	}
+	return status.Error(codes.Internal, paymentErr.Error())
+}
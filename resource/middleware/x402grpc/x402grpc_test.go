@@ -0,0 +1,259 @@
+package x402grpc_test
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"io"
+	"net"
+	"testing"
+
+	"github.com/vorpalengineering/x402-go/resource/middleware"
+	"github.com/vorpalengineering/x402-go/resource/middleware/x402grpc"
+	"github.com/vorpalengineering/x402-go/types"
+	"github.com/vorpalengineering/x402-go/x402test"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+	"google.golang.org/protobuf/types/known/wrapperspb"
+)
+
+const (
+	echoMethod  = "/x402grpc.test.Echo/Echo"
+	countMethod = "/x402grpc.test.Echo/Count"
+	freeMethod  = "/x402grpc.test.Echo/Free"
+)
+
+// echoService answers Echo and Free with their request, and streams Count
+// back three times. Requests of "fail" make the handlers fail.
+var echoService = grpc.ServiceDesc{
+	ServiceName: "x402grpc.test.Echo",
+	HandlerType: (*any)(nil),
+	Methods: []grpc.MethodDesc{
+		{MethodName: "Echo", Handler: echoHandler(echoMethod)},
+		{MethodName: "Free", Handler: echoHandler(freeMethod)},
+	},
+	Streams: []grpc.StreamDesc{
+		{StreamName: "Count", Handler: countHandler, ServerStreams: true},
+	},
+}
+
+var errHandler = errors.New("handler failed")
+
+func echoHandler(method string) func(srv any, ctx context.Context, dec func(any) error, interceptor grpc.UnaryServerInterceptor) (any, error) {
+	return func(srv any, ctx context.Context, dec func(any) error, interceptor grpc.UnaryServerInterceptor) (any, error) {
+		in := new(wrapperspb.StringValue)
+		if err := dec(in); err != nil {
+			return nil, err
+		}
+		handler := func(ctx context.Context, req any) (any, error) {
+			in := req.(*wrapperspb.StringValue)
+			if in.Value == "fail" {
+				return nil, status.Error(codes.Internal, errHandler.Error())
+			}
+			if _, paid := x402grpc.Payment(ctx); paid != (method != freeMethod) {
+				return nil, status.Error(codes.Internal, "unexpected payment state")
+			}
+			return in, nil
+		}
+		return interceptor(ctx, in, &grpc.UnaryServerInfo{FullMethod: method}, handler)
+	}
+}
+
+func countHandler(srv any, stream grpc.ServerStream) error {
+	in := new(wrapperspb.StringValue)
+	if err := stream.RecvMsg(in); err != nil {
+		return err
+	}
+	if in.Value == "fail" {
+		return status.Error(codes.Internal, errHandler.Error())
+	}
+	for range 3 {
+		if err := stream.SendMsg(in); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// newClient serves echoService behind the interceptors and returns a client
+// connection to it
+func newClient(t *testing.T, facilitatorURL string, requirements types.PaymentRequirements) *grpc.ClientConn {
+	t.Helper()
+	cfg := &middleware.MiddlewareConfig{
+		FacilitatorURL:      facilitatorURL,
+		DefaultRequirements: requirements,
+		ProtectedPaths:      []string{echoMethod, countMethod},
+		ReplayStore:         middleware.NewMemoryReplayStore(),
+		LogLevel:            "error",
+	}
+	if err := cfg.Validate(); err != nil {
+		t.Fatalf("Expected valid config, got %v", err)
+	}
+	x402 := middleware.NewX402Middleware(cfg)
+
+	listener := bufconn.Listen(1 << 20)
+	server := grpc.NewServer(
+		grpc.UnaryInterceptor(x402grpc.UnaryServerInterceptor(x402)),
+		grpc.StreamInterceptor(x402grpc.StreamServerInterceptor(x402)),
+	)
+	server.RegisterService(&echoService, struct{}{})
+	go server.Serve(listener)
+	t.Cleanup(server.Stop)
+
+	conn, err := grpc.NewClient("passthrough:///bufnet",
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) {
+			return listener.DialContext(ctx)
+		}),
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+	)
+	if err != nil {
+		t.Fatalf("Failed to dial: %v", err)
+	}
+	t.Cleanup(func() { conn.Close() })
+	return conn
+}
+
+// paid returns a context sending header as the call's payment
+func paid(header string) context.Context {
+	return metadata.AppendToOutgoingContext(context.Background(), x402grpc.PaymentMetadataKey, header)
+}
+
+// decodeTrailer decodes the base64 JSON trailer key into out
+func decodeTrailer(t *testing.T, trailer metadata.MD, key string, out any) {
+	t.Helper()
+	values := trailer.Get(key)
+	if len(values) != 1 {
+		t.Fatalf("Expected %s trailer, got %v", key, trailer)
+	}
+	encoded, err := base64.StdEncoding.DecodeString(values[0])
+	if err != nil {
+		t.Fatalf("Expected base64 %s trailer, got %v", key, err)
+	}
+	if err := json.Unmarshal(encoded, out); err != nil {
+		t.Fatalf("Expected JSON %s trailer, got %v", key, err)
+	}
+}
+
+func TestUnaryServerInterceptor(t *testing.T) {
+	facilitator := x402test.NewFacilitator(t).Strict()
+	requirements := x402test.Requirements("1000")
+	conn := newClient(t, facilitator.URL, requirements)
+	payer := x402test.NewPayer(t)
+
+	// Unprotected methods are served for free
+	out := new(wrapperspb.StringValue)
+	if err := conn.Invoke(context.Background(), freeMethod, wrapperspb.String("hi"), out); err != nil {
+		t.Fatalf("Expected free call to succeed, got %v", err)
+	}
+
+	// Unpaid calls are refused with the requirements in the trailer
+	var trailer metadata.MD
+	err := conn.Invoke(context.Background(), echoMethod, wrapperspb.String("hi"), out, grpc.Trailer(&trailer))
+	if status.Code(err) != codes.PermissionDenied {
+		t.Fatalf("Expected PermissionDenied, got %v", err)
+	}
+	var paymentRequired types.PaymentRequired
+	decodeTrailer(t, trailer, x402grpc.PaymentRequiredMetadataKey, &paymentRequired)
+	if len(paymentRequired.Accepts) != 1 || paymentRequired.Accepts[0].Amount != "1000" {
+		t.Errorf("Expected the method's requirements, got %+v", paymentRequired.Accepts)
+	}
+
+	// A failing handler releases the payment, so it can be presented again
+	header := payer.Header(t, requirements)
+	err = conn.Invoke(paid(header), echoMethod, wrapperspb.String("fail"), out)
+	if status.Code(err) != codes.Internal {
+		t.Fatalf("Expected the handler's error, got %v", err)
+	}
+	facilitator.AssertSettled(t, 0)
+
+	// A paid call is settled, with the receipt in the trailer
+	trailer = nil
+	if err := conn.Invoke(paid(header), echoMethod, wrapperspb.String("hi"), out, grpc.Trailer(&trailer)); err != nil {
+		t.Fatalf("Expected paid call to succeed, got %v", err)
+	}
+	if out.Value != "hi" {
+		t.Errorf("Expected echo, got %q", out.Value)
+	}
+	var settleResp types.SettleResponse
+	decodeTrailer(t, trailer, x402grpc.PaymentResponseMetadataKey, &settleResp)
+	if !settleResp.Success || settleResp.Payer != payer.Address {
+		t.Errorf("Expected settlement by %s, got %+v", payer.Address, settleResp)
+	}
+	facilitator.AssertSettled(t, 1)
+
+	// A settled payment is refused
+	err = conn.Invoke(paid(header), echoMethod, wrapperspb.String("hi"), out)
+	if status.Code(err) != codes.PermissionDenied {
+		t.Errorf("Expected replay to be refused, got %v", err)
+	}
+
+	// Failed settlements fail the call
+	facilitator.FailSettlements(types.ErrCodeTransactionReverted)
+	err = conn.Invoke(paid(payer.Header(t, requirements)), echoMethod, wrapperspb.String("hi"), out)
+	if status.Code(err) != codes.PermissionDenied {
+		t.Errorf("Expected failed settlement to be refused, got %v", err)
+	}
+	facilitator.AssertSettled(t, 1)
+}
+
+func TestStreamServerInterceptor(t *testing.T) {
+	facilitator := x402test.NewFacilitator(t).Strict()
+	requirements := x402test.Requirements("1000")
+	conn := newClient(t, facilitator.URL, requirements)
+	payer := x402test.NewPayer(t)
+	desc := &grpc.StreamDesc{StreamName: "Count", ServerStreams: true}
+
+	// count calls Count, returning the messages received and the status
+	count := func(ctx context.Context, value string) (int, metadata.MD, error) {
+		stream, err := conn.NewStream(ctx, desc, countMethod)
+		if err != nil {
+			return 0, nil, err
+		}
+		if err := stream.SendMsg(wrapperspb.String(value)); err != nil {
+			return 0, nil, err
+		}
+		stream.CloseSend()
+		received := 0
+		for {
+			if err := stream.RecvMsg(new(wrapperspb.StringValue)); err != nil {
+				if errors.Is(err, io.EOF) {
+					return received, stream.Trailer(), nil
+				}
+				return received, stream.Trailer(), err
+			}
+			received++
+		}
+	}
+
+	// Unpaid streams are refused before the handler runs
+	received, trailer, err := count(context.Background(), "hi")
+	if status.Code(err) != codes.PermissionDenied || received != 0 {
+		t.Fatalf("Expected PermissionDenied before any message, got %d messages and %v", received, err)
+	}
+	var paymentRequired types.PaymentRequired
+	decodeTrailer(t, trailer, x402grpc.PaymentRequiredMetadataKey, &paymentRequired)
+
+	// A failing handler releases the payment
+	header := payer.Header(t, requirements)
+	if _, _, err := count(paid(header), "fail"); status.Code(err) != codes.Internal {
+		t.Fatalf("Expected the handler's error, got %v", err)
+	}
+	facilitator.AssertSettled(t, 0)
+
+	// A paid stream is settled once the handler returns
+	received, trailer, err = count(paid(header), "hi")
+	if err != nil || received != 3 {
+		t.Fatalf("Expected 3 messages, got %d and %v", received, err)
+	}
+	var settleResp types.SettleResponse
+	decodeTrailer(t, trailer, x402grpc.PaymentResponseMetadataKey, &settleResp)
+	if !settleResp.Success {
+		t.Errorf("Expected settlement, got %+v", settleResp)
+	}
+	facilitator.AssertSettled(t, 1)
+}
@@ -0,0 +1,125 @@
+// Package x402http adapts the x402 payment middleware to net/http, so servers
+// built on http.ServeMux or any framework accepting an http.Handler can charge
+// for requests without using gin themselves.
+package x402http
+
+import (
+	"context"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/go-chi/chi/v5"
+	"github.com/vorpalengineering/x402-go/resource/middleware"
+	"github.com/vorpalengineering/x402-go/types"
+)
+
+// ginContextKey holds the middleware's context in the request passed to next
+type ginContextKey struct{}
+
+// Middleware protects next with a new x402 middleware built from cfg. The
+// verify, fulfill, settle flow is the gin middleware's: next runs once the
+// payment is verified and its response is only sent after settlement.
+func Middleware(next http.Handler, cfg *middleware.MiddlewareConfig) http.Handler {
+	return Handler(middleware.NewX402Middleware(cfg), next)
+}
+
+// Handler protects next with an existing x402 middleware, e.g. one kept to
+// call Shutdown on.
+func Handler(m *middleware.X402Middleware, next http.Handler) http.Handler {
+	return handler(m, next, nil)
+}
+
+// Chain returns the middleware in the func(http.Handler) http.Handler form
+// taken by chi's Use and With. Protected paths and route requirements are
+// matched against the pattern of the chi route the request matches, so they
+// can be declared as the router's routes are (e.g. "/users/{id}"). Requests
+// outside a chi router are matched by path.
+func Chain(m *middleware.X402Middleware) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return handler(m, next, chiRoutePattern)
+	}
+}
+
+func handler(m *middleware.X402Middleware, next http.Handler, routePattern func(r *http.Request) string) http.Handler {
+	engine := gin.New()
+	if routePattern != nil {
+		engine.Use(func(ctx *gin.Context) {
+			if pattern := routePattern(ctx.Request); pattern != "" {
+				ctx.Set(middleware.RoutePatternKey, pattern)
+			}
+		})
+	}
+	engine.Use(m.Handler())
+
+	// Every request is unrouted, so the chain ends in next
+	engine.NoRoute(func(ctx *gin.Context) {
+		// Undo the 404 gin presets for unrouted requests
+		ctx.Status(http.StatusOK)
+		req := ctx.Request.WithContext(context.WithValue(ctx.Request.Context(), ginContextKey{}, ctx))
+		next.ServeHTTP(ctx.Writer, req)
+	})
+	return engine
+}
+
+// chiRoutePattern returns the pattern of the chi route r matches, or "" if
+// r is not served by a chi router
+func chiRoutePattern(r *http.Request) string {
+	rctx := chi.RouteContext(r.Context())
+	if rctx == nil {
+		return ""
+	}
+
+	// Route-level middleware (With, inline groups) runs once the route is
+	// known
+	if pattern := rctx.RoutePattern(); pattern != "" && !strings.HasSuffix(pattern, "/*") {
+		return pattern
+	}
+
+	// Router-level middleware (Use) runs before routing, or mid-way for
+	// mounted routers, so look the route up from the top-level router
+	if rctx.Routes == nil {
+		return ""
+	}
+	path := r.URL.RawPath
+	if path == "" {
+		path = r.URL.Path
+	}
+	return rctx.Routes.Find(chi.NewRouteContext(), r.Method, path)
+}
+
+func fromRequest(r *http.Request) (*gin.Context, bool) {
+	ctx, ok := r.Context().Value(ginContextKey{}).(*gin.Context)
+	return ctx, ok
+}
+
+// RequestID returns the ID of a protected request, also sent back in the
+// X-Request-ID header
+func RequestID(r *http.Request) string {
+	if ctx, ok := fromRequest(r); ok {
+		return ctx.GetString(middleware.RequestIDKey)
+	}
+	return ""
+}
+
+// PaymentRequirements returns the requirements the request's verified payment
+// matched, false when the request was not paid
+func PaymentRequirements(r *http.Request) (types.PaymentRequirements, bool) {
+	ctx, ok := fromRequest(r)
+	if !ok {
+		return types.PaymentRequirements{}, false
+	}
+	requirements, ok := ctx.Get("x402_payment_requirements")
+	if !ok {
+		return types.PaymentRequirements{}, false
+	}
+	return requirements.(types.PaymentRequirements), true
+}
+
+// SetSettleAmount charges amount (in atomic units) for an "upto" scheme
+// request instead of the route's price, which it must not exceed
+func SetSettleAmount(r *http.Request, amount string) {
+	if ctx, ok := fromRequest(r); ok {
+		ctx.Set(middleware.SettleAmountKey, amount)
+	}
+}
@@ -0,0 +1,180 @@
+package x402http
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/vorpalengineering/x402-go/resource/middleware"
+	"github.com/vorpalengineering/x402-go/types"
+)
+
+func TestMiddleware(t *testing.T) {
+	// Facilitator accepting every payment
+	facilitator := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		switch r.URL.Path {
+		case "/verify":
+			json.NewEncoder(w).Encode(types.VerifyResponse{IsValid: true})
+		case "/settle":
+			json.NewEncoder(w).Encode(types.SettleResponse{Success: true, Transaction: "0xabc", Network: "eip155:84532"})
+		}
+	}))
+	defer facilitator.Close()
+
+	requirements := types.PaymentRequirements{
+		Scheme:            "exact",
+		Network:           "eip155:84532",
+		Amount:            "1000",
+		Asset:             "0x036CbD53842c5426634e7929541eC2318f3dCF7e",
+		PayTo:             "0x3C44CdDdB6a900fa2b585dd299e03d12FA4293BC",
+		MaxTimeoutSeconds: 60,
+	}
+	payload, _ := json.Marshal(types.PaymentPayload{
+		X402Version: 2,
+		Accepted:    requirements,
+		Payload: map[string]any{
+			"signature": "0x" + strings.Repeat("11", 65),
+			"authorization": map[string]any{
+				"from":        "0x70997970C51812dc3A010C7d01b50e0d17dc79C8",
+				"to":          requirements.PayTo,
+				"value":       requirements.Amount,
+				"validAfter":  0,
+				"validBefore": time.Now().Add(time.Minute).Unix(),
+				"nonce":       "0x" + strings.Repeat("01", 32),
+			},
+		},
+	})
+	header := base64.StdEncoding.EncodeToString(payload)
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/paid", func(w http.ResponseWriter, r *http.Request) {
+		paid, ok := PaymentRequirements(r)
+		if !ok || paid.Amount != requirements.Amount || RequestID(r) == "" {
+			t.Errorf("Expected payment details in handler, got %+v", paid)
+		}
+		w.Write([]byte("paid content"))
+	})
+	mux.HandleFunc("/free", func(w http.ResponseWriter, r *http.Request) {
+		if _, ok := PaymentRequirements(r); ok {
+			t.Error("Expected no payment on free route")
+		}
+		w.WriteHeader(http.StatusNoContent)
+	})
+	handler := Middleware(mux, &middleware.MiddlewareConfig{
+		FacilitatorURL:      facilitator.URL,
+		DefaultRequirements: requirements,
+		ProtectedPaths:      []string{"/paid"},
+	})
+
+	tests := []struct {
+		name           string
+		path           string
+		header         string
+		expectedStatus int
+		expectedBody   string
+	}{
+		{"unpaid", "/paid", "", http.StatusPaymentRequired, `"accepts"`},
+		{"paid", "/paid", header, http.StatusOK, "paid content"},
+		{"unprotected", "/free", "", http.StatusNoContent, ""},
+		{"unprotected not found", "/missing", "", http.StatusNotFound, "404 page not found"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest("GET", tt.path, nil)
+			if tt.header != "" {
+				req.Header.Set("PAYMENT-SIGNATURE", tt.header)
+			}
+			recorder := httptest.NewRecorder()
+			handler.ServeHTTP(recorder, req)
+
+			if recorder.Code != tt.expectedStatus || !strings.Contains(recorder.Body.String(), tt.expectedBody) {
+				t.Errorf("Expected %d containing %q, got %d: %s", tt.expectedStatus, tt.expectedBody, recorder.Code, recorder.Body.String())
+			}
+			if tt.header != "" && recorder.Header().Get("PAYMENT-RESPONSE") == "" {
+				t.Error("Expected PAYMENT-RESPONSE header on paid response")
+			}
+		})
+	}
+}
+
+func TestChainMatchesRoutePattern(t *testing.T) {
+	requirements := types.PaymentRequirements{
+		Scheme:  "exact",
+		Network: "eip155:84532",
+		Amount:  "1000",
+		Asset:   "0x036CbD53842c5426634e7929541eC2318f3dCF7e",
+		PayTo:   "0x3C44CdDdB6a900fa2b585dd299e03d12FA4293BC",
+	}
+	userRequirements := requirements
+	userRequirements.Amount = "5000"
+	orderRequirements := requirements
+	orderRequirements.Amount = "7000"
+
+	m := middleware.NewX402Middleware(&middleware.MiddlewareConfig{
+		FacilitatorURL:      "http://127.0.0.1:1",
+		DefaultRequirements: requirements,
+		ProtectedPaths:      []string{"/users/{id}", "/api/orders/{orderID}", "/reports/{year}"},
+		RouteRequirements: map[string]types.PaymentRequirements{
+			"/users/{id}":           userRequirements,
+			"/api/orders/{orderID}": orderRequirements,
+		},
+	})
+	content := func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("content"))
+	}
+
+	// Router-level, mounted and route-level middleware
+	router := chi.NewRouter()
+	router.Use(Chain(m))
+	router.Get("/users/{id}", content)
+	router.Get("/users", content)
+	router.Mount("/api", func() http.Handler {
+		api := chi.NewRouter()
+		api.Get("/orders/{orderID}", content)
+		return api
+	}())
+	reports := chi.NewRouter()
+	reports.With(Chain(m)).Get("/reports/{year}", content)
+	reports.Get("/reports", content)
+
+	tests := []struct {
+		name   string
+		router http.Handler
+		path   string
+		status int
+		amount string
+	}{
+		{"use", router, "/users/42", http.StatusPaymentRequired, "5000"},
+		{"use unprotected", router, "/users", http.StatusOK, ""},
+		{"use mounted", router, "/api/orders/7", http.StatusPaymentRequired, "7000"},
+		{"with", reports, "/reports/2024", http.StatusPaymentRequired, "1000"},
+		{"with unprotected", reports, "/reports", http.StatusOK, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			recorder := httptest.NewRecorder()
+			tt.router.ServeHTTP(recorder, httptest.NewRequest("GET", tt.path, nil))
+			if recorder.Code != tt.status {
+				t.Fatalf("Expected status %d, got %d: %s", tt.status, recorder.Code, recorder.Body.String())
+			}
+			if tt.status != http.StatusPaymentRequired {
+				return
+			}
+			var response types.PaymentRequired
+			json.Unmarshal(recorder.Body.Bytes(), &response)
+			if len(response.Accepts) != 1 || response.Accepts[0].Amount != tt.amount {
+				t.Errorf("Expected the route's price %s, got %+v", tt.amount, response.Accepts)
+			}
+			if response.Resource == nil || response.Resource.URL != tt.path {
+				t.Errorf("Expected resource URL of the request, got %+v", response.Resource)
+			}
+		})
+	}
+}
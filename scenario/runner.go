@@ -0,0 +1,262 @@
+package scenario
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/gin-gonic/gin"
+	"github.com/vorpalengineering/x402-go/resource/client"
+	"github.com/vorpalengineering/x402-go/resource/middleware"
+	"github.com/vorpalengineering/x402-go/types"
+	"github.com/vorpalengineering/x402-go/utils"
+)
+
+// StepResult is the outcome of one step
+type StepResult struct {
+	Name     string
+	Status   int
+	Body     string
+	Failures []string
+}
+
+// Result is the outcome of a scenario
+type Result struct {
+	Name  string
+	Steps []StepResult
+}
+
+// Passed reports whether every step met its expectations
+func (r *Result) Passed() bool {
+	for _, step := range r.Steps {
+		if len(step.Failures) > 0 {
+			return false
+		}
+	}
+	return true
+}
+
+// Run executes a scenario against an in-process stack: a scripted
+// facilitator, a gin server with the x402 middleware, and a resource client.
+func Run(s *Scenario) (*Result, error) {
+	// Start scripted facilitator
+	facilitator := newScriptedFacilitator(s.Facilitator)
+	facilitatorServer := httptest.NewServer(facilitator)
+	defer facilitatorServer.Close()
+
+	// Start resource server
+	resourceServer := httptest.NewServer(newResourceRouter(s.Resource, facilitatorServer.URL))
+	defer resourceServer.Close()
+
+	// Create client
+	privateKey, err := crypto.HexToECDSA(strings.TrimPrefix(s.Client.PrivateKey, "0x"))
+	if err != nil {
+		return nil, fmt.Errorf("invalid client private key: %w", err)
+	}
+	rc := client.NewResourceClient(privateKey)
+
+	result := &Result{Name: s.Name}
+	for i, step := range s.Steps {
+		name := step.Name
+		if name == "" {
+			name = fmt.Sprintf("step %d", i+1)
+		}
+		method := step.Method
+		if method == "" {
+			method = http.MethodGet
+		}
+
+		stepResult := StepResult{Name: name}
+		resp, err := runStep(rc, s.Client, method, resourceServer.URL+step.Path, step.Pay)
+		if err != nil {
+			stepResult.Failures = append(stepResult.Failures, err.Error())
+			result.Steps = append(result.Steps, stepResult)
+			continue
+		}
+
+		body, _ := io.ReadAll(resp.Body)
+		resp.Body.Close()
+		stepResult.Status = resp.StatusCode
+		stepResult.Body = string(body)
+		stepResult.Failures = checkExpectation(step.Expect, resp, stepResult.Body, facilitator)
+
+		result.Steps = append(result.Steps, stepResult)
+	}
+
+	return result, nil
+}
+
+func runStep(rc *client.ResourceClient, spec ClientSpec, method, url string, pay bool) (*http.Response, error) {
+	resp, paymentRequired, err := rc.Check(method, url, "", nil)
+	if err != nil {
+		return nil, err
+	}
+	if !pay || paymentRequired == nil {
+		if paymentRequired != nil {
+			// Check consumed the body, serve it again for body expectations
+			data, _ := json.Marshal(paymentRequired)
+			resp.Body = io.NopCloser(strings.NewReader(string(data)))
+		}
+		return resp, nil
+	}
+	if len(paymentRequired.Accepts) == 0 {
+		return nil, fmt.Errorf("402 response has no accepted requirements")
+	}
+
+	if spec.FollowHeaderName {
+		rc.SetPaymentHeaderName(utils.GetPaymentHeaderName(paymentRequired))
+	}
+	return rc.Pay(method, url, "", nil, &paymentRequired.Accepts[0])
+}
+
+func checkExpectation(expect Expectation, resp *http.Response, body string, facilitator *scriptedFacilitator) []string {
+	var failures []string
+
+	if expect.Status != 0 && resp.StatusCode != expect.Status {
+		failures = append(failures, fmt.Sprintf("expected status %d, got %d", expect.Status, resp.StatusCode))
+	}
+	if expect.BodyContains != "" && !strings.Contains(body, expect.BodyContains) {
+		failures = append(failures, fmt.Sprintf("expected body to contain %q, got %q", expect.BodyContains, body))
+	}
+	if expect.PaymentResponse != nil {
+		present := resp.Header.Get("PAYMENT-RESPONSE") != ""
+		if present != *expect.PaymentResponse {
+			failures = append(failures, fmt.Sprintf("expected PAYMENT-RESPONSE header present=%v", *expect.PaymentResponse))
+		}
+	}
+
+	verifyCalls, settleCalls := facilitator.calls()
+	if expect.VerifyCalls != nil && verifyCalls != *expect.VerifyCalls {
+		failures = append(failures, fmt.Sprintf("expected %d verify calls, got %d", *expect.VerifyCalls, verifyCalls))
+	}
+	if expect.SettleCalls != nil && settleCalls != *expect.SettleCalls {
+		failures = append(failures, fmt.Sprintf("expected %d settle calls, got %d", *expect.SettleCalls, settleCalls))
+	}
+
+	return failures
+}
+
+func newResourceRouter(spec ResourceSpec, facilitatorURL string) *gin.Engine {
+	gin.SetMode(gin.ReleaseMode)
+	router := gin.New()
+
+	x402 := middleware.NewX402Middleware(&middleware.MiddlewareConfig{
+		FacilitatorURL:      facilitatorURL,
+		DefaultRequirements: spec.Requirements,
+		ProtectedPaths:      spec.ProtectedPaths,
+		RouteRequirements:   spec.RouteRequirements,
+		MaxBufferSize:       spec.MaxBufferSize,
+		PaymentHeaderName:   spec.PaymentHeaderName,
+	})
+	router.Use(x402.Handler())
+
+	// Serve configured handlers, anything else returns 200 "ok"
+	router.NoRoute(func(ctx *gin.Context) {
+		handler, ok := spec.Handlers[ctx.Request.URL.Path]
+		if !ok {
+			handler = HandlerSpec{Status: http.StatusOK, Body: "ok"}
+		}
+		if handler.Status == 0 {
+			handler.Status = http.StatusOK
+		}
+		ctx.String(handler.Status, handler.Body)
+	})
+
+	return router
+}
+
+// scriptedFacilitator answers /verify and /settle from the scenario script
+type scriptedFacilitator struct {
+	spec        FacilitatorSpec
+	mu          sync.Mutex
+	verifyCalls int
+	settleCalls int
+}
+
+func newScriptedFacilitator(spec FacilitatorSpec) *scriptedFacilitator {
+	return &scriptedFacilitator{spec: spec}
+}
+
+func (f *scriptedFacilitator) calls() (int, int) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.verifyCalls, f.settleCalls
+}
+
+// next returns the scripted response for call n (1-based), repeating the last
+func next(script []FacilitatorResponse, n int, fallback FacilitatorResponse) FacilitatorResponse {
+	if len(script) == 0 {
+		return fallback
+	}
+	if n > len(script) {
+		n = len(script)
+	}
+	return script[n-1]
+}
+
+func (f *scriptedFacilitator) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	f.mu.Lock()
+	var scripted FacilitatorResponse
+	switch r.URL.Path {
+	case "/verify":
+		f.verifyCalls++
+		scripted = next(f.spec.Verify, f.verifyCalls, FacilitatorResponse{Valid: true})
+	case "/settle":
+		f.settleCalls++
+		scripted = next(f.spec.Settle, f.settleCalls, FacilitatorResponse{Success: true})
+	default:
+		f.mu.Unlock()
+		http.NotFound(w, r)
+		return
+	}
+	f.mu.Unlock()
+
+	if scripted.DelayMs > 0 {
+		time.Sleep(time.Duration(scripted.DelayMs) * time.Millisecond)
+	}
+	if scripted.Status != 0 && scripted.Status != http.StatusOK {
+		w.WriteHeader(scripted.Status)
+		return
+	}
+
+	// Decode request for payer and network
+	var req types.SettleRequest
+	json.NewDecoder(r.Body).Decode(&req)
+	var payer string
+	if auth, err := utils.ExtractExactAuthorization(&req.PaymentPayload); err == nil {
+		payer = auth.From
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if r.URL.Path == "/verify" {
+		json.NewEncoder(w).Encode(types.VerifyResponse{
+			IsValid:        scripted.Valid,
+			InvalidReason:  scripted.Code,
+			InvalidMessage: scripted.Reason,
+			Payer:          payer,
+		})
+		return
+	}
+	resp := types.SettleResponse{
+		Success:      scripted.Success,
+		ErrorReason:  scripted.Code,
+		ErrorMessage: scripted.Reason,
+		Payer:        payer,
+		Network:      req.PaymentRequirements.Network,
+	}
+	if scripted.Success {
+		resp.Transaction = fmt.Sprintf("0x%064x", f.settleCallsSnapshot())
+	}
+	json.NewEncoder(w).Encode(resp)
+}
+
+func (f *scriptedFacilitator) settleCallsSnapshot() int {
+	_, settleCalls := f.calls()
+	return settleCalls
+}
@@ -0,0 +1,124 @@
+// Package scenario runs declarative end-to-end payment scenarios. A scenario
+// describes a resource server, a scripted facilitator, a paying client and a
+// list of requests with their expected outcomes.
+package scenario
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+
+	"github.com/vorpalengineering/x402-go/types"
+	"gopkg.in/yaml.v3"
+)
+
+// Scenario is an end-to-end flow run against an in-process stack
+type Scenario struct {
+	Name        string          `yaml:"name"`
+	Resource    ResourceSpec    `yaml:"resource"`
+	Facilitator FacilitatorSpec `yaml:"facilitator"`
+	Client      ClientSpec      `yaml:"client"`
+	Steps       []Step          `yaml:"steps"`
+}
+
+// ResourceSpec configures the resource server and its middleware
+type ResourceSpec struct {
+	ProtectedPaths    []string                             `yaml:"protected_paths"`
+	Requirements      types.PaymentRequirements            `yaml:"requirements"`
+	RouteRequirements map[string]types.PaymentRequirements `yaml:"route_requirements"`
+	MaxBufferSize     int                                  `yaml:"max_buffer_size"`
+	PaymentHeaderName string                               `yaml:"payment_header_name"`
+	// Handlers maps paths to the response they serve (default 200 "ok")
+	Handlers map[string]HandlerSpec `yaml:"handlers"`
+}
+
+type HandlerSpec struct {
+	Status int    `yaml:"status"`
+	Body   string `yaml:"body"`
+}
+
+// FacilitatorSpec scripts the facilitator. Responses are used in order and
+// the last one repeats, so [fail, ok] fails once and then succeeds.
+type FacilitatorSpec struct {
+	Verify []FacilitatorResponse `yaml:"verify"`
+	Settle []FacilitatorResponse `yaml:"settle"`
+}
+
+type FacilitatorResponse struct {
+	// HTTP status of the facilitator response (default 200)
+	Status  int  `yaml:"status"`
+	Valid   bool `yaml:"valid"`
+	Success bool `yaml:"success"`
+	// Code is the error code of a rejection, Reason its message
+	Code    string `yaml:"code"`
+	Reason  string `yaml:"reason"`
+	DelayMs int    `yaml:"delay_ms"`
+}
+
+// ClientSpec configures the paying client
+type ClientSpec struct {
+	PrivateKey string `yaml:"private_key"`
+	// FollowHeaderName sends the payment in the header advertised by the 402
+	FollowHeaderName bool `yaml:"follow_header_name"`
+}
+
+// Step is one request made during a scenario
+type Step struct {
+	Name   string `yaml:"name"`
+	Method string `yaml:"method"`
+	Path   string `yaml:"path"`
+	// Pay fetches requirements and pays; otherwise the request is sent unpaid
+	Pay    bool        `yaml:"pay"`
+	Expect Expectation `yaml:"expect"`
+}
+
+// Expectation is checked after a step. Unset fields are not checked.
+// Call counts are cumulative over the scenario.
+type Expectation struct {
+	Status          int    `yaml:"status"`
+	BodyContains    string `yaml:"body_contains"`
+	PaymentResponse *bool  `yaml:"payment_response"`
+	VerifyCalls     *int   `yaml:"verify_calls"`
+	SettleCalls     *int   `yaml:"settle_calls"`
+}
+
+// Load reads a scenario from a YAML file
+func Load(path string) (*Scenario, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read scenario: %w", err)
+	}
+
+	var s Scenario
+	if err := yaml.Unmarshal(data, &s); err != nil {
+		return nil, fmt.Errorf("failed to parse scenario: %w", err)
+	}
+	if s.Name == "" {
+		s.Name = filepath.Base(path)
+	}
+	if len(s.Steps) == 0 {
+		return nil, fmt.Errorf("scenario %s has no steps", s.Name)
+	}
+
+	return &s, nil
+}
+
+// LoadDir reads every *.yaml scenario in dir, sorted by file name
+func LoadDir(dir string) ([]*Scenario, error) {
+	paths, err := filepath.Glob(filepath.Join(dir, "*.yaml"))
+	if err != nil {
+		return nil, fmt.Errorf("failed to list scenarios: %w", err)
+	}
+	sort.Strings(paths)
+
+	scenarios := make([]*Scenario, 0, len(paths))
+	for _, path := range paths {
+		s, err := Load(path)
+		if err != nil {
+			return nil, err
+		}
+		scenarios = append(scenarios, s)
+	}
+	return scenarios, nil
+}
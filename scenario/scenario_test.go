@@ -0,0 +1,26 @@
+package scenario
+
+import (
+	"testing"
+)
+
+func TestScenarios(t *testing.T) {
+	scenarios, err := LoadDir("testdata")
+	if err != nil {
+		t.Fatalf("Failed to load scenarios: %v", err)
+	}
+
+	for _, s := range scenarios {
+		t.Run(s.Name, func(t *testing.T) {
+			result, err := Run(s)
+			if err != nil {
+				t.Fatalf("Failed to run scenario: %v", err)
+			}
+			for _, step := range result.Steps {
+				for _, failure := range step.Failures {
+					t.Errorf("%s: %s", step.Name, failure)
+				}
+			}
+		})
+	}
+}
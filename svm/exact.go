@@ -0,0 +1,92 @@
+package svm
+
+import (
+	"errors"
+	"fmt"
+)
+
+// Compute budget of exact scheme payments built by NewExactPaymentTransaction
+const (
+	DefaultComputeUnitLimit = 40_000
+	DefaultComputeUnitPrice = 1
+)
+
+// ExactPayment is an exact scheme payment: a TransferChecked of Amount from
+// the payer's associated token account to PayTo's, paid for by FeePayer
+type ExactPayment struct {
+	FeePayer        PublicKey
+	Payer           PublicKey
+	PayTo           PublicKey
+	Mint            PublicKey
+	TokenProgramID  PublicKey
+	Amount          uint64
+	Decimals        uint8
+	RecentBlockhash [32]byte
+	// Compute budget, defaults apply when zero
+	ComputeUnitLimit uint32
+	ComputeUnitPrice uint64
+}
+
+// NewExactPaymentTransaction builds the unsigned transaction of an exact
+// scheme payment
+func NewExactPaymentTransaction(payment ExactPayment) (*Transaction, error) {
+	source, err := FindAssociatedTokenAddress(payment.Payer, payment.Mint, payment.TokenProgramID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to derive source account: %w", err)
+	}
+	destination, err := FindAssociatedTokenAddress(payment.PayTo, payment.Mint, payment.TokenProgramID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to derive destination account: %w", err)
+	}
+
+	unitLimit := payment.ComputeUnitLimit
+	if unitLimit == 0 {
+		unitLimit = DefaultComputeUnitLimit
+	}
+	unitPrice := payment.ComputeUnitPrice
+	if unitPrice == 0 {
+		unitPrice = DefaultComputeUnitPrice
+	}
+
+	return NewTransaction(
+		payment.FeePayer,
+		payment.RecentBlockhash,
+		NewSetComputeUnitLimitInstruction(unitLimit),
+		NewSetComputeUnitPriceInstruction(unitPrice),
+		NewTransferCheckedInstruction(payment.TokenProgramID, source, payment.Mint, destination, payment.Payer, payment.Amount, payment.Decimals),
+	), nil
+}
+
+// DecodeExactPayment checks a transaction has the shape of an exact scheme
+// payment, optional compute budget instructions followed by a single
+// TransferChecked, and returns the transfer and compute budget
+func DecodeExactPayment(tx *Transaction) (*TransferChecked, *ComputeBudget, error) {
+	msg := &tx.Message
+	if len(msg.AddressTableLookups) > 0 {
+		return nil, nil, errors.New("address lookup tables are not supported")
+	}
+	if len(msg.Instructions) == 0 {
+		return nil, nil, errors.New("transaction has no instructions")
+	}
+
+	budget := &ComputeBudget{}
+	last := len(msg.Instructions) - 1
+	for i, instruction := range msg.Instructions[:last] {
+		programID, err := msg.Account(instruction.ProgramIDIndex)
+		if err != nil {
+			return nil, nil, err
+		}
+		if programID != ComputeBudgetProgramID {
+			return nil, nil, fmt.Errorf("unexpected instruction %d for program %s", i, programID)
+		}
+		if err := DecodeComputeBudget(budget, instruction); err != nil {
+			return nil, nil, fmt.Errorf("instruction %d: %w", i, err)
+		}
+	}
+
+	transfer, err := DecodeTransferChecked(msg, msg.Instructions[last])
+	if err != nil {
+		return nil, nil, fmt.Errorf("invalid transfer instruction: %w", err)
+	}
+	return transfer, budget, nil
+}
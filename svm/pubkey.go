@@ -0,0 +1,122 @@
+package svm
+
+import (
+	"bytes"
+	"crypto/ed25519"
+	"crypto/sha256"
+	"fmt"
+	"math/big"
+)
+
+// Program IDs used by exact scheme payments
+var (
+	SystemProgramID          = MustPublicKey("11111111111111111111111111111111")
+	TokenProgramID           = MustPublicKey("TokenkegQfeZyiNwAJbNbGKPFXCWuBvf9Ss623VQ5DA")
+	Token2022ProgramID       = MustPublicKey("TokenzQdBNbLqP5VEhdkAS6EPFLC1PHnBqCXEpPxuEb")
+	AssociatedTokenProgramID = MustPublicKey("ATokenGPvbdGVxr1b2hvZbsiqW5xWH25efTNsLJA8knL")
+	ComputeBudgetProgramID   = MustPublicKey("ComputeBudget111111111111111111111111111111")
+)
+
+// PublicKey is a Solana account address
+type PublicKey [32]byte
+
+// PublicKeyFromBase58 parses a base58 encoded address
+func PublicKeyFromBase58(s string) (PublicKey, error) {
+	decoded, err := DecodeBase58(s)
+	if err != nil {
+		return PublicKey{}, fmt.Errorf("invalid public key: %w", err)
+	}
+	if len(decoded) != 32 {
+		return PublicKey{}, fmt.Errorf("invalid public key length: expected 32 bytes, got %d", len(decoded))
+	}
+	return PublicKey(decoded), nil
+}
+
+// MustPublicKey parses a base58 encoded address and panics if it is invalid
+func MustPublicKey(s string) PublicKey {
+	key, err := PublicKeyFromBase58(s)
+	if err != nil {
+		panic(err)
+	}
+	return key
+}
+
+// PublicKeyOf returns the address of an ed25519 private key
+func PublicKeyOf(key ed25519.PrivateKey) PublicKey {
+	return PublicKey(key.Public().(ed25519.PublicKey))
+}
+
+func (p PublicKey) String() string {
+	return EncodeBase58(p[:])
+}
+
+// FindProgramAddress derives the program derived address (PDA) of seeds,
+// trying bump seeds from 255 down until the address is off the ed25519 curve
+func FindProgramAddress(seeds [][]byte, programID PublicKey) (PublicKey, uint8, error) {
+	for bump := 255; bump >= 0; bump-- {
+		hash := sha256.New()
+		for _, seed := range seeds {
+			hash.Write(seed)
+		}
+		hash.Write([]byte{byte(bump)})
+		hash.Write(programID[:])
+		hash.Write([]byte("ProgramDerivedAddress"))
+
+		var address PublicKey
+		copy(address[:], hash.Sum(nil))
+		if !isOnCurve(address) {
+			return address, uint8(bump), nil
+		}
+	}
+	return PublicKey{}, 0, fmt.Errorf("no viable bump seed found")
+}
+
+// FindAssociatedTokenAddress returns the associated token account of owner
+// for mint, under the token program that owns the mint
+func FindAssociatedTokenAddress(owner, mint, tokenProgramID PublicKey) (PublicKey, error) {
+	address, _, err := FindProgramAddress([][]byte{owner[:], tokenProgramID[:], mint[:]}, AssociatedTokenProgramID)
+	return address, err
+}
+
+// IsTokenProgram reports whether id is the SPL Token or Token-2022 program
+func IsTokenProgram(id PublicKey) bool {
+	return bytes.Equal(id[:], TokenProgramID[:]) || bytes.Equal(id[:], Token2022ProgramID[:])
+}
+
+var (
+	curveP = new(big.Int).Sub(new(big.Int).Lsh(big.NewInt(1), 255), big.NewInt(19))
+	// curveD is -121665/121666 mod p
+	curveD = func() *big.Int {
+		d := new(big.Int).ModInverse(big.NewInt(121666), curveP)
+		d.Mul(d, big.NewInt(-121665))
+		return d.Mod(d, curveP)
+	}()
+)
+
+// isOnCurve reports whether a compressed ed25519 point decompresses, which is
+// the case when x² = (y² - 1) / (d·y² + 1) has a square root mod p
+func isOnCurve(key PublicKey) bool {
+	// Decode y, little endian without the sign bit of x
+	encoded := make([]byte, 32)
+	for i := range key {
+		encoded[31-i] = key[i]
+	}
+	encoded[0] &= 0x7f
+	y := new(big.Int).SetBytes(encoded)
+	y.Mod(y, curveP)
+
+	ySquared := new(big.Int).Mul(y, y)
+	u := new(big.Int).Sub(ySquared, big.NewInt(1))
+	u.Mod(u, curveP)
+	v := new(big.Int).Mul(curveD, ySquared)
+	v.Add(v, big.NewInt(1))
+	v.Mod(v, curveP)
+
+	if v.Sign() == 0 {
+		return u.Sign() == 0
+	}
+	xSquared := new(big.Int).ModInverse(v, curveP)
+	xSquared.Mul(xSquared, u)
+	xSquared.Mod(xSquared, curveP)
+	return big.Jacobi(xSquared, curveP) >= 0
+}
@@ -0,0 +1,252 @@
+package svm
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sync/atomic"
+)
+
+// Commitment levels of a confirmed transaction
+const (
+	CommitmentProcessed = "processed"
+	CommitmentConfirmed = "confirmed"
+	CommitmentFinalized = "finalized"
+)
+
+// Client is a minimal Solana JSON-RPC client
+type Client struct {
+	url        string
+	httpClient *http.Client
+	id         atomic.Uint64
+}
+
+// NewClient returns a client for a Solana RPC endpoint
+func NewClient(url string) *Client {
+	return &Client{url: url, httpClient: http.DefaultClient}
+}
+
+// RPCError is an error returned by the node
+type RPCError struct {
+	Code    int             `json:"code"`
+	Message string          `json:"message"`
+	Data    json.RawMessage `json:"data,omitempty"`
+}
+
+func (e *RPCError) Error() string {
+	return fmt.Sprintf("rpc error %d: %s", e.Code, e.Message)
+}
+
+// call sends a JSON-RPC request and decodes its result into result
+func (c *Client) call(ctx context.Context, result any, method string, params ...any) error {
+	body, err := json.Marshal(map[string]any{
+		"jsonrpc": "2.0",
+		"id":      c.id.Add(1),
+		"method":  method,
+		"params":  params,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to marshal request: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, c.url, bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to send request: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("unexpected status code: %d", resp.StatusCode)
+	}
+
+	var rpcResp struct {
+		Result json.RawMessage `json:"result"`
+		Error  *RPCError       `json:"error"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&rpcResp); err != nil {
+		return fmt.Errorf("failed to decode response: %w", err)
+	}
+	if rpcResp.Error != nil {
+		return rpcResp.Error
+	}
+	if result == nil {
+		return nil
+	}
+	if err := json.Unmarshal(rpcResp.Result, result); err != nil {
+		return fmt.Errorf("failed to decode %s result: %w", method, err)
+	}
+	return nil
+}
+
+// GetHealth returns an error unless the node is healthy
+func (c *Client) GetHealth(ctx context.Context) error {
+	var health string
+	if err := c.call(ctx, &health, "getHealth"); err != nil {
+		return err
+	}
+	if health != "ok" {
+		return fmt.Errorf("node unhealthy: %s", health)
+	}
+	return nil
+}
+
+// GetLatestBlockhash returns a recent blockhash to build a transaction with
+func (c *Client) GetLatestBlockhash(ctx context.Context) ([32]byte, error) {
+	var result struct {
+		Value struct {
+			Blockhash string `json:"blockhash"`
+		} `json:"value"`
+	}
+	if err := c.call(ctx, &result, "getLatestBlockhash", map[string]any{"commitment": CommitmentFinalized}); err != nil {
+		return [32]byte{}, err
+	}
+	blockhash, err := PublicKeyFromBase58(result.Value.Blockhash)
+	if err != nil {
+		return [32]byte{}, fmt.Errorf("invalid blockhash: %w", err)
+	}
+	return [32]byte(blockhash), nil
+}
+
+// AccountInfo is the owner and data of an account
+type AccountInfo struct {
+	Owner PublicKey
+	Data  []byte
+}
+
+// GetAccountInfo returns an account, or nil if it doesn't exist
+func (c *Client) GetAccountInfo(ctx context.Context, account PublicKey) (*AccountInfo, error) {
+	var result struct {
+		Value *struct {
+			Owner string   `json:"owner"`
+			Data  []string `json:"data"`
+		} `json:"value"`
+	}
+	if err := c.call(ctx, &result, "getAccountInfo", account.String(), map[string]any{"encoding": "base64"}); err != nil {
+		return nil, err
+	}
+	if result.Value == nil {
+		return nil, nil
+	}
+	owner, err := PublicKeyFromBase58(result.Value.Owner)
+	if err != nil {
+		return nil, fmt.Errorf("invalid owner: %w", err)
+	}
+	info := &AccountInfo{Owner: owner}
+	if len(result.Value.Data) > 0 {
+		if info.Data, err = base64.StdEncoding.DecodeString(result.Value.Data[0]); err != nil {
+			return nil, fmt.Errorf("invalid account data: %w", err)
+		}
+	}
+	return info, nil
+}
+
+// GetMint returns the token program owning a mint and the mint's decimals
+func (c *Client) GetMint(ctx context.Context, mint PublicKey) (PublicKey, uint8, error) {
+	info, err := c.GetAccountInfo(ctx, mint)
+	if err != nil {
+		return PublicKey{}, 0, err
+	}
+	if info == nil {
+		return PublicKey{}, 0, fmt.Errorf("mint %s not found", mint)
+	}
+	if !IsTokenProgram(info.Owner) {
+		return PublicKey{}, 0, fmt.Errorf("account %s is not a token mint", mint)
+	}
+	// Decimals follow the mint authority option and supply
+	if len(info.Data) < 45 {
+		return PublicKey{}, 0, fmt.Errorf("invalid mint data length %d", len(info.Data))
+	}
+	return info.Owner, info.Data[44], nil
+}
+
+// SimulationError is returned when a simulated transaction fails
+type SimulationError struct {
+	Err  json.RawMessage
+	Logs []string
+}
+
+func (e *SimulationError) Error() string {
+	msg := fmt.Sprintf("simulation failed: %s", e.Err)
+	if len(e.Logs) > 0 {
+		msg += ": " + e.Logs[len(e.Logs)-1]
+	}
+	return msg
+}
+
+// SimulateTransaction runs a transaction against the current state without
+// checking its signatures, returning a SimulationError if it fails
+func (c *Client) SimulateTransaction(ctx context.Context, tx *Transaction) error {
+	var result struct {
+		Value struct {
+			Err  json.RawMessage `json:"err"`
+			Logs []string        `json:"logs"`
+		} `json:"value"`
+	}
+	err := c.call(ctx, &result, "simulateTransaction", base64.StdEncoding.EncodeToString(tx.Serialize()), map[string]any{
+		"encoding":   "base64",
+		"sigVerify":  false,
+		"commitment": CommitmentConfirmed,
+	})
+	if err != nil {
+		return err
+	}
+	if len(result.Value.Err) > 0 && string(result.Value.Err) != "null" {
+		return &SimulationError{Err: result.Value.Err, Logs: result.Value.Logs}
+	}
+	return nil
+}
+
+// SendTransaction submits a signed transaction and returns its signature
+func (c *Client) SendTransaction(ctx context.Context, tx *Transaction) (string, error) {
+	var signature string
+	err := c.call(ctx, &signature, "sendTransaction", base64.StdEncoding.EncodeToString(tx.Serialize()), map[string]any{
+		"encoding":            "base64",
+		"preflightCommitment": CommitmentConfirmed,
+	})
+	return signature, err
+}
+
+// SignatureStatus is the processing status of a transaction
+type SignatureStatus struct {
+	Slot               uint64          `json:"slot"`
+	Err                json.RawMessage `json:"err"`
+	ConfirmationStatus string          `json:"confirmationStatus"`
+}
+
+// Failed reports whether the transaction failed on chain
+func (s *SignatureStatus) Failed() bool {
+	return len(s.Err) > 0 && string(s.Err) != "null"
+}
+
+var commitmentLevels = map[string]int{
+	CommitmentProcessed: 1,
+	CommitmentConfirmed: 2,
+	CommitmentFinalized: 3,
+}
+
+// Reached reports whether the transaction reached the commitment level
+func (s *SignatureStatus) Reached(commitment string) bool {
+	return commitmentLevels[s.ConfirmationStatus] >= commitmentLevels[commitment] && commitmentLevels[s.ConfirmationStatus] > 0
+}
+
+// GetSignatureStatus returns the status of a transaction, or nil if the node
+// hasn't seen it
+func (c *Client) GetSignatureStatus(ctx context.Context, signature string) (*SignatureStatus, error) {
+	var result struct {
+		Value []*SignatureStatus `json:"value"`
+	}
+	if err := c.call(ctx, &result, "getSignatureStatuses", []string{signature}, map[string]any{"searchTransactionHistory": true}); err != nil {
+		return nil, err
+	}
+	if len(result.Value) == 0 {
+		return nil, nil
+	}
+	return result.Value[0], nil
+}
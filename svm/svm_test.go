@@ -0,0 +1,137 @@
+package svm
+
+import (
+	"bytes"
+	"crypto/ed25519"
+	"testing"
+)
+
+func testKey(seed byte) ed25519.PrivateKey {
+	return ed25519.NewKeyFromSeed(bytes.Repeat([]byte{seed}, ed25519.SeedSize))
+}
+
+func TestBase58(t *testing.T) {
+	if encoded := EncodeBase58([]byte("Hello World!")); encoded != "2NEpo7TZRRrLZSi2U" {
+		t.Errorf("Expected 2NEpo7TZRRrLZSi2U, got %s", encoded)
+	}
+
+	// Leading zero bytes round trip as '1'
+	decoded, err := DecodeBase58("11111111111111111111111111111111")
+	if err != nil {
+		t.Fatalf("Failed to decode: %v", err)
+	}
+	if !bytes.Equal(decoded, make([]byte, 32)) {
+		t.Errorf("Expected 32 zero bytes, got %x", decoded)
+	}
+	if SystemProgramID != (PublicKey{}) {
+		t.Errorf("Expected zero system program ID, got %s", SystemProgramID)
+	}
+
+	if _, err := DecodeBase58("0OIl"); err == nil {
+		t.Error("Expected error for characters outside the alphabet")
+	}
+}
+
+func TestFindAssociatedTokenAddress(t *testing.T) {
+	owner := PublicKeyOf(testKey(1))
+	if !isOnCurve(owner) {
+		t.Error("Expected ed25519 public key to be on curve")
+	}
+
+	ata, err := FindAssociatedTokenAddress(owner, TokenProgramID, TokenProgramID)
+	if err != nil {
+		t.Fatalf("Failed to derive address: %v", err)
+	}
+	if isOnCurve(ata) {
+		t.Error("Expected derived address to be off curve")
+	}
+
+	// Token-2022 accounts live at a different address
+	ata2022, _ := FindAssociatedTokenAddress(owner, TokenProgramID, Token2022ProgramID)
+	if ata == ata2022 {
+		t.Error("Expected different addresses for different token programs")
+	}
+}
+
+func TestExactPaymentTransaction(t *testing.T) {
+	feePayerKey, payerKey := testKey(1), testKey(2)
+	payment := ExactPayment{
+		FeePayer:       PublicKeyOf(feePayerKey),
+		Payer:          PublicKeyOf(payerKey),
+		PayTo:          PublicKeyOf(testKey(3)),
+		Mint:           PublicKeyOf(testKey(4)),
+		TokenProgramID: TokenProgramID,
+		Amount:         10000,
+		Decimals:       6,
+	}
+	tx, err := NewExactPaymentTransaction(payment)
+	if err != nil {
+		t.Fatalf("Failed to build transaction: %v", err)
+	}
+
+	// Fee payer first, then the transfer authority
+	if tx.Message.Header.NumRequiredSignatures != 2 {
+		t.Fatalf("Expected 2 signers, got %d", tx.Message.Header.NumRequiredSignatures)
+	}
+	if tx.Message.AccountKeys[0] != payment.FeePayer {
+		t.Errorf("Expected fee payer first, got %s", tx.Message.AccountKeys[0])
+	}
+	if err := tx.Sign(payerKey); err != nil {
+		t.Fatalf("Failed to sign: %v", err)
+	}
+	if err := tx.Sign(testKey(5)); err == nil {
+		t.Error("Expected error signing with a non-signer key")
+	}
+
+	// Round trip through wire format
+	parsed, err := ParseTransaction(tx.Serialize())
+	if err != nil {
+		t.Fatalf("Failed to parse: %v", err)
+	}
+	if !bytes.Equal(parsed.Serialize(), tx.Serialize()) {
+		t.Error("Expected parsed transaction to serialize identically")
+	}
+	if parsed.VerifySignature(0) {
+		t.Error("Expected missing fee payer signature to fail")
+	}
+	if !parsed.VerifySignature(1) {
+		t.Error("Expected payer signature to verify")
+	}
+
+	transfer, budget, err := DecodeExactPayment(parsed)
+	if err != nil {
+		t.Fatalf("Failed to decode payment: %v", err)
+	}
+	if transfer.Authority != payment.Payer || transfer.Amount != 10000 || transfer.Decimals != 6 {
+		t.Errorf("Unexpected transfer: %+v", transfer)
+	}
+	expected, _ := FindAssociatedTokenAddress(payment.PayTo, payment.Mint, TokenProgramID)
+	if transfer.Destination != expected {
+		t.Errorf("Expected destination %s, got %s", expected, transfer.Destination)
+	}
+	if budget.UnitLimit != DefaultComputeUnitLimit || budget.UnitPrice != DefaultComputeUnitPrice {
+		t.Errorf("Unexpected compute budget: %+v", budget)
+	}
+
+	// Signing as fee payer completes the transaction
+	if err := parsed.Sign(feePayerKey); err != nil {
+		t.Fatalf("Failed to sign as fee payer: %v", err)
+	}
+	if !parsed.VerifySignature(0) || !parsed.VerifySignature(1) {
+		t.Error("Expected both signatures to verify")
+	}
+	if parsed.ID() != EncodeBase58(parsed.Signatures[0][:]) {
+		t.Error("Expected ID to be the fee payer signature")
+	}
+}
+
+func TestDecodeExactPaymentRejectsExtraInstructions(t *testing.T) {
+	feePayer, payer := PublicKeyOf(testKey(1)), PublicKeyOf(testKey(2))
+	tx := NewTransaction(feePayer, [32]byte{},
+		NewTransferCheckedInstruction(TokenProgramID, PublicKeyOf(testKey(3)), PublicKeyOf(testKey(4)), PublicKeyOf(testKey(5)), payer, 1, 6),
+		NewSetComputeUnitPriceInstruction(1),
+	)
+	if _, _, err := DecodeExactPayment(tx); err == nil {
+		t.Error("Expected error when the transfer is not the last instruction")
+	}
+}
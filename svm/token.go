@@ -0,0 +1,126 @@
+package svm
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+)
+
+// Instruction discriminators
+const (
+	tokenTransferChecked       = 12
+	computeBudgetSetUnitLimit  = 2
+	computeBudgetSetUnitPrice  = 3
+	transferCheckedDataLength  = 10
+	computeUnitLimitDataLength = 5
+	computeUnitPriceDataLength = 9
+)
+
+// TransferChecked is a decoded SPL Token TransferChecked instruction
+type TransferChecked struct {
+	ProgramID   PublicKey
+	Source      PublicKey
+	Mint        PublicKey
+	Destination PublicKey
+	Authority   PublicKey
+	Amount      uint64
+	Decimals    uint8
+}
+
+// NewTransferCheckedInstruction moves amount of mint from source to
+// destination, signed by authority
+func NewTransferCheckedInstruction(tokenProgramID, source, mint, destination, authority PublicKey, amount uint64, decimals uint8) Instruction {
+	data := make([]byte, transferCheckedDataLength)
+	data[0] = tokenTransferChecked
+	binary.LittleEndian.PutUint64(data[1:], amount)
+	data[9] = decimals
+	return Instruction{
+		ProgramID: tokenProgramID,
+		Accounts: []AccountMeta{
+			{PublicKey: source, IsWritable: true},
+			{PublicKey: mint},
+			{PublicKey: destination, IsWritable: true},
+			{PublicKey: authority, IsSigner: true},
+		},
+		Data: data,
+	}
+}
+
+// DecodeTransferChecked decodes a compiled TransferChecked instruction
+func DecodeTransferChecked(msg *Message, instruction CompiledInstruction) (*TransferChecked, error) {
+	programID, err := msg.Account(instruction.ProgramIDIndex)
+	if err != nil {
+		return nil, err
+	}
+	if !IsTokenProgram(programID) {
+		return nil, fmt.Errorf("program %s is not a token program", programID)
+	}
+	if len(instruction.Data) != transferCheckedDataLength || instruction.Data[0] != tokenTransferChecked {
+		return nil, errors.New("instruction is not TransferChecked")
+	}
+	// Multisig authorities append their signers after the authority
+	if len(instruction.Accounts) != 4 {
+		return nil, fmt.Errorf("expected 4 accounts, got %d", len(instruction.Accounts))
+	}
+
+	transfer := &TransferChecked{
+		ProgramID: programID,
+		Amount:    binary.LittleEndian.Uint64(instruction.Data[1:9]),
+		Decimals:  instruction.Data[9],
+	}
+	for i, target := range []*PublicKey{&transfer.Source, &transfer.Mint, &transfer.Destination, &transfer.Authority} {
+		if *target, err = msg.Account(instruction.Accounts[i]); err != nil {
+			return nil, err
+		}
+	}
+	return transfer, nil
+}
+
+// NewSetComputeUnitLimitInstruction caps the compute units a transaction may use
+func NewSetComputeUnitLimitInstruction(units uint32) Instruction {
+	data := make([]byte, computeUnitLimitDataLength)
+	data[0] = computeBudgetSetUnitLimit
+	binary.LittleEndian.PutUint32(data[1:], units)
+	return Instruction{ProgramID: ComputeBudgetProgramID, Data: data}
+}
+
+// NewSetComputeUnitPriceInstruction sets the priority fee in micro-lamports
+// per compute unit
+func NewSetComputeUnitPriceInstruction(microLamports uint64) Instruction {
+	data := make([]byte, computeUnitPriceDataLength)
+	data[0] = computeBudgetSetUnitPrice
+	binary.LittleEndian.PutUint64(data[1:], microLamports)
+	return Instruction{ProgramID: ComputeBudgetProgramID, Data: data}
+}
+
+// ComputeBudget is the compute budget a message requests
+type ComputeBudget struct {
+	UnitLimit    uint32
+	HasUnitLimit bool
+	UnitPrice    uint64
+	HasUnitPrice bool
+}
+
+// DecodeComputeBudget applies a compiled compute budget instruction to budget
+func DecodeComputeBudget(budget *ComputeBudget, instruction CompiledInstruction) error {
+	if len(instruction.Accounts) != 0 || len(instruction.Data) == 0 {
+		return errors.New("invalid compute budget instruction")
+	}
+	switch {
+	case instruction.Data[0] == computeBudgetSetUnitLimit && len(instruction.Data) == computeUnitLimitDataLength:
+		if budget.HasUnitLimit {
+			return errors.New("duplicate compute unit limit")
+		}
+		budget.UnitLimit = binary.LittleEndian.Uint32(instruction.Data[1:])
+		budget.HasUnitLimit = true
+	case instruction.Data[0] == computeBudgetSetUnitPrice && len(instruction.Data) == computeUnitPriceDataLength:
+		if budget.HasUnitPrice {
+			return errors.New("duplicate compute unit price")
+		}
+		budget.UnitPrice = binary.LittleEndian.Uint64(instruction.Data[1:])
+		budget.HasUnitPrice = true
+	default:
+		return fmt.Errorf("unsupported compute budget instruction %d", instruction.Data[0])
+	}
+	return nil
+}
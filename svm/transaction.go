@@ -0,0 +1,408 @@
+package svm
+
+import (
+	"crypto/ed25519"
+	"errors"
+	"fmt"
+)
+
+// MessageHeader counts the signer and read-only accounts of a message
+type MessageHeader struct {
+	NumRequiredSignatures       uint8
+	NumReadonlySignedAccounts   uint8
+	NumReadonlyUnsignedAccounts uint8
+}
+
+// CompiledInstruction is an instruction referencing accounts by index into
+// the message's account keys
+type CompiledInstruction struct {
+	ProgramIDIndex uint8
+	Accounts       []uint8
+	Data           []byte
+}
+
+// AddressTableLookup loads accounts of a v0 message from an address lookup table
+type AddressTableLookup struct {
+	AccountKey      PublicKey
+	WritableIndexes []uint8
+	ReadonlyIndexes []uint8
+}
+
+// Message is a legacy or v0 transaction message
+type Message struct {
+	// Versioned is set for v0 messages
+	Versioned           bool
+	Header              MessageHeader
+	AccountKeys         []PublicKey
+	RecentBlockhash     [32]byte
+	Instructions        []CompiledInstruction
+	AddressTableLookups []AddressTableLookup
+}
+
+// Transaction is a message and the signatures of its signers, in account order
+type Transaction struct {
+	Signatures [][64]byte
+	Message    Message
+}
+
+// AccountMeta is an account passed to an instruction
+type AccountMeta struct {
+	PublicKey  PublicKey
+	IsSigner   bool
+	IsWritable bool
+}
+
+// Instruction is an uncompiled instruction
+type Instruction struct {
+	ProgramID PublicKey
+	Accounts  []AccountMeta
+	Data      []byte
+}
+
+// NewTransaction compiles instructions into an unsigned legacy transaction
+// paid for by feePayer
+func NewTransaction(feePayer PublicKey, recentBlockhash [32]byte, instructions ...Instruction) *Transaction {
+	// Merge account flags, the fee payer first
+	type account struct {
+		key      PublicKey
+		signer   bool
+		writable bool
+	}
+	accounts := []*account{{key: feePayer, signer: true, writable: true}}
+	byKey := map[PublicKey]*account{feePayer: accounts[0]}
+	add := func(key PublicKey, signer, writable bool) {
+		if existing, ok := byKey[key]; ok {
+			existing.signer = existing.signer || signer
+			existing.writable = existing.writable || writable
+			return
+		}
+		byKey[key] = &account{key: key, signer: signer, writable: writable}
+		accounts = append(accounts, byKey[key])
+	}
+	for _, instruction := range instructions {
+		for _, meta := range instruction.Accounts {
+			add(meta.PublicKey, meta.IsSigner, meta.IsWritable)
+		}
+		add(instruction.ProgramID, false, false)
+	}
+
+	// Order signers before non-signers and writable before read-only
+	msg := Message{RecentBlockhash: recentBlockhash}
+	index := make(map[PublicKey]uint8)
+	for _, group := range []struct{ signer, writable bool }{{true, true}, {true, false}, {false, true}, {false, false}} {
+		for _, acct := range accounts {
+			if acct.signer != group.signer || acct.writable != group.writable {
+				continue
+			}
+			index[acct.key] = uint8(len(msg.AccountKeys))
+			msg.AccountKeys = append(msg.AccountKeys, acct.key)
+			switch {
+			case group.signer && group.writable:
+				msg.Header.NumRequiredSignatures++
+			case group.signer:
+				msg.Header.NumRequiredSignatures++
+				msg.Header.NumReadonlySignedAccounts++
+			case !group.writable:
+				msg.Header.NumReadonlyUnsignedAccounts++
+			}
+		}
+	}
+
+	for _, instruction := range instructions {
+		compiled := CompiledInstruction{ProgramIDIndex: index[instruction.ProgramID], Data: instruction.Data}
+		for _, meta := range instruction.Accounts {
+			compiled.Accounts = append(compiled.Accounts, index[meta.PublicKey])
+		}
+		msg.Instructions = append(msg.Instructions, compiled)
+	}
+
+	return &Transaction{
+		Signatures: make([][64]byte, msg.Header.NumRequiredSignatures),
+		Message:    msg,
+	}
+}
+
+// ParseTransaction decodes a transaction in wire format
+func ParseTransaction(data []byte) (*Transaction, error) {
+	r := &reader{data: data}
+	tx := &Transaction{}
+
+	// Signatures
+	count, err := r.compactU16()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read signatures: %w", err)
+	}
+	for range count {
+		sig, err := r.bytes(64)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read signatures: %w", err)
+		}
+		tx.Signatures = append(tx.Signatures, [64]byte(sig))
+	}
+
+	// Message
+	msg, err := parseMessage(r)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read message: %w", err)
+	}
+	if r.remaining() > 0 {
+		return nil, fmt.Errorf("unexpected %d trailing bytes", r.remaining())
+	}
+	if len(tx.Signatures) != int(msg.Header.NumRequiredSignatures) {
+		return nil, fmt.Errorf("expected %d signatures, got %d", msg.Header.NumRequiredSignatures, len(tx.Signatures))
+	}
+	tx.Message = *msg
+	return tx, nil
+}
+
+func parseMessage(r *reader) (*Message, error) {
+	msg := &Message{}
+
+	// Versioned messages set the top bit of the first byte
+	prefix, err := r.byte()
+	if err != nil {
+		return nil, err
+	}
+	if prefix&0x80 != 0 {
+		if version := prefix & 0x7f; version != 0 {
+			return nil, fmt.Errorf("unsupported message version %d", version)
+		}
+		msg.Versioned = true
+		if prefix, err = r.byte(); err != nil {
+			return nil, err
+		}
+	}
+
+	// Header
+	msg.Header.NumRequiredSignatures = prefix
+	if msg.Header.NumReadonlySignedAccounts, err = r.byte(); err != nil {
+		return nil, err
+	}
+	if msg.Header.NumReadonlyUnsignedAccounts, err = r.byte(); err != nil {
+		return nil, err
+	}
+
+	// Account keys
+	count, err := r.compactU16()
+	if err != nil {
+		return nil, err
+	}
+	for range count {
+		key, err := r.bytes(32)
+		if err != nil {
+			return nil, err
+		}
+		msg.AccountKeys = append(msg.AccountKeys, PublicKey(key))
+	}
+	if int(msg.Header.NumRequiredSignatures) > len(msg.AccountKeys) {
+		return nil, errors.New("more signers than accounts")
+	}
+
+	// Recent blockhash
+	blockhash, err := r.bytes(32)
+	if err != nil {
+		return nil, err
+	}
+	msg.RecentBlockhash = [32]byte(blockhash)
+
+	// Instructions
+	if count, err = r.compactU16(); err != nil {
+		return nil, err
+	}
+	for range count {
+		var instruction CompiledInstruction
+		if instruction.ProgramIDIndex, err = r.byte(); err != nil {
+			return nil, err
+		}
+		if instruction.Accounts, err = r.compactBytes(); err != nil {
+			return nil, err
+		}
+		if instruction.Data, err = r.compactBytes(); err != nil {
+			return nil, err
+		}
+		msg.Instructions = append(msg.Instructions, instruction)
+	}
+
+	// Address table lookups
+	if msg.Versioned {
+		if count, err = r.compactU16(); err != nil {
+			return nil, err
+		}
+		for range count {
+			var lookup AddressTableLookup
+			key, err := r.bytes(32)
+			if err != nil {
+				return nil, err
+			}
+			lookup.AccountKey = PublicKey(key)
+			if lookup.WritableIndexes, err = r.compactBytes(); err != nil {
+				return nil, err
+			}
+			if lookup.ReadonlyIndexes, err = r.compactBytes(); err != nil {
+				return nil, err
+			}
+			msg.AddressTableLookups = append(msg.AddressTableLookups, lookup)
+		}
+	}
+
+	return msg, nil
+}
+
+// Serialize encodes the message, the bytes every signer signs
+func (msg *Message) Serialize() []byte {
+	var out []byte
+	if msg.Versioned {
+		out = append(out, 0x80)
+	}
+	out = append(out, msg.Header.NumRequiredSignatures, msg.Header.NumReadonlySignedAccounts, msg.Header.NumReadonlyUnsignedAccounts)
+	out = appendCompactU16(out, len(msg.AccountKeys))
+	for _, key := range msg.AccountKeys {
+		out = append(out, key[:]...)
+	}
+	out = append(out, msg.RecentBlockhash[:]...)
+	out = appendCompactU16(out, len(msg.Instructions))
+	for _, instruction := range msg.Instructions {
+		out = append(out, instruction.ProgramIDIndex)
+		out = appendCompactU16(out, len(instruction.Accounts))
+		out = append(out, instruction.Accounts...)
+		out = appendCompactU16(out, len(instruction.Data))
+		out = append(out, instruction.Data...)
+	}
+	if msg.Versioned {
+		out = appendCompactU16(out, len(msg.AddressTableLookups))
+		for _, lookup := range msg.AddressTableLookups {
+			out = append(out, lookup.AccountKey[:]...)
+			out = appendCompactU16(out, len(lookup.WritableIndexes))
+			out = append(out, lookup.WritableIndexes...)
+			out = appendCompactU16(out, len(lookup.ReadonlyIndexes))
+			out = append(out, lookup.ReadonlyIndexes...)
+		}
+	}
+	return out
+}
+
+// IsSigner reports whether the account at index must sign
+func (msg *Message) IsSigner(index int) bool {
+	return index < int(msg.Header.NumRequiredSignatures)
+}
+
+// IsWritable reports whether the account at index is writable
+func (msg *Message) IsWritable(index int) bool {
+	if index < int(msg.Header.NumRequiredSignatures) {
+		return index < int(msg.Header.NumRequiredSignatures-msg.Header.NumReadonlySignedAccounts)
+	}
+	return index < len(msg.AccountKeys)-int(msg.Header.NumReadonlyUnsignedAccounts)
+}
+
+// Account returns the account key at index, failing for indexes that point
+// into address lookup tables
+func (msg *Message) Account(index uint8) (PublicKey, error) {
+	if int(index) >= len(msg.AccountKeys) {
+		return PublicKey{}, fmt.Errorf("account index %d out of range", index)
+	}
+	return msg.AccountKeys[index], nil
+}
+
+// Serialize encodes the transaction in wire format
+func (tx *Transaction) Serialize() []byte {
+	out := appendCompactU16(nil, len(tx.Signatures))
+	for _, sig := range tx.Signatures {
+		out = append(out, sig[:]...)
+	}
+	return append(out, tx.Message.Serialize()...)
+}
+
+// Sign adds the signature of key, which must be one of the message's signers
+func (tx *Transaction) Sign(key ed25519.PrivateKey) error {
+	signer := PublicKeyOf(key)
+	for i := 0; i < int(tx.Message.Header.NumRequiredSignatures); i++ {
+		if tx.Message.AccountKeys[i] == signer {
+			copy(tx.Signatures[i][:], ed25519.Sign(key, tx.Message.Serialize()))
+			return nil
+		}
+	}
+	return fmt.Errorf("%s is not a signer of the transaction", signer)
+}
+
+// VerifySignature checks the signature of the signer at index
+func (tx *Transaction) VerifySignature(index int) bool {
+	if index >= len(tx.Signatures) || index >= int(tx.Message.Header.NumRequiredSignatures) {
+		return false
+	}
+	key := tx.Message.AccountKeys[index]
+	return ed25519.Verify(key[:], tx.Message.Serialize(), tx.Signatures[index][:])
+}
+
+// ID returns the transaction's first signature, which identifies it on chain
+func (tx *Transaction) ID() string {
+	if len(tx.Signatures) == 0 {
+		return ""
+	}
+	return EncodeBase58(tx.Signatures[0][:])
+}
+
+type reader struct {
+	data []byte
+	pos  int
+}
+
+func (r *reader) remaining() int {
+	return len(r.data) - r.pos
+}
+
+func (r *reader) byte() (byte, error) {
+	if r.remaining() < 1 {
+		return 0, errors.New("unexpected end of data")
+	}
+	b := r.data[r.pos]
+	r.pos++
+	return b, nil
+}
+
+func (r *reader) bytes(n int) ([]byte, error) {
+	if r.remaining() < n {
+		return nil, errors.New("unexpected end of data")
+	}
+	b := r.data[r.pos : r.pos+n]
+	r.pos += n
+	return b, nil
+}
+
+// compactU16 reads a shortvec length, 7 bits per byte
+func (r *reader) compactU16() (int, error) {
+	value := 0
+	for i := 0; i < 3; i++ {
+		b, err := r.byte()
+		if err != nil {
+			return 0, err
+		}
+		value |= int(b&0x7f) << (7 * i)
+		if b&0x80 == 0 {
+			return value, nil
+		}
+	}
+	return 0, errors.New("invalid compact-u16")
+}
+
+func (r *reader) compactBytes() ([]byte, error) {
+	n, err := r.compactU16()
+	if err != nil {
+		return nil, err
+	}
+	b, err := r.bytes(n)
+	if err != nil {
+		return nil, err
+	}
+	return append([]byte{}, b...), nil
+}
+
+func appendCompactU16(out []byte, value int) []byte {
+	for {
+		b := byte(value & 0x7f)
+		value >>= 7
+		if value == 0 {
+			return append(out, b)
+		}
+		out = append(out, b|0x80)
+	}
+}
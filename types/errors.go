@@ -0,0 +1,112 @@
+package types
+
+// Error codes of VerifyResponse.InvalidReason, SettleResponse.ErrorReason,
+// VerifyFailure.Code and PaymentRequired.ErrorCode. The human readable detail
+// of a failure is in the message field next to its code.
+const (
+	// ErrCodeInvalidPayload is a payload that is missing fields or can't be decoded
+	ErrCodeInvalidPayload = "invalid_payload"
+	// ErrCodeUnsupportedScheme is a scheme, or scheme-network pair, not accepted
+	ErrCodeUnsupportedScheme = "unsupported_scheme"
+	// ErrCodeUnsupportedNetwork is a network not accepted
+	ErrCodeUnsupportedNetwork = "unsupported_network"
+	// ErrCodeUnsupportedAsset is a token not accepted
+	ErrCodeUnsupportedAsset = "unsupported_asset"
+	// ErrCodeInvalidSignature is a missing, malformed or mismatched signature
+	ErrCodeInvalidSignature = "invalid_signature"
+	// ErrCodeInsufficientFunds is a payer without the balance or allowance to pay
+	ErrCodeInsufficientFunds = "insufficient_funds"
+	// ErrCodeInvalidAmount is an amount below the requirements or outside the accepted limits
+	ErrCodeInvalidAmount = "invalid_amount"
+	// ErrCodeExpired is an authorization past its validBefore or deadline
+	ErrCodeExpired = "expired"
+	// ErrCodeNotYetValid is an authorization before its validAfter
+	ErrCodeNotYetValid = "not_yet_valid"
+	// ErrCodeRecipientMismatch is a payment to another address than payTo
+	ErrCodeRecipientMismatch = "recipient_mismatch"
+	// ErrCodeSpenderMismatch is a permit naming another spender than the facilitator
+	ErrCodeSpenderMismatch = "spender_mismatch"
+	// ErrCodeSimulationFailed is a settlement call that reverts when simulated
+	ErrCodeSimulationFailed = "simulation_failed"
+	// ErrCodeNonceUsed is an authorization nonce the token already used or canceled
+	ErrCodeNonceUsed = "nonce_used"
+	// ErrCodeNetworkError is a network whose RPC endpoint could not be reached
+	ErrCodeNetworkError = "network_error"
+	// ErrCodeGasEstimationFailed is a gas estimate that failed without a fallback limit
+	ErrCodeGasEstimationFailed = "gas_estimation_failed"
+	// ErrCodeTransactionReverted is a settlement that reverts or reverted on-chain
+	ErrCodeTransactionReverted = "transaction_reverted"
+	// ErrCodeNotConfirmed is a settlement not confirmed within the timeout, it
+	// may still be mined
+	ErrCodeNotConfirmed = "not_confirmed"
+	// ErrCodeTransactionReorged is a settlement dropped by a reorganization
+	// that could not be sent again
+	ErrCodeTransactionReorged = "transaction_reorged"
+	// ErrCodeSettlementQueueFull is a settlement rejected because too many
+	// others were waiting
+	ErrCodeSettlementQueueFull = "settlement_queue_full"
+	// ErrCodeSettlementInProgress is a duplicate settlement given up on while
+	// the first was still running
+	ErrCodeSettlementInProgress = "settlement_in_progress"
+	// ErrCodeSettlementRace is an authorization another pending transaction
+	// is already using, so a settlement would revert second
+	ErrCodeSettlementRace = "settlement_race"
+	// ErrCodeSettlementFailed is any other settlement failure
+	ErrCodeSettlementFailed = "settlement_failed"
+)
+
+// ErrorCodes lists every error code, in the order they are declared
+var ErrorCodes = []string{
+	ErrCodeInvalidPayload,
+	ErrCodeUnsupportedScheme,
+	ErrCodeUnsupportedNetwork,
+	ErrCodeUnsupportedAsset,
+	ErrCodeInvalidSignature,
+	ErrCodeInsufficientFunds,
+	ErrCodeInvalidAmount,
+	ErrCodeExpired,
+	ErrCodeNotYetValid,
+	ErrCodeRecipientMismatch,
+	ErrCodeSpenderMismatch,
+	ErrCodeSimulationFailed,
+	ErrCodeNonceUsed,
+	ErrCodeNetworkError,
+	ErrCodeGasEstimationFailed,
+	ErrCodeTransactionReverted,
+	ErrCodeNotConfirmed,
+	ErrCodeTransactionReorged,
+	ErrCodeSettlementQueueFull,
+	ErrCodeSettlementInProgress,
+	ErrCodeSettlementRace,
+	ErrCodeSettlementFailed,
+}
+
+var errorCodeDescriptions = map[string]string{
+	ErrCodeInvalidPayload:       "the payment payload is missing fields or can't be decoded",
+	ErrCodeUnsupportedScheme:    "the scheme is not accepted",
+	ErrCodeUnsupportedNetwork:   "the network is not accepted",
+	ErrCodeUnsupportedAsset:     "the token is not accepted",
+	ErrCodeInvalidSignature:     "the signature is missing, malformed or not the payer's",
+	ErrCodeInsufficientFunds:    "the payer's balance or allowance is too low",
+	ErrCodeInvalidAmount:        "the amount doesn't match the requirements",
+	ErrCodeExpired:              "the authorization has expired",
+	ErrCodeNotYetValid:          "the authorization is not valid yet",
+	ErrCodeRecipientMismatch:    "the payment goes to another address than payTo",
+	ErrCodeSpenderMismatch:      "the permit names another spender than the facilitator",
+	ErrCodeSimulationFailed:     "the settlement call reverts when simulated",
+	ErrCodeNonceUsed:            "the authorization nonce was already used or canceled",
+	ErrCodeNetworkError:         "the network's RPC endpoint could not be reached",
+	ErrCodeGasEstimationFailed:  "gas estimation failed and no fallback limit is configured",
+	ErrCodeTransactionReverted:  "the settlement transaction reverted",
+	ErrCodeNotConfirmed:         "the settlement transaction was not confirmed in time and may still be mined",
+	ErrCodeTransactionReorged:   "the settlement transaction was dropped by a chain reorganization",
+	ErrCodeSettlementQueueFull:  "too many settlements are waiting on the network",
+	ErrCodeSettlementInProgress: "the same payment is still being settled",
+	ErrCodeSettlementRace:       "a pending transaction already uses the authorization",
+	ErrCodeSettlementFailed:     "the settlement failed",
+}
+
+// ErrorCodeDescription explains an error code, or returns "" for unknown codes
+func ErrorCodeDescription(code string) string {
+	return errorCodeDescriptions[code]
+}
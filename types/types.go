@@ -14,45 +14,297 @@ type VerifyRequest struct {
 }
 
 type VerifyResponse struct {
-	IsValid       bool   `json:"isValid"`
-	InvalidReason string `json:"invalidReason,omitempty"`
-	Payer         string `json:"payer,omitempty"`
+	// X402Version is the protocol version of the request, when answered by a
+	// facilitator that accepts several
+	X402Version int  `json:"x402Version,omitempty"`
+	IsValid     bool `json:"isValid"`
+	// InvalidReason is one of the ErrCode constants, InvalidMessage its detail
+	InvalidReason  string          `json:"invalidReason,omitempty"`
+	InvalidMessage string          `json:"invalidMessage,omitempty"`
+	Payer          string          `json:"payer,omitempty"`
+	Failures       []VerifyFailure `json:"failures,omitempty"`
+}
+
+// VerifyFailure is a single failed check, returned in full report mode
+type VerifyFailure struct {
+	Check  string `json:"check"`
+	Code   string `json:"code"`
+	Reason string `json:"reason"`
+}
+
+// SimulateResponse is the result of running only the settlement simulation
+// of a payment
+type SimulateResponse struct {
+	Success bool   `json:"success"`
+	Payer   string `json:"payer,omitempty"`
+	// Overload is the transferWithAuthorization overload simulated, "vrs" or "bytes"
+	Overload string `json:"overload,omitempty"`
+	// RevertReason is the decoded reason of a reverted simulation
+	RevertReason string `json:"revertReason,omitempty"`
+	// RevertError is the name of a recognized custom error, e.g. "ERC20InsufficientBalance"
+	RevertError string `json:"revertError,omitempty"`
+	// RevertData is the raw revert data, hex encoded
+	RevertData string `json:"revertData,omitempty"`
+	// Error is why the simulation could not be run
+	Error string `json:"error,omitempty"`
 }
 
 type SettleRequest struct {
 	PaymentPayload      PaymentPayload      `json:"paymentPayload"`
 	PaymentRequirements PaymentRequirements `json:"paymentRequirements"`
+	// Amount is the metered amount to charge for the upto scheme, at most
+	// the required amount
+	Amount string `json:"amount,omitempty"`
 }
 
 type SettleResponse struct {
-	Success     bool   `json:"success"`
-	ErrorReason string `json:"errorReason,omitempty"`
-	Payer       string `json:"payer,omitempty"`
-	Transaction string `json:"transaction"`
-	Network     string `json:"network"`
+	// X402Version is the protocol version of the request, when answered by a
+	// facilitator that accepts several
+	X402Version int  `json:"x402Version,omitempty"`
+	Success     bool `json:"success"`
+	// ErrorReason is one of the ErrCode constants, ErrorMessage its detail
+	ErrorReason  string `json:"errorReason,omitempty"`
+	ErrorMessage string `json:"errorMessage,omitempty"`
+	Payer        string `json:"payer,omitempty"`
+	Transaction  string `json:"transaction"`
+	Network      string `json:"network"`
+	// Set when the facilitator waits for confirmations
+	Status      string `json:"status,omitempty"`
+	BlockNumber uint64 `json:"blockNumber,omitempty"`
+	GasUsed     uint64 `json:"gasUsed,omitempty"`
+	// Attempts made to broadcast the transaction when retries are enabled
+	Attempts int `json:"attempts,omitempty"`
+	// Amount charged by an upto scheme settlement
+	Amount string `json:"amount,omitempty"`
+}
+
+// Settlement transaction statuses
+const (
+	SettleStatusConfirmed = "confirmed"
+	SettleStatusReverted  = "reverted"
+	// SettleStatusReorged marks a settlement whose transaction was dropped by
+	// a chain reorganization after confirmation
+	SettleStatusReorged = "reorged"
+	// SettleStatusDeferred marks a payment accepted for a later batch, its
+	// transaction is not sent yet
+	SettleStatusDeferred = "deferred"
+)
+
+// Settlement job statuses
+const (
+	SettleJobPending   = "pending"
+	SettleJobConfirmed = "confirmed"
+	SettleJobFailed    = "failed"
+)
+
+// BatchSettleRequest settles several payments at once
+type BatchSettleRequest struct {
+	Items []SettleRequest `json:"items"`
+}
+
+// BatchSettleResponse holds the result of each item, in request order
+type BatchSettleResponse struct {
+	Results []SettleResponse `json:"results"`
+}
+
+// SettleJob is an asynchronous settlement. Response is set once the job
+// is confirmed or failed.
+type SettleJob struct {
+	ID        string          `json:"id"`
+	Status    string          `json:"status"`
+	Response  *SettleResponse `json:"response,omitempty"`
+	CreatedAt int64           `json:"createdAt"`
+	UpdatedAt int64           `json:"updatedAt"`
+}
+
+// Payment stream statuses
+const (
+	StreamOpen   = "open"
+	StreamClosed = "closed"
+)
+
+// StreamOpenRequest opens a payment stream. The requirements' amount caps the
+// stream's running total.
+type StreamOpenRequest struct {
+	PaymentRequirements PaymentRequirements `json:"paymentRequirements"`
 }
 
+// StreamAdvanceRequest adds an increment to a payment stream, signed as an
+// exact scheme authorization of the increment's value
+type StreamAdvanceRequest struct {
+	PaymentPayload PaymentPayload `json:"paymentPayload"`
+}
+
+// PaymentStream is the state of a payment stream. Total is the sum of the
+// accepted increments and Settled the part of it transferred on-chain.
+type PaymentStream struct {
+	ID                  string              `json:"id"`
+	Status              string              `json:"status"`
+	PaymentRequirements PaymentRequirements `json:"paymentRequirements"`
+	Payer               string              `json:"payer,omitempty"`
+	Total               string              `json:"total"`
+	Settled             string              `json:"settled"`
+	Transactions        []string            `json:"transactions"`
+	ErrorReason         string              `json:"errorReason,omitempty"`
+	ErrorMessage        string              `json:"errorMessage,omitempty"`
+	CreatedAt           int64               `json:"createdAt"`
+	UpdatedAt           int64               `json:"updatedAt"`
+}
+
+// SupportedKind is a scheme-network pair a facilitator accepts. The optional
+// terms let clients compare facilitators: Assets lists the accepted tokens
+// (any when empty), MinAmount and MaxAmount bound the required amount in
+// atomic units, and ExpectedConfirmationSeconds is how long settlement
+// typically takes to be reported.
 type SupportedKind struct {
-	X402Version int            `json:"x402Version"`
-	Scheme      string         `json:"scheme" yaml:"scheme"`
-	Network     string         `json:"network" yaml:"network"`
-	Extra       map[string]any `json:"extra,omitempty"`
+	X402Version                 int             `json:"x402Version"`
+	Scheme                      string          `json:"scheme" yaml:"scheme"`
+	Network                     string          `json:"network" yaml:"network"`
+	Assets                      []string        `json:"assets,omitempty" yaml:"assets"`
+	MinAmount                   string          `json:"minAmount,omitempty" yaml:"min_amount"`
+	MaxAmount                   string          `json:"maxAmount,omitempty" yaml:"max_amount"`
+	Fee                         *FacilitatorFee `json:"fee,omitempty" yaml:"fee"`
+	ExpectedConfirmationSeconds int             `json:"expectedConfirmationSeconds,omitempty" yaml:"expected_confirmation_seconds"`
+	Extra                       map[string]any  `json:"extra,omitempty"`
+}
+
+// FacilitatorFee is the fee a facilitator charges on top of a payment: a flat
+// amount in atomic units of the asset plus a share in basis points
+type FacilitatorFee struct {
+	Flat        string `json:"flat,omitempty" yaml:"flat"`
+	BasisPoints int    `json:"basisPoints,omitempty" yaml:"basis_points"`
 }
 
 type SupportedResponse struct {
 	Kinds      []SupportedKind     `json:"kinds"`
 	Extensions []string            `json:"extensions"`
 	Signers    map[string][]string `json:"signers"`
+	Version    *VersionInfo        `json:"version,omitempty"`
+}
+
+// VersionInfo identifies the build of an x402-go binary
+type VersionInfo struct {
+	Version     string `json:"version"`
+	Commit      string `json:"commit,omitempty"`
+	BuildDate   string `json:"buildDate,omitempty"`
+	GoVersion   string `json:"goVersion"`
+	X402Version int    `json:"x402Version"`
+}
+
+// HealthResponse reports the facilitator's status and each dependency checked
+type HealthResponse struct {
+	Status string                 `json:"status"`
+	Checks map[string]HealthCheck `json:"checks,omitempty"`
+}
+
+// HealthCheck is the status of one dependency
+type HealthCheck struct {
+	Status    string `json:"status"`
+	Error     string `json:"error,omitempty"`
+	LatencyMs int64  `json:"latencyMs"`
+}
+
+// Health statuses
+const (
+	HealthStatusOK          = "ok"
+	HealthStatusUnavailable = "unavailable"
+)
+
+// Statement types
+
+type StatementChallengeResponse struct {
+	Challenge string `json:"challenge"`
+	ExpiresAt int64  `json:"expiresAt"`
+}
+
+type StatementRequest struct {
+	Payer     string `json:"payer"`
+	Period    string `json:"period"`
+	Challenge string `json:"challenge"`
+	Signature string `json:"signature"`
+}
+
+type Statement struct {
+	Payer    string            `json:"payer"`
+	Period   string            `json:"period"`
+	From     int64             `json:"from"`
+	To       int64             `json:"to"`
+	Payments []StatementLine   `json:"payments"`
+	Refunds  []StatementRefund `json:"refunds"`
+	Totals   []StatementTotal  `json:"totals"`
+}
+
+type StatementLine struct {
+	Timestamp   int64  `json:"timestamp"`
+	Network     string `json:"network"`
+	Asset       string `json:"asset"`
+	PayTo       string `json:"payTo"`
+	Amount      string `json:"amount"`
+	Transaction string `json:"transaction"`
+	Attempts    int    `json:"attempts,omitempty"`
+}
+
+// StatementRefund is a refund of a payment, linked to its settlement
+type StatementRefund struct {
+	Timestamp   int64  `json:"timestamp"`
+	Network     string `json:"network"`
+	Asset       string `json:"asset"`
+	Amount      string `json:"amount"`
+	Transaction string `json:"transaction"`
+	Settlement  string `json:"settlement"`
+	Reason      string `json:"reason,omitempty"`
+}
+
+type StatementTotal struct {
+	Network string `json:"network"`
+	Asset   string `json:"asset"`
+	Amount  string `json:"amount"`
+	Count   int    `json:"count"`
+	// Refunded is the amount refunded in the period
+	Refunded string `json:"refunded,omitempty"`
+}
+
+// Refund types
+
+// RefundRequest sends back all or part of a settled payment
+type RefundRequest struct {
+	Network string `json:"network"`
+	// Transaction is the settlement transaction of the payment
+	Transaction string `json:"transaction"`
+	// Payer picks the payment when the transaction settled several
+	Payer  string `json:"payer,omitempty"`
+	Amount string `json:"amount"`
+	Reason string `json:"reason,omitempty"`
+}
+
+type RefundResponse struct {
+	Success      bool   `json:"success"`
+	ErrorReason  string `json:"errorReason,omitempty"`
+	ErrorMessage string `json:"errorMessage,omitempty"`
+	// Transaction is the refund transaction
+	Transaction string `json:"transaction"`
+	Settlement  string `json:"settlement"`
+	Network     string `json:"network"`
+	Asset       string `json:"asset"`
+	Payer       string `json:"payer"`
+	// From is the treasury or payTo address the refund is sent from
+	From   string `json:"from"`
+	Amount string `json:"amount"`
+	// Refunded is the amount of the payment refunded so far
+	Refunded string `json:"refunded"`
+	Attempts int    `json:"attempts,omitempty"`
 }
 
 // Payment types
 
 type PaymentRequired struct {
-	X402Version int                   `json:"x402Version"`
-	Error       string                `json:"error,omitempty"`
-	Resource    *ResourceInfo         `json:"resource,omitempty"`
-	Accepts     []PaymentRequirements `json:"accepts"`
-	Extensions  map[string]Extension  `json:"extensions,omitempty"`
+	X402Version int    `json:"x402Version"`
+	Error       string `json:"error,omitempty"`
+	// ErrorCode is the ErrCode constant of a rejected payment
+	ErrorCode  string                `json:"errorCode,omitempty"`
+	Resource   *ResourceInfo         `json:"resource,omitempty"`
+	Accepts    []PaymentRequirements `json:"accepts"`
+	Extensions map[string]Extension  `json:"extensions,omitempty"`
 }
 
 type PaymentRequirements struct {
@@ -98,6 +350,29 @@ type ExactEVMSchemeAuthorization struct {
 	Nonce       string `json:"nonce"`
 }
 
+// UptoEVMSchemePayload authorizes the facilitator to transfer up to a maximum
+// amount with a Permit2 signature transfer
+type UptoEVMSchemePayload struct {
+	Signature     string                     `json:"signature"`
+	Authorization UptoEVMSchemeAuthorization `json:"authorization"`
+}
+
+// UptoEVMSchemeAuthorization is a Permit2 PermitTransferFrom of up to Value
+// of the asset, spendable by Spender until Deadline
+type UptoEVMSchemeAuthorization struct {
+	From     string `json:"from"`
+	Spender  string `json:"spender"`
+	Value    string `json:"value"`
+	Nonce    string `json:"nonce"`
+	Deadline int64  `json:"deadline"`
+}
+
+// ExactSVMSchemePayload is a base64 encoded, partially signed Solana
+// transaction the facilitator completes as fee payer
+type ExactSVMSchemePayload struct {
+	Transaction string `json:"transaction"`
+}
+
 type EIP3009Authorization struct {
 	From        common.Address
 	To          common.Address
@@ -112,8 +387,50 @@ type EIP3009Authorization struct {
 
 // DiscoveryResponse represents the /.well-known/x402 discovery document
 type DiscoveryResponse struct {
-	Version         int      `json:"version"`
-	Resources       []string `json:"resources"`
-	OwnershipProofs []string `json:"ownershipProofs,omitempty"`
-	Instructions    string   `json:"instructions,omitempty"`
+	Version         int                 `json:"version"`
+	Resources       []string            `json:"resources"`
+	Endpoints       []DiscoveryEndpoint `json:"endpoints,omitempty"`
+	OwnershipProofs []string            `json:"ownershipProofs,omitempty"`
+	Instructions    string              `json:"instructions,omitempty"`
+	Contact         *DiscoveryContact   `json:"contact,omitempty"`
+}
+
+// DiscoveryEndpoint describes a discoverable resource and how to pay for it
+type DiscoveryEndpoint struct {
+	URL         string                `json:"url"`
+	Description string                `json:"description,omitempty"`
+	MimeType    string                `json:"mimeType,omitempty"`
+	Accepts     []PaymentRequirements `json:"accepts,omitempty"`
+}
+
+// DiscoveryContact tells users who operates the resources
+type DiscoveryContact struct {
+	Name  string `json:"name,omitempty"`
+	Email string `json:"email,omitempty"`
+	URL   string `json:"url,omitempty"`
+}
+
+// DiscoveryResourcesResponse is a page of the Bazaar discovery list served at
+// GET /discovery/resources
+type DiscoveryResourcesResponse struct {
+	X402Version int                 `json:"x402Version"`
+	Items       []DiscoveryResource `json:"items"`
+	Pagination  DiscoveryPagination `json:"pagination"`
+}
+
+// DiscoveryResource is a listed resource and how to pay for it
+type DiscoveryResource struct {
+	Resource    string                `json:"resource"`
+	Type        string                `json:"type"`
+	X402Version int                   `json:"x402Version"`
+	Accepts     []PaymentRequirements `json:"accepts"`
+	LastUpdated int64                 `json:"lastUpdated"`
+	Metadata    map[string]any        `json:"metadata,omitempty"`
+}
+
+// DiscoveryPagination locates a page within the discovery list
+type DiscoveryPagination struct {
+	Limit  int `json:"limit"`
+	Offset int `json:"offset"`
+	Total  int `json:"total"`
 }
@@ -0,0 +1,44 @@
+package types
+
+// x402 protocol versions
+const (
+	X402VersionV1 = 1
+	X402VersionV2 = 2
+)
+
+// x402 v1 types. Facilitators still accept them from v1 clients and convert
+// them to the v2 types.
+
+// PaymentRequirementsV1 names its network (e.g. "base-sepolia") instead of a
+// CAIP-2 id, carries the maximum amount in MaxAmountRequired and describes
+// the resource inline
+type PaymentRequirementsV1 struct {
+	Scheme            string         `json:"scheme"`
+	Network           string         `json:"network"`
+	MaxAmountRequired string         `json:"maxAmountRequired"`
+	Resource          string         `json:"resource"`
+	Description       string         `json:"description"`
+	MimeType          string         `json:"mimeType"`
+	OutputSchema      map[string]any `json:"outputSchema,omitempty"`
+	PayTo             string         `json:"payTo"`
+	MaxTimeoutSeconds int            `json:"maxTimeoutSeconds"`
+	Asset             string         `json:"asset"`
+	Extra             map[string]any `json:"extra,omitempty"`
+}
+
+// PaymentPayloadV1 names the scheme and network it pays with instead of the
+// accepted requirements
+type PaymentPayloadV1 struct {
+	X402Version int            `json:"x402Version"`
+	Scheme      string         `json:"scheme"`
+	Network     string         `json:"network"`
+	Payload     map[string]any `json:"payload"`
+}
+
+// PaymentRequiredV1 is the 402 response body of v1. Resources are described
+// in each of the accepted requirements.
+type PaymentRequiredV1 struct {
+	X402Version int                     `json:"x402Version"`
+	Error       string                  `json:"error,omitempty"`
+	Accepts     []PaymentRequirementsV1 `json:"accepts"`
+}
@@ -0,0 +1,41 @@
+package utils
+
+import "strings"
+
+// v1Networks maps the network names of x402 v1 to CAIP-2 ids
+var v1Networks = map[string]string{
+	"base":             "eip155:8453",
+	"base-sepolia":     "eip155:84532",
+	"avalanche":        "eip155:43114",
+	"avalanche-fuji":   "eip155:43113",
+	"polygon":          "eip155:137",
+	"polygon-amoy":     "eip155:80002",
+	"sei":              "eip155:1329",
+	"sei-testnet":      "eip155:1328",
+	"iotex":            "eip155:4689",
+	"abstract":         "eip155:2741",
+	"abstract-testnet": "eip155:11124",
+	"peaq":             "eip155:3338",
+	"solana":           "solana:mainnet",
+	"solana-devnet":    "solana:devnet",
+}
+
+// NetworkFromV1 returns the CAIP-2 id of an x402 v1 network name. CAIP-2 ids
+// and unknown names are returned unchanged.
+func NetworkFromV1(name string) string {
+	if network, ok := v1Networks[strings.ToLower(name)]; ok {
+		return network
+	}
+	return name
+}
+
+// NetworkToV1 returns the x402 v1 name of a CAIP-2 network, or the id itself
+// when v1 has no name for it
+func NetworkToV1(network string) string {
+	for name, id := range v1Networks {
+		if id == network {
+			return name
+		}
+	}
+	return network
+}
@@ -0,0 +1,177 @@
+package utils
+
+import (
+	"crypto/ecdsa"
+	"encoding/json"
+	"fmt"
+	"math/big"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/common/math"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/signer/core/apitypes"
+	"github.com/vorpalengineering/x402-go/types"
+)
+
+// Permit2Address is the address Uniswap's Permit2 is deployed at on every EVM chain
+const Permit2Address = "0x000000000022D473030F116dDEE9F6B43aC78BA3"
+
+// Permit2PermitTransferFromABI is Permit2's signature transfer, which moves
+// up to the permitted amount from owner with a single use signature
+const Permit2PermitTransferFromABI = `[{
+	"inputs": [
+		{
+			"components": [
+				{
+					"components": [
+						{"name": "token", "type": "address"},
+						{"name": "amount", "type": "uint256"}
+					],
+					"name": "permitted",
+					"type": "tuple"
+				},
+				{"name": "nonce", "type": "uint256"},
+				{"name": "deadline", "type": "uint256"}
+			],
+			"name": "permit",
+			"type": "tuple"
+		},
+		{
+			"components": [
+				{"name": "to", "type": "address"},
+				{"name": "requestedAmount", "type": "uint256"}
+			],
+			"name": "transferDetails",
+			"type": "tuple"
+		},
+		{"name": "owner", "type": "address"},
+		{"name": "signature", "type": "bytes"}
+	],
+	"name": "permitTransferFrom",
+	"outputs": [],
+	"stateMutability": "nonpayable",
+	"type": "function"
+}]`
+
+// ERC20AllowanceABI reads how much a spender may transfer from an owner
+const ERC20AllowanceABI = `[{
+	"constant": true,
+	"inputs": [
+		{"name": "owner", "type": "address"},
+		{"name": "spender", "type": "address"}
+	],
+	"name": "allowance",
+	"outputs": [{"name": "", "type": "uint256"}],
+	"type": "function"
+}]`
+
+// ExtractUptoAuthorization returns the Permit2 authorization of an upto scheme payload
+func ExtractUptoAuthorization(payload *types.PaymentPayload) (*types.UptoEVMSchemeAuthorization, error) {
+	// Get authorization object
+	authData, ok := payload.Payload["authorization"]
+	if !ok {
+		return nil, fmt.Errorf("missing authorization")
+	}
+
+	// Convert to JSON and back to struct
+	authJSON, err := json.Marshal(authData)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal authorization: %w", err)
+	}
+
+	var auth types.UptoEVMSchemeAuthorization
+	if err := json.Unmarshal(authJSON, &auth); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal authorization: %w", err)
+	}
+
+	return &auth, nil
+}
+
+// BuildPermit2TypedData builds the Permit2 PermitTransferFrom message an upto
+// scheme authorization is signed over, permitting Spender to transfer up to
+// Value of the requirements' asset
+func BuildPermit2TypedData(auth *types.UptoEVMSchemeAuthorization, requirements *types.PaymentRequirements) (*apitypes.TypedData, error) {
+	// Parse amounts
+	value, ok := new(big.Int).SetString(auth.Value, 10)
+	if !ok {
+		return nil, fmt.Errorf("invalid value: %s", auth.Value)
+	}
+	nonce, ok := new(big.Int).SetString(auth.Nonce, 10)
+	if !ok {
+		return nil, fmt.Errorf("invalid nonce: %s", auth.Nonce)
+	}
+
+	// Get Chain ID
+	chainID, err := GetChainID(requirements.Network)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse chain id: %w", err)
+	}
+
+	return &apitypes.TypedData{
+		Types: apitypes.Types{
+			"EIP712Domain": []apitypes.Type{
+				{Name: "name", Type: "string"},
+				{Name: "chainId", Type: "uint256"},
+				{Name: "verifyingContract", Type: "address"},
+			},
+			"PermitTransferFrom": []apitypes.Type{
+				{Name: "permitted", Type: "TokenPermissions"},
+				{Name: "spender", Type: "address"},
+				{Name: "nonce", Type: "uint256"},
+				{Name: "deadline", Type: "uint256"},
+			},
+			"TokenPermissions": []apitypes.Type{
+				{Name: "token", Type: "address"},
+				{Name: "amount", Type: "uint256"},
+			},
+		},
+		PrimaryType: "PermitTransferFrom",
+		Domain: apitypes.TypedDataDomain{
+			Name:              "Permit2",
+			ChainId:           (*math.HexOrDecimal256)(chainID),
+			VerifyingContract: Permit2Address,
+		},
+		Message: apitypes.TypedDataMessage{
+			"permitted": map[string]any{
+				"token":  common.HexToAddress(requirements.Asset).Hex(),
+				"amount": value.String(),
+			},
+			"spender":  common.HexToAddress(auth.Spender).Hex(),
+			"nonce":    nonce.String(),
+			"deadline": fmt.Sprintf("%d", auth.Deadline),
+		},
+	}, nil
+}
+
+// HashPermit2 returns the EIP-712 hash an upto scheme authorization is signed over
+func HashPermit2(auth *types.UptoEVMSchemeAuthorization, requirements *types.PaymentRequirements) (common.Hash, error) {
+	typedData, err := BuildPermit2TypedData(auth, requirements)
+	if err != nil {
+		return common.Hash{}, err
+	}
+	hash, _, err := apitypes.TypedDataAndHash(*typedData)
+	if err != nil {
+		return common.Hash{}, fmt.Errorf("failed to hash typed data: %w", err)
+	}
+	return common.BytesToHash(hash), nil
+}
+
+// SignPermit2 signs an upto scheme authorization for the given requirements
+func SignPermit2(auth *types.UptoEVMSchemeAuthorization, privateKey *ecdsa.PrivateKey, requirements *types.PaymentRequirements) (string, error) {
+	hash, err := HashPermit2(auth, requirements)
+	if err != nil {
+		return "", err
+	}
+
+	// Sign
+	sig, err := crypto.Sign(hash.Bytes(), privateKey)
+	if err != nil {
+		return "", fmt.Errorf("failed to sign: %w", err)
+	}
+
+	// Adjust v for Ethereum (add 27)
+	sig[64] += 27
+
+	return hexutil.Encode(sig), nil
+}
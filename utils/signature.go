@@ -0,0 +1,77 @@
+package utils
+
+import (
+	"bytes"
+	"fmt"
+	"math/big"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/crypto"
+)
+
+var (
+	// secp256k1N is the order of the secp256k1 curve
+	secp256k1N = crypto.S256().Params().N
+	// secp256k1HalfN is the largest canonical s value
+	secp256k1HalfN = new(big.Int).Rsh(secp256k1N, 1)
+)
+
+// IsLowS reports whether s is canonical, in the lower half of the curve order.
+// For every signature (r, s) the signature (r, n-s) is valid for the same
+// message too, so only the low-s form is accepted.
+func IsLowS(s *big.Int) bool {
+	return s.Sign() > 0 && s.Cmp(secp256k1HalfN) <= 0
+}
+
+// NormalizeSignature checks a 65 byte [r || s || v] ECDSA signature is
+// canonical and returns a copy with v as 27 or 28. v may be given as 0/1 or
+// 27/28, any other value is rejected. r must be in [1, n) and s in [1, n/2].
+func NormalizeSignature(signature []byte) ([]byte, error) {
+	// Signature should be 65 bytes (r: 32, s: 32, v: 1)
+	if len(signature) != 65 {
+		return nil, fmt.Errorf("invalid signature length: expected 65, got %d", len(signature))
+	}
+	sig := bytes.Clone(signature)
+
+	// Ethereum uses v = 27 or 28, some signers produce 0 or 1
+	switch sig[64] {
+	case 0, 1:
+		sig[64] += 27
+	case 27, 28:
+	default:
+		return nil, fmt.Errorf("invalid signature v: %d, expected 27 or 28", sig[64])
+	}
+
+	// Check r and s are in range and s is canonical
+	r := new(big.Int).SetBytes(sig[0:32])
+	if r.Sign() == 0 || r.Cmp(secp256k1N) >= 0 {
+		return nil, fmt.Errorf("invalid signature r: out of range")
+	}
+	s := new(big.Int).SetBytes(sig[32:64])
+	if s.Sign() == 0 || s.Cmp(secp256k1N) >= 0 {
+		return nil, fmt.Errorf("invalid signature s: out of range")
+	}
+	if !IsLowS(s) {
+		return nil, fmt.Errorf("malleable signature: s is in the upper half of the curve order")
+	}
+
+	return sig, nil
+}
+
+// RecoverAddress recovers the address that signed hash with a 65 byte
+// signature, rejecting non-canonical signatures
+func RecoverAddress(hash []byte, signature []byte) (common.Address, error) {
+	sig, err := NormalizeSignature(signature)
+	if err != nil {
+		return common.Address{}, err
+	}
+
+	// Adjust v (Ethereum uses 27/28, but ecrecover expects 0/1)
+	sig[64] -= 27
+
+	pubKey, err := crypto.SigToPub(hash, sig)
+	if err != nil {
+		return common.Address{}, fmt.Errorf("failed to recover public key: %w", err)
+	}
+	return crypto.PubkeyToAddress(*pubKey), nil
+}
@@ -0,0 +1,98 @@
+package utils
+
+import (
+	"bytes"
+	"math/big"
+	"strings"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/crypto"
+)
+
+func TestNormalizeSignature(t *testing.T) {
+	key, _ := crypto.HexToECDSA("ac0974bec39a17e36ba4a6b4d238ff944bacb478cbed5efcae784d7bf4f2ff80")
+	hash := crypto.Keccak256([]byte("x402"))
+	sig, _ := crypto.Sign(hash, key)
+
+	// withS replaces s, flipping v when s is mirrored
+	withS := func(sig []byte, s *big.Int, flipV bool) []byte {
+		out := bytes.Clone(sig)
+		copy(out[32:64], common.LeftPadBytes(s.Bytes(), 32))
+		if flipV {
+			out[64] ^= 1
+		}
+		return out
+	}
+	withByte := func(sig []byte, i int, b byte) []byte {
+		out := bytes.Clone(sig)
+		out[i] = b
+		return out
+	}
+	s := new(big.Int).SetBytes(sig[32:64])
+	highS := withS(sig, new(big.Int).Sub(secp256k1N, s), true)
+
+	tests := []struct {
+		name string
+		sig  []byte
+		err  string
+	}{
+		{"v as 0/1", sig, ""},
+		{"v as 27/28", withByte(sig, 64, sig[64]+27), ""},
+		{"s at half order", withS(sig, secp256k1HalfN, false), ""},
+		{"v of 29", withByte(sig, 64, 29), "invalid signature v"},
+		{"v of 2", withByte(sig, 64, 2), "invalid signature v"},
+		{"eip-155 style v", withByte(sig, 64, 37), "invalid signature v"},
+		{"high s", highS, "malleable signature"},
+		{"s above half order", withS(sig, new(big.Int).Add(secp256k1HalfN, big.NewInt(1)), false), "malleable signature"},
+		{"zero s", withS(sig, new(big.Int), false), "invalid signature s"},
+		{"s of order", withS(sig, secp256k1N, false), "invalid signature s"},
+		{"zero r", append(make([]byte, 32), sig[32:]...), "invalid signature r"},
+		{"r of order", append(common.LeftPadBytes(secp256k1N.Bytes(), 32), sig[32:]...), "invalid signature r"},
+		{"short", sig[:64], "invalid signature length"},
+	}
+	for _, test := range tests {
+		normalized, err := NormalizeSignature(test.sig)
+		if test.err == "" {
+			if err != nil {
+				t.Errorf("%s: expected signature to be valid, got %v", test.name, err)
+			} else if normalized[64] != 27 && normalized[64] != 28 {
+				t.Errorf("%s: expected v of 27 or 28, got %d", test.name, normalized[64])
+			}
+			continue
+		}
+		if err == nil || !strings.Contains(err.Error(), test.err) {
+			t.Errorf("%s: expected %q, got %v", test.name, test.err, err)
+		}
+	}
+
+	// The mirrored signature recovers the same key, which is why it is rejected
+	if pub, err := crypto.SigToPub(hash, highS); err != nil || crypto.PubkeyToAddress(*pub) != crypto.PubkeyToAddress(key.PublicKey) {
+		t.Fatalf("Expected high-s signature to recover the signer, got %v", err)
+	}
+	if _, err := RecoverAddress(hash, highS); err == nil {
+		t.Error("Expected RecoverAddress to reject high-s signature")
+	}
+	if addr, err := RecoverAddress(hash, sig); err != nil || addr != crypto.PubkeyToAddress(key.PublicKey) {
+		t.Errorf("Expected RecoverAddress to recover the signer, got %s, %v", addr.Hex(), err)
+	}
+}
+
+func TestExtractVRS(t *testing.T) {
+	key, _ := crypto.HexToECDSA("ac0974bec39a17e36ba4a6b4d238ff944bacb478cbed5efcae784d7bf4f2ff80")
+	sig, _ := crypto.Sign(crypto.Keccak256([]byte("x402")), key)
+
+	v, r, s, err := ExtractVRS(common.Bytes2Hex(sig))
+	if err != nil {
+		t.Fatalf("Expected signature to be valid, got %v", err)
+	}
+	if v != sig[64]+27 || !bytes.Equal(r[:], sig[:32]) || !bytes.Equal(s[:], sig[32:64]) {
+		t.Errorf("Expected v=%d, got v=%d", sig[64]+27, v)
+	}
+
+	// Out of range v is rejected instead of shifted
+	sig[64] = 3
+	if _, _, _, err := ExtractVRS(common.Bytes2Hex(sig)); err == nil {
+		t.Error("Expected error for v of 3")
+	}
+}
@@ -0,0 +1,54 @@
+package utils
+
+import (
+	"math/big"
+	"strings"
+)
+
+// ERC20MetadataABI reads a token's name, EIP-712 version and decimals. version()
+// is not part of ERC-20 but is exposed by EIP-3009 tokens such as USDC.
+const ERC20MetadataABI = `[
+	{"constant": true, "inputs": [], "name": "name", "outputs": [{"name": "", "type": "string"}], "type": "function"},
+	{"constant": true, "inputs": [], "name": "version", "outputs": [{"name": "", "type": "string"}], "type": "function"},
+	{"constant": true, "inputs": [], "name": "decimals", "outputs": [{"name": "", "type": "uint8"}], "type": "function"}
+]`
+
+// EIP5267DomainABI reads the EIP-712 domain a contract signs with
+const EIP5267DomainABI = `[{
+	"inputs": [],
+	"name": "eip712Domain",
+	"outputs": [
+		{"name": "fields", "type": "bytes1"},
+		{"name": "name", "type": "string"},
+		{"name": "version", "type": "string"},
+		{"name": "chainId", "type": "uint256"},
+		{"name": "verifyingContract", "type": "address"},
+		{"name": "salt", "type": "bytes32"},
+		{"name": "extensions", "type": "uint256[]"}
+	],
+	"stateMutability": "view",
+	"type": "function"
+}]`
+
+// FormatAmount formats an amount in atomic units as a decimal number of
+// tokens, e.g. 1500000 with 6 decimals as "1.5"
+func FormatAmount(amount *big.Int, decimals uint8) string {
+	if decimals == 0 {
+		return amount.String()
+	}
+
+	// Split into whole and fractional parts
+	unit := new(big.Int).Exp(big.NewInt(10), big.NewInt(int64(decimals)), nil)
+	whole, frac := new(big.Int).QuoRem(new(big.Int).Abs(amount), unit, new(big.Int))
+
+	result := whole.String()
+	if frac.Sign() != 0 {
+		fracStr := frac.String()
+		fracStr = strings.Repeat("0", int(decimals)-len(fracStr)) + fracStr
+		result += "." + strings.TrimRight(fracStr, "0")
+	}
+	if amount.Sign() < 0 {
+		result = "-" + result
+	}
+	return result
+}
@@ -2,11 +2,15 @@ package utils
 
 import (
 	"crypto/ecdsa"
+	"crypto/rand"
 	"encoding/base64"
 	"encoding/hex"
 	"encoding/json"
 	"fmt"
+	"log/slog"
 	"math/big"
+	"os"
+	"strconv"
 	"strings"
 
 	"github.com/ethereum/go-ethereum/common"
@@ -25,6 +29,31 @@ const ERC20BalanceOfABI = `[{
 	"type": "function"
 }]`
 
+// ERC20TransferABI moves tokens from the caller
+const ERC20TransferABI = `[{
+	"constant": false,
+	"inputs": [
+		{"name": "to", "type": "address"},
+		{"name": "value", "type": "uint256"}
+	],
+	"name": "transfer",
+	"outputs": [{"name": "", "type": "bool"}],
+	"type": "function"
+}]`
+
+// ERC20TransferFromABI moves tokens from an owner that approved the caller
+const ERC20TransferFromABI = `[{
+	"constant": false,
+	"inputs": [
+		{"name": "from", "type": "address"},
+		{"name": "to", "type": "address"},
+		{"name": "value", "type": "uint256"}
+	],
+	"name": "transferFrom",
+	"outputs": [{"name": "", "type": "bool"}],
+	"type": "function"
+}]`
+
 const EIP3009TransferWithAuthABI = `[{
 	"inputs": [
 		{"name": "from", "type": "address"},
@@ -43,6 +72,139 @@ const EIP3009TransferWithAuthABI = `[{
 	"type": "function"
 }]`
 
+// EIP3009TransferWithAuthBytesABI is the transferWithAuthorization overload
+// taking the signature as bytes (USDC v2.2+), which also accepts ERC-1271
+// signatures from contract wallets
+const EIP3009TransferWithAuthBytesABI = `[{
+	"inputs": [
+		{"name": "from", "type": "address"},
+		{"name": "to", "type": "address"},
+		{"name": "value", "type": "uint256"},
+		{"name": "validAfter", "type": "uint256"},
+		{"name": "validBefore", "type": "uint256"},
+		{"name": "nonce", "type": "bytes32"},
+		{"name": "signature", "type": "bytes"}
+	],
+	"name": "transferWithAuthorization",
+	"outputs": [],
+	"stateMutability": "nonpayable",
+	"type": "function"
+}]`
+
+// EIP3009AuthorizationStateABI reports whether an authorization nonce of an
+// authorizer was used or canceled
+const EIP3009AuthorizationStateABI = `[{
+	"inputs": [
+		{"name": "authorizer", "type": "address"},
+		{"name": "nonce", "type": "bytes32"}
+	],
+	"name": "authorizationState",
+	"outputs": [{"name": "", "type": "bool"}],
+	"stateMutability": "view",
+	"type": "function"
+}]`
+
+// ERC1271IsValidSignatureABI is the signature check smart contract wallets
+// implement in place of ecrecover
+const ERC1271IsValidSignatureABI = `[{
+	"inputs": [
+		{"name": "hash", "type": "bytes32"},
+		{"name": "signature", "type": "bytes"}
+	],
+	"name": "isValidSignature",
+	"outputs": [{"name": "magicValue", "type": "bytes4"}],
+	"stateMutability": "view",
+	"type": "function"
+}]`
+
+// ERC1271MagicValue is returned by isValidSignature for a valid signature
+const ERC1271MagicValue = "0x1626ba7e"
+
+// Multicall3Address is the address Multicall3 is deployed at on most EVM chains
+const Multicall3Address = "0xcA11bde05977b3631167028862bE2a173976CA11"
+
+// Multicall3ABI is Multicall3's aggregate3, which runs calls in order and
+// reports the success and return data of each
+const Multicall3ABI = `[{
+	"inputs": [
+		{
+			"components": [
+				{"name": "target", "type": "address"},
+				{"name": "allowFailure", "type": "bool"},
+				{"name": "callData", "type": "bytes"}
+			],
+			"name": "calls",
+			"type": "tuple[]"
+		}
+	],
+	"name": "aggregate3",
+	"outputs": [
+		{
+			"components": [
+				{"name": "success", "type": "bool"},
+				{"name": "returnData", "type": "bytes"}
+			],
+			"name": "returnData",
+			"type": "tuple[]"
+		}
+	],
+	"stateMutability": "payable",
+	"type": "function"
+}]`
+
+// DefaultPaymentHeaderName is the request header carrying the payment payload
+const DefaultPaymentHeaderName = "PAYMENT-SIGNATURE"
+
+// PaymentHeaderExtension is the PaymentRequired extension key a resource server
+// uses to advertise a non-default payment header name
+const PaymentHeaderExtension = "paymentHeader"
+
+// RequestIDHeader carries the ID that correlates the logs of one request
+const RequestIDHeader = "X-Request-ID"
+
+// NewRequestID returns a random request ID
+func NewRequestID() string {
+	var id [8]byte
+	rand.Read(id[:])
+	return hex.EncodeToString(id[:])
+}
+
+// NewLogger returns a structured logger writing to stderr at level ("debug",
+// "info", "warn" or "error", default info). Format "json" writes JSON lines,
+// anything else key=value text.
+func NewLogger(level, format string) *slog.Logger {
+	var slogLevel slog.Level
+	switch strings.ToLower(level) {
+	case "debug":
+		slogLevel = slog.LevelDebug
+	case "warn":
+		slogLevel = slog.LevelWarn
+	case "error":
+		slogLevel = slog.LevelError
+	default:
+		slogLevel = slog.LevelInfo
+	}
+
+	opts := &slog.HandlerOptions{Level: slogLevel}
+	if format == "json" {
+		return slog.New(slog.NewJSONHandler(os.Stderr, opts))
+	}
+	return slog.New(slog.NewTextHandler(os.Stderr, opts))
+}
+
+// GetPaymentHeaderName returns the payment header name advertised in a 402
+// response, falling back to DefaultPaymentHeaderName.
+func GetPaymentHeaderName(paymentRequired *types.PaymentRequired) string {
+	if paymentRequired != nil {
+		if ext, ok := paymentRequired.Extensions[PaymentHeaderExtension]; ok {
+			if name, ok := ext.Info["name"].(string); ok && name != "" {
+				return name
+			}
+		}
+	}
+	return DefaultPaymentHeaderName
+}
+
 func GetChainID(network string) (*big.Int, error) {
 	// network string is in CAIP-2 format (e.g. "eip155:8453")
 	substrings := strings.Split(network, ":")
@@ -57,6 +219,12 @@ func GetChainID(network string) (*big.Int, error) {
 	return chainId, nil
 }
 
+// IsSolanaNetwork reports whether a CAIP-2 network is a Solana cluster
+// (e.g. "solana:mainnet")
+func IsSolanaNetwork(network string) bool {
+	return strings.HasPrefix(network, "solana:")
+}
+
 func DecodePaymentHeader(header string) (*types.PaymentPayload, error) {
 	// Decode base64
 	decoded, err := base64.StdEncoding.DecodeString(header)
@@ -102,6 +270,15 @@ func ExtractExactAuthorization(payload *types.PaymentPayload) (*types.ExactEVMSc
 	return &auth, nil
 }
 
+// ExtractSVMTransaction returns the encoded transaction of an exact SVM payload
+func ExtractSVMTransaction(payload *types.PaymentPayload) (string, error) {
+	transaction, ok := payload.Payload["transaction"].(string)
+	if !ok || transaction == "" {
+		return "", fmt.Errorf("missing transaction")
+	}
+	return transaction, nil
+}
+
 func ExtractVRS(signatureHex string) (v uint8, r [32]byte, s [32]byte, err error) {
 	// Remove 0x prefix if present
 	if len(signatureHex) > 2 && signatureHex[:2] == "0x" {
@@ -114,9 +291,10 @@ func ExtractVRS(signatureHex string) (v uint8, r [32]byte, s [32]byte, err error
 		return 0, [32]byte{}, [32]byte{}, fmt.Errorf("invalid signature format: %w", err)
 	}
 
-	// Signature should be 65 bytes (r: 32, s: 32, v: 1)
-	if len(signature) != 65 {
-		return 0, [32]byte{}, [32]byte{}, fmt.Errorf("invalid signature length: expected 65, got %d", len(signature))
+	// Check the signature is canonical, with v as 27 or 28
+	signature, err = NormalizeSignature(signature)
+	if err != nil {
+		return 0, [32]byte{}, [32]byte{}, err
 	}
 
 	// Extract r (first 32 bytes)
@@ -128,14 +306,30 @@ func ExtractVRS(signatureHex string) (v uint8, r [32]byte, s [32]byte, err error
 	// Extract v (last byte)
 	v = signature[64]
 
-	// Ethereum uses v = 27 or 28, ensure it's in that range
-	if v < 27 {
-		v += 27
-	}
-
 	return v, r, s, nil
 }
 
+// StatementMessage returns the message a payer signs to retrieve a statement
+func StatementMessage(payer, period, challenge string) string {
+	return fmt.Sprintf("x402 statement request\nPayer: %s\nPeriod: %s\nChallenge: %s",
+		common.HexToAddress(payer).Hex(), period, challenge)
+}
+
+// HashPersonalMessage returns the EIP-191 personal message hash of msg
+func HashPersonalMessage(msg string) common.Hash {
+	prefix := "\x19Ethereum Signed Message:\n" + strconv.Itoa(len(msg))
+	return crypto.Keccak256Hash([]byte(prefix + msg))
+}
+
+// RecoverPersonalSigner recovers the address that produced an EIP-191 signature over msg
+func RecoverPersonalSigner(msg string, signatureHex string) (common.Address, error) {
+	sig, err := hexutil.Decode("0x" + strings.TrimPrefix(signatureHex, "0x"))
+	if err != nil {
+		return common.Address{}, fmt.Errorf("invalid signature format: %w", err)
+	}
+	return RecoverAddress(HashPersonalMessage(msg).Bytes(), sig)
+}
+
 func BuildEIP712TypedData(auth *types.ExactEVMSchemeAuthorization, requirements *types.PaymentRequirements) (*apitypes.TypedData, error) {
 	// Parse value as big.Int
 	value := new(big.Int)
@@ -192,6 +386,47 @@ func BuildEIP712TypedData(auth *types.ExactEVMSchemeAuthorization, requirements
 	}, nil
 }
 
+// RecoverEIP3009Signer recovers the address that signed an exact-scheme
+// authorization for the given requirements
+func RecoverEIP3009Signer(auth *types.ExactEVMSchemeAuthorization, requirements *types.PaymentRequirements, signatureHex string) (common.Address, error) {
+	sig, err := hexutil.Decode("0x" + strings.TrimPrefix(signatureHex, "0x"))
+	if err != nil {
+		return common.Address{}, fmt.Errorf("invalid signature format: %w", err)
+	}
+
+	// Hash typed data
+	typedData, err := BuildEIP712TypedData(auth, requirements)
+	if err != nil {
+		return common.Address{}, err
+	}
+	hash, _, err := apitypes.TypedDataAndHash(*typedData)
+	if err != nil {
+		return common.Address{}, fmt.Errorf("failed to hash typed data: %w", err)
+	}
+
+	return RecoverAddress(hash, sig)
+}
+
+// EncodeTypedDataV4 encodes typed data as eth_signTypedData_v4 JSON, suitable
+// for signing with an external wallet (MetaMask, Ledger, etc.)
+func EncodeTypedDataV4(typedData *apitypes.TypedData) ([]byte, error) {
+	domain := map[string]any{
+		"name":              typedData.Domain.Name,
+		"version":           typedData.Domain.Version,
+		"verifyingContract": typedData.Domain.VerifyingContract,
+	}
+	if typedData.Domain.ChainId != nil {
+		domain["chainId"] = (*big.Int)(typedData.Domain.ChainId)
+	}
+
+	return json.MarshalIndent(map[string]any{
+		"types":       typedData.Types,
+		"primaryType": typedData.PrimaryType,
+		"domain":      domain,
+		"message":     typedData.Message,
+	}, "", "  ")
+}
+
 func SignEIP3009(auth *types.ExactEVMSchemeAuthorization, privateKey *ecdsa.PrivateKey, asset, domainName, domainVersion string, chainID int64) (string, error) {
 	// Parse addresses and values
 	fromAddr := common.HexToAddress(auth.From)
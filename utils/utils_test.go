@@ -0,0 +1,156 @@
+package utils
+
+import (
+	"encoding/hex"
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/signer/core/apitypes"
+	"github.com/vorpalengineering/x402-go/types"
+)
+
+func TestRecoverEIP3009Signer(t *testing.T) {
+	key, _ := crypto.HexToECDSA("ac0974bec39a17e36ba4a6b4d238ff944bacb478cbed5efcae784d7bf4f2ff80")
+	payer := crypto.PubkeyToAddress(key.PublicKey)
+	requirements := &types.PaymentRequirements{
+		Scheme:  "exact",
+		Network: "eip155:8453",
+		Amount:  "1000000",
+		PayTo:   "0x70997970C51812dc3A010C7d01b50e0d17dc79C8",
+		Asset:   "0x833589fCD6eDb6E08f4c7C32D4f71b54bdA02913",
+		Extra:   map[string]any{"name": "USD Coin", "version": "2"},
+	}
+	auth := &types.ExactEVMSchemeAuthorization{
+		From:        payer.Hex(),
+		To:          requirements.PayTo,
+		Value:       requirements.Amount,
+		ValidBefore: 1900000000,
+		Nonce:       "0x" + strings.Repeat("01", 32),
+	}
+	signature, err := SignEIP3009(auth, key, requirements.Asset, "USD Coin", "2", 8453)
+	if err != nil {
+		t.Fatalf("Failed to sign: %v", err)
+	}
+
+	signer, err := RecoverEIP3009Signer(auth, requirements, signature)
+	if err != nil || signer != payer {
+		t.Errorf("Expected signer %s, got %s (%v)", payer.Hex(), signer.Hex(), err)
+	}
+
+	// A different authorization recovers a different address
+	otherPayer := *auth
+	otherPayer.From = "0x3C44CdDdB6a900fa2b585dd299e03d12FA4293BC"
+	if signer, err := RecoverEIP3009Signer(&otherPayer, requirements, signature); err == nil && signer == payer {
+		t.Error("Expected signature not to recover the payer for another authorization")
+	}
+
+	if _, err := RecoverEIP3009Signer(auth, requirements, "0xzz"); err == nil || !strings.Contains(err.Error(), "invalid signature format") {
+		t.Errorf("Expected invalid signature format, got %v", err)
+	}
+
+	noDomain := *requirements
+	noDomain.Extra = nil
+	if _, err := RecoverEIP3009Signer(auth, &noDomain, signature); err == nil {
+		t.Error("Expected error without EIP712 domain in extra")
+	}
+}
+
+// transferWithAuthorization vector: Base Sepolia USDC, signed by the first
+// development account. The digest agrees with the hand-rolled encoding in
+// SignEIP3009, which produced the signature.
+var (
+	vectorAuth = &types.ExactEVMSchemeAuthorization{
+		From:        "0xf39Fd6e51aad88F6F4ce6aB8827279cffFb92266",
+		To:          "0x3C44CdDdB6a900fa2b585dd299e03d12FA4293BC",
+		Value:       "1000000",
+		ValidAfter:  0,
+		ValidBefore: 1893456000,
+		Nonce:       "0x0000000000000000000000000000000000000000000000000000000000000001",
+	}
+	vectorRequirements = &types.PaymentRequirements{
+		Network: "eip155:84532",
+		Asset:   "0x036CbD53842c5426634e7929541eC2318f3dCF7e",
+		Extra:   map[string]any{"name": "USDC", "version": "2"},
+	}
+	vectorDigest    = "2b837f58b0f591f34f562dc31f86ed8407e0673354347de96f6e0d41781804b6"
+	vectorSignature = "0x71a809509187e718cb4f7ecf02e831717c8d2926eb772dd0cfc227fdcbdd8acd49c21c3e009a958c77eafea87c5e7ac95e141f2ad2e11a7457f27c8ecf75416b1b"
+)
+
+func TestEncodeTypedDataV4(t *testing.T) {
+	typedData, err := BuildEIP712TypedData(vectorAuth, vectorRequirements)
+	if err != nil {
+		t.Fatalf("Failed to build typed data: %v", err)
+	}
+	encoded, err := EncodeTypedDataV4(typedData)
+	if err != nil {
+		t.Fatalf("Failed to encode typed data: %v", err)
+	}
+
+	// Wallets expect a numeric chain id and the domain fields by name
+	var raw struct {
+		PrimaryType string         `json:"primaryType"`
+		Domain      map[string]any `json:"domain"`
+		Message     map[string]any `json:"message"`
+	}
+	if err := json.Unmarshal(encoded, &raw); err != nil {
+		t.Fatalf("Expected JSON, got %v", err)
+	}
+	if raw.PrimaryType != "TransferWithAuthorization" {
+		t.Errorf("Expected primary type TransferWithAuthorization, got %s", raw.PrimaryType)
+	}
+	if raw.Domain["chainId"] != float64(84532) || raw.Domain["name"] != "USDC" || raw.Domain["version"] != "2" || raw.Domain["verifyingContract"] != vectorRequirements.Asset {
+		t.Errorf("Unexpected domain %v", raw.Domain)
+	}
+	if raw.Message["value"] != "1000000" || raw.Message["nonce"] != vectorAuth.Nonce || raw.Message["validBefore"] != "1893456000" {
+		t.Errorf("Unexpected message %v", raw.Message)
+	}
+
+	// Signing the decoded JSON signs the vector's digest
+	var decoded apitypes.TypedData
+	if err := json.Unmarshal(encoded, &decoded); err != nil {
+		t.Fatalf("Expected eth_signTypedData_v4 JSON, got %v", err)
+	}
+	digest, _, err := apitypes.TypedDataAndHash(decoded)
+	if err != nil {
+		t.Fatalf("Failed to hash typed data: %v", err)
+	}
+	if hex.EncodeToString(digest) != vectorDigest {
+		t.Errorf("Expected digest %s, got %x", vectorDigest, digest)
+	}
+	key, _ := crypto.HexToECDSA("ac0974bec39a17e36ba4a6b4d238ff944bacb478cbed5efcae784d7bf4f2ff80")
+	if signature, err := SignEIP3009(vectorAuth, key, vectorRequirements.Asset, "USDC", "2", 84532); err != nil || signature != vectorSignature {
+		t.Errorf("Expected signature %s, got %s (%v)", vectorSignature, signature, err)
+	}
+	signer, err := RecoverEIP3009Signer(vectorAuth, vectorRequirements, vectorSignature)
+	if err != nil || signer.Hex() != vectorAuth.From {
+		t.Errorf("Expected vector signature to recover %s, got %s (%v)", vectorAuth.From, signer.Hex(), err)
+	}
+}
+
+func TestGetPaymentHeaderName(t *testing.T) {
+	advertising := func(info map[string]any) *types.PaymentRequired {
+		return &types.PaymentRequired{Extensions: map[string]types.Extension{PaymentHeaderExtension: {Info: info}}}
+	}
+
+	tests := []struct {
+		name            string
+		paymentRequired *types.PaymentRequired
+		expected        string
+	}{
+		{"nil response", nil, DefaultPaymentHeaderName},
+		{"no extensions", &types.PaymentRequired{}, DefaultPaymentHeaderName},
+		{"advertised name", advertising(map[string]any{"name": "X-PAYMENT"}), "X-PAYMENT"},
+		{"empty name", advertising(map[string]any{"name": ""}), DefaultPaymentHeaderName},
+		{"name not a string", advertising(map[string]any{"name": 42}), DefaultPaymentHeaderName},
+		{"other extension", &types.PaymentRequired{Extensions: map[string]types.Extension{"bazaar": {Info: map[string]any{"name": "X-PAYMENT"}}}}, DefaultPaymentHeaderName},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if name := GetPaymentHeaderName(tt.paymentRequired); name != tt.expected {
+				t.Errorf("Expected %s, got %s", tt.expected, name)
+			}
+		})
+	}
+}
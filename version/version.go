@@ -0,0 +1,101 @@
+// Package version identifies the build of the x402-go binaries and libraries.
+//
+// Release builds set the variables below with -ldflags, e.g.
+//
+//	go build -trimpath -ldflags "-X github.com/vorpalengineering/x402-go/version.Version=v1.2.0" ./cmd/facilitator
+//
+// Otherwise the module version and VCS details embedded by the Go toolchain are used.
+package version
+
+import (
+	"fmt"
+	"net/http"
+	"runtime"
+	"runtime/debug"
+
+	"github.com/vorpalengineering/x402-go/types"
+)
+
+// Set at build time with -ldflags "-X ..."
+var (
+	Version   = ""
+	Commit    = ""
+	BuildDate = ""
+)
+
+// X402Version is the x402 protocol version this build speaks
+const X402Version = 2
+
+// Get returns the version information of the running build
+func Get() types.VersionInfo {
+	info := types.VersionInfo{
+		Version:     Version,
+		Commit:      Commit,
+		BuildDate:   BuildDate,
+		GoVersion:   runtime.Version(),
+		X402Version: X402Version,
+	}
+
+	// Fill gaps from the toolchain's build info
+	if build, ok := debug.ReadBuildInfo(); ok {
+		if info.Version == "" && build.Main.Version != "(devel)" {
+			info.Version = build.Main.Version
+		}
+		var modified bool
+		for _, setting := range build.Settings {
+			switch setting.Key {
+			case "vcs.revision":
+				if info.Commit == "" {
+					info.Commit = setting.Value
+				}
+			case "vcs.time":
+				if info.BuildDate == "" {
+					info.BuildDate = setting.Value
+				}
+			case "vcs.modified":
+				modified = setting.Value == "true"
+			}
+		}
+		if modified && Commit == "" && info.Commit != "" {
+			info.Commit += "-dirty"
+		}
+	}
+	if info.Version == "" {
+		info.Version = "dev"
+	}
+
+	return info
+}
+
+// UserAgent returns the User-Agent for requests made by product,
+// e.g. "x402cli/v1.2.0 (x402/2)"
+func UserAgent(product string) string {
+	return fmt.Sprintf("%s/%s (x402/%d)", product, Get().Version, X402Version)
+}
+
+// Transport sets a User-Agent on requests that do not already have one
+type Transport struct {
+	// Base is the underlying transport (default http.DefaultTransport)
+	Base      http.RoundTripper
+	UserAgent string
+}
+
+// NewTransport returns a Transport identifying requests as coming from product
+func NewTransport(product string) *Transport {
+	return &Transport{UserAgent: UserAgent(product)}
+}
+
+func (t *Transport) RoundTrip(req *http.Request) (*http.Response, error) {
+	base := t.Base
+	if base == nil {
+		base = http.DefaultTransport
+	}
+	if req.Header.Get("User-Agent") != "" {
+		return base.RoundTrip(req)
+	}
+
+	// RoundTrippers must not modify the caller's request
+	req = req.Clone(req.Context())
+	req.Header.Set("User-Agent", t.UserAgent)
+	return base.RoundTrip(req)
+}
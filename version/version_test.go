@@ -0,0 +1,57 @@
+package version
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestTransportSetsUserAgent(t *testing.T) {
+	var got []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		got = append(got, r.UserAgent())
+	}))
+	defer server.Close()
+
+	client := &http.Client{Transport: NewTransport("x402-go")}
+
+	// Default User-Agent identifies the build and protocol
+	resp, err := client.Get(server.URL)
+	if err != nil {
+		t.Fatalf("Request failed: %v", err)
+	}
+	resp.Body.Close()
+
+	// Caller-set User-Agent is kept
+	req, _ := http.NewRequest(http.MethodGet, server.URL, nil)
+	req.Header.Set("User-Agent", "custom/1.0")
+	resp, err = client.Do(req)
+	if err != nil {
+		t.Fatalf("Request failed: %v", err)
+	}
+	resp.Body.Close()
+
+	if !strings.HasPrefix(got[0], "x402-go/") || !strings.HasSuffix(got[0], "(x402/2)") {
+		t.Errorf("Expected x402-go User-Agent, got %s", got[0])
+	}
+	if got[1] != "custom/1.0" {
+		t.Errorf("Expected custom User-Agent to be kept, got %s", got[1])
+	}
+	if req.Header.Get("User-Agent") != "custom/1.0" {
+		t.Errorf("Expected caller's request to be unchanged")
+	}
+}
+
+func TestGetLdflagsOverride(t *testing.T) {
+	Version, Commit = "v9.9.9", "abc123"
+	defer func() { Version, Commit = "", "" }()
+
+	info := Get()
+	if info.Version != "v9.9.9" || info.Commit != "abc123" || info.X402Version != X402Version {
+		t.Errorf("Expected ldflags values, got %+v", info)
+	}
+	if UserAgent("x402cli") != "x402cli/v9.9.9 (x402/2)" {
+		t.Errorf("Unexpected User-Agent: %s", UserAgent("x402cli"))
+	}
+}
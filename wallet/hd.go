@@ -0,0 +1,227 @@
+package wallet
+
+import (
+	"crypto/ecdsa"
+	"crypto/hmac"
+	"crypto/pbkdf2"
+	"crypto/sha256"
+	"crypto/sha512"
+	_ "embed"
+	"encoding/binary"
+	"fmt"
+	"math/big"
+	"strings"
+	"sync"
+
+	"github.com/ethereum/go-ethereum/accounts"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/crypto"
+	"golang.org/x/text/unicode/norm"
+)
+
+// DefaultDerivationPath is the standard Ethereum account path (BIP-44)
+const DefaultDerivationPath = "m/44'/60'/0'/0/0"
+
+// DefaultBasePath is the parent path HDWallet derives labelled accounts under
+const DefaultBasePath = "m/44'/60'/0'/0"
+
+// englishWords is the BIP-39 English wordlist
+//
+//go:embed english.txt
+var englishWords string
+
+// wordIndex maps each word of the English wordlist to its 11-bit index
+var wordIndex = func() map[string]int {
+	words := strings.Fields(englishWords)
+	index := make(map[string]int, len(words))
+	for i, word := range words {
+		index[word] = i
+	}
+	return index
+}()
+
+// MnemonicToSeed converts a BIP-39 mnemonic and optional passphrase into a seed.
+// The mnemonic must use the English wordlist and carry a valid checksum.
+func MnemonicToSeed(mnemonic, passphrase string) ([]byte, error) {
+	words := strings.Fields(mnemonic)
+	if err := validateMnemonic(words); err != nil {
+		return nil, err
+	}
+
+	normalized := norm.NFKD.String(strings.Join(words, " "))
+	salt := norm.NFKD.String("mnemonic" + passphrase)
+	return pbkdf2.Key(sha512.New, normalized, []byte(salt), 2048, 64)
+}
+
+// validateMnemonic checks a mnemonic's words against the English wordlist
+// and its checksum against the entropy they encode
+func validateMnemonic(words []string) error {
+	switch len(words) {
+	case 12, 15, 18, 21, 24:
+	default:
+		return fmt.Errorf("invalid mnemonic: expected 12-24 words, got %d", len(words))
+	}
+
+	// Each word encodes 11 bits: the entropy followed by a checksum of
+	// one bit per 32 bits of entropy
+	bits := new(big.Int)
+	for i, word := range words {
+		index, ok := wordIndex[word]
+		if !ok {
+			return fmt.Errorf("invalid mnemonic: word %d (%q) is not in the BIP-39 wordlist", i+1, word)
+		}
+		bits.Lsh(bits, 11)
+		bits.Or(bits, big.NewInt(int64(index)))
+	}
+	checksumBits := uint(len(words) * 11 / 33)
+	checksum := new(big.Int).And(bits, big.NewInt(1<<checksumBits-1))
+	entropy := common.LeftPadBytes(new(big.Int).Rsh(bits, checksumBits).Bytes(), int(checksumBits)*4)
+
+	hash := sha256.Sum256(entropy)
+	if uint64(hash[0]>>(8-checksumBits)) != checksum.Uint64() {
+		return fmt.Errorf("invalid mnemonic: checksum mismatch")
+	}
+	return nil
+}
+
+// DeriveKey derives the private key at a BIP-32 path (e.g. "m/44'/60'/0'/0/0") from a seed
+func DeriveKey(seed []byte, path string) (*ecdsa.PrivateKey, error) {
+	derivationPath, err := accounts.ParseDerivationPath(path)
+	if err != nil {
+		return nil, fmt.Errorf("invalid derivation path: %w", err)
+	}
+
+	// Master key
+	mac := hmac.New(sha512.New, []byte("Bitcoin seed"))
+	mac.Write(seed)
+	sum := mac.Sum(nil)
+	key, chainCode := new(big.Int).SetBytes(sum[:32]), sum[32:]
+
+	// Walk the path
+	for _, index := range derivationPath {
+		key, chainCode, err = deriveChild(key, chainCode, index)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return crypto.ToECDSA(common.LeftPadBytes(key.Bytes(), 32))
+}
+
+// DeriveKeyFromMnemonic derives the private key at path from a BIP-39 mnemonic
+func DeriveKeyFromMnemonic(mnemonic, passphrase, path string) (*ecdsa.PrivateKey, error) {
+	seed, err := MnemonicToSeed(mnemonic, passphrase)
+	if err != nil {
+		return nil, err
+	}
+	return DeriveKey(seed, path)
+}
+
+// deriveChild performs BIP-32 private child key derivation
+func deriveChild(key *big.Int, chainCode []byte, index uint32) (*big.Int, []byte, error) {
+	curveOrder := crypto.S256().Params().N
+
+	// Hardened children commit to the private key, normal ones to the public key
+	var data []byte
+	if index >= 0x80000000 {
+		data = append([]byte{0x00}, common.LeftPadBytes(key.Bytes(), 32)...)
+	} else {
+		privateKey, err := crypto.ToECDSA(common.LeftPadBytes(key.Bytes(), 32))
+		if err != nil {
+			return nil, nil, err
+		}
+		data = crypto.CompressPubkey(&privateKey.PublicKey)
+	}
+	data = binary.BigEndian.AppendUint32(data, index)
+
+	mac := hmac.New(sha512.New, chainCode)
+	mac.Write(data)
+	sum := mac.Sum(nil)
+
+	tweak := new(big.Int).SetBytes(sum[:32])
+	if tweak.Cmp(curveOrder) >= 0 {
+		return nil, nil, fmt.Errorf("invalid child key at index %d", index)
+	}
+	child := new(big.Int).Add(tweak, key)
+	child.Mod(child, curveOrder)
+	if child.Sign() == 0 {
+		return nil, nil, fmt.Errorf("invalid child key at index %d", index)
+	}
+
+	return child, sum[32:], nil
+}
+
+// Derivation records which child index was handed out for a label
+type Derivation struct {
+	Label   string `json:"label"`
+	Path    string `json:"path"`
+	Address string `json:"address"`
+}
+
+// HDWallet derives a separate payer account per label (counterparty, session, ...)
+// from a single seed and keeps track of which index each label received.
+type HDWallet struct {
+	seed        []byte
+	basePath    string
+	mu          sync.Mutex
+	derivations []Derivation
+	byLabel     map[string]int
+}
+
+// NewHDWallet creates a wallet deriving labelled accounts under basePath.
+// Previously issued derivations can be restored so labels keep their address.
+func NewHDWallet(mnemonic, passphrase, basePath string, restored []Derivation) (*HDWallet, error) {
+	seed, err := MnemonicToSeed(mnemonic, passphrase)
+	if err != nil {
+		return nil, err
+	}
+	if basePath == "" {
+		basePath = DefaultBasePath
+	}
+
+	w := &HDWallet{
+		seed:     seed,
+		basePath: strings.TrimSuffix(basePath, "/"),
+		byLabel:  make(map[string]int),
+	}
+	for _, d := range restored {
+		w.byLabel[d.Label] = len(w.derivations)
+		w.derivations = append(w.derivations, d)
+	}
+
+	return w, nil
+}
+
+// KeyFor returns the private key for label, deriving the next unused index
+// the first time a label is seen.
+func (w *HDWallet) KeyFor(label string) (*ecdsa.PrivateKey, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	// Existing label
+	if i, ok := w.byLabel[label]; ok {
+		return DeriveKey(w.seed, w.derivations[i].Path)
+	}
+
+	// New label takes the next index
+	path := fmt.Sprintf("%s/%d", w.basePath, len(w.derivations))
+	key, err := DeriveKey(w.seed, path)
+	if err != nil {
+		return nil, err
+	}
+	w.byLabel[label] = len(w.derivations)
+	w.derivations = append(w.derivations, Derivation{
+		Label:   label,
+		Path:    path,
+		Address: crypto.PubkeyToAddress(key.PublicKey).Hex(),
+	})
+
+	return key, nil
+}
+
+// Derivations returns every label issued so far, for persisting and restoring
+func (w *HDWallet) Derivations() []Derivation {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return append([]Derivation(nil), w.derivations...)
+}
@@ -0,0 +1,121 @@
+package wallet
+
+import (
+	"encoding/hex"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/crypto"
+)
+
+const testMnemonic = "test test test test test test test test test test test junk"
+
+func TestMnemonicToSeed(t *testing.T) {
+	// BIP-39 reference vector
+	seed, err := MnemonicToSeed("abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon about", "TREZOR")
+	if err != nil {
+		t.Fatalf("Failed to derive seed: %v", err)
+	}
+
+	expected := "c55257c360c07c72029aebc1b53c05ed0362ada38ead3e3e9efa3708e53495531f09a6987599d18264c1e1c92f2cf141630c7a3c4ab7c81b2f001698e7463b04"
+	if hex.EncodeToString(seed) != expected {
+		t.Errorf("Expected seed %s, got %x", expected, seed)
+	}
+}
+
+func TestMnemonicValidation(t *testing.T) {
+	tests := []struct {
+		name     string
+		mnemonic string
+		wantErr  bool
+	}{
+		{name: "valid 12 words", mnemonic: "abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon about"},
+		{name: "valid 24 words", mnemonic: "zoo zoo zoo zoo zoo zoo zoo zoo zoo zoo zoo zoo zoo zoo zoo zoo zoo zoo zoo zoo zoo zoo zoo vote"},
+		{name: "bad checksum", mnemonic: "abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon", wantErr: true},
+		{name: "swapped words", mnemonic: "about abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon", wantErr: true},
+		{name: "unknown word", mnemonic: "abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon abandn", wantErr: true},
+		{name: "upper case word", mnemonic: "ABANDON abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon about", wantErr: true},
+		{name: "wrong length", mnemonic: "abandon abandon abandon", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := MnemonicToSeed(tt.mnemonic, "")
+			if tt.wantErr && err == nil {
+				t.Error("Expected error, got nil")
+			}
+			if !tt.wantErr && err != nil {
+				t.Errorf("Expected no error, got %v", err)
+			}
+		})
+	}
+}
+
+func TestDeriveKey(t *testing.T) {
+	// BIP-32 test vector 1, chain m/0'/1/2'/2/1000000000
+	seed, _ := hex.DecodeString("000102030405060708090a0b0c0d0e0f")
+	key, err := DeriveKey(seed, "m/0'/1/2'/2/1000000000")
+	if err != nil {
+		t.Fatalf("Failed to derive key: %v", err)
+	}
+
+	expected := "471b76e389e528d6de6d816857e012c5455051cad6660850e58372a6c3e6e7c8"
+	if got := hex.EncodeToString(crypto.FromECDSA(key)); got != expected {
+		t.Errorf("Expected key %s, got %s", expected, got)
+	}
+}
+
+func TestDeriveKeyFromMnemonic(t *testing.T) {
+	key, err := DeriveKeyFromMnemonic(testMnemonic, "", DefaultDerivationPath)
+	if err != nil {
+		t.Fatalf("Failed to derive key: %v", err)
+	}
+
+	// Well-known first development account
+	expected := "0xf39Fd6e51aad88F6F4ce6aB8827279cffFb92266"
+	if addr := crypto.PubkeyToAddress(key.PublicKey).Hex(); addr != expected {
+		t.Errorf("Expected address %s, got %s", expected, addr)
+	}
+
+	// First account of the BIP-39 "abandon ... about" vector
+	key, err = DeriveKeyFromMnemonic("abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon about", "", DefaultDerivationPath)
+	if err != nil {
+		t.Fatalf("Failed to derive key: %v", err)
+	}
+	expected = "0x9858EfFD232B4033E47d90003D41EC34EcaEda94"
+	if addr := crypto.PubkeyToAddress(key.PublicKey).Hex(); addr != expected {
+		t.Errorf("Expected address %s, got %s", expected, addr)
+	}
+
+	// Mnemonics failing validation derive nothing
+	if _, err := DeriveKeyFromMnemonic("test test test test test test test test test test test test", "", DefaultDerivationPath); err == nil {
+		t.Error("Expected error for mnemonic with bad checksum")
+	}
+}
+
+func TestHDWalletLabels(t *testing.T) {
+	w, err := NewHDWallet(testMnemonic, "", "", nil)
+	if err != nil {
+		t.Fatalf("Failed to create wallet: %v", err)
+	}
+
+	first, _ := w.KeyFor("api.example.com")
+	second, _ := w.KeyFor("data.example.com")
+	again, _ := w.KeyFor("api.example.com")
+
+	if first.D.Cmp(second.D) == 0 {
+		t.Error("Expected different keys for different labels")
+	}
+	if first.D.Cmp(again.D) != 0 {
+		t.Error("Expected same key for repeated label")
+	}
+
+	// Restored wallet keeps label assignments
+	restored, _ := NewHDWallet(testMnemonic, "", "", w.Derivations())
+	third, _ := restored.KeyFor("other.example.com")
+	if derivations := restored.Derivations(); derivations[2].Path != "m/44'/60'/0'/0/2" {
+		t.Errorf("Expected new label at index 2, got %s", derivations[2].Path)
+	}
+	if third.D.Cmp(first.D) == 0 || third.D.Cmp(second.D) == 0 {
+		t.Error("Expected restored wallet to skip issued indexes")
+	}
+}
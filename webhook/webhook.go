@@ -0,0 +1,148 @@
+package webhook
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// SignatureHeader is the request header carrying the webhook signature
+const SignatureHeader = "X-X402-Signature"
+
+// DefaultTolerance is the maximum accepted age of a signed webhook
+const DefaultTolerance = 5 * time.Minute
+
+var (
+	ErrInvalidHeader      = errors.New("invalid signature header")
+	ErrTimestampExpired   = errors.New("signature timestamp outside tolerance")
+	ErrSignatureMismatch  = errors.New("no matching signature")
+	ErrNoSecretsAvailable = errors.New("no signing secrets configured")
+)
+
+// Endpoint is an outbound webhook destination with its signing secrets.
+// During rotation list the new secret first and keep the old one until
+// every receiver has been updated; each delivery is signed with all of them.
+type Endpoint struct {
+	URL     string   `json:"url" yaml:"url"`
+	Secrets []string `json:"secrets" yaml:"secrets"`
+}
+
+// Sign builds the signature header value for payload at the given time.
+// The header has the form "t=<unix>,v1=<hex>[,v1=<hex>...]" with one v1
+// entry per secret, where each is HMAC-SHA256(secret, "<unix>.<payload>").
+func Sign(payload []byte, secrets []string, timestamp time.Time) (string, error) {
+	if len(secrets) == 0 {
+		return "", ErrNoSecretsAvailable
+	}
+
+	ts := strconv.FormatInt(timestamp.Unix(), 10)
+	parts := []string{"t=" + ts}
+	for _, secret := range secrets {
+		parts = append(parts, "v1="+computeSignature(secret, ts, payload))
+	}
+	return strings.Join(parts, ","), nil
+}
+
+// Verify checks a signature header against payload. The signature must be
+// produced by one of secrets and its timestamp must be within tolerance of
+// now, which protects receivers against replayed deliveries.
+func Verify(payload []byte, header string, secrets []string, tolerance time.Duration) error {
+	if len(secrets) == 0 {
+		return ErrNoSecretsAvailable
+	}
+
+	// Parse header
+	var ts string
+	var signatures []string
+	for _, part := range strings.Split(header, ",") {
+		key, value, ok := strings.Cut(strings.TrimSpace(part), "=")
+		if !ok {
+			return ErrInvalidHeader
+		}
+		switch key {
+		case "t":
+			ts = value
+		case "v1":
+			signatures = append(signatures, value)
+		}
+	}
+	if ts == "" || len(signatures) == 0 {
+		return ErrInvalidHeader
+	}
+
+	// Check timestamp
+	unix, err := strconv.ParseInt(ts, 10, 64)
+	if err != nil {
+		return ErrInvalidHeader
+	}
+	age := time.Since(time.Unix(unix, 0))
+	if age < 0 {
+		age = -age
+	}
+	if tolerance > 0 && age > tolerance {
+		return ErrTimestampExpired
+	}
+
+	// Compare against every secret in constant time
+	for _, secret := range secrets {
+		expected := computeSignature(secret, ts, payload)
+		for _, sig := range signatures {
+			if hmac.Equal([]byte(expected), []byte(sig)) {
+				return nil
+			}
+		}
+	}
+
+	return ErrSignatureMismatch
+}
+
+// VerifyRequest reads and verifies a signed webhook request, returning its body.
+// The request body is restored so it can be read again by the caller.
+func VerifyRequest(req *http.Request, secrets []string, tolerance time.Duration) ([]byte, error) {
+	body, err := io.ReadAll(req.Body)
+	req.Body.Close()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read body: %w", err)
+	}
+	req.Body = io.NopCloser(bytes.NewReader(body))
+
+	if err := Verify(body, req.Header.Get(SignatureHeader), secrets, tolerance); err != nil {
+		return nil, err
+	}
+
+	return body, nil
+}
+
+// NewRequest builds a signed JSON POST request delivering payload to endpoint
+func NewRequest(ctx context.Context, endpoint Endpoint, payload []byte) (*http.Request, error) {
+	signature, err := Sign(payload, endpoint.Secrets, time.Now())
+	if err != nil {
+		return nil, err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint.URL, bytes.NewReader(payload))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(SignatureHeader, signature)
+
+	return req, nil
+}
+
+func computeSignature(secret string, ts string, payload []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(ts))
+	mac.Write([]byte("."))
+	mac.Write(payload)
+	return hex.EncodeToString(mac.Sum(nil))
+}
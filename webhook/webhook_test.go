@@ -0,0 +1,76 @@
+package webhook
+
+import (
+	"errors"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestSignAndVerify(t *testing.T) {
+	payload := []byte(`{"event":"settled"}`)
+
+	header, err := Sign(payload, []string{"secret"}, time.Now())
+	if err != nil {
+		t.Fatalf("Failed to sign: %v", err)
+	}
+
+	if err := Verify(payload, header, []string{"secret"}, DefaultTolerance); err != nil {
+		t.Errorf("Expected valid signature, got error: %v", err)
+	}
+
+	if err := Verify([]byte(`{"event":"tampered"}`), header, []string{"secret"}, DefaultTolerance); !errors.Is(err, ErrSignatureMismatch) {
+		t.Errorf("Expected ErrSignatureMismatch for tampered payload, got %v", err)
+	}
+}
+
+func TestVerifyRotatedSecrets(t *testing.T) {
+	payload := []byte(`{"event":"settled"}`)
+
+	// Sender signs with new and old secret during rotation
+	header, err := Sign(payload, []string{"new", "old"}, time.Now())
+	if err != nil {
+		t.Fatalf("Failed to sign: %v", err)
+	}
+
+	// Receivers that only know either secret accept the delivery
+	if err := Verify(payload, header, []string{"old"}, DefaultTolerance); err != nil {
+		t.Errorf("Expected old secret to verify, got error: %v", err)
+	}
+	if err := Verify(payload, header, []string{"new"}, DefaultTolerance); err != nil {
+		t.Errorf("Expected new secret to verify, got error: %v", err)
+	}
+	if err := Verify(payload, header, []string{"other"}, DefaultTolerance); !errors.Is(err, ErrSignatureMismatch) {
+		t.Errorf("Expected ErrSignatureMismatch for unknown secret, got %v", err)
+	}
+}
+
+func TestVerifyExpiredTimestamp(t *testing.T) {
+	payload := []byte(`{"event":"settled"}`)
+
+	header, err := Sign(payload, []string{"secret"}, time.Now().Add(-10*time.Minute))
+	if err != nil {
+		t.Fatalf("Failed to sign: %v", err)
+	}
+
+	if err := Verify(payload, header, []string{"secret"}, DefaultTolerance); !errors.Is(err, ErrTimestampExpired) {
+		t.Errorf("Expected ErrTimestampExpired, got %v", err)
+	}
+}
+
+func TestVerifyRequest(t *testing.T) {
+	payload := []byte(`{"event":"settled"}`)
+
+	header, _ := Sign(payload, []string{"secret"}, time.Now())
+	req := httptest.NewRequest("POST", "/hook", strings.NewReader(string(payload)))
+	req.Header.Set(SignatureHeader, header)
+
+	body, err := VerifyRequest(req, []string{"secret"}, DefaultTolerance)
+	if err != nil {
+		t.Fatalf("Expected valid request, got error: %v", err)
+	}
+	if string(body) != string(payload) {
+		t.Errorf("Expected body %s, got %s", payload, body)
+	}
+}
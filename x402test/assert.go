@@ -0,0 +1,67 @@
+package x402test
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/vorpalengineering/x402-go/types"
+)
+
+// AssertPaymentRequired fails the test unless res is a 402 asking for a
+// payment, and returns the decoded PAYMENT-REQUIRED header
+func AssertPaymentRequired(t testing.TB, res *httptest.ResponseRecorder) *types.PaymentRequired {
+	t.Helper()
+	if res.Code != http.StatusPaymentRequired {
+		t.Fatalf("Expected status 402, got %d: %s", res.Code, res.Body.String())
+	}
+	var paymentRequired types.PaymentRequired
+	decodeHeader(t, res, "PAYMENT-REQUIRED", &paymentRequired)
+	if len(paymentRequired.Accepts) == 0 {
+		t.Errorf("Expected PAYMENT-REQUIRED to accept a payment, got %+v", paymentRequired)
+	}
+	return &paymentRequired
+}
+
+// AssertRejected fails the test unless res is a 402 refusing a payment with
+// code (e.g. types.ErrCodeInvalidAmount)
+func AssertRejected(t testing.TB, res *httptest.ResponseRecorder, code string) {
+	t.Helper()
+	paymentRequired := AssertPaymentRequired(t, res)
+	if paymentRequired.ErrorCode != code {
+		t.Errorf("Expected error code %s, got %s", code, paymentRequired.ErrorCode)
+	}
+}
+
+// AssertPaid fails the test unless res is a successful response to a settled
+// payment, and returns the decoded PAYMENT-RESPONSE header
+func AssertPaid(t testing.TB, res *httptest.ResponseRecorder) *types.SettleResponse {
+	t.Helper()
+	if res.Code < 200 || res.Code >= 300 {
+		t.Fatalf("Expected a 2xx status, got %d: %s", res.Code, res.Body.String())
+	}
+	var settleResp types.SettleResponse
+	decodeHeader(t, res, "PAYMENT-RESPONSE", &settleResp)
+	if !settleResp.Success || settleResp.Transaction == "" {
+		t.Errorf("Expected a successful settlement, got %+v", settleResp)
+	}
+	return &settleResp
+}
+
+// decodeHeader decodes a base64 JSON response header into v
+func decodeHeader(t testing.TB, res *httptest.ResponseRecorder, name string, v any) {
+	t.Helper()
+	header := res.Header().Get(name)
+	if header == "" {
+		t.Fatalf("Expected %s header", name)
+	}
+	decoded, err := base64.StdEncoding.DecodeString(header)
+	if err != nil {
+		t.Fatalf("Invalid %s header: %v", name, err)
+	}
+	if err := json.Unmarshal(decoded, v); err != nil {
+		t.Fatalf("Invalid %s header: %v", name, err)
+	}
+}
@@ -0,0 +1,189 @@
+// Package x402test helps unit-test x402-protected handlers without a chain:
+// an in-memory facilitator, payers minting signed exact-scheme headers, and
+// assertions on 402 and paid responses.
+package x402test
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/vorpalengineering/x402-go/facilitator"
+	"github.com/vorpalengineering/x402-go/types"
+	"github.com/vorpalengineering/x402-go/utils"
+)
+
+// Facilitator is an in-memory facilitator that verifies and settles every
+// payment, unless told to reject them. Point the middleware's
+// FacilitatorURL at URL.
+type Facilitator struct {
+	// URL is the base URL of the facilitator
+	URL string
+
+	server *httptest.Server
+
+	mu           sync.Mutex
+	strict       bool
+	invalid      string
+	settleFailed string
+	verifies     []types.VerifyRequest
+	settles      []types.SettleRequest
+}
+
+// NewFacilitator starts a facilitator that is closed when the test ends
+func NewFacilitator(t testing.TB) *Facilitator {
+	t.Helper()
+	f := &Facilitator{}
+	f.server = httptest.NewServer(http.HandlerFunc(f.serveHTTP))
+	f.URL = f.server.URL
+	t.Cleanup(f.server.Close)
+	return f
+}
+
+// Strict checks exact EVM payments offline before accepting them: amount,
+// recipient, validity window and signature, as with LocalVerification.
+// Requirements must then carry the token's "name" and "version" in Extra,
+// as Requirements does.
+func (f *Facilitator) Strict() *Facilitator {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.strict = true
+	return f
+}
+
+// RejectPayments makes verification fail with code (e.g.
+// types.ErrCodeInsufficientFunds). "" accepts payments again.
+func (f *Facilitator) RejectPayments(code string) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.invalid = code
+}
+
+// FailSettlements makes settlement fail with code (e.g.
+// types.ErrCodeTransactionReverted). "" settles payments again.
+func (f *Facilitator) FailSettlements(code string) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.settleFailed = code
+}
+
+// Verifications returns the verify requests received so far
+func (f *Facilitator) Verifications() []types.VerifyRequest {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return append([]types.VerifyRequest{}, f.verifies...)
+}
+
+// Settlements returns the settle requests received so far
+func (f *Facilitator) Settlements() []types.SettleRequest {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return append([]types.SettleRequest{}, f.settles...)
+}
+
+// AssertSettled fails the test unless exactly n payments were settled
+func (f *Facilitator) AssertSettled(t testing.TB, n int) {
+	t.Helper()
+	if settled := len(f.Settlements()); settled != n {
+		t.Errorf("Expected %d settlements, got %d", n, settled)
+	}
+}
+
+func (f *Facilitator) serveHTTP(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	switch r.URL.Path {
+	case "/supported":
+		json.NewEncoder(w).Encode(types.SupportedResponse{
+			Kinds: []types.SupportedKind{
+				{X402Version: 2, Scheme: "exact", Network: Network},
+				{X402Version: 2, Scheme: "exact", Network: "eip155:8453"},
+			},
+			Extensions: []string{},
+			Signers:    map[string][]string{"eip155:*": {}},
+		})
+	case "/verify":
+		var req types.VerifyRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			json.NewEncoder(w).Encode(map[string]string{"error": err.Error()})
+			return
+		}
+		f.mu.Lock()
+		f.verifies = append(f.verifies, req)
+		f.mu.Unlock()
+		json.NewEncoder(w).Encode(f.verify(&req.PaymentPayload, &req.PaymentRequirements))
+	case "/settle":
+		var req types.SettleRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			json.NewEncoder(w).Encode(map[string]string{"error": err.Error()})
+			return
+		}
+		json.NewEncoder(w).Encode(f.settle(&req))
+	default:
+		http.NotFound(w, r)
+	}
+}
+
+func (f *Facilitator) verify(payload *types.PaymentPayload, requirements *types.PaymentRequirements) *types.VerifyResponse {
+	f.mu.Lock()
+	strict, invalid := f.strict, f.invalid
+	f.mu.Unlock()
+
+	resp := &types.VerifyResponse{X402Version: 2, IsValid: true, Payer: payer(payload)}
+	if invalid != "" {
+		resp.IsValid = false
+		resp.InvalidReason = invalid
+		resp.InvalidMessage = "rejected by test facilitator"
+		return resp
+	}
+	if strict && requirements.Scheme == "exact" && !utils.IsSolanaNetwork(requirements.Network) {
+		if failures := facilitator.VerifyOffline(payload, requirements, time.Now()); len(failures) > 0 {
+			resp.IsValid = false
+			resp.InvalidReason = failures[0].Code
+			resp.InvalidMessage = failures[0].Reason
+		}
+	}
+	return resp
+}
+
+func (f *Facilitator) settle(req *types.SettleRequest) *types.SettleResponse {
+	if verified := f.verify(&req.PaymentPayload, &req.PaymentRequirements); !verified.IsValid {
+		return &types.SettleResponse{
+			ErrorReason:  verified.InvalidReason,
+			ErrorMessage: verified.InvalidMessage,
+			Payer:        verified.Payer,
+			Network:      req.PaymentRequirements.Network,
+		}
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	resp := &types.SettleResponse{
+		Payer:   payer(&req.PaymentPayload),
+		Network: req.PaymentRequirements.Network,
+		Amount:  req.Amount,
+	}
+	if f.settleFailed != "" {
+		resp.ErrorReason = f.settleFailed
+		resp.ErrorMessage = "failed by test facilitator"
+		return resp
+	}
+	f.settles = append(f.settles, *req)
+	resp.Success = true
+	resp.Transaction = fmt.Sprintf("0x%064x", len(f.settles))
+	return resp
+}
+
+// payer is the address paying with an exact EVM payload
+func payer(payload *types.PaymentPayload) string {
+	if auth, err := utils.ExtractExactAuthorization(payload); err == nil {
+		return auth.From
+	}
+	return ""
+}
@@ -0,0 +1,96 @@
+package x402test
+
+import (
+	"crypto/ecdsa"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/vorpalengineering/x402-go/resource/client"
+	"github.com/vorpalengineering/x402-go/types"
+	"github.com/vorpalengineering/x402-go/utils"
+)
+
+// Test network and token: USDC on Base Sepolia, paid to a development address
+const (
+	Network = "eip155:84532"
+	USDC    = "0x036CbD53842c5426634e7929541eC2318f3dCF7e"
+	PayTo   = "0x3C44CdDdB6a900fa2b585dd299e03d12FA4293BC"
+)
+
+// Requirements prices a route at amount atomic units of USDC on Base Sepolia,
+// with the token's EIP-712 domain in Extra so payments verify offline
+func Requirements(amount string) types.PaymentRequirements {
+	return types.PaymentRequirements{
+		Scheme:            "exact",
+		Network:           Network,
+		Amount:            amount,
+		Asset:             USDC,
+		PayTo:             PayTo,
+		MaxTimeoutSeconds: 60,
+		Extra:             map[string]any{"name": "USDC", "version": "2"},
+	}
+}
+
+// Payer signs exact-scheme payments with a test key
+type Payer struct {
+	// Address is the payer's checksummed address
+	Address string
+
+	key    *ecdsa.PrivateKey
+	client *client.ResourceClient
+}
+
+// NewPayer returns a payer with a fresh random key
+func NewPayer(t testing.TB) *Payer {
+	t.Helper()
+	key, err := crypto.GenerateKey()
+	if err != nil {
+		t.Fatalf("Failed to generate payer key: %v", err)
+	}
+	return newPayer(key)
+}
+
+// NewPayerFromKey returns a payer signing with a hex private key, e.g. a
+// well-known development key when tests compare addresses
+func NewPayerFromKey(t testing.TB, hexKey string) *Payer {
+	t.Helper()
+	key, err := crypto.HexToECDSA(hexKey)
+	if err != nil {
+		t.Fatalf("Invalid payer key: %v", err)
+	}
+	return newPayer(key)
+}
+
+func newPayer(key *ecdsa.PrivateKey) *Payer {
+	return &Payer{
+		Address: crypto.PubkeyToAddress(key.PublicKey).Hex(),
+		key:     key,
+		client:  client.NewResourceClient(key),
+	}
+}
+
+// Header signs a payment for requirements and returns it encoded for the
+// PAYMENT-SIGNATURE header. Each call uses a fresh nonce.
+func (p *Payer) Header(t testing.TB, requirements types.PaymentRequirements) string {
+	t.Helper()
+	payload, err := p.client.Payload(&requirements)
+	if err != nil {
+		t.Fatalf("Failed to sign payment: %v", err)
+	}
+	header, err := utils.EncodePaymentHeader(payload)
+	if err != nil {
+		t.Fatalf("Failed to encode payment: %v", err)
+	}
+	return header
+}
+
+// Request returns a request to target carrying a payment for requirements
+func (p *Payer) Request(t testing.TB, method, target string, body io.Reader, requirements types.PaymentRequirements) *http.Request {
+	t.Helper()
+	req := httptest.NewRequest(method, target, body)
+	req.Header.Set(utils.DefaultPaymentHeaderName, p.Header(t, requirements))
+	return req
+}
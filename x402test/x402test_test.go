@@ -0,0 +1,97 @@
+package x402test_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/vorpalengineering/x402-go/resource/middleware"
+	"github.com/vorpalengineering/x402-go/types"
+	"github.com/vorpalengineering/x402-go/x402test"
+)
+
+func newRouter(t *testing.T, facilitatorURL string, requirements types.PaymentRequirements) *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	cfg := &middleware.MiddlewareConfig{
+		FacilitatorURL:      facilitatorURL,
+		DefaultRequirements: requirements,
+		ProtectedPaths:      []string{"/data"},
+	}
+	if err := cfg.Validate(); err != nil {
+		t.Fatalf("Expected valid config, got %v", err)
+	}
+	router := gin.New()
+	router.Use(middleware.NewX402Middleware(cfg).Handler())
+	router.GET("/data", func(ctx *gin.Context) {
+		ctx.String(http.StatusOK, "data")
+	})
+	return router
+}
+
+func TestPaidRequest(t *testing.T) {
+	facilitator := x402test.NewFacilitator(t).Strict()
+	requirements := x402test.Requirements("1000")
+	router := newRouter(t, facilitator.URL, requirements)
+	payer := x402test.NewPayer(t)
+
+	// Unpaid requests are asked for the route's price
+	recorder := httptest.NewRecorder()
+	router.ServeHTTP(recorder, httptest.NewRequest("GET", "/data", nil))
+	paymentRequired := x402test.AssertPaymentRequired(t, recorder)
+	if paymentRequired.Accepts[0].Amount != "1000" {
+		t.Errorf("Expected price 1000, got %s", paymentRequired.Accepts[0].Amount)
+	}
+
+	// Signed payments verify offline and settle
+	recorder = httptest.NewRecorder()
+	router.ServeHTTP(recorder, payer.Request(t, "GET", "/data", nil, requirements))
+	settleResp := x402test.AssertPaid(t, recorder)
+	if settleResp.Payer != payer.Address {
+		t.Errorf("Expected payer %s, got %s", payer.Address, settleResp.Payer)
+	}
+	facilitator.AssertSettled(t, 1)
+}
+
+func TestStrictFacilitatorChecksPayments(t *testing.T) {
+	facilitator := x402test.NewFacilitator(t).Strict()
+	requirements := x402test.Requirements("1000")
+	router := newRouter(t, facilitator.URL, requirements)
+
+	// A header signed for another recipient fails verification
+	other := requirements
+	other.PayTo = "0x70997970C51812dc3A010C7d01b50e0d17dc79C8"
+	header := x402test.NewPayer(t).Header(t, other)
+	req := httptest.NewRequest("GET", "/data", nil)
+	req.Header.Set("PAYMENT-SIGNATURE", header)
+	recorder := httptest.NewRecorder()
+	router.ServeHTTP(recorder, req)
+	if recorder.Code != http.StatusPaymentRequired {
+		t.Errorf("Expected status 402 for mismatched payment, got %d", recorder.Code)
+	}
+	facilitator.AssertSettled(t, 0)
+}
+
+func TestFacilitatorFailures(t *testing.T) {
+	facilitator := x402test.NewFacilitator(t)
+	requirements := x402test.Requirements("1000")
+	router := newRouter(t, facilitator.URL, requirements)
+	payer := x402test.NewPayer(t)
+
+	facilitator.RejectPayments(types.ErrCodeInsufficientFunds)
+	recorder := httptest.NewRecorder()
+	router.ServeHTTP(recorder, payer.Request(t, "GET", "/data", nil, requirements))
+	x402test.AssertRejected(t, recorder, types.ErrCodeInsufficientFunds)
+
+	facilitator.RejectPayments("")
+	facilitator.FailSettlements(types.ErrCodeTransactionReverted)
+	recorder = httptest.NewRecorder()
+	router.ServeHTTP(recorder, payer.Request(t, "GET", "/data", nil, requirements))
+	if recorder.Code != http.StatusPaymentRequired || recorder.Body.String() == "data" {
+		t.Errorf("Expected failed settlement to withhold the response, got %d: %s", recorder.Code, recorder.Body.String())
+	}
+	facilitator.AssertSettled(t, 0)
+	if verifications := len(facilitator.Verifications()); verifications != 2 {
+		t.Errorf("Expected 2 verifications, got %d", verifications)
+	}
+}